@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"cronhustler/api-service/internal/backup"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// server backup  [-out=backup.tar.gz] [-passphrase-file=path]
+// server restore -in=backup.tar.gz [-verify-only] [-yes] [-passphrase-file=path]
+//
+// Passphrase lấy từ -passphrase-file hoặc BACKUP_PASSPHRASE, rỗng = không mã hoá.
+func runBackup(args []string) {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	out := fs.String("out", "", "file archive (mặc định cronchat-backup-<time>.tar.gz[.enc])")
+	passFile := fs.String("passphrase-file", "", "file chứa passphrase mã hoá (ưu tiên hơn BACKUP_PASSPHRASE)")
+	fs.Parse(args)
+
+	passphrase := loadPassphrase(*passFile)
+	if *out == "" {
+		*out = "cronchat-backup-" + time.Now().Format("20060102-150405") + ".tar.gz"
+		if len(passphrase) > 0 {
+			*out += ".enc"
+		}
+	}
+
+	database := openCommandDB()
+	defer database.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// ghi file tạm rồi rename: archive dở dang không bị nhầm là backup hợp lệ
+	tmp := *out + ".partial"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	h := sha256.New()
+	m, err := backup.Create(ctx, database, uploadDirs(), passphrase, io.MultiWriter(f, h))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		log.Fatalf("❌ Backup lỗi: %v", err)
+	}
+	if err := os.Rename(tmp, *out); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// checksum cả file (format sha256sum) để kiểm tra trước khi restore / sau khi copy
+	sum := hex.EncodeToString(h.Sum(nil))
+	if err := os.WriteFile(*out+".sha256", []byte(sum+"  "+filepath.Base(*out)+"\n"), 0o644); err != nil {
+		log.Printf("⚠️  Không ghi được %s.sha256: %v", *out, err)
+	}
+
+	log.Printf("📦 Backup          : %s", *out)
+	log.Printf("   database       : %s, %d tables, %d rows", m.Database.Database, m.Database.Tables, m.Database.Rows)
+	log.Printf("   files          : %d (%d bytes), encrypted=%v", len(m.Files), m.TotalBytes(), m.Encrypted)
+	log.Printf("   sha256         : %s", sum)
+	log.Println("✅ Done")
+}
+
+func runRestore(args []string) {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	in := fs.String("in", "", "file archive cần restore")
+	passFile := fs.String("passphrase-file", "", "file chứa passphrase (ưu tiên hơn BACKUP_PASSPHRASE)")
+	verifyOnly := fs.Bool("verify-only", false, "chỉ giải mã + kiểm tra checksum, không ghi DB / thư mục")
+	yes := fs.Bool("yes", false, "xác nhận ghi đè DB và thư mục upload hiện tại")
+	fs.Parse(args)
+
+	if *in == "" {
+		log.Fatal("❌ Thiếu -in")
+	}
+	if !*verifyOnly && !*yes {
+		log.Fatal("❌ Restore sẽ ghi đè DB + thư mục upload hiện tại, thêm -yes để xác nhận (hoặc -verify-only)")
+	}
+
+	f, err := os.Open(*in)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	defer f.Close()
+
+	var database *sql.DB
+	if !*verifyOnly {
+		database = openCommandDB()
+		defer database.Close()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	res, err := backup.Restore(ctx, database, uploadDirs(), loadPassphrase(*passFile), f, *verifyOnly)
+	if err != nil {
+		log.Fatalf("❌ Restore lỗi: %v", err)
+	}
+
+	out, _ := json.MarshalIndent(res, "", "  ")
+	log.Printf("📊 Restore:\n%s", out)
+	for name, old := range res.OldDirs {
+		log.Printf("🗂️  %s cũ giữ ở %s (xoá tay khi đã kiểm tra)", name, old)
+	}
+	log.Println("✅ Done")
+}
+
+// uploadDirs: cùng ENV với server
+func uploadDirs() []backup.Dir {
+	return []backup.Dir{
+		{Name: "avatars", Path: envOr("AVATAR_DIR", "./data/user_avatars")},
+		{Name: "chat_uploads", Path: envOr("CHAT_UPLOAD_DIR", "./data/chat_uploads")},
+	}
+}
+
+func loadPassphrase(file string) []byte {
+	if file == "" {
+		return []byte(os.Getenv("BACKUP_PASSPHRASE"))
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		log.Fatalf("❌ Không đọc được passphrase: %v", err)
+	}
+	return []byte(strings.TrimRight(string(b), "\r\n"))
+}
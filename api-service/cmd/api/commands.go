@@ -0,0 +1,50 @@
+package main
+
+import (
+	"cronhustler/api-service/internal/config"
+	"cronhustler/db"
+	"database/sql"
+	"log"
+	"os"
+)
+
+// Subcommand (không chạy HTTP server): server <migrate|backup|restore|seed|loadtest> [flags]
+func runCommand(name string, args []string) {
+	switch name {
+	case "migrate":
+		runMigrate(args)
+	case "backup":
+		runBackup(args)
+	case "restore":
+		runRestore(args)
+	case "seed":
+		runSeed(args)
+	case "loadtest":
+		runLoadtest(args)
+	default:
+		log.Fatalf("❌ Lệnh không hợp lệ: %q (migrate | backup | restore | seed | loadtest)", name)
+	}
+}
+
+func openCommandDB() *sql.DB {
+	mysqlCfg, err := config.MySQLFromEnv()
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	database, err := db.OpenMySQL(mysqlCfg.DSN())
+	if err != nil {
+		log.Fatalf("❌ Không kết nối được MySQL: %v", err)
+	}
+	if err := database.Ping(); err != nil {
+		log.Fatalf("❌ MySQL không sẵn sàng: %v", err)
+	}
+	return database
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"cronhustler/api-service/internal/loadtest"
+	"cronhustler/api-service/internal/seed"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// server loadtest -target=http://localhost:5555 -users=50 -duration=2m [-rate=0.2] [-users-file=path] [-json=report.json]
+// User lấy từ -users-file (mỗi dòng "username" hoặc "username:password"), không có thì lấy user demo_* trong DB (xem seed).
+func runLoadtest(args []string) {
+	fs := flag.NewFlagSet("loadtest", flag.ExitOnError)
+	target := fs.String("target", "http://localhost:5555", "URL server cần test")
+	users := fs.Int("users", 20, "số user ảo đồng thời")
+	usersFile := fs.String("users-file", "", "file danh sách user (username[:password] mỗi dòng)")
+	password := fs.String("password", seed.DefaultPassword, "mật khẩu mặc định khi dòng không có password")
+	duration := fs.Duration("duration", time.Minute, "thời gian chạy (sau ramp-up)")
+	ramp := fs.Duration("ramp", 10*time.Second, "dàn đều thời điểm user ảo bắt đầu")
+	rate := fs.Float64("rate", 0.2, "tin / giây / user")
+	seen := fs.Duration("seen", 2*time.Second, "chu kỳ mark seen room có tin mới")
+	unread := fs.Duration("unread", 15*time.Second, "chu kỳ poll /rooms/unread-counts (0 = tắt)")
+	jsonOut := fs.String("json", "", "ghi report JSON ra file")
+	fs.Parse(args)
+
+	creds := loadtestUsers(*usersFile, *users, *password)
+	if len(creds) < *users {
+		log.Printf("⚠️  Chỉ có %d user (yêu cầu %d), chạy seed để tạo thêm", len(creds), *users)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("🔥 Loadtest %s: %d users, %.2f msg/s/user, ramp %v, duration %v", *target, len(creds), *rate, *ramp, *duration)
+	rep, err := loadtest.Run(ctx, loadtest.Config{
+		Target:      *target,
+		Users:       creds,
+		Duration:    *duration,
+		RampUp:      *ramp,
+		SendRate:    *rate,
+		SeenEvery:   *seen,
+		UnreadEvery: *unread,
+	})
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	log.Printf("📊 run=%s elapsed=%s connected=%d/%d sent=%d (%.1f msg/s) delivered=%d",
+		rep.RunID, rep.Elapsed, rep.Connected, rep.Users, rep.Sent, rep.SendRPS, rep.Delivered)
+	loadtest.WriteTable(os.Stdout, rep.Ops)
+
+	if *jsonOut != "" {
+		b, _ := json.MarshalIndent(rep, "", "  ")
+		if err := os.WriteFile(*jsonOut, b, 0o644); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		log.Printf("💾 Report: %s", *jsonOut)
+	}
+}
+
+func loadtestUsers(file string, n int, password string) []loadtest.Credential {
+	var out []loadtest.Credential
+	if file == "" {
+		database := openCommandDB()
+		defer database.Close()
+
+		like := strings.ReplaceAll(seed.UsernamePrefix, "_", `\_`) + "%"
+		rows, err := database.Query(`SELECT username FROM users WHERE username LIKE ? AND is_active = 1 ORDER BY id LIMIT ?`, like, n)
+		if err != nil {
+			log.Fatalf("❌ Đọc user demo: %v", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var u string
+			if err := rows.Scan(&u); err != nil {
+				log.Fatalf("❌ %v", err)
+			}
+			out = append(out, loadtest.Credential{Username: u, Password: password})
+		}
+		if err := rows.Err(); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		return out
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() && len(out) < n {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		u, p, ok := strings.Cut(line, ":")
+		if !ok {
+			p = password
+		}
+		out = append(out, loadtest.Credential{Username: u, Password: p})
+	}
+	if err := sc.Err(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	return out
+}
@@ -1,11 +1,27 @@
 package main
 
 import (
+	"context"
+	"cronhustler/api-service/internal/broker"
+	"cronhustler/api-service/internal/config"
 	"cronhustler/api-service/internal/httpserver"
+	"cronhustler/api-service/internal/linkpreview"
+	"cronhustler/api-service/internal/notify"
+	"cronhustler/api-service/internal/oauth"
+	"cronhustler/api-service/internal/push"
+	"cronhustler/api-service/internal/reqlog"
+	"cronhustler/api-service/internal/scan"
+	"cronhustler/api-service/internal/sms"
+	"cronhustler/api-service/internal/storage"
+	"cronhustler/api-service/internal/summary"
 	"cronhustler/db"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -19,38 +35,37 @@ func main() {
 		log.Println("⚠️  Không tìm thấy file .env, dùng ENV hệ thống")
 	}
 
-	// ============================
-	// 2) Build MySQL DSN
-	// ============================
-	mysqlUser := os.Getenv("MYSQL_USER")
-	mysqlPass := os.Getenv("MYSQL_PASSWORD")
-	mysqlHost := os.Getenv("MYSQL_HOST")
-	mysqlPort := os.Getenv("MYSQL_PORT")
-	mysqlDB := os.Getenv("MYSQL_DATABASE")
-
-	if mysqlUser == "" || mysqlDB == "" {
-		log.Fatal("❌ Thiếu MYSQL_USER hoặc MYSQL_DATABASE trong ENV")
+	// CONFIG_FILE (YAML) chỉ lấp key env chưa set, subcommand cũng dùng được
+	if err := config.ApplyFile(os.Getenv("CONFIG_FILE")); err != nil {
+		log.Fatalf("❌ %v", err)
 	}
 
-	if mysqlHost == "" {
-		mysqlHost = "127.0.0.1"
-	}
-	if mysqlPort == "" {
-		mysqlPort = "3306"
+	// subcommand: backup | restore (xem backup.go), không chạy HTTP server
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1], os.Args[2:])
+		return
 	}
 
-	dsn := mysqlUser + ":" + mysqlPass +
-		"@tcp(" + mysqlHost + ":" + mysqlPort + ")/" +
-		mysqlDB + "?parseTime=true&charset=utf8mb4&loc=Local"
+	// slog (LOG_FORMAT text | json), log.Printf cũ cũng đi qua đây
+	reqlog.Setup()
+
+	// ============================
+	// 2) Config (validate 1 lần, báo hết lỗi)
+	// ============================
+	cfg, err := config.FromEnv()
+	if err != nil {
+		log.Fatalf("❌ Config không hợp lệ:\n%v", err)
+	}
+	cfg.Print(log.Printf)
 
 	// ============================
 	// 3) Kết nối MySQL
 	// ============================
-	database, err := db.OpenMySQL(dsn)
+	// lỗi query trong request -> log + error kèm request_id
+	database, err := db.OpenMySQLWrapped(cfg.MySQL.DSN(), reqlog.WrapConnector)
 	if err != nil {
 		log.Fatalf("❌ Không kết nối được MySQL: %v", err)
 	}
-	defer database.Close()
 
 	for i := 1; i <= 3; i++ {
 		if err := database.Ping(); err == nil {
@@ -65,65 +80,276 @@ func main() {
 
 	log.Println("✅ MySQL connected")
 
+	// schema: migration nhúng trong binary (db/migrations), nhiều instance start cùng lúc thì chờ lock
+	if cfg.MigrateOnStart {
+		migrator, err := db.NewMigrator(database)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		n, err := migrator.Up(context.Background())
+		if err != nil {
+			log.Fatalf("❌ Migrate lỗi: %v", err)
+		}
+		log.Printf("📜 Migrations      : %d applied", n)
+	}
+
+	// ============================
+	// 4) Thư mục avatar + chat upload
+	// ============================
+	mustCreateDir("Avatar", cfg.AvatarDir)
+	mustCreateDir("Chat upload", cfg.ChatUploadDir)
+
+	// SIGINT / SIGTERM -> dừng worker nền + graceful shutdown (bước 7)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// ============================
+	// 5) Create server
+	// ============================
+	// STORAGE_DRIVER local (mặc định) | s3 | minio, S3 dùng chung S3_* với migrate-storage
+	store, err := storage.NewFromEnv(cfg.ChatUploadDir, cfg.AvatarDir)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// media CDN, ffmpeg, quota, SMS country code, provisioning, service token, Swagger UI lấy từ cfg
+	srv := httpserver.NewServer(database, cfg, httpserver.WithStorage(store))
+
+	// ============================
+	// 5.1) Video transcode (optional, cần ffmpeg)
+	// ============================
+	// FFMPEG_PATH dùng chung cho transcode video + waveform audio
+	if cfg.VideoTranscode {
+		if srv.EnableVideoTranscoding(ctx, cfg.FFmpegPath) {
+			log.Println("🎬 Video transcode : enabled")
+		} else {
+			log.Println("⚠️  VIDEO_TRANSCODE=1 nhưng không tìm thấy ffmpeg, bỏ qua")
+		}
+	}
+
+	// ============================
+	// 5.1.1) Quét virus file upload (SCAN_PROVIDER clamav | http, rỗng = tắt)
+	// ============================
+	if scanner, err := scan.NewScannerFromEnv(); err == nil {
+		srv.SetUploadScanner(scanner, cfg.ScanQuarantineDir)
+		log.Printf("🛡️  Upload scan     : %s (quarantine %s)", cfg.ScanProvider, cfg.ScanQuarantineDir)
+	} else if !errors.Is(err, scan.ErrDisabled) {
+		log.Fatalf("❌ Upload scan: %v", err)
+	}
+
+	// ============================
+	// 5.2) Push notification (optional, FCM / APNs / Web Push)
+	// ============================
+	var fcmSender, apnsSender push.Sender
+	if f := os.Getenv("FCM_SERVICE_ACCOUNT_FILE"); f != "" {
+		sender, err := push.NewFCMSender(f)
+		if err != nil {
+			log.Fatalf("❌ FCM: %v", err)
+		}
+		fcmSender = sender
+		log.Println("📲 Push FCM       : enabled")
+	}
+	if f := os.Getenv("APNS_KEY_FILE"); f != "" {
+		sender, err := push.NewAPNsSender(f,
+			os.Getenv("APNS_KEY_ID"),
+			os.Getenv("APNS_TEAM_ID"),
+			os.Getenv("APNS_TOPIC"),
+			os.Getenv("APNS_PRODUCTION") == "1",
+		)
+		if err != nil {
+			log.Fatalf("❌ APNs: %v", err)
+		}
+		apnsSender = sender
+		log.Println("📲 Push APNs      : enabled")
+	}
+	// Web Push (trình duyệt): bật khi có VAPID_SUBJECT, key lấy từ env hoặc tự sinh lưu DB
+	var webSender *push.WebPushSender
+	if subject := os.Getenv("VAPID_SUBJECT"); subject != "" {
+		sender, err := srv.LoadWebPush(ctx, subject,
+			os.Getenv("VAPID_PUBLIC_KEY"),
+			os.Getenv("VAPID_PRIVATE_KEY"),
+		)
+		if err != nil {
+			log.Fatalf("❌ Web Push: %v", err)
+		}
+		webSender = sender
+		log.Println("📲 Push Web       : enabled")
+	}
+	if fcmSender != nil || apnsSender != nil || webSender != nil {
+		srv.EnablePush(ctx, fcmSender, apnsSender, webSender)
+	}
+
+	// ============================
+	// 5.3) Media janitor (dọn file upload mồ côi)
+	// ============================
+	if cfg.MediaJanitorInterval > 0 {
+		srv.StartMediaJanitor(ctx, cfg.MediaJanitorInterval, cfg.MediaOrphanGrace)
+		log.Printf("🧹 Media janitor   : every %s", cfg.MediaJanitorInterval)
+	}
+
+	// ============================
+	// 5.4) Email: MAIL_PROVIDER smtp | sendgrid | ses (chưa cấu hình = tắt) + digest tin chưa đọc
+	// ============================
+	if mailer, err := notify.NewMailerFromEnv(); err == nil {
+		tpl, err := notify.LoadTemplates(cfg.MailTemplateDir)
+		if err != nil {
+			log.Fatalf("❌ MAIL_TEMPLATE_DIR: %v", err)
+		}
+		srv.SetMailer(mailer, tpl)
+		srv.EnableEmailDigest(ctx, cfg.AppPublicURL, cfg.EmailDigestInterval)
+		log.Printf("📧 Email digest    : every %s", cfg.EmailDigestInterval)
+	} else if !errors.Is(err, notify.ErrMailDisabled) {
+		log.Fatalf("❌ Mail: %v", err)
+	}
+
+	// ============================
+	// 5.5) Outgoing webhooks (giao event message/member ra URL ngoài)
+	// ============================
+	srv.StartWebhookDispatcher(ctx)
+
+	// ============================
+	// 5.6) Reminder scheduler (/remind, gửi bằng Reminder bot)
+	// ============================
+	srv.StartReminderScheduler(ctx, cfg.ReminderInterval)
+	log.Printf("⏰ Reminders       : every %s", cfg.ReminderInterval)
+
+	// ============================
+	// 5.7) Cron job runner (/jobs, lock trong DB nên chạy nhiều instance được)
+	// ============================
+	srv.StartJobRunner(ctx, cfg.JobPollInterval)
+	log.Printf("🗓️  Job runner      : every %s", cfg.JobPollInterval)
+
+	// ============================
+	// 5.8) Data export (GDPR): hàng đợi trong DB, file zip giữ EXPORT_TTL
+	// ============================
+	if err := srv.StartExportWorker(ctx, cfg.ExportDir, cfg.ExportTTL, cfg.ExportPollInterval); err != nil {
+		log.Fatalf("❌ Không tạo được EXPORT_DIR %s: %v", cfg.ExportDir, err)
+	}
+	log.Printf("📦 Data export     : %s (keep %s)", cfg.ExportDir, cfg.ExportTTL)
+
+	// ============================
+	// 5.9) Quota / flood control (0 = không giới hạn, admin ghi đè theo user)
+	// ============================
+	log.Printf("🚦 Quota           : %d msg/min, %d msg/day, %d rooms/day", cfg.Quota.MessagesPerMinute, cfg.Quota.MessagesPerDay, cfg.Quota.RoomsPerDay)
+
 	// ============================
-	// 4) JWT Secret
+	// 5.10) SMS OTP (SMS_PROVIDER twilio | log, rỗng = tắt): xác minh số điện thoại + 2FA khi login
 	// ============================
-	secret := []byte(os.Getenv("GO_SECRET_KEY"))
-	if len(secret) == 0 {
-		log.Fatal("❌ GO_SECRET_KEY chưa được cấu hình")
+	if sender, err := sms.NewSenderFromEnv(); err == nil {
+		srv.SetSMSSender(sender, cfg.SMSCountryCode)
+		log.Printf("📱 SMS OTP         : %s", os.Getenv("SMS_PROVIDER"))
+	} else if !errors.Is(err, sms.ErrDisabled) {
+		log.Fatalf("❌ SMS: %v", err)
 	}
 
 	// ============================
-	// 5) Server address
+	// 5.11) Provisioning API cho HR / IdP (PROVISIONING_TOKEN rỗng = tắt)
 	// ============================
-	addr := os.Getenv("BASE_URL")
-	if addr == "" {
-		addr = ":5555"
+	if cfg.ProvisioningToken != "" {
+		log.Println("🪪 Provisioning    : enabled")
 	}
 
 	// ============================
-	// 6) Avatar directory
+	// 5.12) Tóm tắt room bằng LLM (LLM_PROVIDER openai | anthropic, rỗng = tắt)
 	// ============================
-	avatarDir := os.Getenv("AVATAR_DIR")
-	if avatarDir == "" {
-		avatarDir = "./data/user_avatars"
+	if provider, err := summary.NewProviderFromEnv(); err == nil {
+		srv.SetSummaryProvider(provider, cfg.LLMSummaryPerHour)
+		log.Printf("🧠 Room summary    : %s (%s), %d/user/h", os.Getenv("LLM_PROVIDER"), os.Getenv("LLM_MODEL"), cfg.LLMSummaryPerHour)
+	} else if !errors.Is(err, summary.ErrDisabled) {
+		log.Fatalf("❌ LLM: %v", err)
 	}
-	mustCreateDir("Avatar", avatarDir)
 
 	// ============================
-	// 7) Chat upload directory (NEW)
+	// 5.13) Service token cho caller nội bộ (SERVICE_TOKEN_SECRET rỗng = tắt)
 	// ============================
-	chatUploadDir := os.Getenv("CHAT_UPLOAD_DIR")
-	if chatUploadDir == "" {
-		chatUploadDir = "./data/chat_uploads"
+	if cfg.ServiceTokenSecret != "" {
+		log.Println("🔑 Service tokens  : enabled")
 	}
-	mustCreateDir("Chat upload", chatUploadDir)
 
 	// ============================
-	// 8) Create server
+	// 5.14) WS pub/sub (PUBSUB_DRIVER redis khi chạy nhiều replica)
+	// ============================
+	wsBroker, err := broker.NewFromEnv()
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if wsBroker != nil {
+		defer wsBroker.Close()
+		srv.EnableWSBroker(ctx, wsBroker)
+		log.Printf("📡 WS pub/sub      : %s", os.Getenv("PUBSUB_DRIVER"))
+	}
+	// journal event để client kết nối lại replay (/ws?last_seq=), dùng chung DB nên đúng cả khi nhiều replica
+	srv.EnableWSJournal(ctx, cfg.WSJournalTTL)
+	log.Printf("🧾 WS journal      : keep %s", cfg.WSJournalTTL)
+
 	// ============================
-	srv := httpserver.NewServer(
-		database,
-		secret,
-		avatarDir,
-		chatUploadDir, // 👈 NEW
-	)
+	// 5.15) Tin tự huỷ theo room (PATCH /rooms/{id}/retention)
+	// ============================
+	srv.StartRoomTTLPurger(ctx, cfg.RoomTTLInterval)
+	log.Printf("⏳ Room TTL purge  : every %s", cfg.RoomTTLInterval)
+
+	// ============================
+	// 5.16) Preview link trong tin (OG metadata), LINK_PREVIEW_ENABLED=0 để tắt
+	// ============================
+	if cfg.LinkPreviewEnabled {
+		srv.EnableLinkPreviews(linkpreview.NewFetcher(cfg.LinkPreviewTimeout))
+		log.Printf("🔗 Link preview    : enabled (timeout %s)", cfg.LinkPreviewTimeout)
+	}
 
-	log.Printf("🖼  Avatar dir      : %s", avatarDir)
-	log.Printf("🖼  Chat upload dir : %s", chatUploadDir)
+	// ============================
+	// 5.17) Social login Google / GitHub (OAUTH_*_CLIENT_ID rỗng hết = tắt)
+	// ============================
+	if oauthCfg, err := oauth.NewConfigFromEnv(); err == nil {
+		srv.EnableOAuth(oauthCfg, cfg.AppPublicURL)
+		log.Printf("🌐 Social login    : %s (signup %v)", strings.Join(oauthCfg.Names(), ", "), oauthCfg.AllowSignup)
+	} else if !errors.Is(err, oauth.ErrDisabled) {
+		log.Fatalf("❌ OAuth: %v", err)
+	}
 
 	// ============================
-	// 9) Routes + CORS
+	// 6) Routes + CORS
 	// ============================
 	handler := httpserver.WithCORS(srv.Routes())
 
 	// ============================
-	// 10) Run server
+	// 7) Run server (timeout + graceful shutdown)
 	// ============================
-	log.Printf("🚀 Server running on http://%s", addr)
-	if err := http.ListenAndServe(addr, handler); err != nil {
+	httpSrv := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       cfg.HTTPReadTimeout, // upload video tới 100MB
+		WriteTimeout:      cfg.HTTPWriteTimeout,
+		IdleTimeout:       cfg.HTTPIdleTimeout,
+	}
+	// WS đã hijack, Shutdown không chờ -> tự gửi close frame
+	httpSrv.RegisterOnShutdown(srv.CloseWebSockets)
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Printf("🚀 Server running on http://%s", cfg.Addr)
+		errCh <- httpSrv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
 		log.Fatal(err)
+	case <-ctx.Done():
+	}
+	stop() // Ctrl+C lần 2 -> thoát ngay
+
+	log.Printf("🛑 Shutting down (chờ request đang chạy tối đa %s)...", cfg.ShutdownTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := httpSrv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("⚠️  Shutdown: %v", err)
+	}
+
+	if err := database.Close(); err != nil {
+		log.Printf("⚠️  Đóng MySQL: %v", err)
 	}
+	log.Println("👋 Bye")
 }
 
 // helper tạo thư mục
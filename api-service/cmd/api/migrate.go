@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"cronhustler/db"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// server migrate [up | down -steps=N | status | baseline -version=N]
+// Migration nhúng trong binary (db/migrations), server cũng tự chạy up lúc start (MIGRATE_ON_START=0 để tắt).
+func runMigrate(args []string) {
+	action := "up"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		action, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	steps := fs.Int("steps", 1, "down: số migration rollback")
+	version := fs.Int("version", db.LegacyVersion, "baseline: đánh dấu đã apply tới version này")
+	fs.Parse(args)
+
+	database := openCommandDB()
+	defer database.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	m, err := db.NewMigrator(database)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	switch action {
+	case "up":
+		n, err := m.Up(ctx)
+		if err != nil {
+			log.Fatalf("❌ Migrate lỗi: %v", err)
+		}
+		log.Printf("✅ Applied %d migration(s)", n)
+	case "down":
+		if err := m.Down(ctx, *steps); err != nil {
+			log.Fatalf("❌ Rollback lỗi: %v", err)
+		}
+		log.Println("✅ Done")
+	case "baseline":
+		if err := m.Baseline(ctx, *version); err != nil {
+			log.Fatalf("❌ Baseline lỗi: %v", err)
+		}
+		log.Printf("✅ Baseline tới version %d", *version)
+	case "status":
+		list, err := m.Status(ctx)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		for _, st := range list {
+			at := "pending"
+			if st.AppliedAt != nil {
+				at = st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			log.Printf("   %04d_%-28s %s", st.Version, st.Name, at)
+		}
+	default:
+		log.Fatalf("❌ migrate: action không hợp lệ %q (up | down | status | baseline)", action)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"cronhustler/api-service/internal/seed"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// server seed [-scale=small|medium|large] [-users=N -groups=N -directs=N ...] [-media=0.05] [-seed=1] [-reset]
+// Tạo user demo_* (mật khẩu chung demo1234), room, tin nhắn, reply, reaction, receipt, ảnh / file.
+func runSeed(args []string) {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	scale := fs.String("scale", "small", "preset: small | medium | large")
+	users := fs.Int("users", 0, "số user (ghi đè preset)")
+	groups := fs.Int("groups", -1, "số group room (ghi đè preset)")
+	directs := fs.Int("directs", -1, "số direct room (ghi đè preset)")
+	groupSize := fs.Int("group-size", 0, "số thành viên tối đa / group (ghi đè preset)")
+	groupMsgs := fs.Int("group-messages", -1, "số tin / group (ghi đè preset)")
+	directMsgs := fs.Int("direct-messages", -1, "số tin / direct room (ghi đè preset)")
+	days := fs.Int("days", 0, "lịch sử trải trong N ngày (ghi đè preset)")
+	media := fs.Float64("media", -1, "tỉ lệ tin là ảnh / file 0..1 (ghi đè preset)")
+	seedVal := fs.Int64("seed", 0, "random seed (0 = theo thời gian)")
+	reset := fs.Bool("reset", false, "xoá dữ liệu demo cũ (user demo_*) trước khi tạo")
+	fs.Parse(args)
+
+	cfg, ok := seed.Scales[*scale]
+	if !ok {
+		log.Fatalf("❌ -scale không hợp lệ: %q (small | medium | large)", *scale)
+	}
+	if *users > 0 {
+		cfg.Users = *users
+	}
+	if *groups >= 0 {
+		cfg.GroupRooms = *groups
+	}
+	if *directs >= 0 {
+		cfg.DirectRooms = *directs
+	}
+	if *groupSize > 0 {
+		cfg.GroupSize = *groupSize
+	}
+	if *groupMsgs >= 0 {
+		cfg.GroupMessages = *groupMsgs
+	}
+	if *directMsgs >= 0 {
+		cfg.DirectMessages = *directMsgs
+	}
+	if *days > 0 {
+		cfg.Days = *days
+	}
+	if *media >= 0 {
+		cfg.MediaRatio = *media
+	}
+	cfg.Seed = *seedVal
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	database := openCommandDB()
+	defer database.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	g := seed.NewGenerator(database, cfg,
+		envOr("CHAT_UPLOAD_DIR", "./data/chat_uploads"),
+		envOr("AVATAR_DIR", "./data/user_avatars"),
+	)
+	g.Logf = func(format string, args ...any) { log.Printf("🌱 "+format, args...) }
+
+	if *reset {
+		n, err := g.Reset(ctx)
+		if err != nil {
+			log.Fatalf("❌ Reset lỗi: %v", err)
+		}
+		log.Printf("🧹 Đã xoá %d user demo cũ", n)
+	}
+
+	start := time.Now()
+	conf, _ := json.Marshal(cfg)
+	log.Printf("🌱 Seed %s: %s", *scale, conf)
+
+	rep, err := g.Run(ctx)
+	if rep != nil {
+		out, _ := json.MarshalIndent(rep, "", "  ")
+		log.Printf("📊 Report:\n%s", out)
+	}
+	if err != nil {
+		log.Fatalf("❌ Seed dừng: %v", err)
+	}
+	log.Printf("✅ Done in %v (login: %s<...> / %s)", time.Since(start).Round(time.Second), seed.UsernamePrefix, seed.DefaultPassword)
+}
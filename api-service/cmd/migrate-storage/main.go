@@ -0,0 +1,115 @@
+// migrate-storage: copy file upload local (avatar + chat uploads) lên S3,
+// rewrite media_url/avatar_url trong DB theo batch. Chạy lại được (resume) nhờ bảng storage_migrations.
+//
+//	go run ./api-service/cmd/migrate-storage -batch=200
+//	go run ./api-service/cmd/migrate-storage -dry-run
+package main
+
+import (
+	"context"
+	"cronhustler/api-service/internal/storage"
+	"cronhustler/db"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	batch := flag.Int("batch", 100, "số file mỗi batch (mỗi batch rewrite DB trong 1 transaction)")
+	dryRun := flag.Bool("dry-run", false, "chỉ đếm, không upload / không sửa DB")
+	only := flag.String("only", "", "chỉ chạy 1 loại: chat_upload | avatar")
+	flag.Parse()
+
+	// ============================
+	// 1) Load ENV
+	// ============================
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️  Không tìm thấy file .env, dùng ENV hệ thống")
+	}
+
+	// ============================
+	// 2) MySQL
+	// ============================
+	mysqlUser := os.Getenv("MYSQL_USER")
+	mysqlDB := os.Getenv("MYSQL_DATABASE")
+	if mysqlUser == "" || mysqlDB == "" {
+		log.Fatal("❌ Thiếu MYSQL_USER hoặc MYSQL_DATABASE trong ENV")
+	}
+	mysqlHost := envOr("MYSQL_HOST", "127.0.0.1")
+	mysqlPort := envOr("MYSQL_PORT", "3306")
+
+	dsn := mysqlUser + ":" + os.Getenv("MYSQL_PASSWORD") +
+		"@tcp(" + mysqlHost + ":" + mysqlPort + ")/" +
+		mysqlDB + "?parseTime=true&charset=utf8mb4&loc=Local"
+
+	database, err := db.OpenMySQL(dsn)
+	if err != nil {
+		log.Fatalf("❌ Không kết nối được MySQL: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.Ping(); err != nil {
+		log.Fatalf("❌ MySQL không sẵn sàng: %v", err)
+	}
+
+	// ============================
+	// 3) Object store
+	// ============================
+	store, err := storage.NewS3StoreFromEnv()
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// ============================
+	// 4) Run
+	// ============================
+	m := storage.NewMigrator(database, store,
+		envOr("CHAT_UPLOAD_DIR", "./data/chat_uploads"),
+		envOr("AVATAR_DIR", "./data/user_avatars"),
+	)
+	m.BatchSize = *batch
+	m.DryRun = *dryRun
+
+	if *only != "" {
+		filtered := m.Sources[:0]
+		for _, src := range m.Sources {
+			if src.Kind == *only {
+				filtered = append(filtered, src)
+			}
+		}
+		if len(filtered) == 0 {
+			log.Fatalf("❌ -only không hợp lệ: %q", *only)
+		}
+		m.Sources = filtered
+	}
+
+	// Ctrl+C -> dừng sau batch hiện tại, lần sau chạy tiếp
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("🚚 Migrate -> s3://%s (batch=%d, dry-run=%v)", store.Bucket, m.BatchSize, m.DryRun)
+
+	rep, err := m.Run(ctx)
+	out, _ := json.MarshalIndent(rep, "", "  ")
+	log.Printf("📊 Report:\n%s", out)
+	if err != nil {
+		log.Fatalf("❌ Migrate dừng: %v", err)
+	}
+	if rep.Failed > 0 {
+		log.Printf("⚠️  %d file lỗi, chạy lại để thử tiếp", rep.Failed)
+		os.Exit(1)
+	}
+	log.Println("✅ Done")
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,232 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+type Repository struct {
+	DB *sql.DB
+
+	// user đã ghi activity hôm nay (tránh INSERT mỗi request)
+	mu      sync.Mutex
+	day     string
+	touched map[int64]struct{}
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db, touched: map[int64]struct{}{}}
+}
+
+// Point: 1 điểm trên biểu đồ theo ngày
+type Point struct {
+	Date  string `json:"date"` // YYYY-MM-DD
+	Value int64  `json:"value"`
+}
+
+// Overview: số liệu tổng cho dashboard admin
+type Overview struct {
+	DAU           int64   `json:"dau"`
+	WAU           int64   `json:"wau"`
+	MAU           int64   `json:"mau"`
+	Stickiness    float64 `json:"stickiness"` // DAU / MAU
+	TotalUsers    int64   `json:"total_users"`
+	NewUsersToday int64   `json:"new_users_today"`
+	MessagesToday int64   `json:"messages_today"`
+	ActiveRooms7d int64   `json:"active_rooms_7d"`
+	StorageBytes  int64   `json:"storage_bytes"`
+	GeneratedAt   string  `json:"generated_at"`
+}
+
+// Touch: đánh dấu user hoạt động hôm nay (login, refresh, mở WS, gửi tin)
+func (r *Repository) Touch(ctx context.Context, userID int64) {
+	if userID <= 0 {
+		return
+	}
+	today := time.Now().Format("2006-01-02")
+
+	r.mu.Lock()
+	if r.day != today {
+		r.day = today
+		r.touched = map[int64]struct{}{}
+	}
+	_, seen := r.touched[userID]
+	r.touched[userID] = struct{}{}
+	r.mu.Unlock()
+
+	if seen {
+		return
+	}
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT IGNORE INTO user_daily_activity (day, user_id) VALUES (?, ?)
+	`, today, userID)
+	if err != nil {
+		// ghi lỗi -> lần sau thử lại
+		r.mu.Lock()
+		delete(r.touched, userID)
+		r.mu.Unlock()
+	}
+}
+
+// Days: list ngày [from, to] dạng YYYY-MM-DD (để lấp ngày không có dữ liệu = 0)
+func Days(from, to time.Time) []string {
+	var out []string
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		out = append(out, d.Format("2006-01-02"))
+	}
+	return out
+}
+
+// series: query trả (day, value) -> đủ từng ngày trong khoảng
+func (r *Repository) series(ctx context.Context, days []string, q string, args ...any) ([]Point, error) {
+	rows, err := r.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byDay := map[string]int64{}
+	for rows.Next() {
+		var day string
+		var v int64
+		if err := rows.Scan(&day, &v); err != nil {
+			return nil, err
+		}
+		byDay[day] = v
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]Point, 0, len(days))
+	for _, d := range days {
+		out = append(out, Point{Date: d, Value: byDay[d]})
+	}
+	return out, nil
+}
+
+// DailyActiveUsers: số user hoạt động mỗi ngày
+func (r *Repository) DailyActiveUsers(ctx context.Context, from, to time.Time) ([]Point, error) {
+	return r.series(ctx, Days(from, to), `
+		SELECT DATE_FORMAT(day, '%Y-%m-%d'), COUNT(*)
+		FROM user_daily_activity
+		WHERE day BETWEEN ? AND ?
+		GROUP BY day
+	`, from.Format("2006-01-02"), to.Format("2006-01-02"))
+}
+
+// MessagesPerDay: tin nhắn thật (bỏ tin tạm + system)
+func (r *Repository) MessagesPerDay(ctx context.Context, from, to time.Time) ([]Point, error) {
+	return r.series(ctx, Days(from, to), `
+		SELECT DATE_FORMAT(created_at, '%Y-%m-%d') AS d, COUNT(*)
+		FROM messages
+		WHERE created_at >= ? AND created_at < ?
+		  AND is_temp = 0 AND message_type <> 'system'
+		GROUP BY d
+	`, from, to.AddDate(0, 0, 1))
+}
+
+func (r *Repository) NewUsersPerDay(ctx context.Context, from, to time.Time) ([]Point, error) {
+	return r.series(ctx, Days(from, to), `
+		SELECT DATE_FORMAT(created_at, '%Y-%m-%d') AS d, COUNT(*)
+		FROM users
+		WHERE created_at >= ? AND created_at < ?
+		GROUP BY d
+	`, from, to.AddDate(0, 0, 1))
+}
+
+// ActiveRoomsPerDay: số room có ít nhất 1 tin trong ngày
+func (r *Repository) ActiveRoomsPerDay(ctx context.Context, from, to time.Time) ([]Point, error) {
+	return r.series(ctx, Days(from, to), `
+		SELECT DATE_FORMAT(created_at, '%Y-%m-%d') AS d, COUNT(DISTINCT room_id)
+		FROM messages
+		WHERE created_at >= ? AND created_at < ?
+		  AND is_temp = 0 AND message_type <> 'system'
+		GROUP BY d
+	`, from, to.AddDate(0, 0, 1))
+}
+
+// StoragePerDay: byte upload mới mỗi ngày (file chat)
+func (r *Repository) StoragePerDay(ctx context.Context, from, to time.Time) ([]Point, error) {
+	return r.series(ctx, Days(from, to), `
+		SELECT DATE_FORMAT(created_at, '%Y-%m-%d') AS d, COALESCE(SUM(file_size), 0)
+		FROM chat_uploads
+		WHERE created_at >= ? AND created_at < ?
+		GROUP BY d
+	`, from, to.AddDate(0, 0, 1))
+}
+
+// StorageBefore: tổng byte đã upload trước thời điểm t (làm gốc cho đường cộng dồn)
+func (r *Repository) StorageBefore(ctx context.Context, t time.Time) (int64, error) {
+	var n int64
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(file_size), 0) FROM chat_uploads WHERE created_at < ?
+	`, t).Scan(&n)
+	return n, err
+}
+
+// UsersBefore: tổng user tạo trước thời điểm t (gốc cho đường cộng dồn)
+func (r *Repository) UsersBefore(ctx context.Context, t time.Time) (int64, error) {
+	var n int64
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM users WHERE created_at < ?
+	`, t).Scan(&n)
+	return n, err
+}
+
+func (r *Repository) activeUsersSince(ctx context.Context, from time.Time) (int64, error) {
+	var n int64
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT user_id) FROM user_daily_activity WHERE day >= ?
+	`, from.Format("2006-01-02")).Scan(&n)
+	return n, err
+}
+
+func (r *Repository) Overview(ctx context.Context, now time.Time) (*Overview, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	o := &Overview{GeneratedAt: now.Format(time.RFC3339)}
+
+	var err error
+	if o.DAU, err = r.activeUsersSince(ctx, today); err != nil {
+		return nil, err
+	}
+	if o.WAU, err = r.activeUsersSince(ctx, today.AddDate(0, 0, -6)); err != nil {
+		return nil, err
+	}
+	if o.MAU, err = r.activeUsersSince(ctx, today.AddDate(0, 0, -29)); err != nil {
+		return nil, err
+	}
+	if o.MAU > 0 {
+		o.Stickiness = float64(o.DAU) / float64(o.MAU)
+	}
+
+	err = r.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(created_at >= ?), 0) FROM users
+	`, today).Scan(&o.TotalUsers, &o.NewUsersToday)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM messages
+		WHERE created_at >= ? AND is_temp = 0 AND message_type <> 'system'
+	`, today).Scan(&o.MessagesToday)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.DB.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT room_id) FROM messages
+		WHERE created_at >= ? AND is_temp = 0 AND message_type <> 'system'
+	`, today.AddDate(0, 0, -6)).Scan(&o.ActiveRooms7d)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.StorageBytes, err = r.StorageBefore(ctx, now); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
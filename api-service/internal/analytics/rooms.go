@@ -0,0 +1,297 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// room insights: chỉ lưu số đếm theo giờ (không nội dung, không user id), room phải bật opt-in
+const (
+	// tin trả lời sau khoảng này không tính là "phản hồi"
+	MaxResponseGap = 4 * time.Hour
+	// lần rollup đầu (hoặc bị dừng lâu) chỉ lấy lại tối đa bấy nhiêu
+	MaxBackfill = 30 * 24 * time.Hour
+)
+
+// InsightsSettings: trạng thái opt-in của 1 room
+type InsightsSettings struct {
+	RoomID        int64      `json:"room_id"`
+	Enabled       bool       `json:"enabled"`
+	EnabledBy     int64      `json:"enabled_by,omitempty"`
+	EnabledAt     *time.Time `json:"enabled_at,omitempty"`
+	RolledUpUntil *time.Time `json:"rolled_up_until,omitempty"`
+}
+
+func (r *Repository) GetInsightsSettings(ctx context.Context, roomID int64) (*InsightsSettings, error) {
+	st := InsightsSettings{RoomID: roomID}
+	var enabledAt, until sql.NullTime
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT enabled, enabled_by, enabled_at, rolled_up_until
+		FROM room_insights_settings WHERE room_id = ?
+	`, roomID).Scan(&st.Enabled, &st.EnabledBy, &enabledAt, &until)
+	if err == sql.ErrNoRows {
+		return &st, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if enabledAt.Valid {
+		st.EnabledAt = &enabledAt.Time
+	}
+	if until.Valid {
+		st.RolledUpUntil = &until.Time
+	}
+	return &st, nil
+}
+
+// SetInsightsEnabled: tắt -> xoá luôn số liệu đã gom (opt-out = không giữ dữ liệu)
+func (r *Repository) SetInsightsEnabled(ctx context.Context, roomID, userID int64, on bool) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if on {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO room_insights_settings (room_id, enabled, enabled_by, enabled_at)
+			VALUES (?, 1, ?, ?)
+			ON DUPLICATE KEY UPDATE
+				enabled_by = IF(enabled = 1, enabled_by, VALUES(enabled_by)),
+				enabled_at = IF(enabled = 1, enabled_at, VALUES(enabled_at)),
+				enabled = 1
+		`, roomID, userID, time.Now())
+		if err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE room_insights_settings
+		SET enabled = 0, rolled_up_until = NULL
+		WHERE room_id = ?
+	`, roomID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM room_activity_hourly WHERE room_id = ?`, roomID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// hourBucket: số liệu 1 giờ của 1 room
+type hourBucket struct {
+	messages      int64
+	responses     int64
+	responseMsSum int64
+}
+
+// RollupRooms: gom tin của các room bật insights từ mốc lần trước tới giờ tròn gần nhất.
+// trả số room đã xử lý
+func (r *Repository) RollupRooms(ctx context.Context, now time.Time) (int, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT s.room_id, s.rolled_up_until
+		FROM room_insights_settings s
+		JOIN rooms rm ON rm.id = s.room_id
+		WHERE s.enabled = 1 AND rm.is_active = 1
+		ORDER BY s.room_id ASC
+	`)
+	if err != nil {
+		return 0, err
+	}
+	type target struct {
+		roomID int64
+		from   time.Time
+	}
+	to := now.Truncate(time.Hour)
+	var targets []target
+	for rows.Next() {
+		var roomID int64
+		var until sql.NullTime
+		if err := rows.Scan(&roomID, &until); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		// lần đầu: lấy lại tối đa MaxBackfill để có biểu đồ ngay
+		from := to.Add(-MaxBackfill)
+		if until.Valid && until.Time.After(from) {
+			from = until.Time
+		}
+		if from.Before(to) {
+			targets = append(targets, target{roomID: roomID, from: from})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	done := 0
+	for _, t := range targets {
+		if err := ctx.Err(); err != nil {
+			return done, err
+		}
+		if err := r.rollupRoom(ctx, t.roomID, t.from, to); err != nil {
+			return done, err
+		}
+		done++
+	}
+	return done, nil
+}
+
+// rollupRoom: chỉ đọc (sender_id, created_at), thời gian phản hồi = tin đầu tiên của người khác
+// sau tin trước đó (trong MaxResponseGap)
+func (r *Repository) rollupRoom(ctx context.Context, roomID int64, from, to time.Time) error {
+	var prevSender int64
+	var prevAt time.Time
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT sender_id, created_at FROM messages
+		WHERE room_id = ? AND created_at < ? AND is_temp = 0 AND message_type <> 'system'
+		ORDER BY created_at DESC, id DESC LIMIT 1
+	`, roomID, from).Scan(&prevSender, &prevAt)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT sender_id, created_at FROM messages
+		WHERE room_id = ? AND created_at >= ? AND created_at < ?
+		  AND is_temp = 0 AND message_type <> 'system'
+		ORDER BY created_at ASC, id ASC
+	`, roomID, from, to)
+	if err != nil {
+		return err
+	}
+	buckets := map[time.Time]*hourBucket{}
+	for rows.Next() {
+		var sender int64
+		var at time.Time
+		if err := rows.Scan(&sender, &at); err != nil {
+			rows.Close()
+			return err
+		}
+		h := at.Truncate(time.Hour)
+		b := buckets[h]
+		if b == nil {
+			b = &hourBucket{}
+			buckets[h] = b
+		}
+		b.messages++
+		if prevSender != 0 && sender != prevSender {
+			if gap := at.Sub(prevAt); gap >= 0 && gap <= MaxResponseGap {
+				b.responses++
+				b.responseMsSum += gap.Milliseconds()
+			}
+		}
+		prevSender, prevAt = sender, at
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// khoảng [from, to) được tính lại toàn bộ nên ghi đè (chạy lại không bị cộng trùng)
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM room_activity_hourly WHERE room_id = ? AND bucket_hour >= ? AND bucket_hour < ?
+	`, roomID, from, to); err != nil {
+		return err
+	}
+	for h, b := range buckets {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO room_activity_hourly (room_id, bucket_hour, messages, responses, response_ms_sum)
+			VALUES (?, ?, ?, ?, ?)
+		`, roomID, h, b.messages, b.responses, b.responseMsSum); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE room_insights_settings SET rolled_up_until = ? WHERE room_id = ? AND enabled = 1
+	`, to, roomID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RoomInsights: số liệu cho owner xem
+type RoomInsights struct {
+	RoomID              int64     `json:"room_id"`
+	From                string    `json:"from"`
+	To                  string    `json:"to"`
+	TotalMessages       int64     `json:"total_messages"`
+	AvgResponseSeconds  float64   `json:"avg_response_seconds"`
+	MessagesPerDay      []Point   `json:"messages_per_day"`
+	ResponseSecondsDay  []Point   `json:"avg_response_seconds_per_day"`
+	MessagesByHourOfDay [24]int64 `json:"messages_by_hour_of_day"`
+	BusiestHour         int       `json:"busiest_hour"` // -1 = chưa có dữ liệu
+}
+
+func (r *Repository) RoomInsights(ctx context.Context, roomID int64, from, to time.Time) (*RoomInsights, error) {
+	days := Days(from, to)
+	out := &RoomInsights{
+		RoomID:      roomID,
+		From:        from.Format("2006-01-02"),
+		To:          to.Format("2006-01-02"),
+		BusiestHour: -1,
+	}
+
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT bucket_hour, messages, responses, response_ms_sum
+		FROM room_activity_hourly
+		WHERE room_id = ? AND bucket_hour >= ? AND bucket_hour < ?
+	`, roomID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	msgByDay := map[string]int64{}
+	respByDay := map[string][2]int64{} // count, ms
+	var respCount, respMs int64
+	for rows.Next() {
+		var h time.Time
+		var msgs, resps, ms int64
+		if err := rows.Scan(&h, &msgs, &resps, &ms); err != nil {
+			return nil, err
+		}
+		d := h.Format("2006-01-02")
+		msgByDay[d] += msgs
+		rd := respByDay[d]
+		respByDay[d] = [2]int64{rd[0] + resps, rd[1] + ms}
+		out.MessagesByHourOfDay[h.Hour()] += msgs
+		out.TotalMessages += msgs
+		respCount += resps
+		respMs += ms
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out.MessagesPerDay = make([]Point, 0, len(days))
+	out.ResponseSecondsDay = make([]Point, 0, len(days))
+	for _, d := range days {
+		out.MessagesPerDay = append(out.MessagesPerDay, Point{Date: d, Value: msgByDay[d]})
+		var avg int64
+		if rd := respByDay[d]; rd[0] > 0 {
+			avg = rd[1] / rd[0] / 1000
+		}
+		out.ResponseSecondsDay = append(out.ResponseSecondsDay, Point{Date: d, Value: avg})
+	}
+	if respCount > 0 {
+		out.AvgResponseSeconds = float64(respMs) / float64(respCount) / 1000
+	}
+	var best int64
+	for h, n := range out.MessagesByHourOfDay {
+		if n > best {
+			best, out.BusiestHour = n, h
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,335 @@
+package announcement
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// mức hiển thị (client chọn màu banner)
+const (
+	LevelInfo     = "info"
+	LevelWarning  = "warning"
+	LevelCritical = "critical"
+)
+
+// gửi cho ai
+const (
+	AudienceAll   = "all"
+	AudienceUsers = "users" // chỉ user trong announcement_targets
+)
+
+var ErrNotFound = errors.New("announcement: not found")
+
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+type Announcement struct {
+	ID         int64      `json:"id"`
+	Title      string     `json:"title"`
+	Body       string     `json:"body"`
+	Level      string     `json:"level"`
+	Audience   string     `json:"audience"`
+	RequireAck bool       `json:"require_ack"` // banner chỉ tắt khi user bấm xác nhận
+	CreatedBy  int64      `json:"created_by"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // nil = tới khi thu hồi
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	// phía user
+	AckedAt *time.Time `json:"acked_at,omitempty"`
+
+	// phía admin
+	TargetCount int64 `json:"target_count,omitempty"`
+	AckCount    int64 `json:"ack_count,omitempty"`
+}
+
+// Ack: 1 user đã xác nhận
+type Ack struct {
+	UserID   int64     `json:"user_id"`
+	Username string    `json:"username"`
+	FullName string    `json:"full_name,omitempty"`
+	AckedAt  time.Time `json:"acked_at"`
+}
+
+func IsValidLevel(v string) bool {
+	return v == LevelInfo || v == LevelWarning || v == LevelCritical
+}
+
+const selectAnnouncement = `
+	SELECT a.id, a.title, a.body, a.level, a.audience, a.require_ack, a.created_by,
+	       a.expires_at, a.revoked_at, a.created_at
+	FROM announcements a
+`
+
+func scanAnnouncement(sc interface{ Scan(...any) error }, extra ...any) (*Announcement, error) {
+	var a Announcement
+	var expires, revoked sql.NullTime
+	dest := []any{&a.ID, &a.Title, &a.Body, &a.Level, &a.Audience, &a.RequireAck, &a.CreatedBy,
+		&expires, &revoked, &a.CreatedAt}
+	if err := sc.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if expires.Valid {
+		a.ExpiresAt = &expires.Time
+	}
+	if revoked.Valid {
+		a.RevokedAt = &revoked.Time
+	}
+	return &a, nil
+}
+
+// Create: userIDs rỗng = gửi toàn hệ thống
+func (r *Repository) Create(ctx context.Context, a *Announcement, userIDs []int64) error {
+	a.Audience = AudienceAll
+	if len(userIDs) > 0 {
+		a.Audience = AudienceUsers
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var expires any
+	if a.ExpiresAt != nil {
+		expires = *a.ExpiresAt
+	}
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO announcements (title, body, level, audience, require_ack, created_by, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, a.Title, a.Body, a.Level, a.Audience, a.RequireAck, a.CreatedBy, expires)
+	if err != nil {
+		return err
+	}
+	a.ID, _ = res.LastInsertId()
+
+	if len(userIDs) > 0 {
+		ph := make([]string, 0, len(userIDs))
+		args := make([]any, 0, len(userIDs)*2)
+		for _, uid := range userIDs {
+			ph = append(ph, "(?, ?)")
+			args = append(args, a.ID, uid)
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT IGNORE INTO announcement_targets (announcement_id, user_id)
+			VALUES `+strings.Join(ph, ", "), args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	a.CreatedAt = time.Now()
+	return nil
+}
+
+// Get: kèm số người nhận + số đã xác nhận (admin)
+func (r *Repository) Get(ctx context.Context, id int64) (*Announcement, error) {
+	row := r.DB.QueryRowContext(ctx, selectAnnouncement+` WHERE a.id = ?`, id)
+	a, err := scanAnnouncement(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.fillCounts(ctx, a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (r *Repository) fillCounts(ctx context.Context, a *Announcement) error {
+	q := `SELECT COUNT(*) FROM users WHERE is_active = 1`
+	args := []any{}
+	if a.Audience == AudienceUsers {
+		q = `SELECT COUNT(*) FROM announcement_targets WHERE announcement_id = ?`
+		args = append(args, a.ID)
+	}
+	if err := r.DB.QueryRowContext(ctx, q, args...).Scan(&a.TargetCount); err != nil {
+		return err
+	}
+	return r.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM announcement_acks WHERE announcement_id = ?
+	`, a.ID).Scan(&a.AckCount)
+}
+
+// List (admin): mới nhất trước; activeOnly = bỏ cái đã hết hạn / thu hồi
+func (r *Repository) List(ctx context.Context, activeOnly bool, beforeID int64, limit int) ([]*Announcement, error) {
+	q := selectAnnouncement + ` WHERE 1 = 1`
+	args := []any{}
+	if activeOnly {
+		q += ` AND a.revoked_at IS NULL AND (a.expires_at IS NULL OR a.expires_at > ?)`
+		args = append(args, time.Now())
+	}
+	if beforeID > 0 {
+		q += ` AND a.id < ?`
+		args = append(args, beforeID)
+	}
+	q += ` ORDER BY a.id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*Announcement{}
+	for rows.Next() {
+		a, err := scanAnnouncement(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, a := range out {
+		if err := r.fillCounts(ctx, a); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// ListForUser: thông báo còn hiệu lực gửi tới user (chưa xác nhận lên trước)
+func (r *Repository) ListForUser(ctx context.Context, userID int64, now time.Time) ([]*Announcement, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT a.id, a.title, a.body, a.level, a.audience, a.require_ack, a.created_by,
+		       a.expires_at, a.revoked_at, a.created_at, k.acked_at
+		FROM announcements a
+		LEFT JOIN announcement_acks k ON k.announcement_id = a.id AND k.user_id = ?
+		WHERE a.revoked_at IS NULL
+		  AND (a.expires_at IS NULL OR a.expires_at > ?)
+		  AND (a.audience = 'all' OR EXISTS (
+		        SELECT 1 FROM announcement_targets t
+		        WHERE t.announcement_id = a.id AND t.user_id = ?))
+		ORDER BY k.acked_at IS NOT NULL, a.id DESC
+		LIMIT 100
+	`, userID, now, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*Announcement{}
+	for rows.Next() {
+		var acked sql.NullTime
+		a, err := scanAnnouncement(rows, &acked)
+		if err != nil {
+			return nil, err
+		}
+		if acked.Valid {
+			a.AckedAt = &acked.Time
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// IsTarget: user có nằm trong danh sách nhận không
+func (r *Repository) IsTarget(ctx context.Context, a *Announcement, userID int64) (bool, error) {
+	if a.Audience == AudienceAll {
+		return true, nil
+	}
+	var n int
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM announcement_targets WHERE announcement_id = ? AND user_id = ?
+	`, a.ID, userID).Scan(&n)
+	return n > 0, err
+}
+
+// TargetUserIDs: danh sách nhận (audience = users)
+func (r *Repository) TargetUserIDs(ctx context.Context, id int64) ([]int64, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT user_id FROM announcement_targets WHERE announcement_id = ?
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []int64
+	for rows.Next() {
+		var uid int64
+		if err := rows.Scan(&uid); err != nil {
+			return nil, err
+		}
+		out = append(out, uid)
+	}
+	return out, rows.Err()
+}
+
+// Ack: xác nhận nhiều lần vẫn giữ thời điểm đầu tiên
+func (r *Repository) Ack(ctx context.Context, id, userID int64) (time.Time, error) {
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT IGNORE INTO announcement_acks (announcement_id, user_id) VALUES (?, ?)
+	`, id, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var at time.Time
+	err = r.DB.QueryRowContext(ctx, `
+		SELECT acked_at FROM announcement_acks WHERE announcement_id = ? AND user_id = ?
+	`, id, userID).Scan(&at)
+	return at, err
+}
+
+func (r *Repository) ListAcks(ctx context.Context, id int64, beforeUserID int64, limit int) ([]Ack, error) {
+	q := `
+		SELECT k.user_id, u.username, COALESCE(u.full_name, ''), k.acked_at
+		FROM announcement_acks k
+		JOIN users u ON u.id = k.user_id
+		WHERE k.announcement_id = ?`
+	args := []any{id}
+	if beforeUserID > 0 {
+		q += ` AND k.user_id < ?`
+		args = append(args, beforeUserID)
+	}
+	q += ` ORDER BY k.user_id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Ack{}
+	for rows.Next() {
+		var k Ack
+		if err := rows.Scan(&k.UserID, &k.Username, &k.FullName, &k.AckedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+// Revoke: gỡ banner ngay (giữ lại lịch sử + ack)
+func (r *Repository) Revoke(ctx context.Context, id int64) error {
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE announcements SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND revoked_at IS NULL
+	`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
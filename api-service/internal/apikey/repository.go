@@ -0,0 +1,207 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+// scope của API key (mỗi scope mở 1 nhóm route, xem httpserver/apikey.go)
+const (
+	ScopeSendMessage = "send_message" // gửi tin vào room user của key là thành viên
+	ScopeReadRooms   = "read_rooms"   // xem room, thành viên, lịch sử tin
+)
+
+var AllScopes = []string{ScopeSendMessage, ScopeReadRooms}
+
+// key dạng ck_<43 ký tự>, lưu sha256 + vài ký tự đầu để admin nhận ra key trong danh sách
+const (
+	keyPrefix     = "ck_"
+	displayPrefix = 10
+)
+
+var (
+	ErrNotFound     = errors.New("apikey: not found")
+	ErrInvalidScope = errors.New("apikey: scopes must be send_message and/or read_rooms")
+)
+
+type Key struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`  // "ck_AbC1234" (không đủ để dùng)
+	UserID     int64      `json:"user_id"` // tin gửi bằng key hiện dưới tên user này
+	Username   string     `json:"username"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  int64      `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // nil = không hết hạn
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (k *Key) HasScope(scope string) bool {
+	for _, v := range k.Scopes {
+		if v == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func IsValidScope(scope string) bool {
+	for _, v := range AllScopes {
+		if v == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+const selectKey = `
+	SELECT k.id, k.name, k.key_prefix, k.user_id, u.username, k.scopes, COALESCE(k.created_by, 0),
+	       k.created_at, k.expires_at, k.last_used_at, k.revoked_at
+	FROM api_keys k
+	JOIN users u ON u.id = k.user_id
+`
+
+func scanKey(sc interface{ Scan(...any) error }) (*Key, error) {
+	var k Key
+	var scopes string
+	var exp, used, revoked sql.NullTime
+	if err := sc.Scan(&k.ID, &k.Name, &k.Prefix, &k.UserID, &k.Username, &scopes, &k.CreatedBy,
+		&k.CreatedAt, &exp, &used, &revoked); err != nil {
+		return nil, err
+	}
+	k.Scopes = []string{}
+	for _, s := range strings.Split(scopes, ",") {
+		if s != "" {
+			k.Scopes = append(k.Scopes, s)
+		}
+	}
+	if exp.Valid {
+		k.ExpiresAt = &exp.Time
+	}
+	if used.Valid {
+		k.LastUsedAt = &used.Time
+	}
+	if revoked.Valid {
+		k.RevokedAt = &revoked.Time
+	}
+	return &k, nil
+}
+
+// Create: trả key dạng plaintext đúng 1 lần (DB chỉ giữ hash)
+func (r *Repository) Create(ctx context.Context, k *Key) (string, error) {
+	if len(k.Scopes) == 0 {
+		return "", ErrInvalidScope
+	}
+	for _, s := range k.Scopes {
+		if !IsValidScope(s) {
+			return "", ErrInvalidScope
+		}
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	plain := keyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	k.Prefix = plain[:displayPrefix]
+
+	var createdBy any
+	if k.CreatedBy > 0 {
+		createdBy = k.CreatedBy
+	}
+	res, err := r.DB.ExecContext(ctx, `
+		INSERT INTO api_keys (name, key_prefix, key_hash, user_id, scopes, created_by, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, k.Name, k.Prefix, hashKey(plain), k.UserID, strings.Join(k.Scopes, ","), createdBy, k.ExpiresAt)
+	if err != nil {
+		return "", err
+	}
+	k.ID, _ = res.LastInsertId()
+	k.CreatedAt = time.Now()
+	return plain, nil
+}
+
+func (r *Repository) List(ctx context.Context) ([]*Key, error) {
+	rows, err := r.DB.QueryContext(ctx, selectKey+` ORDER BY k.id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*Key{}
+	for rows.Next() {
+		k, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+func (r *Repository) Get(ctx context.Context, id int64) (*Key, error) {
+	k, err := scanKey(r.DB.QueryRowContext(ctx, selectKey+` WHERE k.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return k, err
+}
+
+// Authenticate: key còn hiệu lực (chưa thu hồi, chưa hết hạn, user còn active), ErrNotFound nếu không
+func (r *Repository) Authenticate(ctx context.Context, plain string, now time.Time) (*Key, error) {
+	if !strings.HasPrefix(plain, keyPrefix) {
+		return nil, ErrNotFound
+	}
+	k, err := scanKey(r.DB.QueryRowContext(ctx, selectKey+`
+		WHERE k.key_hash = ? AND k.revoked_at IS NULL
+		  AND (k.expires_at IS NULL OR k.expires_at > ?) AND u.is_active = 1
+	`, hashKey(plain), now))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// last_used_at: ghi tối đa 1 lần / phút, không chặn request nếu lỗi
+	if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= time.Minute {
+		_, _ = r.DB.ExecContext(ctx, `UPDATE api_keys SET last_used_at = ? WHERE id = ?`, now, k.ID)
+	}
+	return k, nil
+}
+
+// Revoke: thu hồi (giữ dòng để tra audit), ErrNotFound nếu không có / đã thu hồi
+func (r *Repository) Revoke(ctx context.Context, id int64, now time.Time) error {
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL
+	`, now, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
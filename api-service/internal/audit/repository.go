@@ -0,0 +1,160 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// hành động được ghi (bảng chỉ INSERT, trigger chặn UPDATE / DELETE)
+const (
+	ActionUserSuspend       = "user.suspend"
+	ActionUserUnsuspend     = "user.unsuspend"
+	ActionUserRoleChange    = "user.role_change"
+	ActionUserImpersonate   = "user.impersonate"
+	ActionConfigChange      = "config.change"
+	ActionTokenRevoke       = "token.revoke"
+	ActionRoomDelete        = "room.delete"
+	ActionRoomOwnerTransfer = "room.owner_transfer"
+	ActionDataExport        = "user.data_export" // admin export dữ liệu của user khác
+	ActionTwoFactorChange   = "user.2fa_change"
+	ActionIPBlocked         = "security.ip_blocked" // request bị chặn bởi ip_rules
+	ActionLoginLocked       = "security.login_locked"
+	ActionOAuthLink         = "user.oauth_link"
+	ActionAPIKeyCreate      = "api_key.create"
+	ActionBotCreate         = "bot.create"
+	ActionBotDelete         = "bot.delete"
+	ActionEmojiCreate       = "emoji.create"
+	ActionEmojiUpdate       = "emoji.update"
+	ActionEmojiDelete       = "emoji.delete"
+	ActionMessageDelete     = "message.delete"
+	ActionRoomPurge         = "room.purge"
+)
+
+// loại đối tượng bị tác động
+const (
+	TargetUser    = "user"
+	TargetRoom    = "room"
+	TargetBot     = "bot"
+	TargetConfig  = "config"
+	TargetIPRule  = "ip_rule"
+	TargetAPIKey  = "api_key"
+	TargetEmoji   = "emoji" // target_id = 0, code nằm trong details
+	TargetMessage = "message"
+)
+
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+type Event struct {
+	ID         int64          `json:"id"`
+	ActorID    int64          `json:"actor_id"` // 0 = hệ thống
+	ActorName  string         `json:"actor_name,omitempty"`
+	Action     string         `json:"action"`
+	TargetType string         `json:"target_type"`
+	TargetID   int64          `json:"target_id,omitempty"`
+	Details    map[string]any `json:"details,omitempty"`
+	IP         string         `json:"ip,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// Filter: điều kiện GET /admin/audit (zero value = bỏ qua)
+type Filter struct {
+	ActorID    int64
+	Action     string
+	TargetType string
+	TargetID   int64
+	From       time.Time
+	To         time.Time
+	BeforeID   int64
+	Limit      int
+}
+
+func (r *Repository) Record(ctx context.Context, e *Event) error {
+	var details any
+	if len(e.Details) > 0 {
+		b, err := json.Marshal(e.Details)
+		if err != nil {
+			return err
+		}
+		details = string(b)
+	}
+
+	res, err := r.DB.ExecContext(ctx, `
+		INSERT INTO audit_log (actor_id, action, target_type, target_id, details, ip)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, e.ActorID, e.Action, e.TargetType, e.TargetID, details, e.IP)
+	if err != nil {
+		return err
+	}
+	e.ID, _ = res.LastInsertId()
+	e.CreatedAt = time.Now()
+	return nil
+}
+
+func (r *Repository) List(ctx context.Context, f Filter) ([]*Event, error) {
+	q := `
+		SELECT a.id, a.actor_id, COALESCE(u.username, ''), a.action, a.target_type, a.target_id,
+		       COALESCE(a.details, ''), COALESCE(a.ip, ''), a.created_at
+		FROM audit_log a
+		LEFT JOIN users u ON u.id = a.actor_id
+		WHERE 1 = 1`
+	args := []any{}
+	if f.ActorID > 0 {
+		q += ` AND a.actor_id = ?`
+		args = append(args, f.ActorID)
+	}
+	if f.Action != "" {
+		q += ` AND a.action = ?`
+		args = append(args, f.Action)
+	}
+	if f.TargetType != "" {
+		q += ` AND a.target_type = ?`
+		args = append(args, f.TargetType)
+	}
+	if f.TargetID > 0 {
+		q += ` AND a.target_id = ?`
+		args = append(args, f.TargetID)
+	}
+	if !f.From.IsZero() {
+		q += ` AND a.created_at >= ?`
+		args = append(args, f.From)
+	}
+	if !f.To.IsZero() {
+		q += ` AND a.created_at < ?`
+		args = append(args, f.To)
+	}
+	if f.BeforeID > 0 {
+		q += ` AND a.id < ?`
+		args = append(args, f.BeforeID)
+	}
+	q += ` ORDER BY a.id DESC LIMIT ?`
+	args = append(args, f.Limit)
+
+	rows, err := r.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*Event{}
+	for rows.Next() {
+		var e Event
+		var details string
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.ActorName, &e.Action, &e.TargetType, &e.TargetID,
+			&details, &e.IP, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if details != "" {
+			_ = json.Unmarshal([]byte(details), &e.Details)
+		}
+		out = append(out, &e)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,324 @@
+package automation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// cách khớp nội dung tin
+const (
+	MatchContains = "contains" // chứa chuỗi (không phân biệt hoa thường)
+	MatchWord     = "word"     // nguyên từ
+	MatchRegex    = "regex"    // RE2, không backtracking nên không lo regex "độc"
+)
+
+// hành động khi khớp
+const (
+	ActionReact   = "react"   // bot thả reaction vào tin
+	ActionWebhook = "webhook" // bắn event automation_triggered ra webhook của room
+	ActionReply   = "reply"   // bot trả lời trong room
+	ActionFlag    = "flag"    // đẩy tin vào hàng đợi moderation
+)
+
+const (
+	MaxRulesPerRoom = 50
+	maxPatternLen   = 200
+	maxReplyLen     = 1000
+	maxReactionLen  = 32
+	maxNameLen      = 64
+)
+
+// cache rule đã compile theo room (giống word filter)
+const cacheTTL = 30 * time.Second
+
+var (
+	ErrNotFound     = errors.New("automation: rule not found")
+	ErrTooManyRules = errors.New("automation: too many rules in this room")
+	ErrInvalidMatch = errors.New("automation: match_type must be contains, word or regex")
+	ErrInvalidRule  = errors.New("automation: pattern is required (max 200 chars)")
+	ErrInvalidRegex = errors.New("automation: invalid regex pattern")
+	ErrInvalidParam = errors.New("automation: action must be react (reaction), webhook, reply (reply_text) or flag")
+)
+
+type Rule struct {
+	ID              int64     `json:"id"`
+	RoomID          int64     `json:"room_id"`
+	Name            string    `json:"name"`
+	MatchType       string    `json:"match_type"`
+	Pattern         string    `json:"pattern"`
+	Action          string    `json:"action"`
+	Reaction        string    `json:"reaction,omitempty"`   // action = react
+	ReplyText       string    `json:"reply_text,omitempty"` // action = reply
+	CooldownSeconds int       `json:"cooldown_seconds"`     // 0 = không giới hạn
+	Enabled         bool      `json:"enabled"`
+	CreatedBy       int64     `json:"created_by"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+
+	re *regexp.Regexp
+}
+
+type cached struct {
+	rules []*Rule
+	at    time.Time
+}
+
+type Repository struct {
+	DB *sql.DB
+
+	mu    sync.Mutex
+	cache map[int64]*cached
+	fired map[int64]time.Time // rule id -> lần chạy gần nhất (cooldown, chỉ trong 1 instance)
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db, cache: map[int64]*cached{}, fired: map[int64]time.Time{}}
+}
+
+// Validate: chuẩn hoá + compile, dùng chung cho create / update / test
+func (ru *Rule) Validate() error {
+	ru.Name = strings.TrimSpace(ru.Name)
+	if utf8.RuneCountInString(ru.Name) > maxNameLen {
+		return errors.New("automation: name is too long (max 64 chars)")
+	}
+	ru.Pattern = strings.TrimSpace(ru.Pattern)
+	if ru.Pattern == "" || utf8.RuneCountInString(ru.Pattern) > maxPatternLen {
+		return ErrInvalidRule
+	}
+	if ru.MatchType == "" {
+		ru.MatchType = MatchContains
+	}
+	re, err := compile(ru.MatchType, ru.Pattern)
+	if err != nil {
+		return err
+	}
+	ru.re = re
+
+	ru.Reaction = strings.TrimSpace(ru.Reaction)
+	ru.ReplyText = strings.TrimSpace(ru.ReplyText)
+	switch ru.Action {
+	case ActionReact:
+		if ru.Reaction == "" || utf8.RuneCountInString(ru.Reaction) > maxReactionLen {
+			return ErrInvalidParam
+		}
+		ru.ReplyText = ""
+	case ActionReply:
+		if ru.ReplyText == "" || utf8.RuneCountInString(ru.ReplyText) > maxReplyLen {
+			return ErrInvalidParam
+		}
+		ru.Reaction = ""
+	case ActionWebhook, ActionFlag:
+		ru.Reaction, ru.ReplyText = "", ""
+	default:
+		return ErrInvalidParam
+	}
+	if ru.CooldownSeconds < 0 || ru.CooldownSeconds > 86400 {
+		return errors.New("automation: cooldown_seconds must be 0-86400")
+	}
+	return nil
+}
+
+func compile(matchType, pattern string) (*regexp.Regexp, error) {
+	switch matchType {
+	case MatchContains:
+		return regexp.MustCompile(`(?i)` + regexp.QuoteMeta(pattern)), nil
+	case MatchWord:
+		return regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}_])` + regexp.QuoteMeta(pattern) + `(?:$|[^\p{L}\p{N}_])`), nil
+	case MatchRegex:
+		re, err := regexp.Compile(`(?i)` + pattern)
+		if err != nil {
+			return nil, ErrInvalidRegex
+		}
+		return re, nil
+	}
+	return nil, ErrInvalidMatch
+}
+
+// Matches: rule có khớp nội dung không (rule phải qua Validate / load)
+func (ru *Rule) Matches(content string) bool {
+	return ru.re != nil && ru.re.MatchString(content)
+}
+
+// ===== CRUD =====
+
+const selectRule = `
+	SELECT id, room_id, name, match_type, pattern, action,
+	       COALESCE(reaction, ''), COALESCE(reply_text, ''),
+	       cooldown_seconds, enabled, created_by, created_at, updated_at
+	FROM automation_rules
+`
+
+func scanRule(sc interface{ Scan(...any) error }) (*Rule, error) {
+	var ru Rule
+	err := sc.Scan(&ru.ID, &ru.RoomID, &ru.Name, &ru.MatchType, &ru.Pattern, &ru.Action,
+		&ru.Reaction, &ru.ReplyText, &ru.CooldownSeconds, &ru.Enabled, &ru.CreatedBy, &ru.CreatedAt, &ru.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &ru, nil
+}
+
+func (r *Repository) List(ctx context.Context, roomID int64) ([]*Rule, error) {
+	rows, err := r.DB.QueryContext(ctx, selectRule+` WHERE room_id = ? ORDER BY id ASC`, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*Rule{}
+	for rows.Next() {
+		ru, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ru)
+	}
+	return out, rows.Err()
+}
+
+func (r *Repository) Get(ctx context.Context, roomID, id int64) (*Rule, error) {
+	ru, err := scanRule(r.DB.QueryRowContext(ctx, selectRule+` WHERE id = ? AND room_id = ?`, id, roomID))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return ru, err
+}
+
+func (r *Repository) Create(ctx context.Context, ru *Rule) error {
+	if err := ru.Validate(); err != nil {
+		return err
+	}
+	var n int
+	if err := r.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM automation_rules WHERE room_id = ?`, ru.RoomID).Scan(&n); err != nil {
+		return err
+	}
+	if n >= MaxRulesPerRoom {
+		return ErrTooManyRules
+	}
+
+	res, err := r.DB.ExecContext(ctx, `
+		INSERT INTO automation_rules
+			(room_id, name, match_type, pattern, action, reaction, reply_text, cooldown_seconds, enabled, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, ru.RoomID, ru.Name, ru.MatchType, ru.Pattern, ru.Action,
+		nullIfEmpty(ru.Reaction), nullIfEmpty(ru.ReplyText), ru.CooldownSeconds, ru.Enabled, ru.CreatedBy)
+	if err != nil {
+		return err
+	}
+	ru.ID, _ = res.LastInsertId()
+	ru.CreatedAt = time.Now()
+	ru.UpdatedAt = ru.CreatedAt
+	r.invalidate(ru.RoomID)
+	return nil
+}
+
+func (r *Repository) Update(ctx context.Context, ru *Rule) error {
+	if err := ru.Validate(); err != nil {
+		return err
+	}
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE automation_rules
+		SET name = ?, match_type = ?, pattern = ?, action = ?, reaction = ?, reply_text = ?,
+		    cooldown_seconds = ?, enabled = ?
+		WHERE id = ? AND room_id = ?
+	`, ru.Name, ru.MatchType, ru.Pattern, ru.Action, nullIfEmpty(ru.Reaction), nullIfEmpty(ru.ReplyText),
+		ru.CooldownSeconds, ru.Enabled, ru.ID, ru.RoomID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// MySQL: không đổi gì cũng ra 0 -> check lại tồn tại
+		if _, err := r.Get(ctx, ru.RoomID, ru.ID); err != nil {
+			return err
+		}
+	}
+	ru.UpdatedAt = time.Now()
+	r.invalidate(ru.RoomID)
+	return nil
+}
+
+func (r *Repository) Delete(ctx context.Context, roomID, id int64) error {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM automation_rules WHERE id = ? AND room_id = ?`, id, roomID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	r.invalidate(roomID)
+	return nil
+}
+
+func (r *Repository) invalidate(roomID int64) {
+	r.mu.Lock()
+	delete(r.cache, roomID)
+	r.mu.Unlock()
+}
+
+// ===== Đánh giá =====
+
+func (r *Repository) load(ctx context.Context, roomID int64) ([]*Rule, error) {
+	r.mu.Lock()
+	c := r.cache[roomID]
+	r.mu.Unlock()
+	if c != nil && time.Since(c.at) < cacheTTL {
+		return c.rules, nil
+	}
+
+	list, err := r.List(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	c = &cached{at: time.Now()}
+	for _, ru := range list {
+		if !ru.Enabled {
+			continue
+		}
+		re, err := compile(ru.MatchType, ru.Pattern)
+		if err != nil {
+			continue // rule hỏng trong DB -> bỏ qua, không chặn chat
+		}
+		ru.re = re
+		c.rules = append(c.rules, ru)
+	}
+
+	r.mu.Lock()
+	r.cache[roomID] = c
+	r.mu.Unlock()
+	return c.rules, nil
+}
+
+// Evaluate: các rule đang bật khớp tin (đã trừ rule còn trong cooldown, đánh dấu lần chạy)
+func (r *Repository) Evaluate(ctx context.Context, roomID int64, content string, now time.Time) ([]*Rule, error) {
+	rules, err := r.load(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*Rule
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, ru := range rules {
+		if !ru.Matches(content) {
+			continue
+		}
+		if ru.CooldownSeconds > 0 {
+			if last, ok := r.fired[ru.ID]; ok && now.Sub(last) < time.Duration(ru.CooldownSeconds)*time.Second {
+				continue
+			}
+		}
+		r.fired[ru.ID] = now
+		out = append(out, ru)
+	}
+	return out, nil
+}
+
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,399 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Archive = tar.gz (mã hoá nếu có passphrase):
+//
+//	database.sql           DumpSQL
+//	<dir.Name>/<file>      file trong từng thư mục upload
+//	manifest.json          ghi cuối: sha256 + size của mọi entry phía trên
+const (
+	ManifestVersion = 1
+
+	dbEntry       = "database.sql"
+	manifestEntry = "manifest.json"
+)
+
+// Dir: 1 thư mục đưa vào backup, Name là prefix trong archive
+type Dir struct {
+	Name string // avatars | chat_uploads
+	Path string
+}
+
+type FileEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+type Manifest struct {
+	Version   int         `json:"version"`
+	CreatedAt time.Time   `json:"created_at"`
+	Encrypted bool        `json:"encrypted"`
+	Database  *DumpStats  `json:"database"`
+	Dirs      []string    `json:"dirs"`
+	Files     []FileEntry `json:"files"` // gồm cả database.sql
+}
+
+// TotalBytes: tổng dung lượng (chưa nén) các entry
+func (m *Manifest) TotalBytes() int64 {
+	var n int64
+	for _, f := range m.Files {
+		n += f.Size
+	}
+	return n
+}
+
+// Create: dump DB + copy các thư mục vào archive ghi ra out.
+// passphrase rỗng = không mã hoá.
+func Create(ctx context.Context, db *sql.DB, dirs []Dir, passphrase []byte, out io.Writer) (*Manifest, error) {
+	m := &Manifest{Version: ManifestVersion, CreatedAt: time.Now(), Encrypted: len(passphrase) > 0}
+
+	// dump ra file tạm trước: tar header cần biết size
+	tmp, err := os.CreateTemp("", "cronchat-dump-*.sql")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if m.Database, err = DumpSQL(ctx, db, tmp); err != nil {
+		return nil, fmt.Errorf("dump database: %w", err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var sink io.Writer = out
+	var enc io.WriteCloser
+	if m.Encrypted {
+		if enc, err = newEncryptWriter(out, passphrase); err != nil {
+			return nil, err
+		}
+		sink = enc
+	}
+	gz := gzip.NewWriter(sink)
+	tw := tar.NewWriter(gz)
+
+	st, err := tmp.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := addEntry(tw, m, dbEntry, st.Size(), st.ModTime(), tmp); err != nil {
+		return nil, err
+	}
+
+	for _, d := range dirs {
+		m.Dirs = append(m.Dirs, d.Name)
+		if err := addDir(ctx, tw, m, d); err != nil {
+			return nil, fmt.Errorf("backup %s: %w", d.Name, err)
+		}
+	}
+
+	mb, _ := json.MarshalIndent(m, "", "  ")
+	if err := tw.WriteHeader(&tar.Header{Name: manifestEntry, Mode: 0o600, Size: int64(len(mb)), ModTime: m.CreatedAt}); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(mb); err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	if enc != nil {
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func addEntry(tw *tar.Writer, m *Manifest, name string, size int64, mod time.Time, r io.Reader) error {
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: size, ModTime: mod}); err != nil {
+		return err
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tw, h), r)
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("%s: size changed during backup", name)
+	}
+	m.Files = append(m.Files, FileEntry{Path: name, Size: size, SHA256: hex.EncodeToString(h.Sum(nil))})
+	return nil
+}
+
+func addDir(ctx context.Context, tw *tar.Writer, m *Manifest, d Dir) error {
+	return filepath.WalkDir(d.Path, func(p string, e fs.DirEntry, err error) error {
+		if err != nil {
+			// thư mục chưa tồn tại -> backup rỗng
+			if p == d.Path && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !e.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(d.Path, p)
+		if err != nil {
+			return err
+		}
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return addEntry(tw, m, path.Join(d.Name, filepath.ToSlash(rel)), info.Size(), info.ModTime(), f)
+	})
+}
+
+// RestoreResult: kết quả Restore
+type RestoreResult struct {
+	Manifest   *Manifest         `json:"manifest"`
+	Statements int               `json:"statements"`  // số câu SQL đã chạy
+	OldDirs    map[string]string `json:"old_dirs"`    // thư mục cũ đã đổi tên (xoá tay khi chắc chắn)
+	VerifyOnly bool              `json:"verify_only"` // chỉ kiểm tra, không ghi gì
+}
+
+// Restore: giải nén vào thư mục tạm, so từng entry với manifest; khớp hết mới
+// import DB rồi đổi tên thư mục tạm thành thư mục thật (thư mục cũ giữ lại .bak-*).
+func Restore(ctx context.Context, db *sql.DB, dirs []Dir, passphrase []byte, in io.Reader, verifyOnly bool) (*RestoreResult, error) {
+	br := bufio.NewReader(in)
+	var src io.Reader = br
+	if magic, _ := br.Peek(len(encMagic)); bytes.Equal(magic, encMagic) {
+		if len(passphrase) == 0 {
+			return nil, ErrPassphraseRequired
+		}
+		_, _ = br.Discard(len(encMagic))
+		dr, err := newDecryptReader(br, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		src = dr
+	}
+	gz, err := gzip.NewReader(src)
+	if err != nil {
+		return nil, fmt.Errorf("backup: not a backup archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	// thư mục tạm cạnh thư mục đích (cùng filesystem -> rename được)
+	stamp := time.Now().Format("20060102-150405")
+	staging := map[string]string{}
+	byName := map[string]Dir{}
+	for _, d := range dirs {
+		byName[d.Name] = d
+		staging[d.Name] = strings.TrimRight(d.Path, `/\`) + ".restore-" + stamp
+	}
+	cleanup := func() {
+		for _, p := range staging {
+			os.RemoveAll(p)
+		}
+	}
+
+	dump, err := os.CreateTemp("", "cronchat-restore-*.sql")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(dump.Name())
+	defer dump.Close()
+
+	got := map[string]FileEntry{}
+	var m *Manifest
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			cleanup()
+			return nil, fmt.Errorf("backup: read archive: %w", err)
+		}
+		if ctx.Err() != nil {
+			cleanup()
+			return nil, ctx.Err()
+		}
+
+		if hdr.Name == manifestEntry {
+			m = &Manifest{}
+			if err := json.NewDecoder(io.LimitReader(tr, 64<<20)).Decode(m); err != nil {
+				cleanup()
+				return nil, fmt.Errorf("backup: invalid manifest: %w", err)
+			}
+			continue
+		}
+		if m != nil {
+			cleanup()
+			return nil, fmt.Errorf("backup: unexpected entry %q after manifest", hdr.Name)
+		}
+
+		var dst io.Writer
+		var f *os.File
+		switch name, rel, _ := strings.Cut(hdr.Name, "/"); {
+		case hdr.Typeflag != tar.TypeReg:
+			cleanup()
+			return nil, fmt.Errorf("backup: unsupported entry %q", hdr.Name)
+		case hdr.Name == dbEntry:
+			dst = dump
+		default:
+			root, ok := staging[name]
+			if !ok || !isSafeRel(rel) {
+				cleanup()
+				return nil, fmt.Errorf("backup: unexpected entry %q", hdr.Name)
+			}
+			if verifyOnly {
+				dst = io.Discard
+				break
+			}
+			p := filepath.Join(root, filepath.FromSlash(rel))
+			if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+				cleanup()
+				return nil, err
+			}
+			if f, err = os.OpenFile(p, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644); err != nil {
+				cleanup()
+				return nil, err
+			}
+			dst = f
+		}
+
+		h := sha256.New()
+		n, err := io.Copy(io.MultiWriter(dst, h), tr)
+		if f != nil {
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			_ = os.Chtimes(f.Name(), hdr.ModTime, hdr.ModTime)
+		}
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		got[hdr.Name] = FileEntry{Path: hdr.Name, Size: n, SHA256: hex.EncodeToString(h.Sum(nil))}
+	}
+
+	if err := verifyManifest(m, got); err != nil {
+		cleanup()
+		return nil, err
+	}
+	res := &RestoreResult{Manifest: m, OldDirs: map[string]string{}, VerifyOnly: verifyOnly}
+	if verifyOnly {
+		cleanup()
+		return res, nil
+	}
+
+	// ===== DB =====
+	if _, err := dump.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return nil, err
+	}
+	if res.Statements, err = RestoreSQL(ctx, db, dump); err != nil {
+		cleanup()
+		return nil, fmt.Errorf("restore database: %w", err)
+	}
+
+	// ===== thư mục upload: chỉ thay những thư mục có trong backup =====
+	inBackup := map[string]bool{}
+	for _, name := range m.Dirs {
+		inBackup[name] = true
+	}
+	names := make([]string, 0, len(staging))
+	for name := range staging {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		d, tmp := byName[name], staging[name]
+		if !inBackup[name] {
+			os.RemoveAll(tmp)
+			continue
+		}
+		if err := os.MkdirAll(tmp, 0o755); err != nil {
+			return res, err
+		}
+		if _, err := os.Stat(d.Path); err == nil {
+			old := strings.TrimRight(d.Path, `/\`) + ".bak-" + stamp
+			if err := os.Rename(d.Path, old); err != nil {
+				return res, fmt.Errorf("move old %s: %w", name, err)
+			}
+			res.OldDirs[name] = old
+		}
+		if err := os.Rename(tmp, d.Path); err != nil {
+			return res, fmt.Errorf("restore %s: %w", name, err)
+		}
+	}
+	return res, nil
+}
+
+// isSafeRel: path tương đối, không thoát ra ngoài thư mục gốc
+func isSafeRel(rel string) bool {
+	if rel == "" || strings.HasPrefix(rel, "/") || strings.Contains(rel, `\`) {
+		return false
+	}
+	for _, part := range strings.Split(rel, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+func verifyManifest(m *Manifest, got map[string]FileEntry) error {
+	if m == nil {
+		return errors.New("backup: manifest.json missing (archive truncated?)")
+	}
+	if m.Version != ManifestVersion {
+		return fmt.Errorf("backup: unsupported manifest version %d", m.Version)
+	}
+	if len(m.Files) != len(got) {
+		return fmt.Errorf("backup: manifest lists %d entries, archive has %d", len(m.Files), len(got))
+	}
+	hasDB := false
+	for _, want := range m.Files {
+		g, ok := got[want.Path]
+		if !ok {
+			return fmt.Errorf("backup: %s missing from archive", want.Path)
+		}
+		if g.Size != want.Size || g.SHA256 != want.SHA256 {
+			return fmt.Errorf("backup: %s checksum mismatch", want.Path)
+		}
+		hasDB = hasDB || want.Path == dbEntry
+	}
+	if !hasDB {
+		return errors.New("backup: database.sql missing from archive")
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package backup
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// File mã hoá: magic | salt(16) | iterations(u32) | nonce prefix(8) | chunk...
+// chunk = header(u32: bit 31 = chunk cuối, còn lại = độ dài ciphertext) | AES-256-GCM(plaintext ≤ 64 KiB).
+// nonce = prefix || số thứ tự chunk, header làm AAD -> đổi thứ tự / cắt cụt / nối thêm đều bị phát hiện.
+const (
+	encChunkSize  = 64 << 10
+	encIterations = 600_000
+	encSaltLen    = 16
+	encPrefixLen  = 8
+	encFinalBit   = 1 << 31
+)
+
+var encMagic = []byte("CRONCHAT-BACKUP-ENC1\n")
+
+var (
+	ErrPassphraseRequired = errors.New("backup: archive is encrypted, passphrase required")
+	ErrDecrypt            = errors.New("backup: decrypt failed (wrong passphrase or corrupted archive)")
+	ErrTruncated          = errors.New("backup: encrypted archive is truncated")
+)
+
+func deriveAEAD(passphrase []byte, salt []byte, iter int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, string(passphrase), salt, iter, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+type encWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	prefix []byte
+	seq    uint32
+	buf    []byte
+}
+
+// newEncryptWriter: phải Close để ghi chunk cuối
+func newEncryptWriter(w io.Writer, passphrase []byte) (io.WriteCloser, error) {
+	salt := make([]byte, encSaltLen)
+	prefix := make([]byte, encPrefixLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	aead, err := deriveAEAD(passphrase, salt, encIterations)
+	if err != nil {
+		return nil, err
+	}
+
+	hdr := append(append([]byte{}, encMagic...), salt...)
+	hdr = binary.BigEndian.AppendUint32(hdr, encIterations)
+	hdr = append(hdr, prefix...)
+	if _, err := w.Write(hdr); err != nil {
+		return nil, err
+	}
+	return &encWriter{w: w, aead: aead, prefix: prefix, buf: make([]byte, 0, encChunkSize*2)}, nil
+}
+
+func (e *encWriter) Write(p []byte) (int, error) {
+	e.buf = append(e.buf, p...)
+	// chỉ seal khi vượt 1 chunk -> phần còn lại luôn dành cho chunk cuối lúc Close
+	for len(e.buf) > encChunkSize {
+		if err := e.seal(e.buf[:encChunkSize], false); err != nil {
+			return 0, err
+		}
+		e.buf = append(e.buf[:0], e.buf[encChunkSize:]...)
+	}
+	return len(p), nil
+}
+
+func (e *encWriter) Close() error {
+	return e.seal(e.buf, true)
+}
+
+func (e *encWriter) seal(chunk []byte, final bool) error {
+	h := uint32(len(chunk) + e.aead.Overhead())
+	if final {
+		h |= encFinalBit
+	}
+	hdr := binary.BigEndian.AppendUint32(nil, h)
+	ct := e.aead.Seal(append([]byte{}, hdr...), e.nonce(), chunk, hdr)
+	e.seq++
+	_, err := e.w.Write(ct)
+	return err
+}
+
+func (e *encWriter) nonce() []byte {
+	return binary.BigEndian.AppendUint32(append([]byte{}, e.prefix...), e.seq)
+}
+
+type decReader struct {
+	r    io.Reader
+	enc  *encWriter // dùng chung aead / prefix / seq
+	buf  []byte
+	done bool
+}
+
+// newDecryptReader: r đã bỏ qua magic
+func newDecryptReader(r io.Reader, passphrase []byte) (io.Reader, error) {
+	hdr := make([]byte, encSaltLen+4+encPrefixLen)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, ErrTruncated
+	}
+	salt := hdr[:encSaltLen]
+	iter := binary.BigEndian.Uint32(hdr[encSaltLen:])
+	if iter < 1000 || iter > 10_000_000 {
+		return nil, ErrDecrypt
+	}
+	aead, err := deriveAEAD(passphrase, salt, int(iter))
+	if err != nil {
+		return nil, err
+	}
+	return &decReader{r: r, enc: &encWriter{aead: aead, prefix: hdr[encSaltLen+4:]}}, nil
+}
+
+func (d *decReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+func (d *decReader) next() error {
+	hdr := make([]byte, 4)
+	if _, err := io.ReadFull(d.r, hdr); err != nil {
+		return ErrTruncated
+	}
+	h := binary.BigEndian.Uint32(hdr)
+	size := int(h &^ encFinalBit)
+	if size < d.enc.aead.Overhead() || size > encChunkSize+d.enc.aead.Overhead() {
+		return ErrDecrypt
+	}
+	ct := make([]byte, size)
+	if _, err := io.ReadFull(d.r, ct); err != nil {
+		return ErrTruncated
+	}
+	pt, err := d.enc.aead.Open(ct[:0], d.enc.nonce(), ct, hdr)
+	if err != nil {
+		return ErrDecrypt
+	}
+	d.enc.seq++
+	d.buf = pt
+
+	if h&encFinalBit != 0 {
+		d.done = true
+		// sau chunk cuối không được còn dữ liệu
+		if n, _ := d.r.Read(make([]byte, 1)); n > 0 {
+			return ErrDecrypt
+		}
+	}
+	return nil
+}
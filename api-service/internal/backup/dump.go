@@ -0,0 +1,342 @@
+package backup
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// INSERT gộp tối đa bấy nhiêu dòng / byte (dưới max_allowed_packet mặc định)
+const (
+	insertMaxRows  = 500
+	insertMaxBytes = 1 << 20
+)
+
+// bỏ DEFINER=`x`@`y` để restore bằng user khác không cần quyền SUPER
+var definerRe = regexp.MustCompile("DEFINER=`[^`]*`@`[^`]*`\\s*")
+
+var sqlEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`'`, `\'`,
+	"\x00", `\0`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\x1a", `\Z`,
+)
+
+// DumpStats: số liệu của 1 lần dump
+type DumpStats struct {
+	Database string `json:"database"`
+	Tables   int    `json:"tables"`
+	Rows     int64  `json:"rows"`
+	Routines int    `json:"routines"`
+	Triggers int    `json:"triggers"`
+}
+
+// DumpSQL: schema + data + procedure/function + trigger của DB hiện tại.
+// Format giống mysqldump (mysql CLI import được), mỗi câu INSERT nằm trên 1 dòng.
+// Đọc trong 1 transaction consistent snapshot -> không cần khoá bảng.
+func DumpSQL(ctx context.Context, db *sql.DB, out io.Writer) (*DumpStats, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ`); err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, `START TRANSACTION WITH CONSISTENT SNAPSHOT`); err != nil {
+		return nil, err
+	}
+	defer conn.ExecContext(context.Background(), `ROLLBACK`)
+
+	st := &DumpStats{}
+	if err := conn.QueryRowContext(ctx, `SELECT DATABASE()`).Scan(&st.Database); err != nil {
+		return nil, err
+	}
+
+	w := bufio.NewWriterSize(out, 256<<10)
+	fmt.Fprintf(w, "-- CronChat backup: database `%s`, %s\n", st.Database, time.Now().Format(time.RFC3339))
+	w.WriteString("SET NAMES utf8mb4;\nSET FOREIGN_KEY_CHECKS=0;\nSET UNIQUE_CHECKS=0;\n\n")
+
+	tables, err := showNames(ctx, conn, `SHOW FULL TABLES WHERE Table_type = 'BASE TABLE'`, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tables {
+		create, err := showCreate(ctx, conn, "SHOW CREATE TABLE "+quoteIdent(t), 1)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(w, "DROP TABLE IF EXISTS %s;\n%s;\n\n", quoteIdent(t), create)
+
+		n, err := dumpRows(ctx, conn, w, t)
+		if err != nil {
+			return nil, fmt.Errorf("dump %s: %w", t, err)
+		}
+		st.Tables++
+		st.Rows += n
+	}
+
+	// routine + trigger sau data: trigger không chạy lại khi INSERT lúc restore
+	for _, kind := range []string{"PROCEDURE", "FUNCTION"} {
+		names, err := showNames(ctx, conn, "SHOW "+kind+" STATUS WHERE Db = DATABASE()", 1)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			create, err := showCreate(ctx, conn, "SHOW CREATE "+kind+" "+quoteIdent(name), 2)
+			if err != nil {
+				return nil, err
+			}
+			writeRoutine(w, "DROP "+kind+" IF EXISTS "+quoteIdent(name), create)
+			st.Routines++
+		}
+	}
+
+	triggers, err := showNames(ctx, conn, `SHOW TRIGGERS`, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range triggers {
+		create, err := showCreate(ctx, conn, "SHOW CREATE TRIGGER "+quoteIdent(name), 2)
+		if err != nil {
+			return nil, err
+		}
+		writeRoutine(w, "DROP TRIGGER IF EXISTS "+quoteIdent(name), create)
+		st.Triggers++
+	}
+
+	w.WriteString("SET FOREIGN_KEY_CHECKS=1;\nSET UNIQUE_CHECKS=1;\n")
+	return st, w.Flush()
+}
+
+func writeRoutine(w *bufio.Writer, drop, create string) {
+	fmt.Fprintf(w, "%s;\nDELIMITER ;;\n%s;;\nDELIMITER ;\n\n", drop, definerRe.ReplaceAllString(create, ""))
+}
+
+// showNames: cột col của câu SHOW ... (tên bảng / routine / trigger)
+func showNames(ctx context.Context, conn *sql.Conn, query string, col int) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for rows.Next() {
+		vals := make([]sql.NullString, len(cols))
+		dest := make([]any, len(cols))
+		for i := range vals {
+			dest[i] = &vals[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		out = append(out, vals[col].String)
+	}
+	return out, rows.Err()
+}
+
+// showCreate: câu CREATE nằm ở cột col của SHOW CREATE ...
+func showCreate(ctx context.Context, conn *sql.Conn, query string, col int) (string, error) {
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("%s: no result", query)
+	}
+	vals := make([]sql.NullString, len(cols))
+	dest := make([]any, len(cols))
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return "", err
+	}
+	if !vals[col].Valid {
+		return "", fmt.Errorf("%s: definition is NULL (missing privilege?)", query)
+	}
+	return vals[col].String, nil
+}
+
+func dumpRows(ctx context.Context, conn *sql.Conn, w *bufio.Writer, table string) (int64, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT * FROM "+quoteIdent(table))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, err
+	}
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = quoteIdent(t.Name())
+	}
+	prefix := "INSERT INTO " + quoteIdent(table) + " (" + strings.Join(names, ",") + ") VALUES "
+
+	vals := make([]any, len(types))
+	dest := make([]any, len(types))
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+
+	var stmt strings.Builder
+	var total int64
+	inStmt := 0
+	flush := func() {
+		if inStmt > 0 {
+			w.WriteString(stmt.String())
+			w.WriteString(";\n")
+			stmt.Reset()
+			inStmt = 0
+		}
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return total, err
+		}
+		if inStmt == 0 {
+			stmt.WriteString(prefix)
+		} else {
+			stmt.WriteByte(',')
+		}
+		stmt.WriteByte('(')
+		for i, v := range vals {
+			if i > 0 {
+				stmt.WriteByte(',')
+			}
+			stmt.WriteString(sqlLiteral(v, types[i].DatabaseTypeName()))
+		}
+		stmt.WriteByte(')')
+		inStmt++
+		total++
+		if inStmt >= insertMaxRows || stmt.Len() >= insertMaxBytes {
+			flush()
+		}
+	}
+	flush()
+	if total > 0 {
+		w.WriteString("\n")
+	}
+	return total, rows.Err()
+}
+
+// sqlLiteral: giá trị scan được -> literal MySQL
+func sqlLiteral(v any, dbType string) string {
+	switch x := v.(type) {
+	case nil:
+		return "NULL"
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case uint64:
+		return strconv.FormatUint(x, 10)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(x), 'g', -1, 32)
+	case bool:
+		if x {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		// parseTime=true: DATE / DATETIME / TIMESTAMP về time.Time (giờ theo loc của DSN)
+		if x.IsZero() {
+			return "'0000-00-00 00:00:00'"
+		}
+		if dbType == "DATE" {
+			return "'" + x.Format("2006-01-02") + "'"
+		}
+		return "'" + x.Format("2006-01-02 15:04:05.999999") + "'"
+	case []byte:
+		if !utf8.Valid(x) {
+			return "X'" + hex.EncodeToString(x) + "'"
+		}
+		return "'" + sqlEscaper.Replace(string(x)) + "'"
+	case string:
+		return "'" + sqlEscaper.Replace(x) + "'"
+	default:
+		return "'" + sqlEscaper.Replace(fmt.Sprint(x)) + "'"
+	}
+}
+
+func quoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// RestoreSQL: chạy lại file của DumpSQL (hiểu DELIMITER như mysql CLI), trả số câu lệnh đã chạy
+func RestoreSQL(ctx context.Context, db *sql.DB, in io.Reader) (int, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	// lỗi giữa chừng: trả lại FK check trước khi conn về pool
+	defer conn.ExecContext(context.Background(), `SET FOREIGN_KEY_CHECKS=1, UNIQUE_CHECKS=1`)
+
+	r := bufio.NewReaderSize(in, 256<<10)
+	delim := ";"
+	var stmt strings.Builder
+	n := 0
+
+	for {
+		line, readErr := r.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return n, readErr
+		}
+		trimmed := strings.TrimRight(line, "\r\n")
+
+		switch {
+		case stmt.Len() == 0 && (strings.TrimSpace(trimmed) == "" || strings.HasPrefix(trimmed, "-- ")):
+			// dòng trống / comment ngoài câu lệnh
+		case stmt.Len() == 0 && strings.HasPrefix(trimmed, "DELIMITER "):
+			delim = strings.TrimSpace(strings.TrimPrefix(trimmed, "DELIMITER "))
+		default:
+			if strings.HasSuffix(strings.TrimRight(trimmed, " \t"), delim) {
+				stmt.WriteString(strings.TrimSuffix(strings.TrimRight(trimmed, " \t"), delim))
+				if _, err := conn.ExecContext(ctx, stmt.String()); err != nil {
+					return n, fmt.Errorf("statement %d: %w", n+1, err)
+				}
+				stmt.Reset()
+				n++
+			} else {
+				stmt.WriteString(line)
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+	}
+	if strings.TrimSpace(stmt.String()) != "" {
+		return n, fmt.Errorf("statement %d: unexpected end of dump", n+1)
+	}
+	return n, nil
+}
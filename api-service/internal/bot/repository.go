@@ -0,0 +1,323 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// event bot có thể đăng ký (gửi qua webhook_url của bot)
+const (
+	EventMessageCreated = "message_created"
+	EventMemberAdded    = "member_added"
+	EventMemberRemoved  = "member_removed"
+	EventButtonClicked  = "button_clicked"
+)
+
+var AllEvents = []string{EventMessageCreated, EventMemberAdded, EventMemberRemoved, EventButtonClicked}
+
+var (
+	ErrNotFound        = errors.New("bot: not found")
+	ErrInvalidUsername = errors.New("bot: username must be 3-32 chars [a-z0-9_] and end with _bot")
+	ErrUsernameTaken   = errors.New("bot: username already taken")
+	ErrInvalidURL      = errors.New("bot: webhook_url must be absolute http(s)")
+	ErrInvalidEvents   = errors.New("bot: unknown event")
+)
+
+var usernameRe = regexp.MustCompile(`^[a-z0-9_]{3,32}$`)
+
+// AnyOwner: admin quản lý bot của mọi user (RotateKey / Delete bỏ qua check owner)
+const AnyOwner int64 = -1
+
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// Bot: 1 user (users.id) điều khiển bằng API key thay vì mật khẩu
+type Bot struct {
+	UserID     int64     `json:"user_id"`
+	Username   string    `json:"username"`
+	FullName   string    `json:"full_name"`
+	OwnerID    int64     `json:"owner_id"`
+	WebhookURL string    `json:"webhook_url,omitempty"`
+	Events     []string  `json:"events"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func IsValidEvent(e string) bool {
+	for _, v := range AllEvents {
+		if v == e {
+			return true
+		}
+	}
+	return false
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomToken(prefix string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Create: tạo user bot + API key (key chỉ trả 1 lần)
+func (r *Repository) Create(ctx context.Context, ownerID int64, username, fullName string) (*Bot, string, error) {
+	username = strings.ToLower(strings.TrimSpace(username))
+	if !usernameRe.MatchString(username) || !strings.HasSuffix(username, "_bot") {
+		return nil, "", ErrInvalidUsername
+	}
+	fullName = strings.TrimSpace(fullName)
+	if fullName == "" {
+		fullName = username
+	}
+
+	key, err := randomToken("bot_")
+	if err != nil {
+		return nil, "", err
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var exists int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE username = ?`, username).Scan(&exists)
+	if err != nil {
+		return nil, "", err
+	}
+	if exists > 0 {
+		return nil, "", ErrUsernameTaken
+	}
+
+	// password "!" không bao giờ khớp hash -> bot không login bằng mật khẩu được
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO users (username, password, role, full_name, is_active)
+		VALUES (?, '!', 'user', ?, 1)
+	`, username, fullName)
+	if err != nil {
+		return nil, "", err
+	}
+	userID, err := res.LastInsertId()
+	if err != nil {
+		return nil, "", err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO bots (user_id, owner_id, api_key_hash, events)
+		VALUES (?, ?, ?, ?)
+	`, userID, ownerID, hashKey(key), strings.Join(AllEvents, ","))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, "", err
+	}
+
+	return &Bot{
+		UserID:    userID,
+		Username:  username,
+		FullName:  fullName,
+		OwnerID:   ownerID,
+		Events:    AllEvents,
+		CreatedAt: time.Now(),
+	}, key, nil
+}
+
+// EnsureSystemBot: bot nội bộ (owner_id = 0, vd Reminder bot), chưa có thì tạo
+func (r *Repository) EnsureSystemBot(ctx context.Context, username, fullName string) (int64, error) {
+	var id int64
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT b.user_id FROM bots b JOIN users u ON u.id = b.user_id WHERE u.username = ?
+	`, username).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	// key không dùng tới: bot hệ thống chỉ gửi tin từ server
+	b, _, err := r.Create(ctx, 0, username, fullName)
+	if err != nil {
+		return 0, err
+	}
+	return b.UserID, nil
+}
+
+const selectBot = `
+	SELECT b.user_id, u.username, COALESCE(u.full_name, ''), b.owner_id,
+	       COALESCE(b.webhook_url, ''), b.events, b.created_at
+	FROM bots b
+	JOIN users u ON u.id = b.user_id
+`
+
+func scanBot(sc interface{ Scan(...any) error }) (*Bot, error) {
+	var b Bot
+	var events string
+	if err := sc.Scan(&b.UserID, &b.Username, &b.FullName, &b.OwnerID, &b.WebhookURL, &events, &b.CreatedAt); err != nil {
+		return nil, err
+	}
+	b.Events = splitEvents(events)
+	return &b, nil
+}
+
+func (r *Repository) ListByOwner(ctx context.Context, ownerID int64) ([]*Bot, error) {
+	rows, err := r.DB.QueryContext(ctx, selectBot+` WHERE b.owner_id = ? ORDER BY b.user_id ASC`, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	return scanBots(rows)
+}
+
+// ListAll: mọi bot còn hoạt động (admin), kể cả bot hệ thống owner_id = 0
+func (r *Repository) ListAll(ctx context.Context) ([]*Bot, error) {
+	rows, err := r.DB.QueryContext(ctx, selectBot+` WHERE u.is_active = 1 ORDER BY b.user_id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	return scanBots(rows)
+}
+
+func scanBots(rows *sql.Rows) ([]*Bot, error) {
+	defer rows.Close()
+
+	out := []*Bot{}
+	for rows.Next() {
+		b, err := scanBot(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+func (r *Repository) GetByUserID(ctx context.Context, userID int64) (*Bot, error) {
+	b, err := scanBot(r.DB.QueryRowContext(ctx, selectBot+` WHERE b.user_id = ?`, userID))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return b, err
+}
+
+// Authenticate: API key -> bot (user bị khoá coi như không tồn tại)
+func (r *Repository) Authenticate(ctx context.Context, key string) (*Bot, error) {
+	b, err := scanBot(r.DB.QueryRowContext(ctx, selectBot+` WHERE b.api_key_hash = ? AND u.is_active = 1`, hashKey(key)))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return b, err
+}
+
+// RotateKey: sinh key mới, key cũ hết hiệu lực ngay
+func (r *Repository) RotateKey(ctx context.Context, ownerID, userID int64) (string, error) {
+	key, err := randomToken("bot_")
+	if err != nil {
+		return "", err
+	}
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE bots SET api_key_hash = ? WHERE user_id = ? AND (? = -1 OR owner_id = ?)
+	`, hashKey(key), userID, ownerID, ownerID)
+	if err != nil {
+		return "", err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return "", ErrNotFound
+	}
+	return key, nil
+}
+
+// Delete: xoá bot + khoá user (giữ user để tin nhắn cũ vẫn hiển thị tên)
+func (r *Repository) Delete(ctx context.Context, ownerID, userID int64) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, `
+		DELETE FROM bots WHERE user_id = ? AND (? = -1 OR owner_id = ?)
+	`, userID, ownerID, ownerID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET is_active = 0 WHERE id = ?`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetSubscription: webhook_url rỗng = tắt; trả secret mới để bot verify chữ ký
+func (r *Repository) SetSubscription(ctx context.Context, userID int64, webhookURL string, events []string) (string, error) {
+	webhookURL = strings.TrimSpace(webhookURL)
+	if webhookURL != "" {
+		u, err := url.Parse(webhookURL)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return "", ErrInvalidURL
+		}
+	}
+	if len(events) == 0 {
+		events = AllEvents
+	}
+	for _, e := range events {
+		if !IsValidEvent(e) {
+			return "", ErrInvalidEvents
+		}
+	}
+
+	secret := ""
+	if webhookURL != "" {
+		s, err := randomToken("whsec_")
+		if err != nil {
+			return "", err
+		}
+		secret = s
+	}
+
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE bots SET webhook_url = ?, webhook_secret = ?, events = ?
+		WHERE user_id = ?
+	`, nullIfEmpty(webhookURL), nullIfEmpty(secret), strings.Join(events, ","), userID)
+	if err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+func splitEvents(s string) []string {
+	out := []string{}
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,29 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Broker: pub/sub giữa các instance api-service (WS event, ...)
+type Broker interface {
+	Publish(ctx context.Context, channel string, payload []byte) error
+	// Subscribe: chạy tới khi ctx bị huỷ, tự kết nối lại khi mất kết nối
+	Subscribe(ctx context.Context, channel string, handle func(payload []byte)) error
+	Close() error
+}
+
+// NewFromEnv: PUBSUB_DRIVER = local (mặc định, 1 instance) | redis
+// local trả nil: caller gửi thẳng trong process, không qua broker
+func NewFromEnv() (Broker, error) {
+	switch driver := strings.ToLower(strings.TrimSpace(os.Getenv("PUBSUB_DRIVER"))); driver {
+	case "", "local":
+		return nil, nil
+	case "redis":
+		return NewRedisFromEnv()
+	default:
+		return nil, fmt.Errorf("broker: unknown PUBSUB_DRIVER %q (local | redis)", driver)
+	}
+}
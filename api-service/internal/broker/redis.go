@@ -0,0 +1,286 @@
+package broker
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Redis: PUBLISH / SUBSCRIBE qua giao thức RESP, chỉ cần 2 lệnh nên không kéo thêm client
+type Redis struct {
+	Addr     string
+	Username string
+	Password string
+	DB       int
+	TLS      bool
+
+	DialTimeout time.Duration
+
+	mu  sync.Mutex // 1 conn publish dùng chung
+	pub *redisConn
+}
+
+// NewRedisFromEnv: REDIS_URL = redis://[user:pass@]host:6379/0 (rediss:// = TLS)
+func NewRedisFromEnv() (*Redis, error) {
+	raw := strings.TrimSpace(os.Getenv("REDIS_URL"))
+	if raw == "" {
+		return nil, errors.New("broker: REDIS_URL is required when PUBSUB_DRIVER=redis")
+	}
+	return NewRedis(raw)
+}
+
+func NewRedis(rawURL string) (*Redis, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("broker: invalid REDIS_URL: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("broker: REDIS_URL scheme must be redis or rediss, got %q", u.Scheme)
+	}
+
+	r := &Redis{
+		Addr:        u.Host,
+		TLS:         u.Scheme == "rediss",
+		DialTimeout: 5 * time.Second,
+	}
+	if u.Port() == "" {
+		r.Addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		r.Username = u.User.Username()
+		r.Password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if r.DB, err = strconv.Atoi(db); err != nil || r.DB < 0 {
+			return nil, fmt.Errorf("broker: invalid redis db %q", db)
+		}
+	}
+	return r, nil
+}
+
+func (r *Redis) Publish(ctx context.Context, channel string, payload []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// conn publish có thể đã chết (redis restart) -> thử lại 1 lần với conn mới
+	for attempt := 0; ; attempt++ {
+		if r.pub == nil {
+			c, err := r.dial(ctx)
+			if err != nil {
+				return err
+			}
+			r.pub = c
+		}
+		_, err := r.pub.do(ctx, "PUBLISH", []byte(channel), payload)
+		if err == nil {
+			return nil
+		}
+		r.pub.Close()
+		r.pub = nil
+		if attempt > 0 || ctx.Err() != nil {
+			return err
+		}
+	}
+}
+
+func (r *Redis) Subscribe(ctx context.Context, channel string, handle func(payload []byte)) error {
+	backoff := time.Second
+	for {
+		err := r.subscribeOnce(ctx, channel, handle)
+		if ctx.Err() != nil {
+			return nil
+		}
+		log.Printf("[broker] redis subscribe %s: %v (retry in %s)", channel, err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 30*time.Second)
+	}
+}
+
+func (r *Redis) subscribeOnce(ctx context.Context, channel string, handle func(payload []byte)) error {
+	c, err := r.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	// ctx huỷ -> đóng conn để ReadReply đang block trả về
+	stop := context.AfterFunc(ctx, func() { c.Close() })
+	defer stop()
+
+	if err := c.write("SUBSCRIBE", []byte(channel)); err != nil {
+		return err
+	}
+	for {
+		reply, err := c.readReply()
+		if err != nil {
+			return err
+		}
+		msg, ok := reply.([]any)
+		if !ok || len(msg) < 3 {
+			continue
+		}
+		kind, _ := msg[0].([]byte)
+		switch string(kind) {
+		case "subscribe":
+			log.Printf("[broker] redis subscribed %s", channel)
+		case "message":
+			if payload, ok := msg[2].([]byte); ok {
+				handle(payload)
+			}
+		}
+	}
+}
+
+func (r *Redis) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pub != nil {
+		r.pub.Close()
+		r.pub = nil
+	}
+	return nil
+}
+
+// ==============================
+// RESP
+// ==============================
+
+type redisConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func (r *Redis) dial(ctx context.Context) (*redisConn, error) {
+	d := &net.Dialer{Timeout: r.DialTimeout}
+	var (
+		nc  net.Conn
+		err error
+	)
+	if r.TLS {
+		host, _, _ := net.SplitHostPort(r.Addr)
+		td := &tls.Dialer{NetDialer: d, Config: &tls.Config{ServerName: host}}
+		nc, err = td.DialContext(ctx, "tcp", r.Addr)
+	} else {
+		nc, err = d.DialContext(ctx, "tcp", r.Addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &redisConn{conn: nc, br: bufio.NewReader(nc)}
+	if r.Password != "" {
+		args := [][]byte{[]byte(r.Password)}
+		if r.Username != "" {
+			args = [][]byte{[]byte(r.Username), []byte(r.Password)}
+		}
+		if _, err := c.do(ctx, "AUTH", args...); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("redis auth: %w", err)
+		}
+	}
+	if r.DB > 0 {
+		if _, err := c.do(ctx, "SELECT", []byte(strconv.Itoa(r.DB))); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("redis select: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// do: gửi lệnh + đọc 1 reply (deadline theo ctx, mặc định 5s)
+func (c *redisConn) do(ctx context.Context, cmd string, args ...[]byte) (any, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(5 * time.Second)
+	}
+	_ = c.conn.SetDeadline(deadline)
+	defer c.conn.SetDeadline(time.Time{})
+
+	if err := c.write(cmd, args...); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+func (c *redisConn) write(cmd string, args ...[]byte) error {
+	var b []byte
+	b = fmt.Appendf(b, "*%d\r\n$%d\r\n%s\r\n", len(args)+1, len(cmd), cmd)
+	for _, a := range args {
+		b = fmt.Appendf(b, "$%d\r\n", len(a))
+		b = append(b, a...)
+		b = append(b, '\r', '\n')
+	}
+	_, err := c.conn.Write(b)
+	return err
+}
+
+// readReply: simple string / error / integer / bulk ([]byte, nil) / array ([]any)
+func (c *redisConn) readReply() (any, error) {
+	line, err := c.br.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, errors.New("redis: " + line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.br, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		out := make([]any, n)
+		for i := range out {
+			if out[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
+
+func (c *redisConn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,75 @@
+package calendar
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const icsTime = "20060102T150405Z"
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// WriteICS: ghi feed iCalendar (RFC 5545) cho danh sách event của 1 room
+func WriteICS(w io.Writer, calName string, events []*Event) error {
+	bw := bufio.NewWriter(w)
+	line := func(s string) { writeFolded(bw, s) }
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//CronChat//Room Calendar//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + icsEscaper.Replace(calName))
+
+	for _, e := range events {
+		line("BEGIN:VEVENT")
+		line("UID:room-event-" + strconv.FormatInt(e.ID, 10) + "@cronchat")
+		line("DTSTAMP:" + e.UpdatedAt.UTC().Format(icsTime))
+		line("DTSTART:" + e.StartAt.UTC().Format(icsTime))
+		line("DTEND:" + e.EndAt.UTC().Format(icsTime))
+		line("SUMMARY:" + icsEscaper.Replace(e.Title))
+		if e.Description != "" {
+			line("DESCRIPTION:" + icsEscaper.Replace(e.Description))
+		}
+		if e.Location != "" {
+			line("LOCATION:" + icsEscaper.Replace(e.Location))
+		}
+		if e.Recurrence != "" {
+			line("RRULE:" + e.Recurrence)
+		}
+		if e.RemindMinutes > 0 {
+			line("BEGIN:VALARM")
+			line("ACTION:DISPLAY")
+			line("DESCRIPTION:" + icsEscaper.Replace(e.Title))
+			line("TRIGGER:-PT" + strconv.Itoa(e.RemindMinutes) + "M")
+			line("END:VALARM")
+		}
+		line("END:VEVENT")
+	}
+
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
+
+// writeFolded: dòng > 75 octet thì gập (CRLF + space), không cắt giữa ký tự UTF-8
+func writeFolded(w *bufio.Writer, s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		limit = 74 // dòng tiếp đã có 1 space đầu
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
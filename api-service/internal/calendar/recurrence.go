@@ -0,0 +1,148 @@
+package calendar
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRule = errors.New("calendar: recurrence must be RRULE with FREQ=DAILY|WEEKLY|MONTHLY|YEARLY[;INTERVAL=n][;COUNT=n][;UNTIL=YYYYMMDDTHHMMSSZ]")
+
+// Rule: tập con RRULE (RFC 5545) đủ cho lịch họp lặp
+type Rule struct {
+	Freq     string
+	Interval int
+	Count    int       // 0 = không giới hạn
+	Until    time.Time // zero = không giới hạn
+}
+
+// ParseRule: "" -> nil (không lặp); nhận cả "RRULE:FREQ=..." lẫn "FREQ=..."
+// cho phép viết tắt "daily" / "weekly" / "monthly" / "yearly"
+func ParseRule(s string) (*Rule, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	s = strings.TrimPrefix(strings.ToUpper(s), "RRULE:")
+
+	switch s {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+		return &Rule{Freq: s, Interval: 1}, nil
+	}
+
+	rule := &Rule{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		k, v, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, ErrInvalidRule
+		}
+		switch k {
+		case "FREQ":
+			switch v {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				rule.Freq = v
+			default:
+				return nil, ErrInvalidRule
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > 366 {
+				return nil, ErrInvalidRule
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > 10000 {
+				return nil, ErrInvalidRule
+			}
+			rule.Count = n
+		case "UNTIL":
+			t, err := time.Parse("20060102T150405Z", v)
+			if err != nil {
+				t, err = time.Parse("20060102", v)
+			}
+			if err != nil {
+				return nil, ErrInvalidRule
+			}
+			rule.Until = t
+		default:
+			return nil, ErrInvalidRule
+		}
+	}
+	if rule.Freq == "" {
+		return nil, ErrInvalidRule
+	}
+	return rule, nil
+}
+
+// String: dạng chuẩn để lưu DB + ghi ra ICS (không có tiền tố RRULE:)
+func (r *Rule) String() string {
+	if r == nil {
+		return ""
+	}
+	s := "FREQ=" + r.Freq
+	if r.Interval > 1 {
+		s += ";INTERVAL=" + strconv.Itoa(r.Interval)
+	}
+	if r.Count > 0 {
+		s += ";COUNT=" + strconv.Itoa(r.Count)
+	}
+	if !r.Until.IsZero() {
+		s += ";UNTIL=" + r.Until.UTC().Format("20060102T150405Z")
+	}
+	return s
+}
+
+// nth: lần lặp thứ n (0 = start)
+func (r *Rule) nth(start time.Time, n int) time.Time {
+	k := n * r.Interval
+	switch r.Freq {
+	case "DAILY":
+		return start.AddDate(0, 0, k)
+	case "WEEKLY":
+		return start.AddDate(0, 0, 7*k)
+	case "MONTHLY":
+		return start.AddDate(0, k, 0)
+	default:
+		return start.AddDate(k, 0, 0)
+	}
+}
+
+// giới hạn vòng lặp phòng rule lặp dày mà start quá xa
+const maxIterations = 100000
+
+// NextOccurrence: lần bắt đầu đầu tiên sau after (rule nil = sự kiện 1 lần)
+func NextOccurrence(start time.Time, r *Rule, after time.Time) (time.Time, bool) {
+	if r == nil {
+		return start, start.After(after)
+	}
+	for n := 0; n < maxIterations; n++ {
+		if r.Count > 0 && n >= r.Count {
+			break
+		}
+		o := r.nth(start, n)
+		if !r.Until.IsZero() && o.After(r.Until) {
+			break
+		}
+		if o.After(after) {
+			return o, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Occurrences: các lần bắt đầu trong [from, to), tối đa max
+func Occurrences(start time.Time, r *Rule, from, to time.Time, max int) []time.Time {
+	out := []time.Time{}
+	t := from.Add(-time.Nanosecond)
+	for len(out) < max {
+		o, ok := NextOccurrence(start, r, t)
+		if !ok || !o.Before(to) {
+			break
+		}
+		out = append(out, o)
+		t = o
+	}
+	return out
+}
@@ -0,0 +1,303 @@
+package calendar
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// trạng thái RSVP
+const (
+	RSVPYes   = "yes"
+	RSVPNo    = "no"
+	RSVPMaybe = "maybe"
+)
+
+var ErrNotFound = errors.New("calendar: event not found")
+
+func IsValidRSVP(s string) bool {
+	return s == RSVPYes || s == RSVPNo || s == RSVPMaybe
+}
+
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// Event: sự kiện của room (lặp theo RRULE nếu Recurrence khác rỗng)
+type Event struct {
+	ID            int64     `json:"id"`
+	RoomID        int64     `json:"room_id"`
+	CreatorID     int64     `json:"creator_id"`
+	Title         string    `json:"title"`
+	Description   string    `json:"description,omitempty"`
+	Location      string    `json:"location,omitempty"`
+	StartAt       time.Time `json:"start_at"`
+	EndAt         time.Time `json:"end_at"`
+	Recurrence    string    `json:"recurrence,omitempty"` // FREQ=WEEKLY;INTERVAL=1
+	RemindMinutes int       `json:"remind_minutes"`       // 0 = không nhắc
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+
+	// lần nhắc kế tiếp (scheduler dùng)
+	NextRemindAt *time.Time `json:"-"`
+	RemindOccAt  *time.Time `json:"-"`
+
+	// chỉ có khi trả về API
+	RSVPCounts  map[string]int `json:"rsvp_counts,omitempty"`
+	MyRSVP      string         `json:"my_rsvp,omitempty"`
+	Occurrences []time.Time    `json:"occurrences,omitempty"`
+}
+
+func (e *Event) Rule() *Rule {
+	r, _ := ParseRule(e.Recurrence)
+	return r
+}
+
+// ScheduleReminder: tính lần nhắc cho lần diễn ra đầu tiên sau after
+// giờ nhắc đã qua (event sắp diễn ra) thì nhắc ngay ở lượt quét tới
+func (e *Event) ScheduleReminder(after, now time.Time) {
+	e.NextRemindAt, e.RemindOccAt = nil, nil
+	if e.RemindMinutes <= 0 {
+		return
+	}
+	occ, ok := NextOccurrence(e.StartAt, e.Rule(), after)
+	if !ok {
+		return
+	}
+	at := occ.Add(-time.Duration(e.RemindMinutes) * time.Minute)
+	if at.Before(now) {
+		at = now
+	}
+	e.NextRemindAt, e.RemindOccAt = &at, &occ
+}
+
+const selectEvent = `
+	SELECT id, room_id, creator_id, title, COALESCE(description, ''), COALESCE(location, ''),
+	       start_at, end_at, COALESCE(recurrence, ''), remind_minutes, next_remind_at, remind_occurrence_at,
+	       created_at, updated_at
+	FROM room_events
+`
+
+func scanEvent(sc interface{ Scan(...any) error }) (*Event, error) {
+	var e Event
+	var nextRemind, occ sql.NullTime
+	if err := sc.Scan(&e.ID, &e.RoomID, &e.CreatorID, &e.Title, &e.Description, &e.Location,
+		&e.StartAt, &e.EndAt, &e.Recurrence, &e.RemindMinutes, &nextRemind, &occ,
+		&e.CreatedAt, &e.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if nextRemind.Valid {
+		e.NextRemindAt = &nextRemind.Time
+	}
+	if occ.Valid {
+		e.RemindOccAt = &occ.Time
+	}
+	return &e, nil
+}
+
+func (r *Repository) Create(ctx context.Context, e *Event) error {
+	res, err := r.DB.ExecContext(ctx, `
+		INSERT INTO room_events
+			(room_id, creator_id, title, description, location, start_at, end_at, recurrence,
+			 remind_minutes, next_remind_at, remind_occurrence_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, e.RoomID, e.CreatorID, e.Title, nullIfEmpty(e.Description), nullIfEmpty(e.Location),
+		e.StartAt, e.EndAt, nullIfEmpty(e.Recurrence), e.RemindMinutes, e.NextRemindAt, e.RemindOccAt)
+	if err != nil {
+		return err
+	}
+	e.ID, _ = res.LastInsertId()
+	e.CreatedAt = time.Now()
+	e.UpdatedAt = e.CreatedAt
+	return nil
+}
+
+func (r *Repository) Update(ctx context.Context, e *Event) error {
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE room_events
+		SET title = ?, description = ?, location = ?, start_at = ?, end_at = ?, recurrence = ?,
+		    remind_minutes = ?, next_remind_at = ?, remind_occurrence_at = ?
+		WHERE id = ? AND room_id = ?
+	`, e.Title, nullIfEmpty(e.Description), nullIfEmpty(e.Location), e.StartAt, e.EndAt,
+		nullIfEmpty(e.Recurrence), e.RemindMinutes, e.NextRemindAt, e.RemindOccAt, e.ID, e.RoomID)
+	if err != nil {
+		return err
+	}
+	e.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *Repository) Delete(ctx context.Context, roomID, id int64) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM room_events WHERE id = ? AND room_id = ?`, id, roomID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM room_event_rsvps WHERE event_id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *Repository) Get(ctx context.Context, roomID, id int64) (*Event, error) {
+	e, err := scanEvent(r.DB.QueryRowContext(ctx, selectEvent+` WHERE id = ? AND room_id = ?`, id, roomID))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return e, err
+}
+
+func (r *Repository) ListByRoom(ctx context.Context, roomID int64) ([]*Event, error) {
+	rows, err := r.DB.QueryContext(ctx, selectEvent+` WHERE room_id = ? ORDER BY start_at ASC, id ASC`, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*Event{}
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// ===== RSVP =====
+
+type RSVP struct {
+	UserID    int64     `json:"user_id"`
+	FullName  string    `json:"full_name"`
+	Status    string    `json:"status"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SetRSVP: 1 member 1 trạng thái cho cả chuỗi sự kiện
+func (r *Repository) SetRSVP(ctx context.Context, eventID, userID int64, status string) error {
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT INTO room_event_rsvps (event_id, user_id, status)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE status = VALUES(status), updated_at = CURRENT_TIMESTAMP
+	`, eventID, userID, status)
+	return err
+}
+
+func (r *Repository) ListRSVPs(ctx context.Context, eventID int64) ([]*RSVP, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT v.user_id, COALESCE(u.full_name, u.username), v.status, v.updated_at
+		FROM room_event_rsvps v
+		JOIN users u ON u.id = v.user_id
+		WHERE v.event_id = ?
+		ORDER BY v.updated_at ASC
+	`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*RSVP{}
+	for rows.Next() {
+		var v RSVP
+		if err := rows.Scan(&v.UserID, &v.FullName, &v.Status, &v.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, &v)
+	}
+	return out, rows.Err()
+}
+
+// AttachRSVPs: gắn số lượng yes/no/maybe + trạng thái của userID vào list event của 1 room
+func (r *Repository) AttachRSVPs(ctx context.Context, roomID, userID int64, events []*Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	byID := make(map[int64]*Event, len(events))
+	for _, e := range events {
+		e.RSVPCounts = map[string]int{RSVPYes: 0, RSVPNo: 0, RSVPMaybe: 0}
+		byID[e.ID] = e
+	}
+
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT v.event_id, v.user_id, v.status
+		FROM room_event_rsvps v
+		JOIN room_events e ON e.id = v.event_id
+		WHERE e.room_id = ?
+	`, roomID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var eventID, uid int64
+		var status string
+		if err := rows.Scan(&eventID, &uid, &status); err != nil {
+			return err
+		}
+		e := byID[eventID]
+		if e == nil {
+			continue
+		}
+		e.RSVPCounts[status]++
+		if uid == userID {
+			e.MyRSVP = status
+		}
+	}
+	return rows.Err()
+}
+
+// ===== Nhắc trước sự kiện =====
+
+func (r *Repository) ListDueReminders(ctx context.Context, now time.Time, limit int) ([]*Event, error) {
+	rows, err := r.DB.QueryContext(ctx, selectEvent+`
+		WHERE next_remind_at IS NOT NULL AND next_remind_at <= ?
+		ORDER BY next_remind_at ASC, id ASC
+		LIMIT ?
+	`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*Event
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// ClaimReminder: chuyển sang lần nhắc kế tiếp (next nil = hết), false = instance khác đã gửi
+func (r *Repository) ClaimReminder(ctx context.Context, e *Event, nextRemind, nextOcc *time.Time) (bool, error) {
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE room_events SET next_remind_at = ?, remind_occurrence_at = ?
+		WHERE id = ? AND next_remind_at = ?
+	`, nextRemind, nextOcc, e.ID, e.NextRemindAt)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,226 @@
+package chat
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// loại emoji trong catalog reaction
+const (
+	EmojiUnicode = "unicode" // glyph unicode (👍) hoặc keyword cũ (like) kèm glyph
+	EmojiCustom  = "custom"  // ảnh upload, code dạng :party_parrot:
+)
+
+var (
+	ErrReactionNotAllowed = errors.New("reaction is not in the emoji catalog")
+	ErrEmojiNotFound      = errors.New("emoji not found")
+	ErrEmojiExists        = errors.New("emoji code already exists")
+	ErrInvalidEmoji       = errors.New("invalid emoji")
+)
+
+// code custom: :ten_emoji: (chữ thường, số, _ + -)
+var customEmojiCode = regexp.MustCompile(`^:[a-z0-9_+-]{2,30}:$`)
+
+func IsCustomEmojiCode(code string) bool {
+	return customEmojiCode.MatchString(code)
+}
+
+func invalidEmoji(msg string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidEmoji, msg)
+}
+
+// Emoji: 1 reaction được phép (message_reactions.reaction = Code)
+type Emoji struct {
+	Code      string `json:"code"`
+	Kind      string `json:"kind"`
+	Glyph     string `json:"glyph,omitempty"`
+	ImageURL  string `json:"image_url,omitempty"` // custom: /static/chat_uploads/... (ký ở httpserver)
+	Label     string `json:"label,omitempty"`
+	SortOrder int    `json:"sort_order"`
+	IsActive  bool   `json:"is_active"`
+}
+
+// ValidateEmoji: chuẩn hoá + check code theo kind (lỗi bọc ErrInvalidEmoji)
+func ValidateEmoji(e *Emoji) error {
+	e.Code = strings.TrimSpace(e.Code)
+	e.Label = strings.TrimSpace(e.Label)
+	e.Glyph = strings.TrimSpace(e.Glyph)
+	if utf8.RuneCountInString(e.Label) > 64 {
+		return invalidEmoji("label too long (max 64)")
+	}
+
+	switch e.Kind {
+	case EmojiCustom:
+		if !IsCustomEmojiCode(e.Code) {
+			return invalidEmoji("custom code must look like :name:")
+		}
+		if e.ImageURL == "" {
+			return invalidEmoji("custom emoji requires an image")
+		}
+		e.Glyph = ""
+	case EmojiUnicode:
+		// VARCHAR(32) như message_reactions.reaction, không khoảng trắng
+		if e.Code == "" || len(e.Code) > 32 || strings.ContainsAny(e.Code, " \t\r\n") || strings.HasPrefix(e.Code, ":") {
+			return invalidEmoji("code must be 1-32 bytes without spaces")
+		}
+		if len(e.Glyph) > 32 {
+			return invalidEmoji("glyph too long")
+		}
+		e.ImageURL = ""
+	default:
+		return invalidEmoji("kind must be unicode or custom")
+	}
+	return nil
+}
+
+const emojiColumns = `code, kind, COALESCE(glyph, ''), COALESCE(image_url, ''), COALESCE(label, ''), sort_order, is_active`
+
+func scanEmoji(sc interface{ Scan(...any) error }) (*Emoji, error) {
+	var e Emoji
+	var active int
+	if err := sc.Scan(&e.Code, &e.Kind, &e.Glyph, &e.ImageURL, &e.Label, &e.SortOrder, &active); err != nil {
+		return nil, err
+	}
+	e.IsActive = active == 1
+	return &e, nil
+}
+
+// ListEmojis: catalog theo sort_order (picker chỉ cần active)
+func (r *Repository) ListEmojis(ctx context.Context, includeInactive bool) ([]*Emoji, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT `+emojiColumns+`
+		FROM reaction_emojis
+		WHERE ? OR is_active = 1
+		ORDER BY sort_order ASC, code ASC
+	`, includeInactive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*Emoji{}
+	for rows.Next() {
+		e, err := scanEmoji(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (r *Repository) GetEmoji(ctx context.Context, code string) (*Emoji, error) {
+	e, err := scanEmoji(r.DB.QueryRowContext(ctx, `
+		SELECT `+emojiColumns+` FROM reaction_emojis WHERE code = ?
+	`, code))
+	if err == sql.ErrNoRows {
+		return nil, ErrEmojiNotFound
+	}
+	return e, err
+}
+
+// CreateEmoji: thêm emoji vào catalog (trùng code -> ErrEmojiExists)
+func (r *Repository) CreateEmoji(ctx context.Context, e *Emoji, createdBy int64) error {
+	res, err := r.DB.ExecContext(ctx, `
+		INSERT IGNORE INTO reaction_emojis (code, kind, glyph, image_url, label, sort_order, is_active, created_by)
+		VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?)
+	`, e.Code, e.Kind, e.Glyph, e.ImageURL, e.Label, e.SortOrder, e.IsActive, createdBy)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrEmojiExists
+	}
+	return nil
+}
+
+// EmojiUpdate: field nil = giữ nguyên
+type EmojiUpdate struct {
+	Label     *string
+	Glyph     *string
+	SortOrder *int
+	IsActive  *bool
+}
+
+func (r *Repository) UpdateEmoji(ctx context.Context, code string, u EmojiUpdate) (*Emoji, error) {
+	e, err := r.GetEmoji(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if u.Label != nil {
+		e.Label = *u.Label
+	}
+	if u.Glyph != nil {
+		e.Glyph = *u.Glyph
+	}
+	if u.SortOrder != nil {
+		e.SortOrder = *u.SortOrder
+	}
+	if u.IsActive != nil {
+		e.IsActive = *u.IsActive
+	}
+	if err := ValidateEmoji(e); err != nil {
+		return nil, err
+	}
+
+	_, err = r.DB.ExecContext(ctx, `
+		UPDATE reaction_emojis
+		SET glyph = NULLIF(?, ''), label = NULLIF(?, ''), sort_order = ?, is_active = ?
+		WHERE code = ?
+	`, e.Glyph, e.Label, e.SortOrder, e.IsActive, code)
+	return e, err
+}
+
+// DeleteEmoji: xoá khỏi catalog, reaction cũ vẫn giữ (hiện không có metadata)
+// trả image_url để caller dọn file custom
+func (r *Repository) DeleteEmoji(ctx context.Context, code string) (imageURL string, err error) {
+	e, err := r.GetEmoji(ctx, code)
+	if err != nil {
+		return "", err
+	}
+	if _, err := r.DB.ExecContext(ctx, `DELETE FROM reaction_emojis WHERE code = ?`, code); err != nil {
+		return "", err
+	}
+	return e.ImageURL, nil
+}
+
+// IsReactionAllowed: reaction có trong catalog và đang bật
+func (r *Repository) IsReactionAllowed(ctx context.Context, reaction string) (bool, error) {
+	var ok int
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM reaction_emojis WHERE code = ? AND is_active = 1)
+	`, reaction).Scan(&ok)
+	return ok == 1, err
+}
+
+// cột metadata khi LEFT JOIN reaction_emojis e (GROUP BY reaction -> MAX)
+const reactionEmojiColumns = `MAX(e.kind), MAX(e.glyph), MAX(e.image_url), MAX(e.label), MAX(e.is_active)`
+
+// emojiJoin: scan reactionEmojiColumns, kind NULL = reaction không có trong catalog (dữ liệu cũ)
+type emojiJoin struct {
+	kind, glyph, imageURL, label sql.NullString
+	active                       sql.NullInt64
+}
+
+func (j *emojiJoin) dest() []any {
+	return []any{&j.kind, &j.glyph, &j.imageURL, &j.label, &j.active}
+}
+
+func (j *emojiJoin) emoji(code string) *Emoji {
+	if !j.kind.Valid {
+		return nil
+	}
+	return &Emoji{
+		Code:     code,
+		Kind:     j.kind.String,
+		Glyph:    j.glyph.String,
+		ImageURL: j.imageURL.String,
+		Label:    j.label.String,
+		IsActive: j.active.Int64 == 1,
+	}
+}
@@ -3,8 +3,12 @@ package chat
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -31,11 +35,31 @@ type Message struct {
 
 	ReplyToMessageID *int64 `json:"reply_to_message_id,omitempty"`
 
+	// id client tự sinh (idempotency), unique theo sender_id
+	ClientMsgID string `json:"client_msg_id,omitempty"`
+
 	// ✅ cache reply content để GET nhanh + UI render
 	ReplyPreview     string `json:"reply_preview,omitempty"`
 	ReplySenderName  string `json:"reply_sender_name,omitempty"`
 	ReplyMessageType string `json:"reply_message_type,omitempty"`
 
+	// tin gửi qua incoming webhook (sender_id = người tạo webhook)
+	WebhookID *int64 `json:"webhook_id,omitempty"`
+	// tên / avatar ghi đè cho riêng tin này (rỗng = theo webhook)
+	WebhookName      string `json:"webhook_name,omitempty"`
+	WebhookAvatarURL string `json:"webhook_avatar_url,omitempty"`
+
+	// nút bấm tương tác (chỉ bot gửi), click -> event button_clicked về bot
+	Buttons []Button `json:"buttons,omitempty"`
+
+	// khối nội dung có màu (attachments kiểu Slack từ incoming webhook)
+	Embeds []Embed `json:"embeds,omitempty"`
+
+	// media chính của tin, ghi lúc tạo (upload-image ?send=true); voice / video cập nhật sau
+	MediaURL  string `json:"media_url,omitempty"`
+	MediaMIME string `json:"media_mime,omitempty"`
+	MediaSize int64  `json:"media_size,omitempty"`
+
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt *time.Time `json:"updated_at,omitempty"`
 }
@@ -48,6 +72,64 @@ type Attachment struct {
 	ContentType string    `json:"content_type"`
 	FilePath    string    `json:"file_path"`
 	CreatedAt   time.Time `json:"created_at"`
+
+	// /media/download/{file} (chỉ set khi trả về client)
+	DownloadURL string `json:"download_url,omitempty"`
+
+	// metadata để FE giữ chỗ layout trước khi tải media
+	Width      int   `json:"width,omitempty"`
+	Height     int   `json:"height,omitempty"`
+	DurationMs int64 `json:"duration_ms,omitempty"`
+
+	// audio: peaks 0..100 để FE vẽ waveform không cần tải file
+	Waveform []int `json:"waveform,omitempty"`
+
+	// ảnh: bản thu nhỏ cho message list / xem nhanh (rỗng -> dùng file_path)
+	ThumbURL  string `json:"thumb_url,omitempty"`
+	MediumURL string `json:"medium_url,omitempty"`
+}
+
+// Upload: file chat đã lưu, giữ tên gốc (file trên đĩa là r{room}_u{user}_{ts})
+type Upload struct {
+	FileName     string    `json:"file_name"`
+	RoomID       int64     `json:"room_id"`
+	UploaderID   int64     `json:"uploader_id"`
+	OriginalName string    `json:"original_name"`
+	ContentType  string    `json:"content_type"`
+	FileSize     int64     `json:"file_size"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	// quét virus không sạch (file đã bị xoá khỏi storage)
+	QuarantinedAt *time.Time `json:"quarantined_at,omitempty"`
+}
+
+// ErrUploadNotFound: file không có trong chat_uploads (upload cũ trước khi có bảng, hoặc sai tên)
+var ErrUploadNotFound = errors.New("upload not found")
+
+// Button: nút tương tác gắn trên tin nhắn của bot
+type Button struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	Value string `json:"value,omitempty"`
+	Style string `json:"style,omitempty"` // primary | danger | "" (default)
+}
+
+// Embed: 1 khối nội dung phụ (title, text, fields, màu viền)
+type Embed struct {
+	Color     string       `json:"color,omitempty"` // #RRGGBB
+	Pretext   string       `json:"pretext,omitempty"`
+	Title     string       `json:"title,omitempty"`
+	TitleLink string       `json:"title_link,omitempty"`
+	Text      string       `json:"text,omitempty"`
+	Fields    []EmbedField `json:"fields,omitempty"`
+	Footer    string       `json:"footer,omitempty"`
+	ImageURL  string       `json:"image_url,omitempty"`
+}
+
+type EmbedField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short,omitempty"`
 }
 
 type MessageRead struct {
@@ -63,6 +145,9 @@ type MessageRead struct {
 
 var ErrInvalidReplyTarget = errors.New("invalid reply target message")
 
+// ErrDuplicateClientMsgID: sender đã có tin với client_msg_id này (request retry)
+var ErrDuplicateClientMsgID = errors.New("duplicate client_msg_id")
+
 // EnsureReplyTargetValid:
 // - reply message phải tồn tại
 // - và phải nằm cùng room
@@ -80,6 +165,8 @@ func buildReplyPreview(messageType string, content sql.NullString) string {
 		return "📷 Image"
 	case "file":
 		return "📎 File"
+	case "audio":
+		return "🎤 Voice message"
 	case "system", "text":
 		// ok
 	default:
@@ -191,6 +278,14 @@ func fetchReplyInfoTx(ctx context.Context, tx *sql.Tx, roomID int64, replyToID i
 	}, nil
 }
 
+// nullJSON: n = 0 -> NULL, còn lại marshal v
+func nullJSON(v any, n int) ([]byte, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
 func nullIfEmpty(s string) sql.NullString {
 	s = strings.TrimSpace(s)
 	if s == "" {
@@ -222,9 +317,15 @@ func (r *Repository) EnsureReplyTargetValid(ctx context.Context, roomID int64, r
 // Create message (core)
 // ==============================
 
-// CreateMessage: insert 1 message (supports reply_to_message_id)
-// ctx để mày dễ cancel/timeout + đồng bộ style các repo khác
+// DaySeparatorSenderID: sender của tin system "--- 2006-01-02 ---" ngăn cách ngày
+const DaySeparatorSenderID = 99999
 
+// daySeparatorLabelSQL: nội dung tin ngăn cách của ngày hiện tại theo timezone của MySQL
+// (giữ mốc ngày CURDATE() như sp_send_message_with_day_sep cũ)
+const daySeparatorLabelSQL = `CONCAT('--- ', DATE_FORMAT(CURDATE(), '%Y-%m-%d'), ' ---')`
+
+// CreateMessage: insert 1 message (supports reply_to_message_id)
+// tin đầu tiên trong ngày của room (không tính tin system) -> chèn tin ngăn cách ngày trước, cùng transaction
 func (r *Repository) CreateMessage(ctx context.Context, msg *Message, validateReply bool) (int64, error) {
 	if msg == nil {
 		return 0, errors.New("msg is nil")
@@ -242,6 +343,9 @@ func (r *Repository) CreateMessage(ctx context.Context, msg *Message, validateRe
 			msg.ReplyMessageType = info.MessageType
 		}
 	}
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = time.Now()
+	}
 
 	tx, err := r.DB.BeginTx(ctx, nil)
 	if err != nil {
@@ -249,37 +353,96 @@ func (r *Repository) CreateMessage(ctx context.Context, msg *Message, validateRe
 	}
 	defer func() { _ = tx.Rollback() }()
 
-	// ✅ CALL proc (now supports reply fields)
-	_, err = tx.ExecContext(ctx, `
-	CALL sp_send_message_with_day_sep(?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`,
-	msg.RoomID,
-	msg.SenderID,
-	msg.Content,
-	msg.MessageType,
-	msg.IsTemp,
-	msg.ReplyToMessageID,
-	nullIfEmpty(msg.ReplyPreview),
-	nullIfEmpty(msg.ReplySenderName),
-	nullIfEmpty(msg.ReplyMessageType),
-	)
+	if msg.MessageType != "system" {
+		if err := insertDaySeparatorTx(ctx, tx, msg.RoomID); err != nil {
+			return 0, err
+		}
+	}
 
+	id, err := r.CreateMessageTx(ctx, tx, msg, false)
 	if err != nil {
+		if msg.ClientMsgID != "" && isDuplicate(err) {
+			return 0, ErrDuplicateClientMsgID
+		}
 		return 0, err
 	}
 
-	// ✅ Last insert inside the proc is the "real message"
-	var id int64
-	if err := tx.QueryRowContext(ctx, `SELECT LAST_INSERT_ID()`).Scan(&id); err != nil {
+	if err := tx.Commit(); err != nil {
 		return 0, err
 	}
+	return id, nil
+}
 
-	if err := tx.Commit(); err != nil {
-		return 0, err
+// isDuplicate: MySQL "Duplicate entry" (1062)
+func isDuplicate(err error) bool {
+	return strings.Contains(err.Error(), "Duplicate entry")
+}
+
+// GetMessageByClientMsgID: tin sender đã gửi với client_msg_id (ErrMessageNotFound nếu chưa có)
+func (r *Repository) GetMessageByClientMsgID(ctx context.Context, senderID int64, clientMsgID string) (*Message, error) {
+	var (
+		m                               Message
+		replyTo                         sql.NullInt64
+		preview, replySender, replyType sql.NullString
+	)
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT id, room_id, sender_id, client_msg_id, content, message_type, is_temp,
+			reply_to_message_id, reply_preview, reply_sender_name, reply_message_type,
+			created_at
+		FROM messages
+		WHERE sender_id = ? AND client_msg_id = ?
+		LIMIT 1
+	`, senderID, clientMsgID).Scan(
+		&m.ID, &m.RoomID, &m.SenderID, &m.ClientMsgID, &m.Content, &m.MessageType, &m.IsTemp,
+		&replyTo, &preview, &replySender, &replyType,
+		&m.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if replyTo.Valid {
+		m.ReplyToMessageID = &replyTo.Int64
 	}
+	m.ReplyPreview = preview.String
+	m.ReplySenderName = replySender.String
+	m.ReplyMessageType = replyType.String
+	return &m, nil
+}
 
-	msg.ID = id
-	return id, nil
+// insertDaySeparatorTx: chưa có tin ngăn cách của ngày CURDATE() trong room -> insert lúc 00:00
+// khoá dòng rooms để 2 tin đầu ngày gửi cùng lúc không tạo 2 separator
+func insertDaySeparatorTx(ctx context.Context, tx *sql.Tx, roomID int64) error {
+	var locked int64
+	if err := tx.QueryRowContext(ctx, `SELECT id FROM rooms WHERE id = ? FOR UPDATE`, roomID).Scan(&locked); err != nil {
+		return err
+	}
+
+	var exists int
+	err := tx.QueryRowContext(ctx, `
+		SELECT 1
+		FROM messages
+		WHERE room_id = ?
+		  AND sender_id = ?
+		  AND message_type = 'system'
+		  AND content = `+daySeparatorLabelSQL+`
+		  AND created_at >= CURDATE()
+		LIMIT 1
+	`, roomID, DaySeparatorSenderID).Scan(&exists)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO messages (room_id, sender_id, content, message_type, is_temp, created_at)
+		VALUES (?, ?, `+daySeparatorLabelSQL+`, 'system', 0, TIMESTAMP(CURDATE()))
+	`, roomID, DaySeparatorSenderID)
+	return err
 }
 
 // CreateMessageTx: tạo message trong transaction (để dùng kèm attachments)
@@ -303,16 +466,32 @@ func (r *Repository) CreateMessageTx(ctx context.Context, tx *sql.Tx, msg *Messa
 		}
 	}
 
+	buttons, err := nullJSON(msg.Buttons, len(msg.Buttons))
+	if err != nil {
+		return 0, err
+	}
+	embeds, err := nullJSON(msg.Embeds, len(msg.Embeds))
+	if err != nil {
+		return 0, err
+	}
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = time.Now()
+	}
+
 	res, err := tx.ExecContext(ctx, `
 		INSERT INTO messages (
-			room_id, sender_id,
+			room_id, sender_id, client_msg_id,
 			reply_to_message_id, reply_preview, reply_sender_name, reply_message_type,
-			content, message_type, is_temp
+			content, message_type, is_temp,
+			media_url, media_mime, media_size,
+			webhook_id, webhook_name, webhook_avatar_url, buttons, embeds,
+			created_at
 		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`,
 		msg.RoomID,
 		msg.SenderID,
+		nullIfEmpty(msg.ClientMsgID),
 
 		msg.ReplyToMessageID,
 		nullIfEmpty(msg.ReplyPreview),
@@ -322,6 +501,18 @@ func (r *Repository) CreateMessageTx(ctx context.Context, tx *sql.Tx, msg *Messa
 		msg.Content,
 		msg.MessageType,
 		msg.IsTemp,
+
+		nullIfEmpty(msg.MediaURL),
+		nullIfEmpty(msg.MediaMIME),
+		nullIfZero(msg.MediaSize),
+
+		msg.WebhookID,
+		nullIfEmpty(msg.WebhookName),
+		nullIfEmpty(msg.WebhookAvatarURL),
+		buttons,
+		embeds,
+
+		msg.CreatedAt,
 	)
 	if err != nil {
 		return 0, err
@@ -376,15 +567,26 @@ func (r *Repository) CreateAttachment(ctx context.Context, att *Attachment) (int
 		return 0, errors.New("att is nil")
 	}
 
+	waveformArg, err := attachmentWaveformArg(att)
+	if err != nil {
+		return 0, err
+	}
+
 	res, err := r.DB.ExecContext(ctx, `
-		INSERT INTO attachments (message_id, file_name, file_size, content_type, file_path)
-		VALUES (?, ?, ?, ?, ?)
+		INSERT INTO attachments (message_id, file_name, file_size, content_type, file_path, waveform, width, height, duration_ms, thumb_url, medium_url)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`,
 		att.MessageID,
 		att.FileName,
 		att.FileSize,
 		att.ContentType,
 		att.FilePath,
+		waveformArg,
+		nullIfZero(int64(att.Width)),
+		nullIfZero(int64(att.Height)),
+		nullIfZero(att.DurationMs),
+		nullIfEmpty(att.ThumbURL),
+		nullIfEmpty(att.MediumURL),
 	)
 	if err != nil {
 		return 0, err
@@ -406,15 +608,26 @@ func (r *Repository) CreateAttachmentTx(ctx context.Context, tx *sql.Tx, att *At
 		return 0, errors.New("tx is nil")
 	}
 
+	waveformArg, err := attachmentWaveformArg(att)
+	if err != nil {
+		return 0, err
+	}
+
 	res, err := tx.ExecContext(ctx, `
-		INSERT INTO attachments (message_id, file_name, file_size, content_type, file_path)
-		VALUES (?, ?, ?, ?, ?)
+		INSERT INTO attachments (message_id, file_name, file_size, content_type, file_path, waveform, width, height, duration_ms, thumb_url, medium_url)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`,
 		att.MessageID,
 		att.FileName,
 		att.FileSize,
 		att.ContentType,
 		att.FilePath,
+		waveformArg,
+		nullIfZero(int64(att.Width)),
+		nullIfZero(int64(att.Height)),
+		nullIfZero(att.DurationMs),
+		nullIfEmpty(att.ThumbURL),
+		nullIfEmpty(att.MediumURL),
 	)
 	if err != nil {
 		return 0, err
@@ -428,6 +641,198 @@ func (r *Repository) CreateAttachmentTx(ctx context.Context, tx *sql.Tx, att *At
 	return id, nil
 }
 
+// ==============================
+// Uploads (tên file gốc)
+// ==============================
+
+func (r *Repository) CreateUpload(ctx context.Context, up *Upload) error {
+	if up == nil {
+		return errors.New("upload is nil")
+	}
+
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT INTO chat_uploads (file_name, room_id, uploader_id, original_name, content_type, file_size)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`,
+		up.FileName,
+		up.RoomID,
+		up.UploaderID,
+		up.OriginalName,
+		up.ContentType,
+		up.FileSize,
+	)
+	return err
+}
+
+// QuarantineUpload: đánh dấu file upload bị cách ly (quét virus không sạch)
+func (r *Repository) QuarantineUpload(ctx context.Context, fileName, signature string) error {
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE chat_uploads SET quarantined_at = NOW(), scan_signature = ? WHERE file_name = ?
+	`, nullIfEmpty(signature), fileName)
+	return err
+}
+
+// GetUpload: 1 file trong chat_uploads (room_id = 0 với emoji tuỳ chỉnh), không có -> ErrUploadNotFound
+func (r *Repository) GetUpload(ctx context.Context, fileName string) (*Upload, error) {
+	var up Upload
+	var quarantinedAt sql.NullTime
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT file_name, room_id, uploader_id, original_name, content_type, file_size, created_at, quarantined_at
+		FROM chat_uploads
+		WHERE file_name = ?
+	`, fileName).Scan(&up.FileName, &up.RoomID, &up.UploaderID, &up.OriginalName, &up.ContentType, &up.FileSize, &up.CreatedAt, &quarantinedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if quarantinedAt.Valid {
+		up.QuarantinedAt = &quarantinedAt.Time
+	}
+	return &up, nil
+}
+
+// GetUploadRooms: file_name -> room_id lúc upload, file không có trong chat_uploads thì không có trong map
+func (r *Repository) GetUploadRooms(ctx context.Context, fileNames []string) (map[string]int64, error) {
+	out := make(map[string]int64, len(fileNames))
+	if len(fileNames) == 0 {
+		return out, nil
+	}
+
+	args := make([]any, 0, len(fileNames))
+	for _, name := range fileNames {
+		args = append(args, name)
+	}
+	q := fmt.Sprintf(`
+		SELECT file_name, room_id FROM chat_uploads WHERE file_name IN (%s)
+	`, strings.TrimSuffix(strings.Repeat("?,", len(fileNames)), ","))
+
+	rows, err := r.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		var roomID int64
+		if err := rows.Scan(&name, &roomID); err != nil {
+			return nil, err
+		}
+		out[name] = roomID
+	}
+	return out, rows.Err()
+}
+
+// IsUploadQuarantined: file đã bị cách ly chưa (không có trong chat_uploads -> false)
+func (r *Repository) IsUploadQuarantined(ctx context.Context, fileName string) (bool, error) {
+	var quarantined bool
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT quarantined_at IS NOT NULL FROM chat_uploads WHERE file_name = ?
+	`, fileName).Scan(&quarantined)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return quarantined, err
+}
+
+// GetUploadOriginalName: tên gốc theo file đã lưu, ưu tiên chat_uploads rồi tới attachments
+// không có thì trả "" (không coi là lỗi)
+func (r *Repository) GetUploadOriginalName(ctx context.Context, fileName string) (string, error) {
+	var name string
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT original_name FROM (
+			SELECT original_name, 0 AS prio
+			FROM chat_uploads
+			WHERE file_name = ?
+			UNION ALL
+			SELECT file_name AS original_name, 1 AS prio
+			FROM attachments
+			WHERE file_path = ?
+		) t
+		WHERE original_name <> ''
+		ORDER BY prio ASC
+		LIMIT 1
+	`, fileName, "/static/chat_uploads/"+fileName).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// attachmentWaveformArg: waveform lưu JSON, không có thì NULL
+func attachmentWaveformArg(att *Attachment) (any, error) {
+	if len(att.Waveform) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(att.Waveform)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func nullIfZero(v int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: v, Valid: v != 0}
+}
+
+// ListAttachmentsBatch: map[messageID][]Attachment (dùng khi load list message)
+func (r *Repository) ListAttachmentsBatch(ctx context.Context, messageIDs []int64) (map[int64][]Attachment, error) {
+	result := make(map[int64][]Attachment)
+	if len(messageIDs) == 0 {
+		return result, nil
+	}
+
+	inClause, args := buildInt64InClause(messageIDs)
+	q := fmt.Sprintf(`
+		SELECT id, message_id, file_name, file_size, content_type, file_path, waveform, width, height, duration_ms,
+		       COALESCE(thumb_url, ''), COALESCE(medium_url, ''), created_at
+		FROM attachments
+		WHERE message_id IN (%s)
+		ORDER BY message_id ASC, id ASC
+	`, inClause)
+
+	rows, err := r.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			att        Attachment
+			waveform   sql.NullString
+			width      sql.NullInt64
+			height     sql.NullInt64
+			durationMs sql.NullInt64
+		)
+		if err := rows.Scan(
+			&att.ID, &att.MessageID, &att.FileName, &att.FileSize, &att.ContentType, &att.FilePath,
+			&waveform, &width, &height, &durationMs, &att.ThumbURL, &att.MediumURL, &att.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if waveform.Valid && waveform.String != "" {
+			if err := json.Unmarshal([]byte(waveform.String), &att.Waveform); err != nil {
+				return nil, err
+			}
+		}
+		att.Width = int(width.Int64)
+		att.Height = int(height.Int64)
+		att.DurationMs = durationMs.Int64
+		result[att.MessageID] = append(result[att.MessageID], att)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
 // ==========================
 // Reactions
 // ==========================
@@ -440,6 +845,7 @@ type ReactionSummaryItem struct {
 	Reaction    string `json:"reaction"`
 	Count       int    `json:"count"`
 	ReactedByMe bool   `json:"reacted_by_me"`
+	Emoji       *Emoji `json:"emoji,omitempty"` // metadata từ catalog (nil = reaction cũ ngoài catalog)
 }
 
 type ReactionUserItem struct {
@@ -456,12 +862,21 @@ type ReactionUserItem struct {
 
 // ToggleReaction: nếu chưa có -> insert (added=true)
 // nếu đã có -> delete (added=false)
+// reaction phải có trong catalog reaction_emojis (ErrReactionNotAllowed)
 func (r *Repository) ToggleReaction(ctx context.Context, messageID, userID int64, reaction string) (added bool, err error) {
 	reaction = strings.TrimSpace(reaction)
 	if messageID <= 0 || userID <= 0 || reaction == "" {
 		return false, errors.New("invalid input")
 	}
 
+	ok, err := r.IsReactionAllowed(ctx, reaction)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, ErrReactionNotAllowed
+	}
+
 	// INSERT IGNORE để tránh duplicate theo unique(message_id,user_id,reaction)
 	res, err := r.DB.ExecContext(ctx, `
 		INSERT IGNORE INTO message_reactions (message_id, user_id, reaction)
@@ -519,13 +934,15 @@ func (r *Repository) GetReactionSummary(ctx context.Context, messageID, viewerUs
 
 	rows, err := r.DB.QueryContext(ctx, `
 		SELECT
-			reaction,
+			mr.reaction,
 			COUNT(*) AS cnt,
-			(SUM(user_id = ?) > 0) AS reacted_by_me
-		FROM message_reactions
-		WHERE message_id = ?
-		GROUP BY reaction
-		ORDER BY cnt DESC, reaction ASC
+			(SUM(mr.user_id = ?) > 0) AS reacted_by_me,
+			`+reactionEmojiColumns+`
+		FROM message_reactions mr
+		LEFT JOIN reaction_emojis e ON e.code = mr.reaction
+		WHERE mr.message_id = ?
+		GROUP BY mr.reaction
+		ORDER BY cnt DESC, mr.reaction ASC
 	`, viewerUserID, messageID)
 	if err != nil {
 		return nil, err
@@ -536,10 +953,12 @@ func (r *Repository) GetReactionSummary(ctx context.Context, messageID, viewerUs
 	for rows.Next() {
 		var it ReactionSummaryItem
 		var reactedByMeBoolInt int // MySQL trả 0/1
-		if err := rows.Scan(&it.Reaction, &it.Count, &reactedByMeBoolInt); err != nil {
+		var ej emojiJoin
+		if err := rows.Scan(append([]any{&it.Reaction, &it.Count, &reactedByMeBoolInt}, ej.dest()...)...); err != nil {
 			return nil, err
 		}
 		it.ReactedByMe = reactedByMeBoolInt == 1
+		it.Emoji = ej.emoji(it.Reaction)
 		out = append(out, it)
 	}
 	if err := rows.Err(); err != nil {
@@ -568,15 +987,17 @@ func (r *Repository) GetReactionSummaryBatch(ctx context.Context, messageIDs []i
 
 	q := fmt.Sprintf(`
 		SELECT
-			message_id,
-			reaction,
+			mr.message_id,
+			mr.reaction,
 			COUNT(*) AS cnt,
-			(SUM(user_id = ?) > 0) AS reacted_by_me
-		FROM message_reactions
-		WHERE message_id IN (%s)
-		GROUP BY message_id, reaction
-		ORDER BY message_id ASC, cnt DESC, reaction ASC
-	`, inClause)
+			(SUM(mr.user_id = ?) > 0) AS reacted_by_me,
+			%s
+		FROM message_reactions mr
+		LEFT JOIN reaction_emojis e ON e.code = mr.reaction
+		WHERE mr.message_id IN (%s)
+		GROUP BY mr.message_id, mr.reaction
+		ORDER BY mr.message_id ASC, cnt DESC, mr.reaction ASC
+	`, reactionEmojiColumns, inClause)
 
 	rows, err := r.DB.QueryContext(ctx, q, queryArgs...)
 	if err != nil {
@@ -588,10 +1009,12 @@ func (r *Repository) GetReactionSummaryBatch(ctx context.Context, messageIDs []i
 		var messageID int64
 		var it ReactionSummaryItem
 		var reactedByMeBoolInt int
-		if err := rows.Scan(&messageID, &it.Reaction, &it.Count, &reactedByMeBoolInt); err != nil {
+		var ej emojiJoin
+		if err := rows.Scan(append([]any{&messageID, &it.Reaction, &it.Count, &reactedByMeBoolInt}, ej.dest()...)...); err != nil {
 			return nil, err
 		}
 		it.ReactedByMe = reactedByMeBoolInt == 1
+		it.Emoji = ej.emoji(it.Reaction)
 		result[messageID] = append(result[messageID], it)
 	}
 	if err := rows.Err(); err != nil {
@@ -605,13 +1028,44 @@ func (r *Repository) GetReactionSummaryBatch(ctx context.Context, messageIDs []i
 // LIST USERS REACTED (DETAIL VIEW)
 // =========================
 
-func (r *Repository) ListReactionsByMessage(ctx context.Context, messageID int64) ([]ReactionUserItem, error) {
+const (
+	DefaultReactionUsersLimit = 50
+	MaxReactionUsersLimit     = 200
+)
+
+var ErrInvalidReactionCursor = errors.New("invalid reaction cursor")
+
+// EncodeReactionCursor: next_cursor opaque (message_reactions.id cuối trang)
+func EncodeReactionCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func DecodeReactionCursor(token string) (int64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, ErrInvalidReactionCursor
+	}
+	id, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidReactionCursor
+	}
+	return id, nil
+}
+
+// ListReactionsByMessage: 1 trang người đã react, react trước lên trước
+// reaction rỗng = mọi reaction; afterID = cursor trang trước (0 = trang đầu), next = 0 nếu hết
+func (r *Repository) ListReactionsByMessage(ctx context.Context, messageID int64, reaction string, afterID int64, limit int) ([]ReactionUserItem, int64, error) {
 	if messageID <= 0 {
-		return nil, errors.New("invalid message id")
+		return nil, 0, errors.New("invalid message id")
+	}
+	if limit <= 0 {
+		limit = DefaultReactionUsersLimit
 	}
+	limit = min(limit, MaxReactionUsersLimit)
 
 	rows, err := r.DB.QueryContext(ctx, `
 		SELECT
+			mr.id,
 			mr.user_id,
 			COALESCE(u.full_name, u.username) AS full_name,
 			u.avatar_url,
@@ -619,26 +1073,33 @@ func (r *Repository) ListReactionsByMessage(ctx context.Context, messageID int64
 			mr.created_at
 		FROM message_reactions mr
 		JOIN users u ON u.id = mr.user_id
-		WHERE mr.message_id = ?
-		ORDER BY mr.created_at ASC, mr.id ASC
-	`, messageID)
+		WHERE mr.message_id = ? AND (? = '' OR mr.reaction = ?) AND mr.id > ?
+		ORDER BY mr.id ASC
+		LIMIT ?
+	`, messageID, reaction, reaction, afterID, limit+1)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
-	var out []ReactionUserItem
+	out := []ReactionUserItem{}
+	var lastID, next int64
 	for rows.Next() {
+		if len(out) == limit {
+			// còn dòng thứ limit+1 -> có trang sau
+			next = lastID
+			break
+		}
 		var it ReactionUserItem
-		if err := rows.Scan(&it.UserID, &it.FullName, &it.AvatarURL, &it.Reaction, &it.CreatedAt); err != nil {
-			return nil, err
+		if err := rows.Scan(&lastID, &it.UserID, &it.FullName, &it.AvatarURL, &it.Reaction, &it.CreatedAt); err != nil {
+			return nil, 0, err
 		}
 		out = append(out, it)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	return out, nil
+	return out, next, nil
 }
 
 // =========================
@@ -689,6 +1150,132 @@ func (r *Repository) GetMessageRoomID(ctx context.Context, messageID int64) (int
 	return roomID, nil
 }
 
+// GetRoomIDByMediaURL: map media URL (/static/chat_uploads/xxx) -> room được xem file
+// file có trong chat_uploads: room lúc upload (tin dán lại URL ở room khác không đổi quyền xem)
+// upload cũ trước khi có bảng / file sinh thêm (poster...): room của message chứa nó
+// (message ảnh cũ lưu URL trong content, message mới lưu trong media_url -> check cả 2)
+// emoji tuỳ chỉnh (room_id = 0) không thuộc room nào -> ErrMessageNotFound
+func (r *Repository) GetRoomIDByMediaURL(ctx context.Context, mediaURL string) (int64, error) {
+	mediaURL = strings.TrimSpace(mediaURL)
+	if mediaURL == "" {
+		return 0, ErrMessageNotFound
+	}
+
+	var roomID int64
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT room_id FROM (
+			SELECT 0 AS prio, 0 AS id, room_id
+			FROM chat_uploads
+			WHERE file_name = ? AND room_id > 0
+			UNION ALL
+			SELECT 1, id, room_id
+			FROM messages
+			WHERE media_url = ?
+			   OR media_poster_url = ?
+			   OR (message_type IN ('image','file') AND content = ?)
+			UNION ALL
+			SELECT 1, m.id, m.room_id
+			FROM attachments a
+			JOIN messages m ON m.id = a.message_id
+			WHERE a.file_path = ? OR a.thumb_url = ? OR a.medium_url = ?
+		) t
+		ORDER BY prio ASC, id ASC
+		LIMIT 1
+	`, strings.TrimPrefix(mediaURL, "/static/chat_uploads/"),
+		mediaURL, mediaURL, mediaURL, mediaURL, mediaURL, mediaURL).Scan(&roomID)
+	if err == sql.ErrNoRows {
+		return 0, ErrMessageNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return roomID, nil
+}
+
+// BlockedMessage: tin bị chặn vì file đính kèm bị cách ly
+type BlockedMessage struct {
+	ID       int64
+	RoomID   int64
+	SenderID int64
+}
+
+// BlockMessagesByMediaURL: chặn mọi tin trỏ tới mediaURL (content / media_url / attachment)
+// chỉ trả tin vừa bị chặn lần này (tin đã chặn trước đó bỏ qua)
+func (r *Repository) BlockMessagesByMediaURL(ctx context.Context, mediaURL, reason string) ([]BlockedMessage, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT id, room_id, sender_id
+		FROM messages
+		WHERE blocked_at IS NULL
+		  AND id IN (
+			SELECT id FROM messages
+			WHERE media_url = ?
+			   OR (message_type IN ('image','file','audio') AND content = ?)
+			UNION
+			SELECT message_id FROM attachments
+			WHERE file_path = ? OR thumb_url = ? OR medium_url = ?
+		  )
+	`, mediaURL, mediaURL, mediaURL, mediaURL, mediaURL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []BlockedMessage
+	for rows.Next() {
+		var m BlockedMessage
+		if err := rows.Scan(&m.ID, &m.RoomID, &m.SenderID); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, m := range out {
+		if _, err := r.DB.ExecContext(ctx, `
+			UPDATE messages SET blocked_at = NOW(), blocked_reason = ? WHERE id = ? AND blocked_at IS NULL
+		`, nullIfEmpty(reason), m.ID); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// UpdateMessageMedia: ghi kết quả xử lý media (transcode, thumbnail...) vào message
+func (r *Repository) UpdateMessageMedia(ctx context.Context, messageID int64, mediaURL, mediaMIME string, mediaSize int64, posterURL string) error {
+	if messageID <= 0 {
+		return errors.New("invalid message id")
+	}
+
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE messages
+		SET media_url = ?,
+		    media_mime = ?,
+		    media_size = ?,
+		    media_poster_url = ?
+		WHERE id = ?
+	`, nullIfEmpty(mediaURL), nullIfEmpty(mediaMIME), mediaSize, nullIfEmpty(posterURL), messageID)
+	return err
+}
+
+// UpdateMessageAudio: voice message -> media_url / mime / size + độ dài (ms)
+func (r *Repository) UpdateMessageAudio(ctx context.Context, messageID int64, mediaURL, mediaMIME string, mediaSize, durationMs int64) error {
+	if messageID <= 0 {
+		return errors.New("invalid message id")
+	}
+
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE messages
+		SET media_url = ?,
+		    media_mime = ?,
+		    media_size = ?,
+		    media_duration_ms = ?
+		WHERE id = ?
+	`, nullIfEmpty(mediaURL), nullIfEmpty(mediaMIME), mediaSize, nullIfZero(durationMs), messageID)
+	return err
+}
+
 // ========== RECEIPTS TYPES ==========
 
 type ReceiptStatus string
@@ -716,21 +1303,40 @@ type MessageSeenSummary struct {
 
 // ========== UPSERT HELPERS ==========
 
-func (r *Repository) SetDelivered(ctx context.Context, roomID, messageID, userID int64) error {
+// SetDelivered: client ack message_created qua WS -> dời watermark delivered của user (không lùi)
+// advanced=false nếu watermark đã >= messageID (ack trùng / trễ) hoặc user không còn trong room
+func (r *Repository) SetDelivered(ctx context.Context, roomID, messageID, userID int64) (advanced bool, err error) {
 	if roomID <= 0 || messageID <= 0 || userID <= 0 {
-		return errors.New("invalid input")
+		return false, errors.New("invalid input")
 	}
 
-	// ✅ nếu đã seen rồi thì KHÔNG downgrade về delivered
-	_, err := r.DB.ExecContext(ctx, `
-		INSERT INTO message_receipts (room_id, message_id, user_id, status, seen_at)
-		VALUES (?, ?, ?, 'delivered', NOW())
-		ON DUPLICATE KEY UPDATE
-			status = IF(status = 'seen', 'seen', 'delivered'),
-			seen_at = IF(status = 'seen', seen_at, GREATEST(seen_at, VALUES(seen_at)))
-	`, roomID, messageID, userID)
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE room_members
+		SET last_delivered_message_id = ?
+		WHERE room_id = ? AND user_id = ?
+		  AND COALESCE(last_delivered_message_id, 0) < ?
+	`, messageID, roomID, userID, messageID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
 
-	return err
+// GetReceiptWatermarks: watermark delivered / seen xa nhất của các member khác trong room
+// tin của viewer có id <= seenUpTo -> seen, <= deliveredUpTo -> delivered, còn lại sent
+func (r *Repository) GetReceiptWatermarks(ctx context.Context, roomID, viewerUserID int64) (deliveredUpTo, seenUpTo int64, err error) {
+	if roomID <= 0 || viewerUserID <= 0 {
+		return 0, 0, errors.New("invalid input")
+	}
+
+	err = r.DB.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(GREATEST(COALESCE(last_delivered_message_id, 0), COALESCE(last_seen_message_id, 0))), 0),
+		       COALESCE(MAX(last_seen_message_id), 0)
+		FROM room_members
+		WHERE room_id = ? AND user_id <> ?
+	`, roomID, viewerUserID).Scan(&deliveredUpTo, &seenUpTo)
+	return
 }
 
 func (r *Repository) SetSeen(ctx context.Context, roomID, messageID, userID int64) error {
@@ -750,30 +1356,86 @@ func (r *Repository) SetSeen(ctx context.Context, roomID, messageID, userID int6
 }
 
 // ========== BULK MARK SEEN (UP TO MESSAGE) ==========
-// MarkRoomSeenUpTo: set seen cho tất cả messages trong room có id <= upToMessageID
-// - default: skip message do chính user gửi (thường UI không cần receipt cho msg của mình)
+
+// room <= ngưỡng này mới ghi receipt từng tin (cho "Seen by ..." có seen_at riêng)
+// room lớn chỉ dời watermark room_members.last_seen_message_id -> 1 UPDATE / lần seen
+const ReceiptDetailMaxMembers = 50
+
+// MarkRoomSeenUpTo: dời watermark seen của user tới upToMessageID (không lùi)
+// - room nhỏ: thêm receipt 'seen' cho đoạn mới (watermark cũ, upTo], skip tin của chính user
+// - affected = số tin (của người khác) vừa được seen
 func (r *Repository) MarkRoomSeenUpTo(ctx context.Context, roomID, userID, upToMessageID int64) (affected int64, err error) {
 	if roomID <= 0 || userID <= 0 || upToMessageID <= 0 {
 		return 0, errors.New("invalid input")
 	}
 
-	res, err := r.DB.ExecContext(ctx, `
-		INSERT INTO message_receipts (room_id, message_id, user_id, status, seen_at)
-		SELECT m.room_id, m.id, ?, 'seen', NOW()
-		FROM messages m
-		WHERE m.room_id = ?
-		  AND m.id <= ?
-		  AND m.sender_id <> ?
-		ON DUPLICATE KEY UPDATE
-			status = 'seen',
-			seen_at = GREATEST(seen_at, VALUES(seen_at))
-	`, userID, roomID, upToMessageID, userID)
+	tx, err := r.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, err
 	}
+	defer tx.Rollback()
+
+	var prev sql.NullInt64
+	err = tx.QueryRowContext(ctx, `
+		SELECT last_seen_message_id
+		FROM room_members
+		WHERE room_id = ? AND user_id = ?
+		FOR UPDATE
+	`, roomID, userID).Scan(&prev)
+	if err == sql.ErrNoRows {
+		return 0, nil // không phải member
+	}
+	if err != nil {
+		return 0, err
+	}
+	from := prev.Int64
+	if from >= upToMessageID {
+		return 0, tx.Commit()
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE room_members
+		SET last_seen_message_id = ?, last_seen_at = NOW()
+		WHERE room_id = ? AND user_id = ?
+	`, upToMessageID, roomID, userID); err != nil {
+		return 0, err
+	}
 
-	ra, _ := res.RowsAffected()
-	return ra, nil
+	var members int
+	if err := tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM room_members WHERE room_id = ?`, roomID,
+	).Scan(&members); err != nil {
+		return 0, err
+	}
+
+	if members <= ReceiptDetailMaxMembers {
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO message_receipts (room_id, message_id, user_id, status, seen_at)
+			SELECT m.room_id, m.id, ?, 'seen', NOW()
+			FROM messages m
+			WHERE m.room_id = ?
+			  AND m.id > ?
+			  AND m.id <= ?
+			  AND m.sender_id <> ?
+			ON DUPLICATE KEY UPDATE
+				status = 'seen',
+				seen_at = GREATEST(seen_at, VALUES(seen_at))
+		`, userID, roomID, from, upToMessageID, userID)
+		if err != nil {
+			return 0, err
+		}
+		affected, _ = res.RowsAffected()
+	} else {
+		if err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*)
+			FROM messages
+			WHERE room_id = ? AND id > ? AND id <= ? AND sender_id <> ?
+		`, roomID, from, upToMessageID, userID).Scan(&affected); err != nil {
+			return 0, err
+		}
+	}
+
+	return affected, tx.Commit()
 }
 
 // ========== QUERIES ==========
@@ -803,34 +1465,25 @@ func (r *Repository) GetReceiptStatus(ctx context.Context, messageID, userID int
 	return ReceiptStatus(st), &tt, nil
 }
 
-// CountSeenByMessage: đếm số người seen 1 message (thường exclude sender)
+// CountSeenByMessage: đếm số member đã seen 1 message (watermark >= message id, thường exclude sender)
 func (r *Repository) CountSeenByMessage(ctx context.Context, messageID int64, excludeUserID int64) (int64, error) {
 	if messageID <= 0 {
 		return 0, errors.New("invalid input")
 	}
 
 	var c int64
-	if excludeUserID > 0 {
-		err := r.DB.QueryRowContext(ctx, `
-			SELECT COUNT(*)
-			FROM message_receipts
-			WHERE message_id = ?
-			  AND status = 'seen'
-			  AND user_id <> ?
-		`, messageID, excludeUserID).Scan(&c)
-		return c, err
-	}
-
 	err := r.DB.QueryRowContext(ctx, `
 		SELECT COUNT(*)
-		FROM message_receipts
-		WHERE message_id = ?
-		  AND status = 'seen'
-	`, messageID).Scan(&c)
+		FROM messages m
+		JOIN room_members rm ON rm.room_id = m.room_id
+		WHERE m.id = ?
+		  AND rm.last_seen_message_id >= m.id
+		  AND (? = 0 OR rm.user_id <> ?)
+	`, messageID, excludeUserID, excludeUserID).Scan(&c)
 	return c, err
 }
 
-// HasSeenMessage: user đã seen message chưa
+// HasSeenMessage: user đã seen message chưa (theo watermark)
 func (r *Repository) HasSeenMessage(ctx context.Context, messageID, userID int64) (bool, error) {
 	if messageID <= 0 || userID <= 0 {
 		return false, errors.New("invalid input")
@@ -840,15 +1493,16 @@ func (r *Repository) HasSeenMessage(ctx context.Context, messageID, userID int64
 	err := r.DB.QueryRowContext(ctx, `
 		SELECT EXISTS(
 			SELECT 1
-			FROM message_receipts
-			WHERE message_id = ? AND user_id = ? AND status = 'seen'
-			LIMIT 1
+			FROM messages m
+			JOIN room_members rm ON rm.room_id = m.room_id AND rm.user_id = ?
+			WHERE m.id = ? AND rm.last_seen_message_id >= m.id
 		)
-	`, messageID, userID).Scan(&ok)
+	`, userID, messageID).Scan(&ok)
 	return ok == 1, err
 }
 
 // ListSeenUsersByMessage: list người đã seen message (kèm full_name/avatar_url)
+// seen_at lấy từ receipt nếu có (room nhỏ), không thì lấy last_seen_at của watermark
 func (r *Repository) ListSeenUsersByMessage(ctx context.Context, messageID int64, excludeUserID int64, limit int) ([]SeenUser, error) {
 	if messageID <= 0 {
 		return nil, errors.New("invalid input")
@@ -858,16 +1512,18 @@ func (r *Repository) ListSeenUsersByMessage(ctx context.Context, messageID int64
 	}
 
 	rows, err := r.DB.QueryContext(ctx, `
-		SELECT r.user_id,
+		SELECT rm.user_id,
 		       COALESCE(u.full_name, u.username) AS full_name,
 		       COALESCE(u.avatar_url, '') AS avatar_url,
-		       r.seen_at
-		FROM message_receipts r
-		JOIN users u ON u.id = r.user_id
-		WHERE r.message_id = ?
-		  AND r.status = 'seen'
-		  AND (? = 0 OR r.user_id <> ?)
-		ORDER BY r.seen_at DESC
+		       COALESCE(r.seen_at, rm.last_seen_at, m.created_at) AS seen_at
+		FROM messages m
+		JOIN room_members rm ON rm.room_id = m.room_id AND rm.last_seen_message_id >= m.id
+		JOIN users u ON u.id = rm.user_id
+		LEFT JOIN message_receipts r
+		       ON r.message_id = m.id AND r.user_id = rm.user_id AND r.status = 'seen'
+		WHERE m.id = ?
+		  AND (? = 0 OR rm.user_id <> ?)
+		ORDER BY seen_at DESC
 		LIMIT ?
 	`, messageID, excludeUserID, excludeUserID, limit)
 	if err != nil {
@@ -886,7 +1542,7 @@ func (r *Repository) ListSeenUsersByMessage(ctx context.Context, messageID int64
 	return out, rows.Err()
 }
 
-// GetRoomLastSeenMessageID: last message_id mà user đã seen trong room (dựa trên receipts)
+// GetRoomLastSeenMessageID: watermark seen của user trong room (room_members.last_seen_message_id)
 func (r *Repository) GetRoomLastSeenMessageID(ctx context.Context, roomID, userID int64) (int64, *time.Time, error) {
 	if roomID <= 0 || userID <= 0 {
 		return 0, nil, errors.New("invalid input")
@@ -895,14 +1551,14 @@ func (r *Repository) GetRoomLastSeenMessageID(ctx context.Context, roomID, userI
 	var lastID sql.NullInt64
 	var lastAt sql.NullTime
 	err := r.DB.QueryRowContext(ctx, `
-		SELECT MAX(message_id) AS last_message_id,
-		       MAX(seen_at)    AS last_seen_at
-		FROM message_receipts
-		WHERE room_id = ?
-		  AND user_id = ?
-		  AND status = 'seen'
+		SELECT last_seen_message_id, last_seen_at
+		FROM room_members
+		WHERE room_id = ? AND user_id = ?
 	`, roomID, userID).Scan(&lastID, &lastAt)
 
+	if err == sql.ErrNoRows {
+		return 0, nil, nil
+	}
 	if err != nil {
 		return 0, nil, err
 	}
@@ -923,46 +1579,44 @@ func (r *Repository) GetMessageSeenSummary(ctx context.Context, messageID, meUse
 		return MessageSeenSummary{}, errors.New("invalid input")
 	}
 
-	var seenCount int64
-	var seenByMe int64
-
 	// count seen (exclude sender nếu truyền excludeUserID)
-	if excludeUserID > 0 {
-		if err := r.DB.QueryRowContext(ctx, `
-			SELECT COUNT(*)
-			FROM message_receipts
-			WHERE message_id = ? AND status = 'seen' AND user_id <> ?
-		`, messageID, excludeUserID).Scan(&seenCount); err != nil {
-			return MessageSeenSummary{}, err
-		}
-	} else {
-		if err := r.DB.QueryRowContext(ctx, `
-			SELECT COUNT(*)
-			FROM message_receipts
-			WHERE message_id = ? AND status = 'seen'
-		`, messageID).Scan(&seenCount); err != nil {
-			return MessageSeenSummary{}, err
-		}
+	seenCount, err := r.CountSeenByMessage(ctx, messageID, excludeUserID)
+	if err != nil {
+		return MessageSeenSummary{}, err
 	}
-
-	if err := r.DB.QueryRowContext(ctx, `
-		SELECT EXISTS(
-			SELECT 1 FROM message_receipts
-			WHERE message_id = ? AND user_id = ? AND status = 'seen'
-			LIMIT 1
-		)
-	`, messageID, meUserID).Scan(&seenByMe); err != nil {
+	seenByMe, err := r.HasSeenMessage(ctx, messageID, meUserID)
+	if err != nil {
 		return MessageSeenSummary{}, err
 	}
 
 	return MessageSeenSummary{
 		MessageID: messageID,
 		SeenCount: seenCount,
-		SeenByMe:  seenByMe == 1,
+		SeenByMe:  seenByMe,
 	}, nil
 }
 
 // internal/chat/repository_receipts.go (hoặc repository_messages.go)
+// GetMessageButtons: room, người gửi và các nút của 1 tin (ErrMessageNotFound nếu không có)
+func (r *Repository) GetMessageButtons(ctx context.Context, messageID int64) (roomID, senderID int64, buttons []Button, err error) {
+	var raw []byte
+	err = r.DB.QueryRowContext(ctx, `
+		SELECT room_id, sender_id, buttons FROM messages WHERE id = ?
+	`, messageID).Scan(&roomID, &senderID, &raw)
+	if err == sql.ErrNoRows {
+		return 0, 0, nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &buttons); err != nil {
+			return 0, 0, nil, err
+		}
+	}
+	return roomID, senderID, buttons, nil
+}
+
 func (r *Repository) GetMessageRoomAndSender(ctx context.Context, messageID int64) (roomID int64, senderID int64, err error) {
 	err = r.DB.QueryRowContext(ctx, `SELECT room_id, sender_id FROM messages WHERE id=? LIMIT 1`, messageID).
 		Scan(&roomID, &senderID)
@@ -999,70 +1653,125 @@ func (r *Repository) ListRoomMemberUserIDsExcept(ctx context.Context, roomID, ex
 	return ids, rows.Err()
 }
 
-// ===============================
-// 2) Unread count (DB truth)
-// ===============================
+// ==============================
+// Mentions
+// ==============================
 
-// Unread of 1 room for 1 user
-// rule: messages.created_at > rm.last_seen_at AND sender_id != user AND message_type != 'system'
-func (r *Repository) GetUnreadCount(ctx context.Context, roomID, userID int64) (int64, error) {
-	var lastSeen sql.NullTime
-	err := r.DB.QueryRowContext(ctx, `
-		SELECT last_seen_at
-		FROM room_members
-		WHERE room_id = ? AND user_id = ?
-	`, roomID, userID).Scan(&lastSeen)
+// mức thông báo của 1 member trong room
+const (
+	NotifyLevelAll      = "all"
+	NotifyLevelMentions = "mentions" // badge/unread push chỉ tính tin nhắc đến mình
+)
+
+var mentionRe = regexp.MustCompile(`(?:^|[^\w@])@([\p{L}\p{N}_.\-]+)`)
+
+// ExtractMentions: "@alice hi @bob." -> [alice bob] (không trùng, lowercase)
+func ExtractMentions(content string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, m := range mentionRe.FindAllStringSubmatch(content, -1) {
+		name := strings.ToLower(strings.TrimRight(m[1], ".-"))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		out = append(out, name)
+	}
+	return out
+}
+
+// RecordMentions: lưu user được @ (chỉ member của room, trừ người gửi), trả user ids
+func (r *Repository) RecordMentions(ctx context.Context, messageID, roomID, senderID int64, usernames []string) ([]int64, error) {
+	if len(usernames) == 0 {
+		return nil, nil
+	}
+
+	ph := strings.TrimRight(strings.Repeat("?,", len(usernames)), ",")
+	args := []any{roomID, senderID}
+	for _, u := range usernames {
+		args = append(args, u)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT u.id
+		FROM room_members rm
+		JOIN users u ON u.id = rm.user_id
+		WHERE rm.room_id = ? AND rm.user_id <> ? AND u.username IN (`+ph+`)
+	`, args...)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	// If never seen -> treat as "very old" => count all non-system messages not from me
-	seenAt := time.Unix(0, 0)
-	if lastSeen.Valid {
-		seenAt = lastSeen.Time
+	for _, uid := range ids {
+		if _, err := r.DB.ExecContext(ctx, `
+			INSERT IGNORE INTO message_mentions (message_id, room_id, user_id) VALUES (?, ?, ?)
+		`, messageID, roomID, uid); err != nil {
+			return nil, err
+		}
 	}
+	return ids, nil
+}
 
-	var cnt int64
-	err = r.DB.QueryRowContext(ctx, `
-		SELECT COUNT(*)
-		FROM messages
-		WHERE room_id = ?
-		  AND message_type <> 'system'
-		  AND sender_id <> ?
-		  AND created_at > ?
-	`, roomID, userID, seenAt).Scan(&cnt)
-	return cnt, err
+// GetNotifyLevels: user_id -> notify_level của các member trong room
+func (r *Repository) GetNotifyLevels(ctx context.Context, roomID int64) (map[int64]string, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT user_id, notify_level FROM room_members WHERE room_id = ?
+	`, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[int64]string)
+	for rows.Next() {
+		var uid int64
+		var level string
+		if err := rows.Scan(&uid, &level); err != nil {
+			return nil, err
+		}
+		out[uid] = level
+	}
+	return out, rows.Err()
 }
 
-// Unread counts for sidebar: return map room_id -> unread_count
-func (r *Repository) GetUnreadCountsByRooms(ctx context.Context, userID int64) (map[int64]int64, error) {
+// GetNotifyLevelsByUser: room_id -> notify_level (chỉ room khác mặc định)
+func (r *Repository) GetNotifyLevelsByUser(ctx context.Context, userID int64) (map[int64]string, error) {
 	rows, err := r.DB.QueryContext(ctx, `
-		SELECT
-			rm.room_id,
-			COUNT(m.id) AS unread_count
-		FROM room_members rm
-		LEFT JOIN messages m
-		  ON m.room_id = rm.room_id
-		 AND m.message_type <> 'system'
-		 AND m.sender_id <> rm.user_id
-		 AND m.created_at > COALESCE(rm.last_seen_at, '1970-01-01 00:00:00')
-		WHERE rm.user_id = ?
-		GROUP BY rm.room_id
-		HAVING COUNT(m.id) > 0
-
-	`, userID)
+		SELECT room_id, notify_level FROM room_members WHERE user_id = ? AND notify_level <> ?
+	`, userID, NotifyLevelAll)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	out := make(map[int64]int64)
+	out := make(map[int64]string)
 	for rows.Next() {
-		var roomID, cnt int64
-		if err := rows.Scan(&roomID, &cnt); err != nil {
+		var roomID int64
+		var level string
+		if err := rows.Scan(&roomID, &level); err != nil {
 			return nil, err
 		}
-		out[roomID] = cnt
+		out[roomID] = level
 	}
 	return out, rows.Err()
 }
+
+func (r *Repository) SetNotifyLevel(ctx context.Context, roomID, userID int64, level string) error {
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE room_members SET notify_level = ? WHERE room_id = ? AND user_id = ?
+	`, level, roomID, userID)
+	return err
+}
@@ -0,0 +1,272 @@
+package config
+
+import (
+	"cronhustler/api-service/internal/linkpreview"
+	"cronhustler/api-service/internal/lockout"
+	"cronhustler/api-service/internal/quota"
+	"cronhustler/api-service/internal/summary"
+	"cronhustler/api-service/internal/wsjournal"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MySQLConfig: MYSQL_* (dùng chung cho server và subcommand backup/restore/seed)
+type MySQLConfig struct {
+	User     string
+	Password string
+	Host     string
+	Port     string
+	Database string
+}
+
+// DSN cho go-sql-driver/mysql
+func (m MySQLConfig) DSN() string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true&charset=utf8mb4&loc=Local",
+		m.User, m.Password, m.Host, m.Port, m.Database)
+}
+
+// Config: cấu hình api-service, đọc từ env (file YAML chỉ lấp các key env chưa set)
+// Các driver có bộ key riêng (STORAGE_*, S3_*, MAIL_*, SMS_*, LLM_*, PUBSUB_*, SCAN_*) vẫn tự đọc env
+// trong package của nó, ở đây chỉ giữ tên driver để in ra lúc khởi động
+type Config struct {
+	MySQL          MySQLConfig
+	MigrateOnStart bool // MIGRATE_ON_START=0 -> chỉ chạy bằng `server migrate`
+
+	JWTSecret     string // GO_SECRET_KEY
+	Addr          string // BASE_URL
+	AppPublicURL  string
+	AvatarDir     string
+	ChatUploadDir string
+	MediaBaseURL  string // MEDIA_CDN_BASE_URL, rỗng = path tương đối
+	StorageDriver string
+
+	FFmpegPath           string
+	VideoTranscode       bool
+	MediaJanitorInterval time.Duration // 0 = tắt
+	MediaOrphanGrace     time.Duration // 0 = mặc định của janitor
+	ScanProvider         string        // rỗng = không quét virus file upload
+	ScanQuarantineDir    string        // giữ file bị cách ly để admin xem lại
+	ImageStripMetadata   bool          // bỏ EXIF (GPS...) + xoay ảnh theo orientation khi upload
+
+	MailTemplateDir     string
+	EmailDigestInterval time.Duration
+
+	ReminderInterval   time.Duration
+	JobPollInterval    time.Duration
+	RoomTTLInterval    time.Duration
+	ExportDir          string
+	ExportTTL          time.Duration
+	ExportPollInterval time.Duration
+
+	Quota        quota.Limits
+	LoginLockout lockout.Limits
+
+	SMSCountryCode     string
+	ProvisioningToken  string   // rỗng = tắt /provisioning/*
+	ServiceTokenSecret string   // rỗng = tắt service token
+	JobTokenOrigins    []string // origin job http_request được nhận service token (URL public của API)
+	PasswordHashCost   int      // bcrypt cost, 0 = mặc định
+	OpenAPIDocs        bool     // false = tắt Swagger UI (/openapi.json vẫn bật)
+	LLMSummaryPerHour  int
+
+	LinkPreviewEnabled bool
+	LinkPreviewTimeout time.Duration
+
+	WSJournalTTL     time.Duration // event giữ để client WS resume (?last_seq=)
+	WSAllowedOrigins []string      // Origin được mở WS ngoài cùng host / APP_PUBLIC_URL, "*" = mọi origin
+
+	HTTPReadTimeout  time.Duration
+	HTTPWriteTimeout time.Duration
+	HTTPIdleTimeout  time.Duration
+	ShutdownTimeout  time.Duration
+}
+
+// Load: ApplyFile(path) rồi FromEnv
+func Load(path string) (*Config, error) {
+	if err := ApplyFile(path); err != nil {
+		return nil, err
+	}
+	return FromEnv()
+}
+
+// FromEnv: đọc + validate, gom hết lỗi để báo 1 lần
+func FromEnv() (*Config, error) {
+	e := &envReader{}
+	c := &Config{
+		MySQL:          e.mysql(),
+		MigrateOnStart: os.Getenv("MIGRATE_ON_START") != "0",
+
+		JWTSecret:     os.Getenv("GO_SECRET_KEY"),
+		Addr:          e.str("BASE_URL", ":5555"),
+		AppPublicURL:  strings.TrimRight(os.Getenv("APP_PUBLIC_URL"), "/"),
+		AvatarDir:     e.str("AVATAR_DIR", "./data/user_avatars"),
+		ChatUploadDir: e.str("CHAT_UPLOAD_DIR", "./data/chat_uploads"),
+		MediaBaseURL:  os.Getenv("MEDIA_CDN_BASE_URL"),
+		StorageDriver: e.str("STORAGE_DRIVER", "local"),
+
+		FFmpegPath:           os.Getenv("FFMPEG_PATH"),
+		VideoTranscode:       os.Getenv("VIDEO_TRANSCODE") == "1",
+		MediaJanitorInterval: e.duration("MEDIA_JANITOR_INTERVAL", 0),
+		MediaOrphanGrace:     e.duration("MEDIA_ORPHAN_GRACE", 0),
+		ScanProvider:         os.Getenv("SCAN_PROVIDER"),
+		ScanQuarantineDir:    e.str("SCAN_QUARANTINE_DIR", "./data/quarantine"),
+		ImageStripMetadata:   os.Getenv("IMAGE_STRIP_METADATA") != "0",
+
+		MailTemplateDir:     os.Getenv("MAIL_TEMPLATE_DIR"),
+		EmailDigestInterval: e.duration("EMAIL_DIGEST_INTERVAL", 5*time.Minute),
+
+		ReminderInterval:   e.duration("REMINDER_INTERVAL", 30*time.Second),
+		JobPollInterval:    e.duration("JOB_POLL_INTERVAL", 15*time.Second),
+		RoomTTLInterval:    e.duration("ROOM_TTL_INTERVAL", time.Minute),
+		ExportDir:          e.str("EXPORT_DIR", "./data/exports"),
+		ExportTTL:          e.duration("EXPORT_TTL", 7*24*time.Hour),
+		ExportPollInterval: e.duration("EXPORT_POLL_INTERVAL", 10*time.Second),
+
+		Quota: quota.Limits{
+			MessagesPerMinute: e.count("QUOTA_MESSAGES_PER_MINUTE", quota.DefaultLimits.MessagesPerMinute),
+			MessagesPerDay:    e.count("QUOTA_MESSAGES_PER_DAY", quota.DefaultLimits.MessagesPerDay),
+			RoomsPerDay:       e.count("QUOTA_ROOMS_PER_DAY", quota.DefaultLimits.RoomsPerDay),
+		},
+		LoginLockout: lockout.Limits{
+			MaxUserFailures: e.count("LOGIN_MAX_FAILURES", lockout.DefaultLimits.MaxUserFailures),
+			MaxIPFailures:   e.count("LOGIN_IP_MAX_FAILURES", lockout.DefaultLimits.MaxIPFailures),
+			Window:          e.duration("LOGIN_FAILURE_WINDOW", lockout.DefaultLimits.Window),
+			LockFor:         e.duration("LOGIN_LOCKOUT_DURATION", lockout.DefaultLimits.LockFor),
+		},
+
+		SMSCountryCode:     strings.TrimPrefix(e.str("SMS_DEFAULT_COUNTRY_CODE", "84"), "+"),
+		ProvisioningToken:  os.Getenv("PROVISIONING_TOKEN"),
+		ServiceTokenSecret: os.Getenv("SERVICE_TOKEN_SECRET"),
+		JobTokenOrigins:    e.origins("JOB_SERVICE_TOKEN_ORIGINS"),
+		PasswordHashCost:   e.count("PASSWORD_HASH_COST", 0),
+		OpenAPIDocs:        os.Getenv("OPENAPI_DOCS") != "0",
+		LLMSummaryPerHour:  e.count("LLM_SUMMARY_PER_HOUR", summary.DefaultPerHour),
+
+		LinkPreviewEnabled: os.Getenv("LINK_PREVIEW_ENABLED") != "0",
+		LinkPreviewTimeout: e.duration("LINK_PREVIEW_TIMEOUT", linkpreview.DefaultTimeout),
+
+		WSJournalTTL:     e.duration("WS_JOURNAL_TTL", wsjournal.DefaultTTL),
+		WSAllowedOrigins: e.origins("WS_ALLOWED_ORIGINS"),
+
+		HTTPReadTimeout:  e.duration("HTTP_READ_TIMEOUT", 5*time.Minute), // upload video tới 100MB
+		HTTPWriteTimeout: e.duration("HTTP_WRITE_TIMEOUT", 5*time.Minute),
+		HTTPIdleTimeout:  e.duration("HTTP_IDLE_TIMEOUT", 2*time.Minute),
+		ShutdownTimeout:  e.duration("SHUTDOWN_TIMEOUT", 20*time.Second),
+	}
+	if err := c.validate(); err != nil {
+		e.errs = append(e.errs, err)
+	}
+	if len(e.errs) > 0 {
+		return nil, errors.Join(e.errs...)
+	}
+	return c, nil
+}
+
+// MySQLFromEnv: chỉ phần MYSQL_* (subcommand không cần GO_SECRET_KEY, ...)
+func MySQLFromEnv() (MySQLConfig, error) {
+	e := &envReader{}
+	m := e.mysql()
+	return m, errors.Join(e.errs...)
+}
+
+func (c *Config) validate() error {
+	var errs []error
+	if c.JWTSecret == "" {
+		errs = append(errs, errors.New("GO_SECRET_KEY is required"))
+	}
+	if c.ProvisioningToken != "" && len(c.ProvisioningToken) < 32 {
+		errs = append(errs, errors.New("PROVISIONING_TOKEN must be at least 32 characters"))
+	}
+	if c.ServiceTokenSecret != "" {
+		if len(c.ServiceTokenSecret) < 32 {
+			errs = append(errs, errors.New("SERVICE_TOKEN_SECRET must be at least 32 characters"))
+		}
+		if c.ServiceTokenSecret == c.JWTSecret {
+			errs = append(errs, errors.New("SERVICE_TOKEN_SECRET must differ from GO_SECRET_KEY"))
+		}
+	}
+	if slices.Contains(c.JobTokenOrigins, "*") {
+		errs = append(errs, errors.New("JOB_SERVICE_TOKEN_ORIGINS: \"*\" is not allowed"))
+	}
+	return errors.Join(errs...)
+}
+
+// envReader: đọc env kèm default, lỗi parse gom vào errs thay vì dừng ngay
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) mysql() MySQLConfig {
+	m := MySQLConfig{
+		User:     os.Getenv("MYSQL_USER"),
+		Password: os.Getenv("MYSQL_PASSWORD"),
+		Host:     e.str("MYSQL_HOST", "127.0.0.1"),
+		Port:     e.str("MYSQL_PORT", "3306"),
+		Database: os.Getenv("MYSQL_DATABASE"),
+	}
+	if m.User == "" || m.Database == "" {
+		e.errs = append(e.errs, errors.New("MYSQL_USER and MYSQL_DATABASE are required"))
+	}
+	return m
+}
+
+func (e *envReader) str(key, def string) string {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return def
+}
+
+// duration: rỗng -> def, sai format / <= 0 -> lỗi
+func (e *envReader) duration(key string, def time.Duration) time.Duration {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		e.errs = append(e.errs, fmt.Errorf("%s: invalid duration %q", key, v))
+		return def
+	}
+	return d
+}
+
+// origins: "https://app.example.com, http://localhost:3000" (scheme://host[:port], không path) hoặc "*"
+func (e *envReader) origins(key string) []string {
+	var out []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		v = strings.TrimRight(strings.TrimSpace(v), "/")
+		if v == "" {
+			continue
+		}
+		if v != "*" {
+			u, err := url.Parse(v)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" {
+				e.errs = append(e.errs, fmt.Errorf("%s: invalid origin %q", key, v))
+				continue
+			}
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// count: số nguyên >= 0 (0 thường = không giới hạn)
+func (e *envReader) count(key string, def int) int {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		e.errs = append(e.errs, fmt.Errorf("%s: invalid number %q", key, v))
+		return def
+	}
+	return n
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ApplyFile: đọc file YAML (CONFIG_FILE) rồi set vào env các key chưa có -> env luôn thắng file
+// Chỉ hỗ trợ map lồng nhau dạng key: value, key ghép bằng "_" và viết hoa:
+//
+//	mysql:
+//	  host: db        # -> MYSQL_HOST
+//	go_secret_key: x  # -> GO_SECRET_KEY
+//
+// path rỗng = không dùng file
+func ApplyFile(path string) error {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	defer f.Close()
+
+	values, err := parseYAML(bufio.NewScanner(f))
+	if err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+	for k, v := range values {
+		if _, ok := os.LookupEnv(k); ok {
+			continue
+		}
+		if err := os.Setenv(k, v); err != nil {
+			return fmt.Errorf("config: set %s: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// parseYAML: tập con YAML đủ cho file cấu hình phẳng / lồng map, không hỗ trợ list, multi-line
+func parseYAML(sc *bufio.Scanner) (map[string]string, error) {
+	type level struct {
+		indent int
+		prefix string
+	}
+	out := map[string]string{}
+	var stack []level
+
+	for n := 1; sc.Scan(); n++ {
+		raw := sc.Text()
+		line := strings.TrimRight(stripComment(raw), " \t")
+		if strings.TrimSpace(line) == "" || strings.TrimSpace(line) == "---" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if strings.HasPrefix(strings.TrimLeft(line, " "), "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", n)
+		}
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("line %d: expected key: value", n)
+		}
+		if strings.HasPrefix(strings.TrimSpace(key), "- ") {
+			return nil, fmt.Errorf("line %d: lists are not supported", n)
+		}
+
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		name := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(key), "-", "_"))
+		if len(stack) > 0 {
+			name = stack[len(stack)-1].prefix + "_" + name
+		}
+
+		value = strings.TrimSpace(value)
+		if value == "" {
+			// mở map con
+			stack = append(stack, level{indent: indent, prefix: name})
+			continue
+		}
+		out[name] = unquote(value)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// stripComment: bỏ "# ..." nằm ngoài chuỗi quote
+func stripComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+func unquote(v string) string {
+	if len(v) >= 2 && (v[0] == '"' && v[len(v)-1] == '"' || v[0] == '\'' && v[len(v)-1] == '\'') {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Entries: config hiệu lực theo tên env, secret đã che (chỉ để log lúc khởi động)
+func (c *Config) Entries() [][2]string {
+	return [][2]string{
+		{"MYSQL_USER", c.MySQL.User},
+		{"MYSQL_PASSWORD", mask(c.MySQL.Password)},
+		{"MYSQL_HOST", c.MySQL.Host},
+		{"MYSQL_PORT", c.MySQL.Port},
+		{"MYSQL_DATABASE", c.MySQL.Database},
+		{"MIGRATE_ON_START", strconv.FormatBool(c.MigrateOnStart)},
+		{"GO_SECRET_KEY", mask(c.JWTSecret)},
+		{"BASE_URL", c.Addr},
+		{"APP_PUBLIC_URL", c.AppPublicURL},
+		{"AVATAR_DIR", c.AvatarDir},
+		{"CHAT_UPLOAD_DIR", c.ChatUploadDir},
+		{"MEDIA_CDN_BASE_URL", c.MediaBaseURL},
+		{"STORAGE_DRIVER", c.StorageDriver},
+		{"FFMPEG_PATH", c.FFmpegPath},
+		{"VIDEO_TRANSCODE", strconv.FormatBool(c.VideoTranscode)},
+		{"MEDIA_JANITOR_INTERVAL", c.MediaJanitorInterval.String()},
+		{"MEDIA_ORPHAN_GRACE", c.MediaOrphanGrace.String()},
+		{"SCAN_PROVIDER", c.ScanProvider},
+		{"SCAN_QUARANTINE_DIR", c.ScanQuarantineDir},
+		{"IMAGE_STRIP_METADATA", strconv.FormatBool(c.ImageStripMetadata)},
+		{"MAIL_TEMPLATE_DIR", c.MailTemplateDir},
+		{"EMAIL_DIGEST_INTERVAL", c.EmailDigestInterval.String()},
+		{"REMINDER_INTERVAL", c.ReminderInterval.String()},
+		{"JOB_POLL_INTERVAL", c.JobPollInterval.String()},
+		{"ROOM_TTL_INTERVAL", c.RoomTTLInterval.String()},
+		{"EXPORT_DIR", c.ExportDir},
+		{"EXPORT_TTL", c.ExportTTL.String()},
+		{"EXPORT_POLL_INTERVAL", c.ExportPollInterval.String()},
+		{"QUOTA_MESSAGES_PER_MINUTE", strconv.Itoa(c.Quota.MessagesPerMinute)},
+		{"QUOTA_MESSAGES_PER_DAY", strconv.Itoa(c.Quota.MessagesPerDay)},
+		{"QUOTA_ROOMS_PER_DAY", strconv.Itoa(c.Quota.RoomsPerDay)},
+		{"LOGIN_MAX_FAILURES", strconv.Itoa(c.LoginLockout.MaxUserFailures)},
+		{"LOGIN_IP_MAX_FAILURES", strconv.Itoa(c.LoginLockout.MaxIPFailures)},
+		{"LOGIN_FAILURE_WINDOW", c.LoginLockout.Window.String()},
+		{"LOGIN_LOCKOUT_DURATION", c.LoginLockout.LockFor.String()},
+		{"SMS_DEFAULT_COUNTRY_CODE", c.SMSCountryCode},
+		{"PROVISIONING_TOKEN", mask(c.ProvisioningToken)},
+		{"SERVICE_TOKEN_SECRET", mask(c.ServiceTokenSecret)},
+		{"JOB_SERVICE_TOKEN_ORIGINS", strings.Join(c.JobTokenOrigins, ",")},
+		{"PASSWORD_HASH_COST", strconv.Itoa(c.PasswordHashCost)},
+		{"OPENAPI_DOCS", strconv.FormatBool(c.OpenAPIDocs)},
+		{"LLM_SUMMARY_PER_HOUR", strconv.Itoa(c.LLMSummaryPerHour)},
+		{"LINK_PREVIEW_ENABLED", strconv.FormatBool(c.LinkPreviewEnabled)},
+		{"LINK_PREVIEW_TIMEOUT", c.LinkPreviewTimeout.String()},
+		{"WS_JOURNAL_TTL", c.WSJournalTTL.String()},
+		{"WS_ALLOWED_ORIGINS", strings.Join(c.WSAllowedOrigins, ",")},
+		{"HTTP_READ_TIMEOUT", c.HTTPReadTimeout.String()},
+		{"HTTP_WRITE_TIMEOUT", c.HTTPWriteTimeout.String()},
+		{"HTTP_IDLE_TIMEOUT", c.HTTPIdleTimeout.String()},
+		{"SHUTDOWN_TIMEOUT", c.ShutdownTimeout.String()},
+	}
+}
+
+// Print: in config hiệu lực qua logf (vd log.Printf)
+func (c *Config) Print(logf func(format string, args ...any)) {
+	for _, e := range c.Entries() {
+		v := e[1]
+		if v == "" {
+			v = "-"
+		}
+		logf("⚙️  %-26s = %s", e[0], v)
+	}
+}
+
+// mask: chỉ cho biết secret có set hay không + độ dài
+func mask(s string) string {
+	if s == "" {
+		return ""
+	}
+	return fmt.Sprintf("*** (%d chars)", len(s))
+}
@@ -0,0 +1,275 @@
+package contact
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	StatusPending  = "pending"
+	StatusAccepted = "accepted"
+	StatusDeclined = "declined"
+)
+
+var (
+	ErrNotFound       = errors.New("contact: request not found")
+	ErrNotPending     = errors.New("contact: request already answered")
+	ErrAlreadyContact = errors.New("contact: already in contacts")
+	ErrUserNotFound   = errors.New("contact: user not found")
+)
+
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// Contact: 1 người trong danh bạ
+type Contact struct {
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"username"`
+	FullName  string    `json:"full_name"`
+	AvatarURL string    `json:"avatar_url"`
+	Since     time.Time `json:"since"`
+}
+
+// Request: lời mời kết bạn (kèm thông tin người kia để FE hiển thị)
+type Request struct {
+	ID          int64      `json:"id"`
+	FromUserID  int64      `json:"from_user_id"`
+	ToUserID    int64      `json:"to_user_id"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+
+	// người còn lại (incoming -> người gửi, outgoing -> người nhận)
+	OtherUsername  string `json:"other_username"`
+	OtherFullName  string `json:"other_full_name"`
+	OtherAvatarURL string `json:"other_avatar_url"`
+}
+
+func (r *Repository) IsContact(ctx context.Context, userID, otherID int64) (bool, error) {
+	var one int
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT 1 FROM user_contacts WHERE user_id = ? AND contact_id = ?
+	`, userID, otherID).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ListContacts: danh bạ của user, sắp theo tên
+func (r *Repository) ListContacts(ctx context.Context, userID int64) ([]Contact, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT u.id, u.username, COALESCE(u.full_name, ''), COALESCE(u.avatar_url, ''), c.created_at
+		FROM user_contacts c
+		JOIN users u ON u.id = c.contact_id
+		WHERE c.user_id = ? AND u.is_active = 1
+		ORDER BY COALESCE(NULLIF(u.full_name, ''), u.username)
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Contact{}
+	for rows.Next() {
+		var c Contact
+		if err := rows.Scan(&c.UserID, &c.Username, &c.FullName, &c.AvatarURL, &c.Since); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// RemoveContact: xoá 2 chiều, false = không phải contact
+func (r *Repository) RemoveContact(ctx context.Context, userID, otherID int64) (bool, error) {
+	res, err := r.DB.ExecContext(ctx, `
+		DELETE FROM user_contacts
+		WHERE (user_id = ? AND contact_id = ?) OR (user_id = ? AND contact_id = ?)
+	`, userID, otherID, otherID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// SendRequest: gửi lời mời kết bạn. Nếu người kia đã mời mình trước -> tự accept luôn
+// (accepted = true, trả về request của người kia)
+func (r *Repository) SendRequest(ctx context.Context, fromID, toID int64) (req *Request, accepted bool, err error) {
+	var one int
+	err = r.DB.QueryRowContext(ctx, `
+		SELECT 1 FROM users WHERE id = ? AND is_active = 1
+	`, toID).Scan(&one)
+	if err == sql.ErrNoRows {
+		return nil, false, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	isContact, err := r.IsContact(ctx, fromID, toID)
+	if err != nil {
+		return nil, false, err
+	}
+	if isContact {
+		return nil, false, ErrAlreadyContact
+	}
+
+	var reverseID int64
+	err = r.DB.QueryRowContext(ctx, `
+		SELECT id FROM contact_requests
+		WHERE from_user_id = ? AND to_user_id = ? AND status = 'pending'
+	`, toID, fromID).Scan(&reverseID)
+	if err == nil {
+		req, err = r.RespondRequest(ctx, reverseID, fromID, true)
+		return req, true, err
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, err
+	}
+
+	// đã từng bị từ chối -> gửi lại dùng row cũ
+	res, err := r.DB.ExecContext(ctx, `
+		INSERT INTO contact_requests (from_user_id, to_user_id, status)
+		VALUES (?, ?, 'pending')
+		ON DUPLICATE KEY UPDATE
+			id = LAST_INSERT_ID(id),
+			status = 'pending',
+			created_at = NOW(),
+			responded_at = NULL
+	`, fromID, toID)
+	if err != nil {
+		return nil, false, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, false, err
+	}
+	req, err = r.GetRequest(ctx, id, fromID)
+	return req, false, err
+}
+
+// RespondRequest: chỉ người nhận trả lời được, accept -> thêm contact 2 chiều
+func (r *Repository) RespondRequest(ctx context.Context, id, userID int64, accept bool) (*Request, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var fromID, toID int64
+	var status string
+	err = tx.QueryRowContext(ctx, `
+		SELECT from_user_id, to_user_id, status FROM contact_requests WHERE id = ? FOR UPDATE
+	`, id).Scan(&fromID, &toID, &status)
+	if err == sql.ErrNoRows || (err == nil && toID != userID) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status != StatusPending {
+		return nil, ErrNotPending
+	}
+
+	newStatus := StatusDeclined
+	if accept {
+		newStatus = StatusAccepted
+		if _, err := tx.ExecContext(ctx, `
+			INSERT IGNORE INTO user_contacts (user_id, contact_id) VALUES (?, ?), (?, ?)
+		`, fromID, toID, toID, fromID); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE contact_requests SET status = ?, responded_at = NOW() WHERE id = ?
+	`, newStatus, id); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetRequest(ctx, id, userID)
+}
+
+// CancelRequest: người gửi rút lại lời mời còn pending
+func (r *Repository) CancelRequest(ctx context.Context, id, fromID int64) (bool, error) {
+	res, err := r.DB.ExecContext(ctx, `
+		DELETE FROM contact_requests WHERE id = ? AND from_user_id = ? AND status = 'pending'
+	`, id, fromID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// requestSelect: "other" = người còn lại so với viewer (tham số đầu tiên)
+const requestSelect = `
+	SELECT q.id, q.from_user_id, q.to_user_id, q.status, q.created_at, q.responded_at,
+	       u.username, COALESCE(u.full_name, ''), COALESCE(u.avatar_url, '')
+	FROM contact_requests q
+	JOIN users u ON u.id = IF(q.from_user_id = ?, q.to_user_id, q.from_user_id)
+`
+
+// GetRequest: viewerID phải là người gửi hoặc người nhận
+func (r *Repository) GetRequest(ctx context.Context, id, viewerID int64) (*Request, error) {
+	row := r.DB.QueryRowContext(ctx, requestSelect+`
+		WHERE q.id = ? AND (q.from_user_id = ? OR q.to_user_id = ?)
+	`, viewerID, id, viewerID, viewerID)
+	req, err := scanRequest(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return req, err
+}
+
+// ListPending: incoming = người khác mời mình, ngược lại là mình đã gửi
+func (r *Repository) ListPending(ctx context.Context, userID int64, incoming bool) ([]*Request, error) {
+	where := `WHERE q.from_user_id = ? AND q.status = 'pending'`
+	if incoming {
+		where = `WHERE q.to_user_id = ? AND q.status = 'pending'`
+	}
+	rows, err := r.DB.QueryContext(ctx, requestSelect+where+`
+		ORDER BY q.created_at DESC, q.id DESC
+	`, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*Request{}
+	for rows.Next() {
+		req, err := scanRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, req)
+	}
+	return out, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRequest(sc scanner) (*Request, error) {
+	var q Request
+	var responded sql.NullTime
+	if err := sc.Scan(&q.ID, &q.FromUserID, &q.ToUserID, &q.Status, &q.CreatedAt, &responded,
+		&q.OtherUsername, &q.OtherFullName, &q.OtherAvatarURL); err != nil {
+		return nil, err
+	}
+	if responded.Valid {
+		q.RespondedAt = &responded.Time
+	}
+	return &q, nil
+}
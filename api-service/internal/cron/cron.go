@@ -0,0 +1,157 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule: cron 5 field chuẩn "phút giờ ngày tháng thứ"
+// hỗ trợ *, */n, a-b, a-b/n, danh sách a,b,c và macro @hourly/@daily/@weekly/@monthly/@yearly
+type Schedule struct {
+	minute, hour, dom, month, dow uint64 // bitset
+	domStar, dowStar              bool
+}
+
+var ErrInvalidSpec = errors.New("cron: invalid spec")
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type bounds struct{ min, max int }
+
+var (
+	minuteB = bounds{0, 59}
+	hourB   = bounds{0, 23}
+	domB    = bounds{1, 31}
+	monthB  = bounds{1, 12}
+	dowB    = bounds{0, 7} // 7 = chủ nhật
+)
+
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if m, ok := macros[strings.ToLower(spec)]; ok {
+		spec = m
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: expected 5 fields, got %d", ErrInvalidSpec, len(fields))
+	}
+
+	s := &Schedule{}
+	var err error
+	if s.minute, err = parseField(fields[0], minuteB); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], hourB); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseField(fields[2], domB); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], monthB); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseField(fields[4], dowB); err != nil {
+		return nil, err
+	}
+	// 7 -> 0
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+		s.dow &^= 1 << 7
+	}
+	s.domStar = fields[2] == "*" || fields[2] == "?"
+	s.dowStar = fields[4] == "*" || fields[4] == "?"
+	return s, nil
+}
+
+func parseField(f string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(f, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%w: bad step %q", ErrInvalidSpec, part)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		lo, hi := b.min, b.max
+		switch {
+		case part == "*" || part == "?":
+		case strings.Contains(part, "-"):
+			ab := strings.SplitN(part, "-", 2)
+			a, err1 := strconv.Atoi(ab[0])
+			c, err2 := strconv.Atoi(ab[1])
+			if err1 != nil || err2 != nil || a > c {
+				return 0, fmt.Errorf("%w: bad range %q", ErrInvalidSpec, part)
+			}
+			lo, hi = a, c
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("%w: bad value %q", ErrInvalidSpec, part)
+			}
+			lo = n
+			if step == 1 {
+				hi = n
+			}
+		}
+		if lo < b.min || hi > b.max {
+			return 0, fmt.Errorf("%w: %q out of range %d-%d", ErrInvalidSpec, part, b.min, b.max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Next: thời điểm khớp đầu tiên sau t (theo location của t), zero nếu không tìm thấy trong 5 năm
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// cron chuẩn: cả dom và dow bị giới hạn -> khớp 1 trong 2
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domOK := s.dom&(1<<uint(t.Day())) != 0
+	dowOK := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domOK && dowOK
+	}
+	return domOK || dowOK
+}
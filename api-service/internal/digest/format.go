@@ -0,0 +1,49 @@
+package digest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// mỗi dòng trong digest chỉ lấy phần đầu nội dung
+const maxLineChars = 160
+
+// Lines: 1 mục đã format "[15:04] Tên: nội dung"
+func Lines(items []Item, loc *time.Location) []string {
+	out := make([]string, 0, len(items))
+	for _, it := range items {
+		text := strings.Join(strings.Fields(it.Text), " ")
+		if utf8.RuneCountInString(text) > maxLineChars {
+			text = string([]rune(text)[:maxLineChars]) + "…"
+		}
+		line := fmt.Sprintf("[%s] %s: %s", it.CreatedAt.In(loc).Format("15:04"), it.Sender, text)
+		if it.Reactions > 0 {
+			line += fmt.Sprintf(" (%d reactions)", it.Reactions)
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+// Text: nội dung tin hệ thống đăng vào room
+func (d *Digest) Text(loc *time.Location) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "📰 Tổng kết ngày %s: %d tin nhắn từ %d thành viên\n",
+		d.From.In(loc).Format("02/01/2006"), d.MessageCount, d.ActiveUsers)
+
+	section := func(title string, items []Item) {
+		if len(items) == 0 {
+			return
+		}
+		b.WriteString("\n" + title + "\n")
+		for _, l := range Lines(items, loc) {
+			b.WriteString("• " + l + "\n")
+		}
+	}
+	section("🔥 Nổi bật:", d.TopMessages)
+	section("📌 Quyết định:", d.Decisions)
+	section("📎 File:", d.Files)
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,326 @@
+package digest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// số mục tối đa mỗi phần trong 1 digest
+const (
+	TopMessages  = 5
+	MaxFiles     = 10
+	MaxDecisions = 10
+)
+
+// từ khoá đánh dấu 1 tin là "quyết định" (so khớp không phân biệt hoa thường)
+var DecisionKeywords = []string{"#decision", "#chốt", "chốt", "quyết định", "decided", "decision:", "agreed"}
+
+var ErrInvalidSettings = errors.New("digest: invalid settings")
+
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// Settings: cấu hình digest của 1 room (chưa có dòng = tắt)
+type Settings struct {
+	RoomID      int64      `json:"room_id"`
+	Enabled     bool       `json:"enabled"`
+	PostMessage bool       `json:"post_message"` // đăng tin hệ thống vào room
+	Timezone    string     `json:"timezone"`     // IANA, rỗng = giờ server
+	LastSentAt  *time.Time `json:"last_sent_at,omitempty"`
+	UpdatedBy   int64      `json:"updated_by,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+func (s *Settings) Validate() error {
+	s.Timezone = strings.TrimSpace(s.Timezone)
+	if s.Timezone != "" {
+		if _, err := time.LoadLocation(s.Timezone); err != nil {
+			return ErrInvalidSettings
+		}
+	}
+	return nil
+}
+
+// Location: múi giờ dùng để hiển thị giờ trong digest
+func (s *Settings) Location() *time.Location {
+	if s.Timezone != "" {
+		if loc, err := time.LoadLocation(s.Timezone); err == nil {
+			return loc
+		}
+	}
+	return time.Local
+}
+
+const selectSettings = `
+	SELECT room_id, enabled, post_message, COALESCE(timezone, ''), last_sent_at, updated_by, updated_at
+	FROM room_digest_settings
+`
+
+func scanSettings(sc interface{ Scan(...any) error }) (*Settings, error) {
+	var s Settings
+	var lastSent, updatedAt sql.NullTime
+	if err := sc.Scan(&s.RoomID, &s.Enabled, &s.PostMessage, &s.Timezone, &lastSent, &s.UpdatedBy, &updatedAt); err != nil {
+		return nil, err
+	}
+	if lastSent.Valid {
+		s.LastSentAt = &lastSent.Time
+	}
+	if updatedAt.Valid {
+		s.UpdatedAt = &updatedAt.Time
+	}
+	return &s, nil
+}
+
+func (r *Repository) GetSettings(ctx context.Context, roomID int64) (*Settings, error) {
+	s, err := scanSettings(r.DB.QueryRowContext(ctx, selectSettings+` WHERE room_id = ?`, roomID))
+	if err == sql.ErrNoRows {
+		return &Settings{RoomID: roomID, PostMessage: true}, nil
+	}
+	return s, err
+}
+
+func (r *Repository) SaveSettings(ctx context.Context, s *Settings, userID int64) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT INTO room_digest_settings (room_id, enabled, post_message, timezone, updated_by)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			enabled = VALUES(enabled), post_message = VALUES(post_message),
+			timezone = VALUES(timezone), updated_by = VALUES(updated_by)
+	`, s.RoomID, s.Enabled, s.PostMessage, nullIfEmpty(s.Timezone), userID)
+	return err
+}
+
+// ListEnabled: room bật digest (room còn active)
+func (r *Repository) ListEnabled(ctx context.Context) ([]*Settings, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT s.room_id, s.enabled, s.post_message, COALESCE(s.timezone, ''), s.last_sent_at, s.updated_by, s.updated_at
+		FROM room_digest_settings s
+		JOIN rooms r ON r.id = s.room_id
+		WHERE s.enabled = 1 AND r.is_active = 1
+		ORDER BY s.room_id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*Settings
+	for rows.Next() {
+		s, err := scanSettings(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func (r *Repository) MarkSent(ctx context.Context, roomID int64, at time.Time) error {
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE room_digest_settings SET last_sent_at = ? WHERE room_id = ?
+	`, at, roomID)
+	return err
+}
+
+// ===== đăng ký nhận email theo user =====
+
+// IsSubscribed: user có nhận digest qua email cho room này không
+func (r *Repository) IsSubscribed(ctx context.Context, roomID, userID int64) (bool, error) {
+	var n int
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM room_digest_subscriptions WHERE room_id = ? AND user_id = ?
+	`, roomID, userID).Scan(&n)
+	return n > 0, err
+}
+
+func (r *Repository) SetSubscribed(ctx context.Context, roomID, userID int64, on bool) error {
+	if !on {
+		_, err := r.DB.ExecContext(ctx, `
+			DELETE FROM room_digest_subscriptions WHERE room_id = ? AND user_id = ?
+		`, roomID, userID)
+		return err
+	}
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT IGNORE INTO room_digest_subscriptions (room_id, user_id) VALUES (?, ?)
+	`, roomID, userID)
+	return err
+}
+
+// Recipient: user nhận email digest (còn là member, có email, đang active)
+type Recipient struct {
+	UserID int64
+	Name   string
+	Email  string
+}
+
+func (r *Repository) ListRecipients(ctx context.Context, roomID int64) ([]Recipient, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT u.id, COALESCE(NULLIF(u.full_name, ''), u.username), u.email
+		FROM room_digest_subscriptions ds
+		JOIN room_members rm ON rm.room_id = ds.room_id AND rm.user_id = ds.user_id
+		JOIN users u ON u.id = ds.user_id
+		WHERE ds.room_id = ? AND u.is_active = 1 AND u.email IS NOT NULL AND u.email <> ''
+		ORDER BY u.id ASC
+	`, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Recipient
+	for rows.Next() {
+		var rc Recipient
+		if err := rows.Scan(&rc.UserID, &rc.Name, &rc.Email); err != nil {
+			return nil, err
+		}
+		out = append(out, rc)
+	}
+	return out, rows.Err()
+}
+
+// ===== build =====
+
+// Item: 1 dòng trong digest
+type Item struct {
+	MessageID int64     `json:"message_id"`
+	Sender    string    `json:"sender"`
+	Text      string    `json:"text"`
+	Reactions int       `json:"reactions,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Digest: hoạt động của 1 room trong [From, To)
+type Digest struct {
+	RoomID       int64     `json:"room_id"`
+	RoomName     string    `json:"room_name"`
+	From         time.Time `json:"from"`
+	To           time.Time `json:"to"`
+	MessageCount int       `json:"message_count"`
+	ActiveUsers  int       `json:"active_users"`
+	TopMessages  []Item    `json:"top_messages"`
+	Files        []Item    `json:"files"`
+	Decisions    []Item    `json:"decisions"`
+}
+
+// Empty: không có tin nào -> không gửi
+func (d *Digest) Empty() bool {
+	return d.MessageCount == 0
+}
+
+// Build: gom tin trong khoảng thời gian, bỏ tin tạm / đã gỡ / tin của excludeSender (bot digest)
+func (r *Repository) Build(ctx context.Context, roomID int64, from, to time.Time, excludeSender int64) (*Digest, error) {
+	d := &Digest{
+		RoomID:      roomID,
+		From:        from,
+		To:          to,
+		TopMessages: []Item{},
+		Files:       []Item{},
+		Decisions:   []Item{},
+	}
+	if err := r.DB.QueryRowContext(ctx, `
+		SELECT COALESCE(name, '') FROM rooms WHERE id = ?
+	`, roomID).Scan(&d.RoomName); err != nil {
+		return nil, err
+	}
+
+	const where = `
+		m.room_id = ? AND m.created_at >= ? AND m.created_at < ?
+		AND m.is_temp = 0 AND m.removed_at IS NULL AND m.sender_id <> ?
+	`
+	if err := r.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(DISTINCT m.sender_id) FROM messages m WHERE `+where,
+		roomID, from, to, excludeSender).Scan(&d.MessageCount, &d.ActiveUsers); err != nil {
+		return nil, err
+	}
+	if d.MessageCount == 0 {
+		return d, nil
+	}
+
+	// 1) tin được react nhiều nhất
+	top, err := r.queryItems(ctx, `
+		SELECT m.id, COALESCE(NULLIF(u.full_name, ''), u.username), COALESCE(m.content, ''), COUNT(mr.id) AS n, m.created_at
+		FROM messages m
+		JOIN users u ON u.id = m.sender_id
+		JOIN message_reactions mr ON mr.message_id = m.id
+		WHERE `+where+` AND m.message_type = 'text'
+		GROUP BY m.id, u.full_name, u.username, m.content, m.created_at
+		ORDER BY n DESC, m.id ASC
+		LIMIT ?
+	`, roomID, from, to, excludeSender, TopMessages)
+	if err != nil {
+		return nil, err
+	}
+	d.TopMessages = top
+
+	// 2) file / ảnh đã gửi (tên gốc nếu có)
+	files, err := r.queryItems(ctx, `
+		SELECT m.id, COALESCE(NULLIF(u.full_name, ''), u.username),
+		       COALESCE((SELECT a.file_name FROM attachments a WHERE a.message_id = m.id ORDER BY a.id LIMIT 1),
+		                NULLIF(m.content, ''), m.message_type),
+		       0, m.created_at
+		FROM messages m
+		JOIN users u ON u.id = m.sender_id
+		WHERE `+where+` AND (m.message_type IN ('image', 'file') OR EXISTS (SELECT 1 FROM attachments a WHERE a.message_id = m.id))
+		ORDER BY m.id ASC
+		LIMIT ?
+	`, roomID, from, to, excludeSender, MaxFiles)
+	if err != nil {
+		return nil, err
+	}
+	d.Files = files
+
+	// 3) quyết định: tin có từ khoá
+	conds := make([]string, len(DecisionKeywords))
+	args := []any{roomID, from, to, excludeSender}
+	for i, kw := range DecisionKeywords {
+		conds[i] = "LOWER(m.content) LIKE ?"
+		args = append(args, "%"+strings.ToLower(kw)+"%")
+	}
+	args = append(args, MaxDecisions)
+	decisions, err := r.queryItems(ctx, `
+		SELECT m.id, COALESCE(NULLIF(u.full_name, ''), u.username), COALESCE(m.content, ''), 0, m.created_at
+		FROM messages m
+		JOIN users u ON u.id = m.sender_id
+		WHERE `+where+` AND m.message_type = 'text' AND (`+strings.Join(conds, " OR ")+`)
+		ORDER BY m.id ASC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	d.Decisions = decisions
+	return d, nil
+}
+
+func (r *Repository) queryItems(ctx context.Context, q string, args ...any) ([]Item, error) {
+	rows, err := r.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Item{}
+	for rows.Next() {
+		var it Item
+		if err := rows.Scan(&it.MessageID, &it.Sender, &it.Text, &it.Reactions, &it.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
+
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
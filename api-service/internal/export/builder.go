@@ -0,0 +1,257 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Builder: gom dữ liệu 1 user thành file zip
+//
+//	profile.json          thông tin tài khoản
+//	rooms.json            room đang tham gia
+//	messages/room_N.json  tin user đã gửi, theo room
+//	media_manifest.json   danh sách file đã upload (không kèm file)
+type Builder struct {
+	DB  *sql.DB
+	Dir string
+}
+
+func NewBuilder(db *sql.DB, dir string) *Builder {
+	return &Builder{DB: db, Dir: dir}
+}
+
+type profile struct {
+	ID        int64   `json:"id"`
+	Username  string  `json:"username"`
+	Role      string  `json:"role"`
+	FullName  *string `json:"full_name"`
+	Email     *string `json:"email"`
+	Phone     *string `json:"phone"`
+	AvatarURL *string `json:"avatar_url"`
+	LastLogin *string `json:"last_login"`
+	CreatedAt string  `json:"created_at"`
+}
+
+type roomInfo struct {
+	ID         int64   `json:"id"`
+	Name       *string `json:"name"`
+	Type       string  `json:"type"`
+	MemberRole string  `json:"member_role"`
+	JoinedAt   string  `json:"joined_at"`
+}
+
+type messageRow struct {
+	ID          int64   `json:"id"`
+	ReplyToID   *int64  `json:"reply_to_message_id,omitempty"`
+	Content     *string `json:"content"`
+	MessageType string  `json:"message_type"`
+	CreatedAt   string  `json:"created_at"`
+	UpdatedAt   *string `json:"updated_at,omitempty"`
+	RemovedAt   *string `json:"removed_at,omitempty"`
+}
+
+type mediaItem struct {
+	FileName     string `json:"file_name"`
+	OriginalName string `json:"original_name"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`
+	RoomID       int64  `json:"room_id"`
+	Path         string `json:"path"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// Build: ghi zip vào Dir, progress(0..100) gọi sau mỗi bước; trả tên file + kích thước
+func (b *Builder) Build(ctx context.Context, e *Export, progress func(int)) (string, int64, error) {
+	name := fmt.Sprintf("export_u%d_%d_%d.zip", e.UserID, e.ID, time.Now().Unix())
+	path := filepath.Join(b.Dir, name)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", 0, err
+	}
+	ok := false
+	defer func() {
+		if !ok {
+			f.Close()
+			os.Remove(path)
+		}
+	}()
+
+	zw := zip.NewWriter(f)
+
+	// 1) profile
+	var p profile
+	err = b.DB.QueryRowContext(ctx, `
+		SELECT id, username, role, full_name, email, phone, avatar_url,
+		       DATE_FORMAT(last_login, '%Y-%m-%dT%H:%i:%s'), DATE_FORMAT(created_at, '%Y-%m-%dT%H:%i:%s')
+		FROM users WHERE id = ?
+	`, e.UserID).Scan(&p.ID, &p.Username, &p.Role, &p.FullName, &p.Email, &p.Phone, &p.AvatarURL, &p.LastLogin, &p.CreatedAt)
+	if err != nil {
+		return "", 0, fmt.Errorf("profile: %w", err)
+	}
+	if err := writeJSONFile(zw, "profile.json", p); err != nil {
+		return "", 0, err
+	}
+	progress(5)
+
+	// 2) rooms
+	rooms, err := b.rooms(ctx, e.UserID)
+	if err != nil {
+		return "", 0, fmt.Errorf("rooms: %w", err)
+	}
+	if err := writeJSONFile(zw, "rooms.json", rooms); err != nil {
+		return "", 0, err
+	}
+	progress(10)
+
+	// 3) messages theo room (10% -> 90%)
+	for i, rm := range rooms {
+		if err := ctx.Err(); err != nil {
+			return "", 0, err
+		}
+		if err := b.writeMessages(ctx, zw, e.UserID, rm.ID); err != nil {
+			return "", 0, fmt.Errorf("messages room %d: %w", rm.ID, err)
+		}
+		progress(10 + 80*(i+1)/len(rooms))
+	}
+
+	// 4) media manifest
+	media, err := b.media(ctx, e.UserID)
+	if err != nil {
+		return "", 0, fmt.Errorf("media: %w", err)
+	}
+	if err := writeJSONFile(zw, "media_manifest.json", media); err != nil {
+		return "", 0, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return "", 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return "", 0, err
+	}
+	if err := f.Close(); err != nil {
+		return "", 0, err
+	}
+	ok = true
+	return name, info.Size(), nil
+}
+
+func writeJSONFile(zw *zip.Writer, name string, v any) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (b *Builder) rooms(ctx context.Context, userID int64) ([]roomInfo, error) {
+	rows, err := b.DB.QueryContext(ctx, `
+		SELECT r.id, r.name, r.type, rm.member_role, DATE_FORMAT(rm.joined_at, '%Y-%m-%dT%H:%i:%s')
+		FROM room_members rm
+		JOIN rooms r ON r.id = rm.room_id
+		WHERE rm.user_id = ?
+		ORDER BY r.id ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []roomInfo{}
+	for rows.Next() {
+		var ri roomInfo
+		if err := rows.Scan(&ri.ID, &ri.Name, &ri.Type, &ri.MemberRole, &ri.JoinedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, ri)
+	}
+	return out, rows.Err()
+}
+
+// writeMessages: stream JSON array (room lớn không phải giữ hết trong RAM)
+func (b *Builder) writeMessages(ctx context.Context, zw *zip.Writer, userID, roomID int64) error {
+	rows, err := b.DB.QueryContext(ctx, `
+		SELECT id, reply_to_message_id, content, message_type,
+		       DATE_FORMAT(created_at, '%Y-%m-%dT%H:%i:%s'),
+		       DATE_FORMAT(updated_at, '%Y-%m-%dT%H:%i:%s'),
+		       DATE_FORMAT(removed_at, '%Y-%m-%dT%H:%i:%s')
+		FROM messages
+		WHERE room_id = ? AND sender_id = ? AND is_temp = 0
+		ORDER BY id ASC
+	`, roomID, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	w, err := zw.Create(fmt.Sprintf("messages/room_%d.json", roomID))
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	first := true
+	for rows.Next() {
+		var m messageRow
+		if err := rows.Scan(&m.ID, &m.ReplyToID, &m.Content, &m.MessageType, &m.CreatedAt, &m.UpdatedAt, &m.RemovedAt); err != nil {
+			return err
+		}
+		buf, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		sep := ",\n  "
+		if first {
+			sep = "\n  "
+			first = false
+		}
+		if _, err := io.WriteString(w, sep); err != nil {
+			return err
+		}
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n]\n")
+	return err
+}
+
+func (b *Builder) media(ctx context.Context, userID int64) ([]mediaItem, error) {
+	rows, err := b.DB.QueryContext(ctx, `
+		SELECT file_name, original_name, content_type, file_size, room_id,
+		       DATE_FORMAT(created_at, '%Y-%m-%dT%H:%i:%s')
+		FROM chat_uploads
+		WHERE uploader_id = ?
+		ORDER BY created_at ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []mediaItem{}
+	for rows.Next() {
+		var m mediaItem
+		if err := rows.Scan(&m.FileName, &m.OriginalName, &m.ContentType, &m.Size, &m.RoomID, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		m.Path = "/static/chat_uploads/" + m.FileName
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,235 @@
+package export
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+const (
+	StatusQueued  = "queued"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+	StatusExpired = "expired" // file đã xoá
+)
+
+var (
+	ErrNotFound = errors.New("export: not found")
+	ErrActive   = errors.New("export: an export is already queued or running for this user")
+)
+
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+type Export struct {
+	ID          int64      `json:"id"`
+	UserID      int64      `json:"user_id"`      // dữ liệu của ai
+	RequestedBy int64      `json:"requested_by"` // user tự yêu cầu hoặc admin
+	Status      string     `json:"status"`
+	Progress    int        `json:"progress"` // 0..100
+	FileName    string     `json:"-"`
+	FileSize    int64      `json:"file_size,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	DownloadURL string `json:"download_url,omitempty"` // chỉ có khi done, ký + hết hạn
+}
+
+const selectExport = `
+	SELECT id, user_id, requested_by, status, progress, COALESCE(file_name, ''), file_size,
+	       COALESCE(error, ''), expires_at, started_at, finished_at, created_at
+	FROM data_exports
+`
+
+func scanExport(sc interface{ Scan(...any) error }) (*Export, error) {
+	var e Export
+	var expires, started, finished sql.NullTime
+	if err := sc.Scan(&e.ID, &e.UserID, &e.RequestedBy, &e.Status, &e.Progress, &e.FileName, &e.FileSize,
+		&e.Error, &expires, &started, &finished, &e.CreatedAt); err != nil {
+		return nil, err
+	}
+	if expires.Valid {
+		e.ExpiresAt = &expires.Time
+	}
+	if started.Valid {
+		e.StartedAt = &started.Time
+	}
+	if finished.Valid {
+		e.FinishedAt = &finished.Time
+	}
+	return &e, nil
+}
+
+// Create: mỗi user chỉ 1 export đang chờ / đang chạy
+func (r *Repository) Create(ctx context.Context, userID, requestedBy int64) (*Export, error) {
+	var n int
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM data_exports WHERE user_id = ? AND status IN ('queued', 'running')
+	`, userID).Scan(&n)
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 {
+		return nil, ErrActive
+	}
+
+	res, err := r.DB.ExecContext(ctx, `
+		INSERT INTO data_exports (user_id, requested_by, status) VALUES (?, ?, ?)
+	`, userID, requestedBy, StatusQueued)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := res.LastInsertId()
+	return r.Get(ctx, id)
+}
+
+func (r *Repository) Get(ctx context.Context, id int64) (*Export, error) {
+	e, err := scanExport(r.DB.QueryRowContext(ctx, selectExport+` WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return e, err
+}
+
+// List: userID = 0 -> mọi user (admin)
+func (r *Repository) List(ctx context.Context, userID, beforeID int64, limit int) ([]*Export, error) {
+	q := selectExport + ` WHERE 1 = 1`
+	args := []any{}
+	if userID > 0 {
+		q += ` AND user_id = ?`
+		args = append(args, userID)
+	}
+	if beforeID > 0 {
+		q += ` AND id < ?`
+		args = append(args, beforeID)
+	}
+	q += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*Export{}
+	for rows.Next() {
+		e, err := scanExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// Claim: giữ 1 export đang chờ (UPDATE có điều kiện -> nhiều instance không lấy trùng)
+func (r *Repository) Claim(ctx context.Context) (*Export, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	token := hex.EncodeToString(b)
+
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE data_exports
+		SET status = ?, claim_token = ?, started_at = NOW(), heartbeat_at = NOW(), progress = 0
+		WHERE status = ?
+		ORDER BY id ASC
+		LIMIT 1
+	`, StatusRunning, token, StatusQueued)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, nil
+	}
+
+	e, err := scanExport(r.DB.QueryRowContext(ctx, selectExport+` WHERE claim_token = ?`, token))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return e, err
+}
+
+// Requeue: instance chết giữa chừng (heartbeat cũ) -> cho chạy lại
+func (r *Repository) Requeue(ctx context.Context, staleBefore time.Time) (int64, error) {
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE data_exports
+		SET status = ?, claim_token = NULL
+		WHERE status = ? AND heartbeat_at < ?
+	`, StatusQueued, StatusRunning, staleBefore)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// SetProgress: kèm heartbeat
+func (r *Repository) SetProgress(ctx context.Context, id int64, progress int) error {
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE data_exports SET progress = ?, heartbeat_at = NOW() WHERE id = ? AND status = ?
+	`, progress, id, StatusRunning)
+	return err
+}
+
+func (r *Repository) Finish(ctx context.Context, id int64, fileName string, size int64, expiresAt time.Time) error {
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE data_exports
+		SET status = ?, progress = 100, file_name = ?, file_size = ?, expires_at = ?, finished_at = NOW()
+		WHERE id = ?
+	`, StatusDone, fileName, size, expiresAt, id)
+	return err
+}
+
+func (r *Repository) Fail(ctx context.Context, id int64, errMsg string) error {
+	if len(errMsg) > 500 {
+		errMsg = errMsg[:500]
+	}
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE data_exports SET status = ?, error = ?, finished_at = NOW() WHERE id = ?
+	`, StatusFailed, errMsg, id)
+	return err
+}
+
+// ListExpired: export done đã quá hạn tải (cần xoá file)
+func (r *Repository) ListExpired(ctx context.Context, now time.Time, limit int) ([]*Export, error) {
+	rows, err := r.DB.QueryContext(ctx, selectExport+`
+		WHERE status = ? AND expires_at < ?
+		ORDER BY id ASC
+		LIMIT ?
+	`, StatusDone, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*Export
+	for rows.Next() {
+		e, err := scanExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (r *Repository) MarkExpired(ctx context.Context, id int64) error {
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE data_exports SET status = ?, file_name = NULL WHERE id = ? AND status = ?
+	`, StatusExpired, id, StatusDone)
+	return err
+}
@@ -0,0 +1,137 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/analytics"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const adminAnalyticsPrefix = "/admin/analytics/"
+
+func (s *Server) mountAdminRoutes(mux *http.ServeMux) {
+	// GET  /admin/media/orphans -> dry-run: liệt kê file mồ côi
+	// POST /admin/media/orphans -> xoá thật
+	mux.Handle("/admin/media/orphans", s.RequireAdmin(http.HandlerFunc(s.handleMediaOrphans)))
+
+	// GET /admin/analytics/overview
+	// GET /admin/analytics/{dau|messages|new-users|active-rooms|storage}?days=30
+	mux.Handle(adminAnalyticsPrefix, s.RequireAdmin(http.HandlerFunc(s.handleAdminAnalytics)))
+}
+
+// =======================================
+// HANDLER: GET|POST /admin/media/orphans
+// =======================================
+
+func (s *Server) handleMediaOrphans(w http.ResponseWriter, r *http.Request) {
+	var dryRun bool
+	switch r.Method {
+	case http.MethodGet:
+		dryRun = true
+	case http.MethodPost:
+		dryRun = r.URL.Query().Get("dry_run") == "1"
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
+	defer cancel()
+
+	rep, err := s.janitor.Cleanup(ctx, dryRun)
+	if err != nil {
+		log.Println("janitor Cleanup error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rep)
+}
+
+// =======================================
+// HANDLER: GET /admin/analytics/{metric}
+// =======================================
+
+func (s *Server) handleAdminAnalytics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	metric := strings.Trim(strings.TrimPrefix(r.URL.Path, adminAnalyticsPrefix), "/")
+	now := s.now()
+
+	if metric == "overview" {
+		o, err := s.analyticsRepo.Overview(ctx, now)
+		if err != nil {
+			log.Println("analytics Overview error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, o)
+		return
+	}
+
+	// khoảng ngày: days ngày gần nhất, tính cả hôm nay
+	days := 30
+	if v, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && v > 0 && v <= 365 {
+		days = v
+	}
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	from := to.AddDate(0, 0, -(days - 1))
+
+	var fn func(context.Context, time.Time, time.Time) ([]analytics.Point, error)
+	switch metric {
+	case "dau":
+		fn = s.analyticsRepo.DailyActiveUsers
+	case "messages":
+		fn = s.analyticsRepo.MessagesPerDay
+	case "new-users":
+		fn = s.analyticsRepo.NewUsersPerDay
+	case "active-rooms":
+		fn = s.analyticsRepo.ActiveRoomsPerDay
+	case "storage":
+		fn = s.analyticsRepo.StoragePerDay
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown metric"})
+		return
+	}
+
+	points, err := fn(ctx, from, to)
+	if err != nil {
+		log.Println("analytics", metric, "error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	resp := map[string]any{
+		"metric": metric,
+		"from":   from.Format("2006-01-02"),
+		"to":     to.Format("2006-01-02"),
+		"points": points,
+	}
+
+	// storage: thêm đường cộng dồn để vẽ tăng trưởng
+	if metric == "storage" {
+		base, err := s.analyticsRepo.StorageBefore(ctx, from)
+		if err != nil {
+			log.Println("analytics StorageBefore error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		total := make([]analytics.Point, len(points))
+		for i, p := range points {
+			base += p.Value
+			total[i] = analytics.Point{Date: p.Date, Value: base}
+		}
+		resp["total"] = total
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,193 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/audit"
+	"cronhustler/api-service/internal/moderation"
+	"cronhustler/api-service/internal/room"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const adminMessagesPrefix = "/admin/messages/"
+
+// purge chạy trong request (admin cần số tin đã xoá), quá hạn -> partial, gọi lại để xoá tiếp
+const roomPurgeTimeout = 2 * time.Minute
+
+type adminDeleteMessageRequest struct {
+	Note string `json:"note"`
+}
+
+// DELETE /admin/messages/{id} {note?} -> gỡ tin bất kỳ (placeholder như report delete_message)
+func (s *Server) handleAdminDeleteMessage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	adminID, _ := UserIDFromContext(r.Context())
+
+	messageID, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(r.URL.Path, adminMessagesPrefix), "/"), 10, 64)
+	if err != nil || messageID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid message id"})
+		return
+	}
+
+	// body tuỳ chọn
+	var req adminDeleteMessageRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+			return
+		}
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	if len([]rune(req.Note)) > 1000 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "note too long (max 1000 chars)"})
+		return
+	}
+
+	roomID, err := s.moderationRepo.AdminDeleteMessage(r.Context(), messageID, adminID, req.Note)
+	if err != nil {
+		switch {
+		case errors.Is(err, moderation.ErrMessageNotFound):
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "message not found"})
+		case errors.Is(err, moderation.ErrAlreadyRemoved):
+			writeJSON(w, http.StatusConflict, map[string]string{"error": "message already removed"})
+		default:
+			log.Println("AdminDeleteMessage error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		}
+		return
+	}
+
+	s.broadcastMessageRemoved(roomID, messageID, moderation.RemovedPlaceholder)
+	s.recordAudit(r, adminID, audit.ActionMessageDelete, audit.TargetMessage, messageID, map[string]any{
+		"room_id": roomID,
+		"note":    req.Note,
+	})
+	writeJSON(w, http.StatusOK, map[string]any{"success": true, "message_id": messageID, "room_id": roomID})
+}
+
+type adminPurgeRoomRequest struct {
+	From string `json:"from"` // RFC3339, tính cả mốc
+	To   string `json:"to"`   // RFC3339, không tính mốc
+	Note string `json:"note"`
+}
+
+// POST /admin/rooms/{id}/purge {from, to, note?} -> xoá hẳn tin trong khoảng thời gian
+func (s *Server) adminPurgeRoom(w http.ResponseWriter, r *http.Request, roomID int64) {
+	adminID, _ := UserIDFromContext(r.Context())
+
+	var req adminPurgeRoomRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+	from, err := time.Parse(time.RFC3339, req.From)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "from must be RFC3339"})
+		return
+	}
+	to, err := time.Parse(time.RFC3339, req.To)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "to must be RFC3339"})
+		return
+	}
+	if !from.Before(to) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "from must be before to"})
+		return
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	if len([]rune(req.Note)) > 1000 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "note too long (max 1000 chars)"})
+		return
+	}
+
+	if _, err := s.roomRepo.AdminGetRoom(r.Context(), roomID); err != nil {
+		if errors.Is(err, room.ErrRoomNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "room not found"})
+			return
+		}
+		log.Println("AdminGetRoom error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), roomPurgeTimeout)
+	defer cancel()
+
+	deleted, err := s.moderationRepo.PurgeRoomMessages(ctx, roomID, from, to, adminID, req.Note)
+	partial := err != nil && ctx.Err() != nil
+	if err != nil && !partial {
+		log.Println("PurgeRoomMessages error:", err)
+		if deleted == 0 {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		partial = true
+	}
+
+	if deleted > 0 {
+		s.broadcastMessagesPurged(roomID, from, to, deleted)
+	}
+	s.recordAudit(r, adminID, audit.ActionRoomPurge, audit.TargetRoom, roomID, map[string]any{
+		"from":    from.Format(time.RFC3339),
+		"to":      to.Format(time.RFC3339),
+		"deleted": deleted,
+		"partial": partial,
+		"note":    req.Note,
+	})
+	writeJSON(w, http.StatusOK, map[string]any{
+		"room_id": roomID,
+		"deleted": deleted,
+		"partial": partial, // true -> gọi lại để xoá tiếp
+	})
+}
+
+// broadcastMessagesPurged: FE bỏ tin trong khoảng [from, to) và tải lại preview / unread
+func (s *Server) broadcastMessagesPurged(roomID int64, from, to time.Time, count int64) {
+	memberIDs, err := s.roomRepo.GetRoomMemberIDs(roomID)
+	if err != nil {
+		log.Println("GetRoomMemberIDs error:", err)
+		return
+	}
+	wsSendToUsers(memberIDs, wsEnvelope{
+		Type:   "messages_purged",
+		RoomID: roomID,
+		Data: map[string]any{
+			"from":  from.Format(time.RFC3339),
+			"to":    to.Format(time.RFC3339),
+			"count": count,
+		},
+	})
+}
+
+// GET /admin/moderation/deletions?room_id=&before_id=&limit=
+func (s *Server) handleModerationDeletions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	var roomID int64
+	if v := r.URL.Query().Get("room_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid room_id"})
+			return
+		}
+		roomID = id
+	}
+	beforeID, limit := parsePaging(r)
+	list, err := s.moderationRepo.ListDeletions(r.Context(), roomID, beforeID, limit)
+	if err != nil {
+		log.Println("moderation ListDeletions error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"deletions": list})
+}
@@ -0,0 +1,186 @@
+package httpserver
+
+import (
+	"cronhustler/api-service/internal/audit"
+	"cronhustler/api-service/internal/room"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const adminRoomsPrefix = "/admin/rooms/"
+
+func (s *Server) mountAdminRoomRoutes(mux *http.ServeMux) {
+	// GET /admin/rooms?type=&q=&min_members=&max_members=&inactive_days=&no_owner=1&before_id=&limit=
+	mux.Handle("/admin/rooms", s.RequireAdmin(http.HandlerFunc(s.handleAdminListRooms)))
+	// GET    /admin/rooms/{id}       -> room + danh sách member
+	// DELETE /admin/rooms/{id}       -> xoá hẳn (room bỏ hoang)
+	// POST   /admin/rooms/{id}/owner {user_id} -> chuyển owner
+	// POST   /admin/rooms/{id}/purge {from, to, note?} -> xoá hẳn tin trong khoảng thời gian
+	mux.Handle(adminRoomsPrefix, s.RequireAdmin(s.adminRoomRoutes()))
+}
+
+func (s *Server) handleAdminListRooms(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	q := r.URL.Query()
+	f := room.AdminRoomFilter{
+		Type:       q.Get("type"),
+		Query:      strings.TrimSpace(q.Get("q")),
+		MaxMembers: -1,
+		NoOwner:    q.Get("no_owner") == "1",
+	}
+	if f.Type != "" && f.Type != "direct" && f.Type != "group" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "type must be direct or group"})
+		return
+	}
+	f.MinMembers, _ = strconv.Atoi(q.Get("min_members"))
+	if v, err := strconv.Atoi(q.Get("max_members")); err == nil && v >= 0 {
+		f.MaxMembers = v
+	}
+	f.InactiveDays, _ = strconv.Atoi(q.Get("inactive_days"))
+	f.BeforeID, f.Limit = parsePaging(r)
+
+	list, err := s.roomRepo.AdminListRooms(r.Context(), f)
+	if err != nil {
+		log.Println("AdminListRooms error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"rooms": list})
+}
+
+func (s *Server) adminRoomRoutes() http.Handler {
+	m := newPatternMux(adminRoomsPrefix)
+	m.handle(http.MethodGet, "/admin/rooms/{roomID}", withRoomID(s.adminGetRoom))
+	m.handle(http.MethodDelete, "/admin/rooms/{roomID}", withRoomID(s.adminDeleteRoom))
+	m.handle(http.MethodPost, "/admin/rooms/{roomID}/owner", withRoomID(s.adminTransferRoomOwner))
+	m.handle(http.MethodPost, "/admin/rooms/{roomID}/purge", withRoomID(s.adminPurgeRoom))
+	return m
+}
+
+func (s *Server) adminGetRoom(w http.ResponseWriter, r *http.Request, roomID int64) {
+	rm, err := s.roomRepo.AdminGetRoom(r.Context(), roomID)
+	if err != nil {
+		if errors.Is(err, room.ErrRoomNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "room not found"})
+			return
+		}
+		log.Println("AdminGetRoom error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	members, err := s.roomRepo.GetRoomMembers(roomID)
+	if err != nil {
+		log.Println("GetRoomMembers error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"room": rm, "members": members})
+}
+
+func (s *Server) adminDeleteRoom(w http.ResponseWriter, r *http.Request, roomID int64) {
+	ctx := r.Context()
+	rm, err := s.roomRepo.AdminGetRoom(ctx, roomID)
+	if err != nil {
+		if errors.Is(err, room.ErrRoomNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "room not found"})
+			return
+		}
+		log.Println("AdminGetRoom error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	// lấy member trước khi xoá để báo qua WS
+	memberIDs, err := s.roomRepo.GetRoomMemberIDs(roomID)
+	if err != nil {
+		log.Println("GetRoomMemberIDs error:", err)
+	}
+
+	if err := s.roomRepo.ForceDeleteRoom(ctx, roomID); err != nil {
+		if errors.Is(err, room.ErrRoomNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "room not found"})
+			return
+		}
+		log.Println("ForceDeleteRoom error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	adminID, _ := UserIDFromContext(r.Context())
+	details := map[string]any{
+		"force":         true,
+		"name":          rm.Name,
+		"type":          rm.Type,
+		"member_count":  rm.MemberCount,
+		"message_count": rm.MessageCount,
+	}
+	if rm.LastActivityAt != nil {
+		details["last_activity_at"] = rm.LastActivityAt
+	}
+	s.recordAudit(r, adminID, audit.ActionRoomDelete, audit.TargetRoom, roomID, details)
+
+	go wsSendToUsers(memberIDs, wsEnvelope{
+		Type:   "room_deleted",
+		RoomID: roomID,
+		Data:   map[string]any{"room_id": roomID, "by_admin": true},
+	})
+
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "room_id": roomID, "message": "room deleted"})
+}
+
+func (s *Server) adminTransferRoomOwner(w http.ResponseWriter, r *http.Request, roomID int64) {
+	var req struct {
+		UserID int64 `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user_id is required"})
+		return
+	}
+
+	oldOwnerID, err := s.roomRepo.TransferOwnership(r.Context(), roomID, req.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, room.ErrRoomNotFound):
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "room not found"})
+		case errors.Is(err, room.ErrNotMember), errors.Is(err, room.ErrNotGroup):
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		default:
+			log.Println("TransferOwnership error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		}
+		return
+	}
+	changed := oldOwnerID != req.UserID
+
+	if changed {
+		adminID, _ := UserIDFromContext(r.Context())
+		s.recordAudit(r, adminID, audit.ActionRoomOwnerTransfer, audit.TargetRoom, roomID, map[string]any{
+			"from_user_id": oldOwnerID,
+			"to_user_id":   req.UserID,
+		})
+
+		if memberIDs, err := s.roomRepo.GetRoomMemberIDs(roomID); err == nil {
+			go wsSendToUsers(memberIDs, wsEnvelope{
+				Type:   "room_owner_changed",
+				RoomID: roomID,
+				Data:   map[string]any{"owner_id": req.UserID, "previous_owner_id": oldOwnerID},
+			})
+		} else {
+			log.Println("GetRoomMemberIDs error:", err)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"room_id":           roomID,
+		"owner_id":          req.UserID,
+		"previous_owner_id": oldOwnerID,
+		"changed":           changed,
+	})
+}
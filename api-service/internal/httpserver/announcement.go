@@ -0,0 +1,290 @@
+package httpserver
+
+import (
+	"cronhustler/api-service/internal/announcement"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	adminAnnouncementsPrefix = "/admin/announcements/"
+	announcementsPrefix      = "/announcements/"
+)
+
+// tối đa user trong 1 thông báo có chọn người nhận
+const maxAnnouncementTargets = 5000
+
+func (s *Server) mountAnnouncementRoutes(mux *http.ServeMux) {
+	// GET  /admin/announcements?active=1&before_id=&limit=
+	// POST /admin/announcements {title, body, level, user_ids, require_ack, expires_at | expires_in_hours}
+	mux.Handle("/admin/announcements", s.RequireAdmin(http.HandlerFunc(s.handleAdminAnnouncements)))
+	// GET    /admin/announcements/{id}
+	// GET    /admin/announcements/{id}/acks?before_id=&limit=
+	// DELETE /admin/announcements/{id} -> thu hồi
+	mux.Handle(adminAnnouncementsPrefix, s.RequireAdmin(http.HandlerFunc(s.handleAdminAnnouncement)))
+
+	// GET  /announcements -> thông báo còn hiệu lực của mình (chưa xác nhận lên trước)
+	mux.Handle("/announcements", http.HandlerFunc(s.handleMyAnnouncements))
+	// POST /announcements/{id}/ack
+	mux.Handle(announcementsPrefix, http.HandlerFunc(s.handleAckAnnouncement))
+}
+
+type createAnnouncementRequest struct {
+	Title          string  `json:"title"`
+	Body           string  `json:"body"`
+	Level          string  `json:"level"`
+	UserIDs        []int64 `json:"user_ids"` // rỗng = toàn hệ thống
+	RequireAck     bool    `json:"require_ack"`
+	ExpiresAt      string  `json:"expires_at"` // RFC3339
+	ExpiresInHours int     `json:"expires_in_hours"`
+}
+
+func (s *Server) handleAdminAnnouncements(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		beforeID, limit := parsePaging(r)
+		activeOnly := r.URL.Query().Get("active") == "1"
+		list, err := s.announcementRepo.List(r.Context(), activeOnly, beforeID, limit)
+		if err != nil {
+			log.Println("announcement List error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"announcements": list})
+	case http.MethodPost:
+		s.createAnnouncement(w, r)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func (s *Server) createAnnouncement(w http.ResponseWriter, r *http.Request) {
+	adminID, _ := UserIDFromContext(r.Context())
+
+	var req createAnnouncementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+
+	a := &announcement.Announcement{
+		Title:      strings.TrimSpace(req.Title),
+		Body:       strings.TrimSpace(req.Body),
+		Level:      strings.ToLower(strings.TrimSpace(req.Level)),
+		RequireAck: req.RequireAck,
+		CreatedBy:  adminID,
+	}
+	if a.Title == "" || len([]rune(a.Title)) > 200 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "title is required (max 200 chars)"})
+		return
+	}
+	if len([]rune(a.Body)) > 5000 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "body too long (max 5000 chars)"})
+		return
+	}
+	if a.Level == "" {
+		a.Level = announcement.LevelInfo
+	}
+	if !announcement.IsValidLevel(a.Level) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "level must be info, warning or critical"})
+		return
+	}
+
+	now := s.now()
+	switch {
+	case req.ExpiresAt != "":
+		t, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil || !t.After(now) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "expires_at must be a future RFC3339 time"})
+			return
+		}
+		a.ExpiresAt = &t
+	case req.ExpiresInHours > 0:
+		t := now.Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		a.ExpiresAt = &t
+	}
+
+	// chọn người nhận: bỏ trùng + kiểm tra tồn tại
+	var targets []int64
+	if len(req.UserIDs) > maxAnnouncementTargets {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "too many user_ids (max " + strconv.Itoa(maxAnnouncementTargets) + ")"})
+		return
+	}
+	seen := map[int64]bool{}
+	for _, uid := range req.UserIDs {
+		if uid <= 0 || seen[uid] {
+			continue
+		}
+		seen[uid] = true
+		if _, err := s.userRepo.GetUserByID(int(uid)); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user not found: " + strconv.FormatInt(uid, 10)})
+			return
+		}
+		targets = append(targets, uid)
+	}
+
+	ctx := r.Context()
+	if err := s.announcementRepo.Create(ctx, a, targets); err != nil {
+		log.Println("announcement Create error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	writeJSON(w, http.StatusCreated, a)
+
+	// realtime: user offline sẽ thấy khi mở app (GET /announcements)
+	recipients := targets
+	if a.Audience == announcement.AudienceAll {
+		recipients = wsOnlineUserIDs()
+	}
+	wsSendToUsers(recipients, wsEnvelope{Type: "announcement", Data: a})
+}
+
+func (s *Server) handleAdminAnnouncement(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, adminAnnouncementsPrefix), "/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid announcement id"})
+		return
+	}
+
+	ctx := r.Context()
+	a, err := s.announcementRepo.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, announcement.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "announcement not found"})
+			return
+		}
+		log.Println("announcement Get error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, a)
+
+	case len(parts) == 2 && parts[1] == "acks" && r.Method == http.MethodGet:
+		beforeID, limit := parsePaging(r)
+		acks, err := s.announcementRepo.ListAcks(ctx, id, beforeID, limit)
+		if err != nil {
+			log.Println("announcement ListAcks error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"ack_count":    a.AckCount,
+			"target_count": a.TargetCount,
+			"acks":         acks,
+		})
+
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		if err := s.announcementRepo.Revoke(ctx, id); err != nil {
+			if errors.Is(err, announcement.ErrNotFound) {
+				writeJSON(w, http.StatusConflict, map[string]string{"error": "announcement already revoked"})
+				return
+			}
+			log.Println("announcement Revoke error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"id": id, "revoked": true})
+
+		recipients := wsOnlineUserIDs()
+		if a.Audience == announcement.AudienceUsers {
+			if recipients, err = s.announcementRepo.TargetUserIDs(ctx, id); err != nil {
+				log.Println("announcement TargetUserIDs error:", err)
+				return
+			}
+		}
+		wsSendToUsers(recipients, wsEnvelope{Type: "announcement_revoked", Data: map[string]int64{"id": id}})
+
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
+
+func (s *Server) handleMyAnnouncements(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	list, err := s.announcementRepo.ListForUser(r.Context(), userID, s.now())
+	if err != nil {
+		log.Println("announcement ListForUser error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	unacked := 0
+	for _, a := range list {
+		if a.AckedAt == nil {
+			unacked++
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"announcements": list, "unacked": unacked})
+}
+
+func (s *Server) handleAckAnnouncement(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, announcementsPrefix), "/"), "/")
+	if len(parts) != 2 || parts[1] != "ack" {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid announcement id"})
+		return
+	}
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	ctx := r.Context()
+	a, err := s.announcementRepo.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, announcement.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "announcement not found"})
+			return
+		}
+		log.Println("announcement Get error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	ok, err := s.announcementRepo.IsTarget(ctx, a, userID)
+	if err != nil {
+		log.Println("announcement IsTarget error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "announcement not found"})
+		return
+	}
+
+	at, err := s.announcementRepo.Ack(ctx, id, userID)
+	if err != nil {
+		log.Println("announcement Ack error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"id": id, "acked_at": at})
+
+	// đồng bộ các thiết bị khác của user -> ẩn banner
+	wsSendToUser(userID, wsEnvelope{Type: "announcement_acked", Data: map[string]any{"id": id, "acked_at": at}})
+}
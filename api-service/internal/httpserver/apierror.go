@@ -0,0 +1,143 @@
+package httpserver
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// mã lỗi ổn định cho FE (field "code"), message có thể đổi câu chữ, code thì không
+const (
+	CodeInvalidJSON      = "INVALID_JSON"
+	CodeInvalidRequest   = "INVALID_REQUEST" // thiếu / sai field, path param
+	CodeUnauthorized     = "UNAUTHORIZED"
+	CodeForbidden        = "FORBIDDEN"
+	CodeNotMember        = "NOT_MEMBER" // không ở trong room
+	CodeNotFound         = "NOT_FOUND"
+	CodeMethodNotAllowed = "METHOD_NOT_ALLOWED"
+	CodeConflict         = "CONFLICT"
+	CodePayloadTooLarge  = "PAYLOAD_TOO_LARGE"
+	CodeUnsupportedMedia = "UNSUPPORTED_MEDIA_TYPE"
+	CodeFileBlocked      = "FILE_BLOCKED" // file bị cách ly sau khi quét virus
+	CodeRateLimited      = "RATE_LIMITED"
+	CodeSuspended        = "ACCOUNT_SUSPENDED"
+	CodeLocked           = "LOCKED" // đăng nhập sai quá nhiều, khoá tạm
+	CodeDBError          = "DB_ERROR"
+	CodeInternal         = "INTERNAL_ERROR"
+)
+
+// tạo / sửa user
+const (
+	CodeMissingFields  = "MISSING_FIELDS"
+	CodeInvalidRole    = "INVALID_ROLE"
+	CodeWeakPassword   = "WEAK_PASSWORD"
+	CodeInvalidEmail   = "INVALID_EMAIL"
+	CodeInvalidPhone   = "INVALID_PHONE"
+	CodeUsernameExists = "USERNAME_EXISTS"
+)
+
+// apiError: format lỗi chung
+// error = message: FE cũ vẫn đọc field "error"
+type apiError struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Error     string `json:"error"`
+	Details   any    `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// writeError: trả lỗi theo apiError (request_id gắn trong writeJSON)
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeErrorDetails(w, status, code, message, nil)
+}
+
+func writeErrorDetails(w http.ResponseWriter, status int, code, message string, details any) {
+	if code == "" {
+		code = codeForStatus(status, message)
+	}
+	writeJSON(w, status, &apiError{Code: code, Message: message, Error: message, Details: details})
+}
+
+// codeForStatus: code mặc định khi handler chưa chỉ định (lỗi kiểu map[string]string{"error": ...} cũ)
+func codeForStatus(status int, message string) string {
+	switch status {
+	case http.StatusBadRequest:
+		if strings.Contains(strings.ToLower(message), "json") {
+			return CodeInvalidJSON
+		}
+		return CodeInvalidRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		if strings.Contains(message, "not a member") {
+			return CodeNotMember
+		}
+		if strings.Contains(message, "suspended") {
+			return CodeSuspended
+		}
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupportedMedia
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusLocked:
+		return CodeLocked
+	}
+	if status >= 500 && message == "db error" {
+		return CodeDBError
+	}
+	return CodeInternal
+}
+
+// legacyError: map[string]string{"error": ...} -> apiError, key khác (vd suspended_until) vào details
+func legacyError(status int, m map[string]string) *apiError {
+	e := &apiError{Message: m["error"], Error: m["error"], Code: m["code"]}
+	if e.Code == "" {
+		e.Code = codeForStatus(status, e.Message)
+	}
+	details := map[string]string{}
+	for k, v := range m {
+		if k != "error" && k != "code" && k != "request_id" {
+			details[k] = v
+		}
+	}
+	if len(details) > 0 {
+		e.Details = details
+	}
+	return e
+}
+
+// apiFailure: lỗi chưa ghi ra response, cho logic dùng chung REST / WS (send_message)
+// body giữ đúng shape REST đang trả (*apiError hoặc map có field phụ như suspended_until, words)
+type apiFailure struct {
+	status     int
+	body       any
+	retryAfter int // > 0 -> header Retry-After
+}
+
+func newFailure(status int, code, message string) *apiFailure {
+	return &apiFailure{status: status, body: &apiError{Code: code, Message: message, Error: message}}
+}
+
+func (f *apiFailure) write(w http.ResponseWriter) {
+	if f.retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(f.retryAfter))
+	}
+	writeJSON(w, f.status, f.body)
+}
+
+// payload: body như REST trả (map lỗi cũ đổi sang apiError giống writeJSON)
+func (f *apiFailure) payload() any {
+	if m, ok := f.body.(map[string]string); ok {
+		return legacyError(f.status, m)
+	}
+	return f.body
+}
@@ -0,0 +1,190 @@
+package httpserver
+
+import (
+	"cronhustler/api-service/internal/apikey"
+	"cronhustler/api-service/internal/audit"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// API key: service ngoài / cron gửi header X-API-Key thay cho JWT, request chạy dưới tên user của key
+// (role luôn là user) và chỉ vào được route trong apiKeyRoutes có scope key được cấp
+const (
+	apiKeyHeader   = "X-API-Key"
+	apiKeysPrefix  = "/admin/api-keys/"
+	apiKeyNameMax  = 100
+	apiKeyMaxScope = 10
+)
+
+type apiKeyRoute struct {
+	method string
+	path   string // kết thúc bằng "/" = prefix
+	scope  string
+}
+
+var apiKeyRoutes = []apiKeyRoute{
+	{http.MethodPost, "/rooms/send-messages/", apikey.ScopeSendMessage},
+	{http.MethodGet, "/rooms", apikey.ScopeReadRooms},
+	{http.MethodGet, "/rooms/messages/", apikey.ScopeReadRooms},
+	{http.MethodGet, "/rooms/members/", apikey.ScopeReadRooms},
+}
+
+// apiKeyScopeFor: scope cần cho route, rỗng = route không mở cho API key
+func apiKeyScopeFor(method, path string) string {
+	for _, rt := range apiKeyRoutes {
+		if method != rt.method {
+			continue
+		}
+		if rt.path == path || (strings.HasSuffix(rt.path, "/") && strings.HasPrefix(path, rt.path)) {
+			return rt.scope
+		}
+	}
+	return ""
+}
+
+// authAPIKey: X-API-Key hợp lệ + đủ scope -> claims của user sở hữu key, false = đã trả lỗi
+func (s *Server) authAPIKey(w http.ResponseWriter, r *http.Request, plain string) (*Claims, bool) {
+	k, err := s.apiKeyRepo.Authenticate(r.Context(), plain, s.now())
+	if err != nil {
+		if !errors.Is(err, apikey.ErrNotFound) {
+			log.Println("apikey Authenticate error:", err)
+			writeError(w, http.StatusInternalServerError, CodeDBError, "db error")
+			return nil, false
+		}
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "invalid or revoked api key")
+		return nil, false
+	}
+
+	scope := apiKeyScopeFor(r.Method, r.URL.Path)
+	if scope == "" {
+		writeError(w, http.StatusForbidden, CodeForbidden, "this endpoint does not accept api keys")
+		return nil, false
+	}
+	if !k.HasScope(scope) {
+		writeError(w, http.StatusForbidden, CodeForbidden, "api key is missing scope "+scope)
+		return nil, false
+	}
+
+	return &Claims{UserID: int(k.UserID), Username: k.Username, Role: "user", TokenType: TokenTypeAccess}, true
+}
+
+func (s *Server) mountAPIKeyRoutes(mux *http.ServeMux) {
+	// GET  /admin/api-keys -> danh sách key (không có key thật, chỉ prefix)
+	// POST /admin/api-keys {name, user_id, scopes: [send_message|read_rooms], expires_at} -> {api_key, key}
+	mux.Handle("/admin/api-keys", s.requireUserAdmin(http.HandlerFunc(s.handleAdminAPIKeys)))
+	// DELETE /admin/api-keys/{id} -> thu hồi
+	m := newPatternMux(apiKeysPrefix)
+	m.handle(http.MethodDelete, apiKeysPrefix+"{id}", withPathID("id", "invalid id", s.handleRevokeAPIKey))
+	mux.Handle(apiKeysPrefix, s.requireUserAdmin(m))
+}
+
+func (s *Server) handleAdminAPIKeys(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		list, err := s.apiKeyRepo.List(r.Context())
+		if err != nil {
+			log.Println("apikey List error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"api_keys": list})
+	case http.MethodPost:
+		s.createAPIKey(w, r)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+type apiKeyRequest struct {
+	Name      string     `json:"name"`
+	UserID    int64      `json:"user_id"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (s *Server) createAPIKey(w http.ResponseWriter, r *http.Request) {
+	adminID, _ := UserIDFromContext(r.Context())
+
+	var req apiKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len([]rune(req.Name)) > apiKeyNameMax {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "name is required (max 100 chars)"})
+		return
+	}
+	if len(req.Scopes) == 0 || len(req.Scopes) > apiKeyMaxScope {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": apikey.ErrInvalidScope.Error()})
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(s.now()) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "expires_at must be in the future"})
+		return
+	}
+	if req.UserID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user_id is required"})
+		return
+	}
+	u, err := s.userRepo.GetUserByID(int(req.UserID))
+	if err != nil || u.Is_active == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user not found or inactive"})
+		return
+	}
+
+	k := &apikey.Key{
+		Name:      req.Name,
+		UserID:    req.UserID,
+		Username:  u.Username,
+		Scopes:    req.Scopes,
+		CreatedBy: adminID,
+		ExpiresAt: req.ExpiresAt,
+	}
+	plain, err := s.apiKeyRepo.Create(r.Context(), k)
+	if err != nil {
+		if errors.Is(err, apikey.ErrInvalidScope) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		log.Println("apikey Create error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	s.recordAudit(r, adminID, audit.ActionAPIKeyCreate, audit.TargetAPIKey, k.ID, map[string]any{
+		"name":    k.Name,
+		"user_id": k.UserID,
+		"scopes":  k.Scopes,
+		"prefix":  k.Prefix,
+	})
+	// key thật chỉ trả 1 lần
+	writeJSON(w, http.StatusCreated, map[string]any{"api_key": plain, "key": k})
+}
+
+func (s *Server) handleRevokeAPIKey(w http.ResponseWriter, r *http.Request, id int64) {
+	ctx := r.Context()
+	k, err := s.apiKeyRepo.Get(ctx, id)
+	if err == nil {
+		err = s.apiKeyRepo.Revoke(ctx, id, s.now())
+	}
+	if err != nil {
+		if errors.Is(err, apikey.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "api key not found or already revoked"})
+			return
+		}
+		log.Println("apikey Revoke error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	adminID, _ := UserIDFromContext(ctx)
+	s.recordAudit(r, adminID, audit.ActionTokenRevoke, audit.TargetAPIKey, id, map[string]any{
+		"token":  "api_key",
+		"name":   k.Name,
+		"prefix": k.Prefix,
+	})
+	writeJSON(w, http.StatusOK, map[string]any{"revoked": id})
+}
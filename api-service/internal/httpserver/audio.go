@@ -0,0 +1,205 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/chat"
+	"cronhustler/api-service/internal/media"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// thời gian tối đa để tính waveform lúc gửi message
+const waveformTimeout = 15 * time.Second
+
+// SetFFmpegPath: ffmpeg dùng decode audio khi tính waveform (rỗng = tìm trong PATH)
+// ffprobe (metadata video/audio) tìm cạnh ffmpeg hoặc trong PATH
+func (s *Server) SetFFmpegPath(p string) {
+	s.ffmpegPath = strings.TrimSpace(p)
+	s.ffprobePath = media.FindFFprobe(s.ffmpegPath)
+}
+
+// audioUploadMime: http.DetectContentType không phân biệt webm/m4a audio với video
+// -> dựa thêm vào Content-Type client khai báo hoặc đuôi file
+func audioUploadMime(sniffed, declared, ext string) (string, bool) {
+	switch strings.ToLower(sniffed) {
+	case "audio/wave", "audio/wav", "audio/x-wav":
+		return "audio/wav", true
+	case "audio/mpeg":
+		return "audio/mpeg", true
+	case "application/ogg", "audio/ogg":
+		return "audio/ogg", true
+	case "video/webm", "video/mp4":
+		declared = strings.ToLower(strings.TrimSpace(declared))
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(declared, "audio/") && !isAudioExt(ext) {
+			return "", false
+		}
+		if sniffed == "video/webm" {
+			return "audio/webm", true
+		}
+		return "audio/mp4", true
+	default:
+		return "", false
+	}
+}
+
+func isAudioExt(ext string) bool {
+	switch strings.ToLower(ext) {
+	case ".wav", ".mp3", ".ogg", ".opus", ".weba", ".m4a":
+		return true
+	default:
+		return false
+	}
+}
+
+func audioMimeFromExt(ext string) string {
+	switch strings.ToLower(ext) {
+	case ".wav":
+		return "audio/wav"
+	case ".mp3":
+		return "audio/mpeg"
+	case ".ogg", ".opus":
+		return "audio/ogg"
+	case ".weba":
+		return "audio/webm"
+	case ".m4a":
+		return "audio/mp4"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// fillAudioWaveform: tính waveform cho attachment audio, lỗi thì chỉ log (peaks rỗng)
+func (s *Server) fillAudioWaveform(ctx context.Context, att *chat.Attachment, fullPath string) {
+	wctx, cancel := context.WithTimeout(ctx, waveformTimeout)
+	defer cancel()
+
+	wf, err := media.ComputeWaveform(wctx, s.ffmpegPath, fullPath, media.DefaultWaveformBuckets)
+	switch {
+	case err == nil:
+		att.Waveform = wf.Peaks
+		att.DurationMs = wf.DurationMs
+	case errors.Is(err, media.ErrWaveformUnsupported):
+		// không có ffmpeg / format lạ -> bỏ qua waveform
+	default:
+		log.Printf("[waveform] %s: %v", att.FileName, err)
+	}
+}
+
+// signAttachments: ký file_path + download_url trước khi trả về client
+func (s *Server) signAttachments(atts []chat.Attachment) []chat.Attachment {
+	if len(atts) == 0 {
+		return nil
+	}
+	out := make([]chat.Attachment, len(atts))
+	for i, a := range atts {
+		if strings.HasPrefix(a.FilePath, chatUploadPrefix) {
+			a.DownloadURL = s.signMediaURL(chatDownloadPrefix + strings.TrimPrefix(a.FilePath, chatUploadPrefix))
+		}
+		a.FilePath = s.signMediaURL(a.FilePath)
+		a.ThumbURL = s.signMediaURL(a.ThumbURL)
+		a.MediumURL = s.signMediaURL(a.MediumURL)
+		out[i] = a
+	}
+	return out
+}
+
+// isAllowedAudioMime: mime voice message sau khi qua audioUploadMime
+func isAllowedAudioMime(m string) bool {
+	switch strings.ToLower(m) {
+	case "audio/wav", "audio/mpeg", "audio/ogg", "audio/webm", "audio/mp4":
+		return true
+	default:
+		return false
+	}
+}
+
+// isAudioMessageContent: message_type audio phải trỏ tới file audio đã upload
+func isAudioMessageContent(content string) bool {
+	name, ok := strings.CutPrefix(content, chatUploadPrefix)
+	if !ok || name == "" || name != filepath.Base(name) {
+		return false
+	}
+	return isAudioExt(filepath.Ext(name))
+}
+
+// POST /rooms/upload-audio/{roomID} (multipart field "file": webm / ogg / m4a / mp3 / wav)
+// -> FE gửi message_type=audio với content = media_url
+func (s *Server) handleUploadRoomAudio(w http.ResponseWriter, r *http.Request) {
+	userID, roomID, ok := s.authorizeRoomUpload(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, chatUploadMaxBytes+64<<10)
+	if err := r.ParseMultipartForm(chatUploadMaxBytes); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeChatUploadError(w, errUploadTooLarge)
+			return
+		}
+		http.Error(w, "cannot parse form", http.StatusBadRequest)
+		return
+	}
+	defer func() { _ = r.MultipartForm.RemoveAll() }()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "missing file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		http.Error(w, "file read error", http.StatusBadRequest)
+		return
+	}
+
+	sniffed := http.DetectContentType(head[:n])
+	mime, ok := audioUploadMime(sniffed, header.Header.Get("Content-Type"), filepath.Ext(header.Filename))
+	if !ok || !isAllowedAudioMime(mime) {
+		http.Error(w, "unsupported audio type", http.StatusBadRequest)
+		return
+	}
+
+	up, err := s.storeChatUploadLimit(roomID, userID, mime, mimeToExt(mime), header.Filename, file, chatUploadMaxBytes)
+	defer s.releaseChatUpload(up)
+	if err != nil {
+		if !errors.Is(err, errUploadTooLarge) {
+			log.Println("storeChatUpload (audio) error:", err)
+		}
+		writeChatUploadError(w, err)
+		return
+	}
+
+	// ffprobe không có -> lấy duration từ waveform (ffmpeg) để FE hiện độ dài ngay
+	if (up.Meta == nil || up.Meta.DurationMs == 0) && up.localPath != "" {
+		var att chat.Attachment
+		s.fillAudioWaveform(r.Context(), &att, up.localPath)
+		if att.DurationMs > 0 {
+			up.Meta = &media.Metadata{DurationMs: att.DurationMs}
+		}
+	}
+
+	s.writeChatUploadResponse(w, roomID, up)
+}
+
+// applyAudioMessage: ghi media_url / mime / size / duration của voice message lên message
+// (lấy từ attachment vừa tạo), trả về duration
+func (s *Server) applyAudioMessage(ctx context.Context, messageID int64, content string, atts []chat.Attachment) int64 {
+	if len(atts) == 0 {
+		return 0
+	}
+	a := atts[0]
+	if err := s.chatRepo.UpdateMessageAudio(ctx, messageID, content, a.ContentType, a.FileSize, a.DurationMs); err != nil {
+		log.Println("UpdateMessageAudio error:", err)
+	}
+	return a.DurationMs
+}
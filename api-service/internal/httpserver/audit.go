@@ -0,0 +1,150 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/audit"
+	"cronhustler/api-service/internal/webhook"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func (s *Server) mountAuditRoutes(mux *http.ServeMux) {
+	// GET  /admin/audit?actor_id=&action=&target_type=&target_id=&from=&to=&before_id=&limit=
+	mux.Handle("/admin/audit", s.RequireAdmin(http.HandlerFunc(s.handleAdminAudit)))
+	// POST /admin/users/role {user_id, role: user|admin}
+	mux.Handle("/admin/users/role", s.RequireAdmin(http.HandlerFunc(s.handleChangeUserRole)))
+}
+
+// recordAudit: ghi sự kiện admin / bảo mật, lỗi chỉ log (không chặn request)
+func (s *Server) recordAudit(r *http.Request, actorID int64, action, targetType string, targetID int64, details map[string]any) {
+	// gọi bằng service token: actor = 0, ghi tên service
+	if svc := serviceFromContext(r.Context()); svc != nil {
+		if details == nil {
+			details = map[string]any{}
+		}
+		details["via_service"] = svc.Service
+	}
+	e := &audit.Event{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    details,
+		IP:         getIP(r),
+	}
+	// context riêng: client ngắt kết nối vẫn phải ghi được
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.auditRepo.Record(ctx, e); err != nil {
+		log.Printf("[audit] record %s actor=%d target=%s/%d: %v", action, actorID, targetType, targetID, err)
+	}
+}
+
+// parseAuditTime: RFC3339 hoặc YYYY-MM-DD (giờ local)
+func parseAuditTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", v, time.Local)
+}
+
+func (s *Server) handleAdminAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	q := r.URL.Query()
+	f := audit.Filter{
+		Action:     q.Get("action"),
+		TargetType: q.Get("target_type"),
+	}
+	f.ActorID, _ = strconv.ParseInt(q.Get("actor_id"), 10, 64)
+	f.TargetID, _ = strconv.ParseInt(q.Get("target_id"), 10, 64)
+	f.BeforeID, f.Limit = parsePaging(r)
+
+	if v := q.Get("from"); v != "" {
+		t, err := parseAuditTime(v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "from must be RFC3339 or YYYY-MM-DD"})
+			return
+		}
+		f.From = t
+	}
+	if v := q.Get("to"); v != "" {
+		t, err := parseAuditTime(v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "to must be RFC3339 or YYYY-MM-DD"})
+			return
+		}
+		// to=YYYY-MM-DD -> lấy hết ngày đó
+		if len(v) == len("2006-01-02") {
+			t = t.AddDate(0, 0, 1)
+		}
+		f.To = t
+	}
+
+	list, err := s.auditRepo.List(r.Context(), f)
+	if err != nil {
+		log.Println("audit List error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"events": list})
+}
+
+type changeRoleRequest struct {
+	UserID int64  `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+func (s *Server) handleChangeUserRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	adminID, _ := UserIDFromContext(r.Context())
+
+	var req changeRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+	if req.Role != "admin" && req.Role != "user" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "role must be admin or user"})
+		return
+	}
+	if req.UserID == adminID {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "cannot change your own role"})
+		return
+	}
+
+	u, err := s.userRepo.GetUserByID(int(req.UserID))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "user not found"})
+		return
+	}
+	if u.Role == "superadmin" {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "cannot change role of superadmin"})
+		return
+	}
+	if u.Role == req.Role {
+		writeJSON(w, http.StatusOK, map[string]any{"user_id": req.UserID, "role": req.Role, "changed": false})
+		return
+	}
+
+	if err := s.userRepo.UpdateUserDynamic(req.UserID, map[string]interface{}{"role": req.Role}); err != nil {
+		log.Println("change role error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	s.recordAudit(r, adminID, audit.ActionUserRoleChange, audit.TargetUser, req.UserID, map[string]any{
+		"from": u.Role,
+		"to":   req.Role,
+	})
+	s.emitUserEvent(webhook.EventUserUpdated, req.UserID, map[string]any{"source": "admin", "changed": []string{"role"}})
+	writeJSON(w, http.StatusOK, map[string]any{"user_id": req.UserID, "role": req.Role, "changed": true})
+}
@@ -1,9 +1,10 @@
 package httpserver
 
 import (
-	"crypto/sha256"
+	"cronhustler/api-service/internal/audit"
+	"cronhustler/api-service/internal/reqlog"
+	"cronhustler/api-service/internal/user"
 	"database/sql"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"log"
@@ -24,16 +25,12 @@ func (s *Server) mountAuthRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/logout", s.handleLogout) // 👈 thêm nè
 
 	mux.HandleFunc("/auth/refresh", s.handleRefreshToken)
+	s.mountSessionRoutes(mux)
+	s.mountOAuthRoutes(mux)
 	// nếu muốn logout xoá cookie thì thêm:
 	// mux.HandleFunc("/logout", s.handleLogout)
 }
 
-// hàm tiện ích để hash password
-func hashPassword(pw string) string {
-	h := sha256.Sum256([]byte(pw))
-	return hex.EncodeToString(h[:])
-}
-
 type loginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
@@ -86,6 +83,11 @@ func (s *Server) VerifyWSAuth(r *http.Request) (int64, error) {
 		return 0, errors.New("invalid token type for ws")
 	}
 
+	// session đã bị đăng xuất từ thiết bị khác
+	if err := s.checkSession(r, claims); err != nil {
+		return 0, err
+	}
+
 	// 4) OK
 	return int64(claims.UserID), nil
 }
@@ -106,9 +108,19 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// sai quá nhiều lần -> khoá tạm, không check mật khẩu nữa
+	if f := s.loginLockFailure(r.Context(), req.Username, getIP(r)); f != nil {
+		f.write(w)
+		return
+	}
+
 	u, err := s.userRepo.FindByUsername(req.Username)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
+			if f := s.recordLoginFailure(r, req.Username, 0); f != nil {
+				f.write(w)
+				return
+			}
 			writeJSON(w, http.StatusUnauthorized, loginResponse{Error: "invalid credentials"})
 			return
 		}
@@ -125,39 +137,92 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Hash input password
-	hashedInput := hashPassword(req.Password)
-	if u.Password != hashedInput {
+	// so mật khẩu (hash sha256 cũ -> tự lưu lại bằng bcrypt)
+	if !s.verifyPassword(int64(u.ID), u.Password, req.Password) {
+		if err := s.userRepo.RecordLogin(int64(u.ID), getIP(r), r.UserAgent(), false); err != nil {
+			log.Println("RecordLogin error:", err)
+		}
+		if f := s.recordLoginFailure(r, req.Username, int64(u.ID)); f != nil {
+			f.write(w)
+			return
+		}
 		writeJSON(w, http.StatusUnauthorized, loginResponse{Error: "invalid credentials"})
 		return
 	}
+	s.resetLoginFailures(req.Username)
+
+	// tài khoản đang bị moderation khoá
+	if s.rejectIfSuspended(w, r, int64(u.ID)) {
+		return
+	}
+
+	// bật 2FA SMS -> gửi OTP, token cấp ở POST /login/otp
+	if s.startLoginOTP(w, r, u) {
+		return
+	}
+
+	s.completeLogin(w, r, u)
+}
+
+// completeLogin: mật khẩu (và OTP nếu có) đã đúng -> ghi audit login, cấp token
+func (s *Server) completeLogin(w http.ResponseWriter, r *http.Request, u *user.User) {
+	accessToken, f := s.issueLogin(w, r, u)
+	if f != nil {
+		f.write(w)
+		return
+	}
 
+	// 👉 Gửi response FULL DATA nhưng KHÔNG gửi refreshToken nữa
+	writeJSON(w, http.StatusOK, loginResponse{
+		ID:          int64(u.ID),
+		Username:    u.Username,
+		Full_Name:   nsToString(u.Full_name),
+		Email:       nsToString(u.Email),
+		Phone:       nsToString(u.Phone),
+		AvatarURL:   s.signMediaURL(nsToString(u.AvatarURL)),
+		Role:        u.Role,
+		LastLogin:   nsToString(u.Last_login),
+		LoginIP:     nsToString(u.Login_ip),
+		CreatedIp:   nsToString(u.Created_ip),
+		AccessToken: accessToken,
+	})
+}
+
+// issueLogin: ghi audit login + tạo session, set cookie refresh_token, trả access token
+// (dùng chung cho /login, /login/otp và social login)
+func (s *Server) issueLogin(w http.ResponseWriter, r *http.Request, u *user.User) (string, *apiFailure) {
 	// Lấy IP request
 	ip := getIP(r)
-	loginTime := time.Now().Format("2006-01-02 15:04:05")
+	loginTime := s.now().Format("2006-01-02 15:04:05")
 
 	// 🔥 Update login IP + last_login
 	if err := s.userRepo.UpdateLoginAudit(u.Username, ip, loginTime); err != nil {
 		log.Println("update login audit error:", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{
+		return "", &apiFailure{status: http.StatusInternalServerError, body: map[string]string{
 			"error": "failed to update login info",
-		})
-		return
+		}}
+	}
+	if err := s.userRepo.RecordLogin(int64(u.ID), ip, r.UserAgent(), true); err != nil {
+		log.Println("RecordLogin error:", err)
 	}
 
 	// Tạo tokens
 	accessToken, err := GenerateAccessToken(int(u.ID), u.Username, u.Role, s.jwtSecret)
 	if err != nil {
 		log.Println("jwt error:", err)
-		writeJSON(w, http.StatusInternalServerError, loginResponse{Error: "cannot generate access token"})
-		return
+		return "", &apiFailure{status: http.StatusInternalServerError, body: loginResponse{Error: "cannot generate access token"}}
+	}
+
+	sessionID, err := s.startSession(r, int64(u.ID))
+	if err != nil {
+		log.Println("startSession error:", err)
+		return "", &apiFailure{status: http.StatusInternalServerError, body: loginResponse{Error: "cannot create session"}}
 	}
 
-	refreshToken, err := GenerateRefreshToken(int(u.ID), u.Username, s.jwtSecret)
+	refreshToken, err := GenerateRefreshToken(int(u.ID), u.Username, sessionID, s.jwtSecret)
 	if err != nil {
 		log.Println("jwt error:", err)
-		writeJSON(w, http.StatusInternalServerError, loginResponse{Error: "cannot generate refresh token"})
-		return
+		return "", &apiFailure{status: http.StatusInternalServerError, body: loginResponse{Error: "cannot generate refresh token"}}
 	}
 
 	// 👉 Set refresh token vào HttpOnly cookie
@@ -168,23 +233,12 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		HttpOnly: true,
 		Secure:   false, // Để true khi chạy HTTPS
 		SameSite: http.SameSiteLaxMode,
-		Expires:  time.Now().Add(RefreshTokenTTL),
+		Expires:  s.now().Add(RefreshTokenTTL),
 	})
 
-	// 👉 Gửi response FULL DATA nhưng KHÔNG gửi refreshToken nữa
-	writeJSON(w, http.StatusOK, loginResponse{
-		ID:          int64(u.ID),
-		Username:    u.Username,
-		Full_Name:   nsToString(u.Full_name),
-		Email:       nsToString(u.Email),
-		Phone:       nsToString(u.Phone),
-		AvatarURL:   nsToString(u.AvatarURL),
-		Role:        u.Role,
-		LastLogin:   nsToString(u.Last_login),
-		LoginIP:     nsToString(u.Login_ip),
-		CreatedIp:   nsToString(u.Created_ip),
-		AccessToken: accessToken,
-	})
+	// DAU/MAU
+	s.analyticsRepo.Touch(r.Context(), int64(u.ID))
+	return accessToken, nil
 }
 
 // POST /auth/refresh
@@ -223,6 +277,20 @@ func (s *Server) handleRefreshToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := s.checkSession(r, claims); err != nil {
+		if !errors.Is(err, user.ErrSessionRevoked) {
+			log.Println("checkSession error:", err)
+		}
+		writeJSON(w, http.StatusUnauthorized, refreshResponse{
+			Error: "session revoked",
+		})
+		return
+	}
+
+	if s.rejectIfSuspended(w, r, int64(claims.UserID)) {
+		return
+	}
+
 	// 👉 Generate access token mới
 	accessToken, err := GenerateAccessToken(claims.UserID, claims.Username, claims.Role, s.jwtSecret)
 	if err != nil {
@@ -246,12 +314,23 @@ func (s *Server) handleRefreshToken(w http.ResponseWriter, r *http.Request) {
 	// 	})
 	// }
 
+	s.analyticsRepo.Touch(r.Context(), int64(claims.UserID))
+
 	writeJSON(w, http.StatusOK, refreshResponse{
 		AccessToken: accessToken,
 	})
 }
 
 func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	// lỗi -> format apiError + request_id để đối chiếu log
+	if status >= 400 {
+		if m, ok := v.(map[string]string); ok && m["error"] != "" {
+			v = legacyError(status, m)
+		}
+		if e, ok := v.(*apiError); ok && e.RequestID == "" {
+			e.RequestID = w.Header().Get(reqlog.Header)
+		}
+	}
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(v)
@@ -263,6 +342,13 @@ func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// thu hồi session của cookie hiện tại (không còn trong /auth/sessions)
+	if claims := s.refreshClaims(r); claims != nil && claims.ID != "" {
+		if _, err := s.userRepo.RevokeSession(r.Context(), int64(claims.UserID), claims.ID); err != nil {
+			log.Println("RevokeSession error:", err)
+		}
+	}
+
 	// Set cookie refresh_token hết hạn → xoá
 	http.SetCookie(w, &http.Cookie{
 		Name:     "refresh_token",
@@ -275,6 +361,10 @@ func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
 		SameSite: http.SameSiteLaxMode,
 	})
 
+	if uid, err := UserIDFromContext(r.Context()); err == nil {
+		s.recordAudit(r, uid, audit.ActionTokenRevoke, audit.TargetUser, uid, map[string]any{"token": "refresh_token", "reason": "logout"})
+	}
+
 	writeJSON(w, http.StatusOK, map[string]string{
 		"message": "logged out",
 	})
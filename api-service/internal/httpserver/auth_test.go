@@ -0,0 +1,432 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/audit"
+	"cronhustler/api-service/internal/lockout"
+	"cronhustler/api-service/internal/oauth"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcryptHashArg: arg là bcrypt hash của mật khẩu pw (salt ngẫu nhiên -> không so chuỗi được)
+type bcryptHashArg struct{ pw string }
+
+func (a bcryptHashArg) Match(v driver.Value) bool {
+	h, ok := v.(string)
+	return ok && bcrypt.CompareHashAndPassword([]byte(h), []byte(a.pw)) == nil
+}
+
+func TestLogin(t *testing.T) {
+	const findUser = `SELECT \* FROM users WHERE username = \?`
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		setup      func(mock sqlmock.Sqlmock)
+		wantStatus int
+		wantCookie bool
+	}{
+		{
+			name:       "wrong method",
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "invalid json",
+			body:       `{`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "missing password",
+			body:       `{"username":"alice"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "unknown user",
+			body: `{"username":"ghost","password":"x"}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(findUser).WithArgs("ghost").WillReturnError(sql.ErrNoRows)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "disabled account",
+			body: `{"username":"alice","password":"secret"}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(findUser).WithArgs("alice").WillReturnRows(userRow(1, "alice", "secret", 0))
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "wrong password is recorded",
+			body: `{"username":"alice","password":"nope"}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(findUser).WithArgs("alice").WillReturnRows(userRow(1, "alice", "secret", 1))
+				mock.ExpectExec(`INSERT INTO login_history`).
+					WithArgs(int64(1), sqlmock.AnyArg(), sqlmock.AnyArg(), false).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "legacy sha256 hash is upgraded to bcrypt",
+			body: `{"username":"alice","password":"secret"}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				legacy := sha256.Sum256([]byte("secret"))
+				mock.ExpectQuery(findUser).WithArgs("alice").
+					WillReturnRows(userRowWithHash(1, "alice", hex.EncodeToString(legacy[:]), 1))
+				mock.ExpectExec(`UPDATE users SET password = \? WHERE id = \?`).
+					WithArgs(bcryptHashArg{"secret"}, int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectSuspended(mock, 1, time.Now().Add(time.Hour))
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "suspended account",
+			body: `{"username":"alice","password":"secret"}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(findUser).WithArgs("alice").WillReturnRows(userRow(1, "alice", "secret", 1))
+				expectSuspended(mock, 1, time.Now().Add(time.Hour))
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "2fa state unreadable",
+			body: `{"username":"alice","password":"secret"}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(findUser).WithArgs("alice").WillReturnRows(userRow(1, "alice", "secret", 1))
+				expectSuspended(mock, 1, time.Time{})
+				mock.ExpectQuery(`FROM user_phone_security`).WithArgs(int64(1)).WillReturnError(sql.ErrConnDone)
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name: "ok",
+			body: `{"username":"alice","password":"secret"}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(findUser).WithArgs("alice").WillReturnRows(userRow(1, "alice", "secret", 1))
+				expectSuspended(mock, 1, time.Time{})
+				mock.ExpectQuery(`FROM user_phone_security`).WithArgs(int64(1)).WillReturnError(sql.ErrNoRows)
+				mock.ExpectExec(`UPDATE users SET login_ip = \?`).
+					WithArgs(sqlmock.AnyArg(), testNow.Format("2006-01-02 15:04:05"), "alice").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`INSERT INTO login_history`).
+					WithArgs(int64(1), sqlmock.AnyArg(), sqlmock.AnyArg(), true).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(`INSERT INTO user_sessions`).
+					WithArgs(anyArgs(6)...).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			wantStatus: http.StatusOK,
+			wantCookie: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newTestServer(t)
+			if tt.setup != nil {
+				tt.setup(mock)
+			}
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+
+			rec := serve(s, method, "/login", "", tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			checkExpectations(t, mock)
+			if !tt.wantCookie {
+				return
+			}
+
+			var refresh *http.Cookie
+			for _, c := range rec.Result().Cookies() {
+				if c.Name == RefreshCookieName {
+					refresh = c
+				}
+			}
+			if refresh == nil || !refresh.HttpOnly {
+				t.Fatalf("missing HttpOnly %s cookie", RefreshCookieName)
+			}
+			claims, err := ParseToken(refresh.Value, testSecret)
+			if err != nil || claims.TokenType != TokenTypeRefresh || claims.ID == "" {
+				t.Fatalf("refresh cookie claims = %+v, err %v", claims, err)
+			}
+
+			body := decodeBody(t, rec)
+			access, _ := body["accessToken"].(string)
+			claims, err = ParseToken(access, testSecret)
+			if err != nil || claims.TokenType != TokenTypeAccess || claims.UserID != 1 {
+				t.Fatalf("access token claims = %+v, err %v", claims, err)
+			}
+		})
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	const findUser = `SELECT \* FROM users WHERE username = \?`
+	const checkLock = `FROM login_throttle\s+WHERE \(\(scope = 'user'`
+	lockRow := func(until any) *sqlmock.Rows {
+		rows := sqlmock.NewRows([]string{"scope", "lock_key", "failures", "locked_until"})
+		if until != nil {
+			rows.AddRow("user", "alice", 5, until)
+		}
+		return rows
+	}
+	const ip = "192.0.2.1" // RemoteAddr của httptest
+	window := lockout.DefaultLimits.Window
+	lockFor := lockout.DefaultLimits.LockFor
+
+	t.Run("locked username is rejected before password check", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		mock.ExpectQuery(checkLock).WithArgs("alice", ip, testNow).
+			WillReturnRows(lockRow(testNow.Add(10 * time.Minute)))
+
+		rec := serve(s, http.MethodPost, "/login", "", `{"username":"alice","password":"secret"}`)
+		if rec.Code != http.StatusLocked {
+			t.Fatalf("status = %d, want 423 (body %s)", rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get("Retry-After"); got != "600" {
+			t.Fatalf("Retry-After = %q", got)
+		}
+		if body := decodeBody(t, rec); body["code"] != CodeLocked {
+			t.Fatalf("code = %v", body["code"])
+		}
+		checkExpectations(t, mock)
+	})
+
+	t.Run("failure reaching the limit locks and is audited", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		mock.ExpectQuery(checkLock).WithArgs("alice", ip, testNow).WillReturnRows(lockRow(nil))
+		mock.ExpectQuery(findUser).WithArgs("alice").WillReturnRows(userRow(1, "alice", "secret", 1))
+		mock.ExpectExec(`INSERT INTO login_history`).
+			WithArgs(int64(1), ip, sqlmock.AnyArg(), false).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		mock.ExpectExec(`INSERT INTO login_throttle`).
+			WithArgs(lockout.ScopeUser, "alice", testNow, testNow.Add(-window), testNow).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery(`SELECT failures, locked_until FROM login_throttle`).
+			WithArgs(lockout.ScopeUser, "alice").
+			WillReturnRows(sqlmock.NewRows([]string{"failures", "locked_until"}).AddRow(5, nil))
+		mock.ExpectExec(`UPDATE login_throttle SET locked_until = \?`).
+			WithArgs(testNow.Add(lockFor), lockout.ScopeUser, "alice").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectExec(`INSERT INTO login_throttle`).
+			WithArgs(lockout.ScopeIP, ip, testNow, testNow.Add(-window), testNow).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery(`SELECT failures, locked_until FROM login_throttle`).
+			WithArgs(lockout.ScopeIP, ip).
+			WillReturnRows(sqlmock.NewRows([]string{"failures", "locked_until"}).AddRow(2, nil))
+
+		mock.ExpectExec(`INSERT INTO audit_log`).
+			WithArgs(int64(0), audit.ActionLoginLocked, audit.TargetUser, int64(1), sqlmock.AnyArg(), ip).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		rec := serve(s, http.MethodPost, "/login", "", `{"username":"alice","password":"nope"}`)
+		if rec.Code != http.StatusLocked {
+			t.Fatalf("status = %d, want 423 (body %s)", rec.Code, rec.Body.String())
+		}
+		checkExpectations(t, mock)
+	})
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	otherSecret, err := GenerateAccessToken(1, "user", "user", []byte("other-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		header     string
+		wantStatus int
+	}{
+		{"no header", "", http.StatusUnauthorized},
+		{"not bearer", "Basic abc", http.StatusUnauthorized},
+		{"garbage token", "Bearer not-a-jwt", http.StatusUnauthorized},
+		{"wrong signature", "Bearer " + otherSecret, http.StatusUnauthorized},
+		{"refresh token as access", "Bearer " + refreshTokenFor(t, 1, "sess"), http.StatusUnauthorized},
+		// qua được auth -> handler báo lỗi body
+		{"valid access token", "Bearer " + accessTokenFor(t, 1), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newRequest(http.MethodPost, "/rooms/group", `{`)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := serveRequest(s, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+// fakeOAuthProvider: provider giả, Exchange trả identity cố định khi đúng code + PKCE verifier
+type fakeOAuthProvider struct{ ident oauth.Identity }
+
+func (p *fakeOAuthProvider) Name() string { return "fake" }
+
+func (p *fakeOAuthProvider) AuthURL(state, nonce, codeChallenge, redirectURI string) string {
+	return "https://idp.test/authorize?" + url.Values{
+		"state": {state}, "code_challenge": {codeChallenge}, "redirect_uri": {redirectURI},
+	}.Encode()
+}
+
+func (p *fakeOAuthProvider) Exchange(_ context.Context, code, codeVerifier, _, _ string) (*oauth.Identity, error) {
+	if code != "good" || codeVerifier != "verifier" {
+		return nil, errors.New("bad code")
+	}
+	id := p.ident
+	return &id, nil
+}
+
+func TestOAuthLogin(t *testing.T) {
+	newOAuthServer := func(t *testing.T) (*Server, sqlmock.Sqlmock) {
+		s, mock := newTestServer(t)
+		s.EnableOAuth(&oauth.Config{
+			Providers:       map[string]oauth.Provider{"fake": &fakeOAuthProvider{ident: oauth.Identity{Provider: "fake", Subject: "42"}}},
+			RedirectBaseURL: "https://api.test",
+		}, "https://app.test/")
+		return s, mock
+	}
+	callback := func(s *Server, state string) *httptest.ResponseRecorder {
+		req := newRequest(http.MethodGet, "/auth/oauth/fake/callback?code=good&state="+state, "")
+		req.AddCookie(&http.Cookie{Name: oauthStateCookie, Value: s.encodeOAuthState(&oauthState{
+			Provider: "fake", State: "st", Verifier: "verifier", Redirect: "/rooms", Exp: testNow.Add(time.Minute).Unix(),
+		})})
+		return serveRequest(s, req)
+	}
+
+	t.Run("start redirects to provider with signed state", func(t *testing.T) {
+		s, _ := newOAuthServer(t)
+		rec := serve(s, http.MethodGet, "/auth/oauth/fake/start?redirect=//evil.test", "", "")
+		if rec.Code != http.StatusFound {
+			t.Fatalf("status = %d, want 302 (body %s)", rec.Code, rec.Body.String())
+		}
+		loc, _ := url.Parse(rec.Header().Get("Location"))
+		if loc.Host != "idp.test" || loc.Query().Get("redirect_uri") != "https://api.test/auth/oauth/fake/callback" {
+			t.Fatalf("Location = %s", loc)
+		}
+		var st *oauthState
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == oauthStateCookie {
+				st, _ = s.decodeOAuthState(c.Value)
+			}
+		}
+		if st == nil || st.State != loc.Query().Get("state") || st.Redirect != "/" {
+			t.Fatalf("state cookie = %+v", st)
+		}
+		if oauth.CodeChallenge(st.Verifier) != loc.Query().Get("code_challenge") {
+			t.Fatal("code_challenge does not match verifier")
+		}
+	})
+
+	t.Run("state mismatch is rejected", func(t *testing.T) {
+		s, mock := newOAuthServer(t)
+		rec := callback(s, "forged")
+		if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://app.test/?oauth_error=invalid_state" {
+			t.Fatalf("status = %d, Location = %s", rec.Code, rec.Header().Get("Location"))
+		}
+		checkExpectations(t, mock)
+	})
+
+	t.Run("linked identity gets a refresh cookie", func(t *testing.T) {
+		s, mock := newOAuthServer(t)
+		mock.ExpectQuery(`SELECT user_id FROM oauth_identities`).WithArgs("fake", "42").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+		mock.ExpectExec(`INSERT INTO oauth_identities`).WithArgs(int64(1), "fake", "42", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`SELECT \* FROM users WHERE id = \?`).WithArgs(1).WillReturnRows(userRow(1, "alice", "secret", 1))
+		expectSuspended(mock, 1, time.Time{})
+		mock.ExpectQuery(`FROM user_phone_security`).WithArgs(int64(1)).WillReturnError(sql.ErrNoRows)
+		mock.ExpectExec(`UPDATE users SET login_ip = \?`).WithArgs(anyArgs(3)...).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO login_history`).
+			WithArgs(int64(1), sqlmock.AnyArg(), sqlmock.AnyArg(), true).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(`INSERT INTO user_sessions`).WithArgs(anyArgs(6)...).WillReturnResult(sqlmock.NewResult(1, 1))
+
+		rec := callback(s, "st")
+		if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://app.test/rooms" {
+			t.Fatalf("status = %d, Location = %s", rec.Code, rec.Header().Get("Location"))
+		}
+		var refresh *http.Cookie
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == RefreshCookieName {
+				refresh = c
+			}
+		}
+		if refresh == nil {
+			t.Fatalf("missing %s cookie", RefreshCookieName)
+		}
+		if claims, err := ParseToken(refresh.Value, testSecret); err != nil || claims.UserID != 1 {
+			t.Fatalf("refresh cookie claims = %+v, err %v", claims, err)
+		}
+		checkExpectations(t, mock)
+	})
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	const findKey = `FROM api_keys k\s+JOIN users u ON u.id = k.user_id\s+WHERE k.key_hash = \?`
+	keyRow := func(scopes string) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{
+			"id", "name", "key_prefix", "user_id", "username", "scopes", "created_by",
+			"created_at", "expires_at", "last_used_at", "revoked_at",
+		}).AddRow(7, "cron", "ck_abcdefg", 3, "reporter", scopes, 1, testNow, nil, testNow, nil)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		rows       *sqlmock.Rows
+		wantStatus int
+	}{
+		{"unknown key", http.MethodGet, "/rooms/messages/x", nil, http.StatusUnauthorized},
+		{"endpoint not open to api keys", http.MethodGet, "/me", keyRow("read_rooms,send_message"), http.StatusForbidden},
+		{"missing scope", http.MethodPost, "/rooms/send-messages/1", keyRow("read_rooms"), http.StatusForbidden},
+		// qua được auth -> handler chạy tới bước parse room id
+		{"scoped key reaches handler", http.MethodGet, "/rooms/messages/x", keyRow("read_rooms"), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newTestServer(t)
+			q := mock.ExpectQuery(findKey).WithArgs(sqlmock.AnyArg(), testNow)
+			if tt.rows == nil {
+				q.WillReturnError(sql.ErrNoRows)
+			} else {
+				q.WillReturnRows(tt.rows)
+			}
+
+			req := newRequest(tt.method, tt.path, "")
+			req.Header.Set(apiKeyHeader, "ck_test")
+			rec := serveRequest(s, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			checkExpectations(t, mock)
+		})
+	}
+}
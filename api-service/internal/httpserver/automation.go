@@ -0,0 +1,340 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/automation"
+	"cronhustler/api-service/internal/moderation"
+	"cronhustler/api-service/internal/room"
+	"cronhustler/api-service/internal/webhook"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	roomAutomationPrefix = "/rooms/automation/"
+
+	automationBotUsername = "automation_bot"
+	automationBotName     = "Automation"
+)
+
+func (s *Server) mountAutomationRoutes(mux *http.ServeMux) {
+	// owner / admin của room:
+	// GET    /rooms/automation/{roomID}               -> list rule
+	// POST   /rooms/automation/{roomID}               {name, match_type, pattern, action, reaction, reply_text, cooldown_seconds, enabled}
+	// POST   /rooms/automation/{roomID}/test          {content} -> rule nào khớp (không chạy action)
+	// GET    /rooms/automation/{roomID}/{ruleID}
+	// PUT    /rooms/automation/{roomID}/{ruleID}      (body như POST)
+	// DELETE /rooms/automation/{roomID}/{ruleID}
+	mux.Handle(roomAutomationPrefix, http.HandlerFunc(s.handleRoomAutomation))
+}
+
+type automationRuleRequest struct {
+	Name            string `json:"name"`
+	MatchType       string `json:"match_type"`
+	Pattern         string `json:"pattern"`
+	Action          string `json:"action"`
+	Reaction        string `json:"reaction"`
+	ReplyText       string `json:"reply_text"`
+	CooldownSeconds int    `json:"cooldown_seconds"`
+	Enabled         *bool  `json:"enabled"` // bỏ trống = bật
+}
+
+func (req automationRuleRequest) toRule() *automation.Rule {
+	ru := &automation.Rule{
+		Name:            req.Name,
+		MatchType:       req.MatchType,
+		Pattern:         req.Pattern,
+		Action:          req.Action,
+		Reaction:        req.Reaction,
+		ReplyText:       req.ReplyText,
+		CooldownSeconds: req.CooldownSeconds,
+		Enabled:         true,
+	}
+	if req.Enabled != nil {
+		ru.Enabled = *req.Enabled
+	}
+	return ru
+}
+
+// requireRoomManager: owner hoặc admin của room (tự ghi response lỗi)
+func (s *Server) requireRoomManager(w http.ResponseWriter, r *http.Request, roomID, userID int64) bool {
+	role, err := s.roomRepo.GetMemberRole(r.Context(), roomID, userID)
+	if err != nil {
+		if errors.Is(err, room.ErrNotMember) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "you are not a member of this room"})
+			return false
+		}
+		log.Println("GetMemberRole error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return false
+	}
+	if role != "owner" && role != "admin" {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "only room owner or admin can manage automation rules"})
+		return false
+	}
+	return true
+}
+
+func (s *Server) handleRoomAutomation(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, roomAutomationPrefix), "/"), "/")
+	roomID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || roomID <= 0 || len(parts) > 2 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid room id"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+	if !s.requireRoomManager(w, r, roomID, userID) {
+		return
+	}
+
+	if len(parts) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			list, err := s.automationRepo.List(r.Context(), roomID)
+			if err != nil {
+				log.Println("automation List error:", err)
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"rules": list})
+		case http.MethodPost:
+			var req automationRuleRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+				return
+			}
+			ru := req.toRule()
+			ru.RoomID, ru.CreatedBy = roomID, userID
+			if !s.requireSafeWebhookTargets(w, r, ru) {
+				return
+			}
+			if err := s.automationRepo.Create(r.Context(), ru); err != nil {
+				writeAutomationError(w, "automation Create error:", err)
+				return
+			}
+			writeJSON(w, http.StatusCreated, ru)
+		default:
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		}
+		return
+	}
+
+	if parts[1] == "test" {
+		s.testAutomationRules(w, r, roomID)
+		return
+	}
+
+	ruleID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || ruleID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid rule id"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		ru, err := s.automationRepo.Get(r.Context(), roomID, ruleID)
+		if err != nil {
+			writeAutomationError(w, "automation Get error:", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, ru)
+	case http.MethodPut:
+		existing, err := s.automationRepo.Get(r.Context(), roomID, ruleID)
+		if err != nil {
+			writeAutomationError(w, "automation Get error:", err)
+			return
+		}
+		var req automationRuleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+			return
+		}
+		ru := req.toRule()
+		ru.ID, ru.RoomID = ruleID, roomID
+		ru.CreatedBy, ru.CreatedAt = existing.CreatedBy, existing.CreatedAt
+		if !s.requireSafeWebhookTargets(w, r, ru) {
+			return
+		}
+		if err := s.automationRepo.Update(r.Context(), ru); err != nil {
+			writeAutomationError(w, "automation Update error:", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, ru)
+	case http.MethodDelete:
+		if err := s.automationRepo.Delete(r.Context(), roomID, ruleID); err != nil {
+			writeAutomationError(w, "automation Delete error:", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"deleted": ruleID})
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+// requireSafeWebhookTargets: rule action webhook bắn ra webhook của room -> mọi webhook đang nhận
+// automation_triggered phải qua webhook.ValidateURL (không trỏ vào mạng nội bộ), tự ghi response lỗi
+func (s *Server) requireSafeWebhookTargets(w http.ResponseWriter, r *http.Request, ru *automation.Rule) bool {
+	if ru.Action != automation.ActionWebhook {
+		return true
+	}
+	hooks, err := s.webhookRepo.ListByRoom(r.Context(), ru.RoomID)
+	if err != nil {
+		log.Println("ListByRoom webhooks error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return false
+	}
+	for _, h := range hooks {
+		if !h.IsActive || !slices.Contains(h.Events, webhook.EventAutomationTriggered) {
+			continue
+		}
+		if err := webhook.ValidateURL(h.URL); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("room webhook %d cannot receive automation events: %v", h.ID, err),
+			})
+			return false
+		}
+	}
+	return true
+}
+
+func writeAutomationError(w http.ResponseWriter, logPrefix string, err error) {
+	switch {
+	case errors.Is(err, automation.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "rule not found"})
+	case errors.Is(err, automation.ErrTooManyRules):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	case strings.HasPrefix(err.Error(), "automation:"):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	default:
+		log.Println(logPrefix, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+	}
+}
+
+// testAutomationRules: dry-run, không tính cooldown, không chạy action
+func (s *Server) testAutomationRules(w http.ResponseWriter, r *http.Request, roomID int64) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	var req struct {
+		Content string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+
+	list, err := s.automationRepo.List(r.Context(), roomID)
+	if err != nil {
+		log.Println("automation List error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	matched := []*automation.Rule{}
+	for _, ru := range list {
+		if ru.Enabled && ru.Validate() == nil && ru.Matches(req.Content) {
+			matched = append(matched, ru)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"matched": matched})
+}
+
+// runAutomation: bước đánh giá rule sau khi tin được lưu (tin của bot không qua đây -> không lặp)
+func (s *Server) runAutomation(msgID, roomID, senderID int64, content string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	rules, err := s.automationRepo.Evaluate(ctx, roomID, content, s.now())
+	if err != nil {
+		log.Println("automation Evaluate error:", err)
+		return
+	}
+	if len(rules) == 0 {
+		return
+	}
+
+	var botID int64
+	for _, ru := range rules {
+		if (ru.Action == automation.ActionReact || ru.Action == automation.ActionReply) && botID == 0 {
+			botID, err = s.botRepo.EnsureSystemBot(ctx, automationBotUsername, automationBotName)
+			if err != nil {
+				log.Println("[automation] EnsureSystemBot error:", err)
+				return
+			}
+		}
+
+		switch ru.Action {
+		case automation.ActionReact:
+			if _, err := s.chatRepo.ToggleReaction(ctx, msgID, botID, ru.Reaction); err != nil {
+				log.Printf("[automation] rule=%d react: %v", ru.ID, err)
+				continue
+			}
+			s.broadcastReactionSummary(ctx, roomID, msgID, botID)
+		case automation.ActionReply:
+			if _, err := s.postBotMessage(ctx, botID, roomID, ru.ReplyText); err != nil {
+				log.Printf("[automation] rule=%d reply: %v", ru.ID, err)
+			}
+		case automation.ActionWebhook:
+			s.emitWebhook(roomID, senderID, webhook.EventAutomationTriggered, map[string]any{
+				"rule_id":    ru.ID,
+				"rule_name":  ru.Name,
+				"message_id": msgID,
+				"sender_id":  senderID,
+				"content":    content,
+			})
+		case automation.ActionFlag:
+			reason := ru.Name
+			if reason == "" {
+				reason = ru.Pattern
+			}
+			rp := &moderation.Report{
+				ReporterID:     0,
+				TargetType:     moderation.TargetMessage,
+				MessageID:      msgID,
+				TargetUserID:   senderID,
+				RoomID:         roomID,
+				Reason:         "other",
+				Details:        truncateRunes("automation rule: "+reason, 500),
+				ContentPreview: truncateRunes(content, 500),
+			}
+			if err := s.moderationRepo.Create(ctx, rp); err != nil {
+				log.Printf("[automation] rule=%d flag: %v", ru.ID, err)
+			}
+		}
+	}
+}
+
+// broadcastReactionSummary: reaction_updated cho cả room (giống handleToggleReaction)
+func (s *Server) broadcastReactionSummary(ctx context.Context, roomID, messageID, actorUserID int64) {
+	items, err := s.chatRepo.GetReactionSummary(ctx, messageID, actorUserID)
+	if err != nil {
+		log.Println("GetReactionSummary error:", err)
+		return
+	}
+	memberIDs, err := s.roomRepo.GetRoomMemberIDs(roomID)
+	if err != nil {
+		log.Println("GetRoomMemberIDs error:", err)
+		return
+	}
+	wsSendToUsers(memberIDs, wsEnvelope{
+		Type:   "reaction_updated",
+		RoomID: roomID,
+		Data: map[string]any{
+			"message_id": messageID,
+			"reactions":  s.signReactions(items),
+		},
+	})
+}
@@ -0,0 +1,103 @@
+package httpserver
+
+import (
+	"cronhustler/api-service/internal/contact"
+	"cronhustler/api-service/internal/notify"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+)
+
+func (s *Server) mountBootstrapRoutes(mux *http.ServeMux) {
+	// GET /bootstrap -> profile + rooms + contacts + notification settings (mở app 1 request)
+	mux.Handle("/bootstrap", http.HandlerFunc(s.handleBootstrap))
+}
+
+// bootstrapResponse: các phần giống hệt /me, /rooms, /contacts, /users/notification-settings
+type bootstrapResponse struct {
+	Profile              UserInfoResponse   `json:"profile"`
+	Rooms                []RoomInfoResponse `json:"rooms"` // có last_message + unread_count, không gồm room đã archive
+	Contacts             []contact.Contact  `json:"contacts"`
+	NotificationSettings *notify.Settings   `json:"notification_settings"`
+}
+
+// handleBootstrap: 4 phần đọc song song, phần nào lỗi -> cả request lỗi (FE fallback gọi lẻ)
+// không push rooms_sync như GET /rooms: client vừa nhận đủ list trong response
+func (s *Server) handleBootstrap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
+		return
+	}
+	ctx := r.Context()
+
+	var (
+		resp                                           bootstrapResponse
+		profileErr, roomsErr, contactsErr, settingsErr error
+		wg                                             sync.WaitGroup
+	)
+	wg.Add(4)
+	go func() {
+		defer wg.Done()
+		u, err := s.userRepo.GetUserByID(int(userID))
+		if err != nil {
+			profileErr = err
+			return
+		}
+		resp.Profile = s.userInfoResponse(u)
+	}()
+	go func() {
+		defer wg.Done()
+		rooms, err := s.roomRepo.GetRoomsByUser(userID)
+		if err != nil {
+			roomsErr = err
+			return
+		}
+		resp.Rooms = s.roomInfoResponses(ctx, userID, rooms, false)
+	}()
+	go func() {
+		defer wg.Done()
+		list, err := s.contactRepo.ListContacts(ctx, userID)
+		if err != nil {
+			contactsErr = err
+			return
+		}
+		for i := range list {
+			list[i].AvatarURL = s.signMediaURL(list[i].AvatarURL)
+		}
+		resp.Contacts = list
+	}()
+	go func() {
+		defer wg.Done()
+		resp.NotificationSettings, settingsErr = s.notifyRepo.GetSettings(ctx, userID)
+	}()
+	wg.Wait()
+
+	if errors.Is(profileErr, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, CodeNotFound, "user not found")
+		return
+	}
+	for _, part := range []struct {
+		name string
+		err  error
+	}{
+		{"GetUserByID", profileErr},
+		{"GetRoomsByUser", roomsErr},
+		{"ListContacts", contactsErr},
+		{"GetSettings", settingsErr},
+	} {
+		if part.err != nil {
+			log.Printf("[bootstrap] %s error: %v", part.name, part.err)
+			writeError(w, http.StatusInternalServerError, CodeDBError, "db error")
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
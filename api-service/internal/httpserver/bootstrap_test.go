@@ -0,0 +1,92 @@
+package httpserver
+
+import (
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestBootstrap(t *testing.T) {
+	// 4 phần chạy song song -> không ràng buộc thứ tự query
+	expectParts := func(mock sqlmock.Sqlmock, user *sqlmock.Rows, contactsErr error) {
+		mock.MatchExpectationsInOrder(false)
+		if user != nil {
+			mock.ExpectQuery(`SELECT \* FROM users WHERE id = \?`).WithArgs(1).WillReturnRows(user)
+		} else {
+			mock.ExpectQuery(`SELECT \* FROM users WHERE id = \?`).WithArgs(1).WillReturnError(sql.ErrNoRows)
+		}
+		mock.ExpectQuery(`AS unread_count`).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(`FROM notification_mutes`).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"room_id", "muted_until"}))
+		contacts := mock.ExpectQuery(`FROM user_contacts c`).WithArgs(int64(1))
+		if contactsErr != nil {
+			contacts.WillReturnError(contactsErr)
+		} else {
+			contacts.WillReturnRows(sqlmock.NewRows([]string{"id", "username", "full_name", "avatar_url", "created_at"}).
+				AddRow(2, "bob", "Bob", "", testNow))
+		}
+		mock.ExpectQuery(`FROM user_notification_settings`).WithArgs(int64(1)).WillReturnError(sql.ErrNoRows)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		setup      func(mock sqlmock.Sqlmock)
+		wantStatus int
+	}{
+		{name: "wrong method", method: http.MethodPost, wantStatus: http.StatusMethodNotAllowed},
+		{
+			name: "unknown user",
+			setup: func(mock sqlmock.Sqlmock) {
+				expectParts(mock, nil, nil)
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "one part fails",
+			setup: func(mock sqlmock.Sqlmock) {
+				expectParts(mock, userRow(1, "alice", "x", 1), sql.ErrConnDone)
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name: "ok",
+			setup: func(mock sqlmock.Sqlmock) {
+				expectParts(mock, userRow(1, "alice", "x", 1), nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newTestServer(t)
+			if tt.setup != nil {
+				tt.setup(mock)
+			}
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			rec := serve(s, method, "/bootstrap", accessTokenFor(t, 1), "")
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			checkExpectations(t, mock)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			body := decodeBody(t, rec)
+			profile, _ := body["profile"].(map[string]any)
+			rooms, _ := body["rooms"].([]any)
+			contacts, _ := body["contacts"].([]any)
+			settings, _ := body["notification_settings"].(map[string]any)
+			if profile["username"] != "alice" || rooms == nil || len(rooms) != 0 || len(contacts) != 1 || settings["email_digest"] != "all" {
+				t.Fatalf("body = %v", body)
+			}
+		})
+	}
+}
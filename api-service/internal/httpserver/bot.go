@@ -0,0 +1,561 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/audit"
+	"cronhustler/api-service/internal/bot"
+	"cronhustler/api-service/internal/chat"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// giới hạn nút trên 1 tin của bot
+const (
+	botMaxButtons     = 10
+	botButtonLabelLen = 80
+)
+
+var errMissingBotKey = errors.New("missing bot api key")
+
+const adminBotsPrefix = "/admin/bots/"
+
+func (s *Server) mountBotRoutes(mux *http.ServeMux) {
+	// ===== quản lý bot (user login, chỉ owner) =====
+	// GET /bots -> bot của tôi | POST /bots {username, full_name} -> trả api_key 1 lần
+	mux.Handle("/bots", http.HandlerFunc(s.handleBots))
+	// POST /bots/{id}/rotate-key | DELETE /bots/{id} | GET /bots/{id}/deliveries
+	// POST /bots/{id}/messages (Authorization: Bot <api_key>, id = bot đang gọi)
+	mux.Handle("/bots/", http.HandlerFunc(s.handleBotByID))
+
+	// ===== admin: quản lý bot của mọi user =====
+	// GET /admin/bots | POST /admin/bots {username, full_name} -> trả api_key 1 lần
+	mux.Handle("/admin/bots", s.RequireAdmin(http.HandlerFunc(s.handleAdminBots)))
+	// DELETE /admin/bots/{id} | POST /admin/bots/{id}/rotate-key
+	mux.Handle(adminBotsPrefix, s.RequireAdmin(http.HandlerFunc(s.handleAdminBotByID)))
+
+	// ===== Bot API (Authorization: Bot <api_key>) =====
+	mux.Handle("/bot/me", http.HandlerFunc(s.handleBotMe))
+	// PUT /bot/subscription {webhook_url, events} -> trả webhook_secret
+	mux.Handle("/bot/subscription", http.HandlerFunc(s.handleBotSubscription))
+	// POST /bot/messages {room_id, content, reply_to_message_id?, buttons?}
+	mux.Handle("/bot/messages", http.HandlerFunc(s.handleBotSendMessage))
+
+	// user bấm nút trên tin của bot -> route về bot (webhook + WS)
+	mux.Handle("/messages/button-click", http.HandlerFunc(s.handleButtonClick))
+}
+
+// botFromRequest: header "Authorization: Bot <key>" (WS cho phép ?bot_key=)
+func (s *Server) botFromRequest(r *http.Request) (*bot.Bot, error) {
+	key := ""
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bot ") {
+		key = strings.TrimSpace(strings.TrimPrefix(h, "Bot "))
+	}
+	if key == "" {
+		return nil, errMissingBotKey
+	}
+	return s.botRepo.Authenticate(r.Context(), key)
+}
+
+// isBotAuthRequest: request dùng bot key (header hoặc query cho WS)
+func isBotAuthRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Authorization"), "Bot ") || r.URL.Query().Get("bot_key") != ""
+}
+
+// verifyBotWSAuth: WS mode cho bot, nhận event realtime như user thường
+func (s *Server) verifyBotWSAuth(r *http.Request) (int64, error) {
+	if k := r.URL.Query().Get("bot_key"); k != "" {
+		b, err := s.botRepo.Authenticate(r.Context(), k)
+		if err != nil {
+			return 0, err
+		}
+		return b.UserID, nil
+	}
+	b, err := s.botFromRequest(r)
+	if err != nil {
+		return 0, err
+	}
+	return b.UserID, nil
+}
+
+func (s *Server) requireBot(w http.ResponseWriter, r *http.Request) (*bot.Bot, bool) {
+	b, err := s.botFromRequest(r)
+	if err != nil {
+		if errors.Is(err, errMissingBotKey) || errors.Is(err, bot.ErrNotFound) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid bot api key"})
+			return nil, false
+		}
+		log.Println("bot Authenticate error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return nil, false
+	}
+	return b, true
+}
+
+// ===== quản lý bot =====
+
+type createBotRequest struct {
+	Username string `json:"username"`
+	FullName string `json:"full_name"`
+}
+
+func (s *Server) handleBots(w http.ResponseWriter, r *http.Request) {
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := s.botRepo.ListByOwner(r.Context(), userID)
+		if err != nil {
+			log.Println("ListByOwner bots error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"bots": list})
+
+	case http.MethodPost:
+		s.createBot(w, r, userID)
+
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+// createBot: POST body {username, full_name}, ownerID = người tạo (user hoặc admin)
+func (s *Server) createBot(w http.ResponseWriter, r *http.Request, ownerID int64) *bot.Bot {
+	var req createBotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return nil
+	}
+
+	b, key, err := s.botRepo.Create(r.Context(), ownerID, req.Username, req.FullName)
+	if err != nil {
+		switch {
+		case errors.Is(err, bot.ErrInvalidUsername):
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		case errors.Is(err, bot.ErrUsernameTaken):
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		default:
+			log.Println("Create bot error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		}
+		return nil
+	}
+
+	// api_key chỉ trả 1 lần
+	writeJSON(w, http.StatusCreated, map[string]any{"bot": b, "api_key": key})
+	return b
+}
+
+// deleteBot / rotateBotKey: ownerID = bot.AnyOwner khi admin gọi
+func (s *Server) deleteBot(w http.ResponseWriter, r *http.Request, ownerID, botID int64) bool {
+	if err := s.botRepo.Delete(r.Context(), ownerID, botID); err != nil {
+		if errors.Is(err, bot.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "bot not found"})
+			return false
+		}
+		log.Println("Delete bot error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return false
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+	return true
+}
+
+func (s *Server) rotateBotKey(w http.ResponseWriter, r *http.Request, actorID, ownerID, botID int64) {
+	key, err := s.botRepo.RotateKey(r.Context(), ownerID, botID)
+	if err != nil {
+		if errors.Is(err, bot.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "bot not found"})
+			return
+		}
+		log.Println("RotateKey error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	s.recordAudit(r, actorID, audit.ActionTokenRevoke, audit.TargetBot, botID, map[string]any{"token": "bot_api_key"})
+	writeJSON(w, http.StatusOK, map[string]any{"api_key": key})
+}
+
+func (s *Server) handleBotByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/bots/"), "/"), "/")
+	botID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || botID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid bot id"})
+		return
+	}
+
+	// Bot API: bot tự gửi tin vào room (chỉ được gửi dưới tên chính nó)
+	if len(parts) == 2 && parts[1] == "messages" {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		b, ok := s.requireBot(w, r)
+		if !ok {
+			return
+		}
+		if b.UserID != botID {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "api key does not belong to this bot"})
+			return
+		}
+		s.sendBotMessage(w, r, b)
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	ctx := r.Context()
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		s.deleteBot(w, r, userID, botID)
+
+	case len(parts) == 2 && parts[1] == "rotate-key" && r.Method == http.MethodPost:
+		s.rotateBotKey(w, r, userID, userID, botID)
+
+	case len(parts) == 2 && parts[1] == "deliveries" && r.Method == http.MethodGet:
+		b, err := s.botRepo.GetByUserID(ctx, botID)
+		if err != nil || b.OwnerID != userID {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "bot not found"})
+			return
+		}
+		list, err := s.webhookRepo.ListBotDeliveries(ctx, botID, 50)
+		if err != nil {
+			log.Println("ListBotDeliveries error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"deliveries": list})
+
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
+
+// ===== Bot API =====
+
+func (s *Server) handleBotMe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	b, ok := s.requireBot(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, b)
+}
+
+type botSubscriptionRequest struct {
+	WebhookURL string   `json:"webhook_url"` // rỗng = tắt
+	Events     []string `json:"events"`      // rỗng = tất cả
+}
+
+func (s *Server) handleBotSubscription(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	b, ok := s.requireBot(w, r)
+	if !ok {
+		return
+	}
+
+	var req botSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+
+	secret, err := s.botRepo.SetSubscription(r.Context(), b.UserID, req.WebhookURL, req.Events)
+	if err != nil {
+		if errors.Is(err, bot.ErrInvalidURL) || errors.Is(err, bot.ErrInvalidEvents) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		log.Println("SetSubscription error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "webhook_secret": secret})
+}
+
+type botSendMessageRequest struct {
+	RoomID           int64         `json:"room_id"`
+	Content          string        `json:"content"`
+	ReplyToMessageID *int64        `json:"reply_to_message_id"`
+	Buttons          []chat.Button `json:"buttons"`
+}
+
+func validateBotButtons(buttons []chat.Button) error {
+	if len(buttons) > botMaxButtons {
+		return errors.New("too many buttons")
+	}
+	seen := map[string]bool{}
+	for i := range buttons {
+		b := &buttons[i]
+		b.ID = strings.TrimSpace(b.ID)
+		b.Label = strings.TrimSpace(b.Label)
+		if b.ID == "" || len(b.ID) > 64 || seen[b.ID] {
+			return errors.New("button id must be unique, 1-64 chars")
+		}
+		if b.Label == "" || len([]rune(b.Label)) > botButtonLabelLen {
+			return errors.New("button label is required (max 80 chars)")
+		}
+		switch b.Style {
+		case "", "primary", "danger":
+		default:
+			return errors.New("button style must be primary or danger")
+		}
+		seen[b.ID] = true
+	}
+	return nil
+}
+
+func (s *Server) handleBotSendMessage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	b, ok := s.requireBot(w, r)
+	if !ok {
+		return
+	}
+	s.sendBotMessage(w, r, b)
+}
+
+// sendBotMessage: body {room_id, content, reply_to_message_id?, buttons?}, bot phải là member
+func (s *Server) sendBotMessage(w http.ResponseWriter, r *http.Request, b *bot.Bot) {
+	var req botSendMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+	req.Content = strings.TrimSpace(req.Content)
+	if req.RoomID <= 0 || req.Content == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "room_id and content are required"})
+		return
+	}
+	if err := validateBotButtons(req.Buttons); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	isMember, err := s.roomRepo.IsUserInRoom(req.RoomID, b.UserID)
+	if err != nil && err != sql.ErrNoRows {
+		log.Println("IsUserInRoom error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	if !isMember {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "bot is not a member of this room"})
+		return
+	}
+
+	ctx := r.Context()
+	msg := &chat.Message{
+		RoomID:           req.RoomID,
+		SenderID:         b.UserID,
+		Content:          req.Content,
+		MessageType:      "text",
+		ReplyToMessageID: req.ReplyToMessageID,
+		Buttons:          req.Buttons,
+		CreatedAt:        s.now().UTC(),
+	}
+	id, err := s.chatRepo.CreateMessage(ctx, msg, true)
+	if err != nil {
+		if errors.Is(err, chat.ErrInvalidReplyTarget) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid reply target"})
+			return
+		}
+		log.Println("CreateMessage (bot) error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	var reply *replyInfoResponse
+	if msg.ReplyToMessageID != nil && *msg.ReplyToMessageID > 0 {
+		reply = &replyInfoResponse{
+			MessageID:   *msg.ReplyToMessageID,
+			Preview:     msg.ReplyPreview,
+			SenderName:  msg.ReplySenderName,
+			MessageType: msg.ReplyMessageType,
+		}
+	}
+
+	senderName, senderAvatar := s.senderInfo(b.UserID)
+	resp := sendMessageResponse{
+		ID:               id,
+		RoomID:           req.RoomID,
+		SenderID:         b.UserID,
+		SenderName:       senderName,
+		SenderAvatarURL:  s.signMediaURL(senderAvatar),
+		Content:          msg.Content,
+		MessageType:      msg.MessageType,
+		ReplyToMessageID: msg.ReplyToMessageID,
+		Reply:            reply,
+		Buttons:          msg.Buttons,
+		CreatedAt:        msg.CreatedAt.Format(time.RFC3339),
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+
+	s.broadcastMessageCreated(ctx, req.RoomID, b.UserID, resp)
+}
+
+// ===== Button click =====
+
+type buttonClickRequest struct {
+	MessageID int64  `json:"message_id"`
+	ButtonID  string `json:"button_id"`
+}
+
+func (s *Server) handleButtonClick(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var req buttonClickRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MessageID <= 0 || req.ButtonID == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "message_id and button_id are required"})
+		return
+	}
+
+	ctx := r.Context()
+	roomID, senderID, buttons, err := s.chatRepo.GetMessageButtons(ctx, req.MessageID)
+	if err != nil {
+		if errors.Is(err, chat.ErrMessageNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "message not found"})
+			return
+		}
+		log.Println("GetMessageButtons error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	isMember, err := s.roomRepo.IsUserInRoom(roomID, userID)
+	if err != nil && err != sql.ErrNoRows {
+		log.Println("IsUserInRoom error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	if !isMember {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "you are not a member of this room"})
+		return
+	}
+
+	var clicked *chat.Button
+	for i := range buttons {
+		if buttons[i].ID == req.ButtonID {
+			clicked = &buttons[i]
+			break
+		}
+	}
+	if clicked == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "button not found"})
+		return
+	}
+
+	if _, err := s.botRepo.GetByUserID(ctx, senderID); err != nil {
+		if errors.Is(err, bot.ErrNotFound) {
+			writeJSON(w, http.StatusGone, map[string]string{"error": "bot no longer exists"})
+			return
+		}
+		log.Println("GetByUserID bot error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	userName, _ := s.senderInfo(userID)
+	data := map[string]any{
+		"message_id": req.MessageID,
+		"button_id":  clicked.ID,
+		"value":      clicked.Value,
+		"user_id":    userID,
+		"user_name":  userName,
+	}
+
+	// bot đang nối WS thì nhận ngay, có webhook thì nhận qua webhook (retry)
+	wsSendToUser(senderID, wsEnvelope{Type: "button_clicked", RoomID: roomID, Data: data})
+	go func() {
+		ctx2, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.webhooks.EmitToBot(ctx2, senderID, roomID, bot.EventButtonClicked, data); err != nil {
+			log.Printf("[bot] emit button_clicked bot=%d: %v", senderID, err)
+		}
+	}()
+
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// ===== admin =====
+
+// GET /admin/bots | POST /admin/bots
+func (s *Server) handleAdminBots(w http.ResponseWriter, r *http.Request) {
+	adminID, _ := UserIDFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := s.botRepo.ListAll(r.Context())
+		if err != nil {
+			log.Println("ListAll bots error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"bots": list})
+
+	case http.MethodPost:
+		if b := s.createBot(w, r, adminID); b != nil {
+			s.recordAudit(r, adminID, audit.ActionBotCreate, audit.TargetBot, b.UserID, map[string]any{"username": b.Username})
+		}
+
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+// DELETE /admin/bots/{id} | POST /admin/bots/{id}/rotate-key
+func (s *Server) handleAdminBotByID(w http.ResponseWriter, r *http.Request) {
+	adminID, _ := UserIDFromContext(r.Context())
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, adminBotsPrefix), "/"), "/")
+	botID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || botID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid bot id"})
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		if s.deleteBot(w, r, bot.AnyOwner, botID) {
+			s.recordAudit(r, adminID, audit.ActionBotDelete, audit.TargetBot, botID, nil)
+		}
+
+	case len(parts) == 2 && parts[1] == "rotate-key" && r.Method == http.MethodPost:
+		s.rotateBotKey(w, r, adminID, bot.AnyOwner, botID)
+
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
@@ -0,0 +1,500 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/calendar"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const roomEventsPrefix = "/rooms/events/"
+
+// file feed cho app lịch: /rooms/events/{roomID}/calendar.ics?uid=&sig=
+const icsFeedFile = "calendar.ics"
+
+// user của bot nhắc sự kiện
+const (
+	calendarBotUsername = "calendar_bot"
+	calendarBotName     = "Calendar"
+)
+
+func (s *Server) mountCalendarRoutes(mux *http.ServeMux) {
+	// GET  /rooms/events/{roomID}?from=&to=          -> list (kèm occurrences trong khoảng)
+	// POST /rooms/events/{roomID}                    -> tạo (member)
+	// GET|PUT|DELETE /rooms/events/{roomID}/{id}     -> sửa / xoá: người tạo hoặc owner
+	// PUT  /rooms/events/{roomID}/{id}/rsvp {status} -> yes | no | maybe
+	// GET  /rooms/events/{roomID}/{id}/rsvps
+	// GET  /rooms/events/{roomID}/feed               -> URL ICS có ký (cho Google/Apple Calendar)
+	// GET  /rooms/events/{roomID}/calendar.ics?uid=&sig= (không cần login)
+	mux.Handle(roomEventsPrefix, http.HandlerFunc(s.handleRoomEvents))
+}
+
+type roomEventRequest struct {
+	Title         string `json:"title"`
+	Description   string `json:"description"`
+	Location      string `json:"location"`
+	StartAt       string `json:"start_at"` // RFC3339
+	EndAt         string `json:"end_at"`   // RFC3339, rỗng = start + 1h
+	Recurrence    string `json:"recurrence"`
+	RemindMinutes *int   `json:"remind_minutes"` // mặc định 15, 0 = không nhắc
+}
+
+func (s *Server) handleRoomEvents(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, roomEventsPrefix), "/"), "/")
+	roomID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || roomID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid room id"})
+		return
+	}
+
+	// app lịch không gửi được Authorization -> xác thực bằng chữ ký trong URL
+	if len(parts) == 2 && parts[1] == icsFeedFile {
+		s.serveRoomICS(w, r, roomID)
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+	if !s.requireRoomMember(w, roomID, userID) {
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		s.listRoomEvents(w, r, roomID, userID)
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		s.createRoomEvent(w, r, roomID, userID)
+	case len(parts) == 2 && parts[1] == "feed" && r.Method == http.MethodGet:
+		s.roomEventFeedURL(w, r, roomID, userID)
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		s.getRoomEvent(w, r, roomID, userID, parts[1])
+	case len(parts) == 2 && r.Method == http.MethodPut:
+		s.updateRoomEvent(w, r, roomID, userID, parts[1])
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		s.deleteRoomEvent(w, r, roomID, userID, parts[1])
+	case len(parts) == 3 && parts[2] == "rsvp" && r.Method == http.MethodPut:
+		s.setEventRSVP(w, r, roomID, userID, parts[1])
+	case len(parts) == 3 && parts[2] == "rsvps" && r.Method == http.MethodGet:
+		s.listEventRSVPs(w, r, roomID, parts[1])
+	case len(parts) <= 3:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
+
+// requireRoomMember: tự ghi response lỗi khi không phải member
+func (s *Server) requireRoomMember(w http.ResponseWriter, roomID, userID int64) bool {
+	isMember, err := s.roomRepo.IsUserInRoom(roomID, userID)
+	if err != nil && err != sql.ErrNoRows {
+		log.Println("IsUserInRoom error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return false
+	}
+	if !isMember {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "you are not a member of this room"})
+		return false
+	}
+	return true
+}
+
+// toRoomEvent: validate body vào e (giữ nguyên id/room/creator)
+func toRoomEvent(req *roomEventRequest, e *calendar.Event) error {
+	e.Title = strings.TrimSpace(req.Title)
+	if e.Title == "" || len([]rune(e.Title)) > 200 {
+		return errors.New("title is required (max 200 chars)")
+	}
+	e.Description = strings.TrimSpace(req.Description)
+	if len([]rune(e.Description)) > 4000 {
+		return errors.New("description too long (max 4000 chars)")
+	}
+	e.Location = strings.TrimSpace(req.Location)
+	if len([]rune(e.Location)) > 255 {
+		return errors.New("location too long (max 255 chars)")
+	}
+
+	start, err := time.Parse(time.RFC3339, strings.TrimSpace(req.StartAt))
+	if err != nil {
+		return errors.New("start_at must be RFC3339")
+	}
+	end := start.Add(time.Hour)
+	if v := strings.TrimSpace(req.EndAt); v != "" {
+		end, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			return errors.New("end_at must be RFC3339")
+		}
+	}
+	if !end.After(start) {
+		return errors.New("end_at must be after start_at")
+	}
+	e.StartAt, e.EndAt = start, end
+
+	rule, err := calendar.ParseRule(req.Recurrence)
+	if err != nil {
+		return err
+	}
+	e.Recurrence = rule.String()
+
+	e.RemindMinutes = 15
+	if req.RemindMinutes != nil {
+		if *req.RemindMinutes < 0 || *req.RemindMinutes > 7*24*60 {
+			return errors.New("remind_minutes must be 0..10080")
+		}
+		e.RemindMinutes = *req.RemindMinutes
+	}
+
+	now := time.Now()
+	e.ScheduleReminder(now, now)
+	return nil
+}
+
+func parseEventID(w http.ResponseWriter, raw string) (int64, bool) {
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid event id"})
+		return 0, false
+	}
+	return id, true
+}
+
+func writeCalendarError(w http.ResponseWriter, err error) {
+	if errors.Is(err, calendar.ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "event not found"})
+		return
+	}
+	log.Println("calendar error:", err)
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+}
+
+func (s *Server) listRoomEvents(w http.ResponseWriter, r *http.Request, roomID, userID int64) {
+	ctx := r.Context()
+	events, err := s.calendarRepo.ListByRoom(ctx, roomID)
+	if err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+	if err := s.calendarRepo.AttachRSVPs(ctx, roomID, userID, events); err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	// from/to: mở rộng lịch lặp thành từng lần diễn ra (mặc định 30 ngày tới)
+	q := r.URL.Query()
+	from := s.now()
+	to := from.AddDate(0, 0, 30)
+	if v := q.Get("from"); v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			from = t
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil && t.After(from) {
+			to = t
+		}
+	}
+	if to.Sub(from) > 366*24*time.Hour {
+		to = from.AddDate(1, 0, 0)
+	}
+	for _, e := range events {
+		e.Occurrences = calendar.Occurrences(e.StartAt, e.Rule(), from, to, 200)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"events": events})
+}
+
+func (s *Server) getRoomEvent(w http.ResponseWriter, r *http.Request, roomID, userID int64, rawID string) {
+	id, ok := parseEventID(w, rawID)
+	if !ok {
+		return
+	}
+	e, err := s.calendarRepo.Get(r.Context(), roomID, id)
+	if err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+	if err := s.calendarRepo.AttachRSVPs(r.Context(), roomID, userID, []*calendar.Event{e}); err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, e)
+}
+
+func (s *Server) createRoomEvent(w http.ResponseWriter, r *http.Request, roomID, userID int64) {
+	var req roomEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+
+	e := &calendar.Event{RoomID: roomID, CreatorID: userID}
+	if err := toRoomEvent(&req, e); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if err := s.calendarRepo.Create(r.Context(), e); err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, e)
+}
+
+// canManageEvent: người tạo event hoặc owner room
+func (s *Server) canManageEvent(e *calendar.Event, userID int64) (bool, error) {
+	if e.CreatorID == userID {
+		return true, nil
+	}
+	ownerID, err := s.roomRepo.GetRoomOwner(e.RoomID)
+	if err != nil {
+		return false, err
+	}
+	return ownerID == userID, nil
+}
+
+func (s *Server) loadManageableEvent(w http.ResponseWriter, r *http.Request, roomID, userID int64, rawID string) (*calendar.Event, bool) {
+	id, ok := parseEventID(w, rawID)
+	if !ok {
+		return nil, false
+	}
+	e, err := s.calendarRepo.Get(r.Context(), roomID, id)
+	if err != nil {
+		writeCalendarError(w, err)
+		return nil, false
+	}
+	ok, err = s.canManageEvent(e, userID)
+	if err != nil {
+		writeCalendarError(w, err)
+		return nil, false
+	}
+	if !ok {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "only creator or room owner can change this event"})
+		return nil, false
+	}
+	return e, true
+}
+
+func (s *Server) updateRoomEvent(w http.ResponseWriter, r *http.Request, roomID, userID int64, rawID string) {
+	var req roomEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+
+	e, ok := s.loadManageableEvent(w, r, roomID, userID, rawID)
+	if !ok {
+		return
+	}
+	if err := toRoomEvent(&req, e); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if err := s.calendarRepo.Update(r.Context(), e); err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, e)
+}
+
+func (s *Server) deleteRoomEvent(w http.ResponseWriter, r *http.Request, roomID, userID int64, rawID string) {
+	e, ok := s.loadManageableEvent(w, r, roomID, userID, rawID)
+	if !ok {
+		return
+	}
+	if err := s.calendarRepo.Delete(r.Context(), roomID, e.ID); err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+func (s *Server) setEventRSVP(w http.ResponseWriter, r *http.Request, roomID, userID int64, rawID string) {
+	id, ok := parseEventID(w, rawID)
+	if !ok {
+		return
+	}
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+	req.Status = strings.ToLower(strings.TrimSpace(req.Status))
+	if !calendar.IsValidRSVP(req.Status) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "status must be yes, no or maybe"})
+		return
+	}
+
+	ctx := r.Context()
+	if _, err := s.calendarRepo.Get(ctx, roomID, id); err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+	if err := s.calendarRepo.SetRSVP(ctx, id, userID, req.Status); err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"event_id": id, "status": req.Status})
+}
+
+func (s *Server) listEventRSVPs(w http.ResponseWriter, r *http.Request, roomID int64, rawID string) {
+	id, ok := parseEventID(w, rawID)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	if _, err := s.calendarRepo.Get(ctx, roomID, id); err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+	list, err := s.calendarRepo.ListRSVPs(ctx, id)
+	if err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"rsvps": list})
+}
+
+// ===== ICS feed =====
+
+// icsFeedSignature: HMAC-SHA256("ics\n" + roomID + "\n" + uid), không hết hạn
+// rời room là feed bị chặn (check member mỗi lần tải)
+func icsFeedSignature(secret []byte, roomID, userID int64) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "ics\n%d\n%d", roomID, userID)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Server) roomEventFeedURL(w http.ResponseWriter, r *http.Request, roomID, userID int64) {
+	v := url.Values{}
+	v.Set("uid", strconv.FormatInt(userID, 10))
+	v.Set("sig", icsFeedSignature(s.jwtSecret, roomID, userID))
+	path := roomEventsPrefix + strconv.FormatInt(roomID, 10) + "/" + icsFeedFile + "?" + v.Encode()
+
+	base := strings.TrimRight(os.Getenv("APP_PUBLIC_URL"), "/")
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	webcal := "webcal" + strings.TrimPrefix(strings.TrimPrefix(base, "https"), "http") + path
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"url":    base + path,
+		"webcal": webcal,
+	})
+}
+
+func (s *Server) serveRoomICS(w http.ResponseWriter, r *http.Request, roomID int64) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	q := r.URL.Query()
+	userID, err := strconv.ParseInt(q.Get("uid"), 10, 64)
+	if err != nil || userID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid link"})
+		return
+	}
+	want := icsFeedSignature(s.jwtSecret, roomID, userID)
+	if !hmac.Equal([]byte(want), []byte(q.Get("sig"))) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "invalid link"})
+		return
+	}
+	if !s.requireRoomMember(w, roomID, userID) {
+		return
+	}
+
+	ctx := r.Context()
+	rm, err := s.roomRepo.GetRoomByID(roomID)
+	if err != nil {
+		log.Println("GetRoomByID error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	events, err := s.calendarRepo.ListByRoom(ctx, roomID)
+	if err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="room-`+strconv.FormatInt(roomID, 10)+`.ics"`)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	if r.Method == http.MethodHead {
+		return
+	}
+	if err := calendar.WriteICS(w, rm.Name, events); err != nil {
+		log.Println("WriteICS error:", err)
+	}
+}
+
+// ===== Nhắc trước sự kiện (chạy cùng scheduler /remind) =====
+
+func (s *Server) fireDueEventReminders(ctx context.Context) {
+	now := s.now()
+	due, err := s.calendarRepo.ListDueReminders(ctx, now, 100)
+	if err != nil {
+		log.Println("[calendar] ListDueReminders error:", err)
+		return
+	}
+	if len(due) == 0 {
+		return
+	}
+
+	botID, err := s.botRepo.EnsureSystemBot(ctx, calendarBotUsername, calendarBotName)
+	if err != nil {
+		log.Println("[calendar] EnsureSystemBot error:", err)
+		return
+	}
+
+	for _, e := range due {
+		occ := now
+		if e.RemindOccAt != nil {
+			occ = *e.RemindOccAt
+		}
+
+		// lên lịch lần kế tiếp (bỏ qua các lần đã qua nếu scheduler từng dừng lâu)
+		after := occ
+		if now.After(after) {
+			after = now
+		}
+		next := *e
+		next.ScheduleReminder(after, now)
+
+		ok, err := s.calendarRepo.ClaimReminder(ctx, e, next.NextRemindAt, next.RemindOccAt)
+		if err != nil {
+			log.Printf("[calendar] ClaimReminder id=%d: %v", e.ID, err)
+			continue
+		}
+		if !ok || occ.Before(now) {
+			continue // instance khác đã gửi / lần này đã diễn ra rồi
+		}
+
+		content := fmt.Sprintf("📅 Sắp diễn ra: %s lúc %s (còn %d phút)",
+			e.Title, occ.Local().Format("15:04 02/01/2006"), int(occ.Sub(now).Round(time.Minute).Minutes()))
+		if e.Location != "" {
+			content += "\n📍 " + e.Location
+		}
+		if _, err := s.postBotMessage(ctx, botID, e.RoomID, content); err != nil {
+			log.Printf("[calendar] post id=%d: %v", e.ID, err)
+		}
+	}
+}
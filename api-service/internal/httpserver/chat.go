@@ -3,6 +3,9 @@ package httpserver
 import (
 	"context"
 	"cronhustler/api-service/internal/chat"
+	"cronhustler/api-service/internal/quota"
+	"cronhustler/api-service/internal/unread"
+	"cronhustler/api-service/internal/webhook"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -22,9 +25,12 @@ func (s *Server) mountChatRoutes(mux *http.ServeMux) {
 	mux.Handle("/rooms/send-messages/", http.HandlerFunc(s.handleSendMessage))
 
 	// reactions
-	mux.Handle("/messages/react/add", http.HandlerFunc(s.handleToggleReaction))      // POST (toggle)
-	mux.Handle("/messages/react/remove", http.HandlerFunc(s.handleRemoveReaction))   // POST (force remove)
-	mux.Handle("/messages/reactions/", http.HandlerFunc(s.handleGetReactionSummary)) // GET /messages/reactions/{messageID}
+	mux.Handle("/messages/react/add", http.HandlerFunc(s.handleToggleReaction))    // POST (toggle)
+	mux.Handle("/messages/react/remove", http.HandlerFunc(s.handleRemoveReaction)) // POST (force remove)
+	mux.Handle("/messages/reactions/", s.reactionRoutes())                         // GET /messages/reactions/{messageID}[/users]
+
+	// thread
+	mux.Handle(messagesPrefix, s.messageRoutes()) // GET /messages/{id}/thread, DELETE /messages/{id}, POST|DELETE /messages/{id}/pin
 
 	// receipts (seen)
 	mux.Handle("/rooms/seen", http.HandlerFunc(s.handleMarkRoomSeenUpTo))                  // POST
@@ -35,16 +41,23 @@ func (s *Server) mountChatRoutes(mux *http.ServeMux) {
 	// ✅ notifications / unread
 	mux.Handle("/rooms/unread-counts", http.HandlerFunc(s.handleGetUnreadCountsByRooms)) // GET
 	mux.Handle("/rooms/unread/", http.HandlerFunc(s.handleGetUnreadCountForRoom))        // GET /rooms/unread/{roomID}
+
+	// ===== Notify level (all | mentions) =====
+	mux.Handle("/rooms/notify-level/", http.HandlerFunc(s.handleRoomNotifyLevel)) // GET/PUT /rooms/notify-level/{roomID}
 }
 
 // =======================================
 // REQUEST / RESPONSE MODELS
 // =======================================
 
+// client_msg_id do FE sinh (uuid, ...), cột messages.client_msg_id VARCHAR(64)
+const maxClientMsgIDLen = 64
+
 type sendMessageRequest struct {
 	Content          string `json:"content"`
-	MessageType      string `json:"message_type"`                  // text | image | file | system
+	MessageType      string `json:"message_type"`                  // text | image | file | audio | system
 	ReplyToMessageID *int64 `json:"reply_to_message_id,omitempty"` // reply target
+	ClientMsgID      string `json:"client_msg_id,omitempty"`       // idempotency key do client sinh, retry trả lại tin cũ
 }
 
 type replyInfoResponse struct {
@@ -62,10 +75,24 @@ type sendMessageResponse struct {
 	SenderAvatarURL string `json:"sender_avatar_url"`
 	Content         string `json:"content"`
 	MessageType     string `json:"message_type"`
+	ClientMsgID     string `json:"client_msg_id,omitempty"` // FE khớp tin optimistic
 
 	ReplyToMessageID *int64             `json:"reply_to_message_id,omitempty"`
 	Reply            *replyInfoResponse `json:"reply,omitempty"`
 
+	Attachments []chat.Attachment `json:"attachments,omitempty"`
+
+	// như GET messages: media chính của tin (upload-image ?send=true)
+	MediaURL  string `json:"media_url,omitempty"`
+	MediaMIME string `json:"media_mime,omitempty"`
+	MediaSize int64  `json:"media_size,omitempty"`
+
+	MediaDurationMs int64 `json:"media_duration_ms,omitempty"` // voice message
+
+	WebhookID *int64        `json:"webhook_id,omitempty"`
+	Buttons   []chat.Button `json:"buttons,omitempty"`
+	Embeds    []chat.Embed  `json:"embeds,omitempty"`
+
 	CreatedAt string `json:"created_at"`
 }
 
@@ -92,6 +119,14 @@ type reactionSummaryResponse struct {
 	Reactions []chat.ReactionSummaryItem `json:"reactions"`
 }
 
+type reactionUsersResponse struct {
+	MessageID  int64                      `json:"message_id"`
+	Reaction   string                     `json:"reaction,omitempty"` // filter đang dùng
+	Counts     []chat.ReactionSummaryItem `json:"counts"`             // mọi reaction của tin, không theo trang
+	Users      []chat.ReactionUserItem    `json:"users"`
+	NextCursor string                     `json:"next_cursor,omitempty"`
+}
+
 // ===== Receipts (Seen) =====
 
 type markSeenRequest struct {
@@ -141,52 +176,81 @@ type listSeenUsersResponse struct {
 func (s *Server) handleSendMessage(w http.ResponseWriter, r *http.Request) {
 	// 1) only POST
 	if r.Method != http.MethodPost {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	// 2) auth
-	userID, err := GetUserIDFromRequest(r, s.jwtSecret)
+	userID, err := UserIDFromContext(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
 	// 3) parse roomID
 	roomID, err := getIDFromURL(r)
 	if err != nil || roomID <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid room id"})
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid room id")
 		return
 	}
 
-	// 4) membership
+	// 4) parse body
+	var req sendMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidJSON, "invalid json body")
+		return
+	}
+
+	// 5) suspend, membership, quota, validate, lưu, realtime (dùng chung với WS send_message)
+	if f := s.sendMessage(r.Context(), userID, roomID, req, func(v any) {
+		writeJSON(w, http.StatusOK, v)
+	}); f != nil {
+		f.write(w)
+	}
+}
+
+// sendMessage: validate + lưu tin + realtime cho REST và WS send_message.
+// respond nhận response (tin đã lưu, hoặc kết quả lệnh /remind) trước khi broadcast;
+// trả failure (chưa gọi respond) khi bị chặn / lỗi
+func (s *Server) sendMessage(ctx context.Context, userID, roomID int64, req sendMessageRequest, respond func(any)) *apiFailure {
+	// 0) user đang bị khoá
+	if f := s.suspendedFailure(ctx, userID); f != nil {
+		return f
+	}
+
+	// 1) membership
 	isMember, err := s.roomRepo.IsUserInRoom(roomID, userID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "you are not a member of this room"})
-			return
+			return newFailure(http.StatusForbidden, CodeNotMember, "you are not a member of this room")
 		}
 		log.Println("IsUserInRoom error:", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
-		return
+		return newFailure(http.StatusInternalServerError, CodeDBError, "db error")
 	}
 	if !isMember {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "you are not a member of this room"})
-		return
+		return newFailure(http.StatusForbidden, CodeNotMember, "you are not a member of this room")
 	}
 
-	// 5) parse body
-	var req sendMessageRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
-		return
+	// 1.1) retry cùng client_msg_id -> trả lại tin đã lưu (trước quota: retry không bị 429)
+	req.ClientMsgID = strings.TrimSpace(req.ClientMsgID)
+	if len(req.ClientMsgID) > maxClientMsgIDLen {
+		return newFailure(http.StatusBadRequest, CodeInvalidRequest, "client_msg_id is too long")
+	}
+	if req.ClientMsgID != "" {
+		if done, f := s.resendClientMessage(ctx, userID, roomID, req.ClientMsgID, respond); done {
+			return f
+		}
+	}
+
+	// 1.2) flood control: tin / phút, tin / ngày
+	if f := s.quotaFailure(ctx, userID, quota.KindMessagesPerMinute, quota.KindMessagesPerDay); f != nil {
+		return f
 	}
 
-	// 6) validate
+	// 2) validate
 	req.Content = strings.TrimSpace(req.Content)
 	if req.Content == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "content is required"})
-		return
+		return newFailure(http.StatusBadRequest, CodeInvalidRequest, "content is required")
 	}
 
 	msgType := strings.TrimSpace(req.MessageType)
@@ -194,14 +258,41 @@ func (s *Server) handleSendMessage(w http.ResponseWriter, r *http.Request) {
 		msgType = "text"
 	}
 	switch msgType {
-	case "text", "image", "file", "system":
+	case "text", "image", "file", "audio", "system":
 	default:
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid message_type"})
-		return
+		return newFailure(http.StatusBadRequest, CodeInvalidRequest, "invalid message_type")
+	}
+	if msgType == "audio" && !isAudioMessageContent(req.Content) {
+		return newFailure(http.StatusBadRequest, CodeInvalidRequest, "audio message must use media_url from /rooms/upload-audio")
+	}
+	if f := s.quarantinedUploadFailure(ctx, msgType, req.Content); f != nil {
+		return f
+	}
+	if f := s.uploadOwnerFailure(ctx, userID, roomID, msgType, req.Content); f != nil {
+		return f
+	}
+
+	// slash command: /remind ... -> tạo reminder, không lưu thành tin nhắn
+	if msgType == "text" && strings.HasPrefix(req.Content, "/remind") {
+		res, f := s.runRemindCommand(ctx, roomID, userID, req.Content)
+		if f != nil {
+			return f
+		}
+		respond(res)
+		return nil
+	}
+
+	// word filter: reject -> 422, mask -> thay nội dung, flag -> gửi + report
+	var flagged []string
+	if msgType == "text" {
+		var f *apiFailure
+		if req.Content, flagged, f = s.applyWordFilter(ctx, roomID, req.Content); f != nil {
+			return f
+		}
 	}
-	now := time.Now().UTC()
+	now := s.now().UTC()
 
-	// 7) build model
+	// 3) build model
 	msg := &chat.Message{
 		RoomID:           roomID,
 		SenderID:         userID,
@@ -209,46 +300,69 @@ func (s *Server) handleSendMessage(w http.ResponseWriter, r *http.Request) {
 		MessageType:      msgType,
 		IsTemp:           0,
 		ReplyToMessageID: req.ReplyToMessageID,
-		CreatedAt:   now, // ✅ QUAN TRỌNG
-
+		ClientMsgID:      req.ClientMsgID,
+		CreatedAt:        now, // ✅ QUAN TRỌNG
 	}
 
-
-
-	// 8) insert DB (validate reply + fill cache fields in msg)
-	ctx := r.Context()
+	// 4) insert DB (validate reply + fill cache fields in msg)
 	id, err := s.chatRepo.CreateMessage(ctx, msg, true)
 	if err != nil {
 		if errors.Is(err, chat.ErrInvalidReplyTarget) {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid reply target"})
-			return
+			return newFailure(http.StatusBadRequest, CodeInvalidRequest, "invalid reply target")
+		}
+		// 2 request cùng client_msg_id chạy song song: request thua trả tin của request thắng
+		if errors.Is(err, chat.ErrDuplicateClientMsgID) {
+			if done, f := s.resendClientMessage(ctx, userID, roomID, req.ClientMsgID, respond); done {
+				return f
+			}
 		}
 		log.Println("CreateMessage error:", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
-		return
+		return newFailure(http.StatusInternalServerError, CodeDBError, "db error")
 	}
 
-	// 9) sender info for realtime
-	senderName := "Unknown"
-	senderAvatar := ""
-	user, err := s.userRepo.GetUserByID(int(userID))
-	if err != nil {
-		log.Println("GetUserByID error:", err)
-	} else {
-		if user.Full_name.Valid && strings.TrimSpace(user.Full_name.String) != "" {
-			senderName = strings.TrimSpace(user.Full_name.String)
-		} else if strings.TrimSpace(user.Username) != "" {
-			senderName = strings.TrimSpace(user.Username)
-		}
-		if user.AvatarURL.Valid {
-			raw := strings.TrimSpace(user.AvatarURL.String)
-			if raw != "" {
-				senderAvatar = raw
-			}
-		}
+	s.analyticsRepo.Touch(ctx, userID)
+
+	// người gửi coi như đã seen tới tin của mình -> dời watermark (message_created đã báo cho cả room, không push room_seen_update riêng)
+	if err := s.roomRepo.MarkRoomSeenUpTo(ctx, roomID, userID, id); err != nil {
+		log.Println("MarkRoomSeenUpTo (sender) error:", err)
+	}
+
+	if len(flagged) > 0 {
+		s.flagFilteredMessage(ctx, id, roomID, userID, msg.Content, flagged)
+	}
+
+	// 5) response (schema giống GET), file upload -> attachment kèm metadata (kích thước, duration, waveform)
+	resp := s.sendResponse(ctx, msg, s.attachChatUpload(ctx, id, msg.MessageType, msg.Content))
+	if msg.MessageType == "audio" {
+		resp.MediaDurationMs = s.applyAudioMessage(ctx, id, msg.Content, resp.Attachments)
+	}
+
+	// 6) respond to sender
+	respond(resp)
+
+	// video upload -> transcode nền (nếu bật)
+	s.maybeEnqueueTranscode(id, roomID, msg.MessageType, msg.Content)
+
+	// 7) realtime push to room members (style đồng bộ)
+	s.broadcastMessageCreated(ctx, roomID, userID, resp)
+
+	// 8) automation rules của room (chạy nền, sau khi tin đã broadcast)
+	if msg.MessageType == "text" {
+		go s.runAutomation(id, roomID, userID, msg.Content)
+		// link trong tin -> preview OG (nền)
+		s.enqueueLinkPreview(roomID, id, msg.Content)
+	}
+	return nil
+}
+
+// sendResponse: response của tin vừa lưu (REST, WS ack, message_created)
+func (s *Server) sendResponse(ctx context.Context, msg *chat.Message, atts []chat.Attachment) sendMessageResponse {
+	senderName, senderAvatar := s.senderInfo(msg.SenderID)
+	var uploadRooms map[string]int64
+	if name, ok := mediaContentName(msg.MessageType, msg.Content); ok {
+		uploadRooms = s.uploadRooms(ctx, []string{name})
 	}
 
-	// 10) reply object for realtime (schema giống GET)
 	var reply *replyInfoResponse
 	if msg.ReplyToMessageID != nil && *msg.ReplyToMessageID > 0 {
 		reply = &replyInfoResponse{
@@ -259,25 +373,77 @@ func (s *Server) handleSendMessage(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	resp := sendMessageResponse{
-		ID:              id,
-		RoomID:          roomID,
-		SenderID:        userID,
+	return sendMessageResponse{
+		ID:              msg.ID,
+		RoomID:          msg.RoomID,
+		SenderID:        msg.SenderID,
 		SenderName:      senderName,
-		SenderAvatarURL: senderAvatar,
-		Content:         msg.Content,
+		SenderAvatarURL: s.signMediaURL(senderAvatar),
+		Content:         s.signMessageContent(msg.RoomID, msg.MessageType, msg.Content, uploadRooms),
 		MessageType:     msg.MessageType,
+		ClientMsgID:     msg.ClientMsgID,
 
 		ReplyToMessageID: msg.ReplyToMessageID,
 		Reply:            reply,
 
+		Attachments: atts,
+
+		MediaURL:  s.signMediaURL(msg.MediaURL),
+		MediaMIME: msg.MediaMIME,
+		MediaSize: msg.MediaSize,
+
 		CreatedAt: msg.CreatedAt.Format(time.RFC3339),
 	}
+}
 
-	// 11) respond to sender
-	writeJSON(w, http.StatusOK, resp)
+// resendClientMessage: sender đã gửi tin với clientMsgID -> respond lại tin đó, không lưu / broadcast lần nữa.
+// done = false khi chưa có tin hoặc lỗi đọc (gửi tiếp, unique index vẫn chặn tin trùng)
+func (s *Server) resendClientMessage(ctx context.Context, userID, roomID int64, clientMsgID string, respond func(any)) (bool, *apiFailure) {
+	msg, err := s.chatRepo.GetMessageByClientMsgID(ctx, userID, clientMsgID)
+	if err != nil {
+		if !errors.Is(err, chat.ErrMessageNotFound) {
+			log.Println("GetMessageByClientMsgID error:", err)
+		}
+		return false, nil
+	}
+	if msg.RoomID != roomID {
+		return true, newFailure(http.StatusConflict, CodeConflict, "client_msg_id already used in another room")
+	}
+
+	atts, err := s.chatRepo.ListAttachmentsBatch(ctx, []int64{msg.ID})
+	if err != nil {
+		log.Println("ListAttachmentsBatch error:", err)
+	}
+	resp := s.sendResponse(ctx, msg, s.signAttachments(atts[msg.ID]))
+	if msg.MessageType == "audio" && len(resp.Attachments) > 0 {
+		resp.MediaDurationMs = resp.Attachments[0].DurationMs
+	}
+	respond(resp)
+	return true, nil
+}
 
-	// 12) realtime push to room members (style đồng bộ)
+// senderInfo: tên hiển thị + avatar (raw, chưa ký) của người gửi
+func (s *Server) senderInfo(userID int64) (string, string) {
+	senderName := "Unknown"
+	senderAvatar := ""
+	user, err := s.userRepo.GetUserByID(int(userID))
+	if err != nil {
+		log.Println("GetUserByID error:", err)
+		return senderName, senderAvatar
+	}
+	if user.Full_name.Valid && strings.TrimSpace(user.Full_name.String) != "" {
+		senderName = strings.TrimSpace(user.Full_name.String)
+	} else if strings.TrimSpace(user.Username) != "" {
+		senderName = strings.TrimSpace(user.Username)
+	}
+	if user.AvatarURL.Valid {
+		senderAvatar = strings.TrimSpace(user.AvatarURL.String)
+	}
+	return senderName, senderAvatar
+}
+
+// broadcastMessageCreated: message_created cho cả room + unread update cho người nhận
+func (s *Server) broadcastMessageCreated(ctx context.Context, roomID, userID int64, resp sendMessageResponse) {
 	memberIDs, err := s.roomRepo.GetRoomMemberIDs(roomID)
 	if err != nil {
 		log.Println("GetRoomMemberIDs error:", err)
@@ -301,34 +467,88 @@ func (s *Server) handleSendMessage(w http.ResponseWriter, r *http.Request) {
 		},
 	})
 
+	// (B) outgoing webhook của room
+	s.emitWebhook(roomID, userID, webhook.EventMessageCreated, map[string]any{"message": resp})
+
 	// ✅ (C) unread notify: chỉ bắn cho người nhận (exclude sender)
-	// DB truth: mỗi user tự tính unread_count theo last_seen_at
+	// DB truth: unread.Repository (watermark last_seen_message_id)
 	recipients, err := s.chatRepo.ListRoomMemberUserIDsExcept(ctx, roomID, userID)
 	if err != nil {
 		log.Println("ListRoomMemberUserIDsExcept error:", err)
 		return
 	}
 
+	// (D) push cho người nhận đang offline (không có WS)
+	roomName, roomType := "", ""
+	if roomLite != nil {
+		roomName, roomType = roomLite.DisplayName, roomLite.Type
+	}
+	go s.pushOfflineRecipients(roomID, roomName, roomType, recipients, resp)
+
+	// mention: lưu riêng để badge mentions-only đếm được
+	mentioned := map[int64]bool{}
+	if resp.MessageType == "text" {
+		ids, err := s.chatRepo.RecordMentions(ctx, resp.ID, roomID, userID, chat.ExtractMentions(resp.Content))
+		if err != nil {
+			log.Println("RecordMentions error:", err)
+		}
+		for _, id := range ids {
+			mentioned[id] = true
+		}
+	}
+
 	go func(roomID int64, recips []int64) {
 		ctx2, cancel2 := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel2()
 
+		levels, err := s.chatRepo.GetNotifyLevels(ctx2, roomID)
+		if err != nil {
+			log.Println("GetNotifyLevels error:", err)
+		}
+		muted, err := s.pushRepo.RoomMutedUsers(ctx2, roomID, recips)
+		if err != nil {
+			log.Println("RoomMutedUsers error:", err)
+		}
+		archived, err := s.roomRepo.ArchivedMembers(ctx2, roomID)
+		if err != nil {
+			log.Println("ArchivedMembers error:", err)
+		}
+		counts, err := s.unreadRepo.ForMembers(ctx2, roomID, recips)
+		if err != nil {
+			log.Println("unread ForMembers error:", err)
+			return
+		}
+
 		for _, uid := range recips {
-			cnt, err := s.chatRepo.GetUnreadCount(ctx2, roomID, uid)
-			if err != nil {
-				log.Println("GetUnreadCount error:", err)
+			// room để mentions-only: chỉ tin nhắc đến mình mới bắn update
+			if levels[uid] == chat.NotifyLevelMentions && !mentioned[uid] {
+				continue
+			}
+			// room đang mute: không bắn update (unread vẫn đếm, sidebar tự fetch)
+			if muted[uid] {
+				continue
+			}
+			// room đã archive: để yên trong mục archive, không bắn update
+			if archived[uid] {
 				continue
 			}
 
+			cnt, ok := counts[uid]
+			if !ok {
+				continue // rời room giữa chừng
+			}
+
 			wsSendToUser(uid, wsEnvelope{
 				Type:   "room_unread_update",
 				RoomID: roomID,
 				Data: map[string]any{
-					"room_id":      roomID,
-					"user_id":      uid,
-					"unread_count": cnt,
-					"last_message": resp, // optional: FE khỏi fetch lại
-					"bump":         true, // optional: move room to top
+					"room_id":       roomID,
+					"user_id":       uid,
+					"unread_count":  cnt.Unread,
+					"mention_count": cnt.Mentions,
+					"mentioned":     mentioned[uid],
+					"last_message":  resp, // optional: FE khỏi fetch lại
+					"bump":          true, // optional: move room to top
 				},
 			})
 		}
@@ -345,7 +565,6 @@ func (s *Server) handleSendMessage(w http.ResponseWriter, r *http.Request) {
 	// 		"room":            roomLite, // ✅ kèm room_name
 	// 	},
 	// })
-
 }
 
 // =======================================
@@ -354,25 +573,25 @@ func (s *Server) handleSendMessage(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleToggleReaction(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	userID, err := GetUserIDFromRequest(r, s.jwtSecret)
+	userID, err := UserIDFromContext(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
 	var req reactMessageRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		writeError(w, http.StatusBadRequest, CodeInvalidJSON, "invalid json body")
 		return
 	}
 
 	req.Reaction = strings.TrimSpace(req.Reaction)
 	if req.MessageID <= 0 || req.Reaction == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "message_id and reaction are required"})
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "message_id and reaction are required")
 		return
 	}
 
@@ -381,7 +600,11 @@ func (s *Server) handleToggleReaction(w http.ResponseWriter, r *http.Request) {
 
 	added, err := s.chatRepo.ToggleReaction(ctx, req.MessageID, userID, req.Reaction)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		if errors.Is(err, chat.ErrReactionNotAllowed) {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
@@ -419,7 +642,7 @@ func (s *Server) handleToggleReaction(w http.ResponseWriter, r *http.Request) {
 			RoomID: roomID,
 			Data: map[string]any{
 				"message_id": messageID,
-				"reactions":  items,
+				"reactions":  s.signReactions(items),
 			},
 		})
 	}(req.MessageID, userID)
@@ -431,24 +654,24 @@ func (s *Server) handleToggleReaction(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleRemoveReaction(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	userID, err := GetUserIDFromRequest(r, s.jwtSecret)
+	userID, err := UserIDFromContext(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
 	var req removeReactionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		writeError(w, http.StatusBadRequest, CodeInvalidJSON, "invalid json body")
 		return
 	}
 
 	if req.MessageID <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "message_id is required"})
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "message_id is required")
 		return
 	}
 	req.Reaction = strings.TrimSpace(req.Reaction)
@@ -458,7 +681,7 @@ func (s *Server) handleRemoveReaction(w http.ResponseWriter, r *http.Request) {
 
 	if req.Reaction == "" {
 		if err := s.chatRepo.RemoveAllReactionsByUser(ctx, req.MessageID, userID); err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 			return
 		}
 		writeJSON(w, http.StatusOK, map[string]any{
@@ -470,7 +693,7 @@ func (s *Server) handleRemoveReaction(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.chatRepo.RemoveReaction(ctx, req.MessageID, userID, req.Reaction); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
@@ -485,37 +708,119 @@ func (s *Server) handleRemoveReaction(w http.ResponseWriter, r *http.Request) {
 // HANDLER: GET /messages/reactions/{messageID}
 // =======================================
 
-func (s *Server) handleGetReactionSummary(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+func (s *Server) reactionRoutes() http.Handler {
+	m := newPatternMux("/messages/reactions/")
+	withMessageID := func(h func(http.ResponseWriter, *http.Request, int64)) http.HandlerFunc {
+		return withPathID("messageID", "invalid message id", h)
+	}
+	m.handle(http.MethodGet, "/messages/reactions/{messageID}", withMessageID(s.handleGetReactionSummary))
+	m.handle(http.MethodGet, "/messages/reactions/{messageID}/users", withMessageID(s.handleListReactionUsers))
+	return m
+}
+
+func (s *Server) handleGetReactionSummary(w http.ResponseWriter, r *http.Request, messageID int64) {
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
-	userID, err := GetUserIDFromRequest(r, s.jwtSecret)
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	items, err := s.chatRepo.GetReactionSummary(ctx, messageID, userID)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
-	messageID, err := getMessageIDFromReactionsPath(r.URL.Path)
-	if err != nil || messageID <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid message id"})
+	writeJSON(w, http.StatusOK, reactionSummaryResponse{
+		MessageID: messageID,
+		Reactions: s.signReactions(items),
+	})
+}
+
+// GET /messages/reactions/{messageID}/users?reaction=love&cursor=&limit=50
+// người đã react theo trang (reaction rỗng = tất cả) + count từng reaction, chỉ member của room
+func (s *Server) handleListReactionUsers(w http.ResponseWriter, r *http.Request, messageID int64) {
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
+	q := r.URL.Query()
+	reaction := strings.TrimSpace(q.Get("reaction"))
+	limit := chat.DefaultReactionUsersLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid limit")
+			return
+		}
+		limit = min(n, chat.MaxReactionUsersLimit)
+	}
+	var afterID int64
+	if v := q.Get("cursor"); v != "" {
+		if afterID, err = chat.DecodeReactionCursor(v); err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid cursor")
+			return
+		}
+	}
+
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	items, err := s.chatRepo.GetReactionSummary(ctx, messageID, userID)
+	roomID, _, err := s.chatRepo.GetMessageRoomAndSender(ctx, messageID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		if errors.Is(err, chat.ErrMessageNotFound) {
+			writeError(w, http.StatusNotFound, CodeNotFound, "message not found")
+			return
+		}
+		log.Println("GetMessageRoomAndSender error:", err)
+		writeError(w, http.StatusInternalServerError, CodeDBError, "db error")
+		return
+	}
+	isMember, err := s.roomRepo.IsUserInRoom(roomID, userID)
+	if err != nil {
+		log.Println("IsUserInRoom error:", err)
+		writeError(w, http.StatusInternalServerError, CodeDBError, "db error")
+		return
+	}
+	if !isMember {
+		writeError(w, http.StatusForbidden, CodeNotMember, "not a room member")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, reactionSummaryResponse{
+	users, next, err := s.chatRepo.ListReactionsByMessage(ctx, messageID, reaction, afterID, limit)
+	if err != nil {
+		log.Println("ListReactionsByMessage error:", err)
+		writeError(w, http.StatusInternalServerError, CodeDBError, "db error")
+		return
+	}
+	for i := range users {
+		if users[i].AvatarURL != nil {
+			signed := s.signMediaURL(*users[i].AvatarURL)
+			users[i].AvatarURL = &signed
+		}
+	}
+	counts, err := s.chatRepo.GetReactionSummary(ctx, messageID, userID)
+	if err != nil {
+		log.Println("GetReactionSummary error:", err)
+		writeError(w, http.StatusInternalServerError, CodeDBError, "db error")
+		return
+	}
+
+	resp := reactionUsersResponse{
 		MessageID: messageID,
-		Reactions: items,
-	})
+		Reaction:  reaction,
+		Counts:    s.signReactions(counts),
+		Users:     users,
+	}
+	if next > 0 {
+		resp.NextCursor = chat.EncodeReactionCursor(next)
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 // =======================================
@@ -524,34 +829,34 @@ func (s *Server) handleGetReactionSummary(w http.ResponseWriter, r *http.Request
 
 func (s *Server) handleMarkRoomSeenUpTo(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	userID, err := GetUserIDFromRequest(r, s.jwtSecret)
+	userID, err := UserIDFromContext(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
 	var req markSeenRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		writeError(w, http.StatusBadRequest, CodeInvalidJSON, "invalid json body")
 		return
 	}
 	if req.RoomID <= 0 || req.UpToMessage <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "room_id and up_to_message_id are required"})
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "room_id and up_to_message_id are required")
 		return
 	}
 
 	// membership
 	isMember, err := s.roomRepo.IsUserInRoom(req.RoomID, userID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 	if !isMember {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "not a room member"})
+		writeError(w, http.StatusForbidden, CodeNotMember, "not a room member")
 		return
 	}
 
@@ -560,20 +865,20 @@ func (s *Server) handleMarkRoomSeenUpTo(w http.ResponseWriter, r *http.Request)
 
 	affected, err := s.chatRepo.MarkRoomSeenUpTo(ctx, req.RoomID, userID, req.UpToMessage)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
 	lastMsgID, lastAt, err := s.chatRepo.GetRoomLastSeenMessageID(ctx, req.RoomID, userID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
 	// ✅ Lấy info room để trả kèm response
 	room, err := s.roomRepo.GetRoomByIDLite(ctx, req.RoomID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
@@ -607,6 +912,11 @@ func (s *Server) handleMarkRoomSeenUpTo(w http.ResponseWriter, r *http.Request)
 	// respond first
 	writeJSON(w, http.StatusOK, resp)
 
+	// watermark không đổi (gửi lại / trễ) -> không push, tránh spam WS room lớn
+	if affected == 0 {
+		return
+	}
+
 	// realtime (style đồng bộ)
 	memberIDs, err := s.roomRepo.GetRoomMemberIDs(req.RoomID)
 	if err != nil {
@@ -633,6 +943,9 @@ func (s *Server) handleMarkRoomSeenUpTo(w http.ResponseWriter, r *http.Request)
 		},
 	})
 
+	// (B) badge unread trên các thiết bị khác của chính user
+	go s.pushUnreadToSelf(req.RoomID, userID)
+
 	// // (B) room_updated: nếu sidebar mày gom về room_updated thì nhét seen_update vào đây
 	// go wsSendToUsers(memberIDs, wsEnvelope{
 	// 	Type:   "room_updated",
@@ -660,29 +973,29 @@ func (s *Server) handleMarkRoomSeenUpTo(w http.ResponseWriter, r *http.Request)
 
 func (s *Server) handleGetRoomLastSeen(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	userID, err := GetUserIDFromRequest(r, s.jwtSecret)
+	userID, err := UserIDFromContext(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
 	roomID, err := getIDFromURL(r) // expects /rooms/last-seen/{roomID}
 	if err != nil || roomID <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid room id"})
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid room id")
 		return
 	}
 
 	isMember, err := s.roomRepo.IsUserInRoom(roomID, userID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 	if !isMember {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "not a room member"})
+		writeError(w, http.StatusForbidden, CodeNotMember, "not a room member")
 		return
 	}
 
@@ -691,7 +1004,7 @@ func (s *Server) handleGetRoomLastSeen(w http.ResponseWriter, r *http.Request) {
 
 	lastMsgID, lastAt, err := s.chatRepo.GetRoomLastSeenMessageID(ctx, roomID, userID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
@@ -713,19 +1026,19 @@ func (s *Server) handleGetRoomLastSeen(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleGetMessageSeenSummary(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	userID, err := GetUserIDFromRequest(r, s.jwtSecret)
+	userID, err := UserIDFromContext(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
 	messageID, err := getIDFromURL(r) // expects /messages/seen/summary/{messageID}
 	if err != nil || messageID <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid message id"})
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid message id")
 		return
 	}
 
@@ -735,26 +1048,26 @@ func (s *Server) handleGetMessageSeenSummary(w http.ResponseWriter, r *http.Requ
 	roomID, senderID, err := s.chatRepo.GetMessageRoomAndSender(ctx, messageID)
 	if err != nil {
 		if errors.Is(err, chat.ErrMessageNotFound) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "message not found"})
+			writeError(w, http.StatusNotFound, CodeNotFound, "message not found")
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
 	isMember, err := s.roomRepo.IsUserInRoom(roomID, userID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 	if !isMember {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "not a room member"})
+		writeError(w, http.StatusForbidden, CodeNotMember, "not a room member")
 		return
 	}
 
 	sum, err := s.chatRepo.GetMessageSeenSummary(ctx, messageID, userID, senderID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
@@ -771,19 +1084,19 @@ func (s *Server) handleGetMessageSeenSummary(w http.ResponseWriter, r *http.Requ
 
 func (s *Server) handleListSeenUsersByMessage(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	userID, err := GetUserIDFromRequest(r, s.jwtSecret)
+	userID, err := UserIDFromContext(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
 	messageID, err := getIDFromURL(r) // expects /messages/seen/users/{messageID}
 	if err != nil || messageID <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid message id"})
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid message id")
 		return
 	}
 
@@ -800,26 +1113,26 @@ func (s *Server) handleListSeenUsersByMessage(w http.ResponseWriter, r *http.Req
 	roomID, _, err := s.chatRepo.GetMessageRoomAndSender(ctx, messageID)
 	if err != nil {
 		if errors.Is(err, chat.ErrMessageNotFound) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "message not found"})
+			writeError(w, http.StatusNotFound, CodeNotFound, "message not found")
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
 	isMember, err := s.roomRepo.IsUserInRoom(roomID, userID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 	if !isMember {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "not a room member"})
+		writeError(w, http.StatusForbidden, CodeNotMember, "not a room member")
 		return
 	}
 
 	users, err := s.chatRepo.ListSeenUsersByMessage(ctx, messageID, userID, limit)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
@@ -833,98 +1146,198 @@ func (s *Server) handleListSeenUsersByMessage(w http.ResponseWriter, r *http.Req
 // HELPERS
 // =======================================
 
-// expects: /messages/reactions/{messageID}
-func getMessageIDFromReactionsPath(path string) (int64, error) {
-	prefix := "/messages/reactions/"
-	if !strings.HasPrefix(path, prefix) {
-		return 0, errors.New("invalid path")
-	}
-	raw := strings.Trim(strings.TrimPrefix(path, prefix), "/")
-	if raw == "" {
-		return 0, errors.New("missing id")
+// pushUnreadToSelf: room_unread_update (không bump) sau khi user dời watermark seen
+func (s *Server) pushUnreadToSelf(roomID, userID int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cnt, err := s.unreadRepo.Room(ctx, roomID, userID)
+	if err != nil {
+		log.Println("unread Room error:", err)
+		return
 	}
-	return strconv.ParseInt(raw, 10, 64)
+	wsSendToUser(userID, wsEnvelope{
+		Type:   "room_unread_update",
+		RoomID: roomID,
+		Data: map[string]any{
+			"room_id":       roomID,
+			"user_id":       userID,
+			"unread_count":  cnt.Unread,
+			"mention_count": cnt.Mentions,
+		},
+	})
 }
 
 type unreadCountForRoomResponse struct {
-	RoomID      int64 `json:"room_id"`
-	UserID      int64 `json:"user_id"`
-	UnreadCount int64 `json:"unread_count"`
+	RoomID       int64 `json:"room_id"`
+	UserID       int64 `json:"user_id"`
+	UnreadCount  int64 `json:"unread_count"`
+	MentionCount int64 `json:"mention_count"`
 }
 
 type unreadCountsByRoomsResponse struct {
-	UserID int64           `json:"user_id"`
-	Counts map[int64]int64 `json:"counts"` // room_id -> unread_count
+	UserID        int64            `json:"user_id"`
+	Counts        map[int64]int64  `json:"counts"`         // room_id -> unread_count
+	MentionCounts map[int64]int64  `json:"mention_counts"` // room_id -> số tin chưa đọc nhắc đến mình
+	NotifyLevels  map[int64]string `json:"notify_levels"`  // room_id -> mentions (room không có = all)
 }
 
 func (s *Server) handleGetUnreadCountsByRooms(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	userID, err := GetUserIDFromRequest(r, s.jwtSecret)
+	userID, err := UserIDFromContext(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	counts, err := s.chatRepo.GetUnreadCountsByRooms(ctx, userID)
+	byRoom, err := s.unreadRepo.ByRooms(ctx, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	counts := make(map[int64]int64, len(byRoom))
+	mentions := make(map[int64]int64)
+	for roomID, c := range byRoom {
+		if c.Unread > 0 {
+			counts[roomID] = c.Unread
+		}
+		if c.Mentions > 0 {
+			mentions[roomID] = c.Mentions
+		}
+	}
+
+	levels, err := s.chatRepo.GetNotifyLevelsByUser(ctx, userID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
 	writeJSON(w, http.StatusOK, unreadCountsByRoomsResponse{
-		UserID: userID,
-		Counts: counts,
+		UserID:        userID,
+		Counts:        counts,
+		MentionCounts: mentions,
+		NotifyLevels:  levels,
 	})
 }
 
 func (s *Server) handleGetUnreadCountForRoom(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	userID, err := GetUserIDFromRequest(r, s.jwtSecret)
+	userID, err := UserIDFromContext(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
 	roomID, err := getIDFromURL(r) // expects /rooms/unread/{roomID}
 	if err != nil || roomID <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid room id"})
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid room id")
 		return
 	}
 
 	// membership
 	isMember, err := s.roomRepo.IsUserInRoom(roomID, userID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 	if !isMember {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "not a room member"})
+		writeError(w, http.StatusForbidden, CodeNotMember, "not a room member")
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	cnt, err := s.chatRepo.GetUnreadCount(ctx, roomID, userID)
+	cnt, err := s.unreadRepo.Room(ctx, roomID, userID)
+	if errors.Is(err, unread.ErrNotMember) {
+		writeError(w, http.StatusForbidden, CodeNotMember, "not a room member")
+		return
+	}
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
 	writeJSON(w, http.StatusOK, unreadCountForRoomResponse{
-		RoomID:      roomID,
-		UserID:      userID,
-		UnreadCount: cnt,
+		RoomID:       roomID,
+		UserID:       userID,
+		UnreadCount:  cnt.Unread,
+		MentionCount: cnt.Mentions,
 	})
 }
+
+// ===== Notify level =====
+
+type roomNotifyLevelRequest struct {
+	Level string `json:"level"` // all | mentions
+}
+
+func (s *Server) handleRoomNotifyLevel(w http.ResponseWriter, r *http.Request) {
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
+		return
+	}
+
+	roomID, err := getIDFromURL(r) // /rooms/notify-level/{roomID}
+	if err != nil || roomID <= 0 {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid room id")
+		return
+	}
+
+	isMember, err := s.roomRepo.IsUserInRoom(roomID, userID)
+	if err != nil && err != sql.ErrNoRows {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	if !isMember {
+		writeError(w, http.StatusForbidden, CodeNotMember, "not a room member")
+		return
+	}
+
+	ctx := r.Context()
+	switch r.Method {
+	case http.MethodGet:
+		levels, err := s.chatRepo.GetNotifyLevelsByUser(ctx, userID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+			return
+		}
+		level := levels[roomID]
+		if level == "" {
+			level = chat.NotifyLevelAll
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"room_id": roomID, "level": level})
+
+	case http.MethodPut:
+		var req roomNotifyLevelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidJSON, "invalid json body")
+			return
+		}
+		if req.Level != chat.NotifyLevelAll && req.Level != chat.NotifyLevelMentions {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "level must be all or mentions")
+			return
+		}
+		if err := s.chatRepo.SetNotifyLevel(ctx, roomID, userID, req.Level); err != nil {
+			log.Println("SetNotifyLevel error:", err)
+			writeError(w, http.StatusInternalServerError, CodeDBError, "db error")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"room_id": roomID, "level": req.Level})
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+	}
+}
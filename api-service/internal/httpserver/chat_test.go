@@ -0,0 +1,602 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/chat"
+	"cronhustler/api-service/internal/scan"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSendMessage(t *testing.T) {
+	// tx của chat.Repository.CreateMessage: khoá room, chèn separator đầu ngày, chèn tin
+	expectInsert := func(mock sqlmock.Sqlmock, content string, clientMsgID any) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id FROM rooms WHERE id = \? FOR UPDATE`).WithArgs(int64(10)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+		mock.ExpectQuery(`SELECT 1 FROM messages WHERE room_id = \? AND sender_id = \?`).
+			WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+		args := anyArgs(19)
+		args[0], args[1], args[2], args[7], args[8] = int64(10), int64(1), clientMsgID, content, "text"
+		mock.ExpectExec(`INSERT INTO messages`).WithArgs(args...).
+			WillReturnResult(sqlmock.NewResult(100, 1))
+		mock.ExpectCommit()
+	}
+	const byClientMsgID = `FROM messages WHERE sender_id = \? AND client_msg_id = \?`
+	storedRow := func(roomID int64) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{
+			"id", "room_id", "sender_id", "client_msg_id", "content", "message_type", "is_temp",
+			"reply_to_message_id", "reply_preview", "reply_sender_name", "reply_message_type", "created_at",
+		}).AddRow(100, roomID, 1, "c-1", "hello", "text", 0, nil, nil, nil, nil, testNow)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		setup      func(mock sqlmock.Sqlmock)
+		wantStatus int
+		wantID     float64
+		wantClient any
+	}{
+		{
+			name:       "invalid room id",
+			path:       "/rooms/send-messages/abc",
+			body:       `{"content":"hi"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "suspended sender",
+			path: "/rooms/send-messages/10",
+			body: `{"content":"hi"}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				expectSuspended(mock, 1, time.Now().Add(time.Hour))
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "not a member",
+			path: "/rooms/send-messages/10",
+			body: `{"content":"hi"}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				expectMember(mock, 10, 1, false)
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "membership db error",
+			path: "/rooms/send-messages/10",
+			body: `{"content":"hi"}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`FROM room_members WHERE room_id = \?`).WillReturnError(sql.ErrConnDone)
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name: "empty content",
+			path: "/rooms/send-messages/10",
+			body: `{"content":"   "}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				expectMember(mock, 10, 1, true)
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "unknown message type",
+			path: "/rooms/send-messages/10",
+			body: `{"content":"hi","message_type":"sticker"}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				expectMember(mock, 10, 1, true)
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "invalid reply target",
+			path: "/rooms/send-messages/10",
+			body: `{"content":"hi","reply_to_message_id":55}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				expectMember(mock, 10, 1, true)
+				mock.ExpectQuery(`FROM messages rm LEFT JOIN users u`).WithArgs(int64(55), int64(10)).WillReturnError(sql.ErrNoRows)
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "ok",
+			path: "/rooms/send-messages/10",
+			body: `{"content":"  hello  "}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				expectMember(mock, 10, 1, true)
+				expectInsert(mock, "hello", nil)
+			},
+			wantStatus: http.StatusOK,
+			wantID:     100,
+		},
+		{
+			name: "client_msg_id too long",
+			path: "/rooms/send-messages/10",
+			body: `{"content":"hello","client_msg_id":"` + strings.Repeat("x", 65) + `"}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				expectMember(mock, 10, 1, true)
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "new client_msg_id is stored",
+			path: "/rooms/send-messages/10",
+			body: `{"content":"hello","client_msg_id":"c-1"}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				expectMember(mock, 10, 1, true)
+				mock.ExpectQuery(byClientMsgID).WithArgs(int64(1), "c-1").WillReturnError(sql.ErrNoRows)
+				expectInsert(mock, "hello", "c-1")
+			},
+			wantStatus: http.StatusOK,
+			wantID:     100,
+			wantClient: "c-1",
+		},
+		{
+			// retry sau lỗi mạng: không insert, trả tin đã lưu
+			name: "retry returns stored message",
+			path: "/rooms/send-messages/10",
+			body: `{"content":"hello","client_msg_id":"c-1"}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				expectMember(mock, 10, 1, true)
+				mock.ExpectQuery(byClientMsgID).WithArgs(int64(1), "c-1").WillReturnRows(storedRow(10))
+			},
+			wantStatus: http.StatusOK,
+			wantID:     100,
+			wantClient: "c-1",
+		},
+		{
+			name: "concurrent retry returns winner",
+			path: "/rooms/send-messages/10",
+			body: `{"content":"hello","client_msg_id":"c-1"}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				expectMember(mock, 10, 1, true)
+				mock.ExpectQuery(byClientMsgID).WithArgs(int64(1), "c-1").WillReturnError(sql.ErrNoRows)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT id FROM rooms WHERE id = \? FOR UPDATE`).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+				mock.ExpectQuery(`SELECT 1 FROM messages WHERE room_id = \? AND sender_id = \?`).
+					WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+				mock.ExpectExec(`INSERT INTO messages`).
+					WillReturnError(errors.New("Error 1062 (23000): Duplicate entry '1-c-1' for key 'messages.uq_messages_sender_client_msg'"))
+				mock.ExpectRollback()
+				mock.ExpectQuery(byClientMsgID).WithArgs(int64(1), "c-1").WillReturnRows(storedRow(10))
+			},
+			wantStatus: http.StatusOK,
+			wantID:     100,
+			wantClient: "c-1",
+		},
+		{
+			name: "client_msg_id used in another room",
+			path: "/rooms/send-messages/10",
+			body: `{"content":"hello","client_msg_id":"c-1"}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				expectMember(mock, 10, 1, true)
+				mock.ExpectQuery(byClientMsgID).WithArgs(int64(1), "c-1").WillReturnRows(storedRow(11))
+			},
+			wantStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newTestServer(t)
+			if tt.setup != nil {
+				tt.setup(mock)
+			}
+			rec := serve(s, http.MethodPost, tt.path, accessTokenFor(t, 1), tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			checkExpectations(t, mock)
+			if tt.wantID == 0 {
+				return
+			}
+			body := decodeBody(t, rec)
+			if body["id"] != tt.wantID || body["content"] != "hello" || body["created_at"] != testNow.Format(time.RFC3339) ||
+				body["client_msg_id"] != tt.wantClient {
+				t.Fatalf("body = %v", body)
+			}
+		})
+	}
+}
+
+func TestDaySeparatorInsertedOnFirstMessageOfDay(t *testing.T) {
+	s, mock := newTestServer(t)
+	expectMember(mock, 10, 1, true)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM rooms WHERE id = \? FOR UPDATE`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+	mock.ExpectQuery(`SELECT 1 FROM messages`).WillReturnError(sql.ErrNoRows)
+	// mốc ngày lấy từ CURDATE() của MySQL, không phải giờ UTC của api-service
+	mock.ExpectExec(`INSERT INTO messages .* DATE_FORMAT\(CURDATE\(\), '%Y-%m-%d'\).* TIMESTAMP\(CURDATE\(\)\)`).
+		WithArgs(int64(10), int64(chat.DaySeparatorSenderID)).
+		WillReturnResult(sqlmock.NewResult(99, 1))
+	mock.ExpectExec(`INSERT INTO messages`).WillReturnResult(sqlmock.NewResult(100, 1))
+	mock.ExpectCommit()
+
+	rec := serve(s, http.MethodPost, "/rooms/send-messages/10", accessTokenFor(t, 1), `{"content":"hi"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+	}
+	checkExpectations(t, mock)
+}
+
+// cleanScanner: scanner giả, chỉ để bật check file đã cách ly
+type cleanScanner struct{}
+
+func (cleanScanner) Scan(context.Context, string, io.Reader) (scan.Result, error) {
+	return scan.Result{Clean: true}, nil
+}
+
+func TestSendMessageQuarantinedFile(t *testing.T) {
+	s, mock := newTestServer(t)
+	s.SetUploadScanner(cleanScanner{}, "")
+	expectMember(mock, 10, 1, true)
+	mock.ExpectQuery(`FROM chat_uploads WHERE file_name = \?`).WithArgs("r10_u1_1.pdf").
+		WillReturnRows(sqlmock.NewRows([]string{"quarantined"}).AddRow(true))
+
+	rec := serve(s, http.MethodPost, "/rooms/send-messages/10", accessTokenFor(t, 1),
+		`{"content":"/static/chat_uploads/r10_u1_1.pdf","message_type":"file"}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+	}
+	if body := decodeBody(t, rec); body["code"] != CodeFileBlocked {
+		t.Fatalf("body = %v", body)
+	}
+	checkExpectations(t, mock)
+}
+
+func TestSendMessageUploadOwnership(t *testing.T) {
+	const byUpload = `FROM chat_uploads\s+WHERE file_name = \?`
+	uploadRow := func(roomID, uploaderID int64) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{
+			"file_name", "room_id", "uploader_id", "original_name", "content_type", "file_size", "created_at", "quarantined_at",
+		}).AddRow("r11_u1_1.png", roomID, uploaderID, "cat.png", "image/png", 3, testNow, nil)
+	}
+
+	tests := []struct {
+		name       string
+		content    string
+		setup      func(mock sqlmock.Sqlmock)
+		wantStatus int
+	}{
+		{
+			// regression: URL copy từ room 11 gửi vào room 10 -> tin được ký -> đọc được file room 11
+			name:       "upload of another room",
+			content:    "/static/chat_uploads/r11_u1_1.png",
+			setup:      func(mock sqlmock.Sqlmock) { mock.ExpectQuery(byUpload).WillReturnRows(uploadRow(11, 1)) },
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "upload of another member",
+			content:    "/static/chat_uploads/r11_u1_1.png",
+			setup:      func(mock sqlmock.Sqlmock) { mock.ExpectQuery(byUpload).WillReturnRows(uploadRow(10, 2)) },
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown file",
+			content:    "/static/chat_uploads/r10_u1_9.png",
+			setup:      func(mock sqlmock.Sqlmock) { mock.ExpectQuery(byUpload).WillReturnError(sql.ErrNoRows) },
+			wantStatus: http.StatusBadRequest,
+		},
+		{name: "not an upload url", content: "https://example.com/cat.png", wantStatus: http.StatusBadRequest},
+		{name: "path traversal", content: "/static/chat_uploads/../user_avatars/a.png", wantStatus: http.StatusBadRequest},
+		{
+			name:    "own upload",
+			content: "/static/chat_uploads/r11_u1_1.png?exp=1&sig=x",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(byUpload).WithArgs("r11_u1_1.png").WillReturnRows(uploadRow(10, 1))
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT id FROM rooms WHERE id = \? FOR UPDATE`).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+				mock.ExpectQuery(`SELECT 1 FROM messages WHERE room_id = \? AND sender_id = \?`).
+					WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+				mock.ExpectExec(`INSERT INTO messages`).WillReturnResult(sqlmock.NewResult(100, 1))
+				mock.ExpectCommit()
+			},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newTestServer(t)
+			expectMember(mock, 10, 1, true)
+			if tt.setup != nil {
+				tt.setup(mock)
+			}
+			rec := serve(s, http.MethodPost, "/rooms/send-messages/10", accessTokenFor(t, 1),
+				`{"content":"`+tt.content+`","message_type":"image"}`)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			checkExpectations(t, mock)
+		})
+	}
+}
+
+func TestToggleReaction(t *testing.T) {
+	const allowed = `SELECT EXISTS\(SELECT 1 FROM reaction_emojis`
+
+	tests := []struct {
+		name       string
+		body       string
+		setup      func(mock sqlmock.Sqlmock)
+		wantStatus int
+		wantAdded  any
+	}{
+		{name: "invalid json", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "missing reaction", body: `{"message_id":5,"reaction":" "}`, wantStatus: http.StatusBadRequest},
+		{name: "missing message", body: `{"reaction":"like"}`, wantStatus: http.StatusBadRequest},
+		{
+			name: "reaction not in catalog",
+			body: `{"message_id":5,"reaction":"nope"}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(allowed).WithArgs("nope").WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(0))
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "add",
+			body: `{"message_id":5,"reaction":"like"}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(allowed).WithArgs("like").WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(1))
+				mock.ExpectExec(`INSERT IGNORE INTO message_reactions`).WithArgs(int64(5), int64(1), "like").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			wantStatus: http.StatusOK,
+			wantAdded:  true,
+		},
+		{
+			name: "toggle off",
+			body: `{"message_id":5,"reaction":"like"}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(allowed).WithArgs("like").WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(1))
+				mock.ExpectExec(`INSERT IGNORE INTO message_reactions`).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(`DELETE FROM message_reactions`).WithArgs(int64(5), int64(1), "like").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantStatus: http.StatusOK,
+			wantAdded:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newTestServer(t)
+			if tt.setup != nil {
+				tt.setup(mock)
+			}
+			rec := serve(s, http.MethodPost, "/messages/react/add", accessTokenFor(t, 1), tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			checkExpectations(t, mock)
+			if tt.wantAdded == nil {
+				return
+			}
+			if got := decodeBody(t, rec)["added"]; got != tt.wantAdded {
+				t.Fatalf("added = %v, want %v", got, tt.wantAdded)
+			}
+		})
+	}
+}
+
+func TestMarkRoomSeenUpTo(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		setup      func(mock sqlmock.Sqlmock)
+		wantStatus int
+	}{
+		{name: "invalid json", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "missing message", body: `{"room_id":10}`, wantStatus: http.StatusBadRequest},
+		{
+			name: "not a member",
+			body: `{"room_id":10,"up_to_message_id":40}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				expectMember(mock, 10, 1, false)
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "watermark already ahead",
+			body: `{"room_id":10,"up_to_message_id":40}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				expectMember(mock, 10, 1, true)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT last_seen_message_id FROM room_members .* FOR UPDATE`).
+					WithArgs(int64(10), int64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"last_seen_message_id"}).AddRow(50))
+				mock.ExpectCommit()
+				mock.ExpectQuery(`SELECT last_seen_message_id, last_seen_at FROM room_members`).
+					WithArgs(int64(10), int64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"last_seen_message_id", "last_seen_at"}).AddRow(50, testNow))
+				mock.ExpectQuery(`SELECT id, name, type, updated_at FROM rooms`).WithArgs(int64(10)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "type", "updated_at"}).AddRow(10, "team", "group", testNow))
+			},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newTestServer(t)
+			if tt.setup != nil {
+				tt.setup(mock)
+			}
+			rec := serve(s, http.MethodPost, "/rooms/seen", accessTokenFor(t, 1), tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			checkExpectations(t, mock)
+		})
+	}
+}
+
+func TestUnreadCounts(t *testing.T) {
+	countsRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"room_id", "user_id", "unread_count", "mention_count"})
+	}
+
+	t.Run("room", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		expectMember(mock, 10, 1, true)
+		mock.ExpectQuery(`m.id > COALESCE\(rm.last_seen_message_id, 0\) .* WHERE rm.room_id = \? AND rm.user_id = \?`).
+			WithArgs(int64(10), int64(1)).
+			WillReturnRows(countsRows().AddRow(10, 1, 4, 1))
+
+		rec := serve(s, http.MethodGet, "/rooms/unread/10", accessTokenFor(t, 1), "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+		}
+		checkExpectations(t, mock)
+		if body := decodeBody(t, rec); body["unread_count"] != float64(4) || body["mention_count"] != float64(1) {
+			t.Fatalf("body = %v", body)
+		}
+	})
+
+	t.Run("left room between checks", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		expectMember(mock, 10, 1, true)
+		mock.ExpectQuery(`FROM room_members rm WHERE rm.room_id = \?`).WillReturnRows(countsRows())
+
+		rec := serve(s, http.MethodGet, "/rooms/unread/10", accessTokenFor(t, 1), "")
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+		}
+		checkExpectations(t, mock)
+	})
+
+	t.Run("by rooms", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		mock.ExpectQuery(`FROM room_members rm WHERE rm.user_id = \?`).WithArgs(int64(1)).
+			WillReturnRows(countsRows().AddRow(10, 1, 3, 0).AddRow(11, 1, 0, 0).AddRow(12, 1, 2, 2))
+		mock.ExpectQuery(`SELECT room_id, notify_level FROM room_members`).
+			WillReturnRows(sqlmock.NewRows([]string{"room_id", "notify_level"}))
+
+		rec := serve(s, http.MethodGet, "/rooms/unread-counts", accessTokenFor(t, 1), "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+		}
+		checkExpectations(t, mock)
+		body := decodeBody(t, rec)
+		counts, _ := body["counts"].(map[string]any)
+		mentions, _ := body["mention_counts"].(map[string]any)
+		if len(counts) != 2 || counts["10"] != float64(3) || counts["12"] != float64(2) ||
+			len(mentions) != 1 || mentions["12"] != float64(2) {
+			t.Fatalf("body = %v", body)
+		}
+	})
+}
+
+func TestListReactionUsers(t *testing.T) {
+	const (
+		findMessage = `SELECT room_id, sender_id FROM messages WHERE id=\? LIMIT 1`
+		listUsers   = `FROM message_reactions mr JOIN users u ON u.id = mr.user_id`
+	)
+	reactorRows := func(ids ...int64) *sqlmock.Rows {
+		rows := sqlmock.NewRows([]string{"id", "user_id", "full_name", "avatar_url", "reaction", "created_at"})
+		for _, id := range ids {
+			rows.AddRow(id, id+100, "user", nil, "love", testNow)
+		}
+		return rows
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		setup      func(mock sqlmock.Sqlmock)
+		wantStatus int
+		wantUsers  int
+		wantNext   string
+	}{
+		{name: "invalid message id", path: "/messages/reactions/abc/users", wantStatus: http.StatusBadRequest},
+		{name: "invalid cursor", path: "/messages/reactions/5/users?cursor=not-a-cursor", wantStatus: http.StatusBadRequest},
+		{name: "invalid limit", path: "/messages/reactions/5/users?limit=0", wantStatus: http.StatusBadRequest},
+		{
+			name: "message not found",
+			path: "/messages/reactions/5/users",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(findMessage).WithArgs(int64(5)).WillReturnError(sql.ErrNoRows)
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "not a member",
+			path: "/messages/reactions/5/users",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(findMessage).WithArgs(int64(5)).
+					WillReturnRows(sqlmock.NewRows([]string{"room_id", "sender_id"}).AddRow(10, 2))
+				expectMember(mock, 10, 1, false)
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "first page has next cursor",
+			path: "/messages/reactions/5/users?reaction=love&limit=2",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(findMessage).WithArgs(int64(5)).
+					WillReturnRows(sqlmock.NewRows([]string{"room_id", "sender_id"}).AddRow(10, 2))
+				expectMember(mock, 10, 1, true)
+				mock.ExpectQuery(listUsers).WithArgs(int64(5), "love", "love", int64(0), 3).
+					WillReturnRows(reactorRows(7, 9, 12))
+				mock.ExpectQuery(`GROUP BY mr.reaction`).WithArgs(int64(1), int64(5)).
+					WillReturnRows(sqlmock.NewRows([]string{"reaction", "cnt", "me", "kind", "glyph", "image_url", "label", "is_active"}).
+						AddRow("love", 3, 0, nil, nil, nil, nil, nil))
+			},
+			wantStatus: http.StatusOK,
+			wantUsers:  2,
+			wantNext:   chat.EncodeReactionCursor(9),
+		},
+		{
+			name: "last page",
+			path: "/messages/reactions/5/users?cursor=" + chat.EncodeReactionCursor(9),
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(findMessage).WithArgs(int64(5)).
+					WillReturnRows(sqlmock.NewRows([]string{"room_id", "sender_id"}).AddRow(10, 2))
+				expectMember(mock, 10, 1, true)
+				mock.ExpectQuery(listUsers).WithArgs(int64(5), "", "", int64(9), chat.DefaultReactionUsersLimit+1).
+					WillReturnRows(reactorRows(12))
+				mock.ExpectQuery(`GROUP BY mr.reaction`).WithArgs(int64(1), int64(5)).
+					WillReturnRows(sqlmock.NewRows([]string{"reaction", "cnt", "me", "kind", "glyph", "image_url", "label", "is_active"}).
+						AddRow("love", 3, 0, nil, nil, nil, nil, nil))
+			},
+			wantStatus: http.StatusOK,
+			wantUsers:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newTestServer(t)
+			if tt.setup != nil {
+				tt.setup(mock)
+			}
+			rec := serve(s, http.MethodGet, tt.path, accessTokenFor(t, 1), "")
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			checkExpectations(t, mock)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			body := decodeBody(t, rec)
+			users, _ := body["users"].([]any)
+			counts, _ := body["counts"].([]any)
+			next, _ := body["next_cursor"].(string)
+			if len(users) != tt.wantUsers || len(counts) != 1 || next != tt.wantNext {
+				t.Fatalf("body = %v", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/contact"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	contactsPrefix        = "/contacts/"
+	contactRequestsPrefix = "/contacts/requests/"
+)
+
+func (s *Server) mountContactRoutes(mux *http.ServeMux) {
+	// GET /contacts -> danh bạ của mình
+	mux.Handle("/contacts", http.HandlerFunc(s.handleMyContacts))
+	// DELETE /contacts/{userID} -> xoá khỏi danh bạ (2 chiều)
+	mux.Handle(contactsPrefix, http.HandlerFunc(s.handleRemoveContact))
+	// GET /contacts/requests?direction=incoming|outgoing | POST /contacts/requests {user_id}
+	mux.Handle("/contacts/requests", http.HandlerFunc(s.handleContactRequests))
+	// POST /contacts/requests/{id}/accept | /decline, DELETE /contacts/requests/{id} (người gửi huỷ)
+	mux.Handle(contactRequestsPrefix, http.HandlerFunc(s.handleContactRequestByID))
+}
+
+type sendContactRequest struct {
+	UserID int64 `json:"user_id"`
+}
+
+func (s *Server) signContactRequest(q *contact.Request) *contact.Request {
+	q.OtherAvatarURL = s.signMediaURL(q.OtherAvatarURL)
+	return q
+}
+
+// GET /contacts
+func (s *Server) handleMyContacts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	list, err := s.contactRepo.ListContacts(r.Context(), userID)
+	if err != nil {
+		log.Println("ListContacts error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	for i := range list {
+		list[i].AvatarURL = s.signMediaURL(list[i].AvatarURL)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"contacts": list})
+}
+
+// DELETE /contacts/{userID}
+func (s *Server) handleRemoveContact(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	otherID, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(r.URL.Path, contactsPrefix), "/"), 10, 64)
+	if err != nil || otherID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid user id"})
+		return
+	}
+
+	found, err := s.contactRepo.RemoveContact(r.Context(), userID, otherID)
+	if err != nil {
+		log.Println("RemoveContact error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	if !found {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not in contacts"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"user_id": otherID, "removed": true})
+
+	// đồng bộ các thiết bị của mình, người kia cũng mất contact
+	wsSendToUser(userID, wsEnvelope{Type: "contact_removed", Data: map[string]any{"user_id": otherID}})
+	wsSendToUser(otherID, wsEnvelope{Type: "contact_removed", Data: map[string]any{"user_id": userID}})
+}
+
+// GET|POST /contacts/requests
+func (s *Server) handleContactRequests(w http.ResponseWriter, r *http.Request) {
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		dir := r.URL.Query().Get("direction")
+		if dir == "" {
+			dir = "incoming"
+		}
+		if dir != "incoming" && dir != "outgoing" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "direction must be incoming or outgoing"})
+			return
+		}
+		list, err := s.contactRepo.ListPending(ctx, userID, dir == "incoming")
+		if err != nil {
+			log.Println("ListPending contact requests error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		for _, q := range list {
+			s.signContactRequest(q)
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"direction": dir, "requests": list})
+
+	case http.MethodPost:
+		var req sendContactRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+			return
+		}
+		if req.UserID <= 0 || req.UserID == userID {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid user_id"})
+			return
+		}
+
+		q, accepted, err := s.contactRepo.SendRequest(ctx, userID, req.UserID)
+		if err != nil {
+			writeContactError(w, "SendRequest", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"request": s.signContactRequest(q), "accepted": accepted})
+
+		if accepted {
+			// người kia đã mời mình trước -> thành contact luôn
+			s.notifyContactAccepted(ctx, q)
+			return
+		}
+		s.notifyContactRequest(ctx, q.ID, req.UserID)
+
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+// POST /contacts/requests/{id}/accept | /decline, DELETE /contacts/requests/{id}
+func (s *Server) handleContactRequestByID(w http.ResponseWriter, r *http.Request) {
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, contactRequestsPrefix), "/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request id"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		found, err := s.contactRepo.CancelRequest(ctx, id, userID)
+		if err != nil {
+			log.Println("CancelRequest error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		if !found {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "request not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"id": id, "cancelled": true})
+
+	case len(parts) == 2 && (parts[1] == "accept" || parts[1] == "decline"):
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		accept := parts[1] == "accept"
+		q, err := s.contactRepo.RespondRequest(ctx, id, userID, accept)
+		if err != nil {
+			writeContactError(w, "RespondRequest", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, s.signContactRequest(q))
+		if accept {
+			s.notifyContactAccepted(ctx, q)
+		}
+
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
+
+// notifyContactRequest: WS contact_request_received cho người nhận (request nhìn từ phía họ)
+func (s *Server) notifyContactRequest(ctx context.Context, id, toID int64) {
+	q, err := s.contactRepo.GetRequest(ctx, id, toID)
+	if err != nil {
+		log.Println("GetRequest (notify) error:", err)
+		return
+	}
+	wsSendToUser(toID, wsEnvelope{Type: "contact_request_received", Data: s.signContactRequest(q)})
+}
+
+// notifyContactAccepted: WS contact_request_accepted cho cả 2 bên
+func (s *Server) notifyContactAccepted(ctx context.Context, q *contact.Request) {
+	for _, uid := range []int64{q.FromUserID, q.ToUserID} {
+		view, err := s.contactRepo.GetRequest(ctx, q.ID, uid)
+		if err != nil {
+			log.Println("GetRequest (notify) error:", err)
+			continue
+		}
+		wsSendToUser(uid, wsEnvelope{Type: "contact_request_accepted", Data: s.signContactRequest(view)})
+	}
+}
+
+func writeContactError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, contact.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "request not found"})
+	case errors.Is(err, contact.ErrUserNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "user not found"})
+	case errors.Is(err, contact.ErrNotPending):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "request already answered"})
+	case errors.Is(err, contact.ErrAlreadyContact):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "already in contacts"})
+	default:
+		log.Println(op+" error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+	}
+}
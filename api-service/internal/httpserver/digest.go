@@ -0,0 +1,294 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/digest"
+	"cronhustler/api-service/internal/job"
+	"cronhustler/api-service/internal/notify"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	roomDigestPrefix = "/rooms/digest/"
+
+	jobActionRoomDigest = "room_digest"
+
+	digestBotUsername = "digest_bot"
+	digestBotName     = "Daily Digest"
+)
+
+func (s *Server) mountDigestRoutes(mux *http.ServeMux) {
+	// member của room:
+	// GET /rooms/digest/{roomID}                 -> {settings, subscribed}
+	// PUT /rooms/digest/{roomID}/subscription    {email} -> bật / tắt nhận digest qua email
+	// GET /rooms/digest/{roomID}/preview?hours=  -> digest của N giờ gần nhất (không gửi)
+	// owner / admin của room:
+	// PUT /rooms/digest/{roomID}                 {enabled, post_message, timezone}
+	mux.Handle(roomDigestPrefix, http.HandlerFunc(s.handleRoomDigest))
+}
+
+type roomDigestPayload struct {
+	Hours int `json:"hours"` // khoảng thời gian gom tin, mặc định 24
+}
+
+func parseRoomDigestPayload(raw json.RawMessage) (*roomDigestPayload, error) {
+	var p roomDigestPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, errors.New("payload must be {hours}")
+	}
+	if p.Hours == 0 {
+		p.Hours = 24
+	}
+	if p.Hours < 1 || p.Hours > 168 {
+		return nil, errors.New("payload.hours must be 1..168")
+	}
+	return &p, nil
+}
+
+// runRoomDigestJob: mỗi room bật digest -> đăng tin hệ thống + gửi email cho user đăng ký.
+// gom từ lần gửi trước (tối đa payload.hours) nên job chạy lại không bị trùng tin
+func (s *Server) runRoomDigestJob(ctx context.Context, j *job.Job) (string, error) {
+	p, err := parseRoomDigestPayload(j.Payload)
+	if err != nil {
+		return "", err
+	}
+	rooms, err := s.digestRepo.ListEnabled(ctx)
+	if err != nil {
+		return "", err
+	}
+	botID, err := s.botRepo.EnsureSystemBot(ctx, digestBotUsername, digestBotName)
+	if err != nil {
+		return "", err
+	}
+
+	now := s.now()
+	var posted, emailed, empty, failed int
+	for _, st := range rooms {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		from := now.Add(-time.Duration(p.Hours) * time.Hour)
+		if st.LastSentAt != nil && st.LastSentAt.After(from) {
+			from = *st.LastSentAt
+		}
+
+		d, err := s.digestRepo.Build(ctx, st.RoomID, from, now, botID)
+		if err != nil {
+			log.Printf("[room_digest] room=%d: %v", st.RoomID, err)
+			failed++
+			continue
+		}
+		if d.Empty() {
+			empty++
+			continue
+		}
+
+		loc := st.Location()
+		if st.PostMessage {
+			if _, err := s.postBotMessage(ctx, botID, st.RoomID, d.Text(loc)); err != nil {
+				log.Printf("[room_digest] post room=%d: %v", st.RoomID, err)
+				failed++
+				continue
+			}
+			posted++
+		}
+		emailed += s.mailRoomDigest(ctx, d, loc)
+
+		if err := s.digestRepo.MarkSent(ctx, st.RoomID, now); err != nil {
+			log.Printf("[room_digest] MarkSent room=%d: %v", st.RoomID, err)
+		}
+	}
+
+	out := fmt.Sprintf("rooms=%d posted=%d emailed=%d empty=%d failed=%d", len(rooms), posted, emailed, empty, failed)
+	if failed > 0 && failed == len(rooms) {
+		return out, errors.New("all rooms failed")
+	}
+	return out, nil
+}
+
+// mailRoomDigest: gửi cho user đăng ký email, trả số email đã gửi (chưa cấu hình mail -> 0)
+func (s *Server) mailRoomDigest(ctx context.Context, d *digest.Digest, loc *time.Location) int {
+	if s.mailer == nil {
+		return 0
+	}
+	recipients, err := s.digestRepo.ListRecipients(ctx, d.RoomID)
+	if err != nil {
+		log.Printf("[room_digest] ListRecipients room=%d: %v", d.RoomID, err)
+		return 0
+	}
+
+	data := notify.RoomDigestData{
+		Room:         d.RoomName,
+		Date:         d.From.In(loc).Format("02/01/2006"),
+		MessageCount: d.MessageCount,
+		ActiveUsers:  d.ActiveUsers,
+		TopMessages:  digest.Lines(d.TopMessages, loc),
+		Decisions:    digest.Lines(d.Decisions, loc),
+		Files:        digest.Lines(d.Files, loc),
+	}
+	sent := 0
+	for _, rc := range recipients {
+		data.Name = rc.Name
+		if err := s.sendMail(ctx, notify.TemplateRoomDigest, rc.Email, data); err != nil {
+			log.Printf("[room_digest] mail room=%d user=%d: %v", d.RoomID, rc.UserID, err)
+			continue
+		}
+		sent++
+	}
+	return sent
+}
+
+type roomDigestSettingsRequest struct {
+	Enabled     *bool   `json:"enabled"`
+	PostMessage *bool   `json:"post_message"`
+	Timezone    *string `json:"timezone"`
+}
+
+func (s *Server) handleRoomDigest(w http.ResponseWriter, r *http.Request) {
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, roomDigestPrefix), "/"), "/")
+	roomID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || roomID <= 0 || len(parts) > 2 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid room id"})
+		return
+	}
+	sub := ""
+	if len(parts) == 2 {
+		sub = parts[1]
+	}
+
+	switch {
+	case sub == "" && r.Method == http.MethodGet:
+		if !s.requireRoomMember(w, roomID, userID) {
+			return
+		}
+		s.writeRoomDigestSettings(w, r, roomID, userID)
+
+	case sub == "" && r.Method == http.MethodPut:
+		if !s.requireRoomManager(w, r, roomID, userID) {
+			return
+		}
+		var req roomDigestSettingsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+			return
+		}
+		st, err := s.digestRepo.GetSettings(r.Context(), roomID)
+		if err != nil {
+			log.Println("GetSettings error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		// field bỏ trống -> giữ giá trị cũ
+		if req.Enabled != nil {
+			st.Enabled = *req.Enabled
+		}
+		if req.PostMessage != nil {
+			st.PostMessage = *req.PostMessage
+		}
+		if req.Timezone != nil {
+			st.Timezone = *req.Timezone
+		}
+		if err := s.digestRepo.SaveSettings(r.Context(), st, userID); err != nil {
+			if errors.Is(err, digest.ErrInvalidSettings) {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "timezone is not a valid IANA zone"})
+				return
+			}
+			log.Println("SaveSettings error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		s.writeRoomDigestSettings(w, r, roomID, userID)
+
+	case sub == "subscription" && r.Method == http.MethodPut:
+		if !s.requireRoomMember(w, roomID, userID) {
+			return
+		}
+		var req struct {
+			Email bool `json:"email"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+			return
+		}
+		if err := s.digestRepo.SetSubscribed(r.Context(), roomID, userID, req.Email); err != nil {
+			log.Println("SetSubscribed error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		s.writeRoomDigestSettings(w, r, roomID, userID)
+
+	case sub == "preview" && r.Method == http.MethodGet:
+		if !s.requireRoomMember(w, roomID, userID) {
+			return
+		}
+		hours := 24
+		if v := r.URL.Query().Get("hours"); v != "" {
+			hours, err = strconv.Atoi(v)
+			if err != nil || hours < 1 || hours > 168 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "hours must be 1..168"})
+				return
+			}
+		}
+		st, err := s.digestRepo.GetSettings(r.Context(), roomID)
+		if err != nil {
+			log.Println("GetSettings error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		botID, err := s.botRepo.EnsureSystemBot(r.Context(), digestBotUsername, digestBotName)
+		if err != nil {
+			log.Println("EnsureSystemBot error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		now := s.now()
+		d, err := s.digestRepo.Build(r.Context(), roomID, now.Add(-time.Duration(hours)*time.Hour), now, botID)
+		if err != nil {
+			log.Println("digest Build error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"digest": d,
+			"text":   d.Text(st.Location()),
+		})
+
+	case sub != "" && sub != "subscription" && sub != "preview":
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func (s *Server) writeRoomDigestSettings(w http.ResponseWriter, r *http.Request, roomID, userID int64) {
+	st, err := s.digestRepo.GetSettings(r.Context(), roomID)
+	if err != nil {
+		log.Println("GetSettings error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	subscribed, err := s.digestRepo.IsSubscribed(r.Context(), roomID, userID)
+	if err != nil {
+		log.Println("IsSubscribed error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"settings":   st,
+		"subscribed": subscribed,
+	})
+}
@@ -0,0 +1,272 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/audit"
+	"cronhustler/api-service/internal/chat"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	adminEmojisPrefix = "/admin/emojis/"
+
+	// ảnh custom emoji hiển thị ~32px, không cần lớn
+	emojiImageMaxBytes = 512 << 10
+)
+
+func (s *Server) mountEmojiRoutes(mux *http.ServeMux) {
+	// GET /emojis -> catalog reaction đang bật (picker)
+	mux.Handle("/emojis", http.HandlerFunc(s.handleListEmojis))
+
+	// ===== admin =====
+	// GET /admin/emojis -> cả emoji đã tắt
+	// POST /admin/emojis: JSON {code, glyph?, label?, sort_order?} (unicode)
+	//                     multipart code=:ten: label= sort_order= image=<ảnh> (custom)
+	mux.Handle("/admin/emojis", s.RequireAdmin(http.HandlerFunc(s.handleAdminEmojis)))
+	// PATCH /admin/emojis/{code} {label?, glyph?, sort_order?, is_active?} | DELETE /admin/emojis/{code}
+	mux.Handle(adminEmojisPrefix, s.RequireAdmin(http.HandlerFunc(s.handleAdminEmojiByCode)))
+}
+
+// signEmoji: ảnh custom nằm trong chat_uploads -> ký như media khác
+func (s *Server) signEmoji(e *chat.Emoji) {
+	if e != nil && e.ImageURL != "" {
+		e.ImageURL = s.signMediaURL(e.ImageURL)
+	}
+}
+
+// signReactions: ký ảnh custom emoji trong summary trước khi trả FE / WS
+func (s *Server) signReactions(items []chat.ReactionSummaryItem) []chat.ReactionSummaryItem {
+	for i := range items {
+		s.signEmoji(items[i].Emoji)
+	}
+	return items
+}
+
+func (s *Server) handleListEmojis(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if _, err := UserIDFromContext(r.Context()); err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+	s.writeEmojiList(w, r, false)
+}
+
+func (s *Server) writeEmojiList(w http.ResponseWriter, r *http.Request, includeInactive bool) {
+	list, err := s.chatRepo.ListEmojis(r.Context(), includeInactive)
+	if err != nil {
+		log.Println("ListEmojis error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	for _, e := range list {
+		s.signEmoji(e)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"emojis": list})
+}
+
+type createEmojiRequest struct {
+	Code      string `json:"code"`
+	Glyph     string `json:"glyph"`
+	Label     string `json:"label"`
+	SortOrder int    `json:"sort_order"`
+}
+
+// GET | POST /admin/emojis
+func (s *Server) handleAdminEmojis(w http.ResponseWriter, r *http.Request) {
+	adminID, _ := UserIDFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		s.writeEmojiList(w, r, true)
+	case http.MethodPost:
+		s.createEmoji(w, r, adminID)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func (s *Server) createEmoji(w http.ResponseWriter, r *http.Request, adminID int64) {
+	e := &chat.Emoji{Kind: chat.EmojiUnicode, IsActive: true}
+	var uploaded string // file đã lưu, insert fail thì xoá
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(emojiImageMaxBytes + 64<<10); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "cannot parse form"})
+			return
+		}
+		defer func() { _ = r.MultipartForm.RemoveAll() }()
+
+		e.Kind = chat.EmojiCustom
+		e.Code = r.FormValue("code")
+		e.Label = r.FormValue("label")
+		if v := r.FormValue("sort_order"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid sort_order"})
+				return
+			}
+			e.SortOrder = n
+		}
+
+		// check code trước khi lưu file
+		e.Code = strings.TrimSpace(e.Code)
+		if !chat.IsCustomEmojiCode(e.Code) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "custom code must look like :name:"})
+			return
+		}
+
+		file, header, err := r.FormFile("image")
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "missing image"})
+			return
+		}
+		defer file.Close()
+
+		head := make([]byte, 512)
+		n, _ := io.ReadFull(file, head)
+		mime := http.DetectContentType(head[:n])
+		if !isAllowedImageMime(mime) {
+			writeChatUploadError(w, errUploadUnsupported)
+			return
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "file read error"})
+			return
+		}
+
+		// room 0: không thuộc room nào (như avatar, janitor check qua reaction_emojis)
+		up, err := s.storeChatUploadLimit(0, adminID, mime, mimeToExt(mime), header.Filename, file, emojiImageMaxBytes)
+		s.releaseChatUpload(up)
+		if err != nil {
+			if !errors.Is(err, errUploadTooLarge) {
+				log.Println("storeChatUpload (emoji) error:", err)
+			}
+			writeChatUploadError(w, err)
+			return
+		}
+		uploaded = up.Filename
+		e.ImageURL = up.MediaURL
+	} else {
+		var req createEmojiRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+			return
+		}
+		e.Code, e.Glyph, e.Label, e.SortOrder = req.Code, req.Glyph, req.Label, req.SortOrder
+		if e.Glyph == "" {
+			e.Glyph = strings.TrimSpace(e.Code)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := chat.ValidateEmoji(e); err != nil {
+		if uploaded != "" {
+			s.deleteChatUpload(ctx, uploaded)
+		}
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	if err := s.chatRepo.CreateEmoji(ctx, e, adminID); err != nil {
+		if uploaded != "" {
+			s.deleteChatUpload(ctx, uploaded)
+		}
+		if errors.Is(err, chat.ErrEmojiExists) {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		}
+		log.Println("CreateEmoji error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	s.recordAudit(r, adminID, audit.ActionEmojiCreate, audit.TargetEmoji, 0, map[string]any{"code": e.Code, "kind": e.Kind})
+
+	s.signEmoji(e)
+	writeJSON(w, http.StatusCreated, e)
+}
+
+type updateEmojiRequest struct {
+	Label     *string `json:"label"`
+	Glyph     *string `json:"glyph"`
+	SortOrder *int    `json:"sort_order"`
+	IsActive  *bool   `json:"is_active"`
+}
+
+// PATCH | DELETE /admin/emojis/{code}
+// tắt (is_active=false): không react mới được, reaction cũ vẫn hiện
+func (s *Server) handleAdminEmojiByCode(w http.ResponseWriter, r *http.Request) {
+	adminID, _ := UserIDFromContext(r.Context())
+
+	code := strings.TrimSpace(strings.TrimPrefix(r.URL.Path, adminEmojisPrefix))
+	if code == "" || strings.Contains(code, "/") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid emoji code"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodPatch:
+		var req updateEmojiRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+			return
+		}
+		e, err := s.chatRepo.UpdateEmoji(ctx, code, chat.EmojiUpdate{
+			Label:     req.Label,
+			Glyph:     req.Glyph,
+			SortOrder: req.SortOrder,
+			IsActive:  req.IsActive,
+		})
+		if err != nil {
+			if errors.Is(err, chat.ErrEmojiNotFound) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, chat.ErrInvalidEmoji) {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			log.Println("UpdateEmoji error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		s.recordAudit(r, adminID, audit.ActionEmojiUpdate, audit.TargetEmoji, 0, map[string]any{"code": code, "is_active": e.IsActive})
+		s.signEmoji(e)
+		writeJSON(w, http.StatusOK, e)
+
+	case http.MethodDelete:
+		imageURL, err := s.chatRepo.DeleteEmoji(ctx, code)
+		if err != nil {
+			if errors.Is(err, chat.ErrEmojiNotFound) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+				return
+			}
+			log.Println("DeleteEmoji error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		if name, ok := strings.CutPrefix(imageURL, chatUploadPrefix); ok {
+			s.deleteChatUpload(ctx, name)
+		}
+		s.recordAudit(r, adminID, audit.ActionEmojiDelete, audit.TargetEmoji, 0, map[string]any{"code": code})
+		writeJSON(w, http.StatusOK, map[string]any{"success": true, "code": code})
+
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
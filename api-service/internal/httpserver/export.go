@@ -0,0 +1,333 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/audit"
+	"cronhustler/api-service/internal/export"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const exportsPrefix = "/exports/"
+
+// link tải chỉ sống 1h (lấy link mới bằng GET /exports/{id})
+const exportURLTTL = time.Hour
+
+// export "running" mà heartbeat cũ hơn mức này -> instance đã chết, chạy lại
+const exportStaleAfter = 10 * time.Minute
+
+func (s *Server) mountExportRoutes(mux *http.ServeMux) {
+	// GET  /exports -> export của mình
+	// POST /exports -> yêu cầu export dữ liệu của mình
+	mux.Handle("/exports", http.HandlerFunc(s.handleMyExports))
+	// GET  /exports/{id}                          -> trạng thái + progress (+ download_url khi xong)
+	// GET  /exports/{id}/download?exp=&sig=       -> tải zip (link ký, không cần login)
+	mux.Handle(exportsPrefix, http.HandlerFunc(s.handleExportByID))
+
+	// GET  /admin/exports?user_id=&before_id=&limit=
+	// POST /admin/exports {user_id}
+	mux.Handle("/admin/exports", s.RequireAdmin(http.HandlerFunc(s.handleAdminExports)))
+}
+
+func exportSignature(secret []byte, id, exp int64) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "export\n%d\n%d", id, exp)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// withDownloadURL: gắn link tải (hết hạn sau exportURLTTL hoặc khi file hết hạn)
+func (s *Server) withDownloadURL(e *export.Export) *export.Export {
+	if e.Status != export.StatusDone || e.ExpiresAt == nil {
+		return e
+	}
+	exp := s.now().Add(exportURLTTL)
+	if e.ExpiresAt.Before(exp) {
+		exp = *e.ExpiresAt
+	}
+	q := url.Values{}
+	q.Set("exp", strconv.FormatInt(exp.Unix(), 10))
+	q.Set("sig", exportSignature(s.jwtSecret, e.ID, exp.Unix()))
+	e.DownloadURL = exportsPrefix + strconv.FormatInt(e.ID, 10) + "/download?" + q.Encode()
+	return e
+}
+
+func (s *Server) handleMyExports(w http.ResponseWriter, r *http.Request) {
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.listExports(w, r, userID)
+	case http.MethodPost:
+		s.requestExport(w, r, userID, userID)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func (s *Server) handleAdminExports(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		userID, _ := strconv.ParseInt(r.URL.Query().Get("user_id"), 10, 64)
+		s.listExports(w, r, userID)
+	case http.MethodPost:
+		adminID, _ := UserIDFromContext(r.Context())
+		var req struct {
+			UserID int64 `json:"user_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user_id is required"})
+			return
+		}
+		if _, err := s.userRepo.GetUserByID(int(req.UserID)); err != nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "user not found"})
+			return
+		}
+		s.requestExport(w, r, req.UserID, adminID)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func (s *Server) listExports(w http.ResponseWriter, r *http.Request, userID int64) {
+	beforeID, limit := parsePaging(r)
+	list, err := s.exportRepo.List(r.Context(), userID, beforeID, limit)
+	if err != nil {
+		log.Println("export List error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	for _, e := range list {
+		s.withDownloadURL(e)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"exports": list})
+}
+
+func (s *Server) requestExport(w http.ResponseWriter, r *http.Request, userID, requestedBy int64) {
+	if s.exports == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "data export is disabled"})
+		return
+	}
+	e, err := s.exportRepo.Create(r.Context(), userID, requestedBy)
+	if err != nil {
+		if errors.Is(err, export.ErrActive) {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		}
+		log.Println("export Create error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	if requestedBy != userID {
+		s.recordAudit(r, requestedBy, audit.ActionDataExport, audit.TargetUser, userID, map[string]any{"export_id": e.ID})
+	}
+	writeJSON(w, http.StatusAccepted, e)
+}
+
+func (s *Server) handleExportByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, exportsPrefix), "/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid export id"})
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	if len(parts) == 2 && parts[1] == "download" {
+		s.serveExportFile(w, r, id)
+		return
+	}
+	if len(parts) != 1 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+	e, err := s.exportRepo.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, export.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "export not found"})
+			return
+		}
+		log.Println("export Get error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	if e.UserID != userID && e.RequestedBy != userID {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "export not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.withDownloadURL(e))
+}
+
+func (s *Server) serveExportFile(w http.ResponseWriter, r *http.Request, id int64) {
+	q := r.URL.Query()
+	exp, err := strconv.ParseInt(q.Get("exp"), 10, 64)
+	if err != nil || s.now().Unix() > exp {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "link expired"})
+		return
+	}
+	if !hmac.Equal([]byte(q.Get("sig")), []byte(exportSignature(s.jwtSecret, id, exp))) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "invalid signature"})
+		return
+	}
+
+	e, err := s.exportRepo.Get(r.Context(), id)
+	if err != nil || e.Status != export.StatusDone || e.FileName == "" || s.exports == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "export not available"})
+		return
+	}
+
+	f, err := os.Open(filepath.Join(s.exports.Dir, filepath.Base(e.FileName)))
+	if err != nil {
+		log.Println("open export file error:", err)
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "export not available"})
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="cronchat-export-%d.zip"`, e.ID))
+	w.Header().Set("Cache-Control", "private, no-store")
+	var modTime time.Time
+	if e.FinishedAt != nil {
+		modTime = *e.FinishedAt
+	}
+	http.ServeContent(w, r, "", modTime, f)
+}
+
+// ===== Worker =====
+
+// StartExportWorker: xử lý hàng đợi export (DB), ttl = thời gian giữ file sau khi xong
+func (s *Server) StartExportWorker(ctx context.Context, dir string, ttl, interval time.Duration) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	s.exports = export.NewBuilder(s.exportRepo.DB, dir)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.runExportQueue(ctx, ttl)
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *Server) runExportQueue(ctx context.Context, ttl time.Duration) {
+	now := s.now()
+	if n, err := s.exportRepo.Requeue(ctx, now.Add(-exportStaleAfter)); err != nil {
+		log.Println("[export] Requeue error:", err)
+	} else if n > 0 {
+		log.Printf("[export] requeued %d stale exports", n)
+	}
+	s.expireExports(ctx, now)
+
+	// chạy lần lượt tới khi hết hàng đợi
+	for ctx.Err() == nil {
+		e, err := s.exportRepo.Claim(ctx)
+		if err != nil {
+			log.Println("[export] Claim error:", err)
+			return
+		}
+		if e == nil {
+			return
+		}
+		s.runExport(ctx, e, ttl)
+	}
+}
+
+func (s *Server) runExport(ctx context.Context, e *export.Export, ttl time.Duration) {
+	notify := []int64{e.RequestedBy}
+	if e.UserID != e.RequestedBy {
+		notify = append(notify, e.UserID)
+	}
+
+	last := 0
+	progress := func(p int) {
+		// ghi DB mỗi 5% (kèm heartbeat)
+		if p-last < 5 && p < 100 {
+			return
+		}
+		last = p
+		if err := s.exportRepo.SetProgress(ctx, e.ID, p); err != nil {
+			log.Printf("[export] SetProgress id=%d: %v", e.ID, err)
+		}
+		wsSendToUser(e.RequestedBy, wsEnvelope{Type: "export_progress", Data: map[string]any{"id": e.ID, "progress": p}})
+	}
+
+	name, size, err := s.exports.Build(ctx, e, progress)
+
+	saveCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err != nil {
+		log.Printf("[export] id=%d user=%d failed: %v", e.ID, e.UserID, err)
+		if err := s.exportRepo.Fail(saveCtx, e.ID, err.Error()); err != nil {
+			log.Printf("[export] Fail id=%d: %v", e.ID, err)
+		}
+		wsSendToUsers(notify, wsEnvelope{Type: "export_failed", Data: map[string]any{"id": e.ID}})
+		return
+	}
+
+	if err := s.exportRepo.Finish(saveCtx, e.ID, name, size, s.now().Add(ttl)); err != nil {
+		log.Printf("[export] Finish id=%d: %v", e.ID, err)
+		return
+	}
+	done, err := s.exportRepo.Get(saveCtx, e.ID)
+	if err != nil {
+		log.Printf("[export] Get id=%d: %v", e.ID, err)
+		return
+	}
+	wsSendToUsers(notify, wsEnvelope{Type: "export_ready", Data: s.withDownloadURL(done)})
+}
+
+// expireExports: xoá file đã hết hạn tải
+func (s *Server) expireExports(ctx context.Context, now time.Time) {
+	list, err := s.exportRepo.ListExpired(ctx, now, 100)
+	if err != nil {
+		log.Println("[export] ListExpired error:", err)
+		return
+	}
+	for _, e := range list {
+		if e.FileName != "" {
+			err := os.Remove(filepath.Join(s.exports.Dir, filepath.Base(e.FileName)))
+			if err != nil && !os.IsNotExist(err) {
+				log.Printf("[export] remove %s: %v", e.FileName, err)
+				continue
+			}
+		}
+		if err := s.exportRepo.MarkExpired(ctx, e.ID); err != nil {
+			log.Printf("[export] MarkExpired id=%d: %v", e.ID, err)
+		}
+	}
+}
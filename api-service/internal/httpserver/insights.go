@@ -0,0 +1,116 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/job"
+	"cronhustler/api-service/internal/room"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	roomInsightsPrefix = "/rooms/insights/"
+
+	// gom số liệu theo giờ cho room bật insights (nên chạy mỗi giờ, vd "5 * * * *")
+	jobActionInsightsRollup = "room_insights_rollup"
+)
+
+func (s *Server) mountInsightsRoutes(mux *http.ServeMux) {
+	// chỉ owner của room:
+	// GET /rooms/insights/{roomID}?days=30 -> {settings, insights}
+	// PUT /rooms/insights/{roomID}         {enabled} -> opt-in / opt-out (tắt = xoá số liệu)
+	mux.Handle(roomInsightsPrefix, http.HandlerFunc(s.handleRoomInsights))
+}
+
+func (s *Server) runInsightsRollupJob(ctx context.Context, _ *job.Job) (string, error) {
+	n, err := s.analyticsRepo.RollupRooms(ctx, s.now())
+	return fmt.Sprintf("rooms=%d", n), err
+}
+
+func (s *Server) handleRoomInsights(w http.ResponseWriter, r *http.Request) {
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+	roomID, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(r.URL.Path, roomInsightsPrefix), "/"), 10, 64)
+	if err != nil || roomID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid room id"})
+		return
+	}
+
+	role, err := s.roomRepo.GetMemberRole(r.Context(), roomID, userID)
+	if err != nil {
+		if errors.Is(err, room.ErrNotMember) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "you are not a member of this room"})
+			return
+		}
+		log.Println("GetMemberRole error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	if role != "owner" {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "only room owner can view insights"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		st, err := s.analyticsRepo.GetInsightsSettings(r.Context(), roomID)
+		if err != nil {
+			log.Println("GetInsightsSettings error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		if !st.Enabled {
+			writeJSON(w, http.StatusOK, map[string]any{"settings": st, "insights": nil})
+			return
+		}
+
+		days := 30
+		if v, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && v > 0 && v <= 90 {
+			days = v
+		}
+		now := s.now()
+		to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		from := to.AddDate(0, 0, -(days - 1))
+
+		ins, err := s.analyticsRepo.RoomInsights(r.Context(), roomID, from, to)
+		if err != nil {
+			log.Println("RoomInsights error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"settings": st, "insights": ins})
+
+	case http.MethodPut:
+		var req struct {
+			Enabled *bool `json:"enabled"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "body must be {enabled}"})
+			return
+		}
+		if err := s.analyticsRepo.SetInsightsEnabled(r.Context(), roomID, userID, *req.Enabled); err != nil {
+			log.Println("SetInsightsEnabled error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		st, err := s.analyticsRepo.GetInsightsSettings(r.Context(), roomID)
+		if err != nil {
+			log.Println("GetInsightsSettings error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"settings": st})
+
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
@@ -0,0 +1,265 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/room"
+	"cronhustler/api-service/internal/webhook"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const invitesPrefix = "/invites/"
+
+// mời tối đa bao nhiêu người 1 lần
+const maxInviteBatch = 100
+
+func (s *Server) mountInviteRoutes(mux *http.ServeMux) {
+	// GET /invites -> lời mời vào group đang chờ của mình
+	mux.Handle("/invites", http.HandlerFunc(s.handleMyInvites))
+	// POST /invites/{id}/accept | /invites/{id}/decline
+	mux.Handle(invitesPrefix, http.HandlerFunc(s.handleRespondInvite))
+}
+
+type inviteRequest struct {
+	UserIDs []int64 `json:"user_ids"`
+}
+
+type inviteResponse struct {
+	RoomID  int64          `json:"room_id"`
+	Invited []*room.Invite `json:"invited"`
+	Added   []int64        `json:"added"`   // bot: không trả lời invite được -> vào room luôn
+	Skipped []int64        `json:"skipped"` // đã ở trong room / không tồn tại / input lỗi
+}
+
+// errInviteForbidden: không phải group hoặc người mời không ở trong room
+var errInviteForbidden = errors.New("you are not a member of this group")
+
+// inviteUsers: tạo lời mời pending + bắn WS room_invite cho từng người được mời
+func (s *Server) inviteUsers(ctx context.Context, roomID, inviterID int64, userIDs []int64) (*inviteResponse, error) {
+	rm, err := s.roomRepo.GetRoomByIDLite(ctx, roomID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, room.ErrRoomNotFound
+		}
+		return nil, err
+	}
+	isMember, err := s.roomRepo.IsUserInRoom(roomID, inviterID)
+	if err != nil {
+		return nil, err
+	}
+	if rm.Type != "group" || !isMember {
+		return nil, errInviteForbidden
+	}
+
+	resp := &inviteResponse{RoomID: roomID, Invited: []*room.Invite{}, Added: []int64{}, Skipped: []int64{}}
+	seen := make(map[int64]bool, len(userIDs))
+
+	for _, uid := range userIDs {
+		if uid <= 0 || uid == inviterID || seen[uid] {
+			resp.Skipped = append(resp.Skipped, uid)
+			continue
+		}
+		seen[uid] = true
+
+		if in, err := s.roomRepo.IsUserInRoom(roomID, uid); err != nil || in {
+			if err != nil {
+				log.Println("IsUserInRoom (invitee) error:", err)
+			}
+			resp.Skipped = append(resp.Skipped, uid)
+			continue
+		}
+		if _, err := s.userRepo.GetUserBrief(ctx, uid); err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				log.Println("GetUserBrief error:", err)
+			}
+			resp.Skipped = append(resp.Skipped, uid)
+			continue
+		}
+
+		if _, err := s.botRepo.GetByUserID(ctx, uid); err == nil {
+			if err := s.roomRepo.AddMember(roomID, uid, "member"); err != nil {
+				log.Println("AddMember (bot) error:", err)
+				resp.Skipped = append(resp.Skipped, uid)
+				continue
+			}
+			resp.Added = append(resp.Added, uid)
+			continue
+		}
+
+		inv, err := s.roomRepo.CreateInvite(ctx, roomID, inviterID, uid)
+		if err != nil {
+			log.Println("CreateInvite error:", err)
+			resp.Skipped = append(resp.Skipped, uid)
+			continue
+		}
+		resp.Invited = append(resp.Invited, inv)
+	}
+
+	for _, inv := range resp.Invited {
+		wsSendToUser(inv.InviteeID, wsEnvelope{
+			Type:   "room_invite",
+			RoomID: roomID,
+			Data:   inv,
+		})
+	}
+	if len(resp.Added) > 0 {
+		payload := map[string]any{"user_ids": resp.Added, "added_by": inviterID}
+		if memberIDs, err := s.roomRepo.GetRoomMemberIDs(roomID); err == nil {
+			wsSendToUsers(memberIDs, wsEnvelope{
+				Type:   "room.member_added",
+				RoomID: roomID,
+				Data:   payload,
+			})
+		}
+		s.emitWebhook(roomID, inviterID, webhook.EventMemberAdded, payload)
+	}
+	return resp, nil
+}
+
+// POST /rooms/{roomID}/invite {user_ids}
+func (s *Server) handleRoomInvite(w http.ResponseWriter, r *http.Request, roomID int64) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var req inviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+	if len(req.UserIDs) == 0 || len(req.UserIDs) > maxInviteBatch {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user_ids must have 1-100 items"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := s.inviteUsers(ctx, roomID, userID, req.UserIDs)
+	if err != nil {
+		writeInviteError(w, "inviteUsers", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GET /invites
+func (s *Server) handleMyInvites(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	list, err := s.roomRepo.ListPendingInvites(r.Context(), userID)
+	if err != nil {
+		log.Println("ListPendingInvites error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"invites": list})
+}
+
+// POST /invites/{id}/accept | /invites/{id}/decline
+func (s *Server) handleRespondInvite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, invitesPrefix), "/"), "/")
+	if len(parts) != 2 || (parts[1] != "accept" && parts[1] != "decline") {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+		return
+	}
+	inviteID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || inviteID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid invite id"})
+		return
+	}
+	accept := parts[1] == "accept"
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	inv, err := s.roomRepo.RespondInvite(ctx, inviteID, userID, accept)
+	if err != nil {
+		writeInviteError(w, "RespondInvite", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, inv)
+
+	// người mời biết kết quả
+	wsSendToUser(inv.InviterID, wsEnvelope{
+		Type:   "room_invite_updated",
+		RoomID: inv.RoomID,
+		Data:   inv,
+	})
+	if !accept {
+		return
+	}
+
+	// giống add member cũ: cả room nhận member_added, người mới nhận room.joined
+	payload := map[string]any{
+		"user_ids":   []int64{userID},
+		"added_by":   inv.InviterID,
+		"invite_id":  inv.ID,
+		"via_invite": true,
+	}
+	if memberIDs, err := s.roomRepo.GetRoomMemberIDs(inv.RoomID); err == nil {
+		wsSendToUsers(memberIDs, wsEnvelope{
+			Type:   "room.member_added",
+			RoomID: inv.RoomID,
+			Data:   payload,
+		})
+	}
+	s.emitWebhook(inv.RoomID, userID, webhook.EventMemberAdded, payload)
+
+	if rm, err := s.roomRepo.GetRoomByID(inv.RoomID); err == nil && rm != nil {
+		wsSendToUser(userID, wsEnvelope{
+			Type:   "room.joined",
+			RoomID: inv.RoomID,
+			Data:   map[string]any{"room": rm},
+		})
+	}
+}
+
+func writeInviteError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, room.ErrRoomNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "room not found"})
+	case errors.Is(err, room.ErrInviteNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "invite not found"})
+	case errors.Is(err, room.ErrInviteNotPending):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, errInviteForbidden):
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+	default:
+		log.Println(op+" error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+	}
+}
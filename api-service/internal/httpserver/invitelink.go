@@ -0,0 +1,194 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/room"
+	"cronhustler/api-service/internal/webhook"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const roomJoinPrefix = "/rooms/join/"
+
+// giới hạn link join: tối đa 30 ngày, 1000 lượt
+const (
+	maxInviteLinkMinutes = 30 * 24 * 60
+	maxInviteLinkUses    = 1000
+)
+
+type createInviteLinkRequest struct {
+	ExpiresInMinutes int `json:"expires_in_minutes"` // 0 = không hết hạn
+	MaxUses          int `json:"max_uses"`           // 0 = không giới hạn
+}
+
+type inviteLinkResponse struct {
+	room.InviteLink
+	URL string `json:"url"`
+}
+
+// inviteLinkURL: APP_PUBLIC_URL + /rooms/join/{code} (không cấu hình thì lấy host của request)
+func inviteLinkURL(r *http.Request, code string) string {
+	base := strings.TrimRight(os.Getenv("APP_PUBLIC_URL"), "/")
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	return base + roomJoinPrefix + code
+}
+
+// GET|POST /rooms/{roomID}/invite-link, DELETE /rooms/{roomID}/invite-link/{code} (owner)
+func (s *Server) handleRoomInviteLink(w http.ResponseWriter, r *http.Request, roomID int64, code string) {
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	switch {
+	case r.Method == http.MethodGet && code == "":
+		if _, ok := s.roomPermission(w, ctx, roomID, userID, room.PermInviteLink); !ok {
+			return
+		}
+		links, err := s.roomRepo.ListInviteLinks(ctx, roomID)
+		if err != nil {
+			log.Println("ListInviteLinks error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		out := make([]inviteLinkResponse, 0, len(links))
+		for _, l := range links {
+			out = append(out, inviteLinkResponse{InviteLink: l, URL: inviteLinkURL(r, l.Code)})
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"room_id": roomID, "links": out})
+
+	case r.Method == http.MethodPost && code == "":
+		// body optional
+		var req createInviteLinkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+			return
+		}
+		if req.ExpiresInMinutes < 0 || req.ExpiresInMinutes > maxInviteLinkMinutes {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "expires_in_minutes out of range"})
+			return
+		}
+		if req.MaxUses < 0 || req.MaxUses > maxInviteLinkUses {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "max_uses out of range"})
+			return
+		}
+
+		if _, ok := s.roomPermission(w, ctx, roomID, userID, room.PermInviteLink); !ok {
+			return
+		}
+
+		expiresIn := time.Duration(req.ExpiresInMinutes) * time.Minute
+		link, err := s.roomRepo.CreateInviteLink(ctx, roomID, userID, expiresIn, req.MaxUses)
+		if err != nil {
+			log.Println("CreateInviteLink error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusCreated, inviteLinkResponse{InviteLink: *link, URL: inviteLinkURL(r, link.Code)})
+
+	case r.Method == http.MethodDelete && code != "":
+		if _, ok := s.roomPermission(w, ctx, roomID, userID, room.PermInviteLink); !ok {
+			return
+		}
+		found, err := s.roomRepo.RevokeInviteLink(ctx, roomID, code)
+		if err != nil {
+			log.Println("RevokeInviteLink error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		if !found {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "invite link not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"room_id": roomID, "code": code, "revoked": true})
+
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+// POST /rooms/join/{code} -> tự join group qua link
+func (s *Server) handleJoinRoomByCode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	code := strings.Trim(strings.TrimPrefix(r.URL.Path, roomJoinPrefix), "/")
+	if code == "" || strings.Contains(code, "/") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid code"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	roomID, joined, err := s.roomRepo.JoinByInviteCode(ctx, code, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, room.ErrInviteLinkNotFound):
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "invite link not found"})
+		case errors.Is(err, room.ErrInviteLinkExpired):
+			writeJSON(w, http.StatusGone, map[string]string{"error": err.Error()})
+		default:
+			log.Println("JoinByInviteCode error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		}
+		return
+	}
+
+	rm, err := s.roomRepo.GetRoomByID(roomID)
+	if err != nil {
+		log.Println("GetRoomByID error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"room":           rm,
+		"already_member": !joined,
+	})
+	if !joined {
+		return
+	}
+
+	payload := map[string]any{
+		"user_ids": []int64{userID},
+		"via_link": true,
+	}
+	if memberIDs, err := s.roomRepo.GetRoomMemberIDs(roomID); err == nil {
+		wsSendToUsers(memberIDs, wsEnvelope{
+			Type:   "room.member_added",
+			RoomID: roomID,
+			Data:   payload,
+		})
+	}
+	s.emitWebhook(roomID, userID, webhook.EventMemberAdded, payload)
+
+	wsSendToUser(userID, wsEnvelope{
+		Type:   "room.joined",
+		RoomID: roomID,
+		Data:   map[string]any{"room": rm},
+	})
+}
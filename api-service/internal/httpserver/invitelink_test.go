@@ -0,0 +1,58 @@
+package httpserver
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// hạn link tính bằng NOW() của DB, response trả về giá trị DB đã lưu
+func TestCreateInviteLinkExpiresInDB(t *testing.T) {
+	s, mock := newTestServer(t)
+	mock.ExpectQuery(`SELECT member_role FROM room_members WHERE room_id = \? AND user_id = \?`).
+		WithArgs(int64(5), int64(1)).WillReturnRows(sqlmock.NewRows([]string{"member_role"}).AddRow("owner"))
+	mock.ExpectQuery(`SELECT id, name, type, updated_at\s+FROM rooms`).WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "type", "updated_at"}).AddRow(5, "team", "group", testNow))
+	mock.ExpectExec(`VALUES \(\?, \?, \?, NOW\(\) \+ INTERVAL \? SECOND, \?\)`).
+		WithArgs(int64(5), sqlmock.AnyArg(), int64(1), int64(3600), 10).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	dbExpires := time.Date(2025, 3, 10, 3, 0, 0, 0, time.UTC) // đồng hồ DB khác app
+	mock.ExpectQuery(`SELECT expires_at, created_at FROM room_invite_links WHERE id = \?`).WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"expires_at", "created_at"}).AddRow(dbExpires, dbExpires.Add(-time.Hour)))
+
+	rec := serve(s, http.MethodPost, "/rooms/5/invite-link", accessTokenFor(t, 1), `{"expires_in_minutes":60,"max_uses":10}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201 (body %s)", rec.Code, rec.Body.String())
+	}
+	checkExpectations(t, mock)
+
+	body := decodeBody(t, rec)
+	if body["expires_at"] != dbExpires.Format(time.RFC3339) {
+		t.Fatalf("expires_at = %v, want %s", body["expires_at"], dbExpires.Format(time.RFC3339))
+	}
+	code, _ := body["code"].(string)
+	if len(code) != 10 || strings.Trim(code, "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789") != "" {
+		t.Fatalf("code = %q", code)
+	}
+}
+
+func TestJoinByInviteCodeExpired(t *testing.T) {
+	s, mock := newTestServer(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`l\.expires_at IS NOT NULL AND l\.expires_at <= NOW\(\)`).WithArgs("abc").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "room_id", "expired", "max_uses", "uses", "revoked", "type", "is_active"}).
+			AddRow(7, 5, true, 0, 0, false, "group", 1))
+	mock.ExpectQuery(`SELECT 1 FROM room_members WHERE room_id = \? AND user_id = \?`).WithArgs(int64(5), int64(1)).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	rec := serve(s, http.MethodPost, "/rooms/join/abc", accessTokenFor(t, 1), "")
+	if rec.Code != http.StatusGone {
+		t.Fatalf("status = %d, want 410 (body %s)", rec.Code, rec.Body.String())
+	}
+	checkExpectations(t, mock)
+}
@@ -0,0 +1,241 @@
+package httpserver
+
+import (
+	"cronhustler/api-service/internal/audit"
+	"cronhustler/api-service/internal/iprule"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const ipRulesPrefix = "/admin/ip-rules/"
+
+// cùng rule + IP chỉ ghi audit 1 lần / phút (tránh spam khi bị dội request)
+const ipBlockAuditEvery = time.Minute
+
+// path login / đăng ký (rule scope auth)
+var ipAuthPaths = map[string]bool{
+	"/login":            true,
+	"/login/otp":        true,
+	"/login/otp/resend": true,
+	"/create-user":      true,
+}
+
+type ipBlockLog struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+func (l *ipBlockLog) shouldRecord(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.seen == nil {
+		l.seen = map[string]time.Time{}
+	}
+	if t, ok := l.seen[key]; ok && now.Sub(t) < ipBlockAuditEvery {
+		return false
+	}
+	// dọn key cũ cho map khỏi phình
+	if len(l.seen) > 10000 {
+		for k, t := range l.seen {
+			if now.Sub(t) >= ipBlockAuditEvery {
+				delete(l.seen, k)
+			}
+		}
+	}
+	l.seen[key] = now
+	return true
+}
+
+func (s *Server) mountIPRuleRoutes(mux *http.ServeMux) {
+	// GET  /admin/ip-rules -> danh sách rule
+	// POST /admin/ip-rules {cidr, action: allow|deny, scope: auth|all, note, expires_at, force}
+	mux.Handle("/admin/ip-rules", s.RequireAdmin(http.HandlerFunc(s.handleAdminIPRules)))
+	// DELETE /admin/ip-rules/{id}
+	// GET    /admin/ip-rules/check?ip=&scope=auth|all -> thử rule với 1 IP
+	mux.Handle(ipRulesPrefix, s.RequireAdmin(http.HandlerFunc(s.handleAdminIPRule)))
+}
+
+// IPFilter: chặn request theo ip_rules (IP lấy qua getIP: X-Real-IP / X-Forwarded-For)
+func (s *Server) IPFilter(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := getIP(r)
+		auth := ipAuthPaths[r.URL.Path]
+		d, err := s.ipRuleRepo.Match(r.Context(), ip, auth, s.now())
+		if err != nil {
+			// lỗi DB không chặn traffic
+			log.Println("iprule Match error:", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !d.Blocked {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if s.ipBlocks.shouldRecord(strconv.FormatInt(d.Rule.ID, 10)+"|"+ip, s.now()) {
+			s.recordAudit(r, 0, audit.ActionIPBlocked, audit.TargetIPRule, d.Rule.ID, map[string]any{
+				"cidr":   d.Rule.CIDR,
+				"scope":  d.Rule.Scope,
+				"method": r.Method,
+				"path":   r.URL.Path,
+			})
+		}
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "access denied from your network"})
+	})
+}
+
+type ipRuleRequest struct {
+	CIDR      string     `json:"cidr"`
+	Action    string     `json:"action"`
+	Scope     string     `json:"scope"`
+	Note      string     `json:"note"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Force     bool       `json:"force"` // cho phép rule chặn cả IP của chính admin
+}
+
+func (s *Server) handleAdminIPRules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		list, err := s.ipRuleRepo.List(r.Context())
+		if err != nil {
+			log.Println("iprule List error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"rules": list})
+	case http.MethodPost:
+		s.createIPRule(w, r)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func (s *Server) createIPRule(w http.ResponseWriter, r *http.Request) {
+	adminID, _ := UserIDFromContext(r.Context())
+
+	var req ipRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+	if req.Scope == "" {
+		req.Scope = iprule.ScopeAuth
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(s.now()) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "expires_at must be in the future"})
+		return
+	}
+
+	ru := &iprule.Rule{
+		CIDR:      req.CIDR,
+		Action:    strings.ToLower(strings.TrimSpace(req.Action)),
+		Scope:     strings.ToLower(strings.TrimSpace(req.Scope)),
+		Note:      truncateRunes(strings.TrimSpace(req.Note), 255),
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: adminID,
+	}
+
+	// deny toàn bộ traffic trúng IP admin đang dùng -> tự khoá mình
+	if ru.Action == iprule.ActionDeny && ru.Scope == iprule.ScopeAll && !req.Force {
+		if n, err := iprule.ParseCIDR(ru.CIDR); err == nil && n.Contains(net.ParseIP(getIP(r))) {
+			writeJSON(w, http.StatusConflict, map[string]string{
+				"error": "rule would block your own IP; send force=true to create it anyway",
+			})
+			return
+		}
+	}
+
+	if err := s.ipRuleRepo.Create(r.Context(), ru); err != nil {
+		if errors.Is(err, iprule.ErrInvalidCIDR) || errors.Is(err, iprule.ErrInvalidRule) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		log.Println("iprule Create error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	s.recordAudit(r, adminID, audit.ActionConfigChange, audit.TargetIPRule, ru.ID, map[string]any{
+		"kind":   "ip_rule",
+		"op":     "create",
+		"cidr":   ru.CIDR,
+		"action": ru.Action,
+		"scope":  ru.Scope,
+	})
+	writeJSON(w, http.StatusCreated, ru)
+}
+
+func (s *Server) handleAdminIPRule(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, ipRulesPrefix), "/")
+
+	if rest == "check" {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		s.checkIPRule(w, r)
+		return
+	}
+
+	id, err := strconv.ParseInt(rest, 10, 64)
+	if err != nil || id <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid id"})
+		return
+	}
+	if r.Method != http.MethodDelete {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	ctx := r.Context()
+	ru, err := s.ipRuleRepo.Get(ctx, id)
+	if err == nil {
+		err = s.ipRuleRepo.Delete(ctx, id)
+	}
+	if err != nil {
+		if errors.Is(err, iprule.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "rule not found"})
+			return
+		}
+		log.Println("iprule Delete error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	adminID, _ := UserIDFromContext(r.Context())
+	s.recordAudit(r, adminID, audit.ActionConfigChange, audit.TargetIPRule, id, map[string]any{
+		"kind":   "ip_rule",
+		"op":     "delete",
+		"cidr":   ru.CIDR,
+		"action": ru.Action,
+		"scope":  ru.Scope,
+	})
+	writeJSON(w, http.StatusOK, map[string]any{"deleted": id})
+}
+
+func (s *Server) checkIPRule(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	ip := strings.TrimSpace(q.Get("ip"))
+	if ip == "" {
+		ip = getIP(r)
+	}
+	if net.ParseIP(ip) == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid ip"})
+		return
+	}
+
+	// đọc thẳng DB (không qua cache) để thấy rule vừa sửa
+	rules, err := s.ipRuleRepo.List(r.Context())
+	if err != nil {
+		log.Println("iprule List error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	d := iprule.Evaluate(rules, ip, q.Get("scope") == iprule.ScopeAuth, s.now())
+	writeJSON(w, http.StatusOK, map[string]any{"ip": ip, "blocked": d.Blocked, "rule": d.Rule})
+}
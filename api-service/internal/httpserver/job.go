@@ -0,0 +1,490 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"cronhustler/api-service/internal/audit"
+	"cronhustler/api-service/internal/job"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// action_type có sẵn
+const (
+	jobActionHTTPRequest  = "http_request"
+	jobActionMediaCleanup = "media_cleanup"
+	jobActionRoomPost     = "room_post"
+	jobActionRetention    = "retention_purge"
+)
+
+// http_request: tối đa cho cả request (kể cả đọc body)
+const jobHTTPTimeout = 30 * time.Second
+
+// user của bot đăng tin tự động (tạo lần đầu job room_post chạy)
+const (
+	scheduleBotUsername = "schedule_bot"
+	scheduleBotName     = "Scheduled Post"
+)
+
+// Cron job hệ thống, chỉ admin
+func (s *Server) mountJobRoutes(mux *http.ServeMux) {
+	// GET /jobs | POST /jobs {name, cron, action_type, payload, enabled}
+	// service token scope jobs / admin cũng gọi được
+	mux.Handle("/jobs", s.RequireAdminOrService(ScopeJobs, http.HandlerFunc(s.handleJobs)))
+	// GET|PUT|DELETE /jobs/{id} | POST /jobs/{id}/run | GET /jobs/{id}/runs
+	mux.Handle("/jobs/", s.RequireAdminOrService(ScopeJobs, http.HandlerFunc(s.handleJobByID)))
+}
+
+// StartJobRunner: poll job tới hạn (lock trong DB nên chạy nhiều instance được)
+func (s *Server) StartJobRunner(ctx context.Context, interval time.Duration) {
+	s.jobs.Start(ctx, interval)
+}
+
+func (s *Server) registerJobActions() {
+	// gọi 1 URL ngoài (health check, trigger báo cáo, ...)
+	s.jobs.Register(jobActionHTTPRequest, job.Action{
+		Run:      s.runHTTPRequestJob,
+		Validate: func(p json.RawMessage) error { _, err := parseHTTPRequestPayload(p); return err },
+	})
+	// dọn file upload mồ côi (giống POST /admin/media/orphans)
+	s.jobs.Register(jobActionMediaCleanup, job.Action{
+		Run: func(ctx context.Context, _ *job.Job) (string, error) {
+			rep, err := s.janitor.Cleanup(ctx, false)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("scanned=%d deleted=%d bytes_freed=%d", rep.Scanned, rep.Deleted, rep.BytesFreed), nil
+		},
+	})
+	// đăng tin theo template vào room (nhắc standup, báo cáo tuần, ...)
+	s.jobs.Register(jobActionRoomPost, job.Action{
+		Run:      s.runRoomPostJob,
+		Validate: func(p json.RawMessage) error { _, err := s.parseRoomPostPayload(p); return err },
+	})
+	// xoá dữ liệu quá hạn theo /admin/retention, output = số đã xoá mỗi loại
+	s.jobs.Register(jobActionRetention, job.Action{
+		Run: func(ctx context.Context, _ *job.Job) (string, error) {
+			rep, err := s.retention.Run(ctx, s.now(), false)
+			if err != nil {
+				return "", err
+			}
+			b, _ := json.Marshal(rep)
+			return string(b), nil
+		},
+	})
+	// tổng kết ngày cho các room bật digest (/rooms/digest/{id}): tin hệ thống + email
+	s.jobs.Register(jobActionRoomDigest, job.Action{
+		Run:      s.runRoomDigestJob,
+		Validate: func(p json.RawMessage) error { _, err := parseRoomDigestPayload(p); return err },
+	})
+	// gom số liệu insights theo giờ (chỉ room đã opt-in ở /rooms/insights/{id})
+	s.jobs.Register(jobActionInsightsRollup, job.Action{Run: s.runInsightsRollupJob})
+	// bot gửi survey (/admin/surveys) vào room / DM, survey có cron thì tự tạo job này
+	s.jobs.Register(jobActionSurveyPost, job.Action{
+		Run: s.runSurveyPostJob,
+		Validate: func(p json.RawMessage) error {
+			_, err := s.parseSurveyPostPayload(context.Background(), p)
+			return err
+		},
+	})
+	// báo cáo admin (user_growth, message_volume, storage) dạng CSV / PDF gửi email
+	s.jobs.Register(jobActionAdminReport, job.Action{
+		Run:      s.runAdminReportJob,
+		Validate: func(p json.RawMessage) error { _, err := parseAdminReportPayload(p); return err },
+	})
+}
+
+type roomPostPayload struct {
+	RoomID   int64    `json:"room_id"`
+	Template string   `json:"template"` // "Standup {{weekday_vn}} {{date_vn}} {{mentions}}"
+	Mentions []string `json:"mentions"` // username, render ra "@alice @bob"
+	Timezone string   `json:"timezone"` // IANA, mặc định giờ server
+
+	loc *time.Location
+}
+
+func (s *Server) parseRoomPostPayload(raw json.RawMessage) (*roomPostPayload, error) {
+	var p roomPostPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, errors.New("payload must be {room_id, template, mentions, timezone}")
+	}
+	if p.RoomID <= 0 {
+		return nil, errors.New("payload.room_id is required")
+	}
+	p.Template = strings.TrimSpace(p.Template)
+	if p.Template == "" || len([]rune(p.Template)) > 4000 {
+		return nil, errors.New("payload.template is required (max 4000 chars)")
+	}
+	for i, m := range p.Mentions {
+		m = strings.TrimPrefix(strings.TrimSpace(m), "@")
+		if m == "" {
+			return nil, errors.New("payload.mentions must be usernames")
+		}
+		p.Mentions[i] = m
+	}
+
+	p.loc = time.Local
+	if p.Timezone != "" {
+		loc, err := time.LoadLocation(p.Timezone)
+		if err != nil {
+			return nil, errors.New("payload.timezone is not a valid IANA zone")
+		}
+		p.loc = loc
+	}
+
+	if _, err := s.roomRepo.GetRoomByID(p.RoomID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("payload.room_id: room not found")
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *Server) runRoomPostJob(ctx context.Context, j *job.Job) (string, error) {
+	p, err := s.parseRoomPostPayload(j.Payload)
+	if err != nil {
+		return "", err
+	}
+	rm, err := s.roomRepo.GetRoomByID(p.RoomID)
+	if err != nil {
+		return "", err
+	}
+	if rm.IsActive != 1 {
+		return "", errors.New("room is not active")
+	}
+
+	mentions := make([]string, 0, len(p.Mentions))
+	for _, m := range p.Mentions {
+		mentions = append(mentions, "@"+m)
+	}
+	mentionText := strings.Join(mentions, " ")
+
+	content := job.Render(p.Template, s.now().In(p.loc), map[string]string{
+		"room":     rm.Name,
+		"mentions": mentionText,
+	})
+	// template không có {{mentions}} thì vẫn tag ở đầu tin
+	if mentionText != "" && !strings.Contains(p.Template, "{{mentions}}") {
+		content = mentionText + " " + content
+	}
+
+	botID, err := s.botRepo.EnsureSystemBot(ctx, scheduleBotUsername, scheduleBotName)
+	if err != nil {
+		return "", err
+	}
+	msgID, err := s.postBotMessage(ctx, botID, p.RoomID, content)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("message_id=%d room_id=%d", msgID, p.RoomID), nil
+}
+
+type httpRequestPayload struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method"` // mặc định GET
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+	// gắn "Authorization: Bearer <service token>" của job runner (scopes, rỗng = không gắn),
+	// chỉ khi URL thuộc JOB_SERVICE_TOKEN_ORIGINS
+	ServiceScopes []string `json:"service_scopes"`
+}
+
+func parseHTTPRequestPayload(raw json.RawMessage) (*httpRequestPayload, error) {
+	var p httpRequestPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, errors.New("payload must be {url, method, headers, body}")
+	}
+	u, err := url.Parse(strings.TrimSpace(p.URL))
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, errors.New("payload.url must be absolute http(s)")
+	}
+	p.URL = u.String()
+	p.Method = strings.ToUpper(strings.TrimSpace(p.Method))
+	switch p.Method {
+	case "":
+		p.Method = http.MethodGet
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete:
+	default:
+		return nil, errors.New("payload.method must be GET, POST, PUT or DELETE")
+	}
+	for _, sc := range p.ServiceScopes {
+		if !isValidServiceScope(sc) {
+			return nil, errors.New("payload.service_scopes must be in: " + strings.Join(ServiceScopes, ", "))
+		}
+	}
+	return &p, nil
+}
+
+// checkJobScopes: job http_request chỉ được mang scope người tạo đang có
+// (user admin -> mọi scope, service token -> scope của nó, scope admin = mọi scope)
+func checkJobScopes(ctx context.Context, j *job.Job) error {
+	if j.ActionType != jobActionHTTPRequest {
+		return nil
+	}
+	p, err := parseHTTPRequestPayload(j.Payload)
+	if err != nil || len(p.ServiceScopes) == 0 {
+		return err
+	}
+	svc := serviceFromContext(ctx)
+	if svc == nil {
+		if claims := ClaimsFromContext(ctx); claims != nil && claims.Role == "admin" {
+			return nil
+		}
+		return errors.New("only admins can attach service scopes to a job")
+	}
+	for _, sc := range p.ServiceScopes {
+		if !svc.HasScope(sc) && !svc.HasScope(ScopeAdmin) {
+			return fmt.Errorf("payload.service_scopes: caller does not hold scope %q", sc)
+		}
+	}
+	return nil
+}
+
+func (s *Server) runHTTPRequestJob(ctx context.Context, j *job.Job) (string, error) {
+	p, err := parseHTTPRequestPayload(j.Payload)
+	if err != nil {
+		return "", err
+	}
+
+	var body io.Reader
+	if p.Body != "" {
+		body = strings.NewReader(p.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, p.Method, p.URL, body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "CronChat-Job/1")
+	for k, v := range p.Headers {
+		req.Header.Set(k, v)
+	}
+	// token chỉ dùng được cho API của mình -> URL khác (bên thứ ba) gọi không kèm credentials
+	if len(p.ServiceScopes) > 0 && s.isJobTokenOrigin(req.URL) {
+		token, _, err := GenerateServiceToken(jobRunnerService, p.ServiceScopes, jobServiceTokenTTL, s.serviceSecret)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	// URL do người tạo job chọn -> không cho gọi vào mạng nội bộ (metadata, service nội bộ...)
+	resp, err := s.jobHTTP.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var buf bytes.Buffer
+	_, _ = io.Copy(&buf, io.LimitReader(resp.Body, 2048))
+	out := fmt.Sprintf("HTTP %d\n%s", resp.StatusCode, buf.String())
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return out, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return out, nil
+}
+
+// isJobTokenOrigin: scheme + host (kèm port) của URL nằm trong JOB_SERVICE_TOKEN_ORIGINS
+func (s *Server) isJobTokenOrigin(u *url.URL) bool {
+	origin := u.Scheme + "://" + u.Host
+	for _, o := range s.jobTokenOrigins {
+		if strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+type jobRequest struct {
+	Name       string          `json:"name"`
+	CronSpec   string          `json:"cron"`
+	ActionType string          `json:"action_type"`
+	Payload    json.RawMessage `json:"payload"`
+	Enabled    *bool           `json:"enabled"` // mặc định true
+}
+
+// toJob: validate body, tính next_run_at
+func (s *Server) toJob(req *jobRequest, j *job.Job) error {
+	j.Name = strings.TrimSpace(req.Name)
+	if j.Name == "" || len([]rune(j.Name)) > 100 {
+		return errors.New("name is required (max 100 chars)")
+	}
+	j.CronSpec = strings.TrimSpace(req.CronSpec)
+	j.ActionType = strings.TrimSpace(req.ActionType)
+	j.Payload = req.Payload
+	if len(j.Payload) == 0 || string(j.Payload) == "null" {
+		j.Payload = json.RawMessage("{}")
+	}
+	if !json.Valid(j.Payload) {
+		return errors.New("payload must be valid json")
+	}
+	j.Enabled = req.Enabled == nil || *req.Enabled
+
+	next, err := s.jobs.Validate(j, s.now())
+	if err != nil {
+		if errors.Is(err, job.ErrUnknownAction) {
+			return fmt.Errorf("action_type must be one of: %s", strings.Join(s.jobs.Actions(), ", "))
+		}
+		return err
+	}
+	j.NextRunAt = &next
+	return nil
+}
+
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		list, err := s.jobRepo.List(r.Context())
+		if err != nil {
+			log.Println("List jobs error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"jobs":          list,
+			"actions":       s.jobs.Actions(),
+			"template_vars": job.TemplateVars,
+		})
+
+	case http.MethodPost:
+		// service token -> created_by = 0
+		userID, err := UserIDFromContext(r.Context())
+		if err != nil && serviceFromContext(r.Context()) == nil {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
+		}
+
+		var req jobRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+			return
+		}
+		j := &job.Job{CreatedBy: userID}
+		if err := s.toJob(&req, j); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if err := checkJobScopes(r.Context(), j); err != nil {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+			return
+		}
+		if err := s.jobRepo.Create(r.Context(), j); err != nil {
+			log.Println("Create job error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		s.recordAudit(r, userID, audit.ActionConfigChange, audit.TargetConfig, j.ID, map[string]any{"kind": "job", "op": "create", "name": j.Name})
+		writeJSON(w, http.StatusCreated, j)
+
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func (s *Server) handleJobByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid job id"})
+		return
+	}
+
+	ctx := r.Context()
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		j, err := s.jobRepo.Get(ctx, id)
+		if err != nil {
+			writeJobError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, j)
+
+	case len(parts) == 1 && r.Method == http.MethodPut:
+		var req jobRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+			return
+		}
+		j, err := s.jobRepo.Get(ctx, id)
+		if err != nil {
+			writeJobError(w, err)
+			return
+		}
+		if err := s.toJob(&req, j); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if err := checkJobScopes(r.Context(), j); err != nil {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+			return
+		}
+		if err := s.jobRepo.Update(ctx, j); err != nil {
+			writeJobError(w, err)
+			return
+		}
+		adminID, _ := UserIDFromContext(r.Context())
+		s.recordAudit(r, adminID, audit.ActionConfigChange, audit.TargetConfig, j.ID, map[string]any{"kind": "job", "op": "update", "name": j.Name})
+		writeJSON(w, http.StatusOK, j)
+
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		if err := s.jobRepo.Delete(ctx, id); err != nil {
+			writeJobError(w, err)
+			return
+		}
+		adminID, _ := UserIDFromContext(r.Context())
+		s.recordAudit(r, adminID, audit.ActionConfigChange, audit.TargetConfig, id, map[string]any{"kind": "job", "op": "delete"})
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+
+	case len(parts) == 2 && parts[1] == "run" && r.Method == http.MethodPost:
+		runID, err := s.jobs.RunNow(ctx, id)
+		if err != nil {
+			if errors.Is(err, job.ErrLocked) {
+				writeJSON(w, http.StatusConflict, map[string]string{"error": "job is already running"})
+				return
+			}
+			writeJobError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]any{"run_id": runID})
+
+	case len(parts) == 2 && parts[1] == "runs" && r.Method == http.MethodGet:
+		if _, err := s.jobRepo.Get(ctx, id); err != nil {
+			writeJobError(w, err)
+			return
+		}
+		limit := 50
+		if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 && v <= 500 {
+			limit = v
+		}
+		runs, err := s.jobRepo.ListRuns(ctx, id, limit)
+		if err != nil {
+			writeJobError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"runs": runs})
+
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
+
+func writeJobError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, job.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not found"})
+	case errors.Is(err, job.ErrUnknownAction):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	default:
+		log.Println("job error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+	}
+}
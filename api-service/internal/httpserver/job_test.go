@@ -0,0 +1,163 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/job"
+	"cronhustler/api-service/internal/linkpreview"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCreateJobServiceScopes(t *testing.T) {
+	const serviceSecret = "service-secret-for-tests-0123456789"
+
+	serviceToken := func(t *testing.T, scopes ...string) string {
+		t.Helper()
+		tok, _, err := GenerateServiceToken("deploy-bot", scopes, jobServiceTokenTTL, []byte(serviceSecret))
+		if err != nil {
+			t.Fatalf("GenerateServiceToken: %v", err)
+		}
+		return tok
+	}
+	adminToken := func(t *testing.T) string {
+		t.Helper()
+		tok, err := GenerateAccessToken(1, "root", "admin", testSecret)
+		if err != nil {
+			t.Fatalf("GenerateAccessToken: %v", err)
+		}
+		return tok
+	}
+	expectInsert := func(mock sqlmock.Sqlmock) {
+		mock.ExpectExec(`INSERT INTO jobs`).WillReturnResult(sqlmock.NewResult(3, 1))
+	}
+	body := func(scopes string) string {
+		return `{"name":"ping","cron":"*/5 * * * *","action_type":"http_request",` +
+			`"payload":{"url":"https://example.com/hook","service_scopes":` + scopes + `}}`
+	}
+
+	tests := []struct {
+		name       string
+		token      func(t *testing.T) string
+		body       string
+		setup      func(mock sqlmock.Sqlmock)
+		wantStatus int
+	}{
+		{
+			// regression: token chỉ có scope jobs tạo job mang token admin
+			name:       "jobs token cannot grant admin",
+			token:      func(t *testing.T) string { return serviceToken(t, ScopeJobs) },
+			body:       body(`["admin"]`),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "jobs token cannot grant admin alongside jobs",
+			token:      func(t *testing.T) string { return serviceToken(t, ScopeJobs) },
+			body:       body(`["jobs","admin"]`),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "jobs token grants jobs",
+			token:      func(t *testing.T) string { return serviceToken(t, ScopeJobs) },
+			body:       body(`["jobs"]`),
+			setup:      expectInsert,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "admin service token grants admin",
+			token:      func(t *testing.T) string { return serviceToken(t, ScopeAdmin) },
+			body:       body(`["admin"]`),
+			setup:      expectInsert,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "admin user grants admin",
+			token:      adminToken,
+			body:       body(`["admin"]`),
+			setup:      expectInsert,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "unknown scope",
+			token:      func(t *testing.T) string { return serviceToken(t, ScopeAdmin) },
+			body:       body(`["root"]`),
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, mock := newTestServer(t)
+			s.serviceSecret = []byte(serviceSecret)
+			if tc.setup != nil {
+				tc.setup(mock)
+			}
+
+			rec := serve(s, http.MethodPost, "/jobs", tc.token(t), tc.body)
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tc.wantStatus, rec.Body.String())
+			}
+			checkExpectations(t, mock)
+		})
+	}
+}
+
+func TestHTTPRequestJobBlocksInternalAddress(t *testing.T) {
+	hit := false
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+		_, _ = w.Write([]byte("secret"))
+	}))
+	defer internal.Close()
+
+	s, _ := newTestServer(t)
+	payload, _ := json.Marshal(map[string]string{"url": internal.URL + "/latest/meta-data"})
+	out, err := s.runHTTPRequestJob(context.Background(), &job.Job{ActionType: jobActionHTTPRequest, Payload: payload})
+	if !errors.Is(err, linkpreview.ErrBlockedAddress) {
+		t.Fatalf("err = %v, want ErrBlockedAddress", err)
+	}
+	if hit || out != "" {
+		t.Fatalf("internal server was reached (output %q)", out)
+	}
+}
+
+// service token chỉ gửi tới origin trong JOB_SERVICE_TOKEN_ORIGINS, URL bên thứ ba nhận request không credentials
+func TestHTTPRequestJobServiceTokenOrigins(t *testing.T) {
+	var gotAuth string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+	}))
+	defer target.Close()
+
+	tests := []struct {
+		name     string
+		origins  []string
+		wantAuth bool
+	}{
+		{name: "no allowlist"},
+		{name: "other origin", origins: []string{"https://api.example.com"}},
+		{name: "api origin", origins: []string{"https://api.example.com", target.URL}, wantAuth: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t)
+			s.serviceSecret = []byte("service-secret-for-tests-0123456789")
+			s.jobTokenOrigins = tt.origins
+			s.jobHTTP = target.Client() // httptest chạy trên loopback, bỏ qua safe transport
+			gotAuth = ""
+
+			payload, _ := json.Marshal(map[string]any{"url": target.URL + "/hook", "service_scopes": []string{ScopeJobs}})
+			if _, err := s.runHTTPRequestJob(context.Background(), &job.Job{ActionType: jobActionHTTPRequest, Payload: payload}); err != nil {
+				t.Fatalf("runHTTPRequestJob: %v", err)
+			}
+			if hasAuth := strings.HasPrefix(gotAuth, "Bearer "); hasAuth != tt.wantAuth {
+				t.Fatalf("Authorization = %q, want token: %v", gotAuth, tt.wantAuth)
+			}
+		})
+	}
+}
@@ -50,8 +50,8 @@ func GenerateAccessToken(userID int, username string, role string, secret []byte
 	return token.SignedString(secret)
 }
 
-// GenerateRefreshToken tạo JWT refresh token
-func GenerateRefreshToken(userID int, username string, secret []byte) (string, error) {
+// GenerateRefreshToken tạo JWT refresh token, sessionID -> jti (user_sessions.id)
+func GenerateRefreshToken(userID int, username string, sessionID string, secret []byte) (string, error) {
 	now := time.Now()
 
 	claims := Claims{
@@ -63,6 +63,7 @@ func GenerateRefreshToken(userID int, username string, secret []byte) (string, e
 			ExpiresAt: jwt.NewNumericDate(now.Add(RefreshTokenTTL)),
 			Issuer:    "cronhustler-api",
 			Subject:   username,
+			ID:        sessionID,
 		},
 	}
 
@@ -78,7 +79,7 @@ func ParseToken(tokenStr string, secret []byte) (*Claims, error) {
 			return nil, jwt.ErrTokenSignatureInvalid
 		}
 		return secret, nil
-	})
+	}, jwt.WithIssuer("cronhustler-api")) // không nhận service token
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,96 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/linkpreview"
+	"errors"
+	"log"
+	"time"
+)
+
+// số fetch preview chạy song song, đầy thì bỏ qua (preview không bắt buộc)
+const linkPreviewWorkers = 8
+
+// EnableLinkPreviews: bật lấy OG metadata cho link trong tin text
+func (s *Server) EnableLinkPreviews(f *linkpreview.Fetcher) {
+	s.linkPreviews = f
+	s.linkPreviewSem = make(chan struct{}, linkPreviewWorkers)
+}
+
+// enqueueLinkPreview: tin có link -> fetch nền, xong thì lưu + WS message_preview_ready
+func (s *Server) enqueueLinkPreview(roomID, messageID int64, content string) {
+	if s.linkPreviews == nil {
+		return
+	}
+	link := linkpreview.ExtractURL(content)
+	if link == "" {
+		return
+	}
+
+	select {
+	case s.linkPreviewSem <- struct{}{}:
+	default:
+		log.Printf("[link preview] busy, skip message %d", messageID)
+		return
+	}
+
+	go func() {
+		defer func() { <-s.linkPreviewSem }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*linkpreview.DefaultTimeout)
+		defer cancel()
+
+		p, err := s.linkPreviewRepo.Cached(ctx, link)
+		if err != nil {
+			log.Println("[link preview] Cached error:", err)
+		}
+		if p == nil {
+			p, err = s.linkPreviews.Fetch(ctx, link)
+			if err != nil {
+				if !errors.Is(err, linkpreview.ErrRecentlyFailed) {
+					log.Printf("[link preview] %s: %v", link, err)
+				}
+				return
+			}
+		}
+
+		if err := s.linkPreviewRepo.Save(ctx, messageID, p); err != nil {
+			log.Println("[link preview] Save error:", err)
+			return
+		}
+
+		memberIDs, err := s.roomRepo.GetRoomMemberIDs(roomID)
+		if err != nil {
+			log.Println("[link preview] GetRoomMemberIDs error:", err)
+			return
+		}
+		wsSendToUsers(memberIDs, wsEnvelope{
+			Type:   "message_preview_ready",
+			RoomID: roomID,
+			Data:   map[string]any{"message_id": messageID, "link_preview": p},
+		})
+	}()
+}
+
+// attachLinkPreviews: gắn preview đã lưu vào list message (lỗi chỉ log, không chặn trả list)
+func (s *Server) attachLinkPreviews(ctx context.Context, msgs []RoomMessageResponse) {
+	if len(msgs) == 0 {
+		return
+	}
+	ids := make([]int64, len(msgs))
+	for i, m := range msgs {
+		ids[i] = m.ID
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	previews, err := s.linkPreviewRepo.ListByMessageIDs(ctx, ids)
+	if err != nil {
+		log.Println("ListByMessageIDs (link preview) error:", err)
+		return
+	}
+	for i := range msgs {
+		msgs[i].LinkPreview = previews[msgs[i].ID]
+	}
+}
@@ -0,0 +1,79 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/audit"
+	"log"
+	"net/http"
+	"time"
+)
+
+// loginLockFailure: username / IP đang bị khoá vì đăng nhập sai nhiều lần -> 423 + Retry-After
+// username không tồn tại cũng bị khoá y hệt (không lộ tài khoản nào có thật), lỗi DB không chặn login
+func (s *Server) loginLockFailure(ctx context.Context, username, ip string) *apiFailure {
+	l, err := s.lockoutRepo.Check(ctx, username, ip, s.now())
+	if err != nil {
+		log.Println("lockout Check error:", err)
+		return nil
+	}
+	if l == nil {
+		return nil
+	}
+	return lockedFailure(l.Until, s.now())
+}
+
+func lockedFailure(until, now time.Time) *apiFailure {
+	retry := int(until.Sub(now).Seconds() + 0.999)
+	if retry < 1 {
+		retry = 1
+	}
+	msg := "too many failed login attempts, try again later"
+	return &apiFailure{status: http.StatusLocked, retryAfter: retry, body: &apiError{
+		Code:    CodeLocked,
+		Message: msg,
+		Error:   msg,
+		Details: map[string]any{
+			"locked_until": until.Format(time.RFC3339),
+			"retry_after":  retry,
+		},
+	}}
+}
+
+// recordLoginFailure: +1 lần sai cho username + IP, vừa bị khoá -> ghi audit và trả 423 cho chính lần này
+// userID = 0 khi username không tồn tại
+func (s *Server) recordLoginFailure(r *http.Request, username string, userID int64) *apiFailure {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := s.now()
+	locks, err := s.lockoutRepo.RecordFailure(ctx, username, getIP(r), now)
+	if err != nil {
+		log.Println("lockout RecordFailure error:", err)
+	}
+	if len(locks) == 0 {
+		return nil
+	}
+
+	var until time.Time
+	for _, l := range locks {
+		s.recordAudit(r, 0, audit.ActionLoginLocked, audit.TargetUser, userID, map[string]any{
+			"scope":        l.Scope,
+			"key":          l.Key,
+			"failures":     l.Failures,
+			"locked_until": l.Until.Format(time.RFC3339),
+		})
+		if l.Until.After(until) {
+			until = l.Until
+		}
+	}
+	return lockedFailure(until, now)
+}
+
+// resetLoginFailures: đăng nhập đúng -> xoá đếm sai của username
+func (s *Server) resetLoginFailures(username string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.lockoutRepo.Reset(ctx, username); err != nil {
+		log.Println("lockout Reset error:", err)
+	}
+}
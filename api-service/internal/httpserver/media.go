@@ -0,0 +1,384 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/chat"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TTL cho signed media URL (ảnh chat + avatar)
+const MediaURLTTL = 1 * time.Hour
+
+// prefix các static route cần ký
+const (
+	chatUploadPrefix   = "/static/chat_uploads/"
+	avatarPrefix       = "/static/user_avatars/"
+	chatDownloadPrefix = "/media/download/" // tải file chat với tên gốc
+)
+
+// mediaSignature: HMAC-SHA256(path + "\n" + exp)
+func mediaSignature(secret []byte, path string, exp int64) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(path))
+	mac.Write([]byte("\n"))
+	mac.Write([]byte(strconv.FormatInt(exp, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// isSignableMediaURL: chỉ ký URL nội bộ trỏ vào static media
+func isSignableMediaURL(raw string) bool {
+	return strings.HasPrefix(raw, chatUploadPrefix) ||
+		strings.HasPrefix(raw, avatarPrefix) ||
+		strings.HasPrefix(raw, chatDownloadPrefix)
+}
+
+// signMediaURL: /static/chat_uploads/x.webp -> /static/chat_uploads/x.webp?exp=...&sig=...
+// URL ngoài (http://...) hoặc rỗng thì trả nguyên
+func (s *Server) signMediaURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || !isSignableMediaURL(raw) {
+		return raw
+	}
+
+	// bỏ query cũ (nếu FE lỡ gửi lại signed url)
+	path := raw
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+
+	// làm tròn exp lên theo bucket TTL -> trong 1 khung giờ URL giữ nguyên,
+	// browser/CDN cache được (URL luôn còn hạn ít nhất MediaURLTTL)
+	ttl := int64(MediaURLTTL / time.Second)
+	exp := (s.now().Unix()/ttl + 2) * ttl
+	q := url.Values{}
+	q.Set("exp", strconv.FormatInt(exp, 10))
+	q.Set("sig", mediaSignature(s.jwtSecret, path, exp))
+	return s.mediaBaseURL + path + "?" + q.Encode()
+}
+
+// SetMediaBaseURL: prefix CDN cho media URL trả về FE (vd https://cdn.example.com)
+// rỗng = dùng path tương đối như cũ
+func (s *Server) SetMediaBaseURL(base string) {
+	s.mediaBaseURL = strings.TrimRight(strings.TrimSpace(base), "/")
+}
+
+// setMediaCacheHeaders: avatar (public, qua signed URL) -> filename có timestamp, không bao giờ đổi nội dung -> immutable
+func setMediaCacheHeaders(w http.ResponseWriter, name string) {
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	setMediaETag(w, name)
+}
+
+// setChatMediaCacheHeaders: file chat có thể bị xoá / cách ly (410) sau khi upload -> không immutable
+// signed URL -> public (CDN cache được) nhưng chỉ tới lúc chữ ký hết hạn,
+// auth bằng cookie/token -> private, revalidate mỗi lần (authz chạy lại, ETag -> 304)
+func (s *Server) setChatMediaCacheHeaders(w http.ResponseWriter, r *http.Request, name string, signed bool) {
+	setMediaETag(w, name)
+	if !signed {
+		w.Header().Set("Cache-Control", "private, no-cache")
+		return
+	}
+	exp, _ := strconv.ParseInt(r.URL.Query().Get("exp"), 10, 64)
+	maxAge := max(exp-s.now().Unix(), 0)
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.FormatInt(maxAge, 10))
+}
+
+// setMediaETag: ETag theo tên file (tên đã unique), ServeContent tự xử lý If-None-Match
+func setMediaETag(w http.ResponseWriter, name string) {
+	sum := sha256.Sum256([]byte(name))
+	w.Header().Set("ETag", `"`+base64.RawURLEncoding.EncodeToString(sum[:12])+`"`)
+}
+
+// withMediaCache: gắn cache header cho FileServer (avatar)
+func withMediaCache(prefix string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, prefix)
+		if name != "" && !strings.HasSuffix(name, "/") {
+			setMediaCacheHeaders(w, name)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// signMessageContent: message image/file/audio lưu URL trong content -> chỉ ký khi file được upload
+// vào đúng room của tin (uploadRooms: file -> room, xem Server.uploadRooms). URL file của room khác
+// (hoặc upload cũ không có trong chat_uploads) trả nguyên, member xem bằng login như handleChatMedia
+func (s *Server) signMessageContent(roomID int64, messageType, content string, uploadRooms map[string]int64) string {
+	name, ok := mediaContentName(messageType, content)
+	if !ok {
+		return content
+	}
+	if upRoom, found := uploadRooms[name]; !found || upRoom != roomID {
+		return content
+	}
+	return s.signMediaURL(content)
+}
+
+// mediaContentName: tên file chat upload trong content của tin image/file/audio
+func mediaContentName(messageType, content string) (string, bool) {
+	switch messageType {
+	case "image", "file", "audio":
+		return chatUploadName(content)
+	default:
+		return "", false
+	}
+}
+
+// uploadRooms: room lúc upload của các file (gom content cả trang tin -> 1 query)
+// lỗi DB -> map rỗng (không ký content, không chặn response)
+func (s *Server) uploadRooms(ctx context.Context, names []string) map[string]int64 {
+	if len(names) == 0 {
+		return nil
+	}
+	rooms, err := s.chatRepo.GetUploadRooms(ctx, names)
+	if err != nil {
+		log.Println("GetUploadRooms error:", err)
+		return nil
+	}
+	return rooms
+}
+
+// verifyMediaSignature: check exp + sig trên request
+func (s *Server) verifyMediaSignature(r *http.Request) bool {
+	q := r.URL.Query()
+	expStr := q.Get("exp")
+	sig := q.Get("sig")
+	if expStr == "" || sig == "" {
+		return false
+	}
+
+	exp, err := strconv.ParseInt(expStr, 10, 64)
+	if err != nil || s.now().Unix() > exp {
+		return false
+	}
+
+	want := mediaSignature(s.jwtSecret, r.URL.Path, exp)
+	return hmac.Equal([]byte(want), []byte(sig))
+}
+
+// RequireSignedMedia: chặn truy cập static media nếu không có chữ ký hợp lệ
+func (s *Server) RequireSignedMedia(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+
+		if !s.verifyMediaSignature(r) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "invalid or expired media signature"})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// =======================================
+// HANDLER: GET /static/chat_uploads/{filename}
+// =======================================
+
+// handleChatMedia: thay http.FileServer cho chat uploads
+// - signed URL hợp lệ -> cho qua
+// - không có chữ ký -> bắt buộc login (Bearer hoặc refresh cookie) + là member của room chứa file (room lúc upload)
+// - stream bằng http.ServeContent (có Range, If-Modified-Since)
+func (s *Server) handleChatMedia(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	// 1) filename: chỉ cho phép 1 segment, chặn path traversal
+	name, ok := chatMediaName(r.URL.Path, chatUploadPrefix)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid filename"})
+		return
+	}
+
+	// 2) authz
+	signed, ok := s.authorizeChatMedia(w, r, name)
+	if !ok {
+		return
+	}
+
+	// 3) stream, ảnh/video/audio inline
+	ctype := mediaMimeFromExt(filepath.Ext(name))
+	s.serveChatFile(w, r, name, signed, mediaContentDisposition(ctype, name))
+}
+
+// GET /media/download/{filename}
+// giống handleChatMedia nhưng luôn là attachment + tên file gốc lúc upload
+func (s *Server) handleChatMediaDownload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	name, ok := chatMediaName(r.URL.Path, chatDownloadPrefix)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid filename"})
+		return
+	}
+
+	signed, ok := s.authorizeChatMedia(w, r, name)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	original, err := s.chatRepo.GetUploadOriginalName(ctx, name)
+	if err != nil {
+		log.Println("GetUploadOriginalName error:", err)
+	}
+	if original == "" {
+		original = name
+	}
+
+	disp := mime.FormatMediaType("attachment", map[string]string{"filename": original})
+	if disp == "" {
+		disp = fmt.Sprintf("attachment; filename=%q", name)
+	}
+	s.serveChatFile(w, r, name, signed, disp)
+}
+
+// chatMediaName: lấy filename sau prefix, chỉ 1 segment, chặn path traversal
+func chatMediaName(path, prefix string) (string, bool) {
+	name := strings.TrimPrefix(path, prefix)
+	if name == "" || strings.Contains(name, "/") || strings.Contains(name, "\\") || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return "", false
+	}
+	return name, true
+}
+
+// authorizeChatMedia: signed URL hợp lệ -> cho qua, không thì login + member của room chứa file
+// (room lúc upload theo chat_uploads, upload cũ không có trong bảng thì room của tin trỏ tới file)
+// lỗi thì đã ghi response, trả ok=false
+func (s *Server) authorizeChatMedia(w http.ResponseWriter, r *http.Request, name string) (signed bool, ok bool) {
+	if s.verifyMediaSignature(r) {
+		return true, true
+	}
+
+	userID, err := s.mediaRequesterID(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return false, false
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	// không theo tin chứa URL: URL có thể bị dán lại ở room khác
+	var roomID int64
+	up, err := s.chatRepo.GetUpload(ctx, name)
+	switch {
+	case err == nil && up.QuarantinedAt != nil:
+		writeJSON(w, http.StatusGone, map[string]string{"error": "media was blocked by the virus scan"})
+		return false, false
+	case err == nil && (up.UploaderID == userID || up.RoomID == 0):
+		// người upload xem lại file của mình, emoji (room_id = 0) ai login cũng xem được
+		return false, true
+	case err == nil:
+		roomID = up.RoomID
+	case errors.Is(err, chat.ErrUploadNotFound):
+		roomID, err = s.chatRepo.GetRoomIDByMediaURL(ctx, chatUploadPrefix+name)
+		if errors.Is(err, chat.ErrMessageNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "media not found"})
+			return false, false
+		}
+	}
+	if err != nil {
+		log.Println("media room lookup error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return false, false
+	}
+
+	isMember, err := s.roomRepo.IsUserInRoom(roomID, userID)
+	if err != nil {
+		log.Println("IsUserInRoom error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return false, false
+	}
+	if !isMember {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "you are not a member of this room"})
+		return false, false
+	}
+
+	return false, true
+}
+
+// serveChatFile: mở file trong chatUploadDir + stream bằng http.ServeContent (Range, If-Modified-Since, 304)
+// object store -> redirect sang presigned URL
+func (s *Server) serveChatFile(w http.ResponseWriter, r *http.Request, name string, signed bool, disposition string) {
+	key := chatUploadKey(name)
+	ctype := mediaMimeFromExt(filepath.Ext(name))
+	if s.serveFromStore(w, r, key, ctype, disposition) {
+		return
+	}
+
+	f, err := os.Open(s.store.LocalPath(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "media not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "cannot open file"})
+		return
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil || st.IsDir() {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "media not found"})
+		return
+	}
+
+	w.Header().Set("Content-Type", ctype)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Disposition", disposition)
+	s.setChatMediaCacheHeaders(w, r, name, signed)
+
+	http.ServeContent(w, r, name, st.ModTime(), f)
+}
+
+// mediaRequesterID: thẻ <img> không gắn được Authorization header -> fallback refresh cookie
+func (s *Server) mediaRequesterID(r *http.Request) (int64, error) {
+	if r.Header.Get("Authorization") != "" {
+		return UserIDFromContext(r.Context())
+	}
+	return s.VerifyWSAuth(r)
+}
+
+// mediaMimeFromExt: mime theo đuôi file đã lưu
+func mediaMimeFromExt(ext string) string {
+	ext = strings.ToLower(ext)
+	if ctype := mime.TypeByExtension(ext); ctype != "" {
+		return ctype
+	}
+	// .weba/.m4a... không phải hệ thống nào cũng có trong mime table
+	if isAudioExt(ext) {
+		return audioMimeFromExt(ext)
+	}
+	return "application/octet-stream"
+}
+
+// mediaContentDisposition: ảnh/video/audio -> inline, còn lại -> attachment
+func mediaContentDisposition(ctype, filename string) string {
+	disp := "attachment"
+	if strings.HasPrefix(ctype, "image/") || strings.HasPrefix(ctype, "video/") || strings.HasPrefix(ctype, "audio/") {
+		disp = "inline"
+	}
+	return fmt.Sprintf("%s; filename=%q", disp, filename)
+}
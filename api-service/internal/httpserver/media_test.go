@@ -0,0 +1,196 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/room"
+	"database/sql"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestChatMediaAuthorization(t *testing.T) {
+	const name = "r10_u2_1.png"
+	const byMediaURL = `SELECT room_id FROM \(`
+	const byUpload = `FROM chat_uploads\s+WHERE file_name = \?`
+	uploadRow := func(uploaderID int64, quarantined any) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{
+			"file_name", "room_id", "uploader_id", "original_name", "content_type", "file_size", "created_at", "quarantined_at",
+		}).AddRow(name, 10, uploaderID, "cat.png", "image/png", 3, testNow, quarantined)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		token      bool
+		signed     bool
+		setup      func(mock sqlmock.Sqlmock)
+		wantStatus int
+	}{
+		{name: "hidden file", path: chatUploadPrefix + ".env", token: true, wantStatus: http.StatusBadRequest},
+		{name: "no token no signature", wantStatus: http.StatusUnauthorized},
+		{name: "signed url", signed: true, wantStatus: http.StatusOK},
+		{
+			name:  "member of upload room",
+			token: true,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(byUpload).WithArgs(name).WillReturnRows(uploadRow(2, nil))
+				expectMember(mock, 10, 1, true)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			// regression: URL dán lại vào room 11 của user -> vẫn check room lúc upload (10), không theo tin
+			name:  "not a member of upload room",
+			token: true,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(byUpload).WithArgs(name).WillReturnRows(uploadRow(2, nil))
+				expectMember(mock, 10, 1, false)
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:  "own upload",
+			token: true,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(byUpload).WithArgs(name).WillReturnRows(uploadRow(1, nil))
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:  "quarantined upload",
+			token: true,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(byUpload).WithArgs(name).WillReturnRows(uploadRow(1, testNow))
+			},
+			wantStatus: http.StatusGone,
+		},
+		{
+			name:  "legacy file, member of message room",
+			token: true,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(byUpload).WithArgs(name).WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery(byMediaURL).WillReturnRows(sqlmock.NewRows([]string{"room_id"}).AddRow(10))
+				expectMember(mock, 10, 1, true)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:  "legacy file, not a member",
+			token: true,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(byUpload).WithArgs(name).WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery(byMediaURL).WillReturnRows(sqlmock.NewRows([]string{"room_id"}).AddRow(10))
+				expectMember(mock, 10, 1, false)
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:  "unknown file",
+			token: true,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(byUpload).WithArgs(name).WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery(byMediaURL).WillReturnError(sql.ErrNoRows)
+			},
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newTestServer(t)
+			if err := os.WriteFile(s.store.LocalPath(chatUploadKey(name)), []byte("png"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if tt.setup != nil {
+				tt.setup(mock)
+			}
+
+			path := tt.path
+			if path == "" {
+				path = chatUploadPrefix + name
+			}
+			if tt.signed {
+				path = s.signMediaURL(path)
+			}
+			token := ""
+			if tt.token {
+				token = accessTokenFor(t, 1)
+			}
+
+			rec := serve(s, http.MethodGet, path, token, "")
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			checkExpectations(t, mock)
+			if tt.wantStatus == http.StatusOK && rec.Body.String() != "png" {
+				t.Fatalf("body = %q", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestSignMessageContentOnlyForRoomUploads(t *testing.T) {
+	const (
+		own    = chatUploadPrefix + "r10_u1_1.png"
+		other  = chatUploadPrefix + "r11_u2_1.png" // upload của room 11, bị dán lại vào room 10
+		legacy = chatUploadPrefix + "old.png"      // không có trong chat_uploads
+	)
+	s, mock := newTestServer(t)
+	mock.ExpectQuery(`SELECT file_name, room_id FROM chat_uploads WHERE file_name IN \(\?,\?,\?\)`).
+		WithArgs("r10_u1_1.png", "r11_u2_1.png", "old.png").
+		WillReturnRows(sqlmock.NewRows([]string{"file_name", "room_id"}).
+			AddRow("r10_u1_1.png", 10).AddRow("r11_u2_1.png", 11))
+
+	msgs := []*room.Message{
+		{ID: 1, RoomID: 10, Type: "image", Content: own},
+		{ID: 2, RoomID: 10, Type: "file", Content: other},
+		{ID: 3, RoomID: 10, Type: "image", Content: legacy},
+		{ID: 4, RoomID: 10, Type: "text", Content: own},
+	}
+	uploadRooms := s.messageUploadRooms(context.Background(), msgs...)
+	checkExpectations(t, mock)
+
+	want := map[int64]string{1: s.signMediaURL(own), 2: other, 3: legacy, 4: own}
+	for _, m := range msgs {
+		if got := s.toRoomMessageResponse(m, uploadRooms).Content; got != want[m.ID] {
+			t.Errorf("message %d: content = %q, want %q", m.ID, got, want[m.ID])
+		}
+	}
+	if !strings.Contains(want[1], "sig=") {
+		t.Fatalf("own upload not signed: %q", want[1])
+	}
+}
+
+// file chat có thể bị xoá / cách ly -> không cache quá hạn chữ ký, không immutable
+func TestChatMediaCacheHeaders(t *testing.T) {
+	const name = "r10_u1_1.png"
+	s, mock := newTestServer(t)
+	if err := os.WriteFile(s.store.LocalPath(chatUploadKey(name)), []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// testNow 09:30 -> exp làm tròn lên 11:00
+	rec := serve(s, http.MethodGet, s.signMediaURL(chatUploadPrefix+name), "", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("signed: status = %d (body %s)", rec.Code, rec.Body.String())
+	}
+	if got, want := rec.Header().Get("Cache-Control"), "public, max-age=5400"; got != want {
+		t.Fatalf("signed: Cache-Control = %q, want %q", got, want)
+	}
+
+	mock.ExpectQuery(`FROM chat_uploads\s+WHERE file_name = \?`).WithArgs(name).WillReturnRows(sqlmock.NewRows([]string{
+		"file_name", "room_id", "uploader_id", "original_name", "content_type", "file_size", "created_at", "quarantined_at",
+	}).AddRow(name, 10, 1, "cat.png", "image/png", 3, testNow, nil))
+	rec = serve(s, http.MethodGet, chatUploadPrefix+name, accessTokenFor(t, 1), "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login: status = %d (body %s)", rec.Code, rec.Body.String())
+	}
+	if got, want := rec.Header().Get("Cache-Control"), "private, no-cache"; got != want {
+		t.Fatalf("login: Cache-Control = %q, want %q", got, want)
+	}
+	checkExpectations(t, mock)
+}
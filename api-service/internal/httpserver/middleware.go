@@ -1,55 +1,180 @@
 package httpserver
 
 import (
-	"log"
+	"bufio"
+	"context"
+	"cronhustler/api-service/internal/reqlog"
+	"errors"
+	"log/slog"
+	"net"
 	"net/http"
-	"strings"
 	"time"
 )
 
 // middleware type cho tiện chain
 type Middleware func(http.Handler) http.Handler
 
-// LoggerMiddleware: log request
+// LoggerMiddleware: gắn X-Request-ID (nhận từ gateway hoặc tự sinh) vào ctx + response,
+// log mỗi request bằng slog (status, thời gian, request_id)
 func LoggerMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		log.Printf("▶ %s %s", r.Method, r.URL.Path)
-		next.ServeHTTP(w, r)
-		log.Printf("▲ done %s %s in %v", r.Method, r.URL.Path, time.Since(start))
+
+		id := r.Header.Get(reqlog.Header)
+		if !reqlog.ValidRequestID(id) {
+			id = reqlog.NewRequestID()
+		}
+		w.Header().Set(reqlog.Header, id)
+		ctx := reqlog.WithRequestID(r.Context(), id)
+		r = r.WithContext(ctx)
+
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		slog.LogAttrs(ctx, level, "http request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int64("bytes", rec.bytes),
+			slog.Int64("duration_ms", time.Since(start).Milliseconds()),
+			slog.String("ip", getIP(r)),
+		)
 	})
 }
 
-// Middleware yêu cầu role = admin
-func (s *Server) RequireAdmin(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+// statusRecorder: ghi lại status + số byte cho access log
+// (giữ Hijack / Flush để WS upgrade + stream vẫn chạy)
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
 
-		// Lấy header Authorization
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing Authorization header"})
-			return
-		}
+func (rec *statusRecorder) WriteHeader(code int) {
+	if rec.status == 0 {
+		rec.status = code
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
 
-		// Expect: "Bearer <token>"
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
-			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token format"})
-			return
-		}
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += int64(n)
+	return n, err
+}
 
-		tokenStr := parts[1]
+func (rec *statusRecorder) Flush() {
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
 
-		// Parse token
-		claims, err := ParseToken(tokenStr, s.jwtSecret)
-		if err != nil {
-			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
-			return
+func (rec *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rec.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("hijack not supported")
+	}
+	if rec.status == 0 {
+		rec.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+type authCtxKey struct{}
+
+// authState: kết quả parse access token (claims nil -> err cho biết lý do)
+type authState struct {
+	claims *Claims
+	err    error
+}
+
+var (
+	errMissingAuth = errors.New("missing Authorization header")
+	errInvalidAuth = errors.New("invalid or expired token")
+)
+
+// AuthMiddleware: parse Bearer access token 1 lần, gắn Claims vào ctx cho handler phía sau
+// - không có header / header Bot: cho qua (route public, bot API tự xác thực)
+// - token không phải user JWT (service / provisioning token): cho qua, handler tự check
+// - refresh token dùng thay access token: 401 luôn
+// - không có Authorization mà có X-API-Key: key phải hợp lệ + đủ scope cho route (xem apikey.go)
+func (s *Server) AuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		st := &authState{err: errMissingAuth}
+		if h := r.Header.Get("Authorization"); h != "" {
+			st.err = errors.New("invalid Authorization header format")
+			if tokenStr := bearerToken(r); tokenStr != "" {
+				claims, err := ParseToken(tokenStr, s.jwtSecret)
+				switch {
+				case err != nil:
+					st.err = errInvalidAuth
+				case claims.TokenType != TokenTypeAccess:
+					writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "access token required"})
+					return
+				default:
+					st.claims, st.err = claims, nil
+				}
+			}
+		} else if key := r.Header.Get(apiKeyHeader); key != "" {
+			claims, ok := s.authAPIKey(w, r, key)
+			if !ok {
+				return
+			}
+			st.claims, st.err = claims, nil
 		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), authCtxKey{}, st)))
+	})
+}
+
+// ClaimsFromContext: claims access token đã verify (nil = chưa đăng nhập)
+func ClaimsFromContext(ctx context.Context) *Claims {
+	if st, ok := ctx.Value(authCtxKey{}).(*authState); ok {
+		return st.claims
+	}
+	return nil
+}
 
-		// Chỉ chấp nhận access token
-		if claims.TokenType != TokenTypeAccess {
-			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "access token required"})
+// UserIDFromContext: user của access token, lỗi mô tả vì sao không có
+func UserIDFromContext(ctx context.Context) (int64, error) {
+	st, ok := ctx.Value(authCtxKey{}).(*authState)
+	if !ok {
+		return 0, errMissingAuth
+	}
+	if st.claims == nil {
+		return 0, st.err
+	}
+	return int64(st.claims.UserID), nil
+}
+
+// Middleware yêu cầu role = admin (chạy sau AuthMiddleware)
+func (s *Server) RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims := ClaimsFromContext(r.Context())
+		if claims == nil {
+			// service token nội bộ có scope admin
+			if tokenStr := bearerToken(r); tokenStr != "" && s.serveAsService(w, r, next, tokenStr, ScopeAdmin) {
+				return
+			}
+			_, err := UserIDFromContext(r.Context())
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
 			return
 		}
 
@@ -76,8 +201,9 @@ func WithCORS(next http.Handler) http.Handler {
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
 		}
 
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, X-Request-ID, X-Device-Name")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 
 		// Preflight
 		if r.Method == http.MethodOptions {
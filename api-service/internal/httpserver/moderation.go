@@ -0,0 +1,440 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/audit"
+	"cronhustler/api-service/internal/moderation"
+	"cronhustler/api-service/internal/webhook"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const adminModerationReportsPrefix = "/admin/moderation/reports/"
+
+// bot gửi thông báo kết quả / cảnh cáo
+const (
+	moderationBotUsername = "moderation_bot"
+	moderationBotName     = "Moderation"
+)
+
+// thời gian khoá mặc định / tối đa (giờ)
+const (
+	defaultSuspendHours = 24
+	maxSuspendHours     = 24 * 365
+)
+
+func (s *Server) mountModerationRoutes(mux *http.ServeMux) {
+	// POST /reports {message_id | user_id, reason, details} -> user report tin / người
+	// GET  /reports?before_id=&limit= -> report mình đã gửi + trạng thái xử lý
+	mux.Handle("/reports", http.HandlerFunc(s.handleReports))
+
+	// GET  /admin/moderation/queue?status=open&type=message|user&before_id=&limit=
+	mux.Handle("/admin/moderation/queue", s.RequireAdmin(http.HandlerFunc(s.handleModerationQueue)))
+	// GET  /admin/moderation/reports/{id}
+	// POST /admin/moderation/reports/{id}/action {action: dismiss|delete_message|warn|suspend, note, duration_hours}
+	mux.Handle(adminModerationReportsPrefix, s.RequireAdmin(http.HandlerFunc(s.handleModerationReport)))
+	// GET  /admin/moderation/audit?before_id=&limit=
+	mux.Handle("/admin/moderation/audit", s.RequireAdmin(http.HandlerFunc(s.handleModerationAudit)))
+	// GET  /admin/moderation/deletions?room_id=&before_id=&limit= -> snapshot tin admin đã xoá / purge
+	mux.Handle("/admin/moderation/deletions", s.RequireAdmin(http.HandlerFunc(s.handleModerationDeletions)))
+	// DELETE /admin/messages/{id} {note?} -> gỡ tin bất kỳ không cần report
+	mux.Handle(adminMessagesPrefix, s.RequireAdmin(http.HandlerFunc(s.handleAdminDeleteMessage)))
+}
+
+type createReportRequest struct {
+	MessageID int64  `json:"message_id"`
+	UserID    int64  `json:"user_id"`
+	Reason    string `json:"reason"`
+	Details   string `json:"details"`
+}
+
+func (s *Server) handleReports(w http.ResponseWriter, r *http.Request) {
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.handleMyReports(w, r, userID)
+	case http.MethodPost:
+		s.handleCreateReport(w, r, userID)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func (s *Server) handleMyReports(w http.ResponseWriter, r *http.Request, userID int64) {
+	beforeID, limit := parsePaging(r)
+	list, err := s.moderationRepo.ListByReporter(r.Context(), userID, beforeID, limit)
+	if err != nil {
+		log.Println("moderation ListByReporter error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"reports": list})
+}
+
+func (s *Server) handleCreateReport(w http.ResponseWriter, r *http.Request, userID int64) {
+
+	var req createReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+	req.Reason = strings.ToLower(strings.TrimSpace(req.Reason))
+	if !moderation.IsValidReason(req.Reason) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "reason must be one of: " + strings.Join(moderation.Reasons, ", ")})
+		return
+	}
+	req.Details = strings.TrimSpace(req.Details)
+	if len([]rune(req.Details)) > 1000 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "details too long (max 1000 chars)"})
+		return
+	}
+
+	ctx := r.Context()
+	rp := &moderation.Report{ReporterID: userID, Reason: req.Reason, Details: req.Details}
+
+	switch {
+	case req.MessageID > 0:
+		roomID, senderID, content, err := s.moderationRepo.MessageSnapshot(ctx, req.MessageID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "message not found"})
+				return
+			}
+			log.Println("MessageSnapshot error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		// chỉ report được tin trong room mình thấy
+		if !s.requireRoomMember(w, roomID, userID) {
+			return
+		}
+		rp.TargetType = moderation.TargetMessage
+		rp.MessageID = req.MessageID
+		rp.RoomID = roomID
+		rp.TargetUserID = senderID
+		rp.ContentPreview = truncateRunes(content, 500)
+
+	case req.UserID > 0:
+		if _, err := s.userRepo.GetUserByID(int(req.UserID)); err != nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "user not found"})
+			return
+		}
+		rp.TargetType = moderation.TargetUser
+		rp.TargetUserID = req.UserID
+
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "message_id or user_id is required"})
+		return
+	}
+
+	if rp.TargetUserID == userID {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "cannot report yourself"})
+		return
+	}
+
+	dup, err := s.moderationRepo.HasOpenReport(ctx, userID, rp.TargetType, rp.MessageID, rp.TargetUserID)
+	if err != nil {
+		log.Println("HasOpenReport error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	if dup {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "you already reported this"})
+		return
+	}
+
+	if err := s.moderationRepo.Create(ctx, rp); err != nil {
+		log.Println("Create report error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{"id": rp.ID, "status": rp.Status})
+}
+
+func truncateRunes(s string, n int) string {
+	rs := []rune(s)
+	if len(rs) <= n {
+		return s
+	}
+	return string(rs[:n]) + "…"
+}
+
+// parsePaging: before_id + limit (mặc định 50, tối đa 200)
+func parsePaging(r *http.Request) (int64, int) {
+	q := r.URL.Query()
+	beforeID, _ := strconv.ParseInt(q.Get("before_id"), 10, 64)
+	limit := 50
+	if v, err := strconv.Atoi(q.Get("limit")); err == nil && v > 0 && v <= 200 {
+		limit = v
+	}
+	return beforeID, limit
+}
+
+func (s *Server) handleModerationQueue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	q := r.URL.Query()
+	status := q.Get("status")
+	switch status {
+	case "":
+		status = moderation.StatusOpen
+	case moderation.StatusOpen, moderation.StatusResolved, moderation.StatusDismissed:
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "status must be open, resolved or dismissed"})
+		return
+	}
+	targetType := q.Get("type")
+	if targetType != "" && targetType != moderation.TargetMessage && targetType != moderation.TargetUser {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "type must be message or user"})
+		return
+	}
+
+	beforeID, limit := parsePaging(r)
+	list, err := s.moderationRepo.Queue(r.Context(), status, targetType, beforeID, limit)
+	if err != nil {
+		log.Println("moderation Queue error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"reports": list})
+}
+
+func (s *Server) handleModerationAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	beforeID, limit := parsePaging(r)
+	list, err := s.moderationRepo.ListAudit(r.Context(), beforeID, limit)
+	if err != nil {
+		log.Println("moderation ListAudit error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"entries": list})
+}
+
+type moderationActionRequest struct {
+	Action        string `json:"action"`
+	Note          string `json:"note"`           // gửi kèm cho user bị cảnh cáo / khoá
+	DurationHours int    `json:"duration_hours"` // suspend, mặc định 24
+}
+
+func (s *Server) handleModerationReport(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, adminModerationReportsPrefix), "/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid report id"})
+		return
+	}
+
+	ctx := r.Context()
+	rp, err := s.moderationRepo.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, moderation.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "report not found"})
+			return
+		}
+		log.Println("moderation Get error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, rp)
+	case len(parts) == 2 && parts[1] == "action" && r.Method == http.MethodPost:
+		s.applyModerationAction(w, r, rp)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
+
+func (s *Server) applyModerationAction(w http.ResponseWriter, r *http.Request, rp *moderation.Report) {
+	adminID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var req moderationActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+	req.Note = strings.TrimSpace(req.Note)
+
+	if rp.Status != moderation.StatusOpen {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "report already handled"})
+		return
+	}
+
+	ctx := r.Context()
+	auditNote := req.Note
+
+	// 1) thực hiện hành động
+	switch req.Action {
+	case moderation.ActionDismiss:
+
+	case moderation.ActionDeleteMessage:
+		if rp.MessageID == 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": moderation.ErrNoMessage.Error()})
+			return
+		}
+		if err := s.moderationRepo.RemoveMessage(ctx, rp.MessageID); err != nil {
+			log.Println("RemoveMessage error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		s.broadcastMessageRemoved(rp.RoomID, rp.MessageID, moderation.RemovedPlaceholder)
+
+	case moderation.ActionWarn:
+		text := "⚠️ Bạn nhận được cảnh cáo từ quản trị viên vì vi phạm quy định (" + rp.Reason + ")."
+		if req.Note != "" {
+			text += "\n" + req.Note
+		}
+		s.moderationNotify(ctx, rp.TargetUserID, text)
+
+	case moderation.ActionSuspend:
+		hours := req.DurationHours
+		if hours == 0 {
+			hours = defaultSuspendHours
+		}
+		if hours < 1 || hours > maxSuspendHours {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("duration_hours must be 1..%d", maxSuspendHours)})
+			return
+		}
+		until := s.now().Add(time.Duration(hours) * time.Hour)
+		if err := s.moderationRepo.Suspend(ctx, rp.TargetUserID, until); err != nil {
+			log.Println("Suspend error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		auditNote = strings.TrimSpace(fmt.Sprintf("%dh. %s", hours, req.Note))
+		s.recordAudit(r, adminID, audit.ActionUserSuspend, audit.TargetUser, rp.TargetUserID, map[string]any{
+			"report_id": rp.ID,
+			"hours":     hours,
+			"until":     until.Format(time.RFC3339),
+			"note":      req.Note,
+		})
+		s.emitUserEvent(webhook.EventUserSuspended, rp.TargetUserID, map[string]any{
+			"until":  until.Format(time.RFC3339),
+			"reason": rp.Reason,
+		})
+
+		text := "⛔ Tài khoản của bạn bị tạm khoá tới " + until.Format("15:04 02/01/2006") + " (" + rp.Reason + ")."
+		if req.Note != "" {
+			text += "\n" + req.Note
+		}
+		s.moderationNotify(ctx, rp.TargetUserID, text)
+
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "action must be dismiss, delete_message, warn or suspend"})
+		return
+	}
+
+	// 2) đóng report (+ report trùng đối tượng) và ghi audit
+	reporters, err := s.moderationRepo.Resolve(ctx, rp, adminID, req.Action, auditNote)
+	if err != nil {
+		if errors.Is(err, moderation.ErrAlreadyClosed) {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": "report already handled"})
+			return
+		}
+		log.Println("moderation Resolve error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	// 3) báo kết quả cho người report (chạy nền)
+	outcome := "đã được xử lý. Cảm ơn bạn đã giúp giữ cộng đồng an toàn."
+	if req.Action == moderation.ActionDismiss {
+		outcome = "đã được xem xét và không phát hiện vi phạm."
+	}
+	go func(ids []int64) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		for _, uid := range ids {
+			s.moderationNotify(ctx, uid, fmt.Sprintf("🛡️ Báo cáo #%d của bạn %s", rp.ID, outcome))
+		}
+	}(reporters)
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":                 true,
+		"action":             req.Action,
+		"reporters_notified": len(reporters),
+	})
+}
+
+// moderationNotify: DM từ Moderation bot (lỗi chỉ log)
+func (s *Server) moderationNotify(ctx context.Context, userID int64, text string) {
+	botID, err := s.botRepo.EnsureSystemBot(ctx, moderationBotUsername, moderationBotName)
+	if err != nil {
+		log.Println("[moderation] EnsureSystemBot error:", err)
+		return
+	}
+	roomID, err := s.ensureDirectRoom(botID, userID)
+	if err != nil {
+		log.Println("[moderation] ensureDirectRoom error:", err)
+		return
+	}
+	if _, err := s.postBotMessage(ctx, botID, roomID, text); err != nil {
+		log.Println("[moderation] post error:", err)
+	}
+}
+
+// broadcastMessageRemoved: FE thay nội dung tin bằng placeholder
+func (s *Server) broadcastMessageRemoved(roomID, messageID int64, placeholder string) {
+	memberIDs, err := s.roomRepo.GetRoomMemberIDs(roomID)
+	if err != nil {
+		log.Println("GetRoomMemberIDs error:", err)
+		return
+	}
+	wsSendToUsers(memberIDs, wsEnvelope{
+		Type:   "message_removed",
+		RoomID: roomID,
+		Data: map[string]any{
+			"message_id": messageID,
+			"content":    placeholder,
+		},
+	})
+}
+
+// rejectIfSuspended: user đang bị khoá -> 403 (tự ghi response)
+func (s *Server) rejectIfSuspended(w http.ResponseWriter, r *http.Request, userID int64) bool {
+	if f := s.suspendedFailure(r.Context(), userID); f != nil {
+		f.write(w)
+		return true
+	}
+	return false
+}
+
+// suspendedFailure: nil nếu user không bị khoá (lỗi DB không chặn user)
+func (s *Server) suspendedFailure(ctx context.Context, userID int64) *apiFailure {
+	until, err := s.moderationRepo.SuspendedUntil(ctx, userID)
+	if err != nil {
+		log.Println("SuspendedUntil error:", err)
+		return nil
+	}
+	if until.IsZero() {
+		return nil
+	}
+	return &apiFailure{status: http.StatusForbidden, body: map[string]string{
+		"error":           "account suspended",
+		"suspended_until": until.Format(time.RFC3339),
+	}}
+}
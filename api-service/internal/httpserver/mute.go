@@ -0,0 +1,217 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/chat"
+	"cronhustler/api-service/internal/push"
+	"cronhustler/api-service/internal/room"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// tắt thông báo room tối đa 1 năm (muốn lâu hơn thì bỏ duration = vô thời hạn)
+const maxMuteMinutes = 365 * 24 * 60
+
+type roomMuteRequest struct {
+	DurationMinutes int `json:"duration_minutes"` // 0 / bỏ trống = tới khi bật lại
+}
+
+type roomMuteResponse struct {
+	RoomID     int64      `json:"room_id"`
+	Muted      bool       `json:"muted"`
+	MutedUntil *time.Time `json:"muted_until,omitempty"`
+}
+
+type roomNotificationSetting struct {
+	RoomID      int64      `json:"room_id"`
+	Muted       bool       `json:"muted"`
+	MutedUntil  *time.Time `json:"muted_until,omitempty"`
+	NotifyLevel string     `json:"notify_level"` // all | mentions
+}
+
+type notificationSettingsResponse struct {
+	MuteAll      bool                      `json:"mute_all"`
+	MuteAllUntil *time.Time                `json:"mute_all_until,omitempty"`
+	Rooms        []roomNotificationSetting `json:"rooms"`
+}
+
+// POST /rooms/{roomID}/mute {duration_minutes?} -> tắt thông báo room
+// DELETE /rooms/{roomID}/mute -> bật lại
+func (s *Server) handleRoomMute(w http.ResponseWriter, r *http.Request, roomID int64) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	isMember, err := s.roomRepo.IsUserInRoom(roomID, userID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	if !isMember {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "you are not a member of this room"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	resp := roomMuteResponse{RoomID: roomID}
+
+	if r.Method == http.MethodDelete {
+		if err := s.pushRepo.ClearMute(ctx, userID, roomID); err != nil {
+			log.Println("ClearMute error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+	} else {
+		// body optional
+		var req roomMuteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+			return
+		}
+		if req.DurationMinutes < 0 || req.DurationMinutes > maxMuteMinutes {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "duration_minutes out of range"})
+			return
+		}
+
+		if req.DurationMinutes > 0 {
+			t := s.now().Add(time.Duration(req.DurationMinutes) * time.Minute).UTC()
+			resp.MutedUntil = &t
+		}
+		if err := s.pushRepo.SetMute(ctx, userID, roomID, resp.MutedUntil); err != nil {
+			log.Println("SetMute error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		resp.Muted = true
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+
+	// đồng bộ icon mute cho các tab / thiết bị khác của chính user
+	go wsSendToUser(userID, wsEnvelope{
+		Type:   "room_mute_updated",
+		RoomID: roomID,
+		Data:   resp,
+	})
+}
+
+// GET /rooms/notification-settings -> mute + notify level của mọi room user đang ở
+func (s *Server) handleRoomNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	rooms, err := s.roomRepo.GetRoomsByUser(userID)
+	if err != nil {
+		log.Println("GetRoomsByUser error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	prefs, err := s.pushRepo.GetPreferences(ctx, userID)
+	if err != nil {
+		log.Println("GetPreferences error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	levels, err := s.chatRepo.GetNotifyLevelsByUser(ctx, userID)
+	if err != nil {
+		log.Println("GetNotifyLevelsByUser error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	muted := mutedRoomMap(prefs)
+	resp := notificationSettingsResponse{
+		MuteAll:      prefs.MuteAll,
+		MuteAllUntil: prefs.MuteAllUntil,
+		Rooms:        make([]roomNotificationSetting, 0, len(rooms)),
+	}
+	for _, rm := range rooms {
+		setting := roomNotificationSetting{RoomID: rm.ID, NotifyLevel: chat.NotifyLevelAll}
+		if lv, ok := levels[rm.ID]; ok {
+			setting.NotifyLevel = lv
+		}
+		if m, ok := muted[rm.ID]; ok {
+			setting.Muted, setting.MutedUntil = true, m.Until
+		}
+		resp.Rooms = append(resp.Rooms, setting)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func mutedRoomMap(p *push.Preferences) map[int64]push.RoomMute {
+	out := make(map[int64]push.RoomMute, len(p.MutedRooms))
+	for _, m := range p.MutedRooms {
+		out[m.RoomID] = m
+	}
+	return out
+}
+
+type roomArchiveResponse struct {
+	RoomID     int64      `json:"room_id"`
+	Archived   bool       `json:"archived"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+}
+
+// POST /rooms/{roomID}/archive | /unarchive -> archive room của riêng user
+// room archive: ẩn khỏi GET /rooms (trừ ?include_archived=1), không bắn room_unread_update
+func (s *Server) handleRoomArchive(w http.ResponseWriter, r *http.Request, roomID int64, archived bool) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	at, err := s.roomRepo.SetArchived(ctx, roomID, userID, archived)
+	if err != nil {
+		if errors.Is(err, room.ErrNotMember) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "you are not a member of this room"})
+			return
+		}
+		log.Println("SetArchived error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	resp := roomArchiveResponse{RoomID: roomID, Archived: archived, ArchivedAt: at}
+	writeJSON(w, http.StatusOK, resp)
+
+	// tab / thiết bị khác của user chuyển room sang / ra khỏi mục archive
+	go wsSendToUser(userID, wsEnvelope{
+		Type:   "room_archive_updated",
+		RoomID: roomID,
+		Data:   resp,
+	})
+}
@@ -0,0 +1,167 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/notify"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (s *Server) mountNotifyRoutes(mux *http.ServeMux) {
+	// GET/PUT /users/notification-settings
+	mux.Handle("/users/notification-settings", http.HandlerFunc(s.handleNotificationSettings))
+	// GET /notifications/unsubscribe?uid=&sig=  (link trong email, không cần login)
+	mux.Handle("/notifications/unsubscribe", http.HandlerFunc(s.handleUnsubscribeDigest))
+}
+
+type notificationSettingsRequest struct {
+	EmailDigest        string `json:"email_digest"` // off | mentions | all
+	DigestAfterMinutes int    `json:"digest_after_minutes"`
+}
+
+func (s *Server) handleNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		st, err := s.notifyRepo.GetSettings(r.Context(), userID)
+		if err != nil {
+			log.Println("GetSettings error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, st)
+
+	case http.MethodPut:
+		var req notificationSettingsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+			return
+		}
+
+		// field bỏ trống -> giữ giá trị cũ
+		cur, err := s.notifyRepo.GetSettings(r.Context(), userID)
+		if err != nil {
+			log.Println("GetSettings error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		if req.EmailDigest == "" {
+			req.EmailDigest = cur.EmailDigest
+		}
+		if req.DigestAfterMinutes == 0 {
+			req.DigestAfterMinutes = cur.DigestAfterMinutes
+		}
+
+		if err := s.notifyRepo.UpdateSettings(r.Context(), userID, req.EmailDigest, req.DigestAfterMinutes); err != nil {
+			if errors.Is(err, notify.ErrInvalidSettings) {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "email_digest must be off|mentions|all, digest_after_minutes 5..10080"})
+				return
+			}
+			log.Println("UpdateSettings error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+
+		st, err := s.notifyRepo.GetSettings(r.Context(), userID)
+		if err != nil {
+			log.Println("GetSettings error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, st)
+
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+// unsubscribeSignature: HMAC-SHA256("unsubscribe\n" + uid), không hết hạn
+func unsubscribeSignature(secret []byte, userID int64) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("unsubscribe\n"))
+	mac.Write([]byte(strconv.FormatInt(userID, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Server) handleUnsubscribeDigest(w http.ResponseWriter, r *http.Request) {
+	// POST: one-click unsubscribe (RFC 8058) từ mail client
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	q := r.URL.Query()
+	userID, err := strconv.ParseInt(q.Get("uid"), 10, 64)
+	if err != nil || userID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid link"})
+		return
+	}
+	want := unsubscribeSignature(s.jwtSecret, userID)
+	if !hmac.Equal([]byte(want), []byte(q.Get("sig"))) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "invalid link"})
+		return
+	}
+
+	if err := s.notifyRepo.Unsubscribe(r.Context(), userID); err != nil {
+		log.Println("Unsubscribe error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("Bạn đã huỷ nhận email thông báo tin nhắn chưa đọc.\n"))
+}
+
+// SetMailer: provider gửi email + template, dùng chung cho mọi tính năng gửi mail
+func (s *Server) SetMailer(m notify.Mailer, t *notify.Templates) {
+	s.mailer = m
+	s.mailTemplates = t
+}
+
+// sendMail: render template rồi gửi; chưa cấu hình email -> notify.ErrMailDisabled
+func (s *Server) sendMail(ctx context.Context, template, to string, data any) error {
+	if s.mailer == nil {
+		return notify.ErrMailDisabled
+	}
+	msg, err := s.mailTemplates.Compose(template, to, data)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, msg)
+}
+
+// EnableEmailDigest: bật worker gửi email tóm tắt tin chưa đọc (cần SetMailer trước)
+// publicURL: base URL của API (dùng cho link unsubscribe)
+func (s *Server) EnableEmailDigest(ctx context.Context, publicURL string, interval time.Duration) {
+	base := strings.TrimRight(publicURL, "/")
+
+	d := &notify.DigestWorker{
+		Repo:      s.notifyRepo,
+		Mailer:    s.mailer,
+		Templates: s.mailTemplates,
+		IsOnline:  wsIsOnline,
+		UnsubscribeURL: func(userID int64) string {
+			v := url.Values{}
+			v.Set("uid", strconv.FormatInt(userID, 10))
+			v.Set("sig", unsubscribeSignature(s.jwtSecret, userID))
+			return base + "/notifications/unsubscribe?" + v.Encode()
+		},
+		AppURL: base,
+	}
+	d.Start(ctx, interval)
+}
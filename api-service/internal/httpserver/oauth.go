@@ -0,0 +1,344 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/audit"
+	"cronhustler/api-service/internal/oauth"
+	"cronhustler/api-service/internal/otp"
+	"cronhustler/api-service/internal/sms"
+	"cronhustler/api-service/internal/user"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	oauthStateCookie = "oauth_state"
+	oauthStateTTL    = 10 * time.Minute // thời gian user ở trang đăng nhập của provider
+)
+
+// EnableOAuth: bật social login (Google / GitHub). appPublicURL = FE, callback xong redirect về đây
+func (s *Server) EnableOAuth(cfg *oauth.Config, appPublicURL string) {
+	s.oauthCfg = cfg
+	s.appPublicURL = strings.TrimRight(appPublicURL, "/")
+}
+
+func (s *Server) mountOAuthRoutes(mux *http.ServeMux) {
+	m := newPatternMux("/auth/oauth/")
+	// GET /auth/oauth/providers -> provider đang bật (FE vẽ nút)
+	m.handle(http.MethodGet, "/auth/oauth/providers", s.handleOAuthProviders)
+	// GET /auth/oauth/{provider}/start?redirect=/rooms -> 302 sang trang đăng nhập của provider
+	m.handle(http.MethodGet, "/auth/oauth/{provider}/start", s.handleOAuthStart)
+	// GET /auth/oauth/{provider}/callback?code=&state= -> set cookie refresh_token, 302 về FE
+	m.handle(http.MethodGet, "/auth/oauth/{provider}/callback", s.handleOAuthCallback)
+	mux.Handle("/auth/oauth/", m)
+}
+
+func (s *Server) oauthProvider(r *http.Request) oauth.Provider {
+	if s.oauthCfg == nil {
+		return nil
+	}
+	return s.oauthCfg.Providers[r.PathValue("provider")]
+}
+
+func (s *Server) handleOAuthProviders(w http.ResponseWriter, r *http.Request) {
+	names := []string{}
+	if s.oauthCfg != nil {
+		names = s.oauthCfg.Names()
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"providers": names})
+}
+
+// oauthState: lưu trong cookie ký HMAC giữa start và callback (không cần bảng DB)
+type oauthState struct {
+	Provider string `json:"p"`
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"` // PKCE code_verifier
+	Redirect string `json:"r"` // path FE quay về sau khi login
+	Exp      int64  `json:"e"`
+}
+
+func oauthStateSignature(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "oauth\n%s", payload)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// cookie oauth_state: {base64(json)}.{sig}
+func (s *Server) encodeOAuthState(st *oauthState) string {
+	b, _ := json.Marshal(st)
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return payload + "." + oauthStateSignature(s.jwtSecret, payload)
+}
+
+func (s *Server) decodeOAuthState(v string) (*oauthState, error) {
+	payload, sig, ok := strings.Cut(v, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(oauthStateSignature(s.jwtSecret, payload))) {
+		return nil, errors.New("invalid oauth state")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, errors.New("invalid oauth state")
+	}
+	var st oauthState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return nil, errors.New("invalid oauth state")
+	}
+	if s.now().Unix() > st.Exp {
+		return nil, errors.New("oauth state expired")
+	}
+	return &st, nil
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// safeRedirectPath: chỉ nhận path tương đối của FE ("/rooms/1"), chặn open redirect ("//evil.com", "https://...")
+func safeRedirectPath(p string) string {
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") || strings.ContainsAny(p, "\\\r\n") {
+		return "/"
+	}
+	return p
+}
+
+func (s *Server) oauthCallbackURL(provider string) string {
+	return s.oauthCfg.RedirectBaseURL + "/auth/oauth/" + provider + "/callback"
+}
+
+func (s *Server) handleOAuthStart(w http.ResponseWriter, r *http.Request) {
+	p := s.oauthProvider(r)
+	if p == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "oauth provider not enabled"})
+		return
+	}
+
+	st := &oauthState{
+		Provider: p.Name(),
+		Redirect: safeRedirectPath(r.URL.Query().Get("redirect")),
+		Exp:      s.now().Add(oauthStateTTL).Unix(),
+	}
+	for _, v := range []*string{&st.State, &st.Nonce, &st.Verifier} {
+		tok, err := randomToken()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+			return
+		}
+		*v = tok
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    s.encodeOAuthState(st),
+		Path:     "/auth/oauth/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode, // provider redirect về bằng GET top-level -> Lax vẫn gửi cookie
+		Expires:  s.now().Add(oauthStateTTL),
+	})
+	http.Redirect(w, r, p.AuthURL(st.State, st.Nonce, oauth.CodeChallenge(st.Verifier), s.oauthCallbackURL(p.Name())), http.StatusFound)
+}
+
+// oauthRedirect: 302 về FE, lỗi đi qua query ?oauth_error=, mfa_token đi qua fragment (không lọt vào log server)
+func (s *Server) oauthRedirect(w http.ResponseWriter, r *http.Request, path string, query, fragment url.Values) {
+	target := s.appPublicURL + path
+	if len(query) > 0 {
+		sep := "?"
+		if strings.Contains(path, "?") {
+			sep = "&"
+		}
+		target += sep + query.Encode()
+	}
+	if len(fragment) > 0 {
+		target += "#" + fragment.Encode()
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+func (s *Server) oauthFail(w http.ResponseWriter, r *http.Request, path, code string) {
+	s.oauthRedirect(w, r, path, url.Values{"oauth_error": {code}}, nil)
+}
+
+func (s *Server) handleOAuthCallback(w http.ResponseWriter, r *http.Request) {
+	p := s.oauthProvider(r)
+	if p == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "oauth provider not enabled"})
+		return
+	}
+
+	// state dùng 1 lần: xoá cookie dù kết quả thế nào
+	http.SetCookie(w, &http.Cookie{Name: oauthStateCookie, Value: "", Path: "/auth/oauth/", MaxAge: -1, HttpOnly: true})
+
+	q := r.URL.Query()
+	c, err := r.Cookie(oauthStateCookie)
+	if err != nil {
+		s.oauthFail(w, r, "/", "invalid_state")
+		return
+	}
+	st, err := s.decodeOAuthState(c.Value)
+	if err != nil || st.Provider != p.Name() || q.Get("state") == "" ||
+		!hmac.Equal([]byte(q.Get("state")), []byte(st.State)) {
+		s.oauthFail(w, r, "/", "invalid_state")
+		return
+	}
+	if e := q.Get("error"); e != "" {
+		// user bấm huỷ ở provider (access_denied) hoặc provider báo lỗi
+		s.oauthFail(w, r, st.Redirect, "access_denied")
+		return
+	}
+	if q.Get("code") == "" {
+		s.oauthFail(w, r, st.Redirect, "invalid_request")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	ident, err := p.Exchange(ctx, q.Get("code"), st.Verifier, st.Nonce, s.oauthCallbackURL(p.Name()))
+	if err != nil {
+		log.Printf("[oauth] %s exchange: %v", p.Name(), err)
+		s.oauthFail(w, r, st.Redirect, "exchange_failed")
+		return
+	}
+
+	u, code := s.oauthResolveUser(r, ident)
+	if code != "" {
+		s.oauthFail(w, r, st.Redirect, code)
+		return
+	}
+	if u.Is_active == 0 {
+		s.oauthFail(w, r, st.Redirect, "account_disabled")
+		return
+	}
+	if f := s.suspendedFailure(r.Context(), int64(u.ID)); f != nil {
+		s.oauthFail(w, r, st.Redirect, "account_suspended")
+		return
+	}
+
+	// 2FA SMS vẫn áp dụng: FE đọc mfa_token ở fragment rồi gọi POST /login/otp như login thường
+	ps, err := s.otpRepo.GetSecurity(r.Context(), int64(u.ID))
+	if err != nil {
+		log.Println("otp GetSecurity error:", err)
+		s.oauthFail(w, r, st.Redirect, "internal_error")
+		return
+	}
+	if ps.SMSTwoFactor && ps.VerifiedPhone != "" {
+		if f := s.deliverOTP(r.Context(), int64(u.ID), otp.PurposeLogin, ps.VerifiedPhone); f != nil {
+			s.oauthFail(w, r, st.Redirect, "otp_failed")
+			return
+		}
+		s.oauthRedirect(w, r, st.Redirect, nil, url.Values{
+			"mfa_token":  {s.newMFAToken(int64(u.ID))},
+			"phone":      {sms.MaskPhone(ps.VerifiedPhone)},
+			"expires_in": {strconv.Itoa(int(otp.CodeTTL.Seconds()))},
+		})
+		return
+	}
+
+	// cùng cặp token như /login: refresh_token nằm ở cookie, FE gọi POST /auth/refresh lấy access token
+	if _, f := s.issueLogin(w, r, u); f != nil {
+		s.oauthFail(w, r, st.Redirect, "internal_error")
+		return
+	}
+	s.oauthRedirect(w, r, st.Redirect, nil, nil)
+}
+
+// oauthResolveUser: identity đã link -> user đó; chưa link -> email đã verify khớp đúng 1 user thì link;
+// không khớp ai -> tạo user mới (OAUTH_ALLOW_SIGNUP). code != "" = mã lỗi trả về FE
+func (s *Server) oauthResolveUser(r *http.Request, ident *oauth.Identity) (*user.User, string) {
+	ctx := r.Context()
+	userID, err := s.oauthRepo.FindUserID(ctx, ident.Provider, ident.Subject)
+	if err != nil {
+		log.Println("oauth FindUserID error:", err)
+		return nil, "internal_error"
+	}
+
+	if userID > 0 {
+		if err := s.oauthRepo.Link(ctx, userID, ident); err != nil {
+			log.Println("oauth Link error:", err)
+		}
+	} else {
+		if ident.EmailVerified && ident.Email != "" {
+			if userID, err = s.oauthRepo.FindUserIDByEmail(ctx, ident.Email); err != nil {
+				log.Println("oauth FindUserIDByEmail error:", err)
+				return nil, "internal_error"
+			}
+		}
+		switch {
+		case userID > 0:
+			if err := s.oauthRepo.Link(ctx, userID, ident); err != nil {
+				log.Println("oauth Link error:", err)
+				return nil, "internal_error"
+			}
+		case s.oauthCfg.AllowSignup:
+			if userID, err = s.createOAuthUser(r, ident); err != nil {
+				log.Println("oauth CreateUser error:", err)
+				return nil, "internal_error"
+			}
+		default:
+			return nil, "no_account"
+		}
+		s.recordAudit(r, userID, audit.ActionOAuthLink, audit.TargetUser, userID, map[string]any{
+			"provider": ident.Provider,
+			"subject":  ident.Subject,
+			"email":    ident.Email,
+		})
+	}
+
+	u, err := s.userRepo.GetUserByID(int(userID))
+	if err != nil {
+		log.Println("oauth GetUserByID error:", err)
+		return nil, "internal_error"
+	}
+	return u, ""
+}
+
+// createOAuthUser: username lấy từ email / login của provider, trùng thì thêm hậu tố ngẫu nhiên
+func (s *Server) createOAuthUser(r *http.Request, ident *oauth.Identity) (int64, error) {
+	// mật khẩu ngẫu nhiên không ai biết: user đăng nhập qua provider (hoặc đặt lại password sau)
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return 0, err
+	}
+	hash, err := s.passwordHash(hex.EncodeToString(b))
+	if err != nil {
+		return 0, err
+	}
+	nu := &oauth.NewUser{PasswordHash: hash, FullName: strings.TrimSpace(ident.Name)}
+	if ident.EmailVerified {
+		nu.Email = ident.Email
+	}
+
+	base := ident.Username
+	if base == "" {
+		base = ident.Provider
+	}
+	nu.Username = base
+	for i := 0; i < 5; i++ {
+		id, err := s.oauthRepo.CreateUser(r.Context(), nu, ident, getIP(r))
+		if !errors.Is(err, oauth.ErrUsernameTaken) {
+			return id, err
+		}
+		suffix := make([]byte, 3)
+		if _, err := rand.Read(suffix); err != nil {
+			return 0, err
+		}
+		nu.Username = base + "_" + hex.EncodeToString(suffix)
+	}
+	return 0, oauth.ErrUsernameTaken
+}
@@ -0,0 +1,493 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// OpenAPI 3 sinh từ bảng apiRoutes bên dưới.
+// Thêm / đổi route ở mountXRoutes thì cập nhật bảng này cùng lúc (FE + bên tích hợp đọc /openapi.json).
+
+// kiểu xác thực của 1 route
+const (
+	authNone         = ""             // public / credential nằm trong URL (token, chữ ký)
+	authUser         = "bearer"       // access token
+	authAdmin        = "admin"        // access token role admin (hoặc service token scope admin)
+	authBot          = "bot"          // Authorization: Bot <api_key>
+	authProvisioning = "provisioning" // Bearer <PROVISIONING_TOKEN>
+	authCookie       = "cookie"       // refresh cookie
+)
+
+type apiRoute struct {
+	Method  string
+	Path    string // {param}: id số nếu tên kết thúc bằng ID / id
+	Tag     string
+	Auth    string
+	Summary string
+	Query   string // query param, cách nhau bởi dấu phẩy
+}
+
+var apiRoutes = []apiRoute{
+	// ===== auth =====
+	{"POST", "/login", "auth", authNone, "Log in with username / password (may return mfa_token when 2FA is on); 423 LOCKED after repeated failures", ""},
+	{"POST", "/login/otp", "auth", authNone, "Complete 2FA login with SMS code", ""},
+	{"POST", "/login/otp/resend", "auth", authNone, "Resend 2FA login code", ""},
+	{"POST", "/logout", "auth", authCookie, "Log out and clear refresh cookie", ""},
+	{"POST", "/auth/refresh", "auth", authCookie, "Issue a new access token from the refresh cookie", ""},
+	{"GET", "/auth/oauth/providers", "auth", authNone, "List enabled social login providers", ""},
+	{"GET", "/auth/oauth/{provider}/start", "auth", authNone, "Redirect to Google / GitHub sign-in (?redirect=/path on the app)", ""},
+	{"GET", "/auth/oauth/{provider}/callback", "auth", authNone, "Provider callback: sets the refresh cookie and redirects to the app (?oauth_error= on failure, #mfa_token= when 2FA is on)", ""},
+	{"GET", "/auth/sessions", "auth", authUser, "List signed-in devices", ""},
+	{"DELETE", "/auth/sessions/{sessionID}", "auth", authUser, "Sign out a device", ""},
+	{"GET", "/ws", "realtime", authCookie, "WebSocket (user: refresh cookie or Sec-WebSocket-Protocol: cronchat.v1, bearer.<access_token>; bot: Authorization Bot or ?bot_key=); browser Origin must match WS_ALLOWED_ORIGINS / APP_PUBLIC_URL; ?last_seq= replays missed events; inbound message_ack, send_message", "bot_key,last_seq"},
+
+	// ===== users =====
+	{"POST", "/create-user", "users", authNone, "Create user", ""},
+	{"GET", "/me", "users", authUser, "Current user profile", ""},
+	{"GET", "/bootstrap", "users", authUser, "Initial app load: profile, rooms with last message and unread, contacts, notification settings", ""},
+	{"POST", "/update-user", "users", authUser, "Update own profile", ""},
+	{"POST", "/update-password", "users", authUser, "Change password", ""},
+	{"GET", "/get-all-user-listing", "users", authUser, "User directory", ""},
+	{"GET", "/users/search", "users", authUser, "Search users", "q,limit"},
+	{"POST", "/users/avatar", "users", authUser, "Upload avatar (multipart)", ""},
+	{"GET", "/users/notification-settings", "notifications", authUser, "Notification settings", ""},
+	{"PUT", "/users/notification-settings", "notifications", authUser, "Update notification settings", ""},
+	{"GET", "/me/limits", "users", authUser, "Own rate limits and usage", ""},
+	{"GET", "/me/phone", "users", authUser, "Phone number, verification and 2FA state", ""},
+	{"POST", "/me/phone/verify/send", "users", authUser, "Send phone verification code", ""},
+	{"POST", "/me/phone/verify", "users", authUser, "Verify phone with code", ""},
+	{"PUT", "/me/2fa", "users", authUser, "Enable / disable SMS 2FA", ""},
+
+	// ===== contacts / invites =====
+	{"GET", "/contacts", "contacts", authUser, "My contacts", ""},
+	{"DELETE", "/contacts/{userID}", "contacts", authUser, "Remove contact (both ways)", ""},
+	{"GET", "/contacts/requests", "contacts", authUser, "Contact requests", "direction"},
+	{"POST", "/contacts/requests", "contacts", authUser, "Send contact request", ""},
+	{"POST", "/contacts/requests/{requestID}/accept", "contacts", authUser, "Accept contact request", ""},
+	{"POST", "/contacts/requests/{requestID}/decline", "contacts", authUser, "Decline contact request", ""},
+	{"DELETE", "/contacts/requests/{requestID}", "contacts", authUser, "Cancel sent contact request", ""},
+	{"GET", "/invites", "rooms", authUser, "Pending group invites", ""},
+	{"POST", "/invites/{inviteID}/accept", "rooms", authUser, "Accept group invite", ""},
+	{"POST", "/invites/{inviteID}/decline", "rooms", authUser, "Decline group invite", ""},
+
+	// ===== rooms =====
+	{"GET", "/rooms", "rooms", authUser, "My rooms (paged when limit / cursor is set)", "limit,cursor,include_archived"},
+	{"POST", "/rooms/direct", "rooms", authUser, "Get or create direct room with user_id (201 + room.joined when created)", ""},
+	{"POST", "/rooms/direct/{userID}", "rooms", authUser, "Same as POST /rooms/direct (legacy path)", ""},
+	{"GET", "/rooms/direct-name/{roomID}", "rooms", authUser, "Direct partner display name", ""},
+	{"POST", "/rooms/group", "rooms", authUser, "Create group room", ""},
+	{"POST", "/rooms/add-member", "rooms", authUser, "Invite users to group", ""},
+	{"GET", "/rooms/members/{roomID}", "rooms", authUser, "Room members", ""},
+	{"DELETE", "/rooms/delete/{roomID}", "rooms", authUser, "Delete room (owner)", ""},
+	{"PATCH", "/rooms/{roomID}", "rooms", authUser, "Update group name / description / avatar", ""},
+	{"POST", "/rooms/{roomID}/mute", "rooms", authUser, "Mute room", ""},
+	{"DELETE", "/rooms/{roomID}/mute", "rooms", authUser, "Unmute room", ""},
+	{"POST", "/rooms/{roomID}/archive", "rooms", authUser, "Archive room for me", ""},
+	{"POST", "/rooms/{roomID}/unarchive", "rooms", authUser, "Unarchive room for me", ""},
+	{"GET", "/rooms/{roomID}/pins", "messages", authUser, "Pinned messages", ""},
+	{"POST", "/rooms/{roomID}/invite", "rooms", authUser, "Invite user to group", ""},
+	{"GET", "/rooms/{roomID}/retention", "rooms", authUser, "Disappearing message TTL", ""},
+	{"PATCH", "/rooms/{roomID}/retention", "rooms", authUser, "Set disappearing message TTL (owner)", ""},
+	{"GET", "/rooms/{roomID}/invite-link", "rooms", authUser, "List invite links", ""},
+	{"POST", "/rooms/{roomID}/invite-link", "rooms", authUser, "Create invite link", ""},
+	{"DELETE", "/rooms/{roomID}/invite-link/{code}", "rooms", authUser, "Revoke invite link", ""},
+	{"DELETE", "/rooms/{roomID}/members/{userID}", "rooms", authUser, "Remove member", ""},
+	{"POST", "/rooms/{roomID}/members/{userID}/role", "rooms", authUser, "Change member role", ""},
+	{"POST", "/rooms/join/{code}", "rooms", authUser, "Join group via invite link", ""},
+	{"GET", "/rooms/notification-settings", "notifications", authUser, "Mute and notify level per room", ""},
+	{"GET", "/rooms/notify-level/{roomID}", "notifications", authUser, "Room notify level", ""},
+	{"PUT", "/rooms/notify-level/{roomID}", "notifications", authUser, "Set room notify level (all | mentions)", ""},
+
+	// ===== messages =====
+	{"GET", "/rooms/messages/{roomID}", "messages", authUser, "Room messages", "before_id,limit"},
+	{"POST", "/rooms/send-messages/{roomID}", "messages", authUser, "Send message", ""},
+	{"POST", "/rooms/upload-image/{roomID}", "messages", authUser, "Upload image (multipart), send=true also creates the message", "send"},
+	{"POST", "/rooms/upload-image-base64/{roomID}", "messages", authUser, "Paste image (data URI)", ""},
+	{"POST", "/rooms/upload-audio/{roomID}", "messages", authUser, "Upload voice message", ""},
+	{"POST", "/rooms/upload-file/{roomID}", "messages", authUser, "Upload file message", ""},
+	{"POST", "/rooms/upload-files/{roomID}", "messages", authUser, "Upload several files as one message", ""},
+	{"DELETE", "/messages/{messageID}", "messages", authUser, "Delete message", ""},
+	{"GET", "/messages/{messageID}/thread", "messages", authUser, "Message thread", "after_id,limit"},
+	{"POST", "/messages/{messageID}/pin", "messages", authUser, "Pin message", ""},
+	{"DELETE", "/messages/{messageID}/pin", "messages", authUser, "Unpin message", ""},
+	{"POST", "/messages/button-click", "messages", authUser, "Click a bot message button", ""},
+	{"POST", "/messages/react/add", "reactions", authUser, "Toggle reaction", ""},
+	{"POST", "/messages/react/remove", "reactions", authUser, "Remove reaction", ""},
+	{"GET", "/messages/reactions/{messageID}", "reactions", authUser, "Reaction summary", ""},
+	{"GET", "/messages/reactions/{messageID}/users", "reactions", authUser, "Users who reacted, paginated, with counts per reaction", "reaction,cursor,limit"},
+	{"GET", "/emojis", "reactions", authUser, "Reaction emoji catalog", ""},
+
+	// ===== receipts / unread =====
+	{"POST", "/rooms/seen", "receipts", authUser, "Mark room seen up to a message", ""},
+	{"POST", "/rooms/read/{roomID}", "receipts", authUser, "Mark room read", ""},
+	{"GET", "/rooms/last-seen/{roomID}", "receipts", authUser, "Last seen message of room", ""},
+	{"GET", "/messages/seen/summary/{messageID}", "receipts", authUser, "Seen summary of message", ""},
+	{"GET", "/messages/seen/users/{messageID}", "receipts", authUser, "Users who saw message", "limit"},
+	{"GET", "/rooms/unread-counts", "receipts", authUser, "Unread counts of all rooms", ""},
+	{"GET", "/rooms/unread/{roomID}", "receipts", authUser, "Unread count of room", ""},
+
+	// ===== room features =====
+	{"GET", "/rooms/automation/{roomID}", "automation", authUser, "List automation rules", ""},
+	{"POST", "/rooms/automation/{roomID}", "automation", authUser, "Create automation rule", ""},
+	{"POST", "/rooms/automation/{roomID}/test", "automation", authUser, "Test rules against content", ""},
+	{"GET", "/rooms/automation/{roomID}/{ruleID}", "automation", authUser, "Get automation rule", ""},
+	{"PUT", "/rooms/automation/{roomID}/{ruleID}", "automation", authUser, "Update automation rule", ""},
+	{"DELETE", "/rooms/automation/{roomID}/{ruleID}", "automation", authUser, "Delete automation rule", ""},
+	{"GET", "/rooms/events/{roomID}", "calendar", authUser, "List events", "from,to"},
+	{"POST", "/rooms/events/{roomID}", "calendar", authUser, "Create event", ""},
+	{"GET", "/rooms/events/{roomID}/{eventID}", "calendar", authUser, "Get event", ""},
+	{"PUT", "/rooms/events/{roomID}/{eventID}", "calendar", authUser, "Update event", ""},
+	{"DELETE", "/rooms/events/{roomID}/{eventID}", "calendar", authUser, "Delete event", ""},
+	{"PUT", "/rooms/events/{roomID}/{eventID}/rsvp", "calendar", authUser, "RSVP to event", ""},
+	{"GET", "/rooms/events/{roomID}/{eventID}/rsvps", "calendar", authUser, "Event RSVPs", ""},
+	{"GET", "/rooms/events/{roomID}/feed", "calendar", authUser, "Signed ICS feed URL", ""},
+	{"GET", "/rooms/events/{roomID}/calendar.ics", "calendar", authNone, "ICS feed (signed URL)", "uid,sig"},
+	{"GET", "/rooms/digest/{roomID}", "digest", authUser, "Digest settings and subscription", ""},
+	{"PUT", "/rooms/digest/{roomID}", "digest", authUser, "Update digest settings (owner / admin)", ""},
+	{"PUT", "/rooms/digest/{roomID}/subscription", "digest", authUser, "Subscribe / unsubscribe email digest", ""},
+	{"GET", "/rooms/digest/{roomID}/preview", "digest", authUser, "Preview digest", "hours"},
+	{"GET", "/notifications/unsubscribe", "digest", authNone, "Unsubscribe digest from email link", "uid,sig"},
+	{"GET", "/rooms/insights/{roomID}", "insights", authUser, "Room insights (owner)", "days"},
+	{"PUT", "/rooms/insights/{roomID}", "insights", authUser, "Opt in / out of insights", ""},
+	{"POST", "/rooms/summarize/{roomID}", "summary", authUser, "Summarize recent messages", ""},
+	{"GET", "/rooms/webhooks/{roomID}", "webhooks", authUser, "Outgoing webhooks", ""},
+	{"POST", "/rooms/webhooks/{roomID}", "webhooks", authUser, "Create outgoing webhook", ""},
+	{"DELETE", "/rooms/webhooks/{roomID}/{webhookID}", "webhooks", authUser, "Delete outgoing webhook", ""},
+	{"GET", "/rooms/webhooks/{roomID}/{webhookID}/deliveries", "webhooks", authUser, "Webhook delivery log", ""},
+	{"GET", "/rooms/incoming-webhooks/{roomID}", "webhooks", authUser, "Incoming webhooks", ""},
+	{"POST", "/rooms/incoming-webhooks/{roomID}", "webhooks", authUser, "Create incoming webhook", ""},
+	{"DELETE", "/rooms/incoming-webhooks/{roomID}/{webhookID}", "webhooks", authUser, "Delete incoming webhook", ""},
+	{"POST", "/hooks/{token}", "webhooks", authNone, "Post via incoming webhook", ""},
+	{"POST", "/webhooks/{token}", "webhooks", authNone, "Post via incoming webhook (long URL)", ""},
+	{"GET", "/rooms/word-filter/{roomID}", "moderation", authUser, "Room word filter", ""},
+	{"POST", "/rooms/word-filter/{roomID}", "moderation", authUser, "Add room word filter", ""},
+	{"DELETE", "/rooms/word-filter/{roomID}/{filterID}", "moderation", authUser, "Delete room word filter", ""},
+
+	// ===== personal =====
+	{"GET", "/reminders", "reminders", authUser, "My reminders", "all"},
+	{"POST", "/reminders", "reminders", authUser, "Create reminder", ""},
+	{"DELETE", "/reminders/{reminderID}", "reminders", authUser, "Cancel reminder", ""},
+	{"POST", "/reminders/{reminderID}/snooze", "reminders", authUser, "Snooze reminder", ""},
+	{"GET", "/exports", "exports", authUser, "My data exports", ""},
+	{"POST", "/exports", "exports", authUser, "Request data export", ""},
+	{"GET", "/exports/{exportID}", "exports", authUser, "Export status", ""},
+	{"GET", "/exports/{exportID}/download", "exports", authNone, "Download export (signed URL)", "exp,sig"},
+	{"GET", "/announcements", "announcements", authUser, "Active announcements", ""},
+	{"POST", "/announcements/{announcementID}/ack", "announcements", authUser, "Acknowledge announcement", ""},
+	{"GET", "/surveys/runs/{runID}", "surveys", authUser, "Survey questions and my answers", ""},
+	{"POST", "/surveys/runs/{runID}", "surveys", authUser, "Answer survey", ""},
+	{"GET", "/reports", "moderation", authUser, "My reports and outcomes", "before_id,limit"},
+	{"POST", "/reports", "moderation", authUser, "Report message or user", ""},
+
+	// ===== push =====
+	{"POST", "/devices/register", "notifications", authUser, "Register push device", ""},
+	{"POST", "/devices/unregister", "notifications", authUser, "Unregister push device", ""},
+	{"GET", "/notifications/devices", "notifications", authUser, "Push devices", ""},
+	{"POST", "/notifications/devices", "notifications", authUser, "Add push device", ""},
+	{"DELETE", "/notifications/devices", "notifications", authUser, "Remove push device", ""},
+	{"GET", "/notifications/preferences", "notifications", authUser, "Push preferences", ""},
+	{"PUT", "/notifications/preferences", "notifications", authUser, "Update push preferences", ""},
+	{"GET", "/webpush/vapid-public-key", "notifications", authNone, "VAPID public key", ""},
+	{"POST", "/webpush/subscribe", "notifications", authUser, "Subscribe web push", ""},
+	{"POST", "/webpush/unsubscribe", "notifications", authUser, "Unsubscribe web push", ""},
+
+	// ===== bots =====
+	{"GET", "/bots", "bots", authUser, "My bots", ""},
+	{"POST", "/bots", "bots", authUser, "Create bot (returns api_key once)", ""},
+	{"DELETE", "/bots/{botID}", "bots", authUser, "Delete bot", ""},
+	{"POST", "/bots/{botID}/rotate-key", "bots", authUser, "Rotate bot api key", ""},
+	{"GET", "/bots/{botID}/deliveries", "bots", authUser, "Bot webhook deliveries", ""},
+	{"POST", "/bots/{botID}/messages", "bots", authBot, "Send message as bot", ""},
+	{"GET", "/bot/me", "bots", authBot, "Calling bot", ""},
+	{"PUT", "/bot/subscription", "bots", authBot, "Set bot webhook subscription", ""},
+	{"POST", "/bot/messages", "bots", authBot, "Send message as bot", ""},
+
+	// ===== media =====
+	{"GET", "/static/chat_uploads/{name}", "media", authUser, "Chat upload (signed URL, or member of the room it was uploaded to)", "exp,sig"},
+	{"GET", "/static/user_avatars/{name}", "media", authNone, "Avatar (signed URL)", "exp,sig"},
+	{"GET", "/media/download/{name}", "media", authUser, "Download chat file with original name", "exp,sig"},
+
+	// ===== provisioning =====
+	{"POST", "/provisioning/users/bulk", "provisioning", authProvisioning, "Upsert users from HR / IdP", ""},
+	{"POST", "/provisioning/users/deactivate", "provisioning", authProvisioning, "Deactivate users", ""},
+	{"GET", "/provisioning/users", "provisioning", authProvisioning, "Get provisioned user", "external_id"},
+
+	// ===== admin =====
+	{"GET", "/admin/get-all-user", "admin", authAdmin, "All users", ""},
+	{"POST", "/admin/users/role", "admin", authAdmin, "Change user role", ""},
+	{"GET", "/admin/users/limits", "admin", authAdmin, "User limit override", "user_id"},
+	{"PUT", "/admin/users/limits", "admin", authAdmin, "Set user limit override", ""},
+	{"DELETE", "/admin/users/limits", "admin", authAdmin, "Remove user limit override", "user_id"},
+	{"POST", "/admin/users/{userID}/suspend", "admin", authAdmin, "Suspend user", ""},
+	{"POST", "/admin/users/{userID}/unsuspend", "admin", authAdmin, "Lift suspension", ""},
+	{"GET", "/admin/audit", "admin", authAdmin, "Audit log", "actor_id,action,target_type,target_id,from,to,before_id,limit"},
+	{"GET", "/admin/rooms", "admin", authAdmin, "Search rooms", "type,q,min_members,max_members,inactive_days,no_owner,before_id,limit"},
+	{"GET", "/admin/rooms/{roomID}", "admin", authAdmin, "Room with members", ""},
+	{"DELETE", "/admin/rooms/{roomID}", "admin", authAdmin, "Force delete room", ""},
+	{"POST", "/admin/rooms/{roomID}/owner", "admin", authAdmin, "Transfer room owner", ""},
+	{"POST", "/admin/rooms/{roomID}/purge", "admin", authAdmin, "Purge room messages in a time range", ""},
+	{"DELETE", "/admin/messages/{messageID}", "admin", authAdmin, "Remove any message", ""},
+	{"GET", "/admin/moderation/queue", "admin", authAdmin, "Report queue", "status,type,before_id,limit"},
+	{"GET", "/admin/moderation/reports/{reportID}", "admin", authAdmin, "Report detail", ""},
+	{"POST", "/admin/moderation/reports/{reportID}/action", "admin", authAdmin, "Act on report", ""},
+	{"GET", "/admin/moderation/audit", "admin", authAdmin, "Moderation audit log", "before_id,limit"},
+	{"GET", "/admin/moderation/deletions", "admin", authAdmin, "Deleted message snapshots", "room_id,before_id,limit"},
+	{"GET", "/admin/word-filter", "admin", authAdmin, "Word filter", "room_id"},
+	{"POST", "/admin/word-filter", "admin", authAdmin, "Add word filter", ""},
+	{"DELETE", "/admin/word-filter/{filterID}", "admin", authAdmin, "Delete word filter", ""},
+	{"POST", "/admin/word-filter/test", "admin", authAdmin, "Test text against word filter", ""},
+	{"GET", "/admin/emojis", "admin", authAdmin, "Emoji catalog incl. inactive", ""},
+	{"POST", "/admin/emojis", "admin", authAdmin, "Add emoji (JSON unicode or multipart custom)", ""},
+	{"PATCH", "/admin/emojis/{code}", "admin", authAdmin, "Update emoji", ""},
+	{"DELETE", "/admin/emojis/{code}", "admin", authAdmin, "Delete emoji", ""},
+	{"GET", "/admin/announcements", "admin", authAdmin, "Announcements", "active,before_id,limit"},
+	{"POST", "/admin/announcements", "admin", authAdmin, "Create announcement", ""},
+	{"GET", "/admin/announcements/{announcementID}", "admin", authAdmin, "Announcement", ""},
+	{"GET", "/admin/announcements/{announcementID}/acks", "admin", authAdmin, "Announcement acknowledgements", "before_id,limit"},
+	{"DELETE", "/admin/announcements/{announcementID}", "admin", authAdmin, "Withdraw announcement", ""},
+	{"GET", "/admin/bots", "admin", authAdmin, "All bots", ""},
+	{"POST", "/admin/bots", "admin", authAdmin, "Create bot", ""},
+	{"DELETE", "/admin/bots/{botID}", "admin", authAdmin, "Delete bot", ""},
+	{"POST", "/admin/bots/{botID}/rotate-key", "admin", authAdmin, "Rotate bot api key", ""},
+	{"GET", "/admin/system-webhooks", "admin", authAdmin, "System webhooks", ""},
+	{"POST", "/admin/system-webhooks", "admin", authAdmin, "Create system webhook", ""},
+	{"DELETE", "/admin/system-webhooks/{webhookID}", "admin", authAdmin, "Delete system webhook", ""},
+	{"GET", "/admin/system-webhooks/{webhookID}/deliveries", "admin", authAdmin, "System webhook deliveries", ""},
+	{"GET", "/admin/ip-rules", "admin", authAdmin, "IP rules", ""},
+	{"POST", "/admin/ip-rules", "admin", authAdmin, "Create IP rule", ""},
+	{"DELETE", "/admin/ip-rules/{ruleID}", "admin", authAdmin, "Delete IP rule", ""},
+	{"GET", "/admin/ip-rules/check", "admin", authAdmin, "Check an IP against rules", "ip,scope"},
+	{"GET", "/admin/retention", "admin", authAdmin, "Retention policies", ""},
+	{"PUT", "/admin/retention", "admin", authAdmin, "Update retention policies", ""},
+	{"GET", "/admin/retention/preview", "admin", authAdmin, "Preview retention purge", ""},
+	{"POST", "/admin/retention/run", "admin", authAdmin, "Run retention purge now", ""},
+	{"GET", "/admin/exports", "admin", authAdmin, "Data exports", "user_id,before_id,limit"},
+	{"POST", "/admin/exports", "admin", authAdmin, "Export a user's data", ""},
+	{"GET", "/admin/surveys", "admin", authAdmin, "Surveys", ""},
+	{"POST", "/admin/surveys", "admin", authAdmin, "Create survey", ""},
+	{"GET", "/admin/surveys/{surveyID}", "admin", authAdmin, "Survey", ""},
+	{"PUT", "/admin/surveys/{surveyID}", "admin", authAdmin, "Update survey", ""},
+	{"DELETE", "/admin/surveys/{surveyID}", "admin", authAdmin, "Delete survey", ""},
+	{"POST", "/admin/surveys/{surveyID}/run", "admin", authAdmin, "Send survey now", ""},
+	{"GET", "/admin/surveys/{surveyID}/runs", "admin", authAdmin, "Survey runs", ""},
+	{"GET", "/admin/surveys/{surveyID}/report", "admin", authAdmin, "Survey report", "run_id"},
+	{"GET", "/admin/reports/{kind}", "admin", authAdmin, "Download report (user_growth, message_volume, storage)", "days,format"},
+	{"GET", "/admin/analytics/overview", "admin", authAdmin, "Analytics overview", ""},
+	{"GET", "/admin/analytics/{metric}", "admin", authAdmin, "Analytics series (dau, messages, new-users, active-rooms, storage)", "days"},
+	{"GET", "/admin/media/orphans", "admin", authAdmin, "List orphaned media (dry run)", ""},
+	{"POST", "/admin/media/orphans", "admin", authAdmin, "Delete orphaned media", ""},
+	{"POST", "/admin/webpush/vapid/rotate", "admin", authAdmin, "Rotate VAPID key", ""},
+	{"POST", "/admin/service-tokens", "admin", authAdmin, "Issue service token (user admin only)", ""},
+	{"GET", "/admin/api-keys", "admin", authAdmin, "API keys (prefix only, never the key)", ""},
+	{"POST", "/admin/api-keys", "admin", authAdmin, "Issue API key for a user with scopes send_message / read_rooms (user admin only, key returned once)", ""},
+	{"DELETE", "/admin/api-keys/{keyID}", "admin", authAdmin, "Revoke API key", ""},
+	{"GET", "/jobs", "jobs", authAdmin, "Scheduled jobs (admin or service scope jobs)", ""},
+	{"POST", "/jobs", "jobs", authAdmin, "Create job", ""},
+	{"GET", "/jobs/{jobID}", "jobs", authAdmin, "Job", ""},
+	{"PUT", "/jobs/{jobID}", "jobs", authAdmin, "Update job", ""},
+	{"DELETE", "/jobs/{jobID}", "jobs", authAdmin, "Delete job", ""},
+	{"POST", "/jobs/{jobID}/run", "jobs", authAdmin, "Run job now", ""},
+	{"GET", "/jobs/{jobID}/runs", "jobs", authAdmin, "Job runs", ""},
+}
+
+func (s *Server) mountOpenAPIRoutes(mux *http.ServeMux) {
+	// GET /openapi.json -> OpenAPI 3 của toàn bộ API
+	mux.Handle("/openapi.json", http.HandlerFunc(s.handleOpenAPI))
+	// GET /docs -> Swagger UI (tắt: OPENAPI_DOCS=0)
+	mux.Handle("/docs", http.HandlerFunc(s.handleSwaggerUI))
+}
+
+var (
+	openAPIOnce sync.Once
+	openAPIDoc  []byte
+)
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	openAPIOnce.Do(func() {
+		openAPIDoc, _ = json.Marshal(buildOpenAPI(apiRoutes))
+	})
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	_, _ = w.Write(openAPIDoc)
+}
+
+// buildOpenAPI: gom route theo path, path param lấy từ {x}, lỗi dùng chung schema Error (apiError)
+func buildOpenAPI(routes []apiRoute) map[string]any {
+	paths := map[string]map[string]any{}
+	tags := map[string]bool{}
+
+	for _, rt := range routes {
+		op := map[string]any{
+			"tags":        []string{rt.Tag},
+			"summary":     rt.Summary,
+			"operationId": operationID(rt.Method, rt.Path),
+			"responses": map[string]any{
+				"200":     map[string]any{"description": "OK"},
+				"default": map[string]any{"$ref": "#/components/responses/Error"},
+			},
+		}
+		tags[rt.Tag] = true
+
+		params := []map[string]any{}
+		for _, name := range pathParams(rt.Path) {
+			params = append(params, map[string]any{
+				"name": name, "in": "path", "required": true, "schema": paramSchema(name),
+			})
+		}
+		if rt.Query != "" {
+			for _, name := range strings.Split(rt.Query, ",") {
+				params = append(params, map[string]any{
+					"name": name, "in": "query", "schema": paramSchema(name),
+				})
+			}
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+
+		switch rt.Auth {
+		case authNone:
+			op["security"] = []any{}
+		case authUser, authAdmin:
+			op["security"] = []any{map[string]any{"bearerAuth": []string{}}}
+			if rt.Auth == authAdmin {
+				op["description"] = "Requires role admin (or a service token with scope admin)."
+			}
+			if scope := apiKeyScopeFor(rt.Method, rt.Path); rt.Auth == authUser && scope != "" {
+				op["security"] = append(op["security"].([]any), map[string]any{"apiKey": []string{}})
+				op["description"] = "Also accepts X-API-Key with scope " + scope + "."
+			}
+		case authBot:
+			op["security"] = []any{map[string]any{"botKey": []string{}}}
+		case authProvisioning:
+			op["security"] = []any{map[string]any{"provisioningToken": []string{}}}
+		case authCookie:
+			op["security"] = []any{map[string]any{"refreshCookie": []string{}}}
+		}
+		if rt.Method == http.MethodPost || rt.Method == http.MethodPut || rt.Method == http.MethodPatch {
+			op["requestBody"] = map[string]any{
+				"required": false,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": map[string]any{"type": "object"}},
+				},
+			}
+		}
+
+		if paths[rt.Path] == nil {
+			paths[rt.Path] = map[string]any{}
+		}
+		paths[rt.Path][strings.ToLower(rt.Method)] = op
+	}
+
+	tagList := []map[string]string{}
+	for t := range tags {
+		tagList = append(tagList, map[string]string{"name": t})
+	}
+	sort.Slice(tagList, func(i, j int) bool { return tagList[i]["name"] < tagList[j]["name"] })
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "CronChat API",
+			"version": "1.0.0",
+		},
+		"tags":     tagList,
+		"paths":    paths,
+		"security": []any{map[string]any{"bearerAuth": []string{}}},
+		"components": map[string]any{
+			"securitySchemes": map[string]any{
+				"bearerAuth":        map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"apiKey":            map[string]any{"type": "apiKey", "in": "header", "name": apiKeyHeader},
+				"botKey":            map[string]any{"type": "apiKey", "in": "header", "name": "Authorization", "description": "Bot <api_key>"},
+				"provisioningToken": map[string]any{"type": "http", "scheme": "bearer"},
+				"refreshCookie":     map[string]any{"type": "apiKey", "in": "cookie", "name": RefreshCookieName},
+			},
+			"schemas": map[string]any{
+				"Error": map[string]any{
+					"type":     "object",
+					"required": []string{"code", "message", "error"},
+					"properties": map[string]any{
+						"code":       map[string]any{"type": "string", "example": CodeNotFound},
+						"message":    map[string]any{"type": "string"},
+						"error":      map[string]any{"type": "string", "description": "same as message (older clients)"},
+						"details":    map[string]any{"type": "object"},
+						"request_id": map[string]any{"type": "string"},
+					},
+				},
+			},
+			"responses": map[string]any{
+				"Error": map[string]any{
+					"description": "Error",
+					"content": map[string]any{
+						"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Error"}},
+					},
+				},
+			},
+		},
+	}
+}
+
+// pathParams: "/rooms/{roomID}/members/{userID}" -> [roomID userID]
+func pathParams(path string) []string {
+	var out []string
+	for _, seg := range strings.Split(path, "/") {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			out = append(out, strings.Trim(seg, "{}"))
+		}
+	}
+	return out
+}
+
+func paramSchema(name string) map[string]string {
+	if strings.HasSuffix(name, "ID") || strings.HasSuffix(name, "_id") || name == "limit" || name == "days" || name == "hours" {
+		return map[string]string{"type": "integer"}
+	}
+	return map[string]string{"type": "string"}
+}
+
+// operationID: "GET /rooms/{roomID}/pins" -> "get_rooms_roomID_pins"
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, seg := range strings.Split(strings.Trim(path, "/"), "/") {
+		seg = strings.Trim(seg, "{}")
+		seg = strings.NewReplacer("-", "_", ".", "_").Replace(seg)
+		if seg != "" {
+			id += "_" + seg
+		}
+	}
+	return id
+}
+
+const swaggerUIPage = `<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>CronChat API</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+<script>SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});</script>
+</body>
+</html>`
+
+func (s *Server) handleSwaggerUI(w http.ResponseWriter, r *http.Request) {
+	if s.docsDisabled {
+		writeError(w, http.StatusNotFound, CodeNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(swaggerUIPage))
+}
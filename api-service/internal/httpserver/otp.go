@@ -0,0 +1,415 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/audit"
+	"cronhustler/api-service/internal/otp"
+	"cronhustler/api-service/internal/sms"
+	"cronhustler/api-service/internal/user"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mfa_token: phiên đăng nhập chờ nhập OTP
+const mfaTokenTTL = 10 * time.Minute
+
+func (s *Server) mountOTPRoutes(mux *http.ServeMux) {
+	// POST /login/otp {mfa_token, code} -> cấp token như /login
+	mux.HandleFunc("/login/otp", s.handleLoginOTP)
+	// POST /login/otp/resend {mfa_token}
+	mux.HandleFunc("/login/otp/resend", s.handleLoginOTPResend)
+
+	// GET  /me/phone             -> số điện thoại + đã xác minh chưa + 2FA
+	// POST /me/phone/verify/send -> gửi OTP tới số hiện tại
+	// POST /me/phone/verify {code}
+	mux.HandleFunc("/me/phone", s.handleMyPhone)
+	mux.HandleFunc("/me/phone/verify/send", s.handleSendPhoneVerification)
+	mux.HandleFunc("/me/phone/verify", s.handleVerifyPhone)
+	// PUT /me/2fa {enabled, password} -> bật/tắt OTP SMS khi đăng nhập (cần số đã xác minh)
+	mux.HandleFunc("/me/2fa", s.handleMyTwoFactor)
+}
+
+// SetSMSSender: provider SMS + mã vùng mặc định (số 0xxx -> +{countryCode}xxx)
+func (s *Server) SetSMSSender(sender sms.Sender, countryCode string) {
+	s.sms = sender
+	s.smsCountryCode = countryCode
+}
+
+func (s *Server) normalizePhone(phone string) string {
+	return sms.NormalizePhone(phone, s.smsCountryCode)
+}
+
+func mfaSignature(secret []byte, userID, exp int64) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "mfa\n%d\n%d", userID, exp)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// mfa token: {userID}.{exp}.{sig}
+func (s *Server) newMFAToken(userID int64) string {
+	exp := s.now().Add(mfaTokenTTL).Unix()
+	return fmt.Sprintf("%d.%d.%s", userID, exp, mfaSignature(s.jwtSecret, userID, exp))
+}
+
+func (s *Server) parseMFAToken(tok string) (int64, error) {
+	parts := strings.Split(tok, ".")
+	if len(parts) != 3 {
+		return 0, errors.New("invalid mfa_token")
+	}
+	userID, err1 := strconv.ParseInt(parts[0], 10, 64)
+	exp, err2 := strconv.ParseInt(parts[1], 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0, errors.New("invalid mfa_token")
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(mfaSignature(s.jwtSecret, userID, exp))) {
+		return 0, errors.New("invalid mfa_token")
+	}
+	if s.now().Unix() > exp {
+		return 0, errors.New("mfa_token expired, please log in again")
+	}
+	return userID, nil
+}
+
+// sendOTP: tạo mã + gửi SMS; false = đã trả lỗi
+func (s *Server) sendOTP(w http.ResponseWriter, r *http.Request, userID int64, purpose, phone string) bool {
+	if f := s.deliverOTP(r.Context(), userID, purpose, phone); f != nil {
+		f.write(w)
+		return false
+	}
+	return true
+}
+
+// deliverOTP: như sendOTP nhưng trả lỗi cho caller (nil = đã gửi)
+func (s *Server) deliverOTP(ctx context.Context, userID int64, purpose, phone string) *apiFailure {
+	if s.sms == nil {
+		return &apiFailure{status: http.StatusServiceUnavailable, body: map[string]string{"error": "sms is not configured"}}
+	}
+	code, err := s.otpRepo.Issue(ctx, userID, purpose, phone, s.now())
+	if err != nil {
+		if errors.Is(err, otp.ErrResendTooSoon) || errors.Is(err, otp.ErrHourlyLimit) {
+			return &apiFailure{status: http.StatusTooManyRequests, body: map[string]string{"error": err.Error()}}
+		}
+		log.Println("otp Issue error:", err)
+		return &apiFailure{status: http.StatusInternalServerError, body: map[string]string{"error": "db error"}}
+	}
+
+	body := fmt.Sprintf("CronChat: ma xac nhan cua ban la %s (hieu luc %d phut). Khong chia se ma nay.",
+		code, int(otp.CodeTTL.Minutes()))
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	if err := s.sms.Send(ctx, phone, body); err != nil {
+		log.Printf("[sms] send user=%d: %v", userID, err)
+		return &apiFailure{status: http.StatusBadGateway, body: map[string]string{"error": "failed to send sms"}}
+	}
+	return nil
+}
+
+// startLoginOTP: user bật 2FA SMS -> gửi OTP, trả mfa_token thay cho access token.
+// false = không cần OTP, login tiếp như thường
+func (s *Server) startLoginOTP(w http.ResponseWriter, r *http.Request, u *user.User) bool {
+	ps, err := s.otpRepo.GetSecurity(r.Context(), int64(u.ID))
+	if err != nil {
+		// không đọc được trạng thái 2FA -> không cho qua
+		log.Println("otp GetSecurity error:", err)
+		writeJSON(w, http.StatusInternalServerError, loginResponse{Error: "internal error"})
+		return true
+	}
+	if !ps.SMSTwoFactor || ps.VerifiedPhone == "" {
+		return false
+	}
+
+	if !s.sendOTP(w, r, int64(u.ID), otp.PurposeLogin, ps.VerifiedPhone) {
+		return true
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"mfa_required": true,
+		"mfa_token":    s.newMFAToken(int64(u.ID)),
+		"phone":        sms.MaskPhone(ps.VerifiedPhone),
+		"expires_in":   int(otp.CodeTTL.Seconds()),
+	})
+	return true
+}
+
+type loginOTPRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+// loginOTPUser: parse mfa_token + check tài khoản vẫn dùng được
+func (s *Server) loginOTPUser(w http.ResponseWriter, r *http.Request, req loginOTPRequest) *user.User {
+	userID, err := s.parseMFAToken(req.MFAToken)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, loginResponse{Error: err.Error()})
+		return nil
+	}
+	u, err := s.userRepo.GetUserByID(int(userID))
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, loginResponse{Error: "invalid credentials"})
+		return nil
+	}
+	if u.Is_active == 0 {
+		writeJSON(w, http.StatusForbidden, loginResponse{Error: "account is locked or disabled"})
+		return nil
+	}
+	if s.rejectIfSuspended(w, r, userID) {
+		return nil
+	}
+	return u
+}
+
+func (s *Server) handleLoginOTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	var req loginOTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, loginResponse{Error: "invalid JSON"})
+		return
+	}
+	u := s.loginOTPUser(w, r, req)
+	if u == nil {
+		return
+	}
+
+	if _, err := s.otpRepo.Verify(r.Context(), int64(u.ID), otp.PurposeLogin, strings.TrimSpace(req.Code), s.now()); err != nil {
+		if errors.Is(err, otp.ErrInvalidCode) || errors.Is(err, otp.ErrTooManyAttempts) {
+			if err := s.userRepo.RecordLogin(int64(u.ID), getIP(r), r.UserAgent(), false); err != nil {
+				log.Println("RecordLogin error:", err)
+			}
+			writeJSON(w, http.StatusUnauthorized, loginResponse{Error: err.Error()})
+			return
+		}
+		log.Println("otp Verify error:", err)
+		writeJSON(w, http.StatusInternalServerError, loginResponse{Error: "internal error"})
+		return
+	}
+
+	s.completeLogin(w, r, u)
+}
+
+func (s *Server) handleLoginOTPResend(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	var req loginOTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, loginResponse{Error: "invalid JSON"})
+		return
+	}
+	u := s.loginOTPUser(w, r, req)
+	if u == nil {
+		return
+	}
+
+	ps, err := s.otpRepo.GetSecurity(r.Context(), int64(u.ID))
+	if err != nil {
+		log.Println("otp GetSecurity error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	if !ps.SMSTwoFactor || ps.VerifiedPhone == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "two-factor is not enabled"})
+		return
+	}
+	if !s.sendOTP(w, r, int64(u.ID), otp.PurposeLogin, ps.VerifiedPhone) {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"sent": true, "phone": sms.MaskPhone(ps.VerifiedPhone)})
+}
+
+// =======================================
+// /me/phone, /me/2fa
+// =======================================
+
+// currentPhone: số trong profile (đã chuẩn hoá) + trạng thái bảo mật
+func (s *Server) currentPhone(w http.ResponseWriter, r *http.Request, userID int64) (string, *otp.PhoneSecurity, bool) {
+	u, err := s.userRepo.GetUserByID(int(userID))
+	if err != nil {
+		log.Println("GetUserByID error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return "", nil, false
+	}
+	ps, err := s.otpRepo.GetSecurity(r.Context(), userID)
+	if err != nil {
+		log.Println("otp GetSecurity error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return "", nil, false
+	}
+	return s.normalizePhone(nsToString(u.Phone)), ps, true
+}
+
+func (s *Server) handleMyPhone(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+	phone, ps, ok := s.currentPhone(w, r, userID)
+	if !ok {
+		return
+	}
+
+	resp := map[string]any{
+		"phone":    phone,
+		"verified": phone != "" && phone == ps.VerifiedPhone,
+		"sms_2fa":  ps.SMSTwoFactor,
+	}
+	if ps.VerifiedAt != nil && phone == ps.VerifiedPhone {
+		resp["verified_at"] = ps.VerifiedAt
+	}
+	// 2FA vẫn gửi OTP về số cũ đã xác minh cho tới khi số mới được xác minh
+	if ps.SMSTwoFactor && ps.VerifiedPhone != "" && phone != ps.VerifiedPhone {
+		resp["otp_phone"] = sms.MaskPhone(ps.VerifiedPhone)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleSendPhoneVerification(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+	phone, ps, ok := s.currentPhone(w, r, userID)
+	if !ok {
+		return
+	}
+	if phone == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "no phone number on profile"})
+		return
+	}
+	if phone == ps.VerifiedPhone {
+		writeJSON(w, http.StatusOK, map[string]any{"verified": true})
+		return
+	}
+
+	if !s.sendOTP(w, r, userID, otp.PurposeVerifyPhone, phone) {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"sent":       true,
+		"phone":      sms.MaskPhone(phone),
+		"expires_in": int(otp.CodeTTL.Seconds()),
+	})
+}
+
+func (s *Server) handleVerifyPhone(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Code) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "code is required"})
+		return
+	}
+
+	ctx := r.Context()
+	now := s.now()
+	sentTo, err := s.otpRepo.Verify(ctx, userID, otp.PurposeVerifyPhone, strings.TrimSpace(req.Code), now)
+	if err != nil {
+		if errors.Is(err, otp.ErrInvalidCode) || errors.Is(err, otp.ErrTooManyAttempts) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		log.Println("otp Verify error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	// user đổi số sau khi nhận mã -> mã không còn khớp số hiện tại
+	phone, _, ok := s.currentPhone(w, r, userID)
+	if !ok {
+		return
+	}
+	if phone != sentTo {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "phone number changed, request a new code"})
+		return
+	}
+
+	if err := s.otpRepo.MarkPhoneVerified(ctx, userID, phone, now); err != nil {
+		log.Println("otp MarkPhoneVerified error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"verified": true, "phone": phone, "verified_at": now})
+}
+
+func (s *Server) handleMyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+	var req struct {
+		Enabled  bool   `json:"enabled"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+
+	u, err := s.userRepo.GetUserByID(int(userID))
+	if err != nil {
+		log.Println("GetUserByID error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	if req.Password == "" || !s.verifyPassword(userID, u.Password, req.Password) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid password"})
+		return
+	}
+
+	if req.Enabled {
+		phone, ps, ok := s.currentPhone(w, r, userID)
+		if !ok {
+			return
+		}
+		if phone == "" || phone != ps.VerifiedPhone {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "verify your phone number first"})
+			return
+		}
+	}
+
+	if err := s.otpRepo.SetSMSTwoFactor(r.Context(), userID, req.Enabled); err != nil {
+		log.Println("otp SetSMSTwoFactor error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	s.recordAudit(r, userID, audit.ActionTwoFactorChange, audit.TargetUser, userID, map[string]any{
+		"method":  "sms",
+		"enabled": req.Enabled,
+	})
+	writeJSON(w, http.StatusOK, map[string]any{"sms_2fa": req.Enabled})
+}
@@ -0,0 +1,87 @@
+package httpserver
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DefaultPasswordCost: bcrypt cost khi không cấu hình PASSWORD_HASH_COST (mỗi +1 chậm gấp đôi)
+const DefaultPasswordCost = 12
+
+// bcrypt chỉ dùng 72 byte đầu -> dài hơn thì từ chối thay vì cắt ngầm
+const maxPasswordBytes = 72
+
+var errPasswordTooLong = errors.New("password must be at most 72 bytes")
+
+// passwordCostOrDefault: 0 / ngoài khoảng bcrypt cho phép -> DefaultPasswordCost
+func passwordCostOrDefault(cost int) int {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return DefaultPasswordCost
+	}
+	return cost
+}
+
+// hashPassword: bcrypt, salt ngẫu nhiên nằm sẵn trong chuỗi hash
+func hashPassword(pw string, cost int) (string, error) {
+	if len(pw) > maxPasswordBytes {
+		return "", errPasswordTooLong
+	}
+	h, err := bcrypt.GenerateFromPassword([]byte(pw), cost)
+	if err != nil {
+		return "", err
+	}
+	return string(h), nil
+}
+
+// passwordHash: hash mật khẩu mới theo cost đang cấu hình
+func (s *Server) passwordHash(pw string) (string, error) {
+	return hashPassword(pw, s.passwordCost)
+}
+
+// isLegacyPasswordHash: hash cũ = sha256 hex không salt (64 ký tự hex)
+func isLegacyPasswordHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+// checkPassword: so mật khẩu với hash đã lưu
+// needsRehash = đúng mật khẩu nhưng hash cũ (sha256) hoặc cost thấp hơn cấu hình hiện tại
+func (s *Server) checkPassword(hash, pw string) (ok, needsRehash bool) {
+	if isLegacyPasswordHash(hash) {
+		sum := sha256.Sum256([]byte(pw))
+		ok = subtle.ConstantTimeCompare([]byte(hash), []byte(hex.EncodeToString(sum[:]))) == 1
+		return ok, ok
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(pw)) != nil {
+		return false, false
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return true, err == nil && cost < s.passwordCost
+}
+
+// verifyPassword: checkPassword + lưu lại hash mới khi cần (user cũ tự chuyển sang bcrypt lúc login)
+// lưu lỗi chỉ log, lần đăng nhập sau thử lại
+func (s *Server) verifyPassword(userID int64, hash, pw string) bool {
+	ok, needsRehash := s.checkPassword(hash, pw)
+	if !ok || !needsRehash {
+		return ok
+	}
+
+	newHash, err := s.passwordHash(pw)
+	if err != nil {
+		log.Println("rehash password error:", err)
+		return true
+	}
+	if err := s.userRepo.UpdateUserDynamic(userID, map[string]interface{}{"password": newHash}); err != nil {
+		log.Println("rehash password error:", err)
+	}
+	return true
+}
@@ -0,0 +1,440 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/audit"
+	"cronhustler/api-service/internal/provisioning"
+	"cronhustler/api-service/internal/user"
+	"cronhustler/api-service/internal/webhook"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const adminSystemWebhooksPrefix = "/admin/system-webhooks/"
+
+func (s *Server) mountProvisioningRoutes(mux *http.ServeMux) {
+	// hệ thống HR / IdP, header Authorization: Bearer <PROVISIONING_TOKEN>
+	// POST /provisioning/users/bulk       {users: [{external_id, username, full_name, email, phone, role, active}]}
+	// POST /provisioning/users/deactivate {external_ids: [...]}
+	// GET  /provisioning/users?external_id=
+	mux.Handle("/provisioning/users/bulk", s.requireProvisioningToken(http.HandlerFunc(s.handleProvisionUsers)))
+	mux.Handle("/provisioning/users/deactivate", s.requireProvisioningToken(http.HandlerFunc(s.handleDeprovisionUsers)))
+	mux.Handle("/provisioning/users", s.requireProvisioningToken(http.HandlerFunc(s.handleGetProvisionedUser)))
+
+	// system webhook (event vòng đời user): user_created, user_updated, user_suspended, user_deactivated
+	// GET  /admin/system-webhooks
+	// POST /admin/system-webhooks                 {url, events}
+	// DELETE /admin/system-webhooks/{id}
+	// GET  /admin/system-webhooks/{id}/deliveries
+	mux.Handle("/admin/system-webhooks", s.RequireAdmin(http.HandlerFunc(s.handleSystemWebhooks)))
+	mux.Handle(adminSystemWebhooksPrefix, s.RequireAdmin(http.HandlerFunc(s.handleSystemWebhookItem)))
+}
+
+func (s *Server) requireProvisioningToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.provisionToken == "" {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "provisioning is not enabled"})
+			return
+		}
+		token := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.provisionToken)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid provisioning token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ===== user event -> system webhook =====
+
+// userEventPayload: không kèm password / IP
+func userEventPayload(u *user.User, externalID string) map[string]any {
+	return map[string]any{
+		"user_id":     u.ID,
+		"username":    u.Username,
+		"full_name":   nsToString(u.Full_name),
+		"email":       nsToString(u.Email),
+		"phone":       nsToString(u.Phone),
+		"role":        u.Role,
+		"is_active":   u.Is_active == 1,
+		"external_id": externalID,
+	}
+}
+
+// emitUserEvent: đọc lại user sau khi đổi rồi bắn event, không block request
+func (s *Server) emitUserEvent(event string, userID int64, extra map[string]any) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		u, err := s.userRepo.GetUserByID(int(userID))
+		if err != nil {
+			log.Printf("[webhook] %s user=%d: %v", event, userID, err)
+			return
+		}
+		ext, err := s.provisioningRepo.ExternalIDByUser(ctx, userID)
+		if err != nil {
+			log.Println("ExternalIDByUser error:", err)
+		}
+		data := userEventPayload(u, ext)
+		for k, v := range extra {
+			data[k] = v
+		}
+		if err := s.webhooks.EmitSystem(ctx, event, data); err != nil {
+			log.Printf("[webhook] emit %s user=%d: %v", event, userID, err)
+		}
+	}()
+}
+
+// ===== Provisioning API =====
+
+type provisionUserItem struct {
+	ExternalID string  `json:"external_id"`
+	Username   string  `json:"username"` // chỉ dùng khi tạo mới
+	FullName   *string `json:"full_name"`
+	Email      *string `json:"email"`
+	Phone      *string `json:"phone"`
+	Role       string  `json:"role"`   // user | admin, rỗng = giữ nguyên (tạo mới: user)
+	Active     *bool   `json:"active"` // nil = giữ nguyên (tạo mới: true)
+}
+
+type provisionResult struct {
+	ExternalID string `json:"external_id"`
+	UserID     int64  `json:"user_id,omitempty"`
+	Status     string `json:"status"` // created | updated | unchanged | deactivated | not_found | error
+	Error      string `json:"error,omitempty"`
+}
+
+func (s *Server) handleProvisionUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	var req struct {
+		Users []provisionUserItem `json:"users"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+	if len(req.Users) == 0 || len(req.Users) > provisioning.MaxBatch {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "users must have 1.." + strconv.Itoa(provisioning.MaxBatch) + " items"})
+		return
+	}
+
+	results := make([]provisionResult, 0, len(req.Users))
+	for _, it := range req.Users {
+		results = append(results, s.provisionUser(r, it))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"results": results})
+}
+
+// provisionUser: idempotent theo external_id (chưa có -> tạo, có rồi -> cập nhật field gửi lên)
+func (s *Server) provisionUser(r *http.Request, it provisionUserItem) provisionResult {
+	ctx := r.Context()
+	ext, err := provisioning.ValidateExternalID(it.ExternalID)
+	if err != nil {
+		return provisionResult{ExternalID: it.ExternalID, Status: "error", Error: err.Error()}
+	}
+	res := provisionResult{ExternalID: ext}
+
+	it.Role = strings.TrimSpace(it.Role)
+	if it.Role != "" && it.Role != "user" && it.Role != "admin" {
+		res.Status, res.Error = "error", "role must be user or admin"
+		return res
+	}
+	if it.Email != nil {
+		*it.Email = strings.TrimSpace(*it.Email)
+		if *it.Email != "" && !isValidEmail(*it.Email) {
+			res.Status, res.Error = "error", "invalid email"
+			return res
+		}
+	}
+	if it.Phone != nil {
+		*it.Phone = strings.TrimSpace(*it.Phone)
+		if *it.Phone != "" && !isValidPhone(*it.Phone) {
+			res.Status, res.Error = "error", "invalid phone"
+			return res
+		}
+	}
+
+	userID, err := s.provisioningRepo.UserIDByExternal(ctx, ext)
+	if errors.Is(err, provisioning.ErrNotFound) {
+		userID, err = s.createProvisionedUser(r, ext, it)
+		if err == nil {
+			res.UserID, res.Status = userID, "created"
+			s.emitUserEvent(webhook.EventUserCreated, userID, map[string]any{"source": "provisioning"})
+			return res
+		}
+		// request khác vừa tạo cùng external_id -> xử lý như update
+		if errors.Is(err, provisioning.ErrExternalIDTaken) {
+			userID, err = s.provisioningRepo.UserIDByExternal(ctx, ext)
+		}
+	}
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "provisioning:") {
+			res.Status, res.Error = "error", err.Error()
+			return res
+		}
+		log.Println("provision user error:", err)
+		res.Status, res.Error = "error", "db error"
+		return res
+	}
+
+	res.UserID = userID
+	res.Status, err = s.updateProvisionedUser(userID, it)
+	if err != nil {
+		log.Println("provision update error:", err)
+		res.Status, res.Error = "error", "db error"
+	}
+	return res
+}
+
+func (s *Server) createProvisionedUser(r *http.Request, ext string, it provisionUserItem) (int64, error) {
+	username := strings.TrimSpace(it.Username)
+	if username == "" || len(username) > 50 {
+		return 0, errors.New("provisioning: username is required when creating a user (max 50 chars)")
+	}
+
+	// mật khẩu ngẫu nhiên không ai biết: user đăng nhập qua reset password / SSO phía HR
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return 0, err
+	}
+	hash, err := s.passwordHash(hex.EncodeToString(b))
+	if err != nil {
+		return 0, err
+	}
+	nu := &provisioning.NewUser{
+		ExternalID:   ext,
+		Username:     username,
+		PasswordHash: hash,
+		Role:         "user",
+		Active:       it.Active == nil || *it.Active,
+	}
+	if it.Role != "" {
+		nu.Role = it.Role
+	}
+	if it.FullName != nil {
+		nu.FullName = strings.TrimSpace(*it.FullName)
+	}
+	if it.Email != nil {
+		nu.Email = *it.Email
+	}
+	if it.Phone != nil {
+		nu.Phone = *it.Phone
+	}
+	return s.provisioningRepo.Create(r.Context(), nu, getIP(r))
+}
+
+// updateProvisionedUser: chỉ ghi field thật sự đổi, không đụng superadmin
+func (s *Server) updateProvisionedUser(userID int64, it provisionUserItem) (string, error) {
+	u, err := s.userRepo.GetUserByID(int(userID))
+	if err != nil {
+		return "", err
+	}
+
+	fields := map[string]interface{}{}
+	if it.FullName != nil && strings.TrimSpace(*it.FullName) != nsToString(u.Full_name) {
+		fields["full_name"] = strings.TrimSpace(*it.FullName)
+	}
+	if it.Email != nil && *it.Email != nsToString(u.Email) {
+		fields["email"] = *it.Email
+	}
+	if it.Phone != nil && *it.Phone != nsToString(u.Phone) {
+		fields["phone"] = *it.Phone
+	}
+	if it.Role != "" && it.Role != u.Role && u.Role != "superadmin" {
+		fields["role"] = it.Role
+	}
+	deactivated := false
+	if it.Active != nil {
+		active := 0
+		if *it.Active {
+			active = 1
+		}
+		if active != u.Is_active {
+			fields["is_active"] = active
+			deactivated = active == 0
+		}
+	}
+	if len(fields) == 0 {
+		return "unchanged", nil
+	}
+	if err := s.userRepo.UpdateUserDynamic(userID, fields); err != nil {
+		return "", err
+	}
+
+	if deactivated {
+		s.emitUserEvent(webhook.EventUserDeactivated, userID, map[string]any{"source": "provisioning"})
+		return "deactivated", nil
+	}
+	s.emitUserEvent(webhook.EventUserUpdated, userID, map[string]any{"source": "provisioning", "changed": mapKeys(fields)})
+	return "updated", nil
+}
+
+func (s *Server) handleDeprovisionUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	var req struct {
+		ExternalIDs []string `json:"external_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+	if len(req.ExternalIDs) == 0 || len(req.ExternalIDs) > provisioning.MaxBatch {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "external_ids must have 1.." + strconv.Itoa(provisioning.MaxBatch) + " items"})
+		return
+	}
+
+	inactive := false
+	results := make([]provisionResult, 0, len(req.ExternalIDs))
+	for _, raw := range req.ExternalIDs {
+		ext, err := provisioning.ValidateExternalID(raw)
+		if err != nil {
+			results = append(results, provisionResult{ExternalID: raw, Status: "error", Error: err.Error()})
+			continue
+		}
+		userID, err := s.provisioningRepo.UserIDByExternal(r.Context(), ext)
+		if errors.Is(err, provisioning.ErrNotFound) {
+			results = append(results, provisionResult{ExternalID: ext, Status: "not_found"})
+			continue
+		}
+		if err != nil {
+			log.Println("UserIDByExternal error:", err)
+			results = append(results, provisionResult{ExternalID: ext, Status: "error", Error: "db error"})
+			continue
+		}
+
+		status, err := s.updateProvisionedUser(userID, provisionUserItem{Active: &inactive})
+		if err != nil {
+			log.Println("deprovision error:", err)
+			results = append(results, provisionResult{ExternalID: ext, UserID: userID, Status: "error", Error: "db error"})
+			continue
+		}
+		results = append(results, provisionResult{ExternalID: ext, UserID: userID, Status: status})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"results": results})
+}
+
+func (s *Server) handleGetProvisionedUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	ext, err := provisioning.ValidateExternalID(r.URL.Query().Get("external_id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	userID, err := s.provisioningRepo.UserIDByExternal(r.Context(), ext)
+	if err == nil {
+		var u *user.User
+		if u, err = s.userRepo.GetUserByID(int(userID)); err == nil {
+			writeJSON(w, http.StatusOK, userEventPayload(u, ext))
+			return
+		}
+	}
+	if errors.Is(err, provisioning.ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "user not found"})
+		return
+	}
+	log.Println("get provisioned user error:", err)
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+}
+
+func mapKeys(m map[string]interface{}) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
+
+// ===== System webhooks (admin) =====
+
+func (s *Server) handleSystemWebhooks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		list, err := s.webhookRepo.ListSystem(r.Context())
+		if err != nil {
+			log.Println("ListSystem webhooks error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"webhooks": list})
+	case http.MethodPost:
+		var req createWebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+			return
+		}
+		adminID, _ := UserIDFromContext(r.Context())
+		wh := &webhook.Webhook{URL: strings.TrimSpace(req.URL), Secret: req.Secret, Events: req.Events, CreatedBy: adminID}
+		if err := s.webhookRepo.CreateSystem(r.Context(), wh); err != nil {
+			if errors.Is(err, webhook.ErrInvalidURL) || errors.Is(err, webhook.ErrBlockedURL) || errors.Is(err, webhook.ErrInvalidEvents) {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			log.Println("CreateSystem webhook error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		s.recordAudit(r, adminID, audit.ActionConfigChange, audit.TargetConfig, wh.ID, map[string]any{
+			"key":    "system_webhook.create",
+			"url":    wh.URL,
+			"events": wh.Events,
+		})
+		writeJSON(w, http.StatusCreated, wh)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func (s *Server) handleSystemWebhookItem(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, adminSystemWebhooksPrefix), "/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid webhook id"})
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		if err := s.webhookRepo.DeleteSystem(r.Context(), id); err != nil {
+			if errors.Is(err, webhook.ErrNotFound) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "webhook not found"})
+				return
+			}
+			log.Println("DeleteSystem webhook error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		adminID, _ := UserIDFromContext(r.Context())
+		s.recordAudit(r, adminID, audit.ActionConfigChange, audit.TargetConfig, id, map[string]any{
+			"key": "system_webhook.delete",
+		})
+		writeJSON(w, http.StatusOK, map[string]any{"deleted": id})
+	case len(parts) == 2 && parts[1] == "deliveries" && r.Method == http.MethodGet:
+		list, err := s.webhookRepo.ListSystemDeliveries(r.Context(), id, 50)
+		if err != nil {
+			log.Println("ListSystemDeliveries error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"deliveries": list})
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
@@ -0,0 +1,302 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/push"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// độ dài preview trong push (rune)
+const pushPreviewLen = 100
+
+func (s *Server) mountPushRoutes(mux *http.ServeMux) {
+	// POST /devices/register   {platform: fcm|apns, token}
+	mux.Handle("/devices/register", http.HandlerFunc(s.handleRegisterDevice))
+	// POST /devices/unregister {token}
+	mux.Handle("/devices/unregister", http.HandlerFunc(s.handleUnregisterDevice))
+
+	// GET /notifications/devices | POST {platform, token} | DELETE {token}
+	mux.Handle("/notifications/devices", http.HandlerFunc(s.handleNotificationDevices))
+	// GET /notifications/preferences | PUT {mute_all, mute_all_minutes, mute_rooms, unmute_rooms}
+	mux.Handle("/notifications/preferences", http.HandlerFunc(s.handleNotificationPreferences))
+
+	s.mountWebPushRoutes(mux)
+}
+
+type registerDeviceRequest struct {
+	Platform string `json:"platform"` // fcm | apns
+	Token    string `json:"token"`
+}
+
+func (s *Server) handleRegisterDevice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var req registerDeviceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+	req.Token = strings.TrimSpace(req.Token)
+	if req.Token == "" || len(req.Token) > 512 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "token is required"})
+		return
+	}
+
+	if err := s.pushRepo.UpsertToken(r.Context(), userID, req.Platform, req.Token); err != nil {
+		if errors.Is(err, push.ErrInvalidPlatform) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "platform must be fcm or apns"})
+			return
+		}
+		log.Println("UpsertToken error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+func (s *Server) handleUnregisterDevice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var req registerDeviceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Token) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "token is required"})
+		return
+	}
+
+	if err := s.pushRepo.DeleteToken(r.Context(), userID, req.Token); err != nil {
+		log.Println("DeleteToken error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+func (s *Server) handleNotificationDevices(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		s.handleRegisterDevice(w, r)
+	case http.MethodDelete:
+		s.handleUnregisterDevice(w, r)
+	case http.MethodGet:
+		userID, err := UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
+		}
+		devices, err := s.pushRepo.ListByUser(r.Context(), userID)
+		if err != nil {
+			log.Println("ListByUser error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		if devices == nil {
+			devices = []push.DeviceToken{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"devices": devices})
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+type updatePushPrefsRequest struct {
+	MuteAll        *bool   `json:"mute_all"`         // nil = giữ nguyên
+	MuteAllMinutes int     `json:"mute_all_minutes"` // > 0: tự bật lại sau N phút
+	MuteRooms      []int64 `json:"mute_rooms"`
+	UnmuteRooms    []int64 `json:"unmute_rooms"`
+}
+
+func (s *Server) handleNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if r.Method == http.MethodPut {
+		var req updatePushPrefsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+			return
+		}
+		if req.MuteAllMinutes < 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "mute_all_minutes must be >= 0"})
+			return
+		}
+		for _, roomID := range req.MuteRooms {
+			ok, err := s.roomRepo.IsUserInRoom(roomID, userID)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+				return
+			}
+			if !ok || roomID <= 0 {
+				writeJSON(w, http.StatusForbidden, map[string]string{"error": "you are not a member of room " + strconv.FormatInt(roomID, 10)})
+				return
+			}
+		}
+
+		if req.MuteAll != nil {
+			if *req.MuteAll {
+				var until *time.Time
+				if req.MuteAllMinutes > 0 {
+					t := s.now().Add(time.Duration(req.MuteAllMinutes) * time.Minute)
+					until = &t
+				}
+				err = s.pushRepo.SetMute(ctx, userID, push.MuteAllRooms, until)
+			} else {
+				err = s.pushRepo.ClearMute(ctx, userID, push.MuteAllRooms)
+			}
+			if err != nil {
+				log.Println("SetMute error:", err)
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+				return
+			}
+		}
+		for _, roomID := range req.MuteRooms {
+			if err := s.pushRepo.SetMute(ctx, userID, roomID, nil); err != nil {
+				log.Println("SetMute error:", err)
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+				return
+			}
+		}
+		for _, roomID := range req.UnmuteRooms {
+			if err := s.pushRepo.ClearMute(ctx, userID, roomID); err != nil {
+				log.Println("ClearMute error:", err)
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+				return
+			}
+		}
+	}
+
+	prefs, err := s.pushRepo.GetPreferences(ctx, userID)
+	if err != nil {
+		log.Println("GetPreferences error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, prefs)
+}
+
+// EnablePush: bật worker gửi push (fcm / apns / web nil = tắt platform đó)
+func (s *Server) EnablePush(ctx context.Context, fcm, apns push.Sender, web *push.WebPushSender) {
+	svc := push.NewService(s.pushRepo, fcm, apns)
+	svc.WebPush = web
+	svc.Start(ctx)
+	s.pusher = svc
+}
+
+// wsIsOnline: user còn ít nhất 1 kết nối WS
+func wsIsOnline(userID int64) bool {
+	wsByUserMu.RLock()
+	defer wsByUserMu.RUnlock()
+	return len(wsByUser[userID]) > 0
+}
+
+// pushOfflineRecipients: người nhận không có WS + không tắt push -> push (mention nếu content có @username)
+// resp.Content chỉ bị ký với image/file, mà 2 loại này không dùng content làm preview
+func (s *Server) pushOfflineRecipients(roomID int64, roomName, roomType string, recipients []int64, resp sendMessageResponse) {
+	if s.pusher == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// tắt push room này / tắt tất cả -> bỏ qua (lỗi thì vẫn gửi)
+	muted, err := s.pushRepo.MutedUsers(ctx, roomID, recipients)
+	if err != nil {
+		log.Println("MutedUsers error:", err)
+	}
+
+	preview := pushPreview(resp.MessageType, resp.Content)
+	lowerContent := strings.ToLower(resp.Content)
+
+	for _, uid := range recipients {
+		if muted[uid] || wsIsOnline(uid) {
+			continue
+		}
+
+		kind := "message"
+		title := resp.SenderName
+		body := preview
+		if roomType == "group" {
+			title = roomName
+			body = resp.SenderName + ": " + preview
+		}
+
+		if u, err := s.userRepo.GetUserByID(int(uid)); err == nil && u.Username != "" &&
+			strings.Contains(lowerContent, "@"+strings.ToLower(u.Username)) {
+			kind = "mention"
+			title = resp.SenderName + " đã nhắc đến bạn"
+			if roomType == "group" {
+				title += " trong " + roomName
+			}
+			body = preview
+		}
+
+		err := s.pusher.Enqueue(uid, push.Notification{
+			Title:       title,
+			Body:        body,
+			CollapseKey: "room-" + strconv.FormatInt(roomID, 10),
+			Data: map[string]string{
+				"type":       kind,
+				"room_id":    strconv.FormatInt(roomID, 10),
+				"message_id": strconv.FormatInt(resp.ID, 10),
+				"sent_at":    resp.CreatedAt,
+			},
+		})
+		if err != nil {
+			log.Printf("[push] enqueue user=%d: %v", uid, err)
+		}
+	}
+}
+
+func pushPreview(messageType, content string) string {
+	switch messageType {
+	case "image":
+		return "📷 Hình ảnh"
+	case "file":
+		return "📎 Tệp đính kèm"
+	case "audio":
+		return "🎤 Tin nhắn thoại"
+	}
+
+	r := []rune(strings.TrimSpace(content))
+	if len(r) > pushPreviewLen {
+		return string(r[:pushPreviewLen]) + "…"
+	}
+	return string(r)
+}
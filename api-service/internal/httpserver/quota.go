@@ -0,0 +1,188 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/audit"
+	"cronhustler/api-service/internal/quota"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func (s *Server) mountQuotaRoutes(mux *http.ServeMux) {
+	// GET /me/limits -> giới hạn + đã dùng + còn lại của user hiện tại
+	mux.Handle("/me/limits", http.HandlerFunc(s.handleMyLimits))
+	// GET    /admin/users/limits?user_id= -> override + trạng thái
+	// PUT    /admin/users/limits {user_id, messages_per_minute, messages_per_day, rooms_per_day} (null = mặc định, 0 = không giới hạn)
+	// DELETE /admin/users/limits?user_id= -> bỏ override
+	mux.Handle("/admin/users/limits", s.RequireAdmin(http.HandlerFunc(s.handleAdminUserLimits)))
+}
+
+// rejectIfOverQuota: 429 + Retry-After khi vượt giới hạn; lỗi DB không chặn user
+func (s *Server) rejectIfOverQuota(w http.ResponseWriter, r *http.Request, userID int64, kinds ...string) bool {
+	if f := s.quotaFailure(r.Context(), userID, kinds...); f != nil {
+		f.write(w)
+		return true
+	}
+	return false
+}
+
+// quotaFailure: nil nếu còn quota
+func (s *Server) quotaFailure(ctx context.Context, userID int64, kinds ...string) *apiFailure {
+	now := s.now()
+	for _, kind := range kinds {
+		st, err := s.quotaRepo.Check(ctx, userID, kind, now)
+		if err != nil {
+			log.Println("quota Check error:", err)
+			return nil
+		}
+		if !st.Exceeded() {
+			continue
+		}
+		retry := int(st.ResetsAt.Sub(now).Seconds() + 0.999)
+		if retry < 1 {
+			retry = 1
+		}
+		return &apiFailure{status: http.StatusTooManyRequests, retryAfter: retry, body: map[string]any{
+			"error":       "rate limit exceeded",
+			"limit":       st.Kind,
+			"max":         st.Limit,
+			"retry_after": retry,
+			"resets_at":   st.ResetsAt.Format(time.RFC3339),
+		}}
+	}
+	return nil
+}
+
+func (s *Server) handleMyLimits(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	limits, err := s.quotaRepo.State(r.Context(), userID, s.now())
+	if err != nil {
+		log.Println("quota State error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"limits": limits})
+}
+
+type userLimitsRequest struct {
+	UserID            int64 `json:"user_id"`
+	MessagesPerMinute *int  `json:"messages_per_minute"`
+	MessagesPerDay    *int  `json:"messages_per_day"`
+	RoomsPerDay       *int  `json:"rooms_per_day"`
+}
+
+func (s *Server) handleAdminUserLimits(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		userID, _ := strconv.ParseInt(r.URL.Query().Get("user_id"), 10, 64)
+		if userID <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user_id is required"})
+			return
+		}
+		s.writeUserLimits(w, r, userID)
+	case http.MethodPut:
+		s.updateUserLimits(w, r)
+	case http.MethodDelete:
+		s.deleteUserLimits(w, r)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func (s *Server) writeUserLimits(w http.ResponseWriter, r *http.Request, userID int64) {
+	ctx := r.Context()
+	o, err := s.quotaRepo.GetOverride(ctx, userID)
+	if err != nil {
+		log.Println("quota GetOverride error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	limits, err := s.quotaRepo.State(ctx, userID, s.now())
+	if err != nil {
+		log.Println("quota State error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"user_id":  userID,
+		"defaults": s.quotaRepo.Defaults,
+		"override": o,
+		"limits":   limits,
+	})
+}
+
+func (s *Server) updateUserLimits(w http.ResponseWriter, r *http.Request) {
+	adminID, _ := UserIDFromContext(r.Context())
+
+	var req userLimitsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user_id is required"})
+		return
+	}
+	for _, v := range []*int{req.MessagesPerMinute, req.MessagesPerDay, req.RoomsPerDay} {
+		if v != nil && *v < 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limits must be >= 0 (0 = unlimited, null = default)"})
+			return
+		}
+	}
+	if _, err := s.userRepo.GetUserByID(int(req.UserID)); err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "user not found"})
+		return
+	}
+
+	o := &quota.Override{
+		UserID:            req.UserID,
+		MessagesPerMinute: req.MessagesPerMinute,
+		MessagesPerDay:    req.MessagesPerDay,
+		RoomsPerDay:       req.RoomsPerDay,
+		UpdatedBy:         adminID,
+	}
+	if err := s.quotaRepo.SetOverride(r.Context(), o); err != nil {
+		log.Println("quota SetOverride error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	s.recordAudit(r, adminID, audit.ActionConfigChange, audit.TargetUser, req.UserID, map[string]any{
+		"kind":                "quota",
+		"op":                  "set",
+		"messages_per_minute": req.MessagesPerMinute,
+		"messages_per_day":    req.MessagesPerDay,
+		"rooms_per_day":       req.RoomsPerDay,
+	})
+	s.writeUserLimits(w, r, req.UserID)
+}
+
+func (s *Server) deleteUserLimits(w http.ResponseWriter, r *http.Request) {
+	adminID, _ := UserIDFromContext(r.Context())
+
+	userID, _ := strconv.ParseInt(r.URL.Query().Get("user_id"), 10, 64)
+	if userID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user_id is required"})
+		return
+	}
+	deleted, err := s.quotaRepo.DeleteOverride(r.Context(), userID)
+	if err != nil {
+		log.Println("quota DeleteOverride error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	if deleted {
+		s.recordAudit(r, adminID, audit.ActionConfigChange, audit.TargetUser, userID, map[string]any{
+			"kind": "quota",
+			"op":   "reset",
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"user_id": userID, "deleted": deleted})
+}
@@ -0,0 +1,102 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/chat"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+)
+
+// tin client gửi lên WS: {"type":"message_ack","room_id":1,"data":{"message_id":123}}
+type wsInbound struct {
+	Type   string          `json:"type"`
+	RoomID int64           `json:"room_id"`
+	Data   json.RawMessage `json:"data"`
+}
+
+type wsAckData struct {
+	MessageID int64 `json:"message_id"`
+}
+
+// handleWSInbound: client gửi ack / send_message, type lạ / json lỗi thì bỏ qua
+func (s *Server) handleWSInbound(c *wsClient, raw []byte) {
+	var in wsInbound
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return
+	}
+	switch in.Type {
+	case "message_ack":
+		var d wsAckData
+		if err := json.Unmarshal(in.Data, &d); err != nil || d.MessageID <= 0 {
+			return
+		}
+		s.handleMessageAck(c.userID, d.MessageID)
+	case "send_message":
+		s.handleWSSendMessage(c, in)
+	}
+}
+
+// handleMessageAck: client đã nhận message_created -> delivered, báo người gửi (message_delivered)
+func (s *Server) handleMessageAck(userID, messageID int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	roomID, senderID, err := s.chatRepo.GetMessageRoomAndSender(ctx, messageID)
+	if err != nil {
+		if !errors.Is(err, chat.ErrMessageNotFound) {
+			log.Println("GetMessageRoomAndSender (ack) error:", err)
+		}
+		return
+	}
+	if senderID == userID {
+		return
+	}
+
+	// chỉ update được khi còn là member (WHERE room_id + user_id)
+	advanced, err := s.chatRepo.SetDelivered(ctx, roomID, messageID, userID)
+	if err != nil {
+		log.Println("SetDelivered error:", err)
+		return
+	}
+	if !advanced || senderID <= 0 {
+		return
+	}
+
+	wsSendToUser(senderID, wsEnvelope{
+		Type:   "message_delivered",
+		RoomID: roomID,
+		Data: map[string]any{
+			"message_id": messageID,
+			"user_id":    userID,
+		},
+	})
+}
+
+// attachReceiptStatus: gắn receipt_status cho tin của viewer (lỗi chỉ log)
+func (s *Server) attachReceiptStatus(ctx context.Context, roomID, viewerID int64, msgs []RoomMessageResponse) {
+	if len(msgs) == 0 {
+		return
+	}
+
+	deliveredUpTo, seenUpTo, err := s.chatRepo.GetReceiptWatermarks(ctx, roomID, viewerID)
+	if err != nil {
+		log.Println("GetReceiptWatermarks error:", err)
+		return
+	}
+	for i := range msgs {
+		m := &msgs[i]
+		if m.SenderID != viewerID || m.IsTemp == 1 {
+			continue
+		}
+		switch {
+		case m.ID <= seenUpTo:
+			m.ReceiptStatus = string(chat.ReceiptSeen)
+		case m.ID <= deliveredUpTo:
+			m.ReceiptStatus = string(chat.ReceiptDelivered)
+		default:
+			m.ReceiptStatus = "sent"
+		}
+	}
+}
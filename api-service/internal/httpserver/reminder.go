@@ -0,0 +1,354 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/chat"
+	"cronhustler/api-service/internal/reminder"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// user của Reminder bot (tạo lần đầu scheduler chạy)
+const (
+	reminderBotUsername = "reminder_bot"
+	reminderBotName     = "Reminder"
+)
+
+func (s *Server) mountReminderRoutes(mux *http.ServeMux) {
+	// GET /reminders?all=1 | POST /reminders {target, room_id, text, at|in|cron}
+	mux.Handle("/reminders", http.HandlerFunc(s.handleReminders))
+	// DELETE /reminders/{id} (cancel) | POST /reminders/{id}/snooze {in|at}
+	mux.Handle("/reminders/", http.HandlerFunc(s.handleReminderByID))
+}
+
+type createReminderRequest struct {
+	Target string `json:"target"` // me | room
+	RoomID int64  `json:"room_id"`
+	Text   string `json:"text"`
+	At     string `json:"at"`   // "15:04" | "2006-01-02 15:04" | RFC3339
+	In     string `json:"in"`   // "10m" | "2h" | "1d"
+	Cron   string `json:"cron"` // "0 9 * * 1-5"
+}
+
+func (s *Server) handleReminders(w http.ResponseWriter, r *http.Request) {
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := s.reminderRepo.ListByUser(r.Context(), userID, r.URL.Query().Get("all") == "1")
+		if err != nil {
+			log.Println("ListByUser reminders error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"reminders": list})
+
+	case http.MethodPost:
+		var req createReminderRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+			return
+		}
+
+		sp := &reminder.Spec{Target: req.Target, Text: strings.TrimSpace(req.Text)}
+		now := s.now()
+		switch {
+		case req.Cron != "":
+			sp.CronSpec = strings.TrimSpace(req.Cron)
+			sp.FireAt, err = reminder.NextCron(sp.CronSpec, now)
+		case req.In != "":
+			var d time.Duration
+			d, err = reminder.ParseIn(req.In)
+			sp.FireAt = now.Add(d)
+		case req.At != "":
+			sp.FireAt, err = reminder.ParseAt(req.At, now)
+		default:
+			err = errors.New("one of at, in, cron is required")
+		}
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		rm, status, err := s.createReminder(r.Context(), userID, req.RoomID, sp)
+		if err != nil {
+			writeJSON(w, status, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusCreated, rm)
+
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+type snoozeReminderRequest struct {
+	In string `json:"in"` // mặc định 10m
+	At string `json:"at"`
+}
+
+func (s *Server) handleReminderByID(w http.ResponseWriter, r *http.Request) {
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/reminders/"), "/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid reminder id"})
+		return
+	}
+
+	ctx := r.Context()
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		rm, err := s.reminderRepo.GetForUser(ctx, id, userID)
+		if err != nil {
+			writeReminderError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, rm)
+
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		if err := s.reminderRepo.Cancel(ctx, id, userID); err != nil {
+			writeReminderError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+
+	case len(parts) == 2 && parts[1] == "snooze" && r.Method == http.MethodPost:
+		var req snoozeReminderRequest
+		_ = json.NewDecoder(r.Body).Decode(&req) // body rỗng = snooze 10m
+
+		now := s.now()
+		until := now.Add(10 * time.Minute)
+		switch {
+		case req.At != "":
+			until, err = reminder.ParseAt(req.At, now)
+		case req.In != "":
+			var d time.Duration
+			d, err = reminder.ParseIn(req.In)
+			until = now.Add(d)
+		}
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		if err := s.reminderRepo.Snooze(ctx, id, userID, until); err != nil {
+			writeReminderError(w, err)
+			return
+		}
+		rm, err := s.reminderRepo.GetForUser(ctx, id, userID)
+		if err != nil {
+			writeReminderError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, rm)
+
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
+
+func writeReminderError(w http.ResponseWriter, err error) {
+	if errors.Is(err, reminder.ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "reminder not found"})
+		return
+	}
+	log.Println("reminder error:", err)
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+}
+
+// createReminder: check quyền theo target rồi lưu (trả status HTTP khi lỗi)
+func (s *Server) createReminder(ctx context.Context, userID, roomID int64, sp *reminder.Spec) (*reminder.Reminder, int, error) {
+	if sp.Target != reminder.TargetMe && sp.Target != reminder.TargetRoom {
+		return nil, http.StatusBadRequest, errors.New("target must be me or room")
+	}
+	if sp.Text == "" || len([]rune(sp.Text)) > 1000 {
+		return nil, http.StatusBadRequest, errors.New("text is required (max 1000 chars)")
+	}
+	if sp.Target == reminder.TargetRoom {
+		if roomID <= 0 {
+			return nil, http.StatusBadRequest, errors.New("room_id is required for target room")
+		}
+		isMember, err := s.roomRepo.IsUserInRoom(roomID, userID)
+		if err != nil && err != sql.ErrNoRows {
+			log.Println("IsUserInRoom error:", err)
+			return nil, http.StatusInternalServerError, errors.New("db error")
+		}
+		if !isMember {
+			return nil, http.StatusForbidden, errors.New("you are not a member of this room")
+		}
+	}
+
+	rm := &reminder.Reminder{
+		CreatorID: userID,
+		Target:    sp.Target,
+		RoomID:    roomID,
+		Text:      sp.Text,
+		FireAt:    sp.FireAt,
+		CronSpec:  sp.CronSpec,
+	}
+	if err := s.reminderRepo.Create(ctx, rm); err != nil {
+		log.Println("Create reminder error:", err)
+		return nil, http.StatusInternalServerError, errors.New("db error")
+	}
+	return rm, http.StatusCreated, nil
+}
+
+// runRemindCommand: "/remind ..." gõ trong ô chat -> tạo reminder, không lưu thành tin nhắn
+func (s *Server) runRemindCommand(ctx context.Context, roomID, userID int64, content string) (map[string]any, *apiFailure) {
+	sp, err := reminder.ParseCommand(content, s.now())
+	if err != nil {
+		return nil, &apiFailure{status: http.StatusBadRequest, body: map[string]string{"error": err.Error()}}
+	}
+
+	rm, status, err := s.createReminder(ctx, userID, roomID, sp)
+	if err != nil {
+		return nil, &apiFailure{status: status, body: map[string]string{"error": err.Error()}}
+	}
+
+	// chỉ người gõ lệnh thấy (FE hiển thị như tin tạm)
+	notice := "⏰ Sẽ nhắc lúc " + rm.FireAt.Format("15:04 02/01/2006")
+	if rm.CronSpec != "" {
+		notice = "⏰ Sẽ nhắc theo lịch \"" + rm.CronSpec + "\", lần tới " + rm.FireAt.Format("15:04 02/01/2006")
+	}
+	return map[string]any{
+		"command":   "remind",
+		"ephemeral": notice,
+		"reminder":  rm,
+	}, nil
+}
+
+// ===== Scheduler =====
+
+// StartReminderScheduler: quét reminder + nhắc sự kiện lịch tới hạn mỗi interval
+func (s *Server) StartReminderScheduler(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.fireDueReminders(ctx)
+				s.fireDueEventReminders(ctx)
+			}
+		}
+	}()
+}
+
+func (s *Server) fireDueReminders(ctx context.Context) {
+	now := s.now()
+	due, err := s.reminderRepo.ListDue(ctx, now, 100)
+	if err != nil {
+		log.Println("[reminder] ListDue error:", err)
+		return
+	}
+	if len(due) == 0 {
+		return
+	}
+
+	botID, err := s.botRepo.EnsureSystemBot(ctx, reminderBotUsername, reminderBotName)
+	if err != nil {
+		log.Println("[reminder] EnsureSystemBot error:", err)
+		return
+	}
+
+	for _, rm := range due {
+		// tính lần kế tiếp trước, chốt trong DB rồi mới gửi (không gửi trùng)
+		var next *time.Time
+		if rm.CronSpec != "" {
+			if t, err := reminder.NextCron(rm.CronSpec, now); err == nil {
+				next = &t
+			}
+		}
+		ok, err := s.reminderRepo.Claim(ctx, rm, next)
+		if err != nil {
+			log.Printf("[reminder] Claim id=%d: %v", rm.ID, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		if err := s.postReminder(ctx, botID, rm); err != nil {
+			log.Printf("[reminder] post id=%d: %v", rm.ID, err)
+		}
+	}
+}
+
+func (s *Server) postReminder(ctx context.Context, botID int64, rm *reminder.Reminder) error {
+	roomID := rm.RoomID
+	content := "⏰ Nhắc bạn: " + rm.Text
+
+	if rm.Target == reminder.TargetRoom {
+		name, _ := s.senderInfo(rm.CreatorID)
+		content = "⏰ " + name + " nhắc cả phòng: " + rm.Text
+	} else {
+		id, err := s.ensureDirectRoom(botID, rm.CreatorID)
+		if err != nil {
+			return err
+		}
+		roomID = id
+	}
+
+	_, err := s.postBotMessage(ctx, botID, roomID, content)
+	return err
+}
+
+// postBotMessage: bot hệ thống gửi tin text vào room + broadcast như tin thường
+func (s *Server) postBotMessage(ctx context.Context, botID, roomID int64, content string) (int64, error) {
+	msg := &chat.Message{
+		RoomID:      roomID,
+		SenderID:    botID,
+		Content:     content,
+		MessageType: "text",
+		CreatedAt:   s.now().UTC(),
+	}
+	id, err := s.chatRepo.CreateMessage(ctx, msg, false)
+	if err != nil {
+		return 0, err
+	}
+
+	senderName, senderAvatar := s.senderInfo(botID)
+	resp := sendMessageResponse{
+		ID:              id,
+		RoomID:          roomID,
+		SenderID:        botID,
+		SenderName:      senderName,
+		SenderAvatarURL: s.signMediaURL(senderAvatar),
+		Content:         msg.Content,
+		MessageType:     msg.MessageType,
+		CreatedAt:       msg.CreatedAt.Format(time.RFC3339),
+	}
+	s.broadcastMessageCreated(ctx, roomID, botID, resp)
+	return id, nil
+}
+
+// ensureDirectRoom: room direct giữa 2 user, chưa có thì tạo (giống POST /rooms/direct)
+func (s *Server) ensureDirectRoom(a, b int64) (int64, error) {
+	rm, created, err := s.roomRepo.CreateDirectRoom(context.Background(), a, b)
+	if err != nil {
+		return 0, err
+	}
+	if created {
+		s.notifyDirectRoomJoined(rm, a, b)
+	}
+	return rm.ID, nil
+}
@@ -0,0 +1,189 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/job"
+	"cronhustler/api-service/internal/notify"
+	"cronhustler/api-service/internal/report"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	adminReportsPrefix = "/admin/reports/"
+
+	jobActionAdminReport = "admin_report"
+
+	// tối đa người nhận trong 1 job
+	maxReportRecipients = 50
+)
+
+func (s *Server) mountReportRoutes(mux *http.ServeMux) {
+	// GET /admin/reports/{kind}?days=30&format=csv|pdf -> tải file ngay (kind: user_growth, message_volume, storage)
+	// gửi định kỳ qua email: tạo job action_type=admin_report ở /jobs
+	mux.Handle(adminReportsPrefix, s.RequireAdmin(http.HandlerFunc(s.handleAdminReport)))
+}
+
+type adminReportPayload struct {
+	Report     string   `json:"report"`
+	Format     string   `json:"format"`     // csv (mặc định) | pdf
+	Days       int      `json:"days"`       // mặc định 7
+	Recipients []string `json:"recipients"` // rỗng = mọi admin có email
+}
+
+func parseAdminReportPayload(raw json.RawMessage) (*adminReportPayload, error) {
+	var p adminReportPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, errors.New("payload must be {report, format, days, recipients}")
+	}
+	if !report.IsValidKind(p.Report) {
+		return nil, fmt.Errorf("payload.report must be one of: %s", strings.Join(report.Kinds, ", "))
+	}
+	p.Format = strings.ToLower(strings.TrimSpace(p.Format))
+	if p.Format == "" {
+		p.Format = report.FormatCSV
+	}
+	if !report.IsValidFormat(p.Format) {
+		return nil, errors.New("payload.format must be csv or pdf")
+	}
+	if p.Days == 0 {
+		p.Days = 7
+	}
+	if p.Days < 1 || p.Days > 365 {
+		return nil, errors.New("payload.days must be 1..365")
+	}
+	if len(p.Recipients) > maxReportRecipients {
+		return nil, fmt.Errorf("payload.recipients: max %d", maxReportRecipients)
+	}
+	for i, e := range p.Recipients {
+		p.Recipients[i] = strings.TrimSpace(e)
+		if !isValidEmail(p.Recipients[i]) {
+			return nil, fmt.Errorf("payload.recipients[%d] is not a valid email", i)
+		}
+	}
+	return &p, nil
+}
+
+// reportRange: days ngày gần nhất tính cả hôm nay
+func reportRange(now time.Time, days int) (time.Time, time.Time) {
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return to.AddDate(0, 0, -(days - 1)), to
+}
+
+func (s *Server) runAdminReportJob(ctx context.Context, j *job.Job) (string, error) {
+	p, err := parseAdminReportPayload(j.Payload)
+	if err != nil {
+		return "", err
+	}
+	if s.mailer == nil {
+		return "", notify.ErrMailDisabled
+	}
+
+	recipients := p.Recipients
+	if len(recipients) == 0 {
+		if recipients, err = s.userRepo.ListAdminEmails(ctx); err != nil {
+			return "", err
+		}
+		if len(recipients) == 0 {
+			return "", errors.New("no recipients: set payload.recipients or an email on admin accounts")
+		}
+	}
+
+	from, to := reportRange(s.now(), p.Days)
+	t, err := report.Build(ctx, s.analyticsRepo, p.Report, from, to)
+	if err != nil {
+		return "", err
+	}
+	data, contentType, err := t.Render(p.Format)
+	if err != nil {
+		return "", err
+	}
+
+	msg, err := s.mailTemplates.Compose(notify.TemplateAdminReport, "", notify.AdminReportData{
+		Title:    t.Title,
+		From:     from.Format("2006-01-02"),
+		To:       to.Format("2006-01-02"),
+		Summary:  t.Summary,
+		Filename: t.Filename(p.Format),
+	})
+	if err != nil {
+		return "", err
+	}
+	msg.Attachments = []notify.Attachment{{Filename: t.Filename(p.Format), ContentType: contentType, Data: data}}
+
+	sent := 0
+	var failed []string
+	for _, rcpt := range recipients {
+		msg.To = rcpt
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			log.Printf("[admin_report] send %s: %v", rcpt, err)
+			failed = append(failed, rcpt)
+			continue
+		}
+		sent++
+	}
+
+	out := fmt.Sprintf("report=%s file=%s bytes=%d sent=%d", p.Report, t.Filename(p.Format), len(data), sent)
+	if len(failed) > 0 {
+		out += " failed=" + strings.Join(failed, ",")
+	}
+	if sent == 0 {
+		return out, errors.New("report was not sent to any recipient")
+	}
+	return out, nil
+}
+
+func (s *Server) handleAdminReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	kind := strings.Trim(strings.TrimPrefix(r.URL.Path, adminReportsPrefix), "/")
+	if !report.IsValidKind(kind) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "report must be one of: " + strings.Join(report.Kinds, ", ")})
+		return
+	}
+	q := r.URL.Query()
+	format := strings.ToLower(q.Get("format"))
+	if format == "" {
+		format = report.FormatCSV
+	}
+	if !report.IsValidFormat(format) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "format must be csv or pdf"})
+		return
+	}
+	days := 30
+	if v, err := strconv.Atoi(q.Get("days")); err == nil && v > 0 && v <= 365 {
+		days = v
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	from, to := reportRange(s.now(), days)
+	t, err := report.Build(ctx, s.analyticsRepo, kind, from, to)
+	if err != nil {
+		log.Println("report Build error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	data, contentType, err := t.Render(format)
+	if err != nil {
+		log.Println("report Render error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "render error"})
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+t.Filename(format)+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
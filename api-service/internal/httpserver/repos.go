@@ -0,0 +1,123 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/chat"
+	"cronhustler/api-service/internal/room"
+	"cronhustler/api-service/internal/user"
+	"time"
+)
+
+// UserRepo: phần user.Repository mà handler dùng (test thay bằng fake)
+type UserRepo interface {
+	CreateSession(ctx context.Context, s *user.Session) error
+	CreateUser(u *user.User) (int64, error)
+	FindByUsername(username string) (*user.User, error)
+	GetAllUsers() ([]*user.User, error)
+	GetAllUsersForListing() ([]*user.User, error)
+	GetUserBrief(ctx context.Context, userID int64) (*user.UserBrief, error)
+	GetUserByID(id int) (*user.User, error)
+	ListAdminEmails(ctx context.Context) ([]string, error)
+	ListSessions(ctx context.Context, userID int64) ([]user.Session, error)
+	RecordLogin(userID int64, ip, userAgent string, success bool) error
+	RevokeSession(ctx context.Context, userID int64, id string) (bool, error)
+	SearchUsers(keyword string, limit int) ([]*user.User, error)
+	TouchSession(ctx context.Context, id string, userID int64, ip string) error
+	UpdateAvatar(userID int, avatarURL string) error
+	UpdateLoginAudit(username, ip, lastLogin string) error
+	UpdateUserDynamic(id int64, fields map[string]interface{}) error
+}
+
+// RoomRepo: phần room.Repository mà handler dùng
+type RoomRepo interface {
+	AddMember(roomID, userID int64, role string) error
+	AdminGetRoom(ctx context.Context, roomID int64) (*room.AdminRoom, error)
+	AdminListRooms(ctx context.Context, f room.AdminRoomFilter) ([]*room.AdminRoom, error)
+	ArchivedMembers(ctx context.Context, roomID int64) (map[int64]bool, error)
+	CreateDirectRoom(ctx context.Context, createdBy, other int64) (*room.Room, bool, error)
+	CreateGroupRoom(name string, createdBy int64, memberIDs []int64) (*room.Room, error)
+	CreateInvite(ctx context.Context, roomID, inviterID, inviteeID int64) (*room.Invite, error)
+	CreateInviteLink(ctx context.Context, roomID, createdBy int64, expiresIn time.Duration, maxUses int) (*room.InviteLink, error)
+	DeleteRoom(roomID, userID int64) error
+	DeleteUserGroup(roomID int64, userID int64) error
+	ForceDeleteRoom(ctx context.Context, roomID int64) error
+	GetDirectPartnerFullNameByRoomID(roomID, currentUserID int64) (string, error)
+	GetDirectRoomBetweenUsers(a, b int64) (*room.Room, error)
+	GetMemberRole(ctx context.Context, roomID, userID int64) (string, error)
+	GetMessage(ctx context.Context, roomID, messageID, userID int64) (*room.Message, error)
+	GetMessageCreatedAt(ctx context.Context, roomID int64, messageID int64) (time.Time, error)
+	GetMessageThread(ctx context.Context, roomID, rootID, afterID int64, afterAt time.Time, limit int, userID int64) ([]*room.Message, error)
+	GetRoomBasic(ctx context.Context, roomID int64) (*room.RoomBasic, error)
+	GetRoomByID(id int64) (*room.Room, error)
+	GetRoomByIDLite(ctx context.Context, roomID int64) (*room.RoomLite, error)
+	GetRoomMemberIDs(roomID int64) ([]int64, error)
+	GetRoomMembers(roomID int64) ([]*room.RoomMember, error)
+	GetRoomMessages(roomID int64, beforeID int64, beforeAt time.Time, limit int, userID int64) ([]*room.Message, error)
+	GetRoomOwner(roomID int64) (int64, error)
+	GetRoomProfile(ctx context.Context, roomID int64) (*room.RoomProfile, error)
+	GetRoomsByUser(userID int64) ([]*room.Room, error)
+	GetRoomsByUserPage(ctx context.Context, userID int64, cursor *room.RoomCursor, limit int, includeArchived bool) (rooms []*room.Room, next *room.RoomCursor, err error)
+	IsUserInRoom(roomID, userID int64) (bool, error)
+	JoinByInviteCode(ctx context.Context, code string, userID int64) (roomID int64, joined bool, err error)
+	ListInviteLinks(ctx context.Context, roomID int64) ([]room.InviteLink, error)
+	ListPendingInvites(ctx context.Context, userID int64) ([]*room.Invite, error)
+	ListPins(ctx context.Context, roomID int64) ([]room.PinnedMessage, error)
+	MarkRoomAsRead(roomID, userID int64) error
+	MarkRoomSeenUpTo(ctx context.Context, roomID, userID, lastSeenMessageID int64) error
+	PinMessage(ctx context.Context, roomID, messageID, userID int64) error
+	RespondInvite(ctx context.Context, id, userID int64, accept bool) (*room.Invite, error)
+	RevokeInviteLink(ctx context.Context, roomID int64, code string) (bool, error)
+	SetArchived(ctx context.Context, roomID, userID int64, archived bool) (*time.Time, error)
+	SetMemberRole(ctx context.Context, roomID, userID int64, role string) error
+	TransferOwnership(ctx context.Context, roomID, newOwnerID int64) (int64, error)
+	UnpinMessage(ctx context.Context, roomID, messageID int64) (bool, error)
+	UpdateRoomProfile(ctx context.Context, roomID int64, u room.ProfileUpdate) error
+}
+
+// ChatRepo: phần chat.Repository mà handler dùng
+type ChatRepo interface {
+	BlockMessagesByMediaURL(ctx context.Context, mediaURL, reason string) ([]chat.BlockedMessage, error)
+	CreateAttachment(ctx context.Context, att *chat.Attachment) (int64, error)
+	CreateEmoji(ctx context.Context, e *chat.Emoji, createdBy int64) error
+	CreateMessage(ctx context.Context, msg *chat.Message, validateReply bool) (int64, error)
+	CreateMessageWithAttachments(ctx context.Context, msg *chat.Message, atts []chat.Attachment, validateReply bool) (int64, error)
+	CreateUpload(ctx context.Context, up *chat.Upload) error
+	DeleteEmoji(ctx context.Context, code string) (imageURL string, err error)
+	GetMessageByClientMsgID(ctx context.Context, senderID int64, clientMsgID string) (*chat.Message, error)
+	GetMessageButtons(ctx context.Context, messageID int64) (roomID, senderID int64, buttons []chat.Button, err error)
+	GetMessageRoomAndSender(ctx context.Context, messageID int64) (roomID int64, senderID int64, err error)
+	GetMessageRoomID(ctx context.Context, messageID int64) (int64, error)
+	GetMessageSeenSummary(ctx context.Context, messageID, meUserID int64, excludeUserID int64) (chat.MessageSeenSummary, error)
+	GetNotifyLevels(ctx context.Context, roomID int64) (map[int64]string, error)
+	GetNotifyLevelsByUser(ctx context.Context, userID int64) (map[int64]string, error)
+	GetReactionSummary(ctx context.Context, messageID, viewerUserID int64) ([]chat.ReactionSummaryItem, error)
+	GetReceiptWatermarks(ctx context.Context, roomID, viewerUserID int64) (deliveredUpTo, seenUpTo int64, err error)
+	GetRoomIDByMediaURL(ctx context.Context, mediaURL string) (int64, error)
+	GetRoomLastSeenMessageID(ctx context.Context, roomID, userID int64) (int64, *time.Time, error)
+	GetUpload(ctx context.Context, fileName string) (*chat.Upload, error)
+	GetUploadOriginalName(ctx context.Context, fileName string) (string, error)
+	GetUploadRooms(ctx context.Context, fileNames []string) (map[string]int64, error)
+	IsUploadQuarantined(ctx context.Context, fileName string) (bool, error)
+	ListAttachmentsBatch(ctx context.Context, messageIDs []int64) (map[int64][]chat.Attachment, error)
+	ListEmojis(ctx context.Context, includeInactive bool) ([]*chat.Emoji, error)
+	ListReactionsByMessage(ctx context.Context, messageID int64, reaction string, afterID int64, limit int) ([]chat.ReactionUserItem, int64, error)
+	ListRoomMemberUserIDsExcept(ctx context.Context, roomID, excludeUserID int64) ([]int64, error)
+	ListSeenUsersByMessage(ctx context.Context, messageID int64, excludeUserID int64, limit int) ([]chat.SeenUser, error)
+	MarkRoomSeenUpTo(ctx context.Context, roomID, userID, upToMessageID int64) (affected int64, err error)
+	QuarantineUpload(ctx context.Context, fileName, signature string) error
+	RecordMentions(ctx context.Context, messageID, roomID, senderID int64, usernames []string) ([]int64, error)
+	RemoveAllReactionsByUser(ctx context.Context, messageID, userID int64) error
+	RemoveReaction(ctx context.Context, messageID, userID int64, reaction string) error
+	SetDelivered(ctx context.Context, roomID, messageID, userID int64) (advanced bool, err error)
+	SetNotifyLevel(ctx context.Context, roomID, userID int64, level string) error
+	ToggleReaction(ctx context.Context, messageID, userID int64, reaction string) (added bool, err error)
+	UpdateEmoji(ctx context.Context, code string, u chat.EmojiUpdate) (*chat.Emoji, error)
+	UpdateMessageAudio(ctx context.Context, messageID int64, mediaURL, mediaMIME string, mediaSize, durationMs int64) error
+	UpdateMessageMedia(ctx context.Context, messageID int64, mediaURL, mediaMIME string, mediaSize int64, posterURL string) error
+}
+
+var (
+	_ UserRepo = (*user.Repository)(nil)
+	_ RoomRepo = (*room.Repository)(nil)
+	_ ChatRepo = (*chat.Repository)(nil)
+)
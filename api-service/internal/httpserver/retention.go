@@ -0,0 +1,227 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/audit"
+	"cronhustler/api-service/internal/job"
+	"cronhustler/api-service/internal/retention"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+const (
+	retentionJobName     = "Retention purge"
+	defaultRetentionCron = "0 3 * * *" // 3h sáng mỗi ngày
+)
+
+func (s *Server) mountRetentionRoutes(mux *http.ServeMux) {
+	// GET /admin/retention -> policy + lịch chạy + các lần purge gần nhất
+	// PUT /admin/retention {policies: {messages: 365, receipts: 90, login_audit: 180, uploads: 0}, cron}
+	mux.Handle("/admin/retention", s.RequireAdmin(http.HandlerFunc(s.handleAdminRetention)))
+	// GET  /admin/retention/preview -> đếm số sẽ bị xoá (không xoá)
+	// POST /admin/retention/run     -> chạy ngay (qua job runner)
+	mux.Handle("/admin/retention/", s.RequireAdmin(http.HandlerFunc(s.handleAdminRetentionAction)))
+}
+
+type retentionRequest struct {
+	Policies map[string]int `json:"policies"`
+	CronSpec string         `json:"cron"`
+}
+
+// retentionJob: job retention_purge hiện có (nil = chưa tạo)
+func (s *Server) retentionJob(ctx context.Context) (*job.Job, error) {
+	list, err := s.jobRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, j := range list {
+		if j.ActionType == jobActionRetention {
+			return j, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *Server) handleAdminRetention(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.writeRetentionState(w, r)
+	case http.MethodPut:
+		s.updateRetention(w, r)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func (s *Server) writeRetentionState(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	policies, err := s.retentionRepo.List(ctx)
+	if err != nil {
+		log.Println("retention List error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	j, err := s.retentionJob(ctx)
+	if err != nil {
+		log.Println("retention job error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	runs := []*job.Run{}
+	if j != nil {
+		if runs, err = s.jobRepo.ListRuns(ctx, j.ID, 20); err != nil {
+			log.Println("retention ListRuns error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"policies": policies,
+		"job":      j,
+		"runs":     runs,
+	})
+}
+
+func (s *Server) updateRetention(w http.ResponseWriter, r *http.Request) {
+	adminID, _ := UserIDFromContext(r.Context())
+
+	var req retentionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+	for c, days := range req.Policies {
+		if !retention.IsValidCategory(c) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "category must be one of: " + strings.Join(retention.Categories, ", ")})
+			return
+		}
+		if days < 0 || days > retention.MaxDays {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": retention.ErrInvalidPolicy.Error()})
+			return
+		}
+	}
+	req.CronSpec = strings.TrimSpace(req.CronSpec)
+	if req.CronSpec != "" {
+		if _, err := job.NextRun(req.CronSpec, s.now()); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid cron: " + err.Error()})
+			return
+		}
+	}
+
+	ctx := r.Context()
+	old, err := s.retentionRepo.List(ctx)
+	if err != nil {
+		log.Println("retention List error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	oldDays := map[string]int{}
+	for _, p := range old {
+		oldDays[p.Category] = p.Days
+	}
+
+	changes := map[string]any{}
+	for c, days := range req.Policies {
+		if oldDays[c] == days {
+			continue
+		}
+		if err := s.retentionRepo.Set(ctx, c, days, adminID); err != nil {
+			log.Println("retention Set error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		changes[c] = map[string]int{"from": oldDays[c], "to": days}
+	}
+
+	// tạo job purge lần đầu / đổi lịch
+	j, err := s.retentionJob(ctx)
+	if err != nil {
+		log.Println("retention job error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	if j == nil {
+		j = &job.Job{
+			Name:       retentionJobName,
+			CronSpec:   defaultRetentionCron,
+			ActionType: jobActionRetention,
+			Payload:    json.RawMessage("{}"),
+			Enabled:    true,
+			CreatedBy:  adminID,
+		}
+		if req.CronSpec != "" {
+			j.CronSpec = req.CronSpec
+		}
+		next, _ := job.NextRun(j.CronSpec, s.now())
+		j.NextRunAt = &next
+		if err := s.jobRepo.Create(ctx, j); err != nil {
+			log.Println("create retention job error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+	} else if req.CronSpec != "" && req.CronSpec != j.CronSpec {
+		changes["cron"] = map[string]string{"from": j.CronSpec, "to": req.CronSpec}
+		j.CronSpec = req.CronSpec
+		next, _ := job.NextRun(j.CronSpec, s.now())
+		j.NextRunAt = &next
+		if err := s.jobRepo.Update(ctx, j); err != nil {
+			log.Println("update retention job error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+	}
+
+	if len(changes) > 0 {
+		s.recordAudit(r, adminID, audit.ActionConfigChange, audit.TargetConfig, 0, map[string]any{
+			"kind":    "retention",
+			"changes": changes,
+		})
+	}
+	s.writeRetentionState(w, r)
+}
+
+func (s *Server) handleAdminRetentionAction(w http.ResponseWriter, r *http.Request) {
+	action := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/retention/"), "/")
+	ctx := r.Context()
+
+	switch {
+	case action == "preview" && r.Method == http.MethodGet:
+		rep, err := s.retention.Run(ctx, s.now(), true)
+		if err != nil {
+			log.Println("retention preview error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, rep)
+
+	case action == "run" && r.Method == http.MethodPost:
+		j, err := s.retentionJob(ctx)
+		if err != nil {
+			log.Println("retention job error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		if j == nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "no retention policy configured"})
+			return
+		}
+		runID, err := s.jobs.RunNow(ctx, j.ID)
+		if err != nil {
+			if errors.Is(err, job.ErrLocked) {
+				writeJSON(w, http.StatusConflict, map[string]string{"error": "purge is already running"})
+				return
+			}
+			log.Println("retention RunNow error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]any{"job_id": j.ID, "run_id": runID})
+
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
@@ -2,16 +2,19 @@ package httpserver
 
 import (
 	"context"
+	"cronhustler/api-service/internal/audit"
 	"cronhustler/api-service/internal/chat"
+	"cronhustler/api-service/internal/linkpreview"
+	"cronhustler/api-service/internal/push"
+	"cronhustler/api-service/internal/quota"
 	"cronhustler/api-service/internal/room"
+	"cronhustler/api-service/internal/webhook"
 	"database/sql" // 👈 thêm cái này
 	"encoding/json"
-	"fmt"
+	"errors"
 	"io"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -25,7 +28,9 @@ func (s *Server) mountRoomRoutes(mux *http.ServeMux) {
 	// GET /rooms/{id}/messages
 	mux.Handle("/rooms/messages/", http.HandlerFunc(s.handleGetRoomMessages))
 
-	// POST /rooms/direct -> tạo room direct cho 2 user id
+	// POST /rooms/direct {user_id} -> lấy hoặc tạo room direct với user đó
+	// POST /rooms/direct/{userID} -> như trên, path cũ
+	mux.Handle("/rooms/direct", http.HandlerFunc(s.handleCreateDirectRoom))
 	mux.Handle("/rooms/direct/", http.HandlerFunc(s.handleCreateDirectRoom))
 
 	// ✅ GET /rooms/direct-name/{user_id} -> lấy full_name thằng partner (user_id thứ 2)
@@ -34,7 +39,7 @@ func (s *Server) mountRoomRoutes(mux *http.ServeMux) {
 	// POST /rooms/group -> tạo room group
 	mux.Handle("/rooms/group", http.HandlerFunc(s.handleCreateGroupRoom))
 
-	// POST /rooms/members -> thêm user vào room (chỉ member trong room mới được add)
+	// POST /rooms/add-member -> mời user vào group (tạo lời mời, user accept mới vào room)
 	mux.Handle("/rooms/add-member", http.HandlerFunc(s.handleAddUserToRoom))
 
 	// POST /rooms/read/{id} -> đánh dấu room đã đọc
@@ -44,7 +49,21 @@ func (s *Server) mountRoomRoutes(mux *http.ServeMux) {
 	mux.Handle("/rooms/members/", http.HandlerFunc(s.handleGetRoomMembers))
 
 	// DELETE /rooms/{roomID}/members/{userID} -> xoá user khỏi group room
-	mux.Handle("/rooms/", http.HandlerFunc(s.handleDeleteUserGroup))
+	// PATCH /rooms/{roomID} -> đổi tên / mô tả / avatar group (owner, admin)
+	// POST|DELETE /rooms/{roomID}/mute -> tắt / bật thông báo room
+	// POST /rooms/{roomID}/archive | /unarchive -> ẩn room khỏi GET /rooms (riêng user)
+	// POST /rooms/{roomID}/members/{userID}/role -> đổi role (admin | moderator | member)
+	// GET /rooms/{roomID}/pins -> tin đã ghim
+	// POST /rooms/{roomID}/invite -> mời user vào group
+	// GET|PATCH /rooms/{roomID}/retention {ttl} -> tin tự huỷ (owner đặt)
+	// GET|POST /rooms/{roomID}/invite-link, DELETE /rooms/{roomID}/invite-link/{code} -> link join (owner)
+	mux.Handle("/rooms/", s.roomRoutes())
+
+	// POST /rooms/join/{code} -> tự join group qua link
+	mux.Handle(roomJoinPrefix, http.HandlerFunc(s.handleJoinRoomByCode))
+
+	// GET /rooms/notification-settings -> mute + notify level từng room
+	mux.Handle("/rooms/notification-settings", http.HandlerFunc(s.handleRoomNotificationSettings))
 
 	// DELETE /rooms/delete/{roomID} -> xoá room (chỉ owner mới được xoá)
 	mux.Handle("/rooms/delete/", http.HandlerFunc(s.handleDeleteRoom))
@@ -52,6 +71,18 @@ func (s *Server) mountRoomRoutes(mux *http.ServeMux) {
 	// POST /rooms/upload-image/ -> upload hình ảnh trong room chat
 	mux.Handle("/rooms/upload-image/", http.HandlerFunc(s.handleUploadRoomImage))
 
+	// POST /rooms/upload-image-base64/{roomID} -> paste ảnh (data URI) từ clipboard
+	mux.Handle("/rooms/upload-image-base64/", http.HandlerFunc(s.handleUploadRoomImageBase64))
+
+	// POST /rooms/upload-audio/{roomID} -> voice message (webm / ogg / m4a), gửi message_type=audio
+	mux.Handle("/rooms/upload-audio/", http.HandlerFunc(s.handleUploadRoomAudio))
+
+	// POST /rooms/upload-files/{roomID} -> nhiều file, tạo 1 message kèm attachments
+	mux.Handle("/rooms/upload-files/", http.HandlerFunc(s.handleUploadRoomFiles))
+
+	// POST /rooms/upload-file/{roomID} -> pdf/zip/doc/video..., giới hạn size theo loại, tạo message kèm attachment
+	mux.Handle("/rooms/upload-file/", http.HandlerFunc(s.handleUploadRoomFile))
+
 }
 
 // Response cho 1 room
@@ -64,62 +95,123 @@ type RoomInfoResponse struct {
 
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
+
+	Muted      bool       `json:"muted"` // sidebar hiện icon mute
+	MutedUntil *time.Time `json:"muted_until,omitempty"`
+
+	Description string `json:"description,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"` // signed
+
+	UnreadCount int64                 `json:"unread_count"`
+	LastMessage *room.RoomLastMessage `json:"last_message,omitempty"`
+	Archived    bool                  `json:"archived"`
 }
 
 // Response cho list room của 1 user
 type GetMyRoomsResponse struct {
-	Rooms []RoomInfoResponse `json:"rooms,omitempty"`
-	Error string             `json:"error,omitempty"`
+	Rooms      []RoomInfoResponse `json:"rooms,omitempty"`
+	NextCursor string             `json:"next_cursor,omitempty"` // còn trang sau -> GET /rooms?cursor=...
 }
 
 // handleGetMyRooms: trả về danh sách room mà user trong token đang ở
-// GET /rooms
+// GET /rooms[?limit=30&cursor=...][&include_archived=1]
 // Header: Authorization: Bearer <access_token>
+// - có limit / cursor: phân trang theo last activity, trả next_cursor
+// - không có: trả hết như cũ (FE cũ) + WS rooms_sync
+// - room user đã archive bị ẩn trừ khi include_archived=1
 func (s *Server) handleGetMyRooms(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		writeJSON(w, http.StatusUnauthorized, GetMyRoomsResponse{
-			Error: "missing Authorization header",
-		})
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
-	parts := strings.SplitN(authHeader, " ", 2)
-	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
-		writeJSON(w, http.StatusUnauthorized, GetMyRoomsResponse{
-			Error: "invalid Authorization header",
-		})
-		return
+	q := r.URL.Query()
+	paged := q.Has("limit") || q.Has("cursor")
+	includeArchived := q.Get("include_archived") == "1" || q.Get("include_archived") == "true"
+
+	var rooms []*room.Room
+	var next *room.RoomCursor
+	if paged {
+		limit := room.DefaultRoomsPageSize
+		if v := q.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid limit")
+				return
+			}
+			limit = n
+		}
+		var cursor *room.RoomCursor
+		if v := q.Get("cursor"); v != "" {
+			if cursor, err = room.DecodeRoomCursor(v); err != nil {
+				writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid cursor")
+				return
+			}
+		}
+
+		rooms, next, err = s.roomRepo.GetRoomsByUserPage(r.Context(), userID, cursor, limit, includeArchived)
+		if err != nil {
+			log.Println("GetRoomsByUserPage error:", err)
+			writeError(w, http.StatusInternalServerError, CodeDBError, "db error")
+			return
+		}
+	} else {
+		rooms, err = s.roomRepo.GetRoomsByUser(userID)
+		if err != nil {
+			log.Println("GetRoomsByUser error:", err)
+			writeError(w, http.StatusInternalServerError, CodeDBError, "db error")
+			return
+		}
 	}
 
-	tokenStr := parts[1]
-	claims, err := ParseToken(tokenStr, s.jwtSecret)
-	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, GetMyRoomsResponse{
-			Error: "invalid or expired token",
-		})
+	respRooms := s.roomInfoResponses(r.Context(), userID, rooms, includeArchived)
+
+	if paged {
+		resp := GetMyRoomsResponse{Rooms: respRooms}
+		if next != nil {
+			resp.NextCursor = next.Encode()
+		}
+		// trang lẻ không đủ cho rooms_sync (FE thay cả list)
+		writeJSON(w, http.StatusOK, resp)
 		return
 	}
 
-	userID := int64(claims.UserID)
+	// ✅ HTTP response
+	writeJSON(w, http.StatusOK, GetMyRoomsResponse{
+		Rooms: respRooms,
+	})
+
+	// ✅ WS sync (dùng data đã override name)
+	go wsSendToUser(userID, wsEnvelope{
+		Type: "rooms_sync",
+		Data: map[string]any{
+			"rooms": respRooms,
+		},
+	})
+}
 
-	rooms, err := s.roomRepo.GetRoomsByUser(userID)
-	if err != nil {
-		log.Println("GetRoomsByUser error:", err)
-		writeJSON(w, http.StatusInternalServerError, GetMyRoomsResponse{
-			Error: "db error",
-		})
-		return
+// roomInfoResponses: room của user -> response sidebar (tên direct = tên người kia, mute, avatar ký)
+func (s *Server) roomInfoResponses(ctx context.Context, userID int64, rooms []*room.Room, includeArchived bool) []RoomInfoResponse {
+	// lỗi đọc mute -> vẫn trả list, chỉ thiếu icon
+	muted := map[int64]push.RoomMute{}
+	if prefs, err := s.pushRepo.GetPreferences(ctx, userID); err != nil {
+		log.Println("GetPreferences error:", err)
+	} else {
+		muted = mutedRoomMap(prefs)
 	}
 
 	respRooms := make([]RoomInfoResponse, 0, len(rooms))
 
 	for _, rm := range rooms {
+		if rm.Archived && !includeArchived {
+			continue
+		}
 		roomName := rm.Name
 
 		// ✅ OVERRIDE name cho direct room
@@ -136,29 +228,27 @@ func (s *Server) handleGetMyRooms(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		mute, isMuted := muted[rm.ID]
 		respRooms = append(respRooms, RoomInfoResponse{
-			ID:        rm.ID,
-			Name:      roomName,
-			Type:      rm.Type,
-			CreatedBy: rm.CreatedBy,
-			IsActive:  rm.IsActive,
-			CreatedAt: formatTime(rm.CreatedAt),
-			UpdatedAt: formatTime(rm.UpdatedAt),
+			ID:         rm.ID,
+			Name:       roomName,
+			Type:       rm.Type,
+			CreatedBy:  rm.CreatedBy,
+			IsActive:   rm.IsActive,
+			CreatedAt:  formatTime(rm.CreatedAt),
+			UpdatedAt:  formatTime(rm.UpdatedAt),
+			Muted:      isMuted,
+			MutedUntil: mute.Until,
+
+			Description: rm.Description,
+			AvatarURL:   s.signMediaURL(rm.AvatarURL),
+
+			UnreadCount: rm.UnreadCount,
+			LastMessage: rm.LastMessage,
+			Archived:    rm.Archived,
 		})
 	}
-
-	// ✅ HTTP response
-	writeJSON(w, http.StatusOK, GetMyRoomsResponse{
-		Rooms: respRooms,
-	})
-
-	// ✅ WS sync (dùng data đã override name)
-	go wsSendToUser(userID, wsEnvelope{
-		Type: "rooms_sync",
-		Data: map[string]any{
-			"rooms": respRooms,
-		},
-	})
+	return respRooms
 }
 
 // formatTime: helper nhỏ cho đẹp, tránh nil pointer
@@ -193,32 +283,50 @@ type RoomMessageResponse struct {
 	MediaMIME string `json:"media_mime,omitempty"`
 	MediaSize int64  `json:"media_size,omitempty"`
 
+	MediaPosterURL string `json:"media_poster_url,omitempty"`
+
+	MediaDurationMs int64 `json:"media_duration_ms,omitempty"` // voice message (audio)
+
+	// file không qua quét virus: content / media / attachments đã bị bỏ
+	Blocked bool `json:"blocked,omitempty"`
+
 	Reply     *ReplyInfoResponse         `json:"reply,omitempty"`
 	Reactions []chat.ReactionSummaryItem `json:"reactions,omitempty"`
 
+	Attachments []chat.Attachment `json:"attachments,omitempty"`
+
+	WebhookID int64         `json:"webhook_id,omitempty"`
+	Buttons   []chat.Button `json:"buttons,omitempty"`
+	Embeds    []chat.Embed  `json:"embeds,omitempty"`
+
+	// OG metadata của link trong tin (fetch nền, xong thì WS message_preview_ready)
+	LinkPreview *linkpreview.Preview `json:"link_preview,omitempty"`
+
+	// chỉ tin của mình: sent -> delivered (client ack WS) -> seen
+	ReceiptStatus string `json:"receipt_status,omitempty"`
+
 	CreatedAt string `json:"created_at"`
 }
 
 type getRoomMessagesResponse struct {
 	Messages []RoomMessageResponse `json:"messages,omitempty"`
-	Error    string                `json:"error,omitempty"`
 }
 
 func (s *Server) handleGetRoomMessages(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	userID, err := GetUserIDFromRequest(r, s.jwtSecret)
+	userID, err := UserIDFromContext(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
 	roomID, err := getIDFromURL(r)
 	if err != nil || roomID <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid room id"})
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid room id")
 		return
 	}
 
@@ -250,11 +358,11 @@ func (s *Server) handleGetRoomMessages(w http.ResponseWriter, r *http.Request) {
 	// ==========================
 	isMember, err := s.roomRepo.IsUserInRoom(roomID, userID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		writeError(w, http.StatusInternalServerError, CodeDBError, "db error")
 		return
 	}
 	if !isMember {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "you are not a member of this room"})
+		writeError(w, http.StatusForbidden, CodeNotMember, "you are not a member of this room")
 		return
 	}
 
@@ -281,7 +389,7 @@ func (s *Server) handleGetRoomMessages(w http.ResponseWriter, r *http.Request) {
 	// ==========================
 	msgs, err := s.roomRepo.GetRoomMessages(roomID, beforeID, beforeAt, limit, userID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		writeError(w, http.StatusInternalServerError, CodeDBError, "db error")
 		return
 	}
 
@@ -301,7 +409,9 @@ func (s *Server) handleGetRoomMessages(w http.ResponseWriter, r *http.Request) {
 			ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 			defer cancel()
 
-			_ = s.roomRepo.MarkRoomSeenUpTo(ctx, roomID, userID, newestID)
+			if err := s.roomRepo.MarkRoomSeenUpTo(ctx, roomID, userID, newestID); err == nil {
+				go s.pushUnreadToSelf(roomID, userID)
+			}
 
 			memberIDs, err := s.roomRepo.GetRoomMemberIDs(roomID)
 			if err == nil && len(memberIDs) > 0 {
@@ -312,7 +422,7 @@ func (s *Server) handleGetRoomMessages(w http.ResponseWriter, r *http.Request) {
 				if s.userRepo != nil {
 					if u, e := s.userRepo.GetUserBrief(ctx, userID); e == nil && u != nil {
 						briefName = u.FullName
-						briefAvatar = u.AvatarURL
+						briefAvatar = s.signMediaURL(u.AvatarURL)
 					}
 				}
 
@@ -324,7 +434,7 @@ func (s *Server) handleGetRoomMessages(w http.ResponseWriter, r *http.Request) {
 						"full_name":            briefName,
 						"avatar_url":           briefAvatar,
 						"last_seen_message_id": newestID,
-						"last_seen_at":         time.Now().Format(time.RFC3339),
+						"last_seen_at":         s.now().Format(time.RFC3339),
 					},
 				}
 
@@ -344,45 +454,81 @@ func (s *Server) handleGetRoomMessages(w http.ResponseWriter, r *http.Request) {
 	// ✅ Response mapping
 	// ==========================
 	respMsgs := make([]RoomMessageResponse, 0, len(msgs))
+	uploadRooms := s.messageUploadRooms(r.Context(), msgs...)
+	for _, m := range msgs {
+		respMsgs = append(respMsgs, s.toRoomMessageResponse(m, uploadRooms))
+	}
+	s.attachLinkPreviews(r.Context(), respMsgs)
+	s.attachReceiptStatus(r.Context(), roomID, userID, respMsgs)
+
+	writeJSON(w, http.StatusOK, getRoomMessagesResponse{Messages: respMsgs})
+}
+
+// messageUploadRooms: room lúc upload của file trong content các tin (ký content, xem signMessageContent)
+func (s *Server) messageUploadRooms(ctx context.Context, msgs ...*room.Message) map[string]int64 {
+	names := make([]string, 0, len(msgs))
 	for _, m := range msgs {
-		createdAtStr := ""
-		if !m.CreatedAt.IsZero() {
-			createdAtStr = m.CreatedAt.Format(time.RFC3339)
+		if name, ok := mediaContentName(m.Type, m.Content); ok {
+			names = append(names, name)
 		}
+	}
+	return s.uploadRooms(ctx, names)
+}
 
-		var reply *ReplyInfoResponse
-		if m.ReplyToMessageID > 0 {
-			reply = &ReplyInfoResponse{
-				MessageID:   m.ReplyToMessageID,
-				Preview:     m.ReplyPreview,
-				SenderName:  m.ReplySenderName,
-				MessageType: m.ReplyMessageType,
-			}
+// toRoomMessageResponse: room.Message -> response (ký media URL)
+func (s *Server) toRoomMessageResponse(m *room.Message, uploadRooms map[string]int64) RoomMessageResponse {
+	createdAtStr := ""
+	if !m.CreatedAt.IsZero() {
+		createdAtStr = m.CreatedAt.Format(time.RFC3339)
+	}
+
+	var reply *ReplyInfoResponse
+	if m.ReplyToMessageID > 0 {
+		reply = &ReplyInfoResponse{
+			MessageID:   m.ReplyToMessageID,
+			Preview:     m.ReplyPreview,
+			SenderName:  m.ReplySenderName,
+			MessageType: m.ReplyMessageType,
 		}
+	}
 
-		respMsgs = append(respMsgs, RoomMessageResponse{
-			ID:              m.ID,
-			RoomID:          m.RoomID,
-			SenderID:        m.SenderID,
-			SenderName:      m.SenderName,
-			SenderAvatarURL: m.SenderAvatarURL,
+	if m.Blocked {
+		m.Content, m.MediaURL, m.MediaMIME, m.MediaSize, m.MediaPosterURL = "", "", "", 0, ""
+		m.Attachments = nil
+	}
 
-			Content: m.Content,
-			Type:    m.Type,
-			IsTemp:  m.IsTemp,
+	return RoomMessageResponse{
+		ID:              m.ID,
+		RoomID:          m.RoomID,
+		SenderID:        m.SenderID,
+		SenderName:      m.SenderName,
+		SenderAvatarURL: s.signMediaURL(m.SenderAvatarURL),
 
-			MediaURL:  m.MediaURL,
-			MediaMIME: m.MediaMIME,
-			MediaSize: m.MediaSize,
+		Content: s.signMessageContent(m.RoomID, m.Type, m.Content, uploadRooms),
+		Type:    m.Type,
+		IsTemp:  m.IsTemp,
 
-			Reply:     reply,
-			Reactions: m.Reactions,
+		MediaURL:  s.signMediaURL(m.MediaURL),
+		MediaMIME: m.MediaMIME,
+		MediaSize: m.MediaSize,
 
-			CreatedAt: createdAtStr,
-		})
-	}
+		MediaPosterURL: s.signMediaURL(m.MediaPosterURL),
 
-	writeJSON(w, http.StatusOK, getRoomMessagesResponse{Messages: respMsgs})
+		MediaDurationMs: m.MediaDurationMs,
+
+		Blocked: m.Blocked,
+
+		Reply:     reply,
+		Reactions: s.signReactions(m.Reactions),
+
+		Attachments: s.signAttachments(m.Attachments),
+
+		WebhookID: m.WebhookID,
+		Buttons:   m.Buttons,
+		Embeds:    m.Embeds,
+
+		CreatedAt: createdAtStr,
+	}
 }
 
 // Request tạo room direct giữa current user (trong token) và 1 user khác
@@ -391,159 +537,125 @@ type CreateDirectRoomRequest struct {
 }
 
 type CreateDirectRoomResponse struct {
-	Room  *RoomInfoResponse `json:"room,omitempty"`
-	Error string            `json:"error,omitempty"`
+	Room    *RoomInfoResponse `json:"room,omitempty"`
+	Created bool              `json:"created"` // false = cặp đã có room, trả room cũ
 }
 
+// handleCreateDirectRoom: lấy hoặc tạo room direct, gọi lại bao nhiêu lần cũng ra cùng 1 room
+// POST /rooms/direct {user_id}  (POST /rooms/direct/{userID} cho FE cũ)
+// 201 + room.joined (WS) cho cả 2 user khi vừa tạo, 200 khi đã có
 func (s *Server) handleCreateDirectRoom(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		writeJSON(w, http.StatusMethodNotAllowed, CreateDirectRoomResponse{
-			Error: "method not allowed",
-		})
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	// 1. Lấy currentUser từ token
-	currentUserID, err := GetUserIDFromRequest(r, s.jwtSecret)
+	currentUserID, err := UserIDFromContext(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, CreateDirectRoomResponse{
-			Error: err.Error(),
-		})
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
-	// 2. Extract targetUserID từ URL: /rooms/direct/{id}
-	// cắt prefix "/rooms/direct/"
-	path := strings.TrimPrefix(r.URL.Path, "/rooms/direct/")
-	path = strings.Trim(path, "/")
-
-	targetID, err := strconv.ParseInt(path, 10, 64)
+	var targetID int64
+	if path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/rooms/direct"), "/"); path != "" {
+		targetID, err = strconv.ParseInt(path, 10, 64)
+	} else {
+		var req CreateDirectRoomRequest
+		err = json.NewDecoder(r.Body).Decode(&req)
+		targetID = req.UserID
+	}
 	if err != nil || targetID <= 0 {
-		writeJSON(w, http.StatusBadRequest, CreateDirectRoomResponse{
-			Error: "invalid target user id",
-		})
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid target user id")
 		return
 	}
-
 	if targetID == currentUserID {
-		writeJSON(w, http.StatusBadRequest, CreateDirectRoomResponse{
-			Error: "cannot create direct room with yourself",
-		})
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "cannot create direct room with yourself")
 		return
 	}
 
-	// 3. Kiểm tra đã tồn tại direct-room giữa 2 thằng chưa
-	existingRoom, err := s.roomRepo.GetDirectRoomBetweenUsers(currentUserID, targetID)
-	if err != nil && err != sql.ErrNoRows {
+	// đã có -> trả luôn, không tính quota
+	existing, err := s.roomRepo.GetDirectRoomBetweenUsers(currentUserID, targetID)
+	if err == nil {
+		writeJSON(w, http.StatusOK, CreateDirectRoomResponse{Room: directRoomInfo(existing)})
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
 		log.Println("GetDirectRoomBetweenUsers error:", err)
-		writeJSON(w, http.StatusInternalServerError, CreateDirectRoomResponse{
-			Error: "db error",
-		})
+		writeError(w, http.StatusInternalServerError, CodeDBError, "db error")
 		return
 	}
 
-	// 3.1 Nếu đã tồn tại → trả lại luôn
-	if err == nil && existingRoom != nil {
-		resp := &RoomInfoResponse{
-			ID:        existingRoom.ID,
-			Name:      existingRoom.Name,
-			Type:      existingRoom.Type,
-			CreatedBy: existingRoom.CreatedBy,
-			IsActive:  existingRoom.IsActive,
-			CreatedAt: formatTime(existingRoom.CreatedAt),
-			UpdatedAt: formatTime(existingRoom.UpdatedAt),
+	target, err := s.userRepo.GetUserByID(int(targetID))
+	if err != nil || target.Is_active == 0 {
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Println("GetUserByID error:", err)
+			writeError(w, http.StatusInternalServerError, CodeDBError, "db error")
+			return
 		}
-		writeJSON(w, http.StatusOK, CreateDirectRoomResponse{Room: resp})
+		writeError(w, http.StatusNotFound, CodeNotFound, "user not found")
 		return
 	}
 
-	// 4. Tạo room mới
-	var a, b int64
-	if currentUserID < targetID {
-		a, b = currentUserID, targetID
-	} else {
-		a, b = targetID, currentUserID
-	}
-	roomName := "direct-" + strconv.FormatInt(a, 10) + "-" + strconv.FormatInt(b, 10)
-
-	newRoom := &room.Room{
-		Name:      roomName,
-		Type:      "direct",
-		CreatedBy: currentUserID,
-		IsActive:  1,
+	// giới hạn số room tạo mới mỗi ngày
+	if s.rejectIfOverQuota(w, r, currentUserID, quota.KindRoomsPerDay) {
+		return
 	}
 
-	roomID, err := s.roomRepo.CreateRoom(newRoom)
+	rm, created, err := s.roomRepo.CreateDirectRoom(r.Context(), currentUserID, targetID)
 	if err != nil {
-		log.Println("CreateRoom error:", err)
-		writeJSON(w, http.StatusInternalServerError, CreateDirectRoomResponse{
-			Error: "db error",
-		})
+		log.Println("CreateDirectRoom error:", err)
+		writeError(w, http.StatusInternalServerError, CodeDBError, "db error")
 		return
 	}
 
-	// 5. Add 2 members
-	if err := s.roomRepo.AddMember(roomID, currentUserID, "member"); err != nil {
-		log.Println("AddMember current user error:", err)
-		writeJSON(w, http.StatusInternalServerError, CreateDirectRoomResponse{
-			Error: "db error",
-		})
-		return
-	}
-	if err := s.roomRepo.AddMember(roomID, targetID, "member"); err != nil {
-		log.Println("AddMember target user error:", err)
-		writeJSON(w, http.StatusInternalServerError, CreateDirectRoomResponse{
-			Error: "db error",
-		})
-		return
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
 	}
+	writeJSON(w, status, CreateDirectRoomResponse{Room: directRoomInfo(rm), Created: created})
 
-	// 6. Lấy room lại để trả về
-	createdRoom, err := s.roomRepo.GetRoomByID(roomID)
-	if err != nil {
-		log.Println("GetRoomByID error:", err)
-		writeJSON(w, http.StatusInternalServerError, CreateDirectRoomResponse{
-			Error: "db error",
-		})
-		return
+	if created {
+		s.notifyDirectRoomJoined(rm, currentUserID, targetID)
 	}
+}
 
-	resp := &RoomInfoResponse{
-		ID:        createdRoom.ID,
-		Name:      createdRoom.Name,
-		Type:      createdRoom.Type,
-		CreatedBy: createdRoom.CreatedBy,
-		IsActive:  createdRoom.IsActive,
-		CreatedAt: formatTime(createdRoom.CreatedAt),
-		UpdatedAt: formatTime(createdRoom.UpdatedAt),
+func directRoomInfo(rm *room.Room) *RoomInfoResponse {
+	return &RoomInfoResponse{
+		ID:        rm.ID,
+		Name:      rm.Name,
+		Type:      rm.Type,
+		CreatedBy: rm.CreatedBy,
+		IsActive:  rm.IsActive,
+		CreatedAt: formatTime(rm.CreatedAt),
+		UpdatedAt: formatTime(rm.UpdatedAt),
 	}
+}
 
-	writeJSON(w, http.StatusCreated, CreateDirectRoomResponse{
-		Room: resp,
+// notifyDirectRoomJoined: room.joined cho cả 2 phía (cùng event với accept lời mời group)
+func (s *Server) notifyDirectRoomJoined(rm *room.Room, userIDs ...int64) {
+	wsSendToUsers(userIDs, wsEnvelope{
+		Type:   "room.joined",
+		RoomID: rm.ID,
+		Data:   map[string]any{"room": rm},
 	})
 }
 
 type GetDirectPartnerNameResponse struct {
 	FullName string `json:"full_name,omitempty"`
-	Error    string `json:"error,omitempty"`
 }
 
 // GET /rooms/direct-name/{room_id}
 // Header: Authorization: Bearer <access_token>
 func (s *Server) handleGetDirectPartnerName(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeJSON(w, http.StatusMethodNotAllowed, GetDirectPartnerNameResponse{
-			Error: "method not allowed",
-		})
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	// 1. Lấy current user từ token
-	currentUserID, err := GetUserIDFromRequest(r, s.jwtSecret)
+	currentUserID, err := UserIDFromContext(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, GetDirectPartnerNameResponse{
-			Error: err.Error(),
-		})
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
@@ -553,9 +665,7 @@ func (s *Server) handleGetDirectPartnerName(w http.ResponseWriter, r *http.Reque
 
 	roomID, err := strconv.ParseInt(path, 10, 64)
 	if err != nil || roomID <= 0 {
-		writeJSON(w, http.StatusBadRequest, GetDirectPartnerNameResponse{
-			Error: "invalid room id",
-		})
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid room id")
 		return
 	}
 
@@ -564,16 +674,12 @@ func (s *Server) handleGetDirectPartnerName(w http.ResponseWriter, r *http.Reque
 	if err != nil {
 		if err == sql.ErrNoRows {
 			// room không tồn tại / không phải direct / current user không thuộc room / không tìm được partner
-			writeJSON(w, http.StatusNotFound, GetDirectPartnerNameResponse{
-				Error: "direct partner not found for this room",
-			})
+			writeError(w, http.StatusNotFound, CodeNotFound, "direct partner not found for this room")
 			return
 		}
 
 		log.Println("GetDirectPartnerFullNameByRoomID error:", err)
-		writeJSON(w, http.StatusInternalServerError, GetDirectPartnerNameResponse{
-			Error: "db error",
-		})
+		writeError(w, http.StatusInternalServerError, CodeDBError, "db error")
 		return
 	}
 
@@ -590,39 +696,35 @@ type createGroupRoomRequest struct {
 
 func (s *Server) handleCreateGroupRoom(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	userID, err := GetUserIDFromRequest(r, s.jwtSecret)
+	userID, err := UserIDFromContext(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{
-			"error": "unauthorized",
-		})
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	var req createGroupRoomRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error": "invalid JSON",
-		})
+		writeError(w, http.StatusBadRequest, CodeInvalidJSON, "invalid JSON")
 		return
 	}
 
 	if strings.TrimSpace(req.Name) == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error": "name is required",
-		})
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "name is required")
+		return
+	}
+
+	if s.rejectIfOverQuota(w, r, userID, quota.KindRoomsPerDay) {
 		return
 	}
 
 	room, err := s.roomRepo.CreateGroupRoom(req.Name, userID, req.MemberIDs)
 	if err != nil {
 		log.Println("CreateGroupRoom error:", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{
-			"error": "db error",
-		})
+		writeError(w, http.StatusInternalServerError, CodeDBError, "db error")
 		return
 	}
 
@@ -635,41 +737,33 @@ type addMembersRequest struct {
 }
 
 type addMembersResponse struct {
-	Added   []int64 `json:"added,omitempty"`   // user_id add thành công
+	Invited []int64 `json:"invited,omitempty"` // user_id đã gửi lời mời
+	Added   []int64 `json:"added,omitempty"`   // bot vào room luôn
 	Skipped []int64 `json:"skipped,omitempty"` // đã ở trong room / input lỗi
-	Error   string  `json:"error,omitempty"`
 }
 
 func (s *Server) handleAddUserToRoom(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeJSON(w, http.StatusMethodNotAllowed, addMembersResponse{
-			Error: "method not allowed",
-		})
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	// 1. Lấy current user từ token
-	currentUserID, err := GetUserIDFromRequest(r, s.jwtSecret)
+	currentUserID, err := UserIDFromContext(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, addMembersResponse{
-			Error: err.Error(),
-		})
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
 	// 2. Parse body
 	var req addMembersRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, addMembersResponse{
-			Error: "invalid JSON",
-		})
+		writeError(w, http.StatusBadRequest, CodeInvalidJSON, "invalid JSON")
 		return
 	}
 
 	if req.RoomID <= 0 || len(req.UserIDs) == 0 {
-		writeJSON(w, http.StatusBadRequest, addMembersResponse{
-			Error: "room_id and user_ids are required",
-		})
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "room_id and user_ids are required")
 		return
 	}
 
@@ -677,112 +771,55 @@ func (s *Server) handleAddUserToRoom(w http.ResponseWriter, r *http.Request) {
 	isMember, err := s.roomRepo.IsUserInRoom(req.RoomID, currentUserID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			writeJSON(w, http.StatusForbidden, addMembersResponse{
-				Error: "you are not a member of this room",
-			})
+			writeError(w, http.StatusForbidden, CodeNotMember, "you are not a member of this room")
 			return
 		}
 		log.Println("IsUserInRoom (current user) error:", err)
-		writeJSON(w, http.StatusInternalServerError, addMembersResponse{
-			Error: "db error",
-		})
+		writeError(w, http.StatusInternalServerError, CodeDBError, "db error")
 		return
 	}
 	if !isMember {
-		writeJSON(w, http.StatusForbidden, addMembersResponse{
-			Error: "you are not a member of this room",
-		})
+		writeError(w, http.StatusForbidden, CodeNotMember, "you are not a member of this room")
 		return
 	}
 
-	added := make([]int64, 0)
-	skipped := make([]int64, 0)
-
-	// 4. Loop qua list user_ids
-	for _, uid := range req.UserIDs {
-		if uid <= 0 {
-			skipped = append(skipped, uid)
-			continue
-		}
-
-		// tự add chính mình thì bỏ qua
-		if uid == currentUserID {
-			skipped = append(skipped, uid)
-			continue
-		}
-
-		// Check target user đã ở trong room chưa
-		targetIsMember, err := s.roomRepo.IsUserInRoom(req.RoomID, uid)
-		if err != nil && err != sql.ErrNoRows {
-			log.Println("IsUserInRoom (target user) error:", err)
-			skipped = append(skipped, uid)
-			continue
-		}
-		if targetIsMember {
-			skipped = append(skipped, uid)
-			continue
+	// 4. Không add thẳng nữa -> tạo lời mời, user accept qua POST /invites/{id}/accept
+	resp, err := s.inviteUsers(r.Context(), req.RoomID, currentUserID, req.UserIDs)
+	if err != nil {
+		if errors.Is(err, errInviteForbidden) {
+			writeError(w, http.StatusForbidden, CodeForbidden, "only group members can invite")
+			return
 		}
-
-		// Thêm member, role default = "member"
-		if err := s.roomRepo.AddMember(req.RoomID, uid, "member"); err != nil {
-			log.Println("AddMember error:", err)
-			skipped = append(skipped, uid)
-			continue
+		if errors.Is(err, room.ErrRoomNotFound) {
+			writeError(w, http.StatusNotFound, CodeNotFound, "room not found")
+			return
 		}
-
-		added = append(added, uid)
+		log.Println("inviteUsers error:", err)
+		writeError(w, http.StatusInternalServerError, CodeDBError, "db error")
+		return
 	}
 
-	// ====== ✅ 5) Realtime emit (sau khi add xong) ======
-	if len(added) > 0 {
-		// 5.1) Broadcast cho toàn bộ members trong room (owner/current user cũng phải nhận)
-		memberIDs, _ := s.roomRepo.GetRoomMemberIDs(req.RoomID)
-		// chắc kèo include current user + new users
-		memberIDs = append(memberIDs, currentUserID)
-		memberIDs = append(memberIDs, added...)
-
-		wsSendToUsers(memberIDs, wsEnvelope{
-			Type:   "room.member_added",
-			RoomID: req.RoomID,
-			Data: map[string]any{
-				"user_ids": added,
-				"added_by": currentUserID,
-			},
-		})
-
-		// 5.2) Gửi riêng cho user mới vào: room.joined (để FE add room vào sidebar ngay)
-		// (khuyên có) — nếu mày chưa có repo GetRoomByID thì tạm bỏ block này vẫn chạy được
-		if room, err := s.roomRepo.GetRoomByID(req.RoomID); err == nil && room != nil {
-			for _, uid := range added {
-				wsSendToUser(uid, wsEnvelope{
-					Type:   "room.joined",
-					RoomID: req.RoomID,
-					Data: map[string]any{
-						"room": room, // full room info cho sidebar
-					},
-				})
-			}
-		}
+	invited := make([]int64, 0, len(resp.Invited))
+	for _, inv := range resp.Invited {
+		invited = append(invited, inv.InviteeID)
 	}
-
 	writeJSON(w, http.StatusOK, addMembersResponse{
-		Added:   added,
-		Skipped: skipped,
+		Invited: invited,
+		Added:   resp.Added,
+		Skipped: resp.Skipped,
 	})
 }
 
 func (s *Server) handleMarkRoomAsRead(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	// lấy userID từ token (tuỳ m implement middleware)
-	userID, err := GetUserIDFromRequest(r, s.jwtSecret)
+	userID, err := UserIDFromContext(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, addMembersResponse{
-			Error: err.Error(),
-		})
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
@@ -792,15 +829,16 @@ func (s *Server) handleMarkRoomAsRead(w http.ResponseWriter, r *http.Request) {
 
 	roomID, err := strconv.ParseInt(path, 10, 64)
 	if err != nil || roomID <= 0 {
-		http.Error(w, "invalid room id", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid room id")
 		return
 	}
 
 	if err := s.roomRepo.MarkRoomAsRead(roomID, userID); err != nil {
 		log.Println("MarkRoomAsRead error:", err)
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
 		return
 	}
+	go s.pushUnreadToSelf(roomID, userID)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -829,17 +867,13 @@ type GetRoomMembersResponse struct {
 
 func (s *Server) handleGetRoomMembers(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{
-			"error": "method not allowed",
-		})
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	// bắt buộc login
-	if _, err := GetUserIDFromRequest(r, s.jwtSecret); err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{
-			"error": "unauthorized",
-		})
+	if _, err := UserIDFromContext(r.Context()); err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "unauthorized")
 		return
 	}
 
@@ -847,37 +881,34 @@ func (s *Server) handleGetRoomMembers(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 	prefix := "/rooms/members/"
 	if !strings.HasPrefix(path, prefix) {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error": "invalid path",
-		})
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid path")
 		return
 	}
 
 	roomIDStr := strings.TrimPrefix(path, prefix)
 	if roomIDStr == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error": "missing room id",
-		})
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "missing room id")
 		return
 	}
 
 	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
 	if err != nil || roomID <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error": "invalid room id",
-		})
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid room id")
 		return
 	}
 
 	members, err := s.roomRepo.GetRoomMembers(roomID)
 	if err != nil {
 		log.Printf("GetRoomMembers error: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{
-			"error": "db error",
-		})
+		writeError(w, http.StatusInternalServerError, CodeDBError, "db error")
 		return
 	}
 
+	// ký avatar để FE load được static media
+	for _, m := range members {
+		m.AvatarURL = s.signMediaURL(m.AvatarURL)
+	}
+
 	// ⭐ Bọc lại thành JSON object
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"members": members,
@@ -885,93 +916,51 @@ func (s *Server) handleGetRoomMembers(w http.ResponseWriter, r *http.Request) {
 }
 
 // trong package httpserver
-func (s *Server) handleDeleteUserGroup(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleDeleteUserGroup(w http.ResponseWriter, r *http.Request, roomID, targetUserID int64) {
 	if r.Method != http.MethodDelete {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{
-			"error": "method not allowed",
-		})
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	// ====== 1) Lấy user từ token (để kiểm tra quyền) ======
-	requesterID, err := GetUserIDFromRequest(r, s.jwtSecret)
+	requesterID, err := UserIDFromContext(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{
-			"error": "unauthorized",
-		})
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "unauthorized")
 		return
 	}
 
-	// ====== 2) Parse roomID & userID từ URL ======
-	path := r.URL.Path // /rooms/3/members/10
-	prefix := "/rooms/"
-	if !strings.HasPrefix(path, prefix) {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error": "invalid path",
-		})
-		return
-	}
+	// ====== 2) roomID & userID lấy từ path (/rooms/{roomID}/members/{userID}) ======
 
-	rest := strings.TrimPrefix(path, prefix) // 3/members/10
-	parts := strings.Split(rest, "/")
-	if len(parts) != 3 || parts[1] != "members" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error": "invalid path format",
-		})
-		return
-	}
-
-	roomID, err := strconv.ParseInt(parts[0], 10, 64)
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error": "invalid room id",
-		})
+	// ====== 3) Check requester có quyền kick (owner / admin / moderator) ======
+	requesterRole, ok := s.roomPermission(w, r.Context(), roomID, requesterID, room.PermKickMember)
+	if !ok {
 		return
 	}
 
-	targetUserID, err := strconv.ParseInt(parts[2], 10, 64)
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error": "invalid user id",
-		})
+	// ====== 4) Không tự kick mình, chỉ kick được người role thấp hơn ======
+	if targetUserID == requesterID {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "cannot remove yourself")
 		return
 	}
-
-	// ====== 3) Check requester có phải owner của group không ======
-	ownerID, err := s.roomRepo.GetRoomOwner(roomID)
+	targetRole, err := s.roomRepo.GetMemberRole(r.Context(), roomID, targetUserID)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error": "cannot retrieve room owner",
-		})
+		writeError(w, http.StatusNotFound, CodeNotFound, "user is not a member of this room")
 		return
 	}
-
-	if requesterID != ownerID {
-		writeJSON(w, http.StatusForbidden, map[string]string{
-			"error": "only owner can remove members",
-		})
-		return
-	}
-
-	// ====== 4) Không cho owner tự kick chính mình ======
-	if targetUserID == ownerID {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error": "owner cannot remove himself",
-		})
+	if room.RoleRank(targetRole) >= room.RoleRank(requesterRole) {
+		writeError(w, http.StatusForbidden, CodeForbidden, "cannot remove a member with equal or higher role")
 		return
 	}
 
 	// ====== 5) Gọi repository để xóa ======
 	err = s.roomRepo.DeleteUserGroup(roomID, targetUserID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{
-			"error": err.Error(),
-		})
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
 	memberIDs, _ := s.roomRepo.GetRoomMemberIDs(roomID)
-	memberIDs = append(memberIDs, requesterID)  // đảm bảo owner cũng nhận
+	memberIDs = append(memberIDs, requesterID)  // đảm bảo người kick cũng nhận
 	memberIDs = append(memberIDs, targetUserID) // đảm bảo thằng bị kick cũng nhận
 
 	wsSendToUsers(memberIDs, wsEnvelope{
@@ -982,23 +971,23 @@ func (s *Server) handleDeleteUserGroup(w http.ResponseWriter, r *http.Request) {
 			"removed_by": requesterID,
 		},
 	})
+	s.emitWebhook(roomID, requesterID, webhook.EventMemberRemoved, map[string]any{
+		"user_id":    targetUserID,
+		"removed_by": requesterID,
+	})
 }
 
 // DELETE /rooms/delete/{roomID}
 func (s *Server) handleDeleteRoom(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{
-			"error": "method not allowed",
-		})
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	// bắt buộc login
-	userID, err := GetUserIDFromRequest(r, s.jwtSecret)
+	userID, err := UserIDFromContext(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{
-			"error": "unauthorized",
-		})
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "unauthorized")
 		return
 	}
 
@@ -1006,25 +995,19 @@ func (s *Server) handleDeleteRoom(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 	const prefix = "/rooms/delete/"
 	if !strings.HasPrefix(path, prefix) {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error": "invalid path",
-		})
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid path")
 		return
 	}
 
 	roomIDStr := strings.TrimPrefix(path, prefix)
 	if roomIDStr == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error": "missing room id",
-		})
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "missing room id")
 		return
 	}
 
 	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error": "invalid room id",
-		})
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid room id")
 		return
 	}
 
@@ -1042,12 +1025,12 @@ func (s *Server) handleDeleteRoom(w http.ResponseWriter, r *http.Request) {
 			status = http.StatusBadRequest
 		}
 
-		writeJSON(w, status, map[string]string{
-			"error": msg,
-		})
+		writeError(w, status, "", msg)
 		return
 	}
 
+	s.recordAudit(r, userID, audit.ActionRoomDelete, audit.TargetRoom, roomID, nil)
+
 	writeJSON(w, http.StatusOK, map[string]any{
 		"status":  "ok",
 		"room_id": roomID,
@@ -1067,59 +1050,41 @@ type MessageDTO struct {
 	CreatedAt       string `json:"created_at"`
 }
 
-// POST /rooms/upload-image/{roomID}
+// POST /rooms/upload-image/{roomID}[?send=true]
 // multipart/form-data: file=<image>
+// send=true: tạo luôn message (content=<caption, optional>, reply_to_message_id=<optional>),
+// response = thông tin upload + message, cả room nhận message_created -> FE không gọi send-messages nữa
+// message qua createUploadMessage: bị chặn như send-messages (tài khoản khoá, quota, word filter caption)
 func (s *Server) handleUploadRoomImage(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// 1) auth
-	userID, err := GetUserIDFromRequest(r, s.jwtSecret)
-	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
-		return
-	}
-
-	// 2) parse roomID from URL: /rooms/upload-image/{roomID}
-	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-	if len(parts) < 3 {
-		http.Error(w, "invalid path", http.StatusBadRequest)
-		return
-	}
-	roomIDStr := parts[len(parts)-1]
-	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
-	if err != nil || roomID <= 0 {
-		http.Error(w, "invalid room id", http.StatusBadRequest)
+	// 1) auth + roomID + check member
+	userID, roomID, ok := s.authorizeRoomUpload(w, r)
+	if !ok {
 		return
 	}
 
-	// 3) check member
-	ok, err := s.roomRepo.IsUserInRoom(roomID, int64(userID))
-	if err != nil {
-		http.Error(w, "member check failed", http.StatusInternalServerError)
-		return
-	}
-	if !ok {
-		http.Error(w, "forbidden", http.StatusForbidden)
+	// 2) parse multipart (limit 10MB)
+	if err := r.ParseMultipartForm(chatUploadMaxBytes); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "cannot parse form")
 		return
 	}
 
-	// 4) parse multipart (limit 10MB)
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		http.Error(w, "cannot parse form", http.StatusBadRequest)
-		return
+	q := r.URL.Query().Get("send")
+	send := q == "1" || q == "true"
+	var replyTo *int64
+	if send {
+		if replyTo, ok = formReplyTo(w, r); !ok {
+			return
+		}
 	}
 
 	file, header, err := r.FormFile("file")
 	if err != nil {
-		http.Error(w, "missing file", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "missing file")
 		return
 	}
 	defer file.Close()
 
-	// 5) sniff mime
+	// 3) sniff mime
 	const sniffLen = 512
 	head := make([]byte, sniffLen)
 	n, _ := file.Read(head)
@@ -1131,58 +1096,83 @@ func (s *Server) handleUploadRoomImage(w http.ResponseWriter, r *http.Request) {
 		_ = file.Close()
 		file, header, err = r.FormFile("file")
 		if err != nil {
-			http.Error(w, "file read error", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "file read error")
 			return
 		}
 		defer file.Close()
 	}
 
-	mime := http.DetectContentType(head[:n])
-	if !isAllowedImageMime(mime) {
-		http.Error(w, "unsupported image type", http.StatusBadRequest)
+	mime, ext, err := s.resolveChatUploadMime(head[:n], header.Header.Get("Content-Type"), header.Filename)
+	if err != nil {
+		writeChatUploadError(w, err)
 		return
 	}
 
-	// 6) ensure upload dir exists
-	if err := os.MkdirAll(s.chatUploadDir, 0o755); err != nil {
-		http.Error(w, "cannot create upload dir", http.StatusInternalServerError)
+	// 4) lưu file (+ thumb / medium cho ảnh)
+	up, err := s.storeChatUploadLimit(roomID, userID, mime, ext, header.Filename, file, chatUploadMaxBytes)
+	defer s.releaseChatUpload(up)
+	if err != nil {
+		writeChatUploadError(w, err)
 		return
 	}
-
-	// 7) filename
-	ext := strings.ToLower(filepath.Ext(header.Filename))
-	if ext == "" {
-		ext = mimeToExt(mime)
+	if isAllowedImageMime(up.Mime) {
+		s.storeImageVariants(roomID, userID, up)
 	}
-	filename := fmt.Sprintf("r%d_u%d_%d%s", roomID, userID, time.Now().UnixNano(), ext)
-	fullPath := filepath.Join(s.chatUploadDir, filename)
 
-	out, err := os.Create(fullPath)
-	if err != nil {
-		http.Error(w, "cannot save file", http.StatusInternalServerError)
+	// 5) return json
+	if !send {
+		s.writeChatUploadResponse(w, roomID, up)
 		return
 	}
-	defer out.Close()
 
-	if _, err = io.Copy(out, file); err != nil {
-		_ = os.Remove(fullPath)
-		http.Error(w, "save file error", http.StatusInternalServerError)
+	// 6) send=true: message + attachment + media_* trong 1 tx
+	ctx := r.Context()
+	att := chat.Attachment{
+		FileName:    up.OriginalName,
+		FileSize:    up.Size,
+		ContentType: up.Mime,
+		FilePath:    up.MediaURL,
+		ThumbURL:    up.ThumbURL,
+		MediumURL:   up.MediumURL,
+		CreatedAt:   s.now().UTC(),
+	}
+	up.applyMeta(&att)
+
+	msgType := "file"
+	if isAllowedImageMime(up.Mime) {
+		msgType = "image"
+	}
+	// không có caption -> content = media_url (client cũ vẫn render được)
+	content := strings.TrimSpace(r.FormValue("content"))
+	if content == "" {
+		content = up.MediaURL
+	}
+
+	stored := []string{up.Filename}
+	for _, u := range []string{up.ThumbURL, up.MediumURL} {
+		if u != "" {
+			stored = append(stored, strings.TrimPrefix(u, chatUploadPrefix))
+		}
+	}
+	msg, ok := s.createUploadMessage(w, r, &chat.Message{
+		RoomID:           roomID,
+		SenderID:         userID,
+		Content:          content,
+		MessageType:      msgType,
+		ReplyToMessageID: replyTo,
+		MediaURL:         up.MediaURL,
+		MediaMIME:        up.Mime,
+		MediaSize:        up.Size,
+	}, []chat.Attachment{att}, stored)
+	if !ok {
 		return
 	}
 
-	// 8) media url (FE sẽ dùng url này để insert message)
-	mediaURL := "/static/chat_uploads/" + filename
+	resp := s.chatUploadResponse(roomID, up)
+	resp["message"] = msg
+	writeJSON(w, http.StatusOK, resp)
 
-	// 9) return json
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]any{
-		"ok":        true,
-		"room_id":   roomID,
-		"media_url": mediaURL,
-		"filename":  filename,
-		"mime":      mime,
-		"size":      header.Size,
-	})
+	s.broadcastMessageCreated(ctx, roomID, userID, msg)
 }
 
 func isAllowedImageMime(m string) bool {
@@ -1202,6 +1192,22 @@ func mimeToExt(m string) string {
 		return ".webp"
 	case "image/gif":
 		return ".gif"
+	case "video/mp4":
+		return ".mp4"
+	case "video/webm":
+		return ".webm"
+	case "video/quicktime":
+		return ".mov"
+	case "audio/wav":
+		return ".wav"
+	case "audio/mpeg":
+		return ".mp3"
+	case "audio/ogg":
+		return ".ogg"
+	case "audio/webm":
+		return ".weba"
+	case "audio/mp4":
+		return ".m4a"
 	default:
 		return ".jpg"
 	}
@@ -0,0 +1,275 @@
+package httpserver
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCreateDirectRoom(t *testing.T) {
+	const findDirect = `FROM direct_rooms d JOIN rooms r ON r.id = d.room_id WHERE d.user_a = \? AND d.user_b = \?`
+
+	// tx của room.Repository.CreateDirectRoom: room -> giữ cặp -> 2 member
+	expectCreate := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`SELECT \* FROM users WHERE id = \?`).WithArgs(2).
+			WillReturnRows(userRow(2, "bob", "x", 1))
+		mock.ExpectQuery(findDirect).WithArgs(int64(1), int64(2)).WillReturnError(sql.ErrNoRows)
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO rooms`).WithArgs("direct-1-2", int64(1)).
+			WillReturnResult(sqlmock.NewResult(8, 1))
+		mock.ExpectExec(`INSERT INTO direct_rooms`).WithArgs(int64(1), int64(2), int64(8)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		body        string
+		noAuth      bool
+		setup       func(mock sqlmock.Sqlmock)
+		wantStatus  int
+		wantRoomID  float64
+		wantCreated bool
+	}{
+		{
+			name:       "no token",
+			path:       "/rooms/direct",
+			body:       `{"user_id":2}`,
+			noAuth:     true,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			// regression: GET từng là method duy nhất được nhận dù doc ghi POST
+			name:       "GET is rejected",
+			method:     http.MethodGet,
+			path:       "/rooms/direct/2",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{name: "invalid json", path: "/rooms/direct", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "missing user_id", path: "/rooms/direct", body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid path id", path: "/rooms/direct/abc", wantStatus: http.StatusBadRequest},
+		{name: "with yourself", path: "/rooms/direct", body: `{"user_id":1}`, wantStatus: http.StatusBadRequest},
+		{
+			name: "returns existing room",
+			path: "/rooms/direct",
+			body: `{"user_id":2}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(findDirect).WithArgs(int64(1), int64(2)).
+					WillReturnRows(roomRow(7, "direct-1-2", "direct", 2))
+			},
+			wantStatus: http.StatusOK,
+			wantRoomID: 7,
+		},
+		{
+			name: "legacy path route",
+			path: "/rooms/direct/2",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(findDirect).WithArgs(int64(1), int64(2)).
+					WillReturnRows(roomRow(7, "direct-1-2", "direct", 2))
+			},
+			wantStatus: http.StatusOK,
+			wantRoomID: 7,
+		},
+		{
+			name: "unknown user",
+			path: "/rooms/direct",
+			body: `{"user_id":2}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(findDirect).WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery(`SELECT \* FROM users WHERE id = \?`).WithArgs(2).WillReturnError(sql.ErrNoRows)
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "creates room",
+			path: "/rooms/direct",
+			body: `{"user_id":2}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(findDirect).WillReturnError(sql.ErrNoRows)
+				expectCreate(mock)
+				mock.ExpectExec(`INSERT INTO room_members`).WithArgs(int64(8), int64(1)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(`INSERT INTO room_members`).WithArgs(int64(8), int64(2)).
+					WillReturnResult(sqlmock.NewResult(2, 1))
+				mock.ExpectCommit()
+				mock.ExpectQuery(`FROM rooms WHERE id = \?`).WithArgs(int64(8)).
+					WillReturnRows(roomRow(8, "direct-1-2", "direct", 1))
+			},
+			wantStatus:  http.StatusCreated,
+			wantRoomID:  8,
+			wantCreated: true,
+		},
+		{
+			name: "concurrent create returns winner",
+			path: "/rooms/direct",
+			body: `{"user_id":2}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(findDirect).WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery(`SELECT \* FROM users WHERE id = \?`).WithArgs(2).
+					WillReturnRows(userRow(2, "bob", "x", 1))
+				mock.ExpectQuery(findDirect).WillReturnError(sql.ErrNoRows)
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO rooms`).WillReturnResult(sqlmock.NewResult(9, 1))
+				mock.ExpectExec(`INSERT INTO direct_rooms`).
+					WillReturnError(errors.New("Error 1062 (23000): Duplicate entry '1-2' for key 'direct_rooms.PRIMARY'"))
+				mock.ExpectRollback()
+				mock.ExpectQuery(findDirect).WithArgs(int64(1), int64(2)).
+					WillReturnRows(roomRow(7, "direct-1-2", "direct", 2))
+			},
+			wantStatus: http.StatusOK,
+			wantRoomID: 7,
+		},
+		{
+			name: "db error",
+			path: "/rooms/direct",
+			body: `{"user_id":2}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(findDirect).WillReturnError(sql.ErrConnDone)
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newTestServer(t)
+			if tt.setup != nil {
+				tt.setup(mock)
+			}
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			token := ""
+			if !tt.noAuth {
+				token = accessTokenFor(t, 1)
+			}
+
+			rec := serve(s, method, tt.path, token, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			checkExpectations(t, mock)
+			if tt.wantRoomID == 0 {
+				return
+			}
+			body := decodeBody(t, rec)
+			got, _ := body["room"].(map[string]any)
+			if got["id"] != tt.wantRoomID || got["type"] != "direct" || body["created"] != tt.wantCreated {
+				t.Fatalf("body = %v, want room %v created %v", body, tt.wantRoomID, tt.wantCreated)
+			}
+		})
+	}
+}
+
+func TestCreateGroupRoom(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		setup      func(mock sqlmock.Sqlmock)
+		wantStatus int
+	}{
+		{name: "invalid json", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "missing name", body: `{"name":"  "}`, wantStatus: http.StatusBadRequest},
+		{
+			name: "ok",
+			body: `{"name":"team","member_ids":[1]}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO rooms \(name, type, created_by, is_active\) VALUES \(\?, 'group', \?, 1\)`).
+					WithArgs("team", int64(1)).
+					WillReturnResult(sqlmock.NewResult(9, 1))
+				mock.ExpectExec(`INSERT INTO room_members`).WithArgs(int64(9), int64(1), "owner").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "rolled back on member insert error",
+			body: `{"name":"team"}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO rooms`).WillReturnResult(sqlmock.NewResult(9, 1))
+				mock.ExpectExec(`INSERT INTO room_members`).WillReturnError(sql.ErrConnDone)
+				mock.ExpectRollback()
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newTestServer(t)
+			if tt.setup != nil {
+				tt.setup(mock)
+			}
+			rec := serve(s, http.MethodPost, "/rooms/group", accessTokenFor(t, 1), tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			checkExpectations(t, mock)
+		})
+	}
+}
+
+func TestDeleteRoom(t *testing.T) {
+	const findRoom = `SELECT type, created_by FROM rooms WHERE id = \?`
+
+	tests := []struct {
+		name       string
+		path       string
+		setup      func(mock sqlmock.Sqlmock)
+		wantStatus int
+	}{
+		{name: "invalid id", path: "/rooms/delete/abc", wantStatus: http.StatusBadRequest},
+		{
+			name: "not found",
+			path: "/rooms/delete/5",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(findRoom).WithArgs(int64(5)).WillReturnError(sql.ErrNoRows)
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "group member but not owner",
+			path: "/rooms/delete/5",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(findRoom).WithArgs(int64(5)).
+					WillReturnRows(sqlmock.NewRows([]string{"type", "created_by"}).AddRow("group", 2))
+				mock.ExpectQuery(`SELECT member_role FROM room_members`).WithArgs(int64(5), int64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"member_role"}).AddRow("member"))
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "direct room of someone else",
+			path: "/rooms/delete/5",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(findRoom).WithArgs(int64(5)).
+					WillReturnRows(sqlmock.NewRows([]string{"type", "created_by"}).AddRow("direct", 2))
+				mock.ExpectQuery(`SELECT 1 FROM room_members`).WithArgs(int64(5), int64(1)).
+					WillReturnError(sql.ErrNoRows)
+			},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newTestServer(t)
+			if tt.setup != nil {
+				tt.setup(mock)
+			}
+			rec := serve(s, http.MethodDelete, tt.path, accessTokenFor(t, 1), "")
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			checkExpectations(t, mock)
+		})
+	}
+}
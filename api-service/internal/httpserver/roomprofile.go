@@ -0,0 +1,170 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/room"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type updateRoomRequest struct {
+	Name         *string `json:"name"`
+	Description  *string `json:"description"`
+	RemoveAvatar bool    `json:"remove_avatar"`
+}
+
+type roomProfileResponse struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	AvatarURL   string `json:"avatar_url"`
+	UpdatedBy   int64  `json:"updated_by"`
+}
+
+// PATCH /rooms/{roomID} (owner / admin, chỉ group)
+// JSON {name?, description?, remove_avatar?}
+// hoặc multipart/form-data: name=, description=, avatar=<ảnh> (lưu như chat upload)
+func (s *Server) handleUpdateRoom(w http.ResponseWriter, r *http.Request, roomID int64) {
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	if _, ok := s.roomPermission(w, r.Context(), roomID, userID, room.PermRenameRoom); !ok {
+		return
+	}
+
+	var upd room.ProfileUpdate
+	var newAvatar string // file đã lưu, update DB fail thì xoá
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		r.Body = http.MaxBytesReader(w, r.Body, chatUploadMaxBytes+64<<10)
+		if err := r.ParseMultipartForm(chatUploadMaxBytes); err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				writeChatUploadError(w, errUploadTooLarge)
+				return
+			}
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "cannot parse form"})
+			return
+		}
+		defer func() { _ = r.MultipartForm.RemoveAll() }()
+
+		if v, ok := r.MultipartForm.Value["name"]; ok && len(v) > 0 {
+			upd.Name = &v[0]
+		}
+		if v, ok := r.MultipartForm.Value["description"]; ok && len(v) > 0 {
+			upd.Description = &v[0]
+		}
+
+		if file, header, err := r.FormFile("avatar"); err == nil {
+			defer file.Close()
+
+			head := make([]byte, 512)
+			n, _ := io.ReadFull(file, head)
+			mime := http.DetectContentType(head[:n])
+			if !isAllowedImageMime(mime) {
+				writeChatUploadError(w, errUploadUnsupported)
+				return
+			}
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "file read error"})
+				return
+			}
+
+			up, err := s.storeChatUpload(roomID, userID, mime, mimeToExt(mime), header.Filename, file)
+			if err != nil {
+				if !errors.Is(err, errUploadTooLarge) {
+					log.Println("storeChatUpload error:", err)
+				}
+				writeChatUploadError(w, err)
+				return
+			}
+			newAvatar = up.Filename
+			upd.AvatarURL = &up.MediaURL
+		}
+	} else {
+		var req updateRoomRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+			return
+		}
+		upd.Name, upd.Description = req.Name, req.Description
+		if req.RemoveAvatar {
+			empty := ""
+			upd.AvatarURL = &empty
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	fail := func(status int, msg string) {
+		if newAvatar != "" {
+			s.deleteChatUpload(ctx, newAvatar)
+		}
+		writeJSON(w, status, map[string]string{"error": msg})
+	}
+
+	if upd.Name != nil {
+		name := strings.TrimSpace(*upd.Name)
+		if name == "" || utf8.RuneCountInString(name) > room.MaxRoomNameLen {
+			fail(http.StatusBadRequest, "name must be 1-100 characters")
+			return
+		}
+		upd.Name = &name
+	}
+	if upd.Description != nil {
+		desc := strings.TrimSpace(*upd.Description)
+		if utf8.RuneCountInString(desc) > room.MaxRoomDescriptionLen {
+			fail(http.StatusBadRequest, "description must be at most 500 characters")
+			return
+		}
+		upd.Description = &desc
+	}
+	if upd.Empty() {
+		fail(http.StatusBadRequest, "nothing to update")
+		return
+	}
+
+	if err := s.roomRepo.UpdateRoomProfile(ctx, roomID, upd); err != nil {
+		if errors.Is(err, room.ErrRoomNotFound) {
+			fail(http.StatusNotFound, "room not found")
+			return
+		}
+		log.Println("UpdateRoomProfile error:", err)
+		fail(http.StatusInternalServerError, "db error")
+		return
+	}
+
+	p, err := s.roomRepo.GetRoomProfile(ctx, roomID)
+	if err != nil {
+		log.Println("GetRoomProfile error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	resp := roomProfileResponse{
+		ID:          p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+		AvatarURL:   s.signMediaURL(p.AvatarURL),
+		UpdatedBy:   userID,
+	}
+	writeJSON(w, http.StatusOK, resp)
+
+	// avatar cũ không còn ai tham chiếu -> media janitor dọn
+	if memberIDs, err := s.roomRepo.GetRoomMemberIDs(roomID); err == nil {
+		wsSendToUsers(memberIDs, wsEnvelope{
+			Type:   "room_updated",
+			RoomID: roomID,
+			Data:   resp,
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/moderation"
+	"cronhustler/api-service/internal/room"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+type setMemberRoleRequest struct {
+	Role string `json:"role"` // admin | moderator | member
+}
+
+// roomRoutes: route /rooms/{roomID}/... (mount ở "/rooms/", route tĩnh /rooms/xxx/ khớp trước)
+func (s *Server) roomRoutes() http.Handler {
+	m := newPatternMux("/rooms/")
+	m.handle(http.MethodPatch, "/rooms/{roomID}", withRoomID(s.handleUpdateRoom))
+	m.handle(http.MethodPost, "/rooms/{roomID}/mute", withRoomID(s.handleRoomMute))
+	m.handle(http.MethodDelete, "/rooms/{roomID}/mute", withRoomID(s.handleRoomMute))
+	m.handle(http.MethodPost, "/rooms/{roomID}/archive", withRoomID(func(w http.ResponseWriter, r *http.Request, roomID int64) {
+		s.handleRoomArchive(w, r, roomID, true)
+	}))
+	m.handle(http.MethodPost, "/rooms/{roomID}/unarchive", withRoomID(func(w http.ResponseWriter, r *http.Request, roomID int64) {
+		s.handleRoomArchive(w, r, roomID, false)
+	}))
+	m.handle(http.MethodGet, "/rooms/{roomID}/pins", withRoomID(s.handleRoomPins))
+	m.handle(http.MethodPost, "/rooms/{roomID}/invite", withRoomID(s.handleRoomInvite))
+	m.handle(http.MethodGet, "/rooms/{roomID}/retention", withRoomID(s.handleRoomRetention))
+	m.handle(http.MethodPatch, "/rooms/{roomID}/retention", withRoomID(s.handleRoomRetention))
+
+	inviteLinks := withRoomID(func(w http.ResponseWriter, r *http.Request, roomID int64) {
+		s.handleRoomInviteLink(w, r, roomID, "")
+	})
+	m.handle(http.MethodGet, "/rooms/{roomID}/invite-link", inviteLinks)
+	m.handle(http.MethodPost, "/rooms/{roomID}/invite-link", inviteLinks)
+	m.handle(http.MethodDelete, "/rooms/{roomID}/invite-link/{code}", withRoomID(func(w http.ResponseWriter, r *http.Request, roomID int64) {
+		s.handleRoomInviteLink(w, r, roomID, r.PathValue("code"))
+	}))
+
+	m.handle(http.MethodDelete, "/rooms/{roomID}/members/{userID}", withRoomID(withTargetUser(s.handleDeleteUserGroup)))
+	m.handle(http.MethodPost, "/rooms/{roomID}/members/{userID}/role", withRoomID(withTargetUser(s.handleSetMemberRole)))
+	return m
+}
+
+// withTargetUser: thêm {userID} (member bị tác động) cho handler theo room
+func withTargetUser(h func(http.ResponseWriter, *http.Request, int64, int64)) func(http.ResponseWriter, *http.Request, int64) {
+	return func(w http.ResponseWriter, r *http.Request, roomID int64) {
+		targetID, ok := pathID(r, "userID")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid user id"})
+			return
+		}
+		h(w, r, roomID, targetID)
+	}
+}
+
+// roomPermission: role của user trong room + có quyền perm không.
+// room direct không có phân quyền (ai cũng như member). Lỗi đã ghi response -> ok = false
+func (s *Server) roomPermission(w http.ResponseWriter, ctx context.Context, roomID, userID int64, perm room.Permission) (string, bool) {
+	role, err := s.roomRepo.GetMemberRole(ctx, roomID, userID)
+	if err != nil {
+		if errors.Is(err, room.ErrNotMember) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "you are not a member of this room"})
+			return "", false
+		}
+		log.Println("GetMemberRole error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return "", false
+	}
+
+	rm, err := s.roomRepo.GetRoomByIDLite(ctx, roomID)
+	if err != nil {
+		log.Println("GetRoomByIDLite error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return "", false
+	}
+	if rm.Type != "group" {
+		role = room.RoleMember
+	}
+
+	if !room.Can(role, perm) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "permission denied: " + string(perm)})
+		return role, false
+	}
+	return role, true
+}
+
+// POST /rooms/{roomID}/members/{userID}/role {role}
+// admin chỉ gán được moderator / member, owner gán được cả admin
+func (s *Server) handleSetMemberRole(w http.ResponseWriter, r *http.Request, roomID, targetID int64) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var req setMemberRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+	if !room.IsAssignableRole(req.Role) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "role must be admin, moderator or member"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	actorRole, ok := s.roomPermission(w, ctx, roomID, userID, room.PermManageRoles)
+	if !ok {
+		return
+	}
+
+	targetRole, err := s.roomRepo.GetMemberRole(ctx, roomID, targetID)
+	if err != nil {
+		if errors.Is(err, room.ErrNotMember) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "user is not a member of this room"})
+			return
+		}
+		log.Println("GetMemberRole error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	rank := room.RoleRank(actorRole)
+	if targetID == userID || room.RoleRank(targetRole) >= rank || room.RoleRank(req.Role) >= rank {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "cannot change role of this member"})
+		return
+	}
+
+	if err := s.roomRepo.SetMemberRole(ctx, roomID, targetID, req.Role); err != nil {
+		log.Println("SetMemberRole error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	resp := map[string]any{
+		"room_id":    roomID,
+		"user_id":    targetID,
+		"role":       req.Role,
+		"changed_by": userID,
+	}
+	writeJSON(w, http.StatusOK, resp)
+
+	if memberIDs, err := s.roomRepo.GetRoomMemberIDs(roomID); err == nil {
+		wsSendToUsers(memberIDs, wsEnvelope{
+			Type:   "room.member_role_changed",
+			RoomID: roomID,
+			Data:   resp,
+		})
+	}
+}
+
+// DELETE /messages/{id}: người gửi tự xoá, hoặc admin / moderator của group xoá tin người khác
+func (s *Server) handleDeleteMessage(w http.ResponseWriter, r *http.Request, messageID int64) {
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	roomID, senderID, _, err := s.moderationRepo.MessageSnapshot(ctx, messageID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "message not found"})
+			return
+		}
+		log.Println("MessageSnapshot error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	placeholder := moderation.DeletedPlaceholder
+	if senderID == userID {
+		isMember, err := s.roomRepo.IsUserInRoom(roomID, userID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		if !isMember {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "you are not a member of this room"})
+			return
+		}
+	} else {
+		if _, ok := s.roomPermission(w, ctx, roomID, userID, room.PermDeleteMessage); !ok {
+			return
+		}
+		placeholder = moderation.RemovedPlaceholder
+	}
+
+	if err := s.moderationRepo.RemoveMessageAs(ctx, messageID, placeholder); err != nil {
+		log.Println("RemoveMessageAs error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	// tin đã xoá thì bỏ ghim luôn
+	if _, err := s.roomRepo.UnpinMessage(ctx, roomID, messageID); err != nil {
+		log.Println("UnpinMessage error:", err)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"message_id": messageID, "deleted": true})
+	s.broadcastMessageRemoved(roomID, messageID, placeholder)
+}
+
+// POST /messages/{id}/pin -> ghim, DELETE -> bỏ ghim (moderator trở lên)
+func (s *Server) handleMessagePin(w http.ResponseWriter, r *http.Request, messageID int64) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	roomID, err := s.chatRepo.GetMessageRoomID(ctx, messageID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "message not found"})
+			return
+		}
+		log.Println("GetMessageRoomID error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	if _, ok := s.roomPermission(w, ctx, roomID, userID, room.PermPinMessage); !ok {
+		return
+	}
+
+	pinned := r.Method == http.MethodPost
+	if pinned {
+		err = s.roomRepo.PinMessage(ctx, roomID, messageID, userID)
+	} else {
+		var found bool
+		found, err = s.roomRepo.UnpinMessage(ctx, roomID, messageID)
+		if err == nil && !found {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "message is not pinned"})
+			return
+		}
+	}
+	if err != nil {
+		log.Println("PinMessage error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	resp := map[string]any{
+		"room_id":    roomID,
+		"message_id": messageID,
+		"pinned":     pinned,
+		"by":         userID,
+	}
+	writeJSON(w, http.StatusOK, resp)
+
+	if memberIDs, err := s.roomRepo.GetRoomMemberIDs(roomID); err == nil {
+		wsSendToUsers(memberIDs, wsEnvelope{
+			Type:   "message_pin_updated",
+			RoomID: roomID,
+			Data:   resp,
+		})
+	}
+}
+
+// GET /rooms/{roomID}/pins
+func (s *Server) handleRoomPins(w http.ResponseWriter, r *http.Request, roomID int64) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	isMember, err := s.roomRepo.IsUserInRoom(roomID, userID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	if !isMember {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "you are not a member of this room"})
+		return
+	}
+
+	pins, err := s.roomRepo.ListPins(r.Context(), roomID)
+	if err != nil {
+		log.Println("ListPins error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"room_id": roomID, "pins": pins})
+}
@@ -0,0 +1,155 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/retention"
+	"cronhustler/api-service/internal/room"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type roomRetentionRequest struct {
+	TTL string `json:"ttl"` // "off" | "30m" | "24h" | "7d"
+}
+
+type roomRetentionResponse struct {
+	RoomID     int64  `json:"room_id"`
+	TTLSeconds int64  `json:"ttl_seconds"` // 0 = tắt
+	TTL        string `json:"ttl"`
+}
+
+func newRoomRetentionResponse(roomID int64, ttl time.Duration) roomRetentionResponse {
+	resp := roomRetentionResponse{RoomID: roomID, TTLSeconds: int64(ttl / time.Second), TTL: "off"}
+	if ttl > 0 {
+		resp.TTL = formatRoomTTL(ttl)
+	}
+	return resp
+}
+
+// formatRoomTTL: chẵn ngày -> "7d", còn lại theo time.Duration ("36h0m0s")
+func formatRoomTTL(d time.Duration) string {
+	const day = 24 * time.Hour
+	if d%day == 0 {
+		return strconv.FormatInt(int64(d/day), 10) + "d"
+	}
+	return d.String()
+}
+
+// GET /rooms/{roomID}/retention (member), PATCH {ttl} (owner) -> tin tự huỷ sau ttl
+func (s *Server) handleRoomRetention(w http.ResponseWriter, r *http.Request, roomID int64) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPatch {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if r.Method == http.MethodGet {
+		isMember, err := s.roomRepo.IsUserInRoom(roomID, userID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		if !isMember {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "you are not a member of this room"})
+			return
+		}
+		ttl, err := s.retentionRepo.RoomTTL(ctx, roomID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "room not found"})
+				return
+			}
+			log.Println("RoomTTL error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, newRoomRetentionResponse(roomID, ttl))
+		return
+	}
+
+	var req roomRetentionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+	ttl, err := retention.ParseRoomTTL(req.TTL)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	if _, ok := s.roomPermission(w, ctx, roomID, userID, room.PermRoomRetention); !ok {
+		return
+	}
+
+	if err := s.retentionRepo.SetRoomTTL(ctx, roomID, ttl); err != nil {
+		log.Println("SetRoomTTL error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	resp := newRoomRetentionResponse(roomID, ttl)
+	writeJSON(w, http.StatusOK, resp)
+
+	if memberIDs, err := s.roomRepo.GetRoomMemberIDs(roomID); err == nil {
+		wsSendToUsers(memberIDs, wsEnvelope{
+			Type:   "room_retention_updated",
+			RoomID: roomID,
+			Data:   map[string]any{"ttl_seconds": resp.TTLSeconds, "ttl": resp.TTL, "updated_by": userID},
+		})
+	}
+}
+
+// StartRoomTTLPurger: định kỳ xoá tin hết hạn của room bật tự huỷ + WS messages_expired
+func (s *Server) StartRoomTTLPurger(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.purgeExpiredRoomMessages(ctx, interval)
+			}
+		}
+	}()
+}
+
+func (s *Server) purgeExpiredRoomMessages(ctx context.Context, budget time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, budget)
+	defer cancel()
+
+	n, err := s.retention.PurgeRoomTTL(ctx, func(roomID int64, ids []int64) {
+		memberIDs, err := s.roomRepo.GetRoomMemberIDs(roomID)
+		if err != nil {
+			log.Println("[room ttl] GetRoomMemberIDs error:", err)
+			return
+		}
+		wsSendToUsers(memberIDs, wsEnvelope{
+			Type:   "messages_expired",
+			RoomID: roomID,
+			Data:   map[string]any{"message_ids": ids},
+		})
+	})
+	if err != nil && ctx.Err() == nil {
+		log.Println("[room ttl] purge error:", err)
+	}
+	if n > 0 {
+		log.Printf("[room ttl] expired %d messages", n)
+	}
+}
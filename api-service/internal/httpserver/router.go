@@ -0,0 +1,75 @@
+package httpserver
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// patternMux: ServeMux dùng pattern Go 1.22 ("METHOD /rooms/{roomID}/mute"), gom route có path param
+// của 1 nhóm. Mount vào mux chính bằng prefix (vd "/rooms/"): route tĩnh cũ (/rooms/delete/...) vẫn
+// khớp trước vì cụ thể hơn, phần còn lại vào đây -> không đụng pattern, không cắt path bằng tay.
+// path đúng nhưng sai method -> 405 JSON kèm Allow, không khớp route nào -> 404 JSON
+type patternMux struct {
+	mux     *http.ServeMux
+	methods map[string][]string // path -> method đã đăng ký
+}
+
+func newPatternMux(prefix string) *patternMux {
+	m := &patternMux{mux: http.NewServeMux(), methods: map[string][]string{}}
+	m.mux.Handle(prefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}))
+	return m
+}
+
+// handle: đăng ký method + path, lần đầu gặp path thì thêm fallback 405
+func (m *patternMux) handle(method, path string, h http.HandlerFunc) {
+	m.mux.Handle(method+" "+path, h)
+	if _, ok := m.methods[path]; !ok {
+		m.mux.Handle(path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allow := append([]string(nil), m.methods[path]...)
+			sort.Strings(allow)
+			w.Header().Set("Allow", strings.Join(allow, ", "))
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		}))
+	}
+	m.methods[path] = append(m.methods[path], method)
+}
+
+func (m *patternMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// FE cũ gọi kèm "/" cuối (dispatcher cũ Trim "/") -> bỏ đi cho khớp pattern
+	if p := r.URL.Path; len(p) > 1 && strings.HasSuffix(p, "/") {
+		r2 := new(http.Request)
+		*r2 = *r
+		u := *r.URL
+		u.Path = strings.TrimRight(p, "/")
+		u.RawPath = ""
+		r2.URL = &u
+		r = r2
+	}
+	m.mux.ServeHTTP(w, r)
+}
+
+// pathID: path param {name} -> id > 0
+func pathID(r *http.Request, name string) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue(name), 10, 64)
+	return id, err == nil && id > 0
+}
+
+// withRoomID: lấy {roomID} rồi gọi handler kiểu cũ (w, r, roomID)
+func withRoomID(h func(http.ResponseWriter, *http.Request, int64)) http.HandlerFunc {
+	return withPathID("roomID", "invalid room id", h)
+}
+
+func withPathID(name, errMsg string, h func(http.ResponseWriter, *http.Request, int64)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := pathID(r, name)
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": errMsg})
+			return
+		}
+		h(w, r, id)
+	}
+}
@@ -0,0 +1,173 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/media"
+	"cronhustler/api-service/internal/moderation"
+	"cronhustler/api-service/internal/scan"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// số file quét song song (clamd / scanner ngoài chịu tải có hạn)
+const uploadScanWorkers = 4
+
+// SetUploadScanner: quét mọi file chat upload sau khi lưu
+// quarantineDir rỗng = xoá file bẩn luôn, không giữ bản cách ly
+func (s *Server) SetUploadScanner(sc scan.Scanner, quarantineDir string) {
+	s.scanner = sc
+	s.quarantineDir = quarantineDir
+	s.scanSem = make(chan struct{}, uploadScanWorkers)
+}
+
+// scanChatUpload: mở file ngay (file tạm của object store bị xoá khi request xong, fd vẫn đọc được)
+// rồi quét nền -> upload không phải chờ scanner
+func (s *Server) scanChatUpload(up *chatUpload, roomID, uploaderID int64) {
+	if s.scanner == nil || up == nil || up.localPath == "" {
+		return
+	}
+	f, err := os.Open(up.localPath)
+	if err != nil {
+		log.Printf("[scan] open %s: %v", up.Filename, err)
+		return
+	}
+	name, origName := up.Filename, up.OriginalName
+
+	go func() {
+		defer f.Close()
+		s.scanSem <- struct{}{}
+		defer func() { <-s.scanSem }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+
+		res, err := s.scanner.Scan(ctx, origName, f)
+		if err != nil {
+			// không quét được -> giữ file (scanner chết không làm hỏng chat), chỉ log
+			log.Printf("[scan] %s: %v", name, err)
+			return
+		}
+		if res.Clean {
+			return
+		}
+		s.quarantineChatUpload(ctx, f, name, origName, roomID, uploaderID, res.Signature)
+	}()
+}
+
+// quarantineChatUpload: chép file sang thư mục cách ly, xoá khỏi storage (kèm thumb / medium),
+// chặn tin trỏ tới file + report cho admin, báo room và người upload qua WS
+func (s *Server) quarantineChatUpload(ctx context.Context, f *os.File, name, origName string, roomID, uploaderID int64, signature string) {
+	log.Printf("[scan] %s (%s) infected: %s", name, origName, signature)
+
+	if s.quarantineDir != "" {
+		if err := copyToQuarantine(s.quarantineDir, name, f); err != nil {
+			log.Printf("[scan] quarantine %s: %v", name, err)
+		}
+	}
+	for _, n := range []string{name, media.VariantName(name, media.VariantThumb), media.VariantName(name, media.VariantMedium)} {
+		s.deleteChatUpload(ctx, n)
+	}
+	if err := s.chatRepo.QuarantineUpload(ctx, name, signature); err != nil {
+		log.Println("QuarantineUpload error:", err)
+	}
+
+	mediaURL := chatUploadPrefix + name
+	blocked, err := s.chatRepo.BlockMessagesByMediaURL(ctx, mediaURL, "malware: "+signature)
+	if err != nil {
+		log.Println("BlockMessagesByMediaURL error:", err)
+	}
+
+	messageIDs := make([]int64, 0, len(blocked))
+	for _, m := range blocked {
+		messageIDs = append(messageIDs, m.ID)
+
+		rp := &moderation.Report{
+			ReporterID:     0,
+			TargetType:     moderation.TargetMessage,
+			MessageID:      m.ID,
+			TargetUserID:   m.SenderID,
+			RoomID:         m.RoomID,
+			Reason:         "other",
+			Details:        "virus scan: " + signature,
+			ContentPreview: origName,
+		}
+		if err := s.moderationRepo.Create(ctx, rp); err != nil {
+			log.Println("virus scan report error:", err)
+		}
+
+		memberIDs, err := s.roomRepo.GetRoomMemberIDs(m.RoomID)
+		if err != nil {
+			log.Println("GetRoomMemberIDs error:", err)
+			continue
+		}
+		wsSendToUsers(memberIDs, wsEnvelope{
+			Type:   "message_blocked",
+			RoomID: m.RoomID,
+			Data: map[string]any{
+				"message_id": m.ID,
+				"reason":     "malware",
+			},
+		})
+	}
+
+	// người upload biết vì sao file biến mất (cả khi chưa kịp gửi thành tin)
+	wsSendToUser(uploaderID, wsEnvelope{
+		Type:   "upload_blocked",
+		RoomID: roomID,
+		Data: map[string]any{
+			"media_url":     mediaURL,
+			"original_name": origName,
+			"signature":     signature,
+			"message_ids":   messageIDs,
+		},
+	})
+}
+
+// copyToQuarantine: chỉ owner đọc được, không ghi đè bản đã có
+func copyToQuarantine(dir, name string, f *os.File) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(filepath.Join(dir, filepath.Base(name)), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, f); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// quarantinedUploadFailure: tin image / file / audio trỏ tới file đã bị cách ly -> 422
+// chỉ check khi bật scanner, lỗi DB không chặn gửi
+func (s *Server) quarantinedUploadFailure(ctx context.Context, msgType, content string) *apiFailure {
+	if s.scanner == nil || (msgType != "image" && msgType != "file" && msgType != "audio") {
+		return nil
+	}
+	name, ok := strings.CutPrefix(content, chatUploadPrefix)
+	if !ok {
+		return nil
+	}
+	if i := strings.IndexByte(name, '?'); i >= 0 {
+		name = name[:i]
+	}
+
+	quarantined, err := s.chatRepo.IsUploadQuarantined(ctx, name)
+	if err != nil {
+		log.Println("IsUploadQuarantined error:", err)
+		return nil
+	}
+	if quarantined {
+		return newFailure(http.StatusUnprocessableEntity, CodeFileBlocked, "file was blocked by the virus scan")
+	}
+	return nil
+}
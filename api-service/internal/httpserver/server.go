@@ -1,78 +1,295 @@
 package httpserver
 
 import (
+	"context"
+	"cronhustler/api-service/internal/analytics"
+	"cronhustler/api-service/internal/announcement"
+	"cronhustler/api-service/internal/apikey"
+	"cronhustler/api-service/internal/audit"
+	"cronhustler/api-service/internal/automation"
+	"cronhustler/api-service/internal/bot"
+	"cronhustler/api-service/internal/calendar"
 	"cronhustler/api-service/internal/chat"
+	"cronhustler/api-service/internal/config"
+	"cronhustler/api-service/internal/contact"
+	"cronhustler/api-service/internal/digest"
+	"cronhustler/api-service/internal/export"
+	"cronhustler/api-service/internal/iprule"
+	"cronhustler/api-service/internal/job"
+	"cronhustler/api-service/internal/linkpreview"
+	"cronhustler/api-service/internal/lockout"
+	"cronhustler/api-service/internal/media"
+	"cronhustler/api-service/internal/moderation"
+	"cronhustler/api-service/internal/notify"
+	"cronhustler/api-service/internal/oauth"
+	"cronhustler/api-service/internal/otp"
+	"cronhustler/api-service/internal/provisioning"
+	"cronhustler/api-service/internal/push"
+	"cronhustler/api-service/internal/quota"
+	"cronhustler/api-service/internal/reminder"
+	"cronhustler/api-service/internal/retention"
 	"cronhustler/api-service/internal/room"
+	"cronhustler/api-service/internal/scan"
+	"cronhustler/api-service/internal/sms"
+	"cronhustler/api-service/internal/storage"
+	"cronhustler/api-service/internal/summary"
+	"cronhustler/api-service/internal/survey"
+	"cronhustler/api-service/internal/unread"
 	"cronhustler/api-service/internal/user"
+	"cronhustler/api-service/internal/webhook"
+	"cronhustler/api-service/internal/wordfilter"
+	"cronhustler/api-service/internal/wsjournal"
 	"database/sql"
 	"net/http"
 	"os"
+	"time"
 )
 
 // Server giữ state chung
 type Server struct {
-	mux           *http.ServeMux
-	userRepo      *user.Repository
-	jwtSecret     []byte
-	roomRepo      *room.Repository
-	chatRepo      *chat.Repository
-	avatarDir     string // thư mục vật lý lưu avatar
-	chatUploadDir string // thư mục vật lý lưu hình ảnh chat
-	// jobRepo  *job.Repository
+	mux              *http.ServeMux
+	userRepo         UserRepo
+	jwtSecret        []byte
+	serviceSecret    []byte   // ký service token nội bộ, rỗng = tắt
+	jobTokenOrigins  []string // JOB_SERVICE_TOKEN_ORIGINS: job http_request chỉ gắn service token khi gọi các origin này
+	roomRepo         RoomRepo
+	chatRepo         ChatRepo
+	unreadRepo       *unread.Repository  // đếm tin chưa đọc (REST, list room, WS)
+	contactRepo      *contact.Repository // danh bạ + lời mời kết bạn
+	avatarDir        string              // thư mục vật lý lưu avatar
+	chatUploadDir    string              // thư mục vật lý lưu hình ảnh chat
+	store            storage.Storage     // local disk hoặc S3/MinIO (STORAGE_DRIVER)
+	transcoder       *media.Transcoder   // nil = tắt transcode video
+	janitor          *media.Janitor      // dọn file upload mồ côi
+	mediaBaseURL     string              // prefix CDN cho media URL (optional)
+	ffmpegPath       string              // rỗng = tìm trong PATH (waveform audio)
+	ffprobePath      string              // rỗng = không lấy metadata video/audio
+	keepImageMeta    bool                // IMAGE_STRIP_METADATA=0: giữ nguyên EXIF ảnh upload
+	pushRepo         *push.Repository    // device token (FCM/APNs)
+	pusher           *push.Service       // nil = tắt gửi push
+	webPush          *push.WebPushSender // nil = tắt web push (trình duyệt)
+	vapidFromEnv     bool                // key VAPID lấy từ env -> không rotate qua API
+	notifyRepo       *notify.Repository
+	mailer           notify.Mailer // nil = chưa cấu hình email
+	mailTemplates    *notify.Templates
+	otpRepo          *otp.Repository
+	sms              sms.Sender // nil = tắt OTP qua SMS
+	smsCountryCode   string     // mã vùng cho số nội địa 0xxx
+	webhookRepo      *webhook.Repository
+	webhooks         *webhook.Dispatcher // giao event ra webhook của room / bot
+	botRepo          *bot.Repository
+	reminderRepo     *reminder.Repository
+	jobRepo          *job.Repository
+	jobHTTP          *http.Client // job http_request: có timeout, chỉ dial IP public
+	calendarRepo     *calendar.Repository
+	analyticsRepo    *analytics.Repository // DAU/MAU + số liệu dashboard admin
+	moderationRepo   *moderation.Repository
+	automationRepo   *automation.Repository   // rule tự động theo room
+	provisioningRepo *provisioning.Repository // external id của user provision từ HR / IdP
+	provisionToken   string                   // rỗng = tắt /provisioning/*
+	summaryRepo      *summary.Repository      // cache + đếm lượt tóm tắt room
+	summarizer       summary.Provider         // nil = tắt /rooms/summarize
+	digestRepo       *digest.Repository       // tổng kết ngày theo room
+	surveyRepo       *survey.Repository       // survey / NPS bot gửi theo lịch
+	wordFilterRepo   *wordfilter.Repository   // blocklist chung + ghi đè theo room
+	announcementRepo *announcement.Repository
+	auditRepo        *audit.Repository // log admin / bảo mật (append-only)
+	retentionRepo    *retention.Repository
+	retention        *retention.Purger // chạy qua job retention_purge
+	exportRepo       *export.Repository
+	exports          *export.Builder   // nil = chưa bật worker export
+	quotaRepo        *quota.Repository // giới hạn tin / room theo user
+	ipRuleRepo       *iprule.Repository
+	ipBlocks         ipBlockLog  // chống spam audit khi IP bị chặn
+	jobs             *job.Runner // chạy cron job (lock trong DB)
+	linkPreviewRepo  *linkpreview.Repository
+	linkPreviews     *linkpreview.Fetcher  // nil = tắt preview link
+	linkPreviewSem   chan struct{}         // giới hạn fetch song song
+	scanner          scan.Scanner          // nil = không quét virus file upload
+	quarantineDir    string                // giữ file bị cách ly ("" = xoá luôn)
+	scanSem          chan struct{}         // giới hạn quét song song
+	lockoutRepo      *lockout.Repository   // đếm đăng nhập sai, khoá tạm username / IP
+	oauthRepo        *oauth.Repository     // identity Google / GitHub đã link với user
+	apiKeyRepo       *apikey.Repository    // X-API-Key cho service ngoài (send_message, read_rooms)
+	oauthCfg         *oauth.Config         // nil = tắt social login (EnableOAuth)
+	appPublicURL     string                // FE: callback social login redirect về, origin WS mặc định cho phép
+	wsJournalRepo    *wsjournal.Repository // replay event khi WS kết nối lại (EnableWSJournal)
+	wsOrigins        []string              // WS_ALLOWED_ORIGINS (ngoài cùng host + appPublicURL)
+	docsDisabled     bool                  // tắt Swagger UI ở /docs
+	passwordCost     int                   // bcrypt cost (PASSWORD_HASH_COST)
+	now              func() time.Time      // đồng hồ (test dùng WithClock)
 }
 
-// NewServer: nhận thêm avatarDir
-func NewServer(db *sql.DB, secret []byte, avatarDir string, chatUploadDir string) *Server {
-	mux := http.NewServeMux()
+// Option: ghi đè dependency lúc NewServer (test thay repo bằng fake, cố định giờ...)
+type Option func(*Server)
 
-	// đảm bảo avatarDir tồn tại phòng hờ (thường đã mkdirAll ở main rồi)
-	if avatarDir == "" {
-		avatarDir = "./data/user_avatars"
-	}
+// WithClock: nguồn thời gian cho handler (mặc định time.Now)
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) { s.now = now }
+}
 
-	if chatUploadDir == "" {
-		chatUploadDir = "./data/chat_uploads"
-	}
+// WithStorage: nơi lưu upload (mặc định local disk theo avatarDir / chatUploadDir)
+func WithStorage(st storage.Storage) Option {
+	return func(s *Server) { s.store = st }
+}
+
+func WithUserRepo(r UserRepo) Option {
+	return func(s *Server) { s.userRepo = r }
+}
+
+func WithRoomRepo(r RoomRepo) Option {
+	return func(s *Server) { s.roomRepo = r }
+}
+
+func WithChatRepo(r ChatRepo) Option {
+	return func(s *Server) { s.chatRepo = r }
+}
+
+// NewServer: cfg đã qua config.FromEnv (đã validate, có default), opts ghi đè dependency mặc định
+func NewServer(db *sql.DB, cfg *config.Config, opts ...Option) *Server {
+	mux := http.NewServeMux()
+	secret := []byte(cfg.JWTSecret)
+	avatarDir, chatUploadDir := cfg.AvatarDir, cfg.ChatUploadDir
+
+	// đảm bảo thư mục tồn tại phòng hờ (thường đã mkdirAll ở main rồi)
 	_ = os.MkdirAll(chatUploadDir, 0o755)
 	_ = os.MkdirAll(avatarDir, 0o755)
 
 	s := &Server{
-		mux:           mux,
-		userRepo:      user.NewRepository(db),
-		jwtSecret:     secret,
-		roomRepo:      room.NewRepository(db, chat.NewRepository(db)),
-		chatRepo:      chat.NewRepository(db),
-		avatarDir:     avatarDir,
-		chatUploadDir: chatUploadDir,
+		mux:              mux,
+		userRepo:         user.NewRepository(db),
+		jwtSecret:        secret,
+		roomRepo:         room.NewRepository(db, chat.NewRepository(db)),
+		chatRepo:         chat.NewRepository(db),
+		unreadRepo:       unread.NewRepository(db),
+		contactRepo:      contact.NewRepository(db),
+		avatarDir:        avatarDir,
+		chatUploadDir:    chatUploadDir,
+		store:            storage.NewLocalStore(chatUploadDir, avatarDir),
+		janitor:          media.NewJanitor(db, chatUploadDir, avatarDir, media.DefaultOrphanGrace),
+		pushRepo:         push.NewRepository(db),
+		notifyRepo:       notify.NewRepository(db),
+		webhookRepo:      webhook.NewRepository(db),
+		botRepo:          bot.NewRepository(db),
+		reminderRepo:     reminder.NewRepository(db),
+		jobRepo:          job.NewRepository(db),
+		calendarRepo:     calendar.NewRepository(db),
+		analyticsRepo:    analytics.NewRepository(db),
+		moderationRepo:   moderation.NewRepository(db),
+		automationRepo:   automation.NewRepository(db),
+		provisioningRepo: provisioning.NewRepository(db),
+		summaryRepo:      summary.NewRepository(db),
+		digestRepo:       digest.NewRepository(db),
+		surveyRepo:       survey.NewRepository(db),
+		wordFilterRepo:   wordfilter.NewRepository(db),
+		announcementRepo: announcement.NewRepository(db),
+		auditRepo:        audit.NewRepository(db),
+		retentionRepo:    retention.NewRepository(db),
+		exportRepo:       export.NewRepository(db),
+		quotaRepo:        quota.NewRepository(db),
+		ipRuleRepo:       iprule.NewRepository(db),
+		lockoutRepo:      lockout.NewRepository(db),
+		oauthRepo:        oauth.NewRepository(db),
+		apiKeyRepo:       apikey.NewRepository(db),
+		linkPreviewRepo:  linkpreview.NewRepository(db),
+		wsJournalRepo:    wsjournal.NewRepository(db),
+		jobHTTP:          &http.Client{Timeout: jobHTTPTimeout, Transport: linkpreview.NewSafeTransport(jobHTTPTimeout)},
+		otpRepo:          otp.NewRepository(db, secret),
+		smsCountryCode:   cfg.SMSCountryCode,
+		provisionToken:   cfg.ProvisioningToken,
+		docsDisabled:     !cfg.OpenAPIDocs,
+		keepImageMeta:    !cfg.ImageStripMetadata,
+		passwordCost:     passwordCostOrDefault(cfg.PasswordHashCost),
+		appPublicURL:     cfg.AppPublicURL,
+		wsOrigins:        cfg.WSAllowedOrigins,
+		jobTokenOrigins:  cfg.JobTokenOrigins,
+		now:              time.Now,
+	}
+	if cfg.ServiceTokenSecret != "" {
+		s.serviceSecret = []byte(cfg.ServiceTokenSecret)
 	}
+	s.SetMediaBaseURL(cfg.MediaBaseURL)
+	s.SetFFmpegPath(cfg.FFmpegPath)
+	s.quotaRepo.Defaults = cfg.Quota
+	s.lockoutRepo.Limits = cfg.LoginLockout
+	localStore := s.store
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.retention = retention.NewPurger(s.retentionRepo, chatUploadDir)
+	if s.store != localStore {
+		// upload nằm ở object store -> purge xoá qua store thay vì xoá file trong chatUploadDir
+		s.retention.RemoveUpload = func(ctx context.Context, name string) error {
+			return s.store.Delete(ctx, chatUploadKey(name))
+		}
+	}
+	s.webhooks = webhook.NewDispatcher(s.webhookRepo)
+	s.jobs = job.NewRunner(s.jobRepo)
+	s.registerJobActions()
 
 	// ===== MOUNT ROUTES =====
 
-	// serve static avatar trước cũng được
-	s.mux.Handle("/static/user_avatars/",
-		http.StripPrefix("/static/user_avatars/",
-			http.FileServer(http.Dir(s.avatarDir)),
-		),
-	)
-	// serve static chat images
-	s.mux.Handle("/static/chat_uploads/",
-		http.StripPrefix("/static/chat_uploads/",
-			http.FileServer(http.Dir(s.chatUploadDir)),
-		),
-	)
+	// serve static avatar trước cũng được (bắt buộc signed URL)
+	s.mux.Handle(avatarPrefix, s.RequireSignedMedia(
+		withMediaCache(avatarPrefix, http.HandlerFunc(s.handleAvatarMedia)),
+	))
+	// serve static chat images (signed URL hoặc login + member của room)
+	s.mux.Handle(chatUploadPrefix, http.HandlerFunc(s.handleChatMedia))
+	// tải file chat với tên gốc (cùng rule authz)
+	s.mux.Handle(chatDownloadPrefix, http.HandlerFunc(s.handleChatMediaDownload))
 
 	// chia theo nhóm, mỗi nhóm định nghĩa ở file riêng
 	s.mountAuthRoutes(s.mux)
 	s.mountUserRoutes(s.mux)
+	s.mountBootstrapRoutes(s.mux)
 	s.mountRoomRoutes(s.mux)
+	s.mountInviteRoutes(s.mux)
+	s.mountContactRoutes(s.mux)
 	s.mountChatRoutes(s.mux)
+	s.mountEmojiRoutes(s.mux)
 	s.mountWsRoutes(s.mux)
-	// s.mountJobRoutes(s.mux)
+	s.mountAdminRoutes(s.mux)
+	s.mountPushRoutes(s.mux)
+	s.mountNotifyRoutes(s.mux)
+	s.mountWebhookRoutes(s.mux)
+	s.mountBotRoutes(s.mux)
+	s.mountReminderRoutes(s.mux)
+	s.mountJobRoutes(s.mux)
+	s.mountCalendarRoutes(s.mux)
+	s.mountModerationRoutes(s.mux)
+	s.mountSuspendRoutes(s.mux)
+	s.mountWordFilterRoutes(s.mux)
+	s.mountAnnouncementRoutes(s.mux)
+	s.mountAuditRoutes(s.mux)
+	s.mountRetentionRoutes(s.mux)
+	s.mountExportRoutes(s.mux)
+	s.mountQuotaRoutes(s.mux)
+	s.mountIPRuleRoutes(s.mux)
+	s.mountAdminRoomRoutes(s.mux)
+	s.mountOTPRoutes(s.mux)
+	s.mountAutomationRoutes(s.mux)
+	s.mountProvisioningRoutes(s.mux)
+	s.mountSummaryRoutes(s.mux)
+	s.mountDigestRoutes(s.mux)
+	s.mountInsightsRoutes(s.mux)
+	s.mountSurveyRoutes(s.mux)
+	s.mountReportRoutes(s.mux)
+	s.mountServiceAuthRoutes(s.mux)
+	s.mountAPIKeyRoutes(s.mux)
+	s.mountOpenAPIRoutes(s.mux)
 
 	return s
 }
 
+// StartMediaJanitor: chạy dọn file mồ côi định kỳ (grace <= 0 -> dùng mặc định)
+func (s *Server) StartMediaJanitor(ctx context.Context, interval, grace time.Duration) {
+	if grace > 0 {
+		s.janitor.Grace = grace
+	}
+	s.janitor.Start(ctx, interval)
+}
+
 // Routes trả về handler chính, quấn logger ở đây
 func (s *Server) Routes() http.Handler {
-	return LoggerMiddleware(s.mux)
+	return LoggerMiddleware(s.IPFilter(s.AuthMiddleware(s.mux)))
 }
@@ -0,0 +1,184 @@
+package httpserver
+
+import (
+	"cronhustler/api-service/internal/config"
+	"cronhustler/api-service/internal/lockout"
+	"cronhustler/api-service/internal/quota"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Test handler qua s.Routes() (đủ middleware) trên DB giả sqlmock.
+// Query không khai báo expectation trả lỗi -> các bước "lỗi DB không chặn" (iprule, quota,
+// analytics, push, ...) tự bỏ qua, mỗi case chỉ expect các query quyết định kết quả.
+
+var (
+	testSecret = []byte("test-secret")
+	testNow    = time.Date(2025, 3, 10, 9, 30, 0, 0, time.UTC)
+)
+
+// TestMain: tắt log (handler log mọi lỗi DB, kể cả query cố tình không mock)
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+func newTestServer(t *testing.T) (*Server, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	cfg := &config.Config{
+		JWTSecret:     string(testSecret),
+		AvatarDir:     t.TempDir(),
+		ChatUploadDir: t.TempDir(),
+		Quota:         quota.DefaultLimits,
+		LoginLockout:  lockout.DefaultLimits,
+
+		PasswordHashCost: bcrypt.MinCost, // userRow hash cùng cost -> login không rehash
+	}
+	s := NewServer(db, cfg, WithClock(func() time.Time { return testNow }))
+	return s, mock
+}
+
+func accessTokenFor(t *testing.T, userID int) string {
+	t.Helper()
+	tok, err := GenerateAccessToken(userID, "user", "user", testSecret)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+	return tok
+}
+
+func refreshTokenFor(t *testing.T, userID int, sessionID string) string {
+	t.Helper()
+	tok, err := GenerateRefreshToken(userID, "user", sessionID, testSecret)
+	if err != nil {
+		t.Fatalf("GenerateRefreshToken: %v", err)
+	}
+	return tok
+}
+
+func newRequest(method, path, body string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req
+}
+
+func serveRequest(s *Server, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.Routes().ServeHTTP(rec, req)
+	return rec
+}
+
+// serve: gọi 1 request qua Routes(), token rỗng = không gửi Authorization
+func serve(s *Server, method, path, token, body string) *httptest.ResponseRecorder {
+	req := newRequest(method, path, body)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return serveRequest(s, req)
+}
+
+func decodeBody(t *testing.T, rec *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var out map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode body %q: %v", rec.Body.String(), err)
+	}
+	return out
+}
+
+func checkExpectations(t *testing.T, mock sqlmock.Sqlmock) {
+	t.Helper()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+// userRow: SELECT * FROM users (thứ tự cột theo user.Repository scan)
+func userRow(id int, username, password string, active int) *sqlmock.Rows {
+	hash, _ := hashPassword(password, bcrypt.MinCost)
+	return userRowWithHash(id, username, hash, active)
+}
+
+func userRowWithHash(id int, username, passwordHash string, active int) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{
+		"id", "username", "password", "role", "full_name", "email", "phone", "avatar_url",
+		"is_active", "last_login", "login_ip", "created_ip", "created_at", "updated_at",
+	}).AddRow(id, username, passwordHash, "user", "Test User", nil, nil, nil,
+		active, nil, nil, nil, "2025-01-01 00:00:00", "2025-01-01 00:00:00")
+}
+
+func roomRow(id int64, name, typ string, createdBy int64) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "name", "type", "created_by", "is_active", "created_at", "updated_at"}).
+		AddRow(id, name, typ, createdBy, 1, testNow, testNow)
+}
+
+func expectMember(mock sqlmock.Sqlmock, roomID, userID int64, member bool) {
+	n := 0
+	if member {
+		n = 1
+	}
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM room_members WHERE room_id = \? AND user_id = \?`).
+		WithArgs(roomID, userID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(n))
+}
+
+func expectSuspended(mock sqlmock.Sqlmock, userID int64, until time.Time) {
+	mock.ExpectQuery(`SELECT suspended_until FROM users WHERE id = \?`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"suspended_until"}).AddRow(until))
+}
+
+// anyArgs: n placeholder không quan tâm giá trị
+func anyArgs(n int) []driver.Value {
+	out := make([]driver.Value, n)
+	for i := range out {
+		out[i] = sqlmock.AnyArg()
+	}
+	return out
+}
+
+func TestRoutesRequireMethod(t *testing.T) {
+	s, _ := newTestServer(t)
+	token := accessTokenFor(t, 1)
+
+	tests := []struct {
+		method, path string
+	}{
+		{http.MethodGet, "/rooms/group"},
+		{http.MethodGet, "/rooms/send-messages/10"},
+		{http.MethodGet, "/messages/react/add"},
+		{http.MethodGet, "/rooms/seen"},
+		{http.MethodPost, "/rooms/delete/10"},
+		{http.MethodPut, "/rooms/direct/2"},
+		{http.MethodPost, "/messages/reactions/5/users"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := serve(s, tt.method, tt.path, token, "")
+			if rec.Code != http.StatusMethodNotAllowed {
+				t.Fatalf("status = %d, want 405 (body %s)", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/audit"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Service token: JWT riêng cho caller nội bộ (job runner, service khác), ký bằng
+// SERVICE_TOKEN_SECRET, issuer / audience khác user token -> không dùng lẫn được
+const (
+	ServiceTokenIssuer   = "cronhustler-service"
+	ServiceTokenAudience = "cronhustler-internal"
+
+	ServiceTokenMaxTTL = 30 * 24 * time.Hour
+	// token job runner tự ký cho mỗi lần chạy http_request
+	jobServiceTokenTTL = 5 * time.Minute
+	jobRunnerService   = "job-runner"
+)
+
+// scope của service token
+const (
+	ScopeAdmin = "admin" // mọi route RequireAdmin
+	ScopeJobs  = "jobs"  // chỉ /jobs
+)
+
+var ServiceScopes = []string{ScopeAdmin, ScopeJobs}
+
+var (
+	ErrServiceAuthDisabled = errors.New("service tokens are not enabled (SERVICE_TOKEN_SECRET)")
+	serviceNameRe          = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{1,63}$`)
+)
+
+type ServiceClaims struct {
+	Service string   `json:"service"`
+	Scopes  []string `json:"scopes"`
+	jwt.RegisteredClaims
+}
+
+func (c *ServiceClaims) HasScope(scope string) bool {
+	for _, v := range c.Scopes {
+		if v == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidServiceScope(scope string) bool {
+	for _, v := range ServiceScopes {
+		if v == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateServiceToken tạo service token, Subject = "service:<name>"
+func GenerateServiceToken(service string, scopes []string, ttl time.Duration, secret []byte) (string, time.Time, error) {
+	if len(secret) == 0 {
+		return "", time.Time{}, ErrServiceAuthDisabled
+	}
+	now := time.Now()
+	exp := now.Add(ttl)
+
+	claims := ServiceClaims{
+		Service: service,
+		Scopes:  scopes,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(exp),
+			Issuer:    ServiceTokenIssuer,
+			Audience:  jwt.ClaimStrings{ServiceTokenAudience},
+			Subject:   "service:" + service,
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	s, err := token.SignedString(secret)
+	return s, exp, err
+}
+
+// ParseServiceToken: bắt buộc HS256 + đúng issuer / audience + còn hạn
+func ParseServiceToken(tokenStr string, secret []byte) (*ServiceClaims, error) {
+	if len(secret) == 0 {
+		return nil, ErrServiceAuthDisabled
+	}
+	token, err := jwt.ParseWithClaims(tokenStr, &ServiceClaims{}, func(t *jwt.Token) (interface{}, error) {
+		return secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(ServiceTokenIssuer),
+		jwt.WithAudience(ServiceTokenAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*ServiceClaims)
+	if !ok || !token.Valid || claims.Service == "" {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	return claims, nil
+}
+
+type serviceCtxKey struct{}
+
+// serviceFromContext: service đang gọi (nil = request của user)
+func serviceFromContext(ctx context.Context) *ServiceClaims {
+	c, _ := ctx.Value(serviceCtxKey{}).(*ServiceClaims)
+	return c
+}
+
+// bearerToken: "Bearer <token>" -> token
+func bearerToken(r *http.Request) string {
+	parts := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return ""
+	}
+	return parts[1]
+}
+
+// serveAsService: token là service token có scope -> gọi next với service trong context
+func (s *Server) serveAsService(w http.ResponseWriter, r *http.Request, next http.Handler, tokenStr, scope string) bool {
+	if len(s.serviceSecret) == 0 {
+		return false
+	}
+	claims, err := ParseServiceToken(tokenStr, s.serviceSecret)
+	if err != nil || !(claims.HasScope(scope) || claims.HasScope(ScopeAdmin)) {
+		return false
+	}
+	next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), serviceCtxKey{}, claims)))
+	return true
+}
+
+// RequireAdminOrService: admin (user) hoặc service token có scope (hoặc scope admin)
+func (s *Server) RequireAdminOrService(scope string, next http.Handler) http.Handler {
+	admin := s.RequireAdmin(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tokenStr := bearerToken(r); tokenStr != "" && s.serveAsService(w, r, next, tokenStr, scope) {
+			return
+		}
+		admin.ServeHTTP(w, r)
+	})
+}
+
+// requireUserAdmin: admin đăng nhập thật, không nhận service token
+func (s *Server) requireUserAdmin(next http.Handler) http.Handler {
+	return s.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serviceFromContext(r.Context()) != nil {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "user admin token required"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
+
+func (s *Server) mountServiceAuthRoutes(mux *http.ServeMux) {
+	// POST /admin/service-tokens {service, scopes: [admin|jobs], ttl_hours} -> {token, expires_at}
+	// token không lưu DB: đổi SERVICE_TOKEN_SECRET để thu hồi hết
+	mux.Handle("/admin/service-tokens", s.requireUserAdmin(http.HandlerFunc(s.handleIssueServiceToken)))
+}
+
+func (s *Server) handleIssueServiceToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if len(s.serviceSecret) == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": ErrServiceAuthDisabled.Error()})
+		return
+	}
+
+	var req struct {
+		Service  string   `json:"service"`
+		Scopes   []string `json:"scopes"`
+		TTLHours int      `json:"ttl_hours"` // mặc định 24
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+	req.Service = strings.ToLower(strings.TrimSpace(req.Service))
+	if !serviceNameRe.MatchString(req.Service) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "service must be 2-64 chars [a-z0-9_.-]"})
+		return
+	}
+	if len(req.Scopes) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "scopes is required"})
+		return
+	}
+	for _, sc := range req.Scopes {
+		if !isValidServiceScope(sc) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "scopes must be in: " + strings.Join(ServiceScopes, ", ")})
+			return
+		}
+	}
+	if req.TTLHours == 0 {
+		req.TTLHours = 24
+	}
+	ttl := time.Duration(req.TTLHours) * time.Hour
+	if req.TTLHours < 0 || ttl > ServiceTokenMaxTTL {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "ttl_hours must be 1..720"})
+		return
+	}
+
+	token, exp, err := GenerateServiceToken(req.Service, req.Scopes, ttl, s.serviceSecret)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "cannot sign token"})
+		return
+	}
+
+	adminID, _ := UserIDFromContext(r.Context())
+	s.recordAudit(r, adminID, audit.ActionConfigChange, audit.TargetConfig, 0, map[string]any{
+		"kind": "service_token", "op": "issue", "service": req.Service, "scopes": req.Scopes, "expires_at": exp,
+	})
+
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"service":    req.Service,
+		"scopes":     req.Scopes,
+		"token":      token,
+		"expires_at": exp,
+	})
+}
@@ -0,0 +1,171 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/audit"
+	"cronhustler/api-service/internal/user"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type sessionResponse struct {
+	user.Session
+	Current bool `json:"current"` // session của chính request này
+}
+
+func (s *Server) mountSessionRoutes(mux *http.ServeMux) {
+	// GET /auth/sessions -> các thiết bị đang đăng nhập
+	mux.Handle("/auth/sessions", http.HandlerFunc(s.handleListSessions))
+	// DELETE /auth/sessions/{id} -> đăng xuất thiết bị đó
+	mux.Handle("/auth/sessions/", http.HandlerFunc(s.handleRevokeSession))
+}
+
+// startSession: tạo session cho lần login này, id dùng làm jti của refresh token
+func (s *Server) startSession(r *http.Request, userID int64) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	sess := &user.Session{
+		ID:         hex.EncodeToString(b),
+		UserID:     userID,
+		DeviceName: deviceName(r),
+		IP:         getIP(r),
+		UserAgent:  r.UserAgent(),
+		ExpiresAt:  s.now().Add(RefreshTokenTTL),
+	}
+	if err := s.userRepo.CreateSession(r.Context(), sess); err != nil {
+		return "", err
+	}
+	return sess.ID, nil
+}
+
+// checkSession: refresh token phải thuộc session chưa bị thu hồi
+// (token cấp trước khi có session -> không có jti, cho qua tới khi hết hạn)
+func (s *Server) checkSession(r *http.Request, claims *Claims) error {
+	if claims.ID == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	return s.userRepo.TouchSession(ctx, claims.ID, int64(claims.UserID), getIP(r))
+}
+
+// refreshClaims: claims refresh token trong cookie, nil nếu không có / không hợp lệ
+func (s *Server) refreshClaims(r *http.Request) *Claims {
+	c, err := r.Cookie(RefreshCookieName)
+	if err != nil || c.Value == "" {
+		return nil
+	}
+	claims, err := ParseToken(c.Value, s.jwtSecret)
+	if err != nil || claims.TokenType != TokenTypeRefresh {
+		return nil
+	}
+	return claims
+}
+
+// deviceName: header X-Device-Name (app mobile / desktop tự đặt), không có thì đoán từ user agent
+func deviceName(r *http.Request) string {
+	if name := strings.TrimSpace(r.Header.Get("X-Device-Name")); name != "" {
+		return name
+	}
+
+	ua := r.UserAgent()
+	browser := "Unknown browser"
+	for _, b := range []struct{ key, name string }{
+		{"Edg/", "Edge"}, {"OPR/", "Opera"}, {"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"}, {"Safari/", "Safari"}, {"okhttp", "Android app"},
+		{"CFNetwork", "iOS app"},
+	} {
+		if strings.Contains(ua, b.key) {
+			browser = b.name
+			break
+		}
+	}
+	osName := ""
+	for _, o := range []struct{ key, name string }{
+		{"Windows", "Windows"}, {"iPhone", "iOS"}, {"iPad", "iPadOS"},
+		{"Android", "Android"}, {"Mac OS X", "macOS"}, {"Linux", "Linux"},
+	} {
+		if strings.Contains(ua, o.key) {
+			osName = o.name
+			break
+		}
+	}
+	if osName == "" {
+		return browser
+	}
+	return browser + " on " + osName
+}
+
+// GET /auth/sessions
+func (s *Server) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	sessions, err := s.userRepo.ListSessions(r.Context(), userID)
+	if err != nil {
+		log.Println("ListSessions error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	var currentID string
+	if claims := s.refreshClaims(r); claims != nil {
+		currentID = claims.ID
+	}
+
+	out := make([]sessionResponse, 0, len(sessions))
+	for _, sess := range sessions {
+		out = append(out, sessionResponse{Session: sess, Current: currentID != "" && sess.ID == currentID})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"sessions": out})
+}
+
+// DELETE /auth/sessions/{id}
+// access token đã cấp cho thiết bị đó vẫn sống tới khi hết hạn (AccessTokenTTL),
+// sau đó refresh / WS bị từ chối
+func (s *Server) handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/auth/sessions/"), "/")
+	if id == "" || strings.Contains(id, "/") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid session id"})
+		return
+	}
+
+	ok, err := s.userRepo.RevokeSession(r.Context(), userID, id)
+	if err != nil {
+		log.Println("RevokeSession error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "session not found"})
+		return
+	}
+
+	s.recordAudit(r, userID, audit.ActionTokenRevoke, audit.TargetUser, userID, map[string]any{"token": "refresh_token", "reason": "session_revoke", "session_id": id})
+	writeJSON(w, http.StatusOK, map[string]string{"message": "session revoked"})
+}
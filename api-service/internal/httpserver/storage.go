@@ -0,0 +1,61 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/storage"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// TTL presigned URL khi redirect sang object store (client đi theo redirect ngay)
+const storagePresignTTL = 10 * time.Minute
+
+func chatUploadKey(name string) string {
+	return storage.ChatUploadKeyPrefix + name
+}
+
+// serveFromStore: object store -> redirect sang presigned URL, trả false nếu driver
+// không có URL trực tiếp (local) để caller tự stream file
+func (s *Server) serveFromStore(w http.ResponseWriter, r *http.Request, key, contentType, disposition string) bool {
+	u, err := s.store.PresignGet(key, storagePresignTTL, contentType, disposition)
+	if err != nil {
+		log.Println("PresignGet error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "storage error"})
+		return true
+	}
+	if u == "" {
+		return false
+	}
+
+	// redirect hết hạn theo presign -> không cho cache như file immutable
+	w.Header().Del("ETag")
+	w.Header().Set("Cache-Control", "private, no-store")
+	http.Redirect(w, r, u, http.StatusFound)
+	return true
+}
+
+// handleAvatarMedia: GET /static/user_avatars/{file} (đã qua RequireSignedMedia)
+func (s *Server) handleAvatarMedia(w http.ResponseWriter, r *http.Request) {
+	name, ok := chatMediaName(r.URL.Path, avatarPrefix)
+	if ok && s.serveFromStore(w, r, storage.AvatarKeyPrefix+name, mediaMimeFromExt(filepath.Ext(name)), "") {
+		return
+	}
+	http.StripPrefix(avatarPrefix, http.FileServer(http.Dir(s.avatarDir))).ServeHTTP(w, r)
+}
+
+// releaseChatUpload: xoá file tạm dùng để probe khi upload lên object store
+func (s *Server) releaseChatUpload(up *chatUpload) {
+	if up != nil && up.tmp {
+		_ = os.Remove(up.localPath)
+	}
+}
+
+// deleteChatUpload: dọn file đã lưu khi insert DB fail
+func (s *Server) deleteChatUpload(ctx context.Context, name string) {
+	if err := s.store.Delete(ctx, chatUploadKey(name)); err != nil {
+		log.Printf("[upload] delete %s: %v", name, err)
+	}
+}
@@ -0,0 +1,157 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/summary"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const roomSummarizePrefix = "/rooms/summarize/"
+
+func (s *Server) mountSummaryRoutes(mux *http.ServeMux) {
+	// POST /rooms/summarize/{roomID} {limit?} -> {summary, action_items, cached...}
+	mux.Handle(roomSummarizePrefix, http.HandlerFunc(s.handleSummarizeRoom))
+}
+
+// SetSummaryProvider: bật tóm tắt room bằng LLM, perHour = số lần gọi / user / giờ (0 = không giới hạn)
+func (s *Server) SetSummaryProvider(p summary.Provider, perHour int) {
+	s.summarizer = p
+	s.summaryRepo.PerHour = perHour
+}
+
+func (s *Server) handleSummarizeRoom(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if s.summarizer == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "room summarization is not enabled"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+	roomID, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(r.URL.Path, roomSummarizePrefix), "/"), 10, 64)
+	if err != nil || roomID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid room id"})
+		return
+	}
+	if !s.requireRoomMember(w, roomID, userID) {
+		return
+	}
+
+	// body tuỳ chọn
+	var req struct {
+		Limit int `json:"limit"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+	if req.Limit <= 0 {
+		req.Limit = summary.DefaultMessages
+	}
+	if req.Limit > summary.MaxMessages {
+		req.Limit = summary.MaxMessages
+	}
+
+	ctx := r.Context()
+	msgs, err := s.roomRepo.GetRoomMessages(roomID, 0, time.Time{}, req.Limit, userID)
+	if err != nil {
+		log.Println("GetRoomMessages error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	lines := make([]summary.Line, 0, len(msgs))
+	for _, m := range msgs {
+		text := m.Content
+		if m.Type != "text" && m.Type != "system" {
+			text = "[" + m.Type + "]"
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		sender := m.SenderName
+		if sender == "" {
+			sender = "user #" + strconv.FormatInt(m.SenderID, 10)
+		}
+		lines = append(lines, summary.Line{ID: m.ID, Sender: sender, Text: text, At: m.CreatedAt})
+	}
+	if len(lines) == 0 {
+		writeJSON(w, http.StatusOK, summary.Summary{RoomID: roomID, ActionItems: []summary.ActionItem{}})
+		return
+	}
+
+	roomName := ""
+	if rl, err := s.roomRepo.GetRoomBasic(ctx, roomID); err == nil {
+		roomName = rl.DisplayName
+	}
+	prompt, used := summary.BuildPrompt(roomName, lines)
+	lines = lines[len(lines)-used:]
+	now := s.now()
+	lastID := lines[len(lines)-1].ID
+
+	// 1) cache: không có tin mới -> trả bản cũ
+	if cached, err := s.summaryRepo.Cached(ctx, roomID, lastID, used, now); err != nil {
+		log.Println("summary Cached error:", err)
+	} else if cached != nil {
+		writeJSON(w, http.StatusOK, cached)
+		return
+	}
+
+	// 2) rate limit theo user (chỉ tính lần gọi LLM thật)
+	if retry, err := s.summaryRepo.CheckRate(ctx, userID, now); err != nil {
+		if !errors.Is(err, summary.ErrRateLimited) {
+			log.Println("summary CheckRate error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		sec := int(retry.Seconds() + 0.999)
+		if sec < 1 {
+			sec = 1
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(sec))
+		writeJSON(w, http.StatusTooManyRequests, map[string]any{
+			"error":       "rate limit exceeded",
+			"limit":       "summaries_per_hour",
+			"max":         s.summaryRepo.PerHour,
+			"retry_after": sec,
+		})
+		return
+	}
+
+	// 3) gọi LLM
+	llmCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+	text, items, err := summary.Generate(llmCtx, s.summarizer, prompt)
+	if err != nil {
+		log.Printf("summary Generate room=%d: %v", roomID, err)
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": "summary provider error"})
+		return
+	}
+
+	out := &summary.Summary{
+		RoomID:        roomID,
+		Summary:       text,
+		ActionItems:   items,
+		MessageCount:  used,
+		FromMessageID: lines[0].ID,
+		LastMessageID: lastID,
+		CreatedAt:     now,
+	}
+	if err := s.summaryRepo.Save(ctx, out, userID); err != nil {
+		// lưu lỗi vẫn trả kết quả (chỉ mất cache + đếm rate)
+		log.Println("summary Save error:", err)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,470 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/audit"
+	"cronhustler/api-service/internal/job"
+	"cronhustler/api-service/internal/survey"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	adminSurveysPrefix = "/admin/surveys/"
+	surveyRunsPrefix   = "/surveys/runs/"
+
+	jobActionSurveyPost = "survey_post"
+
+	surveyBotUsername = "survey_bot"
+	surveyBotName     = "Survey"
+)
+
+func (s *Server) mountSurveyRoutes(mux *http.ServeMux) {
+	// admin:
+	// GET  /admin/surveys | POST /admin/surveys {title, intro, questions, room_ids, user_ids, cron, active}
+	mux.Handle("/admin/surveys", s.RequireAdmin(http.HandlerFunc(s.handleAdminSurveys)))
+	// GET|PUT|DELETE /admin/surveys/{id}
+	// POST /admin/surveys/{id}/run              -> gửi ngay
+	// GET  /admin/surveys/{id}/runs
+	// GET  /admin/surveys/{id}/report?run_id=   -> tổng hợp (run_id rỗng = mọi lần gửi)
+	mux.Handle(adminSurveysPrefix, s.RequireAdmin(http.HandlerFunc(s.handleAdminSurvey)))
+
+	// member của room nhận survey:
+	// GET  /surveys/runs/{runID} -> câu hỏi + câu trả lời của mình
+	// POST /surveys/runs/{runID} {answers: [{question_id, value | text}]}
+	mux.Handle(surveyRunsPrefix, http.HandlerFunc(s.handleSurveyRun))
+}
+
+type surveyPostPayload struct {
+	SurveyID int64 `json:"survey_id"`
+}
+
+func (s *Server) parseSurveyPostPayload(ctx context.Context, raw json.RawMessage) (*survey.Survey, error) {
+	var p surveyPostPayload
+	if err := json.Unmarshal(raw, &p); err != nil || p.SurveyID <= 0 {
+		return nil, errors.New("payload must be {survey_id}")
+	}
+	sv, err := s.surveyRepo.Get(ctx, p.SurveyID)
+	if err != nil {
+		if errors.Is(err, survey.ErrNotFound) {
+			return nil, errors.New("payload.survey_id: survey not found")
+		}
+		return nil, err
+	}
+	return sv, nil
+}
+
+func (s *Server) runSurveyPostJob(ctx context.Context, j *job.Job) (string, error) {
+	sv, err := s.parseSurveyPostPayload(ctx, j.Payload)
+	if err != nil {
+		return "", err
+	}
+	if !sv.Active {
+		return "survey inactive, skipped", nil
+	}
+	runID, delivered, err := s.postSurvey(ctx, sv)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("run_id=%d delivered=%d", runID, delivered), nil
+}
+
+// postSurvey: tạo run mới, bot đăng câu hỏi vào từng room + DM từng user
+func (s *Server) postSurvey(ctx context.Context, sv *survey.Survey) (int64, int, error) {
+	botID, err := s.botRepo.EnsureSystemBot(ctx, surveyBotUsername, surveyBotName)
+	if err != nil {
+		return 0, 0, err
+	}
+	runID, err := s.surveyRepo.StartRun(ctx, sv.ID)
+	if err != nil {
+		return 0, 0, err
+	}
+	content := surveyMessage(sv, runID)
+
+	roomIDs := append([]int64{}, sv.RoomIDs...)
+	for _, uid := range sv.UserIDs {
+		roomID, err := s.ensureDirectRoom(botID, uid)
+		if err != nil {
+			log.Printf("[survey] ensureDirectRoom user=%d: %v", uid, err)
+			continue
+		}
+		roomIDs = append(roomIDs, roomID)
+	}
+
+	delivered := 0
+	for _, roomID := range roomIDs {
+		if _, err := s.roomRepo.GetRoomByID(roomID); err != nil {
+			log.Printf("[survey] survey=%d room=%d: %v", sv.ID, roomID, err)
+			continue
+		}
+		// bot phải là member mới đăng được vào room nhóm
+		if ok, _ := s.roomRepo.IsUserInRoom(roomID, botID); !ok {
+			if err := s.roomRepo.AddMember(roomID, botID, "member"); err != nil {
+				log.Printf("[survey] AddMember bot room=%d: %v", roomID, err)
+				continue
+			}
+		}
+		msgID, err := s.postBotMessage(ctx, botID, roomID, content)
+		if err != nil {
+			log.Printf("[survey] post room=%d: %v", roomID, err)
+			continue
+		}
+		if err := s.surveyRepo.AddDelivery(ctx, runID, roomID, msgID); err != nil {
+			log.Printf("[survey] AddDelivery run=%d room=%d: %v", runID, roomID, err)
+			continue
+		}
+		delivered++
+	}
+	if delivered == 0 {
+		return runID, 0, errors.New("survey was not delivered to any room")
+	}
+	return runID, delivered, nil
+}
+
+// surveyMessage: nội dung tin bot đăng (client mở form qua GET /surveys/runs/{runID})
+func surveyMessage(sv *survey.Survey, runID int64) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "📋 %s\n", sv.Title)
+	if sv.Intro != "" {
+		b.WriteString(sv.Intro + "\n")
+	}
+	b.WriteString("\n")
+	for i, q := range sv.Questions {
+		fmt.Fprintf(&b, "%d. %s", i+1, q.Text)
+		switch q.Type {
+		case survey.QuestionNPS:
+			b.WriteString(" (0-10)")
+		case survey.QuestionRating:
+			b.WriteString(" (1-5)")
+		case survey.QuestionChoice:
+			b.WriteString(" (" + strings.Join(q.Options, " / ") + ")")
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "\nTrả lời: %s%d", surveyRunsPrefix, runID)
+	return b.String()
+}
+
+// syncSurveyJob: survey có cron -> tạo / sửa job survey_post tương ứng, bỏ cron -> xoá job
+func (s *Server) syncSurveyJob(ctx context.Context, sv *survey.Survey, adminID int64) error {
+	if sv.CronSpec == "" {
+		if sv.JobID == 0 {
+			return nil
+		}
+		if err := s.jobRepo.Delete(ctx, sv.JobID); err != nil && !errors.Is(err, job.ErrNotFound) {
+			return err
+		}
+		sv.JobID = 0
+		return s.surveyRepo.SetJobID(ctx, sv.ID, 0)
+	}
+
+	payload, _ := json.Marshal(surveyPostPayload{SurveyID: sv.ID})
+	j := &job.Job{
+		Name:       truncateRunes("survey #"+strconv.FormatInt(sv.ID, 10)+": "+sv.Title, 100),
+		CronSpec:   sv.CronSpec,
+		ActionType: jobActionSurveyPost,
+		Payload:    payload,
+		Enabled:    sv.Active,
+		CreatedBy:  adminID,
+	}
+	next, err := s.jobs.Validate(j, s.now())
+	if err != nil {
+		return err
+	}
+	j.NextRunAt = &next
+
+	if sv.JobID > 0 {
+		j.ID = sv.JobID
+		err := s.jobRepo.Update(ctx, j)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, job.ErrNotFound) {
+			return err
+		}
+		// job bị xoá ở /jobs -> tạo lại
+	}
+	if err := s.jobRepo.Create(ctx, j); err != nil {
+		return err
+	}
+	sv.JobID = j.ID
+	return s.surveyRepo.SetJobID(ctx, sv.ID, j.ID)
+}
+
+type surveyRequest struct {
+	Title     string            `json:"title"`
+	Intro     string            `json:"intro"`
+	Questions []survey.Question `json:"questions"`
+	RoomIDs   []int64           `json:"room_ids"`
+	UserIDs   []int64           `json:"user_ids"`
+	CronSpec  string            `json:"cron"` // rỗng = chỉ gửi tay
+	Active    *bool             `json:"active"`
+}
+
+// toSurvey: validate body + kiểm tra room / user tồn tại
+func (s *Server) toSurvey(ctx context.Context, req *surveyRequest, sv *survey.Survey) error {
+	sv.Title = req.Title
+	sv.Intro = req.Intro
+	sv.Questions = req.Questions
+	sv.RoomIDs = req.RoomIDs
+	sv.UserIDs = req.UserIDs
+	sv.CronSpec = strings.TrimSpace(req.CronSpec)
+	sv.Active = req.Active == nil || *req.Active
+	if err := sv.Validate(); err != nil {
+		return err
+	}
+	if sv.CronSpec != "" {
+		if _, err := job.NextRun(sv.CronSpec, s.now()); err != nil {
+			return errors.New("cron is not a valid cron expression")
+		}
+	}
+	for _, id := range sv.RoomIDs {
+		if _, err := s.roomRepo.GetRoomByID(id); err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("room %d not found", id)
+			}
+			return err
+		}
+	}
+	for _, id := range sv.UserIDs {
+		if _, err := s.userRepo.GetUserBrief(ctx, id); err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("user %d not found", id)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) handleAdminSurveys(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		list, err := s.surveyRepo.List(r.Context())
+		if err != nil {
+			log.Println("survey List error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"surveys": list})
+
+	case http.MethodPost:
+		adminID, _ := UserIDFromContext(r.Context())
+		var req surveyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+			return
+		}
+		sv := &survey.Survey{CreatedBy: adminID}
+		if err := s.toSurvey(r.Context(), &req, sv); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if err := s.surveyRepo.Create(r.Context(), sv); err != nil {
+			log.Println("survey Create error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		if err := s.syncSurveyJob(r.Context(), sv, adminID); err != nil {
+			log.Println("syncSurveyJob error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		s.recordAudit(r, adminID, audit.ActionConfigChange, audit.TargetConfig, sv.ID, map[string]any{"kind": "survey", "op": "create", "title": sv.Title})
+		writeJSON(w, http.StatusCreated, sv)
+
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func (s *Server) handleAdminSurvey(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, adminSurveysPrefix), "/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id <= 0 || len(parts) > 2 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid survey id"})
+		return
+	}
+	ctx := r.Context()
+	adminID, _ := UserIDFromContext(r.Context())
+
+	sv, err := s.surveyRepo.Get(ctx, id)
+	if err != nil {
+		writeSurveyError(w, err)
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, sv)
+
+	case len(parts) == 1 && r.Method == http.MethodPut:
+		var req surveyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+			return
+		}
+		if err := s.toSurvey(ctx, &req, sv); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if err := s.surveyRepo.Update(ctx, sv); err != nil {
+			log.Println("survey Update error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		if err := s.syncSurveyJob(ctx, sv, adminID); err != nil {
+			log.Println("syncSurveyJob error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		s.recordAudit(r, adminID, audit.ActionConfigChange, audit.TargetConfig, sv.ID, map[string]any{"kind": "survey", "op": "update", "title": sv.Title})
+		writeJSON(w, http.StatusOK, sv)
+
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		if sv.JobID > 0 {
+			if err := s.jobRepo.Delete(ctx, sv.JobID); err != nil && !errors.Is(err, job.ErrNotFound) {
+				log.Println("survey job Delete error:", err)
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+				return
+			}
+		}
+		if err := s.surveyRepo.Delete(ctx, id); err != nil {
+			writeSurveyError(w, err)
+			return
+		}
+		s.recordAudit(r, adminID, audit.ActionConfigChange, audit.TargetConfig, id, map[string]any{"kind": "survey", "op": "delete"})
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+
+	case len(parts) == 2 && parts[1] == "run" && r.Method == http.MethodPost:
+		runID, delivered, err := s.postSurvey(ctx, sv)
+		if err != nil {
+			log.Printf("postSurvey survey=%d: %v", id, err)
+			writeJSON(w, http.StatusBadGateway, map[string]any{"error": err.Error(), "run_id": runID})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"run_id": runID, "delivered": delivered})
+
+	case len(parts) == 2 && parts[1] == "runs" && r.Method == http.MethodGet:
+		_, limit := parsePaging(r)
+		runs, err := s.surveyRepo.ListRuns(ctx, id, limit)
+		if err != nil {
+			log.Println("survey ListRuns error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"runs": runs})
+
+	case len(parts) == 2 && parts[1] == "report" && r.Method == http.MethodGet:
+		var runID int64
+		if v := r.URL.Query().Get("run_id"); v != "" {
+			runID, err = strconv.ParseInt(v, 10, 64)
+			if err != nil || runID <= 0 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid run_id"})
+				return
+			}
+		}
+		rep, err := s.surveyRepo.Report(ctx, sv, runID)
+		if err != nil {
+			log.Println("survey Report error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, rep)
+
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
+
+func (s *Server) handleSurveyRun(w http.ResponseWriter, r *http.Request) {
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+	runID, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(r.URL.Path, surveyRunsPrefix), "/"), 10, 64)
+	if err != nil || runID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid run id"})
+		return
+	}
+	ctx := r.Context()
+
+	run, sv, err := s.surveyRepo.GetRun(ctx, runID)
+	if err != nil {
+		writeSurveyError(w, err)
+		return
+	}
+	ok, err := s.surveyRepo.CanRespond(ctx, runID, userID)
+	if err != nil {
+		log.Println("survey CanRespond error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	if !ok {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "this survey was not sent to you"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		mine, err := s.surveyRepo.MyAnswers(ctx, runID, userID)
+		if err != nil {
+			log.Println("survey MyAnswers error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"run_id":     run.ID,
+			"title":      sv.Title,
+			"intro":      sv.Intro,
+			"questions":  sv.Questions,
+			"my_answers": mine,
+			"sent_at":    run.CreatedAt,
+		})
+
+	case http.MethodPost:
+		if !sv.Active {
+			writeJSON(w, http.StatusGone, map[string]string{"error": "survey is closed"})
+			return
+		}
+		var req struct {
+			Answers []survey.Answer `json:"answers"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Answers) == 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "answers is required"})
+			return
+		}
+		if err := survey.CheckAnswers(sv, req.Answers); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if err := s.surveyRepo.SaveAnswers(ctx, runID, sv.ID, userID, req.Answers); err != nil {
+			log.Println("survey SaveAnswers error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func writeSurveyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, survey.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "survey not found"})
+	case errors.Is(err, survey.ErrRunNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "survey run not found"})
+	default:
+		log.Println("survey error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+	}
+}
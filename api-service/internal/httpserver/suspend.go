@@ -0,0 +1,151 @@
+package httpserver
+
+import (
+	"cronhustler/api-service/internal/audit"
+	"cronhustler/api-service/internal/webhook"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const adminUsersPrefix = "/admin/users/"
+
+func (s *Server) mountSuspendRoutes(mux *http.ServeMux) {
+	// POST /admin/users/{id}/suspend   {reason, duration_hours? | until?} -> khoá tạm thời
+	// POST /admin/users/{id}/unsuspend {reason}                          -> mở khoá sớm
+	// (/admin/users/role, /admin/users/limits khớp chính xác nên không bị prefix này nuốt)
+	mux.Handle(adminUsersPrefix, s.RequireAdmin(http.HandlerFunc(s.handleAdminUserSuspension)))
+}
+
+type suspendUserRequest struct {
+	Reason        string `json:"reason"`
+	DurationHours int    `json:"duration_hours"` // mặc định 24
+	Until         string `json:"until"`          // RFC3339, ưu tiên hơn duration_hours
+}
+
+func (s *Server) handleAdminUserSuspension(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, adminUsersPrefix), "/"), "/")
+	if len(parts) != 2 || (parts[1] != "suspend" && parts[1] != "unsuspend") {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	userID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || userID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid user id"})
+		return
+	}
+	adminID, _ := UserIDFromContext(r.Context())
+
+	var req suspendUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "reason is required"})
+		return
+	}
+	if len([]rune(req.Reason)) > 1000 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "reason too long (max 1000 chars)"})
+		return
+	}
+
+	u, err := s.userRepo.GetUserByID(int(userID))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "user not found"})
+		return
+	}
+
+	if parts[1] == "unsuspend" {
+		s.adminUnsuspendUser(w, r, adminID, userID, req.Reason)
+		return
+	}
+
+	if userID == adminID {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "cannot suspend yourself"})
+		return
+	}
+	if u.Role == "superadmin" {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "cannot suspend superadmin"})
+		return
+	}
+
+	var until time.Time
+	if req.Until != "" {
+		until, err = time.Parse(time.RFC3339, req.Until)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "until must be RFC3339"})
+			return
+		}
+		if !until.After(s.now()) || until.After(s.now().Add(maxSuspendHours*time.Hour)) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("until must be within the next %d hours", maxSuspendHours)})
+			return
+		}
+	} else {
+		hours := req.DurationHours
+		if hours == 0 {
+			hours = defaultSuspendHours
+		}
+		if hours < 1 || hours > maxSuspendHours {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("duration_hours must be 1..%d", maxSuspendHours)})
+			return
+		}
+		until = s.now().Add(time.Duration(hours) * time.Hour)
+	}
+
+	ctx := r.Context()
+	if err := s.moderationRepo.Suspend(ctx, userID, until); err != nil {
+		log.Println("Suspend error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	s.recordAudit(r, adminID, audit.ActionUserSuspend, audit.TargetUser, userID, map[string]any{
+		"until":  until.Format(time.RFC3339),
+		"reason": req.Reason,
+	})
+	s.emitUserEvent(webhook.EventUserSuspended, userID, map[string]any{
+		"until":  until.Format(time.RFC3339),
+		"reason": req.Reason,
+	})
+	// socket đang mở: FE tự logout (kết nối mới bị chặn ở handleWebSocket)
+	wsSendToUser(userID, wsEnvelope{
+		Type: "account_suspended",
+		Data: map[string]any{"suspended_until": until.Format(time.RFC3339)},
+	})
+	s.moderationNotify(ctx, userID, "⛔ Tài khoản của bạn bị tạm khoá tới "+until.Format("15:04 02/01/2006")+".\n"+req.Reason)
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"user_id":         userID,
+		"suspended_until": until.Format(time.RFC3339),
+	})
+}
+
+func (s *Server) adminUnsuspendUser(w http.ResponseWriter, r *http.Request, adminID, userID int64, reason string) {
+	ok, err := s.moderationRepo.Unsuspend(r.Context(), userID)
+	if err != nil {
+		log.Println("Unsuspend error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	if !ok {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "user is not suspended"})
+		return
+	}
+
+	s.recordAudit(r, adminID, audit.ActionUserUnsuspend, audit.TargetUser, userID, map[string]any{
+		"reason": reason,
+	})
+	s.moderationNotify(r.Context(), userID, "✅ Tài khoản của bạn đã được mở khoá.")
+
+	writeJSON(w, http.StatusOK, map[string]any{"user_id": userID, "suspended": false})
+}
@@ -0,0 +1,131 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/room"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const messagesPrefix = "/messages/"
+
+type messageThreadResponse struct {
+	Message RoomMessageResponse   `json:"message"` // message gốc
+	Replies []RoomMessageResponse `json:"replies"` // cũ -> mới, gồm cả reply của reply
+	HasMore bool                  `json:"has_more"`
+	// trang tiếp: ?after_id=<next_after_id>
+	NextAfterID int64 `json:"next_after_id,omitempty"`
+}
+
+// messageRoutes: /messages/{messageID}[/...] (các route /messages/xxx cố định đăng ký riêng, khớp trước)
+func (s *Server) messageRoutes() http.Handler {
+	m := newPatternMux(messagesPrefix)
+	withMessageID := func(h func(http.ResponseWriter, *http.Request, int64)) http.HandlerFunc {
+		return withPathID("messageID", "invalid message id", h)
+	}
+	m.handle(http.MethodDelete, "/messages/{messageID}", withMessageID(s.handleDeleteMessage))
+	m.handle(http.MethodGet, "/messages/{messageID}/thread", withMessageID(s.handleMessageThread))
+	m.handle(http.MethodPost, "/messages/{messageID}/pin", withMessageID(s.handleMessagePin))
+	m.handle(http.MethodDelete, "/messages/{messageID}/pin", withMessageID(s.handleMessagePin))
+	return m
+}
+
+// GET /messages/{id}/thread?after_id=&limit=
+// message gốc + mọi message reply vào nó (theo chuỗi reply_to_message_id)
+func (s *Server) handleMessageThread(w http.ResponseWriter, r *http.Request, messageID int64) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	q := r.URL.Query()
+	limit := 50
+	if v, err := strconv.Atoi(q.Get("limit")); err == nil && v > 0 && v <= 200 {
+		limit = v
+	}
+	afterID, _ := strconv.ParseInt(q.Get("after_id"), 10, 64)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	roomID, err := s.chatRepo.GetMessageRoomID(ctx, messageID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "message not found"})
+			return
+		}
+		log.Println("GetMessageRoomID error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	isMember, err := s.roomRepo.IsUserInRoom(roomID, userID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	if !isMember {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "you are not a member of this room"})
+		return
+	}
+
+	root, err := s.roomRepo.GetMessage(ctx, roomID, messageID, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "message not found"})
+			return
+		}
+		log.Println("GetMessage error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	// cursor: created_at của message after_id (cùng room)
+	var afterAt time.Time
+	if afterID > 0 {
+		afterAt, err = s.roomRepo.GetMessageCreatedAt(ctx, roomID, afterID)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid after_id"})
+			return
+		}
+	}
+
+	// lấy dư 1 để biết còn trang sau
+	msgs, err := s.roomRepo.GetMessageThread(ctx, roomID, messageID, afterID, afterAt, limit+1, userID)
+	if err != nil {
+		log.Println("GetMessageThread error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	uploadRooms := s.messageUploadRooms(ctx, append([]*room.Message{root}, msgs...)...)
+	resp := messageThreadResponse{
+		Message: s.toRoomMessageResponse(root, uploadRooms),
+		Replies: make([]RoomMessageResponse, 0, min(len(msgs), limit)),
+	}
+	if len(msgs) > limit {
+		msgs = msgs[:limit]
+		resp.HasMore = true
+		resp.NextAfterID = msgs[len(msgs)-1].ID
+	}
+	for _, m := range msgs {
+		resp.Replies = append(resp.Replies, s.toRoomMessageResponse(m, uploadRooms))
+	}
+	s.attachLinkPreviews(ctx, resp.Replies)
+	s.attachReceiptStatus(ctx, roomID, userID, resp.Replies)
+	if p, err := s.linkPreviewRepo.ListByMessageIDs(ctx, []int64{root.ID}); err == nil {
+		resp.Message.LinkPreview = p[root.ID]
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,775 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"cronhustler/api-service/internal/chat"
+	"cronhustler/api-service/internal/media"
+	"cronhustler/api-service/internal/quota"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// giới hạn 1 file upload chat (multipart + base64 dùng chung)
+const chatUploadMaxBytes = 10 << 20
+
+// thời gian tối đa đọc metadata 1 file
+const probeTimeout = 10 * time.Second
+
+var (
+	errUploadUnsupported = errors.New("unsupported image type")
+	errUploadTooLarge    = errors.New("file too large")
+)
+
+// chatUpload: file đã lưu trong chatUploadDir
+type chatUpload struct {
+	Filename     string
+	OriginalName string // tên file lúc client upload
+	MediaURL     string
+	Mime         string
+	Size         int64
+	Meta         *media.Metadata // nil nếu không đọc được
+
+	// ảnh: bản thu nhỏ cho message list ("" = không có, dùng bản gốc)
+	ThumbURL  string
+	MediumURL string
+
+	localPath string // file trên đĩa để probe / waveform
+	tmp       bool   // localPath là file tạm (object store) -> releaseChatUpload
+}
+
+// authorizeRoomUpload: auth + parse roomID (segment cuối) + check member
+// lỗi thì đã ghi response, trả ok=false
+func (s *Server) authorizeRoomUpload(w http.ResponseWriter, r *http.Request) (userID, roomID int64, ok bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return 0, 0, false
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return 0, 0, false
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 3 {
+		http.Error(w, "invalid path", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	roomID, err = strconv.ParseInt(parts[len(parts)-1], 10, 64)
+	if err != nil || roomID <= 0 {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	isMember, err := s.roomRepo.IsUserInRoom(roomID, userID)
+	if err != nil {
+		http.Error(w, "member check failed", http.StatusInternalServerError)
+		return 0, 0, false
+	}
+	if !isMember {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return 0, 0, false
+	}
+
+	return userID, roomID, true
+}
+
+// resolveChatUploadMime: sniff mime từ đầu file, trả mime + đuôi file sẽ lưu
+// declared = Content-Type client khai báo (multipart header / data URI)
+func (s *Server) resolveChatUploadMime(head []byte, declared, origName string) (string, string, error) {
+	mime := http.DetectContentType(head)
+	ext := strings.ToLower(filepath.Ext(origName))
+
+	// audio (voice message, FE gửi message_type=file) -> ép đuôi theo mime
+	// để phân biệt .weba với video .webm
+	if audioMime, ok := audioUploadMime(mime, declared, ext); ok {
+		return audioMime, mimeToExt(audioMime), nil
+	}
+
+	// video chỉ nhận khi bật transcoder (FE gửi message_type=file)
+	if !isAllowedImageMime(mime) && !(s.transcoder != nil && isAllowedVideoMime(mime)) {
+		return "", "", errUploadUnsupported
+	}
+
+	if ext == "" {
+		ext = mimeToExt(mime)
+	}
+	return mime, ext, nil
+}
+
+// storeChatUpload: ghi src vào storage với tên r{room}_u{user}_{ts}{ext}
+// tên gốc lưu riêng trong chat_uploads (dùng cho /media/download)
+func (s *Server) storeChatUpload(roomID, userID int64, mime, ext, origName string, src io.Reader) (*chatUpload, error) {
+	up, err := s.storeChatUploadLimit(roomID, userID, mime, ext, origName, src, chatUploadMaxBytes)
+	s.releaseChatUpload(up)
+	return up, err
+}
+
+// storeChatUploadLimit: như storeChatUpload nhưng giới hạn size theo loại file
+// object store -> giữ file tạm ở localPath, caller gọi releaseChatUpload khi xong
+func (s *Server) storeChatUploadLimit(roomID, userID int64, mime, ext, origName string, src io.Reader, limit int64) (*chatUpload, error) {
+	filename := fmt.Sprintf("r%d_u%d_%d%s", roomID, userID, time.Now().UnixNano(), ext)
+	key := chatUploadKey(filename)
+
+	// local -> ghi thẳng vào chatUploadDir, object store -> file tạm để probe rồi upload
+	fullPath := s.store.LocalPath(key)
+	remote := fullPath == ""
+
+	var out *os.File
+	var err error
+	if remote {
+		out, err = os.CreateTemp("", "chatupload-*"+ext)
+	} else {
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+			return nil, fmt.Errorf("cannot create upload dir: %w", err)
+		}
+		out, err = os.Create(fullPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+	fullPath = out.Name()
+
+	// đọc dư 1 byte để biết có vượt limit không
+	n, err := io.Copy(out, io.LimitReader(src, limit+1))
+	if err != nil {
+		_ = os.Remove(fullPath)
+		return nil, err
+	}
+	if n > limit {
+		_ = os.Remove(fullPath)
+		return nil, errUploadTooLarge
+	}
+
+	// ảnh: bỏ EXIF (GPS...) + xoay theo orientation trước khi probe / upload lên store
+	if !s.keepImageMeta && strings.HasPrefix(mime, "image/") {
+		if size, err := media.StripImageMetadata(fullPath); err != nil {
+			log.Printf("[exif] %s: %v", filename, err)
+		} else {
+			n = size
+		}
+	}
+
+	up := &chatUpload{
+		Filename:     filename,
+		OriginalName: sanitizeOriginalName(origName, filename),
+		MediaURL:     chatUploadPrefix + filename,
+		Mime:         mime,
+		Size:         n,
+		Meta:         s.probeChatUpload(fullPath, mime),
+		localPath:    fullPath,
+		tmp:          remote,
+	}
+
+	if remote {
+		saveCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+
+		if _, err := out.Seek(0, io.SeekStart); err != nil {
+			_ = os.Remove(fullPath)
+			return nil, err
+		}
+		if err := s.store.Save(saveCtx, key, out, mime); err != nil {
+			_ = os.Remove(fullPath)
+			return nil, fmt.Errorf("storage save: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// lưu tên gốc fail -> download dùng tên file trên đĩa, không chặn upload
+	if err := s.chatRepo.CreateUpload(ctx, &chat.Upload{
+		FileName:     up.Filename,
+		RoomID:       roomID,
+		UploaderID:   userID,
+		OriginalName: up.OriginalName,
+		ContentType:  up.Mime,
+		FileSize:     up.Size,
+	}); err != nil {
+		log.Println("CreateUpload error:", err)
+	}
+
+	s.scanChatUpload(up, roomID, userID)
+	return up, nil
+}
+
+// storeImageVariants: tạo thumb + medium từ ảnh vừa upload, lưu cạnh file gốc
+// lỗi chỉ log, FE vẫn dùng được bản gốc
+func (s *Server) storeImageVariants(roomID, userID int64, up *chatUpload) {
+	if up == nil || up.localPath == "" {
+		return
+	}
+
+	variants, err := media.GenerateVariants(up.localPath, media.DefaultVariants)
+	if err != nil {
+		if !errors.Is(err, media.ErrThumbnailUnsupported) {
+			log.Printf("[thumbnail] %s: %v", up.Filename, err)
+		}
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	for _, v := range variants {
+		name := media.VariantName(up.Filename, v.Name)
+		if err := s.store.Save(ctx, chatUploadKey(name), bytes.NewReader(v.Data), v.Mime); err != nil {
+			log.Printf("[thumbnail] save %s: %v", name, err)
+			continue
+		}
+		if err := s.chatRepo.CreateUpload(ctx, &chat.Upload{
+			FileName:     name,
+			RoomID:       roomID,
+			UploaderID:   userID,
+			OriginalName: up.OriginalName,
+			ContentType:  v.Mime,
+			FileSize:     int64(len(v.Data)),
+		}); err != nil {
+			log.Println("CreateUpload (variant) error:", err)
+		}
+
+		switch v.Name {
+		case media.VariantThumb:
+			up.ThumbURL = chatUploadPrefix + name
+		case media.VariantMedium:
+			up.MediumURL = chatUploadPrefix + name
+		}
+	}
+}
+
+// sanitizeOriginalName: bỏ path, ký tự điều khiển, giới hạn 255 ký tự
+func sanitizeOriginalName(name, fallback string) string {
+	name = filepath.Base(strings.ReplaceAll(strings.TrimSpace(name), "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == "/" {
+		return fallback
+	}
+	if r := []rune(name); len(r) > 255 {
+		name = string(r[:255])
+	}
+	return name
+}
+
+// probeChatUpload: kích thước ảnh / độ dài + resolution video / độ dài audio
+func (s *Server) probeChatUpload(fullPath, mime string) *media.Metadata {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	md, err := media.Probe(ctx, s.ffprobePath, fullPath, mime)
+	if err != nil {
+		if !errors.Is(err, media.ErrProbeUnsupported) {
+			log.Printf("[probe] %s: %v", filepath.Base(fullPath), err)
+		}
+		return nil
+	}
+	return md
+}
+
+// applyMeta: copy metadata vào attachment
+func (up *chatUpload) applyMeta(att *chat.Attachment) {
+	if up.Meta == nil {
+		return
+	}
+	att.Width = up.Meta.Width
+	att.Height = up.Meta.Height
+	att.DurationMs = up.Meta.DurationMs
+}
+
+// writeChatUploadResponse: FE sẽ dùng media_url để insert message
+func (s *Server) writeChatUploadResponse(w http.ResponseWriter, roomID int64, up *chatUpload) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.chatUploadResponse(roomID, up))
+}
+
+func (s *Server) chatUploadResponse(roomID int64, up *chatUpload) map[string]any {
+	resp := map[string]any{
+		"ok":            true,
+		"room_id":       roomID,
+		"media_url":     up.MediaURL,
+		"signed_url":    s.signMediaURL(up.MediaURL), // dùng để preview, KHÔNG lưu vào message
+		"filename":      up.Filename,
+		"original_name": up.OriginalName,
+		"mime":          up.Mime,
+		"size":          up.Size,
+	}
+	// metadata để FE giữ chỗ layout ngay khi preview
+	if up.Meta != nil {
+		resp["width"] = up.Meta.Width
+		resp["height"] = up.Meta.Height
+		resp["duration_ms"] = up.Meta.DurationMs
+	}
+	// ảnh lớn: FE hiển thị thumb trong list, medium khi xem nhanh
+	if up.ThumbURL != "" {
+		resp["thumb_url"] = up.ThumbURL
+		resp["signed_thumb_url"] = s.signMediaURL(up.ThumbURL)
+	}
+	if up.MediumURL != "" {
+		resp["medium_url"] = up.MediumURL
+		resp["signed_medium_url"] = s.signMediaURL(up.MediumURL)
+	}
+	return resp
+}
+
+// chatUploadName: "/static/chat_uploads/<name>" (bỏ query nếu FE gửi lại signed URL) -> name 1 segment
+func chatUploadName(content string) (string, bool) {
+	name, ok := strings.CutPrefix(content, chatUploadPrefix)
+	if !ok {
+		return "", false
+	}
+	if i := strings.IndexByte(name, '?'); i >= 0 {
+		name = name[:i]
+	}
+	if name == "" || name != filepath.Base(name) {
+		return "", false
+	}
+	return name, true
+}
+
+// uploadOwnerFailure: tin image / file / audio phải trỏ tới file chính user upload vào room này
+// (dán URL file của room khác vào room mình -> tin được ký URL -> đọc được file của room đó)
+func (s *Server) uploadOwnerFailure(ctx context.Context, userID, roomID int64, msgType, content string) *apiFailure {
+	if msgType != "image" && msgType != "file" && msgType != "audio" {
+		return nil
+	}
+	name, ok := chatUploadName(content)
+	if !ok {
+		return newFailure(http.StatusBadRequest, CodeInvalidRequest, msgType+" message must use media_url from the upload endpoints")
+	}
+	up, err := s.chatRepo.GetUpload(ctx, name)
+	if err != nil && !errors.Is(err, chat.ErrUploadNotFound) {
+		log.Println("GetUpload error:", err)
+		return newFailure(http.StatusInternalServerError, CodeDBError, "db error")
+	}
+	if err != nil || up.RoomID != roomID || up.UploaderID != userID {
+		return newFailure(http.StatusBadRequest, CodeInvalidRequest, "media_url must be a file you uploaded to this room")
+	}
+	return nil
+}
+
+// attachChatUpload: message image/file trỏ tới file trong chat uploads
+// -> lưu attachment kèm metadata (+ waveform nếu là audio)
+func (s *Server) attachChatUpload(ctx context.Context, messageID int64, messageType, content string) []chat.Attachment {
+	if (messageType != "image" && messageType != "file" && messageType != "audio") || !strings.HasPrefix(content, chatUploadPrefix) {
+		return nil
+	}
+
+	name := strings.TrimPrefix(content, chatUploadPrefix)
+	if i := strings.IndexByte(name, '?'); i >= 0 {
+		name = name[:i]
+	}
+	if name == "" || name != filepath.Base(name) {
+		return nil
+	}
+
+	info, err := s.store.Head(ctx, chatUploadKey(name))
+	if err != nil {
+		log.Printf("[attach] stat %s: %v", name, err)
+		return nil
+	}
+
+	original, err := s.chatRepo.GetUploadOriginalName(ctx, name)
+	if err != nil {
+		log.Println("GetUploadOriginalName error:", err)
+	}
+
+	ext := filepath.Ext(name)
+	up := &chatUpload{
+		Filename:     name,
+		OriginalName: sanitizeOriginalName(original, name),
+		MediaURL:     chatUploadPrefix + name,
+		Mime:         mediaMimeFromExt(ext),
+		Size:         info.Size,
+	}
+	// probe / waveform cần file local, object store thì bỏ qua
+	fullPath := s.store.LocalPath(chatUploadKey(name))
+	if fullPath != "" {
+		up.Meta = s.probeChatUpload(fullPath, up.Mime)
+	}
+
+	att := chat.Attachment{
+		MessageID:   messageID,
+		FileName:    up.OriginalName,
+		FileSize:    up.Size,
+		ContentType: up.Mime,
+		FilePath:    up.MediaURL,
+		CreatedAt:   s.now().UTC(),
+	}
+	up.applyMeta(&att)
+	if messageType == "image" {
+		att.ThumbURL = s.existingVariantURL(ctx, name, media.VariantThumb)
+		att.MediumURL = s.existingVariantURL(ctx, name, media.VariantMedium)
+	}
+	if fullPath != "" && isAudioExt(ext) {
+		s.fillAudioWaveform(ctx, &att, fullPath)
+	}
+
+	if _, err := s.chatRepo.CreateAttachment(ctx, &att); err != nil {
+		log.Println("CreateAttachment error:", err)
+		return nil
+	}
+
+	return s.signAttachments([]chat.Attachment{att})
+}
+
+// existingVariantURL: URL biến thể nếu lúc upload đã tạo (ảnh nhỏ / webp thì không có)
+func (s *Server) existingVariantURL(ctx context.Context, name, variant string) string {
+	vname := media.VariantName(name, variant)
+	if _, err := s.store.Head(ctx, chatUploadKey(vname)); err != nil {
+		return ""
+	}
+	return chatUploadPrefix + vname
+}
+
+func writeChatUploadError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errUploadUnsupported), errors.Is(err, errFileUnsupported):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, errUploadTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	default:
+		http.Error(w, "cannot save file", http.StatusInternalServerError)
+	}
+}
+
+// ==============================
+// Base64 / data URI (paste clipboard từ web)
+// ==============================
+
+type uploadBase64Request struct {
+	Data     string `json:"data"`               // data:image/png;base64,....  (hoặc base64 trần)
+	Filename string `json:"filename,omitempty"` // optional, chỉ để lấy đuôi file
+}
+
+// parseDataURI: tách mime khai báo + payload base64
+func parseDataURI(v string) (declared string, payload string, err error) {
+	v = strings.TrimSpace(v)
+	if !strings.HasPrefix(v, "data:") {
+		return "", v, nil
+	}
+
+	meta, payload, found := strings.Cut(v[len("data:"):], ",")
+	if !found {
+		return "", "", errors.New("invalid data uri")
+	}
+	params := strings.Split(meta, ";")
+	if len(params) < 2 || params[len(params)-1] != "base64" {
+		return "", "", errors.New("data uri must be base64")
+	}
+	return params[0], payload, nil
+}
+
+// POST /rooms/upload-image-base64/{roomID}
+// JSON: {"data":"data:image/png;base64,...","filename":"paste.png"}
+func (s *Server) handleUploadRoomImageBase64(w http.ResponseWriter, r *http.Request) {
+	userID, roomID, ok := s.authorizeRoomUpload(w, r)
+	if !ok {
+		return
+	}
+
+	// base64 phình ~4/3 + chút overhead cho JSON
+	r.Body = http.MaxBytesReader(w, r.Body, chatUploadMaxBytes*4/3+4096)
+
+	var req uploadBase64Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeChatUploadError(w, errUploadTooLarge)
+			return
+		}
+		http.Error(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+
+	declared, payload, err := parseDataURI(req.Data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// chấp nhận cả std lẫn url-safe, có/không padding
+	payload = strings.TrimRight(strings.TrimSpace(payload), "=")
+	data, err := base64.RawStdEncoding.DecodeString(payload)
+	if err != nil {
+		data, err = base64.RawURLEncoding.DecodeString(payload)
+	}
+	if err != nil || len(data) == 0 {
+		http.Error(w, "invalid base64 data", http.StatusBadRequest)
+		return
+	}
+	if len(data) > chatUploadMaxBytes {
+		writeChatUploadError(w, errUploadTooLarge)
+		return
+	}
+
+	head := data
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	mime, ext, err := s.resolveChatUploadMime(head, declared, filepath.Base(req.Filename))
+	if err != nil {
+		writeChatUploadError(w, err)
+		return
+	}
+
+	up, err := s.storeChatUpload(roomID, userID, mime, ext, req.Filename, bytes.NewReader(data))
+	if err != nil {
+		writeChatUploadError(w, err)
+		return
+	}
+
+	s.writeChatUploadResponse(w, roomID, up)
+}
+
+// ==============================
+// Multi-file: nhiều file -> 1 message có attachments
+// ==============================
+
+// số file tối đa trong 1 request
+const chatUploadMaxFiles = 10
+
+type uploadFailure struct {
+	FileName string `json:"file_name"`
+	Error    string `json:"error"`
+}
+
+type uploadFilesResponse struct {
+	Message sendMessageResponse `json:"message"`
+	Failed  []uploadFailure     `json:"failed,omitempty"` // file lỗi (các file khác vẫn gửi)
+}
+
+// POST /rooms/upload-files/{roomID}
+// multipart/form-data: files=<f1>, files=<f2>..., content=<caption, optional>, reply_to_message_id=<optional>
+// file lỗi (sai type, quá lớn) bị bỏ qua và trả về trong "failed"; tất cả lỗi -> 400
+func (s *Server) handleUploadRoomFiles(w http.ResponseWriter, r *http.Request) {
+	userID, roomID, ok := s.authorizeRoomUpload(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, chatUploadMaxFiles*(chatUploadMaxBytes+4096))
+	if err := r.ParseMultipartForm(chatUploadMaxBytes); err != nil {
+		http.Error(w, "cannot parse form", http.StatusBadRequest)
+		return
+	}
+	defer func() { _ = r.MultipartForm.RemoveAll() }()
+
+	headers := r.MultipartForm.File["files"]
+	if len(headers) == 0 {
+		http.Error(w, "missing files", http.StatusBadRequest)
+		return
+	}
+	if len(headers) > chatUploadMaxFiles {
+		http.Error(w, fmt.Sprintf("too many files (max %d)", chatUploadMaxFiles), http.StatusBadRequest)
+		return
+	}
+
+	replyTo, ok := formReplyTo(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	// 1) lưu từng file, lỗi file nào ghi vào failed file đó
+	var (
+		atts   []chat.Attachment
+		failed []uploadFailure
+		stored []string // để dọn nếu insert DB fail
+	)
+	allImages := true
+
+	for _, fh := range headers {
+		up, err := s.storeMultipartFile(roomID, userID, fh)
+		if err != nil {
+			msg := err.Error()
+			if !errors.Is(err, errUploadUnsupported) && !errors.Is(err, errUploadTooLarge) {
+				log.Println("storeMultipartFile error:", err)
+				msg = "cannot save file"
+			}
+			failed = append(failed, uploadFailure{FileName: fh.Filename, Error: msg})
+			continue
+		}
+		defer s.releaseChatUpload(up)
+		stored = append(stored, up.Filename)
+
+		att := chat.Attachment{
+			FileName:    up.OriginalName,
+			FileSize:    up.Size,
+			ContentType: up.Mime,
+			FilePath:    up.MediaURL,
+			CreatedAt:   s.now().UTC(),
+		}
+		up.applyMeta(&att)
+		if isAudioExt(filepath.Ext(up.Filename)) {
+			s.fillAudioWaveform(ctx, &att, up.localPath)
+		}
+		if !isAllowedImageMime(up.Mime) {
+			allImages = false
+		}
+		atts = append(atts, att)
+	}
+
+	if len(atts) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error":  "no valid files",
+			"failed": failed,
+		})
+		return
+	}
+
+	// 2) 1 message + n attachments (atomic)
+	msgType := "file"
+	if allImages {
+		msgType = "image"
+	}
+	// không có caption -> content = file đầu (client cũ vẫn render được)
+	content := strings.TrimSpace(r.FormValue("content"))
+	if content == "" {
+		content = atts[0].FilePath
+	}
+
+	resp, ok := s.createUploadMessage(w, r, &chat.Message{
+		RoomID:           roomID,
+		SenderID:         userID,
+		Content:          content,
+		MessageType:      msgType,
+		ReplyToMessageID: replyTo,
+	}, atts, stored)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, uploadFilesResponse{Message: resp, Failed: failed})
+
+	s.broadcastMessageCreated(ctx, roomID, userID, resp)
+}
+
+// formReplyTo: reply_to_message_id trong form (optional), lỗi thì đã ghi response
+func formReplyTo(w http.ResponseWriter, r *http.Request) (*int64, bool) {
+	v := strings.TrimSpace(r.FormValue("reply_to_message_id"))
+	if v == "" {
+		return nil, true
+	}
+	id, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "invalid reply_to_message_id", http.StatusBadRequest)
+		return nil, false
+	}
+	return &id, true
+}
+
+// createUploadMessage: 1 message + n attachments (atomic), dựng response giống send message
+// msg: caller điền room / sender / content / type / reply (+ media_*), created_at set ở đây
+// chặn như send message: tài khoản bị khoá, quota, word filter cho caption
+// bị chặn / insert fail -> xoá file đã lưu (stored = tên file), đã ghi response, trả ok=false
+func (s *Server) createUploadMessage(
+	w http.ResponseWriter,
+	r *http.Request,
+	msg *chat.Message,
+	atts []chat.Attachment,
+	stored []string,
+) (sendMessageResponse, bool) {
+	ctx := r.Context()
+	discard := func() {
+		for _, name := range stored {
+			s.deleteChatUpload(ctx, name)
+		}
+	}
+	reject := func(f *apiFailure) (sendMessageResponse, bool) {
+		discard()
+		f.write(w)
+		return sendMessageResponse{}, false
+	}
+
+	// user đang bị khoá: tin kèm file cũng là gửi tin
+	if f := s.suspendedFailure(ctx, msg.SenderID); f != nil {
+		return reject(f)
+	}
+	// flood control như send message: tin kèm file tính vào quota tin / phút, tin / ngày
+	if f := s.quotaFailure(ctx, msg.SenderID, quota.KindMessagesPerMinute, quota.KindMessagesPerDay); f != nil {
+		return reject(f)
+	}
+	// caption qua word filter như tin text (không có caption thì content là URL file)
+	var flagged []string
+	if isUploadCaption(msg.Content, atts) {
+		var f *apiFailure
+		if msg.Content, flagged, f = s.applyWordFilter(ctx, msg.RoomID, msg.Content); f != nil {
+			return reject(f)
+		}
+	}
+
+	msg.CreatedAt = s.now().UTC()
+
+	id, err := s.chatRepo.CreateMessageWithAttachments(ctx, msg, atts, true)
+	if err != nil {
+		discard()
+		if errors.Is(err, chat.ErrInvalidReplyTarget) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid reply target"})
+			return sendMessageResponse{}, false
+		}
+		log.Println("CreateMessageWithAttachments error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return sendMessageResponse{}, false
+	}
+
+	if len(flagged) > 0 {
+		s.flagFilteredMessage(ctx, id, msg.RoomID, msg.SenderID, msg.Content, flagged)
+	}
+
+	return s.sendResponse(ctx, msg, s.signAttachments(atts)), true
+}
+
+// isUploadCaption: content là chữ người gửi nhập, không phải URL file gắn kèm
+func isUploadCaption(content string, atts []chat.Attachment) bool {
+	for _, a := range atts {
+		if content == a.FilePath {
+			return false
+		}
+	}
+	return true
+}
+
+// storeMultipartFile: sniff + lưu 1 file trong multipart form (caller releaseChatUpload)
+func (s *Server) storeMultipartFile(roomID, userID int64, fh *multipart.FileHeader) (*chatUpload, error) {
+	if fh.Size > chatUploadMaxBytes {
+		return nil, errUploadTooLarge
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	mime, ext, err := s.resolveChatUploadMime(head[:n], fh.Header.Get("Content-Type"), fh.Filename)
+	if err != nil {
+		return nil, err
+	}
+	return s.storeChatUploadLimit(roomID, userID, mime, ext, fh.Filename, f, chatUploadMaxBytes)
+}
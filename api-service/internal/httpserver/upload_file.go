@@ -0,0 +1,224 @@
+package httpserver
+
+import (
+	"bytes"
+	"cronhustler/api-service/internal/chat"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// giới hạn size theo loại file (POST /rooms/upload-file)
+const (
+	chatFileMaxImage    = chatUploadMaxBytes
+	chatFileMaxAudio    = 20 << 20
+	chatFileMaxVideo    = 100 << 20
+	chatFileMaxDocument = 25 << 20
+	chatFileMaxArchive  = 50 << 20
+	chatFileMaxText     = 5 << 20
+)
+
+// sniff: loại nội dung thật của file, để không tin đuôi file client gửi
+const (
+	sniffPDF  = "pdf"
+	sniffZip  = "zip"  // zip + docx/xlsx/pptx (OOXML cũng là zip)
+	sniffOLE  = "ole"  // doc/xls/ppt đời cũ
+	sniffText = "text" // txt/csv/md
+)
+
+type chatFileType struct {
+	Mime     string
+	Sniff    string
+	MaxBytes int64
+}
+
+// document cho phép theo đuôi file
+var chatFileTypes = map[string]chatFileType{
+	".pdf":  {"application/pdf", sniffPDF, chatFileMaxDocument},
+	".zip":  {"application/zip", sniffZip, chatFileMaxArchive},
+	".docx": {"application/vnd.openxmlformats-officedocument.wordprocessingml.document", sniffZip, chatFileMaxDocument},
+	".xlsx": {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", sniffZip, chatFileMaxDocument},
+	".pptx": {"application/vnd.openxmlformats-officedocument.presentationml.presentation", sniffZip, chatFileMaxDocument},
+	".doc":  {"application/msword", sniffOLE, chatFileMaxDocument},
+	".xls":  {"application/vnd.ms-excel", sniffOLE, chatFileMaxDocument},
+	".ppt":  {"application/vnd.ms-powerpoint", sniffOLE, chatFileMaxDocument},
+	".txt":  {"text/plain", sniffText, chatFileMaxText},
+	".csv":  {"text/csv", sniffText, chatFileMaxText},
+	".md":   {"text/markdown", sniffText, chatFileMaxText},
+}
+
+// file lớn nhất trong các loại trên (giới hạn body request)
+const chatFileMaxBytes = chatFileMaxVideo
+
+var errFileUnsupported = errors.New("unsupported file type")
+
+var oleMagic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+func sniffKind(head []byte) string {
+	mime := http.DetectContentType(head)
+	switch {
+	case mime == "application/pdf":
+		return sniffPDF
+	case mime == "application/zip":
+		return sniffZip
+	case bytes.HasPrefix(head, oleMagic):
+		return sniffOLE
+	case strings.HasPrefix(mime, "text/plain"):
+		return sniffText
+	default:
+		return ""
+	}
+}
+
+// resolveChatFileType: ảnh / audio / video theo nội dung, document theo đuôi file
+// (đuôi phải khớp nội dung sniff được). Trả mime + đuôi lưu + giới hạn size
+func resolveChatFileType(head []byte, declared, origName string) (string, string, int64, error) {
+	mime := http.DetectContentType(head)
+	ext := strings.ToLower(filepath.Ext(origName))
+
+	if audioMime, ok := audioUploadMime(mime, declared, ext); ok {
+		return audioMime, mimeToExt(audioMime), chatFileMaxAudio, nil
+	}
+	if isAllowedImageMime(mime) {
+		if ext == "" || !strings.HasPrefix(mediaMimeFromExt(ext), "image/") {
+			ext = mimeToExt(mime)
+		}
+		return mime, ext, chatFileMaxImage, nil
+	}
+	if isAllowedVideoMime(mime) {
+		if !isVideoExt(ext) {
+			ext = mimeToExt(mime)
+		}
+		return mime, ext, chatFileMaxVideo, nil
+	}
+
+	ft, ok := chatFileTypes[ext]
+	if !ok || sniffKind(head) != ft.Sniff {
+		return "", "", 0, errFileUnsupported
+	}
+	return ft.Mime, ext, ft.MaxBytes, nil
+}
+
+type uploadFileResponse struct {
+	Message sendMessageResponse `json:"message"`
+
+	MediaURL     string `json:"media_url"`
+	SignedURL    string `json:"signed_url"`
+	OriginalName string `json:"original_name"`
+	Mime         string `json:"mime"`
+	Size         int64  `json:"size"`
+}
+
+// POST /rooms/upload-file/{roomID}
+// multipart/form-data: file=<pdf|zip|doc|video|...>, content=<caption, optional>, reply_to_message_id=<optional>
+// lưu file + tạo message (image nếu là ảnh, còn lại file) kèm 1 attachment
+func (s *Server) handleUploadRoomFile(w http.ResponseWriter, r *http.Request) {
+	userID, roomID, ok := s.authorizeRoomUpload(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, chatFileMaxBytes+64<<10)
+	if err := r.ParseMultipartForm(chatUploadMaxBytes); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeChatUploadError(w, errUploadTooLarge)
+			return
+		}
+		http.Error(w, "cannot parse form", http.StatusBadRequest)
+		return
+	}
+	defer func() { _ = r.MultipartForm.RemoveAll() }()
+
+	replyTo, ok := formReplyTo(w, r)
+	if !ok {
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "missing file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		http.Error(w, "file read error", http.StatusBadRequest)
+		return
+	}
+
+	mime, ext, limit, err := resolveChatFileType(head[:n], header.Header.Get("Content-Type"), header.Filename)
+	if err != nil {
+		writeChatUploadError(w, err)
+		return
+	}
+	if header.Size > limit {
+		http.Error(w, fmt.Sprintf("%s (max %d MB)", errUploadTooLarge, limit>>20), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	up, err := s.storeChatUploadLimit(roomID, userID, mime, ext, header.Filename, file, limit)
+	if err != nil {
+		if !errors.Is(err, errUploadTooLarge) {
+			log.Println("storeChatUpload error:", err)
+		}
+		writeChatUploadError(w, err)
+		return
+	}
+	defer s.releaseChatUpload(up)
+
+	ctx := r.Context()
+
+	att := chat.Attachment{
+		FileName:    up.OriginalName,
+		FileSize:    up.Size,
+		ContentType: up.Mime,
+		FilePath:    up.MediaURL,
+		CreatedAt:   s.now().UTC(),
+	}
+	up.applyMeta(&att)
+	if isAudioExt(ext) {
+		s.fillAudioWaveform(ctx, &att, up.localPath)
+	}
+
+	msgType := "file"
+	if isAllowedImageMime(up.Mime) {
+		msgType = "image"
+	}
+	// không có caption -> content = media_url (client cũ vẫn render được)
+	content := strings.TrimSpace(r.FormValue("content"))
+	if content == "" {
+		content = up.MediaURL
+	}
+
+	resp, ok := s.createUploadMessage(w, r, &chat.Message{
+		RoomID:           roomID,
+		SenderID:         userID,
+		Content:          content,
+		MessageType:      msgType,
+		ReplyToMessageID: replyTo,
+	}, []chat.Attachment{att}, []string{up.Filename})
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, uploadFileResponse{
+		Message:      resp,
+		MediaURL:     up.MediaURL,
+		SignedURL:    s.signMediaURL(up.MediaURL),
+		OriginalName: up.OriginalName,
+		Mime:         up.Mime,
+		Size:         up.Size,
+	})
+
+	s.broadcastMessageCreated(ctx, roomID, userID, resp)
+
+	// video -> transcode như message file thường
+	s.maybeEnqueueTranscode(resp.ID, roomID, msgType, up.MediaURL)
+}
@@ -0,0 +1,153 @@
+package httpserver
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// uploadRequest: multipart với 1 ảnh png ở field fileField + các field text
+func uploadRequest(t *testing.T, path, token, fileField string, fields map[string]string) *http.Request {
+	t.Helper()
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatalf("png: %v", err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile(fileField, "photo.png")
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	_, _ = fw.Write(img.Bytes())
+	for k, v := range fields {
+		_ = mw.WriteField(k, v)
+	}
+	_ = mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+// các endpoint upload tạo message: đi qua createUploadMessage
+var uploadMessageEndpoints = []struct {
+	name, path, fileField string
+}{
+	{"upload-file", "/rooms/upload-file/10", "file"},
+	{"upload-files", "/rooms/upload-files/10", "files"},
+	{"upload-image send", "/rooms/upload-image/10?send=true", "file"},
+}
+
+func TestUploadMessageSuspendedSender(t *testing.T) {
+	for _, ep := range uploadMessageEndpoints {
+		t.Run(ep.name, func(t *testing.T) {
+			s, mock := newTestServer(t)
+			expectMember(mock, 10, 1, true)
+			expectSuspended(mock, 1, time.Now().Add(time.Hour))
+
+			req := uploadRequest(t, ep.path, accessTokenFor(t, 1), ep.fileField, map[string]string{"content": "caption"})
+			rec := serveRequest(s, req)
+			if rec.Code != http.StatusForbidden {
+				t.Fatalf("status = %d, want 403 (body %s)", rec.Code, rec.Body.String())
+			}
+			checkExpectations(t, mock)
+
+			// file đã lưu bị dọn
+			if left, _ := os.ReadDir(s.chatUploadDir); len(left) != 0 {
+				t.Fatalf("upload dir not cleaned: %d entries", len(left))
+			}
+		})
+	}
+}
+
+func TestUploadMessageQuota(t *testing.T) {
+	for _, ep := range uploadMessageEndpoints {
+		t.Run(ep.name, func(t *testing.T) {
+			s, mock := newTestServer(t)
+			expectMember(mock, 10, 1, true)
+			mock.ExpectQuery(`FROM user_quota_overrides WHERE user_id = \?`).WithArgs(int64(1)).
+				WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+			mock.ExpectQuery(`SELECT COUNT\(\*\), MIN\(created_at\) FROM messages`).
+				WillReturnRows(sqlmock.NewRows([]string{"n", "oldest"}).AddRow(30, testNow.Add(-30*time.Second)))
+
+			req := uploadRequest(t, ep.path, accessTokenFor(t, 1), ep.fileField, map[string]string{"content": "caption"})
+			rec := serveRequest(s, req)
+			if rec.Code != http.StatusTooManyRequests {
+				t.Fatalf("status = %d, want 429 (body %s)", rec.Code, rec.Body.String())
+			}
+			if got := rec.Header().Get("Retry-After"); got != "30" {
+				t.Fatalf("Retry-After = %q, want 30", got)
+			}
+			checkExpectations(t, mock)
+		})
+	}
+}
+
+func TestUploadMessageWordFilter(t *testing.T) {
+	filterRows := func(pattern, mode string) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "room_id", "pattern", "mode", "created_by", "created_at"}).
+			AddRow(1, 0, pattern, mode, 1, testNow)
+	}
+
+	for _, ep := range uploadMessageEndpoints {
+		t.Run(ep.name, func(t *testing.T) {
+			s, mock := newTestServer(t)
+			expectMember(mock, 10, 1, true)
+			mock.ExpectQuery(`FROM word_filters\s+WHERE room_id = \?`).WithArgs(int64(0)).
+				WillReturnRows(filterRows("badword", "reject"))
+			mock.ExpectQuery(`FROM word_filters\s+WHERE room_id = \?`).WithArgs(int64(10)).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "room_id", "pattern", "mode", "created_by", "created_at"}))
+
+			req := uploadRequest(t, ep.path, accessTokenFor(t, 1), ep.fileField, map[string]string{"content": "a badword caption"})
+			rec := serveRequest(s, req)
+			if rec.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("status = %d, want 422 (body %s)", rec.Code, rec.Body.String())
+			}
+			checkExpectations(t, mock)
+			if left, _ := os.ReadDir(s.chatUploadDir); len(left) != 0 {
+				t.Fatalf("upload dir not cleaned: %d entries", len(left))
+			}
+		})
+	}
+}
+
+// upload-image?send=true: caption bị mask trước khi lưu, giống send-messages
+func TestUploadImageSendMasksCaption(t *testing.T) {
+	s, mock := newTestServer(t)
+	expectMember(mock, 10, 1, true)
+	mock.ExpectQuery(`FROM word_filters\s+WHERE room_id = \?`).WithArgs(int64(0)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "room_id", "pattern", "mode", "created_by", "created_at"}).
+			AddRow(1, 0, "badword", "mask", 1, testNow))
+	mock.ExpectQuery(`FROM word_filters\s+WHERE room_id = \?`).WithArgs(int64(10)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "room_id", "pattern", "mode", "created_by", "created_at"}))
+	mock.ExpectBegin()
+	args := anyArgs(19)
+	args[0], args[1], args[7], args[8] = int64(10), int64(1), "a b****** caption", "image"
+	mock.ExpectExec(`INSERT INTO messages`).WithArgs(args...).WillReturnResult(sqlmock.NewResult(100, 1))
+	mock.ExpectExec(`INSERT INTO attachments`).WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectCommit()
+
+	req := uploadRequest(t, "/rooms/upload-image/10?send=true", accessTokenFor(t, 1), "file",
+		map[string]string{"content": "a badword caption"})
+	rec := serveRequest(s, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+	}
+	checkExpectations(t, mock)
+
+	msg, _ := decodeBody(t, rec)["message"].(map[string]any)
+	if msg["content"] != "a b****** caption" {
+		t.Fatalf("message content = %v", msg["content"])
+	}
+}
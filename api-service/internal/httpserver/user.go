@@ -1,7 +1,11 @@
 package httpserver
 
 import (
+	"bytes"
+	"cronhustler/api-service/internal/media"
+	"cronhustler/api-service/internal/storage"
 	"cronhustler/api-service/internal/user" // dùng model User của m, KHÔNG phải os/user
+	"cronhustler/api-service/internal/webhook"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -10,7 +14,6 @@ import (
 	"log"
 	"net"
 	"net/http"
-	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
@@ -33,7 +36,6 @@ type createUserResponse struct {
 	Username string `json:"username,omitempty"`
 	Role     string `json:"role,omitempty"`
 	Message  string `json:"message,omitempty"` // 👈 thêm field này
-	Error    string `json:"error,omitempty"`
 }
 
 type UserInfoResponse struct {
@@ -51,12 +53,10 @@ type UserInfoResponse struct {
 
 type getAllUserResponse struct {
 	Users []UserInfoResponse `json:"users"`
-	Error string             `json:"error,omitempty"`
 }
 
 type getAllUserForListingResponse struct {
 	Users []UserInfoResponse `json:"users"`
-	Error string             `json:"error,omitempty"`
 }
 
 type updateUserRequest struct {
@@ -69,8 +69,7 @@ type updateUserRequest struct {
 }
 
 type updateUserResponse struct {
-	Success bool   `json:"success,omitempty"`
-	Error   string `json:"error,omitempty"`
+	Success bool `json:"success,omitempty"`
 }
 
 func (s *Server) mountUserRoutes(mux *http.ServeMux) {
@@ -135,43 +134,15 @@ func getIP(r *http.Request) string {
 	return r.RemoteAddr
 }
 
-// Trả về userID (int64) hoặc lỗi
-func GetUserIDFromRequest(r *http.Request, secret []byte) (int64, error) {
-	// Lấy Authorization header
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		return 0, errors.New("missing Authorization header")
-	}
-
-	// Expect: "Bearer <token>"
-	parts := strings.SplitN(authHeader, " ", 2)
-	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
-		return 0, errors.New("invalid Authorization header format")
-	}
-
-	tokenStr := parts[1]
-
-	// Parse token
-	claims, err := ParseToken(tokenStr, secret)
-	if err != nil {
-		return 0, errors.New("invalid or expired token")
-	}
-
-	return int64(claims.UserID), nil
-}
-
 func (s *Server) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	var req createUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, createUserResponse{
-			Error:   "INVALID_JSON",
-			Message: "Invalid JSON body",
-		})
+		writeError(w, http.StatusBadRequest, CodeInvalidJSON, "Invalid JSON body")
 		return
 	}
 
@@ -183,51 +154,45 @@ func (s *Server) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 	req.Phone = strings.TrimSpace(req.Phone)
 
 	if req.Username == "" || req.Password == "" || req.Role == "" {
-		writeJSON(w, http.StatusBadRequest, createUserResponse{
-			Error:   "MISSING_FIELDS",
-			Message: "Missing required fields",
-		})
+		writeError(w, http.StatusBadRequest, CodeMissingFields, "Missing required fields")
 		return
 	}
 
 	// Check role hợp lệ
 	if req.Role != "admin" && req.Role != "user" {
-		writeJSON(w, http.StatusBadRequest, createUserResponse{
-			Error:   "INVALID_ROLE",
-			Message: "Role must be admin or user",
-		})
+		writeError(w, http.StatusBadRequest, CodeInvalidRole, "Role must be admin or user")
 		return
 	}
 
 	// 👇 NEW: Validate password length
 	if len(req.Password) < 8 {
-		writeJSON(w, http.StatusBadRequest, createUserResponse{
-			Error:   "WEAK_PASSWORD",
-			Message: "Password must be at least 8 characters",
-		})
+		writeError(w, http.StatusBadRequest, CodeWeakPassword, "Password must be at least 8 characters")
+		return
+	}
+	if len(req.Password) > maxPasswordBytes {
+		writeError(w, http.StatusBadRequest, CodeWeakPassword, errPasswordTooLong.Error())
 		return
 	}
 
 	// Validate email format
 	if req.Email == "" || !isValidEmail(req.Email) {
-		writeJSON(w, http.StatusBadRequest, createUserResponse{
-			Error:   "INVALID_EMAIL",
-			Message: "Invalid email format",
-		})
+		writeError(w, http.StatusBadRequest, CodeInvalidEmail, "Invalid email format")
 		return
 	}
 
 	// Validate phone format
 	if req.Phone == "" || !isValidPhone(req.Phone) {
-		writeJSON(w, http.StatusBadRequest, createUserResponse{
-			Error:   "INVALID_PHONE",
-			Message: "Invalid phone number",
-		})
+		writeError(w, http.StatusBadRequest, CodeInvalidPhone, "Invalid phone number")
 		return
 	}
 
 	// Hash password
-	hashed := hashPassword(req.Password)
+	hashed, err := s.passwordHash(req.Password)
+	if err != nil {
+		log.Println("hash password error:", err)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "cannot hash password")
+		return
+	}
 
 	// Lấy IP từ request
 	ip := getIP(r)
@@ -251,20 +216,16 @@ func (s *Server) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		// SQLite duplicate username thường trả lỗi chứa "UNIQUE"
 		if strings.Contains(err.Error(), "UNIQUE") {
-			writeJSON(w, http.StatusConflict, createUserResponse{
-				Error:   "USERNAME_EXISTS",
-				Message: "Username already exists",
-			})
+			writeError(w, http.StatusConflict, CodeUsernameExists, "Username already exists")
 			return
 		}
 
 		log.Println("create user error:", err)
-		writeJSON(w, http.StatusInternalServerError, createUserResponse{
-			Error:   "DB_ERROR",
-			Message: "Database error",
-		})
+		writeError(w, http.StatusInternalServerError, CodeDBError, "Database error")
 		return
 	}
+	s.emitUserEvent(webhook.EventUserCreated, id, map[string]any{"source": "signup"})
+
 	// Trả về
 	writeJSON(w, http.StatusOK, createUserResponse{
 		ID:       id,
@@ -276,16 +237,14 @@ func (s *Server) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleGetUserInfo(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	// Lấy userID từ token
-	userID, err := GetUserIDFromRequest(r, s.jwtSecret)
+	userID, err := UserIDFromContext(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{
-			"error": err.Error(),
-		})
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
@@ -293,45 +252,42 @@ func (s *Server) handleGetUserInfo(w http.ResponseWriter, r *http.Request) {
 	u, err := s.userRepo.GetUserByID(int(userID))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			writeJSON(w, http.StatusNotFound, map[string]string{
-				"error": "user not found",
-			})
+			writeError(w, http.StatusNotFound, CodeNotFound, "user not found")
 			return
 		}
 		log.Printf("GetUserByID error: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{
-			"error": "db error",
-		})
+		writeError(w, http.StatusInternalServerError, CodeDBError, "db error")
 		return
 	}
 
-	resp := UserInfoResponse{
+	writeJSON(w, http.StatusOK, s.userInfoResponse(u))
+}
+
+// userInfoResponse: profile của chính user (/me, /bootstrap), avatar đã ký
+func (s *Server) userInfoResponse(u *user.User) UserInfoResponse {
+	return UserInfoResponse{
 		ID:        int64(u.ID),
 		Username:  u.Username,
 		Role:      u.Role,
 		FullName:  nsToString(u.Full_name),
 		Email:     nsToString(u.Email),
 		Phone:     nsToString(u.Phone),
-		AvatarURL: nsToString(u.AvatarURL),
+		AvatarURL: s.signMediaURL(nsToString(u.AvatarURL)),
 		LastLogin: nsToString(u.Last_login),
 		LoginIP:   nsToString(u.Login_ip),
 		CreatedIP: nsToString(u.Created_ip),
 	}
-
-	writeJSON(w, http.StatusOK, resp)
 }
 
 func (s *Server) handleGetAllUser(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	users, err := s.userRepo.GetAllUsers()
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, getAllUserResponse{
-			Error: "db error",
-		})
+		writeError(w, http.StatusInternalServerError, CodeDBError, "db error")
 		return
 	}
 
@@ -345,7 +301,7 @@ func (s *Server) handleGetAllUser(w http.ResponseWriter, r *http.Request) {
 			FullName:  nsToString(u.Full_name),
 			Email:     nsToString(u.Email),
 			Phone:     nsToString(u.Phone),
-			AvatarURL: nsToString(u.AvatarURL),
+			AvatarURL: s.signMediaURL(nsToString(u.AvatarURL)),
 			LastLogin: nsToString(u.Last_login),
 			LoginIP:   nsToString(u.Login_ip),
 			CreatedIP: nsToString(u.Created_ip),
@@ -359,25 +315,21 @@ func (s *Server) handleGetAllUser(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleGetAllUserForListing(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	// 1. Query DB lấy danh sách users
 	users, err := s.userRepo.GetAllUsersForListing()
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, getAllUserResponse{
-			Error: "db error",
-		})
+		writeError(w, http.StatusInternalServerError, CodeDBError, "db error")
 		return
 	}
 
 	// 2. Lấy userID từ token
-	userID, err := GetUserIDFromRequest(r, s.jwtSecret)
+	userID, err := UserIDFromContext(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{
-			"error": err.Error(),
-		})
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
@@ -391,9 +343,7 @@ func (s *Server) handleGetAllUserForListing(w http.ResponseWriter, r *http.Reque
 	}
 
 	if !isValidUser {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{
-			"error": "invalid user or user not exist",
-		})
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "invalid user or user not exist")
 		return
 	}
 
@@ -404,7 +354,7 @@ func (s *Server) handleGetAllUserForListing(w http.ResponseWriter, r *http.Reque
 			ID:        int64(u.ID),
 			Username:  u.Username,
 			FullName:  nsToString(u.Full_name),
-			AvatarURL: nsToString(u.AvatarURL),
+			AvatarURL: s.signMediaURL(nsToString(u.AvatarURL)),
 		})
 	}
 
@@ -419,7 +369,10 @@ func (s *Server) applyUserUpdate(id int64, req updateUserRequest) error {
 
 	// nếu gửi password -> hash và update
 	if req.Password != nil {
-		hashed := hashPassword(*req.Password)
+		hashed, err := s.passwordHash(*req.Password)
+		if err != nil {
+			return err
+		}
 		fields["password"] = hashed
 	}
 
@@ -448,43 +401,74 @@ func (s *Server) applyUserUpdate(id int64, req updateUserRequest) error {
 
 func (s *Server) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut {
-		writeJSON(w, http.StatusMethodNotAllowed, updateUserResponse{Error: "method not allowed"})
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	// Lấy userID từ token
-	id, err := GetUserIDFromRequest(r, s.jwtSecret)
+	id, err := UserIDFromContext(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{
-			"error": err.Error(),
-		})
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
 	// parse JSON body
 	var req updateUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, updateUserResponse{Error: "invalid JSON"})
+		writeError(w, http.StatusBadRequest, CodeInvalidJSON, "invalid JSON")
 		return
 	}
 
 	// gọi hàm chung
 	if err := s.applyUserUpdate(id, req); err != nil {
 		if err.Error() == "no fields to update" {
-			writeJSON(w, http.StatusBadRequest, updateUserResponse{Error: err.Error()})
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+			return
+		}
+		if errors.Is(err, errPasswordTooLong) {
+			writeError(w, http.StatusBadRequest, CodeWeakPassword, err.Error())
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, updateUserResponse{Error: err.Error()})
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
+	// đổi password không bắn event (không lộ ra hệ thống ngoài)
+	if changed := updatedProfileFields(req); len(changed) > 0 {
+		event := webhook.EventUserUpdated
+		if req.IsActive != nil && *req.IsActive == 0 {
+			event = webhook.EventUserDeactivated
+		}
+		s.emitUserEvent(event, id, map[string]any{"source": "self", "changed": changed})
+	}
+
 	writeJSON(w, http.StatusOK, updateUserResponse{Success: true})
 }
 
+func updatedProfileFields(req updateUserRequest) []string {
+	var out []string
+	if req.FullName != nil {
+		out = append(out, "full_name")
+	}
+	if req.Email != nil {
+		out = append(out, "email")
+	}
+	if req.Phone != nil {
+		out = append(out, "phone")
+	}
+	if req.AvatarURL != nil {
+		out = append(out, "avatar_url")
+	}
+	if req.IsActive != nil {
+		out = append(out, "is_active")
+	}
+	return out
+}
+
 // handleSearchUsers: search theo username / full_name, dùng cho gợi ý real-time
 func (s *Server) handleSearchUsers(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
@@ -507,11 +491,9 @@ func (s *Server) handleSearchUsers(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// BẮT BUỘC login (có token) mới được search
-	_, err := GetUserIDFromRequest(r, s.jwtSecret)
+	_, err := UserIDFromContext(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{
-			"error": err.Error(),
-		})
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
@@ -519,9 +501,7 @@ func (s *Server) handleSearchUsers(w http.ResponseWriter, r *http.Request) {
 	users, err := s.userRepo.SearchUsers(q, limit)
 	if err != nil {
 		log.Printf("SearchUsers error: %v", err)
-		writeJSON(w, http.StatusInternalServerError, getAllUserResponse{
-			Error: "db error",
-		})
+		writeError(w, http.StatusInternalServerError, CodeDBError, "db error")
 		return
 	}
 
@@ -532,7 +512,7 @@ func (s *Server) handleSearchUsers(w http.ResponseWriter, r *http.Request) {
 			ID:        int64(u.ID),
 			Username:  u.Username,
 			FullName:  nsToString(u.Full_name),
-			AvatarURL: nsToString(u.AvatarURL),
+			AvatarURL: s.signMediaURL(nsToString(u.AvatarURL)),
 			// các field khác để trống
 		})
 	}
@@ -544,38 +524,30 @@ func (s *Server) handleSearchUsers(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleUploadAvatar(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	// Lấy userID từ token
-	userID, err := GetUserIDFromRequest(r, s.jwtSecret)
+	userID, err := UserIDFromContext(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{
-			"error": err.Error(),
-		})
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
 	// 📦 Parse multipart form (max 10MB)
 	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		http.Error(w, "cannot parse form", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "cannot parse form")
 		return
 	}
 
 	file, header, err := r.FormFile("file")
 	if err != nil {
-		http.Error(w, "missing file", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "missing file")
 		return
 	}
 	defer file.Close()
 
-	// đảm bảo avatarDir tồn tại (phòng khi vì lý do gì bị xóa)
-	if err := os.MkdirAll(s.avatarDir, 0o755); err != nil {
-		http.Error(w, "cannot create avatar dir", http.StatusInternalServerError)
-		return
-	}
-
 	// 🧾 Tên file: u<id>_<timestamp>.ext
 	ext := strings.ToLower(filepath.Ext(header.Filename))
 	if ext == "" {
@@ -583,29 +555,36 @@ func (s *Server) handleUploadAvatar(w http.ResponseWriter, r *http.Request) {
 	}
 	filename := fmt.Sprintf("u%d_%d%s", userID, time.Now().UnixNano(), ext)
 
-	// full path trên ổ đĩa (đã được mount bằng volume)
-	fullPath := filepath.Join(s.avatarDir, filename)
-
-	out, err := os.Create(fullPath)
-	if err != nil {
-		http.Error(w, "cannot save file", http.StatusInternalServerError)
-		return
+	// 🧹 Bỏ EXIF (GPS...) + xoay theo orientation trước khi lưu
+	var body io.ReadSeeker = file
+	if !s.keepImageMeta {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "cannot read file")
+			return
+		}
+		if clean, err := media.StripImageMetadataBytes(data); err != nil {
+			log.Printf("[exif] avatar %s: %v", filename, err)
+		} else if clean != nil {
+			data = clean
+		}
+		body = bytes.NewReader(data)
 	}
-	defer out.Close()
 
-	if _, err := io.Copy(out, file); err != nil {
-		http.Error(w, "save file error", http.StatusInternalServerError)
+	// 💾 Lưu qua storage (local disk hoặc S3/MinIO)
+	if err := s.store.Save(r.Context(), storage.AvatarKeyPrefix+filename, body, mediaMimeFromExt(ext)); err != nil {
+		log.Println("save avatar error:", err)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "cannot save file")
 		return
 	}
 
 	// 🌐 URL để FE load
-	// Giả sử bên Server mount static như:
-	//   /static/user_avatars/ -> http.Dir(s.avatarDir)
+	// /static/user_avatars/ -> handleAvatarMedia (local file hoặc redirect presigned URL)
 	avatarURL := "/static/user_avatars/" + filename
 
 	// 💾 Update DB
 	if err := s.userRepo.UpdateAvatar(int(userID), avatarURL); err != nil {
-		http.Error(w, "db update failed", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "db update failed")
 		return
 	}
 
@@ -614,6 +593,7 @@ func (s *Server) handleUploadAvatar(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(map[string]interface{}{
 		"success":    true,
 		"avatar_url": avatarURL,
+		"signed_url": s.signMediaURL(avatarURL),
 	})
 }
 
@@ -624,23 +604,21 @@ type changePasswordRequest struct {
 
 func (s *Server) handleChangePassword(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut {
-		writeJSON(w, http.StatusMethodNotAllowed, updateUserResponse{Error: "method not allowed"})
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	// Lấy userID từ token
-	id, err := GetUserIDFromRequest(r, s.jwtSecret)
+	id, err := UserIDFromContext(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{
-			"error": err.Error(),
-		})
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
 	// parse body
 	var req changePasswordRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, updateUserResponse{Error: "invalid JSON"})
+		writeError(w, http.StatusBadRequest, CodeInvalidJSON, "invalid JSON")
 		return
 	}
 
@@ -648,7 +626,7 @@ func (s *Server) handleChangePassword(w http.ResponseWriter, r *http.Request) {
 	req.NewPassword = strings.TrimSpace(req.NewPassword)
 
 	if req.CurrentPassword == "" || req.NewPassword == "" {
-		writeJSON(w, http.StatusBadRequest, updateUserResponse{Error: "current_password and new_password are required"})
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "current_password and new_password are required")
 		return
 	}
 
@@ -656,13 +634,13 @@ func (s *Server) handleChangePassword(w http.ResponseWriter, r *http.Request) {
 	u, err := s.userRepo.GetUserByID(int(id))
 	if err != nil {
 		// tùy repo của bro trả gì, tạm cho 404 / 500
-		writeJSON(w, http.StatusInternalServerError, updateUserResponse{Error: "user not found"})
+		writeError(w, http.StatusInternalServerError, CodeInternal, "user not found")
 		return
 	}
 
 	// verify mật khẩu cũ
-	if hashPassword(req.CurrentPassword) != u.Password {
-		writeJSON(w, http.StatusBadRequest, updateUserResponse{Error: "current password is incorrect"})
+	if ok, _ := s.checkPassword(u.Password, req.CurrentPassword); !ok {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "current password is incorrect")
 		return
 	}
 
@@ -674,7 +652,11 @@ func (s *Server) handleChangePassword(w http.ResponseWriter, r *http.Request) {
 
 	// gọi lại logic chung giống handleUpdateUser
 	if err := s.applyUserUpdate(id, updateReq); err != nil {
-		writeJSON(w, http.StatusInternalServerError, updateUserResponse{Error: err.Error()})
+		if errors.Is(err, errPasswordTooLong) {
+			writeError(w, http.StatusBadRequest, CodeWeakPassword, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
@@ -0,0 +1,121 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/media"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func isAllowedVideoMime(m string) bool {
+	switch strings.ToLower(m) {
+	case "video/mp4", "video/webm", "video/quicktime":
+		return true
+	default:
+		return false
+	}
+}
+
+func isVideoExt(ext string) bool {
+	switch strings.ToLower(ext) {
+	case ".mp4", ".webm", ".mov":
+		return true
+	default:
+		return false
+	}
+}
+
+// EnableVideoTranscoding: bật worker ffmpeg (ffmpegPath rỗng -> tìm trong PATH)
+// trả false nếu không tìm thấy ffmpeg
+func (s *Server) EnableVideoTranscoding(ctx context.Context, ffmpegPath string) bool {
+	t := media.NewTranscoder(ffmpegPath, s.onTranscodeDone)
+	if t == nil {
+		return false
+	}
+	t.Start(ctx)
+	s.transcoder = t
+	return true
+}
+
+// maybeEnqueueTranscode: message file trỏ tới video trong chat uploads -> đẩy vào queue
+func (s *Server) maybeEnqueueTranscode(messageID, roomID int64, messageType, content string) {
+	if s.transcoder == nil || messageType != "file" {
+		return
+	}
+	if !strings.HasPrefix(content, chatUploadPrefix) {
+		return
+	}
+
+	name := strings.TrimPrefix(content, chatUploadPrefix)
+	if i := strings.IndexByte(name, '?'); i >= 0 {
+		name = name[:i]
+	}
+	if name == "" || name != filepath.Base(name) || !isVideoExt(filepath.Ext(name)) {
+		return
+	}
+
+	// ffmpeg cần file local, object store thì giữ video gốc
+	srcPath := s.store.LocalPath(chatUploadKey(name))
+	if srcPath == "" {
+		return
+	}
+
+	err := s.transcoder.Enqueue(media.TranscodeJob{
+		MessageID: messageID,
+		RoomID:    roomID,
+		SrcPath:   srcPath,
+	})
+	if err != nil {
+		log.Printf("[transcode] enqueue message=%d: %v", messageID, err)
+	}
+}
+
+// onTranscodeDone: update media fields + báo cho room biết video đã sẵn sàng
+func (s *Server) onTranscodeDone(res media.TranscodeResult) {
+	job := res.Job
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	memberIDs, err := s.roomRepo.GetRoomMemberIDs(job.RoomID)
+	if err != nil {
+		log.Println("GetRoomMemberIDs error:", err)
+		return
+	}
+
+	if res.Err != nil {
+		wsSendToUsers(memberIDs, wsEnvelope{
+			Type:   "message_media_failed",
+			RoomID: job.RoomID,
+			Data: map[string]any{
+				"message_id": job.MessageID,
+			},
+		})
+		return
+	}
+
+	mediaURL := chatUploadPrefix + res.OutputName
+	posterURL := ""
+	if res.PosterName != "" {
+		posterURL = chatUploadPrefix + res.PosterName
+	}
+
+	if err := s.chatRepo.UpdateMessageMedia(ctx, job.MessageID, mediaURL, "video/mp4", res.OutputSize, posterURL); err != nil {
+		log.Println("UpdateMessageMedia error:", err)
+		return
+	}
+
+	wsSendToUsers(memberIDs, wsEnvelope{
+		Type:   "message_media_ready",
+		RoomID: job.RoomID,
+		Data: map[string]any{
+			"message_id":       job.MessageID,
+			"media_url":        s.signMediaURL(mediaURL),
+			"media_mime":       "video/mp4",
+			"media_size":       res.OutputSize,
+			"media_poster_url": s.signMediaURL(posterURL),
+		},
+	})
+}
@@ -0,0 +1,473 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/chat"
+	"cronhustler/api-service/internal/webhook"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	roomWebhooksPrefix         = "/rooms/webhooks/"
+	roomIncomingWebhooksPrefix = "/rooms/incoming-webhooks/"
+	incomingWebhookPrefix      = "/webhooks/"
+	hooksPrefix                = "/hooks/" // URL ngắn, trỏ cùng handler với /webhooks/
+)
+
+// giới hạn nội dung tin từ incoming webhook (rune)
+const incomingWebhookMaxContent = 4000
+
+func (s *Server) mountWebhookRoutes(mux *http.ServeMux) {
+	// GET  /rooms/webhooks/{roomID}                          -> list
+	// POST /rooms/webhooks/{roomID}  {url, secret?, events?}  -> tạo (chỉ owner)
+	// DELETE /rooms/webhooks/{roomID}/{webhookID}
+	// GET  /rooms/webhooks/{roomID}/{webhookID}/deliveries   -> log giao
+	mux.Handle(roomWebhooksPrefix, http.HandlerFunc(s.handleRoomWebhooks))
+
+	// GET  /rooms/incoming-webhooks/{roomID}
+	// POST /rooms/incoming-webhooks/{roomID}  {name, avatar_url?} -> trả token 1 lần
+	// DELETE /rooms/incoming-webhooks/{roomID}/{id}
+	mux.Handle(roomIncomingWebhooksPrefix, http.HandlerFunc(s.handleRoomIncomingWebhooks))
+	// POST /hooks/{token} (hoặc /webhooks/{token})  {content, username?, avatar_url?} hoặc payload kiểu Slack
+	// (không cần login, token là credential)
+	mux.Handle(incomingWebhookPrefix, http.HandlerFunc(s.handleIncomingWebhook))
+	mux.Handle(hooksPrefix, http.HandlerFunc(s.handleIncomingWebhook))
+}
+
+// StartWebhookDispatcher: chạy worker giao webhook (event vẫn được lưu khi chưa start)
+func (s *Server) StartWebhookDispatcher(ctx context.Context) {
+	s.webhooks.Start(ctx)
+}
+
+type createWebhookRequest struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"` // rỗng -> server tự sinh
+	Events []string `json:"events"` // rỗng -> tất cả
+}
+
+func (s *Server) handleRoomWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, roomWebhooksPrefix), "/"), "/")
+	roomID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || roomID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid room id"})
+		return
+	}
+
+	if !s.requireRoomOwner(w, roomID, userID) {
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		s.listRoomWebhooks(w, r, roomID)
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		s.createRoomWebhook(w, r, roomID, userID)
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		s.deleteRoomWebhook(w, r, roomID, parts[1])
+	case len(parts) == 3 && parts[2] == "deliveries" && r.Method == http.MethodGet:
+		s.listWebhookDeliveries(w, r, roomID, parts[1])
+	case len(parts) <= 3:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
+
+// requireRoomOwner: chỉ owner của room được quản lý webhook (tự ghi response lỗi)
+func (s *Server) requireRoomOwner(w http.ResponseWriter, roomID, userID int64) bool {
+	ownerID, err := s.roomRepo.GetRoomOwner(roomID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "room not found"})
+			return false
+		}
+		log.Println("GetRoomOwner error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return false
+	}
+	if ownerID != userID {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "only owner can manage webhooks"})
+		return false
+	}
+	return true
+}
+
+func (s *Server) listRoomWebhooks(w http.ResponseWriter, r *http.Request, roomID int64) {
+	list, err := s.webhookRepo.ListByRoom(r.Context(), roomID)
+	if err != nil {
+		log.Println("ListByRoom webhooks error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"webhooks": list})
+}
+
+func (s *Server) createRoomWebhook(w http.ResponseWriter, r *http.Request, roomID, userID int64) {
+	var req createWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+
+	hook := &webhook.Webhook{
+		RoomID:    roomID,
+		URL:       strings.TrimSpace(req.URL),
+		Secret:    req.Secret,
+		Events:    req.Events,
+		CreatedBy: userID,
+	}
+	if err := s.webhookRepo.Create(r.Context(), hook); err != nil {
+		if errors.Is(err, webhook.ErrInvalidURL) || errors.Is(err, webhook.ErrBlockedURL) || errors.Is(err, webhook.ErrInvalidEvents) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		log.Println("Create webhook error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	// secret chỉ trả 1 lần lúc tạo
+	writeJSON(w, http.StatusCreated, hook)
+}
+
+func (s *Server) deleteRoomWebhook(w http.ResponseWriter, r *http.Request, roomID int64, rawID string) {
+	webhookID, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil || webhookID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid webhook id"})
+		return
+	}
+
+	if err := s.webhookRepo.Delete(r.Context(), roomID, webhookID); err != nil {
+		if errors.Is(err, webhook.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "webhook not found"})
+			return
+		}
+		log.Println("Delete webhook error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+func (s *Server) listWebhookDeliveries(w http.ResponseWriter, r *http.Request, roomID int64, rawID string) {
+	webhookID, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil || webhookID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid webhook id"})
+		return
+	}
+
+	ok, err := s.webhookRepo.BelongsToRoom(r.Context(), roomID, webhookID)
+	if err != nil {
+		log.Println("BelongsToRoom error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "webhook not found"})
+		return
+	}
+
+	limit := 50
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 && v <= 200 {
+		limit = v
+	}
+
+	list, err := s.webhookRepo.ListDeliveries(r.Context(), webhookID, limit)
+	if err != nil {
+		log.Println("ListDeliveries error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"deliveries": list})
+}
+
+// emitWebhook: lưu event cho webhook + bot của room, không block request
+func (s *Server) emitWebhook(roomID, actorID int64, event string, data any) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.webhooks.Emit(ctx, roomID, actorID, event, data); err != nil {
+			log.Printf("[webhook] emit %s room=%d: %v", event, roomID, err)
+		}
+	}()
+}
+
+// ===== Incoming webhooks =====
+
+type createIncomingWebhookRequest struct {
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+func (s *Server) handleRoomIncomingWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, roomIncomingWebhooksPrefix), "/"), "/")
+	roomID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || roomID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid room id"})
+		return
+	}
+
+	if !s.requireRoomOwner(w, roomID, userID) {
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		list, err := s.webhookRepo.ListIncoming(r.Context(), roomID)
+		if err != nil {
+			log.Println("ListIncoming error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		for i := range list {
+			list[i].AvatarURL = s.signMediaURL(list[i].AvatarURL)
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"webhooks": list})
+
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		var req createIncomingWebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+			return
+		}
+
+		hook := &webhook.IncomingWebhook{
+			RoomID:    roomID,
+			Name:      req.Name,
+			AvatarURL: strings.TrimSpace(req.AvatarURL),
+			CreatedBy: userID,
+		}
+		if err := s.webhookRepo.CreateIncoming(r.Context(), hook); err != nil {
+			if errors.Is(err, webhook.ErrInvalidName) || errors.Is(err, webhook.ErrInvalidURL) || errors.Is(err, webhook.ErrBlockedURL) {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			log.Println("CreateIncoming error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+
+		// token chỉ trả 1 lần lúc tạo
+		writeJSON(w, http.StatusCreated, map[string]any{
+			"webhook": hook,
+			"url":     hooksPrefix + hook.Token,
+		})
+
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		id, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || id <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid webhook id"})
+			return
+		}
+		if err := s.webhookRepo.DeleteIncoming(r.Context(), roomID, id); err != nil {
+			if errors.Is(err, webhook.ErrNotFound) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "webhook not found"})
+				return
+			}
+			log.Println("DeleteIncoming error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+
+	case len(parts) <= 2:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
+
+type incomingWebhookRequest struct {
+	Content string `json:"content"`
+
+	// ghi đè tên / avatar cho riêng tin này (Slack: username + icon_url)
+	Username  string `json:"username"`
+	AvatarURL string `json:"avatar_url"`
+	IconURL   string `json:"icon_url"`
+}
+
+// incomingWebhookBody: body đã parse của POST /hooks/{token}
+type incomingWebhookBody struct {
+	Content     string
+	Embeds      []chat.Embed
+	SlackFormat bool
+	Username    string // "" = tên của webhook
+	AvatarURL   string // "" = avatar của webhook
+}
+
+// parseIncomingWebhookBody: {content} (native) hoặc payload Slack
+// (JSON hoặc form "payload=<json>" như Slack cũng nhận)
+func parseIncomingWebhookBody(w http.ResponseWriter, r *http.Request) (*incomingWebhookBody, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, 64<<10)
+
+	var raw []byte
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		if err := r.ParseForm(); err != nil {
+			return nil, errors.New("invalid form body")
+		}
+		raw = []byte(r.PostForm.Get("payload"))
+	} else {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, errors.New("invalid body")
+		}
+		raw = b
+	}
+
+	var req incomingWebhookRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return nil, errors.New("invalid json body")
+	}
+
+	body := &incomingWebhookBody{Username: strings.TrimSpace(req.Username)}
+	if len([]rune(body.Username)) > 64 {
+		return nil, errors.New("username too long (max 64 chars)")
+	}
+	body.AvatarURL = strings.TrimSpace(req.AvatarURL)
+	if body.AvatarURL == "" {
+		body.AvatarURL = strings.TrimSpace(req.IconURL)
+	}
+	if body.AvatarURL != "" && (len(body.AvatarURL) > 512 || webhook.ValidateURL(body.AvatarURL) != nil) {
+		return nil, errors.New("avatar_url must be an absolute http(s) url")
+	}
+
+	if c := strings.TrimSpace(req.Content); c != "" {
+		body.Content = c
+		return body, nil
+	}
+
+	content, embeds, err := webhook.ParseSlackPayload(raw)
+	if err != nil {
+		if errors.Is(err, webhook.ErrEmptyPayload) {
+			return nil, errors.New("content is required")
+		}
+		return nil, errors.New("invalid json body")
+	}
+	// chỉ có attachments không có text -> vẫn cần content cho preview
+	if content == "" {
+		content = "[webhook]"
+	}
+	body.Content, body.Embeds, body.SlackFormat = content, embeds, true
+	return body, nil
+}
+
+// POST /hooks/{token} (hoặc /webhooks/{token})
+func (s *Server) handleIncomingWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	token := strings.TrimPrefix(r.URL.Path, incomingWebhookPrefix)
+	token = strings.Trim(strings.TrimPrefix(token, hooksPrefix), "/")
+	if token == "" || strings.Contains(token, "/") {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "webhook not found"})
+		return
+	}
+
+	ctx := r.Context()
+	hook, err := s.webhookRepo.GetIncomingByToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, webhook.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "webhook not found"})
+			return
+		}
+		log.Println("GetIncomingByToken error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	// người tạo rời room -> webhook hết hiệu lực
+	isMember, err := s.roomRepo.IsUserInRoom(hook.RoomID, hook.CreatedBy)
+	if err != nil && err != sql.ErrNoRows {
+		log.Println("IsUserInRoom error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	if !isMember {
+		writeJSON(w, http.StatusGone, map[string]string{"error": "webhook owner is no longer a member of this room"})
+		return
+	}
+
+	body, err := parseIncomingWebhookBody(w, r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if len([]rune(body.Content)) > incomingWebhookMaxContent {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "content too long"})
+		return
+	}
+
+	webhookID := hook.ID
+	msg := &chat.Message{
+		RoomID:      hook.RoomID,
+		SenderID:    hook.CreatedBy,
+		Content:     body.Content,
+		MessageType: "text",
+		WebhookID:   &webhookID,
+		Embeds:      body.Embeds,
+		CreatedAt:   s.now().UTC(),
+
+		WebhookName:      body.Username,
+		WebhookAvatarURL: body.AvatarURL,
+	}
+
+	id, err := s.chatRepo.CreateMessage(ctx, msg, false)
+	if err != nil {
+		log.Println("CreateMessage (webhook) error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	senderName, senderAvatar := hook.Name, hook.AvatarURL
+	if body.Username != "" {
+		senderName = body.Username
+	}
+	if body.AvatarURL != "" {
+		senderAvatar = body.AvatarURL
+	}
+	resp := sendMessageResponse{
+		ID:              id,
+		RoomID:          hook.RoomID,
+		SenderID:        hook.CreatedBy,
+		SenderName:      senderName,
+		SenderAvatarURL: s.signMediaURL(senderAvatar),
+		Content:         msg.Content,
+		MessageType:     msg.MessageType,
+		WebhookID:       &webhookID,
+		Embeds:          msg.Embeds,
+		CreatedAt:       msg.CreatedAt.Format(time.RFC3339),
+	}
+
+	// Slack trả text "ok" -> tool trỏ sang Slack không cần sửa
+	if body.SlackFormat {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	} else {
+		writeJSON(w, http.StatusOK, map[string]any{"id": id})
+	}
+
+	s.broadcastMessageCreated(ctx, hook.RoomID, hook.CreatedBy, resp)
+}
@@ -0,0 +1,139 @@
+package httpserver
+
+import (
+	"cronhustler/api-service/internal/linkpreview"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCreateRoomWebhookURL(t *testing.T) {
+	const ownerQuery = `SELECT user_id\s+FROM room_members\s+WHERE room_id = \? AND member_role = 'owner'`
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+	}{
+		{name: "loopback", url: "http://127.0.0.1:8080/hook", wantStatus: http.StatusBadRequest},
+		{name: "localhost name", url: "http://localhost/hook", wantStatus: http.StatusBadRequest},
+		{name: "cloud metadata", url: "http://169.254.169.254/latest/meta-data", wantStatus: http.StatusBadRequest},
+		{name: "private range", url: "https://10.0.0.5/hook", wantStatus: http.StatusBadRequest},
+		{name: "ipv6 loopback", url: "http://[::1]/hook", wantStatus: http.StatusBadRequest},
+		{name: "ipv4-mapped loopback", url: "http://[::ffff:127.0.0.1]/hook", wantStatus: http.StatusBadRequest},
+		{name: "credentials in url", url: "https://user:pw@93.184.215.14/hook", wantStatus: http.StatusBadRequest},
+		{name: "not http", url: "ftp://93.184.215.14/hook", wantStatus: http.StatusBadRequest},
+		{name: "public ip", url: "https://93.184.215.14/hook", wantStatus: http.StatusCreated},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, mock := newTestServer(t)
+			mock.ExpectQuery(ownerQuery).WithArgs(int64(5)).
+				WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+			if tc.wantStatus == http.StatusCreated {
+				mock.ExpectExec(`INSERT INTO room_webhooks`).WillReturnResult(sqlmock.NewResult(9, 1))
+			}
+
+			rec := serve(s, http.MethodPost, "/rooms/webhooks/5", accessTokenFor(t, 1), `{"url":"`+tc.url+`"}`)
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tc.wantStatus, rec.Body.String())
+			}
+			checkExpectations(t, mock)
+		})
+	}
+}
+
+// dispatcher check IP lúc dial: webhook tạo khi DNS còn trỏ IP public rồi đổi sang nội bộ vẫn bị chặn
+func TestWebhookDispatcherBlocksInternalAddress(t *testing.T) {
+	hit := false
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	defer internal.Close()
+
+	s, _ := newTestServer(t)
+	for _, target := range []string{
+		internal.URL,
+		strings.Replace(internal.URL, "127.0.0.1", "localhost", 1), // tên resolve ra loopback
+	} {
+		resp, err := s.webhooks.Client.Post(target, "application/json", strings.NewReader(`{}`))
+		if err == nil {
+			resp.Body.Close()
+		}
+		if !errors.Is(err, linkpreview.ErrBlockedAddress) {
+			t.Errorf("POST %s: err = %v, want ErrBlockedAddress", target, err)
+		}
+	}
+	if hit {
+		t.Fatal("internal server was reached")
+	}
+}
+
+func TestAutomationWebhookRuleTargets(t *testing.T) {
+	hookRows := func(url, events string, active bool) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "room_id", "url", "events", "is_active", "created_by", "created_at"}).
+			AddRow(3, 5, url, events, active, 1, testNow)
+	}
+
+	tests := []struct {
+		name       string
+		action     string
+		hooks      *sqlmock.Rows
+		wantStatus int
+	}{
+		{
+			// webhook tạo trước khi có ValidateURL mới
+			name:       "legacy internal webhook",
+			action:     "webhook",
+			hooks:      hookRows("http://169.254.169.254/latest/meta-data", "automation_triggered", true),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "internal webhook without automation event",
+			action:     "webhook",
+			hooks:      hookRows("http://127.0.0.1/hook", "message_created", true),
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "inactive internal webhook",
+			action:     "webhook",
+			hooks:      hookRows("http://10.0.0.5/hook", "automation_triggered", false),
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "public webhook",
+			action:     "webhook",
+			hooks:      hookRows("https://93.184.215.14/hook", "automation_triggered,message_created", true),
+			wantStatus: http.StatusCreated,
+		},
+		{name: "other action skips check", action: "flag", wantStatus: http.StatusCreated},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, mock := newTestServer(t)
+			mock.ExpectQuery(`SELECT member_role FROM room_members WHERE room_id = \? AND user_id = \?`).
+				WithArgs(int64(5), int64(1)).WillReturnRows(sqlmock.NewRows([]string{"member_role"}).AddRow("owner"))
+			if tc.hooks != nil {
+				mock.ExpectQuery(`FROM room_webhooks\s+WHERE room_id = \?`).WithArgs(int64(5)).WillReturnRows(tc.hooks)
+			}
+			if tc.wantStatus == http.StatusCreated {
+				mock.ExpectQuery(`SELECT COUNT\(\*\) FROM automation_rules`).WithArgs(int64(5)).
+					WillReturnRows(sqlmock.NewRows([]string{"n"}).AddRow(0))
+				mock.ExpectExec(`INSERT INTO automation_rules`).WillReturnResult(sqlmock.NewResult(4, 1))
+			}
+
+			rec := serve(s, http.MethodPost, "/rooms/automation/5", accessTokenFor(t, 1),
+				`{"pattern":"deploy","action":"`+tc.action+`"}`)
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tc.wantStatus, rec.Body.String())
+			}
+			checkExpectations(t, mock)
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/audit"
+	"cronhustler/api-service/internal/push"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+func (s *Server) mountWebPushRoutes(mux *http.ServeMux) {
+	// GET  /webpush/vapid-public-key -> {public_key} (applicationServerKey cho pushManager.subscribe)
+	mux.Handle("/webpush/vapid-public-key", http.HandlerFunc(s.handleVAPIDPublicKey))
+	// POST /webpush/subscribe   PushSubscription.toJSON(): {endpoint, keys: {p256dh, auth}}
+	mux.Handle("/webpush/subscribe", http.HandlerFunc(s.handleWebPushSubscribe))
+	// POST /webpush/unsubscribe {endpoint}
+	mux.Handle("/webpush/unsubscribe", http.HandlerFunc(s.handleWebPushUnsubscribe))
+	// POST /admin/webpush/vapid/rotate -> key mới, xoá toàn bộ subscription cũ
+	mux.Handle("/admin/webpush/vapid/rotate", s.RequireAdmin(http.HandlerFunc(s.handleRotateVAPID)))
+}
+
+// LoadWebPush: key VAPID từ env (cả 2 public/private) hoặc sinh + lưu DB lần đầu
+func (s *Server) LoadWebPush(ctx context.Context, subject, publicKey, privateKey string) (*push.WebPushSender, error) {
+	keys := push.VAPIDKeys{Public: publicKey, Private: privateKey}
+	fromEnv := publicKey != "" || privateKey != ""
+	if !fromEnv {
+		k, err := s.pushRepo.LoadOrCreateVAPID(ctx)
+		if err != nil {
+			return nil, err
+		}
+		keys = k
+	}
+	sender, err := push.NewWebPushSender(keys, subject)
+	if err != nil {
+		return nil, err
+	}
+	s.webPush = sender
+	s.vapidFromEnv = fromEnv
+	return sender, nil
+}
+
+func (s *Server) handleVAPIDPublicKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if s.webPush == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "web push is not enabled"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"public_key": s.webPush.PublicKey()})
+}
+
+type webPushSubscribeRequest struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+func (s *Server) handleWebPushSubscribe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if s.webPush == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "web push is not enabled"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var req webPushSubscribeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+		return
+	}
+
+	sub := &push.WebSubscription{
+		UserID:    userID,
+		Endpoint:  strings.TrimSpace(req.Endpoint),
+		P256dh:    strings.TrimSpace(req.Keys.P256dh),
+		Auth:      strings.TrimSpace(req.Keys.Auth),
+		UserAgent: r.UserAgent(),
+	}
+	if err := s.pushRepo.UpsertWebSubscription(r.Context(), sub); err != nil {
+		if errors.Is(err, push.ErrInvalidSubscription) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		log.Println("UpsertWebSubscription error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+func (s *Server) handleWebPushUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var req webPushSubscribeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Endpoint) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "endpoint is required"})
+		return
+	}
+
+	if err := s.pushRepo.DeleteWebSubscription(r.Context(), userID, req.Endpoint); err != nil {
+		log.Println("DeleteWebSubscription error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+func (s *Server) handleRotateVAPID(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if s.webPush == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "web push is not enabled"})
+		return
+	}
+	if s.vapidFromEnv {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "vapid keys are set via env (VAPID_PUBLIC_KEY / VAPID_PRIVATE_KEY)"})
+		return
+	}
+
+	keys, err := push.GenerateVAPIDKeys()
+	if err != nil {
+		log.Println("GenerateVAPIDKeys error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	removed, err := s.pushRepo.RotateVAPID(r.Context(), keys)
+	if err != nil {
+		log.Println("RotateVAPID error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	if err := s.webPush.SetKeys(keys); err != nil {
+		log.Println("webpush SetKeys error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+
+	adminID, _ := UserIDFromContext(r.Context())
+	s.recordAudit(r, adminID, audit.ActionConfigChange, audit.TargetConfig, 0, map[string]any{
+		"key":                   "webpush.vapid",
+		"subscriptions_removed": removed,
+	})
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"public_key":            keys.Public,
+		"subscriptions_removed": removed,
+	})
+}
@@ -0,0 +1,98 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/linkpreview"
+	"cronhustler/api-service/internal/push"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// webPushTestKeys: p256dh + auth hợp lệ như PushSubscription.toJSON() của trình duyệt
+func webPushTestKeys(t *testing.T) (p256dh, auth string) {
+	t.Helper()
+	k, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := make([]byte, 16)
+	_, _ = rand.Read(secret)
+	return base64.RawURLEncoding.EncodeToString(k.PublicKey().Bytes()), base64.RawURLEncoding.EncodeToString(secret)
+}
+
+func newWebPushTestServer(t *testing.T) (*Server, sqlmock.Sqlmock) {
+	t.Helper()
+	s, mock := newTestServer(t)
+	keys, err := push.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.LoadWebPush(context.Background(), "mailto:admin@example.com", keys.Public, keys.Private); err != nil {
+		t.Fatalf("LoadWebPush: %v", err)
+	}
+	return s, mock
+}
+
+func TestWebPushSubscribeEndpoint(t *testing.T) {
+	p256dh, auth := webPushTestKeys(t)
+
+	tests := []struct {
+		name       string
+		endpoint   string
+		wantStatus int
+	}{
+		{name: "loopback", endpoint: "https://127.0.0.1/push/1", wantStatus: http.StatusBadRequest},
+		{name: "cloud metadata", endpoint: "https://169.254.169.254/latest/meta-data", wantStatus: http.StatusBadRequest},
+		{name: "private range", endpoint: "https://10.0.0.5/push/1", wantStatus: http.StatusBadRequest},
+		{name: "ipv6 loopback", endpoint: "https://[::1]/push/1", wantStatus: http.StatusBadRequest},
+		{name: "non-https port", endpoint: "https://fcm.googleapis.com:8443/fcm/send/1", wantStatus: http.StatusBadRequest},
+		{name: "credentials in url", endpoint: "https://user:pw@fcm.googleapis.com/fcm/send/1", wantStatus: http.StatusBadRequest},
+		{name: "plain http", endpoint: "http://fcm.googleapis.com/fcm/send/1", wantStatus: http.StatusBadRequest},
+		{name: "push service", endpoint: "https://fcm.googleapis.com/fcm/send/1", wantStatus: http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, mock := newWebPushTestServer(t)
+			if tc.wantStatus == http.StatusOK {
+				mock.ExpectExec(`INSERT INTO webpush_subscriptions`).WillReturnResult(sqlmock.NewResult(1, 1))
+			}
+
+			body := `{"endpoint":"` + tc.endpoint + `","keys":{"p256dh":"` + p256dh + `","auth":"` + auth + `"}}`
+			rec := serve(s, http.MethodPost, "/webpush/subscribe", accessTokenFor(t, 1), body)
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tc.wantStatus, rec.Body.String())
+			}
+			checkExpectations(t, mock)
+		})
+	}
+}
+
+// subscription lưu trước khi có check (hoặc tên miền trỏ về IP nội bộ) vẫn bị chặn lúc gửi
+func TestWebPushSendBlocksInternalAddress(t *testing.T) {
+	hit := false
+	internal := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer internal.Close()
+
+	s, _ := newWebPushTestServer(t)
+	p256dh, auth := webPushTestKeys(t)
+	err := s.webPush.SendTo(context.Background(),
+		push.WebSubscription{Endpoint: internal.URL + "/push/1", P256dh: p256dh, Auth: auth},
+		push.Notification{Title: "hi", Body: "there"})
+	if !errors.Is(err, linkpreview.ErrBlockedAddress) {
+		t.Fatalf("err = %v, want ErrBlockedAddress", err)
+	}
+	if hit {
+		t.Fatal("internal server was reached")
+	}
+}
@@ -0,0 +1,262 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/audit"
+	"cronhustler/api-service/internal/moderation"
+	"cronhustler/api-service/internal/wordfilter"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	adminWordFilterPrefix = "/admin/word-filter/"
+	roomWordFilterPrefix  = "/rooms/word-filter/"
+)
+
+func (s *Server) mountWordFilterRoutes(mux *http.ServeMux) {
+	// GET    /admin/word-filter?room_id=     -> list chung (room_id = 0) hoặc list của room
+	// POST   /admin/word-filter {pattern, mode, room_id}
+	mux.Handle("/admin/word-filter", s.RequireAdmin(http.HandlerFunc(s.handleAdminWordFilter)))
+	// DELETE /admin/word-filter/{id}
+	// POST   /admin/word-filter/test {text, room_id} -> thử 1 câu
+	mux.Handle(adminWordFilterPrefix, s.RequireAdmin(http.HandlerFunc(s.handleAdminWordFilterItem)))
+
+	// owner room ghi đè list chung:
+	// GET    /rooms/word-filter/{roomID}
+	// POST   /rooms/word-filter/{roomID} {pattern, mode: reject|mask|flag|allow}
+	// DELETE /rooms/word-filter/{roomID}/{id}
+	mux.Handle(roomWordFilterPrefix, http.HandlerFunc(s.handleRoomWordFilter))
+}
+
+type wordFilterRequest struct {
+	RoomID  int64  `json:"room_id"`
+	Pattern string `json:"pattern"`
+	Mode    string `json:"mode"`
+}
+
+type wordFilterTestRequest struct {
+	RoomID int64  `json:"room_id"`
+	Text   string `json:"text"`
+}
+
+func (s *Server) handleAdminWordFilter(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		roomID, _ := strconv.ParseInt(r.URL.Query().Get("room_id"), 10, 64)
+		s.listWordFilter(w, r, roomID)
+	case http.MethodPost:
+		var req wordFilterRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+			return
+		}
+		if req.RoomID > 0 {
+			if _, err := s.roomRepo.GetRoomOwner(req.RoomID); err != nil {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "room not found"})
+				return
+			}
+		}
+		s.upsertWordFilter(w, r, req)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func (s *Server) handleAdminWordFilterItem(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, adminWordFilterPrefix), "/")
+
+	if rest == "test" {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		var req wordFilterTestRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+			return
+		}
+		if strings.TrimSpace(req.Text) == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "text is required"})
+			return
+		}
+		res, err := s.wordFilterRepo.Check(r.Context(), req.RoomID, req.Text)
+		if err != nil {
+			log.Println("wordfilter Check error:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, res)
+		return
+	}
+
+	if r.Method != http.MethodDelete {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	id, err := strconv.ParseInt(rest, 10, 64)
+	if err != nil || id <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid id"})
+		return
+	}
+	s.deleteWordFilter(w, r, id, -1)
+}
+
+func (s *Server) handleRoomWordFilter(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, roomWordFilterPrefix), "/"), "/")
+	roomID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || roomID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid room id"})
+		return
+	}
+
+	userID, err := UserIDFromContext(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+	ownerID, err := s.roomRepo.GetRoomOwner(roomID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "room not found"})
+			return
+		}
+		log.Println("GetRoomOwner error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	if ownerID != userID {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "only owner can manage word filter"})
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		s.listWordFilter(w, r, roomID)
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		var req wordFilterRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json body"})
+			return
+		}
+		req.RoomID = roomID
+		s.upsertWordFilter(w, r, req)
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		id, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || id <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid id"})
+			return
+		}
+		s.deleteWordFilter(w, r, id, roomID)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
+
+func (s *Server) listWordFilter(w http.ResponseWriter, r *http.Request, roomID int64) {
+	list, err := s.wordFilterRepo.List(r.Context(), roomID)
+	if err != nil {
+		log.Println("wordfilter List error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"room_id": roomID, "words": list})
+}
+
+func (s *Server) upsertWordFilter(w http.ResponseWriter, r *http.Request, req wordFilterRequest) {
+	userID, _ := UserIDFromContext(r.Context())
+	e := &wordfilter.Entry{
+		RoomID:    req.RoomID,
+		Pattern:   req.Pattern,
+		Mode:      strings.ToLower(strings.TrimSpace(req.Mode)),
+		CreatedBy: userID,
+	}
+	if err := s.wordFilterRepo.Upsert(r.Context(), e); err != nil {
+		if errors.Is(err, wordfilter.ErrInvalidMode) || errors.Is(err, wordfilter.ErrInvalidPattern) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		log.Println("wordfilter Upsert error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	s.recordAudit(r, userID, audit.ActionConfigChange, audit.TargetConfig, e.ID, map[string]any{
+		"kind":    "word_filter",
+		"op":      "upsert",
+		"room_id": e.RoomID,
+		"pattern": e.Pattern,
+		"mode":    e.Mode,
+	})
+	writeJSON(w, http.StatusOK, e)
+}
+
+// deleteWordFilter: roomID = -1 -> admin (xoá được mọi list)
+func (s *Server) deleteWordFilter(w http.ResponseWriter, r *http.Request, id, roomID int64) {
+	ctx := r.Context()
+	e, err := s.wordFilterRepo.Get(ctx, id)
+	if err == nil && roomID >= 0 && e.RoomID != roomID {
+		err = wordfilter.ErrNotFound
+	}
+	if err != nil {
+		if errors.Is(err, wordfilter.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "word not found"})
+			return
+		}
+		log.Println("wordfilter Get error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	if err := s.wordFilterRepo.Delete(ctx, e); err != nil {
+		log.Println("wordfilter Delete error:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	actorID, _ := UserIDFromContext(r.Context())
+	s.recordAudit(r, actorID, audit.ActionConfigChange, audit.TargetConfig, id, map[string]any{
+		"kind":    "word_filter",
+		"op":      "delete",
+		"room_id": e.RoomID,
+		"pattern": e.Pattern,
+	})
+	writeJSON(w, http.StatusOK, map[string]any{"deleted": id})
+}
+
+// applyWordFilter: chạy trước khi lưu tin.
+// failure != nil = bị chặn (422); còn lại gửi tiếp với content (có thể đã mask)
+func (s *Server) applyWordFilter(ctx context.Context, roomID int64, content string) (string, []string, *apiFailure) {
+	res, err := s.wordFilterRepo.Check(ctx, roomID, content)
+	if err != nil {
+		// lỗi filter không chặn chat
+		log.Println("wordfilter Check error:", err)
+		return content, nil, nil
+	}
+	if res.Action == wordfilter.ActionReject {
+		return "", nil, &apiFailure{status: http.StatusUnprocessableEntity, body: map[string]any{
+			"error": "message contains blocked words",
+			"words": res.Matches,
+		}}
+	}
+	return res.Content, res.Flagged, nil
+}
+
+// flagFilteredMessage: tin dính từ mode=flag -> report hệ thống (reporter_id = 0)
+func (s *Server) flagFilteredMessage(ctx context.Context, msgID, roomID, senderID int64, content string, words []string) {
+	rp := &moderation.Report{
+		ReporterID:     0,
+		TargetType:     moderation.TargetMessage,
+		MessageID:      msgID,
+		TargetUserID:   senderID,
+		RoomID:         roomID,
+		Reason:         "other",
+		Details:        "word filter: " + strings.Join(words, ", "),
+		ContentPreview: truncateRunes(content, 500),
+	}
+	if err := s.moderationRepo.Create(ctx, rp); err != nil {
+		log.Println("word filter flag report error:", err)
+	}
+}
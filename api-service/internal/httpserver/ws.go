@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -15,16 +16,20 @@ type wsEnvelope struct {
 	RoomID int64  `json:"room_id,omitempty"`
 	Data   any    `json:"data,omitempty"`
 	TS     int64  `json:"ts"`
+	Seq    int64  `json:"seq,omitempty"` // có khi event được journal (wsJournaled)
+}
+
+// wsFrame: payload đã encode + seq để writer bỏ event đã gửi qua replay
+type wsFrame struct {
+	seq int64
+	b   []byte
 }
 
 type wsClient struct {
 	conn   *websocket.Conn
-	sendCh chan []byte
+	sendCh chan wsFrame
 	userID int64
-}
-
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool { return true },
+	isBot  bool
 }
 
 // wsByUser[userID] => set of clients
@@ -41,14 +46,37 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("[WS] incoming: %s\n", r.URL.Path)
 
-	userID, err := s.VerifyWSAuth(r)
-	if err != nil {
-		log.Println("[WS] auth failed:", err)
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	// check origin trước khi đụng tới cookie (cross-site WebSocket hijacking)
+	if !s.checkWSOrigin(r) {
+		writeError(w, http.StatusForbidden, CodeForbidden, "websocket origin not allowed")
+		return
+	}
+
+	// bot: Authorization: Bot <key> hoặc ?bot_key=, user: subprotocol bearer.<access_token> hoặc refresh cookie
+	userID, isBot, f := s.wsAuth(r)
+	if f != nil {
+		f.write(w)
 		return
 	}
+	// resume: /ws?last_seq=N (seq cuối client nhận được) -> replay event lỡ trước event live
+	var lastSeq int64
+	var err error
+	rawSeq := r.URL.Query().Get("last_seq")
+	if rawSeq != "" {
+		if lastSeq, err = strconv.ParseInt(rawSeq, 10, 64); err != nil || lastSeq < 0 {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid last_seq")
+			return
+		}
+	}
+	if !isBot {
+		if f := s.suspendedFailure(r.Context(), userID); f != nil {
+			f.write(w)
+			return
+		}
+		s.analyticsRepo.Touch(r.Context(), userID)
+	}
 
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := s.wsUpgrader().Upgrade(w, r, nil)
 	if err != nil {
 		log.Println("upgrade error:", err)
 		return
@@ -56,8 +84,9 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 
 	c := &wsClient{
 		conn:   conn,
-		sendCh: make(chan []byte, 32),
+		sendCh: make(chan wsFrame, 32),
 		userID: userID,
+		isBot:  isBot,
 	}
 
 	// ✅ 2) add client
@@ -79,14 +108,31 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			_ = conn.Close()
 		}()
 
+		// client đã đăng ký ở trên -> event live trong lúc replay nằm chờ ở sendCh
+		replayedUpTo, err := wsResume(userID, lastSeq, rawSeq != "", func(env wsEnvelope) error {
+			if env.TS == 0 {
+				env.TS = time.Now().UnixMilli()
+			}
+			b, _ := json.Marshal(env)
+			conn.SetWriteDeadline(time.Now().Add(8 * time.Second))
+			return conn.WriteMessage(websocket.TextMessage, b)
+		})
+		if err != nil {
+			log.Printf("[WS] user=%d resume error: %v\n", userID, err)
+			return
+		}
+
 		for {
 			select {
-			case msg, ok := <-c.sendCh:
+			case f, ok := <-c.sendCh:
 				if !ok {
 					return
 				}
+				if f.seq != 0 && f.seq <= replayedUpTo {
+					continue
+				}
 				conn.SetWriteDeadline(time.Now().Add(8 * time.Second))
-				if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				if err := conn.WriteMessage(websocket.TextMessage, f.b); err != nil {
 					return
 				}
 
@@ -99,7 +145,7 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	// ✅ 4) reader loop để detect disconnect
+	// ✅ 4) reader loop: detect disconnect + nhận ack từ client
 	conn.SetReadLimit(1 << 20)
 	conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 	conn.SetPongHandler(func(string) error {
@@ -124,49 +170,111 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		}()
 
 		for {
-			if _, _, err := conn.ReadMessage(); err != nil {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
 				return
 			}
+			s.handleWSInbound(c, data)
 		}
 	}()
 }
 
+// CloseWebSockets: gửi close frame 1001 (going away) cho mọi client rồi đóng conn
+// gọi khi shutdown: http.Server.Shutdown không quản lý conn đã hijack
+func (s *Server) CloseWebSockets() {
+	wsByUserMu.RLock()
+	var clients []*wsClient
+	for _, set := range wsByUser {
+		for c := range set {
+			clients = append(clients, c)
+		}
+	}
+	wsByUserMu.RUnlock()
+
+	// WriteControl chạy song song với writer loop được (gorilla cho phép)
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	deadline := time.Now().Add(2 * time.Second)
+	for _, c := range clients {
+		_ = c.conn.WriteControl(websocket.CloseMessage, msg, deadline)
+		_ = c.conn.Close()
+	}
+	log.Printf("[WS] closed %d connections\n", len(clients))
+}
+
 // ===== helpers =====
 
 func wsSendToUser(userID int64, env wsEnvelope) {
-	env.TS = time.Now().UnixMilli()
-	b, _ := json.Marshal(env)
+	wsPublish([]int64{userID}, env)
+}
 
-	wsByUserMu.RLock()
-	set := wsByUser[userID]
-	if len(set) == 0 {
-		wsByUserMu.RUnlock()
+// reply: gửi riêng cho kết nối này (ack của tin client gửi lên), không qua broker / journal
+// chỉ gọi từ reader loop (sendCh chưa bị đóng)
+func (c *wsClient) reply(env wsEnvelope) {
+	if env.TS == 0 {
+		env.TS = time.Now().UnixMilli()
+	}
+	b, err := json.Marshal(env)
+	if err != nil {
 		return
 	}
-	clients := make([]*wsClient, 0, len(set))
-	for c := range set {
-		clients = append(clients, c)
+	select {
+	case c.sendCh <- wsFrame{b: b}:
+	default:
+		_ = c.conn.Close()
 	}
-	wsByUserMu.RUnlock()
+}
 
-	for _, c := range clients {
-		select {
-		case c.sendCh <- b:
-		default:
-			// sendCh full -> drop connection cho sạch
-			_ = c.conn.Close()
+// wsDeliverLocal: gửi payload cho các client kết nối vào instance này
+func wsDeliverLocal(userIDs []int64, b []byte, seq int64) {
+	for _, userID := range userIDs {
+		wsByUserMu.RLock()
+		set := wsByUser[userID]
+		if len(set) == 0 {
+			wsByUserMu.RUnlock()
+			continue
+		}
+		clients := make([]*wsClient, 0, len(set))
+		for c := range set {
+			clients = append(clients, c)
+		}
+		wsByUserMu.RUnlock()
+
+		for _, c := range clients {
+			select {
+			case c.sendCh <- wsFrame{seq: seq, b: b}:
+			default:
+				// sendCh full -> drop connection cho sạch
+				_ = c.conn.Close()
+			}
 		}
 	}
 }
 
+// wsOnlineUserIDs: mọi user đang có ít nhất 1 kết nối WS
+func wsOnlineUserIDs() []int64 {
+	wsByUserMu.RLock()
+	defer wsByUserMu.RUnlock()
+	out := make([]int64, 0, len(wsByUser))
+	for uid, set := range wsByUser {
+		if len(set) > 0 {
+			out = append(out, uid)
+		}
+	}
+	return out
+}
+
 func wsSendToUsers(userIDs []int64, env wsEnvelope) {
 	// tránh send trùng user
 	seen := make(map[int64]struct{}, len(userIDs))
+	uniq := make([]int64, 0, len(userIDs))
 	for _, uid := range userIDs {
 		if _, ok := seen[uid]; ok {
 			continue
 		}
 		seen[uid] = struct{}{}
-		wsSendToUser(uid, env)
+		uniq = append(uniq, uid)
+	}
+	if len(uniq) > 0 {
+		wsPublish(uniq, env)
 	}
 }
@@ -0,0 +1,108 @@
+package httpserver
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// Sec-WebSocket-Protocol: trình duyệt không set được header Authorization cho WS -> access token đi qua
+// subprotocol "bearer.<access_token>", kèm "cronchat.v1" để server chọn (không bao giờ echo lại token)
+const (
+	wsSubprotocol      = "cronchat.v1"
+	wsTokenSubprotocol = "bearer."
+)
+
+var errWSAnonymous = errors.New("websocket requires a refresh cookie, an access token (subprotocol bearer.<token>) or a bot key")
+
+// wsUpgrader: lỗi handshake (không phải WS, thiếu version...) cũng trả JSON như REST
+func (s *Server) wsUpgrader() *websocket.Upgrader {
+	return &websocket.Upgrader{
+		CheckOrigin:  s.checkWSOrigin,
+		Subprotocols: []string{wsSubprotocol},
+		Error: func(w http.ResponseWriter, r *http.Request, status int, reason error) {
+			writeError(w, status, codeForStatus(status, reason.Error()), reason.Error())
+		},
+	}
+}
+
+// checkWSOrigin: không có Origin (bot, service, app native) -> cho qua; trình duyệt phải từ cùng host,
+// APP_PUBLIC_URL hoặc WS_ALLOWED_ORIGINS (chặn trang lạ mở WS bằng cookie của user)
+func (s *Server) checkWSOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, o := range s.wsOrigins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	if app, err := url.Parse(s.appPublicURL); err == nil && app.Host != "" {
+		return strings.EqualFold(app.Scheme+"://"+app.Host, origin)
+	}
+	return false
+}
+
+// wsSubprotocolToken: token trong "bearer.<token>", ok = false khi gửi token mà thiếu cronchat.v1
+// (server không có protocol nào để chọn -> trình duyệt tự huỷ kết nối sau handshake)
+func wsSubprotocolToken(r *http.Request) (token string, ok bool) {
+	offered := false
+	for _, p := range websocket.Subprotocols(r) {
+		if p == wsSubprotocol {
+			offered = true
+		} else if strings.HasPrefix(p, wsTokenSubprotocol) {
+			token = strings.TrimPrefix(p, wsTokenSubprotocol)
+		}
+	}
+	return token, token == "" || offered
+}
+
+// wsAuth: bot key -> bot; access token (subprotocol / Authorization: Bearer) -> user; còn lại refresh cookie
+func (s *Server) wsAuth(r *http.Request) (userID int64, isBot bool, f *apiFailure) {
+	if isBotAuthRequest(r) {
+		userID, err := s.verifyBotWSAuth(r)
+		if err != nil {
+			return 0, true, newFailure(http.StatusUnauthorized, CodeUnauthorized, "invalid bot key")
+		}
+		return userID, true, nil
+	}
+
+	token, ok := wsSubprotocolToken(r)
+	if !ok {
+		return 0, false, newFailure(http.StatusBadRequest, CodeInvalidRequest,
+			"offer subprotocol "+wsSubprotocol+" together with the bearer token")
+	}
+	if token != "" {
+		claims, err := ParseToken(token, s.jwtSecret)
+		if err != nil || claims.TokenType != TokenTypeAccess {
+			return 0, false, newFailure(http.StatusUnauthorized, CodeUnauthorized, errInvalidAuth.Error())
+		}
+		return int64(claims.UserID), false, nil
+	}
+	// Authorization: Bearer <access token> đã được AuthMiddleware verify (client không phải trình duyệt)
+	if claims := ClaimsFromContext(r.Context()); claims != nil {
+		return int64(claims.UserID), false, nil
+	}
+
+	if _, err := r.Cookie(RefreshCookieName); err != nil {
+		return 0, false, newFailure(http.StatusUnauthorized, CodeUnauthorized, errWSAnonymous.Error())
+	}
+	userID, err := s.VerifyWSAuth(r)
+	if err != nil {
+		log.Println("[WS] auth failed:", err)
+		return 0, false, newFailure(http.StatusUnauthorized, CodeUnauthorized, "invalid or expired session")
+	}
+	return userID, false, nil
+}
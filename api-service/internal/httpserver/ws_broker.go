@@ -0,0 +1,57 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/broker"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// channel chung cho WS event, mọi instance subscribe và tự gửi cho client của mình
+const wsBrokerChannel = "cronchat:ws"
+
+// nil = 1 instance, gửi thẳng vào wsByUser
+var wsHub broker.Broker
+
+type wsBusMessage struct {
+	UserIDs []int64         `json:"user_ids"`
+	Payload json.RawMessage `json:"payload"` // wsEnvelope đã encode
+	Seq     int64           `json:"seq,omitempty"`
+}
+
+// EnableWSBroker: WS event đi qua broker để chạy nhiều replica sau load balancer
+// (gọi trước khi nhận request). wsIsOnline / wsOnlineUserIDs vẫn chỉ tính client local
+func (s *Server) EnableWSBroker(ctx context.Context, b broker.Broker) {
+	wsHub = b
+	go func() {
+		_ = b.Subscribe(ctx, wsBrokerChannel, func(payload []byte) {
+			var m wsBusMessage
+			if err := json.Unmarshal(payload, &m); err != nil {
+				log.Println("[WS] broker message error:", err)
+				return
+			}
+			wsDeliverLocal(m.UserIDs, m.Payload, m.Seq)
+		})
+	}()
+}
+
+func wsPublish(userIDs []int64, env wsEnvelope) {
+	wsJournalAppend(userIDs, &env)
+	env.TS = time.Now().UnixMilli()
+	b, _ := json.Marshal(env)
+
+	if wsHub == nil {
+		wsDeliverLocal(userIDs, b, env.Seq)
+		return
+	}
+
+	msg, _ := json.Marshal(wsBusMessage{UserIDs: userIDs, Payload: b, Seq: env.Seq})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := wsHub.Publish(ctx, wsBrokerChannel, msg); err != nil {
+		// broker lỗi -> ít nhất client trên instance này vẫn nhận được
+		log.Println("[WS] broker publish error:", err)
+		wsDeliverLocal(userIDs, b, env.Seq)
+	}
+}
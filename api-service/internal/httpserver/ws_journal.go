@@ -0,0 +1,136 @@
+package httpserver
+
+import (
+	"context"
+	"cronhustler/api-service/internal/wsjournal"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+)
+
+// số event replay tối đa 1 lần kết nối lại, lỡ nhiều hơn -> resync_required (FE refetch REST)
+const wsReplayMax = 500
+
+// nil = tắt journal: envelope không có seq, /ws?last_seq= bị bỏ qua
+var wsJournal *wsjournal.Repository
+
+// EnableWSJournal: ghi event room / message vào DB (seq) để client resume sau khi rớt WS,
+// xoá event cũ hơn ttl mỗi giờ. Gọi trước khi nhận request (như EnableWSBroker)
+func (s *Server) EnableWSJournal(ctx context.Context, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = wsjournal.DefaultTTL
+	}
+	repo := s.wsJournalRepo
+	wsJournal = repo
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				pctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+				n, err := repo.Prune(pctx, time.Now().Add(-ttl))
+				cancel()
+				if err != nil {
+					log.Println("[WS] journal prune error:", err)
+				} else if n > 0 {
+					log.Printf("[WS] journal pruned %d events\n", n)
+				}
+			}
+		}
+	}()
+}
+
+// wsJournaled: chỉ event đổi state room / tin (replay được), bỏ typing, export, contact, ...
+// rooms_sync là cả list -> không journal
+func wsJournaled(typ string) bool {
+	return strings.HasPrefix(typ, "room_") ||
+		strings.HasPrefix(typ, "room.") ||
+		strings.HasPrefix(typ, "message") ||
+		strings.HasPrefix(typ, "reaction_")
+}
+
+// wsJournalAppend: gán seq cho env (lỗi DB -> gửi không seq, client chỉ mất khả năng resume event này)
+func wsJournalAppend(userIDs []int64, env *wsEnvelope) {
+	if wsJournal == nil || !wsJournaled(env.Type) {
+		return
+	}
+	var payload []byte
+	if env.Data != nil {
+		b, err := json.Marshal(env.Data)
+		if err != nil {
+			return
+		}
+		payload = b
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	seq, err := wsJournal.Append(ctx, env.Type, env.RoomID, payload, userIDs)
+	if err != nil {
+		log.Println("[WS] journal append error:", err)
+		return
+	}
+	env.Seq = seq
+}
+
+// wsResume: chạy trong writer loop trước khi gửi event live
+// - không có last_seq: ws_ready{last_seq} làm mốc cho lần kết nối lại
+// - có last_seq: replay event lỡ rồi ws_ready{last_seq, replayed}
+// - lỡ quá wsReplayMax hoặc event đã bị prune: resync_required rồi ws_ready
+// trả seq đã cover, event live trong sendCh có seq <= giá trị này bị bỏ (tránh gửi trùng)
+func wsResume(userID, lastSeq int64, resume bool, write func(wsEnvelope) error) (int64, error) {
+	if wsJournal == nil {
+		return 0, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	latest, err := wsJournal.Latest(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	ready := func(upTo int64, replayed int) error {
+		return write(wsEnvelope{
+			Type: "ws_ready",
+			Data: map[string]any{"last_seq": upTo, "replayed": replayed},
+		})
+	}
+	if !resume {
+		return latest, ready(latest, 0)
+	}
+
+	oldest, err := wsJournal.Oldest(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var events []wsjournal.Event
+	if lastSeq+1 >= oldest {
+		if events, err = wsJournal.Since(ctx, userID, lastSeq, wsReplayMax+1); err != nil {
+			return 0, err
+		}
+	}
+	if lastSeq+1 < oldest || len(events) > wsReplayMax {
+		if err := write(wsEnvelope{Type: "resync_required"}); err != nil {
+			return 0, err
+		}
+		return latest, ready(latest, 0)
+	}
+
+	upTo := max(lastSeq, latest)
+	for _, ev := range events {
+		env := wsEnvelope{Type: ev.Type, RoomID: ev.RoomID, TS: ev.CreatedAt.UnixMilli(), Seq: ev.Seq}
+		if ev.Payload != nil {
+			env.Data = ev.Payload
+		}
+		if err := write(env); err != nil {
+			return 0, err
+		}
+		upTo = max(upTo, ev.Seq)
+	}
+	return upTo, ready(upTo, len(events))
+}
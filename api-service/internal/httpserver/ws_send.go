@@ -0,0 +1,74 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// {"type":"send_message","room_id":10,"data":{"content":"hi","client_msg_id":"c-1"}}
+// room_id nằm ở envelope như message_ack, data.room_id cũng nhận
+type wsSendMessageData struct {
+	sendMessageRequest
+	RoomID int64 `json:"room_id"`
+}
+
+// handleWSSendMessage: gửi tin qua WS thay cho POST /rooms/send-messages (cùng validate, quota, filter, client_msg_id).
+// chỉ kết nối gửi nhận send_message_ack {client_msg_id, message_id, message} hoặc
+// send_message_error {client_msg_id, status, error}; cả room vẫn nhận message_created như REST.
+// chạy đồng bộ trong reader loop -> tin từ 1 kết nối lưu đúng thứ tự gửi
+func (s *Server) handleWSSendMessage(c *wsClient, in wsInbound) {
+	var d wsSendMessageData
+	if len(in.Data) > 0 {
+		if err := json.Unmarshal(in.Data, &d); err != nil {
+			c.replySendError("", in.RoomID, newFailure(http.StatusBadRequest, CodeInvalidJSON, "invalid json data"))
+			return
+		}
+	}
+	roomID := in.RoomID
+	if roomID == 0 {
+		roomID = d.RoomID
+	}
+	d.ClientMsgID = strings.TrimSpace(d.ClientMsgID)
+
+	if c.isBot {
+		c.replySendError(d.ClientMsgID, roomID, newFailure(http.StatusForbidden, CodeForbidden, "bots send messages via POST /bot/messages"))
+		return
+	}
+	if roomID <= 0 {
+		c.replySendError(d.ClientMsgID, roomID, newFailure(http.StatusBadRequest, CodeInvalidRequest, "invalid room id"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	f := s.sendMessage(ctx, c.userID, roomID, d.sendMessageRequest, func(v any) {
+		data := map[string]any{"client_msg_id": d.ClientMsgID}
+		if msg, ok := v.(sendMessageResponse); ok {
+			data["message_id"] = msg.ID
+			data["message"] = msg
+		} else {
+			// lệnh /remind: không có tin, trả kết quả lệnh như REST
+			data["command"] = v
+		}
+		c.reply(wsEnvelope{Type: "send_message_ack", RoomID: roomID, Data: data})
+	})
+	if f != nil {
+		c.replySendError(d.ClientMsgID, roomID, f)
+	}
+}
+
+func (c *wsClient) replySendError(clientMsgID string, roomID int64, f *apiFailure) {
+	c.reply(wsEnvelope{
+		Type:   "send_message_error",
+		RoomID: roomID,
+		Data: map[string]any{
+			"client_msg_id": clientMsgID,
+			"status":        f.status,
+			"error":         f.payload(),
+		},
+	})
+}
@@ -0,0 +1,375 @@
+package httpserver
+
+import (
+	"cronhustler/api-service/internal/wsjournal"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestVerifyWSAuth(t *testing.T) {
+	const touch = `UPDATE user_sessions SET last_active_at = CURRENT_TIMESTAMP`
+
+	access := accessTokenFor(t, 1)
+	otherSecret, err := GenerateRefreshToken(1, "user", "sess-1", []byte("other-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cookie  string // rỗng = không gửi cookie
+		setup   func(mock sqlmock.Sqlmock)
+		wantErr bool
+	}{
+		{name: "no cookie", wantErr: true},
+		{name: "blank cookie", cookie: " ", wantErr: true},
+		{name: "garbage token", cookie: "not-a-jwt", wantErr: true},
+		{name: "wrong signature", cookie: otherSecret, wantErr: true},
+		{name: "access token instead of refresh", cookie: access, wantErr: true},
+		{
+			name:   "revoked session",
+			cookie: refreshTokenFor(t, 1, "sess-1"),
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(touch).WithArgs(sqlmock.AnyArg(), "sess-1", int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(`SELECT 1 FROM user_sessions`).WithArgs("sess-1", int64(1)).
+					WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: true,
+		},
+		{
+			name:   "active session",
+			cookie: refreshTokenFor(t, 1, "sess-1"),
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(touch).WithArgs(sqlmock.AnyArg(), "sess-1", int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newTestServer(t)
+			if tt.setup != nil {
+				tt.setup(mock)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: RefreshCookieName, Value: tt.cookie})
+			}
+
+			userID, err := s.VerifyWSAuth(req)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("VerifyWSAuth = %d, want error", userID)
+				}
+			} else if err != nil || userID != 1 {
+				t.Fatalf("VerifyWSAuth = %d, %v, want 1", userID, err)
+			}
+			checkExpectations(t, mock)
+		})
+	}
+}
+
+func TestWebSocketRejectsBeforeUpgrade(t *testing.T) {
+	tests := []struct {
+		name        string
+		cookie      bool
+		origin      string
+		subprotocol string // Sec-WebSocket-Protocol
+		setup       func(mock sqlmock.Sqlmock)
+		wantStatus  int
+	}{
+		{name: "no cookie", wantStatus: http.StatusUnauthorized},
+		{name: "foreign origin", cookie: true, origin: "https://evil.test", wantStatus: http.StatusForbidden},
+		{
+			name:        "token subprotocol without cronchat.v1",
+			subprotocol: "bearer." + accessTokenFor(t, 1),
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "refresh token in subprotocol",
+			subprotocol: "cronchat.v1, bearer." + refreshTokenFor(t, 1, "sess-1"),
+			wantStatus:  http.StatusUnauthorized,
+		},
+		{
+			// access token qua subprotocol: không cần cookie / session, vẫn check suspend
+			name:        "access token subprotocol from allowed origin",
+			origin:      "https://app.test",
+			subprotocol: "cronchat.v1, bearer." + accessTokenFor(t, 1),
+			setup: func(mock sqlmock.Sqlmock) {
+				expectSuspended(mock, 1, time.Now().Add(time.Hour))
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "suspended user",
+			cookie: true,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE user_sessions`).WillReturnResult(sqlmock.NewResult(0, 1))
+				expectSuspended(mock, 1, time.Now().Add(time.Hour))
+			},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newTestServer(t)
+			s.appPublicURL = "https://app.test"
+			if tt.setup != nil {
+				tt.setup(mock)
+			}
+			req := newRequest(http.MethodGet, "/ws", "")
+			if tt.cookie {
+				req.AddCookie(&http.Cookie{Name: RefreshCookieName, Value: refreshTokenFor(t, 1, "sess-1")})
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.subprotocol != "" {
+				req.Header.Set("Sec-WebSocket-Protocol", tt.subprotocol)
+			}
+			rec := serveRequest(s, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if body := decodeBody(t, rec); body["code"] == nil {
+				t.Fatalf("error body without code: %v", body)
+			}
+			checkExpectations(t, mock)
+		})
+	}
+}
+
+func TestWSResume(t *testing.T) {
+	const (
+		latest = `SELECT COALESCE\(MAX\(event_id\), 0\) FROM ws_event_recipients WHERE user_id = \?`
+		oldest = `SELECT COALESCE\(MIN\(id\), 0\) FROM ws_events`
+		since  = `FROM ws_event_recipients er JOIN ws_events e`
+	)
+	seqRow := func(v int64) *sqlmock.Rows { return sqlmock.NewRows([]string{"seq"}).AddRow(v) }
+
+	tests := []struct {
+		name      string
+		lastSeq   int64
+		resume    bool
+		setup     func(mock sqlmock.Sqlmock)
+		wantTypes []string
+		wantUpTo  int64
+	}{
+		{
+			name: "fresh connection gets baseline",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(latest).WithArgs(int64(1)).WillReturnRows(seqRow(7))
+			},
+			wantTypes: []string{"ws_ready"},
+			wantUpTo:  7,
+		},
+		{
+			name:    "replays missed events in order",
+			lastSeq: 5,
+			resume:  true,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(latest).WillReturnRows(seqRow(9))
+				mock.ExpectQuery(oldest).WillReturnRows(seqRow(1))
+				mock.ExpectQuery(since).WithArgs(int64(1), int64(5), wsReplayMax+1).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "type", "room_id", "payload", "created_at"}).
+						AddRow(6, "message_created", 10, []byte(`{"message":{"id":100}}`), testNow).
+						AddRow(9, "room.member_removed", 10, nil, testNow))
+			},
+			wantTypes: []string{"message_created", "room.member_removed", "ws_ready"},
+			wantUpTo:  9,
+		},
+		{
+			name:    "pruned events require resync",
+			lastSeq: 10,
+			resume:  true,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(latest).WillReturnRows(seqRow(80))
+				mock.ExpectQuery(oldest).WillReturnRows(seqRow(50))
+			},
+			wantTypes: []string{"resync_required", "ws_ready"},
+			wantUpTo:  80,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			wsJournal = wsjournal.NewRepository(db)
+			t.Cleanup(func() { wsJournal = nil })
+			tt.setup(mock)
+
+			var got []wsEnvelope
+			upTo, err := wsResume(1, tt.lastSeq, tt.resume, func(env wsEnvelope) error {
+				got = append(got, env)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("wsResume: %v", err)
+			}
+			checkExpectations(t, mock)
+			if upTo != tt.wantUpTo {
+				t.Fatalf("upTo = %d, want %d", upTo, tt.wantUpTo)
+			}
+			if len(got) != len(tt.wantTypes) {
+				t.Fatalf("sent %+v, want types %v", got, tt.wantTypes)
+			}
+			for i, env := range got {
+				if env.Type != tt.wantTypes[i] {
+					t.Fatalf("sent[%d] = %s, want %s", i, env.Type, tt.wantTypes[i])
+				}
+			}
+			if tt.resume && got[0].Type == "message_created" && (got[0].Seq != 6 || got[0].RoomID != 10) {
+				t.Fatalf("replayed = %+v", got[0])
+			}
+		})
+	}
+}
+
+func TestWSJournalAppend(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	wsJournal = wsjournal.NewRepository(db)
+	t.Cleanup(func() { wsJournal = nil })
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO ws_events`).WithArgs("message_created", int64(10), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(42, 1))
+	mock.ExpectExec(`INSERT IGNORE INTO ws_event_recipients`).
+		WithArgs(int64(1), int64(42), int64(2), int64(42)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	env := wsEnvelope{Type: "message_created", RoomID: 10, Data: map[string]any{"id": 1}}
+	wsJournalAppend([]int64{1, 2}, &env)
+	if env.Seq != 42 {
+		t.Fatalf("seq = %d, want 42", env.Seq)
+	}
+
+	// event không journal: không chạm DB, không có seq
+	progress := wsEnvelope{Type: "export_progress"}
+	wsJournalAppend([]int64{1}, &progress)
+	if progress.Seq != 0 {
+		t.Fatalf("export_progress seq = %d", progress.Seq)
+	}
+	checkExpectations(t, mock)
+}
+
+func TestWSSendMessage(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		isBot      bool
+		setup      func(mock sqlmock.Sqlmock)
+		wantType   string
+		wantStatus float64
+	}{
+		{
+			name:       "invalid data",
+			raw:        `{"type":"send_message","room_id":10,"data":"hi"}`,
+			wantType:   "send_message_error",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "missing room id",
+			raw:        `{"type":"send_message","data":{"content":"hi","client_msg_id":"c-1"}}`,
+			wantType:   "send_message_error",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "bot connection",
+			raw:        `{"type":"send_message","room_id":10,"data":{"content":"hi","client_msg_id":"c-1"}}`,
+			isBot:      true,
+			wantType:   "send_message_error",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "not a member",
+			raw:  `{"type":"send_message","room_id":10,"data":{"content":"hi","client_msg_id":"c-1"}}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				expectMember(mock, 10, 1, false)
+			},
+			wantType:   "send_message_error",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "empty content",
+			raw:  `{"type":"send_message","data":{"room_id":10,"content":" ","client_msg_id":"c-1"}}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				expectMember(mock, 10, 1, true)
+			},
+			wantType:   "send_message_error",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "ok",
+			raw:  `{"type":"send_message","room_id":10,"data":{"content":" hello ","client_msg_id":"c-1"}}`,
+			setup: func(mock sqlmock.Sqlmock) {
+				expectMember(mock, 10, 1, true)
+				mock.ExpectQuery(`FROM messages WHERE sender_id = \? AND client_msg_id = \?`).WithArgs(int64(1), "c-1").
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT id FROM rooms WHERE id = \? FOR UPDATE`).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+				mock.ExpectQuery(`SELECT 1 FROM messages WHERE room_id = \? AND sender_id = \?`).
+					WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+				args := anyArgs(19)
+				args[0], args[1], args[2], args[7] = int64(10), int64(1), "c-1", "hello"
+				mock.ExpectExec(`INSERT INTO messages`).WithArgs(args...).
+					WillReturnResult(sqlmock.NewResult(100, 1))
+				mock.ExpectCommit()
+			},
+			wantType: "send_message_ack",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newTestServer(t)
+			if tt.setup != nil {
+				tt.setup(mock)
+			}
+			c := &wsClient{sendCh: make(chan wsFrame, 8), userID: 1, isBot: tt.isBot}
+
+			s.handleWSInbound(c, []byte(tt.raw))
+			checkExpectations(t, mock)
+
+			var env struct {
+				Type string         `json:"type"`
+				Data map[string]any `json:"data"`
+			}
+			select {
+			case f := <-c.sendCh:
+				if err := json.Unmarshal(f.b, &env); err != nil {
+					t.Fatal(err)
+				}
+			default:
+				t.Fatal("no reply sent to the connection")
+			}
+			if env.Type != tt.wantType {
+				t.Fatalf("reply = %+v, want %s", env, tt.wantType)
+			}
+			if tt.wantStatus != 0 && env.Data["status"] != tt.wantStatus {
+				t.Fatalf("status = %v, want %v", env.Data["status"], tt.wantStatus)
+			}
+			if tt.wantType == "send_message_ack" && (env.Data["message_id"] != float64(100) || env.Data["client_msg_id"] != "c-1") {
+				t.Fatalf("ack data = %v", env.Data)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package iprule
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ActionAllow = "allow" // khớp allow -> bỏ qua mọi deny cùng scope
+	ActionDeny  = "deny"
+)
+
+const (
+	ScopeAuth = "auth" // chỉ login / đăng ký
+	ScopeAll  = "all"  // mọi request
+)
+
+var (
+	ErrNotFound    = errors.New("iprule: not found")
+	ErrInvalidCIDR = errors.New("iprule: cidr must be an IP or CIDR range (e.g. 10.0.0.0/8)")
+	ErrInvalidRule = errors.New("iprule: action must be allow or deny, scope must be auth or all")
+)
+
+// cache rule đã parse, hết hạn để instance khác sửa vẫn cập nhật
+const cacheTTL = 30 * time.Second
+
+type Rule struct {
+	ID        int64      `json:"id"`
+	CIDR      string     `json:"cidr"`
+	Action    string     `json:"action"`
+	Scope     string     `json:"scope"`
+	Note      string     `json:"note,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // nil = vĩnh viễn
+	CreatedBy int64      `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	net *net.IPNet
+}
+
+// Decision: kết quả kiểm tra 1 IP
+type Decision struct {
+	Blocked bool  `json:"blocked"`
+	Rule    *Rule `json:"rule,omitempty"` // rule quyết định (allow hoặc deny)
+}
+
+type Repository struct {
+	DB *sql.DB
+
+	mu       sync.Mutex
+	rules    []*Rule
+	loadedAt time.Time
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// ParseCIDR: IP đơn -> /32 (/128), trả dạng chuẩn
+func ParseCIDR(v string) (*net.IPNet, error) {
+	v = strings.TrimSpace(v)
+	if !strings.Contains(v, "/") {
+		ip := net.ParseIP(v)
+		if ip == nil {
+			return nil, ErrInvalidCIDR
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+	}
+	_, n, err := net.ParseCIDR(v)
+	if err != nil {
+		return nil, ErrInvalidCIDR
+	}
+	return n, nil
+}
+
+func validScope(s string) bool  { return s == ScopeAuth || s == ScopeAll }
+func validAction(a string) bool { return a == ActionAllow || a == ActionDeny }
+
+const selectRule = `
+	SELECT id, cidr, action, scope, COALESCE(note, ''), expires_at, created_by, created_at
+	FROM ip_rules
+`
+
+func scanRule(sc interface{ Scan(...any) error }) (*Rule, error) {
+	var ru Rule
+	var exp sql.NullTime
+	if err := sc.Scan(&ru.ID, &ru.CIDR, &ru.Action, &ru.Scope, &ru.Note, &exp, &ru.CreatedBy, &ru.CreatedAt); err != nil {
+		return nil, err
+	}
+	if exp.Valid {
+		ru.ExpiresAt = &exp.Time
+	}
+	ru.net, _ = ParseCIDR(ru.CIDR)
+	return &ru, nil
+}
+
+func (r *Repository) List(ctx context.Context) ([]*Rule, error) {
+	rows, err := r.DB.QueryContext(ctx, selectRule+` ORDER BY id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*Rule{}
+	for rows.Next() {
+		ru, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ru)
+	}
+	return out, rows.Err()
+}
+
+func (r *Repository) Get(ctx context.Context, id int64) (*Rule, error) {
+	ru, err := scanRule(r.DB.QueryRowContext(ctx, selectRule+` WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return ru, err
+}
+
+func (r *Repository) Create(ctx context.Context, ru *Rule) error {
+	n, err := ParseCIDR(ru.CIDR)
+	if err != nil {
+		return err
+	}
+	if !validAction(ru.Action) || !validScope(ru.Scope) {
+		return ErrInvalidRule
+	}
+	ru.CIDR = n.String()
+	ru.net = n
+
+	var note any
+	if ru.Note != "" {
+		note = ru.Note
+	}
+	res, err := r.DB.ExecContext(ctx, `
+		INSERT INTO ip_rules (cidr, action, scope, note, expires_at, created_by)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, ru.CIDR, ru.Action, ru.Scope, note, ru.ExpiresAt, ru.CreatedBy)
+	if err != nil {
+		return err
+	}
+	ru.ID, _ = res.LastInsertId()
+	ru.CreatedAt = time.Now()
+	r.invalidate()
+	return nil
+}
+
+func (r *Repository) Delete(ctx context.Context, id int64) error {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM ip_rules WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	r.invalidate()
+	return nil
+}
+
+func (r *Repository) invalidate() {
+	r.mu.Lock()
+	r.rules = nil
+	r.mu.Unlock()
+}
+
+func (r *Repository) load(ctx context.Context) ([]*Rule, error) {
+	r.mu.Lock()
+	rules, at := r.rules, r.loadedAt
+	r.mu.Unlock()
+	if rules != nil && time.Since(at) < cacheTTL {
+		return rules, nil
+	}
+
+	rules, err := r.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	r.rules, r.loadedAt = rules, time.Now()
+	r.mu.Unlock()
+	return rules, nil
+}
+
+// Match: auth = request login / đăng ký (áp dụng cả rule scope auth lẫn all)
+func (r *Repository) Match(ctx context.Context, ipStr string, auth bool, now time.Time) (*Decision, error) {
+	rules, err := r.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Evaluate(rules, ipStr, auth, now), nil
+}
+
+// Evaluate: allow khớp -> cho qua; không thì deny khớp -> chặn
+func Evaluate(rules []*Rule, ipStr string, auth bool, now time.Time) *Decision {
+	ip := net.ParseIP(strings.TrimSpace(ipStr))
+	if ip == nil {
+		return &Decision{}
+	}
+
+	var deny *Rule
+	for _, ru := range rules {
+		if ru.net == nil || (ru.Scope == ScopeAuth && !auth) {
+			continue
+		}
+		if ru.ExpiresAt != nil && !ru.ExpiresAt.After(now) {
+			continue
+		}
+		if !ru.net.Contains(ip) {
+			continue
+		}
+		if ru.Action == ActionAllow {
+			return &Decision{Rule: ru}
+		}
+		if deny == nil {
+			deny = ru
+		}
+	}
+	return &Decision{Blocked: deny != nil, Rule: deny}
+}
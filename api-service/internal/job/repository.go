@@ -0,0 +1,284 @@
+package job
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// trạng thái 1 lần chạy
+const (
+	RunRunning = "running"
+	RunSuccess = "success"
+	RunFailed  = "failed"
+)
+
+// ai kích hoạt lần chạy
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+var ErrNotFound = errors.New("job: not found")
+
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// Job: 1 tác vụ chạy theo cron (action_type quyết định làm gì, payload là tham số)
+type Job struct {
+	ID         int64           `json:"id"`
+	Name       string          `json:"name"`
+	CronSpec   string          `json:"cron"`
+	ActionType string          `json:"action_type"`
+	Payload    json.RawMessage `json:"payload"`
+	Enabled    bool            `json:"enabled"`
+	NextRunAt  *time.Time      `json:"next_run_at,omitempty"`
+	LastRunAt  *time.Time      `json:"last_run_at,omitempty"`
+	LastStatus string          `json:"last_status,omitempty"`
+	CreatedBy  int64           `json:"created_by"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// Run: lịch sử 1 lần chạy
+type Run struct {
+	ID         int64      `json:"id"`
+	JobID      int64      `json:"job_id"`
+	Trigger    string     `json:"trigger"`
+	Status     string     `json:"status"`
+	Instance   string     `json:"instance"`
+	Output     string     `json:"output,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	DurationMs int64      `json:"duration_ms"`
+}
+
+const selectJob = `
+	SELECT id, name, cron_spec, action_type, payload, enabled, next_run_at, last_run_at,
+	       COALESCE(last_status, ''), created_by, created_at, updated_at
+	FROM jobs
+`
+
+func scanJob(sc interface{ Scan(...any) error }) (*Job, error) {
+	var j Job
+	var payload []byte
+	var nextRun, lastRun sql.NullTime
+	if err := sc.Scan(&j.ID, &j.Name, &j.CronSpec, &j.ActionType, &payload, &j.Enabled,
+		&nextRun, &lastRun, &j.LastStatus, &j.CreatedBy, &j.CreatedAt, &j.UpdatedAt); err != nil {
+		return nil, err
+	}
+	j.Payload = json.RawMessage(payload)
+	if len(j.Payload) == 0 {
+		j.Payload = json.RawMessage("{}")
+	}
+	if nextRun.Valid {
+		j.NextRunAt = &nextRun.Time
+	}
+	if lastRun.Valid {
+		j.LastRunAt = &lastRun.Time
+	}
+	return &j, nil
+}
+
+func (r *Repository) Create(ctx context.Context, j *Job) error {
+	res, err := r.DB.ExecContext(ctx, `
+		INSERT INTO jobs (name, cron_spec, action_type, payload, enabled, next_run_at, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, j.Name, j.CronSpec, j.ActionType, []byte(j.Payload), j.Enabled, j.NextRunAt, j.CreatedBy)
+	if err != nil {
+		return err
+	}
+	j.ID, _ = res.LastInsertId()
+	j.CreatedAt = time.Now()
+	j.UpdatedAt = j.CreatedAt
+	return nil
+}
+
+// Update: sửa cấu hình (không đụng lock / lịch sử chạy)
+func (r *Repository) Update(ctx context.Context, j *Job) error {
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE jobs
+		SET name = ?, cron_spec = ?, action_type = ?, payload = ?, enabled = ?, next_run_at = ?
+		WHERE id = ?
+	`, j.Name, j.CronSpec, j.ActionType, []byte(j.Payload), j.Enabled, j.NextRunAt, j.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// MySQL trả 0 khi dữ liệu không đổi -> check lại có tồn tại không
+		if _, err := r.Get(ctx, j.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) Delete(ctx context.Context, id int64) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM jobs WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM job_runs WHERE job_id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *Repository) Get(ctx context.Context, id int64) (*Job, error) {
+	j, err := scanJob(r.DB.QueryRowContext(ctx, selectJob+` WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return j, err
+}
+
+func (r *Repository) List(ctx context.Context) ([]*Job, error) {
+	rows, err := r.DB.QueryContext(ctx, selectJob+` ORDER BY id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*Job{}
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, j)
+	}
+	return out, rows.Err()
+}
+
+// ListDue: job tới giờ và không bị instance khác giữ lock
+func (r *Repository) ListDue(ctx context.Context, now time.Time, limit int) ([]*Job, error) {
+	rows, err := r.DB.QueryContext(ctx, selectJob+`
+		WHERE enabled = 1 AND next_run_at <= ?
+		  AND (locked_until IS NULL OR locked_until < ?)
+		ORDER BY next_run_at ASC, id ASC
+		LIMIT ?
+	`, now, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*Job
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, j)
+	}
+	return out, rows.Err()
+}
+
+// TryLock: giành quyền chạy job (UPDATE có điều kiện nên chỉ 1 instance thắng)
+// lock hết hạn sau ttl để instance chết giữa chừng không giữ job mãi
+func (r *Repository) TryLock(ctx context.Context, id int64, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE jobs SET locked_by = ?, locked_until = ?
+		WHERE id = ? AND (locked_until IS NULL OR locked_until < ?)
+	`, owner, now.Add(ttl), id, now)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+// Unlock: nhả lock + ghi kết quả; next nil = giữ nguyên lịch (chạy tay)
+func (r *Repository) Unlock(ctx context.Context, id int64, owner string, status string, next *time.Time) error {
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE jobs
+		SET locked_by = NULL, locked_until = NULL,
+		    last_run_at = ?, last_status = ?, next_run_at = COALESCE(?, next_run_at)
+		WHERE id = ? AND locked_by = ?
+	`, time.Now(), status, next, id, owner)
+	return err
+}
+
+func (r *Repository) StartRun(ctx context.Context, jobID int64, trigger, instance string) (int64, error) {
+	res, err := r.DB.ExecContext(ctx, `
+		INSERT INTO job_runs (job_id, trigger_type, status, instance, started_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, jobID, trigger, RunRunning, instance, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *Repository) FinishRun(ctx context.Context, runID int64, status, output, errMsg string, d time.Duration) error {
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE job_runs
+		SET status = ?, output = ?, error = ?, finished_at = ?, duration_ms = ?
+		WHERE id = ?
+	`, status, nullIfEmpty(output), nullIfEmpty(errMsg), time.Now(), d.Milliseconds(), runID)
+	return err
+}
+
+// FailStaleRuns: run "running" quá lâu (instance chết giữa chừng) -> failed
+func (r *Repository) FailStaleRuns(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE job_runs SET status = ?, error = 'interrupted', finished_at = ?
+		WHERE status = ? AND started_at < ?
+	`, RunFailed, time.Now(), RunRunning, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r *Repository) ListRuns(ctx context.Context, jobID int64, limit int) ([]*Run, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT id, job_id, trigger_type, status, instance, COALESCE(output, ''), COALESCE(error, ''),
+		       started_at, finished_at, duration_ms
+		FROM job_runs
+		WHERE job_id = ?
+		ORDER BY id DESC
+		LIMIT ?
+	`, jobID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*Run{}
+	for rows.Next() {
+		var run Run
+		var finished sql.NullTime
+		if err := rows.Scan(&run.ID, &run.JobID, &run.Trigger, &run.Status, &run.Instance, &run.Output, &run.Error,
+			&run.StartedAt, &finished, &run.DurationMs); err != nil {
+			return nil, err
+		}
+		if finished.Valid {
+			run.FinishedAt = &finished.Time
+		}
+		out = append(out, &run)
+	}
+	return out, rows.Err()
+}
+
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
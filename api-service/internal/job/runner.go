@@ -0,0 +1,254 @@
+package job
+
+import (
+	"context"
+	"cronhustler/api-service/internal/cron"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownAction = errors.New("job: unknown action_type")
+	ErrLocked        = errors.New("job: already running")
+)
+
+// output lưu vào job_runs tối đa bấy nhiêu byte
+const maxOutput = 4000
+
+// Action: 1 loại việc job làm được
+type Action struct {
+	Run      func(ctx context.Context, j *Job) (string, error)
+	Validate func(payload json.RawMessage) error // optional, gọi khi tạo / sửa job
+}
+
+// Runner: poll job tới hạn, giành lock trong DB rồi chạy (nhiều instance chạy song song vẫn an toàn)
+type Runner struct {
+	Repo       *Repository
+	InstanceID string
+	Timeout    time.Duration // thời gian tối đa 1 lần chạy
+
+	mu      sync.RWMutex
+	actions map[string]Action
+}
+
+func NewRunner(repo *Repository) *Runner {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+
+	return &Runner{
+		Repo:       repo,
+		InstanceID: fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b)),
+		Timeout:    5 * time.Minute,
+		actions:    map[string]Action{},
+	}
+}
+
+func (r *Runner) Register(name string, a Action) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.actions[name] = a
+}
+
+func (r *Runner) action(name string) (Action, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	a, ok := r.actions[name]
+	return a, ok
+}
+
+// Actions: danh sách action_type đã đăng ký
+func (r *Runner) Actions() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]string, 0, len(r.actions))
+	for k := range r.actions {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Validate: check action_type + payload + cron, trả lần chạy kế tiếp
+func (r *Runner) Validate(j *Job, now time.Time) (time.Time, error) {
+	a, ok := r.action(j.ActionType)
+	if !ok {
+		return time.Time{}, ErrUnknownAction
+	}
+	if a.Validate != nil {
+		if err := a.Validate(j.Payload); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return NextRun(j.CronSpec, now)
+}
+
+func NextRun(spec string, now time.Time) (time.Time, error) {
+	sched, err := cron.Parse(spec)
+	if err != nil {
+		return time.Time{}, err
+	}
+	next := sched.Next(now)
+	if next.IsZero() {
+		return time.Time{}, cron.ErrInvalidSpec
+	}
+	return next, nil
+}
+
+func (r *Runner) lockTTL() time.Duration {
+	return r.Timeout + time.Minute
+}
+
+// Start: chạy vòng poll cho tới khi ctx bị cancel
+func (r *Runner) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			r.runDue(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (r *Runner) runDue(ctx context.Context) {
+	now := time.Now()
+
+	if n, err := r.Repo.FailStaleRuns(ctx, now.Add(-r.lockTTL())); err != nil {
+		log.Println("[job] FailStaleRuns error:", err)
+	} else if n > 0 {
+		log.Printf("[job] marked %d stale run(s) as failed", n)
+	}
+
+	list, err := r.Repo.ListDue(ctx, now, 20)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Println("[job] ListDue error:", err)
+		}
+		return
+	}
+
+	for _, j := range list {
+		ok, err := r.Repo.TryLock(ctx, j.ID, r.InstanceID, r.lockTTL())
+		if err != nil {
+			log.Printf("[job] TryLock id=%d: %v", j.ID, err)
+			continue
+		}
+		if !ok {
+			continue // instance khác đã lấy
+		}
+		go r.execute(ctx, j, TriggerSchedule, 0)
+	}
+}
+
+// RunNow: chạy ngay ngoài lịch, trả run id (job đang chạy -> ErrLocked)
+func (r *Runner) RunNow(ctx context.Context, id int64) (int64, error) {
+	j, err := r.Repo.Get(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := r.action(j.ActionType); !ok {
+		return 0, ErrUnknownAction
+	}
+
+	ok, err := r.Repo.TryLock(ctx, j.ID, r.InstanceID, r.lockTTL())
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, ErrLocked
+	}
+
+	runID, err := r.Repo.StartRun(ctx, j.ID, TriggerManual, r.InstanceID)
+	if err != nil {
+		_ = r.Repo.Unlock(ctx, j.ID, r.InstanceID, RunFailed, nil)
+		return 0, err
+	}
+
+	// không dùng ctx của request: request xong job vẫn chạy tiếp
+	go r.execute(context.Background(), j, TriggerManual, runID)
+	return runID, nil
+}
+
+// execute: chạy action của job đã giữ lock, ghi lịch sử rồi nhả lock
+func (r *Runner) execute(ctx context.Context, j *Job, trigger string, runID int64) {
+	if runID == 0 {
+		id, err := r.Repo.StartRun(ctx, j.ID, trigger, r.InstanceID)
+		if err != nil {
+			log.Printf("[job] StartRun id=%d: %v", j.ID, err)
+			_ = r.Repo.Unlock(ctx, j.ID, r.InstanceID, RunFailed, r.nextAfter(j, trigger))
+			return
+		}
+		runID = id
+	}
+
+	start := time.Now()
+	output, err := r.runAction(ctx, j)
+	status := RunSuccess
+	errMsg := ""
+	if err != nil {
+		status = RunFailed
+		errMsg = err.Error()
+		log.Printf("[job] %q (id=%d) failed: %v", j.Name, j.ID, err)
+	}
+	if len(output) > maxOutput {
+		output = output[:maxOutput]
+	}
+
+	// ghi kết quả bằng context riêng để vẫn lưu được khi ctx chính bị cancel
+	saveCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := r.Repo.FinishRun(saveCtx, runID, status, output, errMsg, time.Since(start)); err != nil {
+		log.Printf("[job] FinishRun run=%d: %v", runID, err)
+	}
+	if err := r.Repo.Unlock(saveCtx, j.ID, r.InstanceID, status, r.nextAfter(j, trigger)); err != nil {
+		log.Printf("[job] Unlock id=%d: %v", j.ID, err)
+	}
+}
+
+func (r *Runner) runAction(ctx context.Context, j *Job) (out string, err error) {
+	a, ok := r.action(j.ActionType)
+	if !ok {
+		return "", ErrUnknownAction
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	// action panic không được làm chết cả server
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return a.Run(ctx, j)
+}
+
+// nextAfter: chạy theo lịch thì tính lần kế tiếp, chạy tay thì giữ nguyên
+func (r *Runner) nextAfter(j *Job, trigger string) *time.Time {
+	if trigger != TriggerSchedule {
+		return nil
+	}
+	next, err := NextRun(j.CronSpec, time.Now())
+	if err != nil {
+		// spec hỏng (sửa tay trong DB?) -> đẩy xa 1 ngày để không chạy liên tục
+		log.Printf("[job] id=%d invalid cron %q: %v", j.ID, j.CronSpec, err)
+		next = time.Now().Add(24 * time.Hour)
+	}
+	return &next
+}
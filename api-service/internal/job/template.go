@@ -0,0 +1,53 @@
+package job
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+var weekdayVN = [...]string{"Chủ nhật", "Thứ hai", "Thứ ba", "Thứ tư", "Thứ năm", "Thứ sáu", "Thứ bảy"}
+
+// TemplateVars: biến dùng được trong template, {{tên}}
+var TemplateVars = []string{"date", "date_vn", "time", "weekday", "weekday_vn", "week", "month", "year", "room", "mentions"}
+
+// Render: thay {{biến}} theo thời điểm now (đã đổi sang timezone mong muốn) + extra (room, mentions, ...)
+// biến không biết giữ nguyên để người viết template thấy lỗi
+func Render(tpl string, now time.Time, extra map[string]string) string {
+	_, week := now.ISOWeek()
+	vars := map[string]string{
+		"date":       now.Format("2006-01-02"),
+		"date_vn":    now.Format("02/01/2006"),
+		"time":       now.Format("15:04"),
+		"weekday":    now.Weekday().String(),
+		"weekday_vn": weekdayVN[now.Weekday()],
+		"week":       strconv.Itoa(week),
+		"month":      strconv.Itoa(int(now.Month())),
+		"year":       strconv.Itoa(now.Year()),
+	}
+	for k, v := range extra {
+		vars[k] = v
+	}
+
+	var b strings.Builder
+	for {
+		i := strings.Index(tpl, "{{")
+		if i < 0 {
+			break
+		}
+		j := strings.Index(tpl[i:], "}}")
+		if j < 0 {
+			break
+		}
+		name := strings.TrimSpace(tpl[i+2 : i+j])
+		b.WriteString(tpl[:i])
+		if v, ok := vars[name]; ok {
+			b.WriteString(v)
+		} else {
+			b.WriteString(tpl[i : i+j+2])
+		}
+		tpl = tpl[i+j+2:]
+	}
+	b.WriteString(tpl)
+	return b.String()
+}
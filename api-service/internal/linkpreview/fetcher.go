@@ -0,0 +1,317 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	DefaultTimeout = 5 * time.Second
+
+	maxBodyBytes   = 512 << 10 // OG nằm trong <head>, không cần đọc hết trang
+	maxRedirects   = 5
+	maxURLLen      = 2048
+	maxTitleLen    = 300
+	maxDescLen     = 1000
+	failureTTL     = 10 * time.Minute // URL lỗi thì không fetch lại trong khoảng này
+	maxFailureKeys = 1000
+)
+
+var (
+	ErrInvalidURL      = errors.New("linkpreview: invalid url")
+	ErrBlockedAddress  = errors.New("linkpreview: blocked address")
+	ErrNotHTML         = errors.New("linkpreview: not an html page")
+	ErrNoMetadata      = errors.New("linkpreview: no metadata")
+	ErrRecentlyFailed  = errors.New("linkpreview: recently failed")
+	errTooManyRedirect = errors.New("linkpreview: too many redirects")
+)
+
+// Preview: metadata OG của 1 link
+type Preview struct {
+	URL         string    `json:"url"`
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	ImageURL    string    `json:"image_url,omitempty"`
+	SiteName    string    `json:"site_name,omitempty"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// dải IP không được gọi tới (ngoài private / loopback / link-local)
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // CGNAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64 -> có thể trỏ vào IPv4 nội bộ
+}
+
+// Fetcher: GET trang, đọc OG / twitter card / <title>. Chặn SSRF ở tầng dial
+// nên redirect hay DNS rebinding sang IP nội bộ cũng bị chặn
+type Fetcher struct {
+	Client *http.Client
+
+	mu       sync.Mutex
+	failures map[string]time.Time
+}
+
+func NewFetcher(timeout time.Duration) *Fetcher {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Fetcher{
+		Client: &http.Client{
+			Timeout:   timeout,
+			Transport: NewSafeTransport(timeout, "80", "443"),
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return errTooManyRedirect
+				}
+				return validateURL(req.URL)
+			},
+		},
+		failures: map[string]time.Time{},
+	}
+}
+
+// NewSafeTransport: chỉ dial tới IP public (check sau khi resolve, mỗi kết nối) -> chặn cả redirect
+// lẫn DNS rebinding vào mạng nội bộ. ports rỗng = mọi port. Webhook / job http_request dùng chung
+func NewSafeTransport(timeout time.Duration, ports ...string) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			return checkDialAddress(address, ports)
+		},
+	}
+	return &http.Transport{
+		Proxy:                 nil, // proxy sẽ bỏ qua check IP ở dial
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+}
+
+// checkDialAddress: chỉ cho IP public, port trong ports (rỗng = mọi port)
+func checkDialAddress(address string, ports []string) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return ErrBlockedAddress
+	}
+	if len(ports) > 0 && !slices.Contains(ports, port) {
+		return ErrBlockedAddress
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return ErrBlockedAddress
+	}
+	if !IsPublicIP(ip) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+// IsPublicIP: false với loopback, private, link-local, multicast, CGNAT...
+func IsPublicIP(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || !ip.IsGlobalUnicast() || ip.IsPrivate() || ip.IsLoopback() ||
+		ip.IsLinkLocalUnicast() || ip.IsUnspecified() || ip.IsMulticast() {
+		return false
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func validateURL(u *url.URL) error {
+	if u == nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return ErrInvalidURL
+	}
+	if p := u.Port(); p != "" && p != "80" && p != "443" {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"'\x60]+`)
+
+// ExtractURL: link http(s) đầu tiên trong tin nhắn ("" = không có)
+func ExtractURL(text string) string {
+	for _, raw := range urlPattern.FindAllString(text, -1) {
+		// bỏ dấu câu dính cuối link ("xem https://a.com/x.")
+		raw = strings.TrimRight(raw, ".,;:!?)]}")
+		if len(raw) > maxURLLen {
+			continue
+		}
+		if u, err := url.Parse(raw); err == nil && validateURL(u) == nil {
+			return raw
+		}
+	}
+	return ""
+}
+
+// Fetch: lấy preview cho rawURL, URL lỗi gần đây -> ErrRecentlyFailed (không gọi lại)
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Preview, error) {
+	if f.recentlyFailed(rawURL) {
+		return nil, ErrRecentlyFailed
+	}
+	p, err := f.fetch(ctx, rawURL)
+	if err != nil && ctx.Err() == nil {
+		f.markFailed(rawURL)
+	}
+	return p, err
+}
+
+func (f *Fetcher) fetch(ctx context.Context, rawURL string) (*Preview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, ErrInvalidURL
+	}
+	if err := validateURL(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "CronChat-LinkPreview/1 (+bot)")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("linkpreview: unexpected status %d", resp.StatusCode)
+	}
+	mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mt != "text/html" && mt != "application/xhtml+xml" {
+		return nil, ErrNotHTML
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	p := parseHTML(string(body), resp.Request.URL)
+	if p.Title == "" && p.Description == "" && p.ImageURL == "" {
+		return nil, ErrNoMetadata
+	}
+	p.URL = rawURL
+	p.FetchedAt = time.Now().UTC()
+	return p, nil
+}
+
+func (f *Fetcher) recentlyFailed(rawURL string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t, ok := f.failures[rawURL]
+	if ok && time.Since(t) > failureTTL {
+		delete(f.failures, rawURL)
+		return false
+	}
+	return ok
+}
+
+func (f *Fetcher) markFailed(rawURL string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.failures) >= maxFailureKeys {
+		f.failures = map[string]time.Time{}
+	}
+	f.failures[rawURL] = time.Now()
+}
+
+var (
+	metaTagPattern = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	attrPattern    = regexp.MustCompile(`(?is)([a-z_:-]+)\s*=\s*("[^"]*"|'[^']*'|[^\s>]+)`)
+	titlePattern   = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+)
+
+// parseHTML: og:* > twitter:* > <meta name=description> / <title>
+func parseHTML(doc string, base *url.URL) *Preview {
+	if !utf8.ValidString(doc) {
+		doc = strings.ToValidUTF8(doc, "")
+	}
+
+	meta := map[string]string{}
+	for _, tag := range metaTagPattern.FindAllString(doc, -1) {
+		attrs := map[string]string{}
+		for _, m := range attrPattern.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(m[1])] = strings.Trim(m[2], `"'`)
+		}
+		key := attrs["property"]
+		if key == "" {
+			key = attrs["name"]
+		}
+		key = strings.ToLower(key)
+		if key == "" || attrs["content"] == "" {
+			continue
+		}
+		if _, ok := meta[key]; !ok {
+			meta[key] = html.UnescapeString(attrs["content"])
+		}
+	}
+
+	first := func(keys ...string) string {
+		for _, k := range keys {
+			if v := strings.TrimSpace(meta[k]); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	p := &Preview{
+		Title:       first("og:title", "twitter:title"),
+		Description: first("og:description", "twitter:description", "description"),
+		SiteName:    first("og:site_name"),
+	}
+	if p.Title == "" {
+		if m := titlePattern.FindStringSubmatch(doc); m != nil {
+			p.Title = strings.Join(strings.Fields(html.UnescapeString(m[1])), " ")
+		}
+	}
+	if p.SiteName == "" && base != nil {
+		p.SiteName = strings.TrimPrefix(base.Hostname(), "www.")
+	}
+	if img := first("og:image:secure_url", "og:image", "twitter:image", "twitter:image:src"); img != "" && base != nil {
+		if u, err := base.Parse(img); err == nil && (u.Scheme == "http" || u.Scheme == "https") && len(u.String()) <= maxURLLen {
+			p.ImageURL = u.String()
+		}
+	}
+
+	p.Title = truncate(p.Title, maxTitleLen)
+	p.Description = truncate(p.Description, maxDescLen)
+	p.SiteName = truncate(p.SiteName, 255)
+	return p
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
@@ -0,0 +1,92 @@
+package linkpreview
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// preview cùng URL fetch trong khoảng này thì dùng lại, không gọi ra ngoài
+const CacheTTL = 24 * time.Hour
+
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+func urlHash(u string) string {
+	sum := sha256.Sum256([]byte(u))
+	return hex.EncodeToString(sum[:])
+}
+
+// Cached: preview gần nhất của URL còn trong CacheTTL (nil = chưa có)
+func (r *Repository) Cached(ctx context.Context, rawURL string) (*Preview, error) {
+	var p Preview
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT url, COALESCE(title, ''), COALESCE(description, ''), COALESCE(image_url, ''), COALESCE(site_name, ''), fetched_at
+		FROM message_link_previews
+		WHERE url_hash = ? AND fetched_at > ?
+		ORDER BY fetched_at DESC
+		LIMIT 1
+	`, urlHash(rawURL), time.Now().UTC().Add(-CacheTTL)).Scan(
+		&p.URL, &p.Title, &p.Description, &p.ImageURL, &p.SiteName, &p.FetchedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Save: 1 preview / message (message bị xoá -> cascade)
+func (r *Repository) Save(ctx context.Context, messageID int64, p *Preview) error {
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT INTO message_link_previews (message_id, url, url_hash, title, description, image_url, site_name, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			url = VALUES(url), url_hash = VALUES(url_hash), title = VALUES(title),
+			description = VALUES(description), image_url = VALUES(image_url),
+			site_name = VALUES(site_name), fetched_at = VALUES(fetched_at)
+	`, messageID, p.URL, urlHash(p.URL), p.Title, p.Description, p.ImageURL, p.SiteName, p.FetchedAt)
+	return err
+}
+
+// ListByMessageIDs: map[messageID]preview (dùng khi load list message)
+func (r *Repository) ListByMessageIDs(ctx context.Context, ids []int64) (map[int64]*Preview, error) {
+	out := map[int64]*Preview{}
+	if len(ids) == 0 {
+		return out, nil
+	}
+
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT message_id, url, COALESCE(title, ''), COALESCE(description, ''), COALESCE(image_url, ''), COALESCE(site_name, ''), fetched_at
+		FROM message_link_previews
+		WHERE message_id IN (`+strings.Repeat("?,", len(ids)-1)+`?)
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var p Preview
+		if err := rows.Scan(&id, &p.URL, &p.Title, &p.Description, &p.ImageURL, &p.SiteName, &p.FetchedAt); err != nil {
+			return nil, err
+		}
+		out[id] = &p
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,474 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	mrand "math/rand"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Config: 1 lần chạy tải vào server đích
+type Config struct {
+	Target      string        // http(s)://host:port của API
+	Users       []Credential  // mỗi credential = 1 user ảo
+	Duration    time.Duration // tổng thời gian gửi tin
+	RampUp      time.Duration // dàn đều thời điểm user ảo bắt đầu
+	SendRate    float64       // tin / giây / user (khoảng cách giữa 2 tin theo phân phối mũ)
+	SeenEvery   time.Duration // mỗi user mark seen các room có tin mới theo chu kỳ này
+	UnreadEvery time.Duration // poll /rooms/unread-counts, 0 = tắt
+	Timeout     time.Duration // timeout mỗi HTTP request
+}
+
+type Credential struct {
+	Username string
+	Password string
+}
+
+func (c *Config) Validate() error {
+	u, err := url.Parse(c.Target)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return errors.New("target must be http(s)://host[:port]")
+	}
+	switch {
+	case len(c.Users) == 0:
+		return errors.New("no users")
+	case c.Duration <= 0:
+		return errors.New("duration must be > 0")
+	case c.SendRate <= 0:
+		return errors.New("send rate must be > 0")
+	case c.SeenEvery <= 0:
+		return errors.New("seen interval must be > 0")
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 10 * time.Second
+	}
+	c.Target = strings.TrimRight(c.Target, "/")
+	return nil
+}
+
+// tên thao tác trong report
+const (
+	OpLogin     = "login"
+	OpRooms     = "rooms"
+	OpWSConnect = "ws_connect"
+	OpSend      = "send"
+	OpWSFanout  = "ws_fanout" // từ lúc gọi send tới lúc 1 member nhận message_created
+	OpSeen      = "seen"
+	OpUnread    = "unread_counts"
+	OpRefresh   = "refresh"
+)
+
+type Report struct {
+	RunID     string     `json:"run_id"`
+	Target    string     `json:"target"`
+	StartedAt time.Time  `json:"started_at"`
+	Elapsed   string     `json:"elapsed"`
+	Users     int        `json:"users"`
+	Connected int64      `json:"connected"` // user ảo mở được WS
+	Sent      int64      `json:"sent"`
+	Delivered int64      `json:"delivered"` // message_created của lần chạy này nhận qua WS
+	SendRPS   float64    `json:"send_rps"`
+	Ops       []OpReport `json:"ops"`
+}
+
+type runner struct {
+	cfg       Config
+	runID     string
+	rec       *Recorder
+	sent      atomic.Int64
+	delivered atomic.Int64
+	connected atomic.Int64
+}
+
+// Run: login -> mở WS -> gửi tin / mark seen / poll unread tới hết Duration (hoặc ctx huỷ)
+func Run(ctx context.Context, cfg Config) (*Report, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	r := &runner{cfg: cfg, runID: hex.EncodeToString(b), rec: NewRecorder()}
+
+	started := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, cfg.RampUp+cfg.Duration)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i, cred := range cfg.Users {
+		delay := time.Duration(0)
+		if len(cfg.Users) > 1 {
+			delay = cfg.RampUp * time.Duration(i) / time.Duration(len(cfg.Users)-1)
+		}
+		wg.Add(1)
+		go func(i int, cred Credential) {
+			defer wg.Done()
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return
+			}
+			v := newVU(r, cred, int64(i))
+			v.run(ctx)
+		}(i, cred)
+	}
+	wg.Wait()
+
+	elapsed := time.Since(started)
+	rep := &Report{
+		RunID:     r.runID,
+		Target:    cfg.Target,
+		StartedAt: started,
+		Elapsed:   elapsed.Round(time.Millisecond).String(),
+		Users:     len(cfg.Users),
+		Connected: r.connected.Load(),
+		Sent:      r.sent.Load(),
+		Delivered: r.delivered.Load(),
+		Ops:       r.rec.Snapshot(),
+	}
+	if s := elapsed.Seconds(); s > 0 {
+		rep.SendRPS = float64(rep.Sent) / s
+	}
+	return rep, nil
+}
+
+// ===== user ảo =====
+
+type vu struct {
+	r      *runner
+	cred   Credential
+	client *http.Client
+	rng    *mrand.Rand
+	token  string
+	rooms  []int64
+
+	mu       sync.Mutex
+	newest   map[int64]int64 // room -> id tin mới nhất thấy qua WS
+	lastSeen map[int64]int64 // room -> id đã mark seen
+}
+
+func newVU(r *runner, cred Credential, n int64) *vu {
+	jar, _ := cookiejar.New(nil)
+	return &vu{
+		r:        r,
+		cred:     cred,
+		client:   &http.Client{Jar: jar, Timeout: r.cfg.Timeout},
+		rng:      mrand.New(mrand.NewSource(time.Now().UnixNano() + n)),
+		newest:   map[int64]int64{},
+		lastSeen: map[int64]int64{},
+	}
+}
+
+// marker trong content: "loadtest <runID> #<seq> t=<unix nano lúc gửi>"
+func (v *vu) marker(seq int64) string {
+	return fmt.Sprintf("loadtest %s #%d t=%d", v.r.runID, seq, time.Now().UnixNano())
+}
+
+func (v *vu) parseMarker(content string) (time.Time, bool) {
+	prefix := "loadtest " + v.r.runID + " "
+	if !strings.HasPrefix(content, prefix) {
+		return time.Time{}, false
+	}
+	i := strings.LastIndex(content, " t=")
+	if i < 0 {
+		return time.Time{}, false
+	}
+	ns, err := strconv.ParseInt(content[i+3:], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, ns), true
+}
+
+func (v *vu) run(ctx context.Context) {
+	if err := v.login(ctx); err != nil {
+		return
+	}
+	if err := v.loadRooms(ctx); err != nil || len(v.rooms) == 0 {
+		if err == nil {
+			v.r.rec.Fail(OpRooms, "user has no rooms")
+		}
+		return
+	}
+
+	conn, err := v.dialWS(ctx)
+	if err != nil {
+		return
+	}
+	v.r.connected.Add(1)
+	done := make(chan struct{})
+	go v.readWS(conn, done)
+	defer func() {
+		_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		_ = conn.Close()
+		<-done
+	}()
+
+	seenTick := time.NewTicker(v.r.cfg.SeenEvery)
+	defer seenTick.Stop()
+	var unreadC <-chan time.Time
+	if v.r.cfg.UnreadEvery > 0 {
+		t := time.NewTicker(v.r.cfg.UnreadEvery)
+		defer t.Stop()
+		unreadC = t.C
+	}
+
+	sendTimer := time.NewTimer(v.nextSend())
+	defer sendTimer.Stop()
+	var seq int64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-sendTimer.C:
+			seq++
+			v.send(ctx, seq)
+			sendTimer.Reset(v.nextSend())
+		case <-seenTick.C:
+			v.markSeen(ctx)
+		case <-unreadC:
+			v.pollUnread(ctx)
+		}
+	}
+}
+
+func (v *vu) nextSend() time.Duration {
+	return time.Duration(v.rng.ExpFloat64() / v.r.cfg.SendRate * float64(time.Second))
+}
+
+// do: gửi request có access token, 401 -> refresh 1 lần rồi thử lại
+func (v *vu) do(ctx context.Context, op, method, path string, body any, out any) error {
+	for attempt := 0; ; attempt++ {
+		status, err := v.doOnce(ctx, op, method, path, body, out)
+		if status == http.StatusUnauthorized && attempt == 0 && v.token != "" {
+			if v.refresh(ctx) == nil {
+				continue
+			}
+		}
+		return err
+	}
+}
+
+func (v *vu) doOnce(ctx context.Context, op, method, path string, body, out any) (int, error) {
+	var rd io.Reader
+	if body != nil {
+		b, _ := json.Marshal(body)
+		rd = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, v.r.cfg.Target+path, rd)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "CronChat-Loadtest/1")
+	if v.token != "" {
+		req.Header.Set("Authorization", "Bearer "+v.token)
+	}
+
+	start := time.Now()
+	resp, err := v.client.Do(req)
+	if err != nil {
+		if ctx.Err() == nil {
+			v.r.rec.Fail(op, errReason(err))
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	elapsed := time.Since(start)
+	if err != nil {
+		v.r.rec.Fail(op, errReason(err))
+		return resp.StatusCode, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		v.r.rec.Fail(op, "HTTP "+strconv.Itoa(resp.StatusCode))
+		return resp.StatusCode, fmt.Errorf("%s %s: HTTP %d", method, path, resp.StatusCode)
+	}
+	v.r.rec.Observe(op, elapsed)
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			v.r.rec.Fail(op, "invalid json")
+			return resp.StatusCode, err
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// errReason: gộp lỗi mạng theo loại (timeout / refused / ...)
+func errReason(err error) string {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "Client.Timeout") || strings.Contains(msg, "deadline exceeded"):
+		return "timeout"
+	case strings.Contains(msg, "connection refused"):
+		return "connection refused"
+	case strings.Contains(msg, "connection reset"):
+		return "connection reset"
+	}
+	if i := strings.LastIndex(msg, ": "); i >= 0 {
+		return msg[i+2:]
+	}
+	return msg
+}
+
+func (v *vu) login(ctx context.Context) error {
+	var resp struct {
+		AccessToken string `json:"accessToken"`
+	}
+	if err := v.do(ctx, OpLogin, http.MethodPost, "/login", map[string]string{
+		"username": v.cred.Username,
+		"password": v.cred.Password,
+	}, &resp); err != nil {
+		return err
+	}
+	if resp.AccessToken == "" {
+		// bật 2FA -> không test được bằng mật khẩu
+		v.r.rec.Fail(OpLogin, "no access token (2FA?)")
+		return errors.New("no access token")
+	}
+	v.token = resp.AccessToken
+	return nil
+}
+
+func (v *vu) refresh(ctx context.Context) error {
+	var resp struct {
+		AccessToken string `json:"accessToken"`
+	}
+	token := v.token
+	v.token = ""
+	if _, err := v.doOnce(ctx, OpRefresh, http.MethodPost, "/auth/refresh", nil, &resp); err != nil || resp.AccessToken == "" {
+		v.token = token
+		return errors.New("refresh failed")
+	}
+	v.token = resp.AccessToken
+	return nil
+}
+
+func (v *vu) loadRooms(ctx context.Context) error {
+	var resp struct {
+		Rooms []struct {
+			ID int64 `json:"id"`
+		} `json:"rooms"`
+	}
+	if err := v.do(ctx, OpRooms, http.MethodGet, "/rooms", nil, &resp); err != nil {
+		return err
+	}
+	for _, rm := range resp.Rooms {
+		v.rooms = append(v.rooms, rm.ID)
+	}
+	return nil
+}
+
+// dialWS: /ws xác thực bằng cookie refresh_token (cookie jar sau login)
+func (v *vu) dialWS(ctx context.Context) (*websocket.Conn, error) {
+	u, _ := url.Parse(v.r.cfg.Target + "/ws")
+	if u.Scheme == "https" {
+		u.Scheme = "wss"
+	} else {
+		u.Scheme = "ws"
+	}
+	d := websocket.Dialer{Jar: v.client.Jar, HandshakeTimeout: v.r.cfg.Timeout}
+
+	start := time.Now()
+	conn, resp, err := d.DialContext(ctx, u.String(), nil)
+	if err != nil {
+		reason := errReason(err)
+		if resp != nil {
+			reason = "HTTP " + strconv.Itoa(resp.StatusCode)
+		}
+		v.r.rec.Fail(OpWSConnect, reason)
+		return nil, err
+	}
+	v.r.rec.Observe(OpWSConnect, time.Since(start))
+	return conn, nil
+}
+
+func (v *vu) readWS(conn *websocket.Conn, done chan<- struct{}) {
+	defer close(done)
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var env struct {
+			Type   string `json:"type"`
+			RoomID int64  `json:"room_id"`
+			Data   struct {
+				Message struct {
+					ID      int64  `json:"id"`
+					Content string `json:"content"`
+				} `json:"message"`
+			} `json:"data"`
+		}
+		if json.Unmarshal(data, &env) != nil || env.Type != "message_created" {
+			continue
+		}
+		m := env.Data.Message
+		if sentAt, ok := v.parseMarker(m.Content); ok {
+			v.r.rec.Observe(OpWSFanout, time.Since(sentAt))
+			v.r.delivered.Add(1)
+		}
+		if env.RoomID > 0 && m.ID > 0 {
+			v.mu.Lock()
+			v.newest[env.RoomID] = max(v.newest[env.RoomID], m.ID)
+			v.mu.Unlock()
+		}
+	}
+}
+
+func (v *vu) send(ctx context.Context, seq int64) {
+	roomID := v.rooms[v.rng.Intn(len(v.rooms))]
+	err := v.do(ctx, OpSend, http.MethodPost, "/rooms/send-messages/"+strconv.FormatInt(roomID, 10), map[string]any{
+		"content":      v.marker(seq),
+		"message_type": "text",
+	}, nil)
+	if err == nil {
+		v.r.sent.Add(1)
+	}
+}
+
+// markSeen: giống FE, chỉ gọi cho room có tin mới kể từ lần trước
+func (v *vu) markSeen(ctx context.Context) {
+	v.mu.Lock()
+	pending := map[int64]int64{}
+	for roomID, id := range v.newest {
+		if id > v.lastSeen[roomID] {
+			pending[roomID] = id
+		}
+	}
+	v.mu.Unlock()
+
+	for roomID, id := range pending {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := v.do(ctx, OpSeen, http.MethodPost, "/rooms/seen", map[string]int64{
+			"room_id":          roomID,
+			"up_to_message_id": id,
+		}, nil); err == nil {
+			v.mu.Lock()
+			v.lastSeen[roomID] = id
+			v.mu.Unlock()
+		}
+	}
+}
+
+func (v *vu) pollUnread(ctx context.Context) {
+	_ = v.do(ctx, OpUnread, http.MethodGet, "/rooms/unread-counts", nil, nil)
+}
@@ -0,0 +1,129 @@
+package loadtest
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Recorder: gom latency + lỗi theo từng thao tác (login, ws_connect, send, ...)
+type Recorder struct {
+	mu   sync.Mutex
+	ops  map[string]*opStats
+	keys []string
+}
+
+type opStats struct {
+	samples []time.Duration
+	errors  map[string]int
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{ops: map[string]*opStats{}}
+}
+
+func (r *Recorder) op(name string) *opStats {
+	s := r.ops[name]
+	if s == nil {
+		s = &opStats{errors: map[string]int{}}
+		r.ops[name] = s
+		r.keys = append(r.keys, name)
+	}
+	return s
+}
+
+func (r *Recorder) Observe(name string, d time.Duration) {
+	r.mu.Lock()
+	r.op(name).samples = append(r.op(name).samples, d)
+	r.mu.Unlock()
+}
+
+// Fail: reason ngắn gọn (status code / loại lỗi) để gộp
+func (r *Recorder) Fail(name, reason string) {
+	r.mu.Lock()
+	r.op(name).errors[reason]++
+	r.mu.Unlock()
+}
+
+// OpReport: thống kê 1 thao tác, latency tính bằng ms
+type OpReport struct {
+	Name   string         `json:"name"`
+	Count  int            `json:"count"`
+	Errors map[string]int `json:"errors,omitempty"`
+	P50    float64        `json:"p50_ms"`
+	P90    float64        `json:"p90_ms"`
+	P95    float64        `json:"p95_ms"`
+	P99    float64        `json:"p99_ms"`
+	Max    float64        `json:"max_ms"`
+	Mean   float64        `json:"mean_ms"`
+}
+
+func (o OpReport) ErrorCount() int {
+	n := 0
+	for _, c := range o.Errors {
+		n += c
+	}
+	return n
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// percentile: nearest-rank trên slice đã sort
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(p/100*float64(len(sorted))+0.5) - 1
+	return sorted[min(max(idx, 0), len(sorted)-1)]
+}
+
+func (r *Recorder) Snapshot() []OpReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]OpReport, 0, len(r.keys))
+	for _, name := range r.keys {
+		s := r.ops[name]
+		sorted := append([]time.Duration(nil), s.samples...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+		rep := OpReport{Name: name, Count: len(sorted)}
+		if len(s.errors) > 0 {
+			rep.Errors = map[string]int{}
+			for k, v := range s.errors {
+				rep.Errors[k] = v
+			}
+		}
+		if len(sorted) > 0 {
+			var sum time.Duration
+			for _, d := range sorted {
+				sum += d
+			}
+			rep.P50 = ms(percentile(sorted, 50))
+			rep.P90 = ms(percentile(sorted, 90))
+			rep.P95 = ms(percentile(sorted, 95))
+			rep.P99 = ms(percentile(sorted, 99))
+			rep.Max = ms(sorted[len(sorted)-1])
+			rep.Mean = ms(sum / time.Duration(len(sorted)))
+		}
+		out = append(out, rep)
+	}
+	return out
+}
+
+// WriteTable: bảng text cho terminal
+func WriteTable(w io.Writer, ops []OpReport) {
+	fmt.Fprintf(w, "%-16s %8s %7s %9s %9s %9s %9s %9s\n", "op", "count", "errors", "p50(ms)", "p90(ms)", "p95(ms)", "p99(ms)", "max(ms)")
+	for _, o := range ops {
+		fmt.Fprintf(w, "%-16s %8d %7d %9.1f %9.1f %9.1f %9.1f %9.1f\n", o.Name, o.Count, o.ErrorCount(), o.P50, o.P90, o.P95, o.P99, o.Max)
+	}
+	for _, o := range ops {
+		for reason, n := range o.Errors {
+			fmt.Fprintf(w, "  ! %s: %s x%d\n", o.Name, reason, n)
+		}
+	}
+}
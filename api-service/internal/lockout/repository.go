@@ -0,0 +1,137 @@
+package lockout
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// phạm vi đếm đăng nhập sai
+const (
+	ScopeUser = "user"
+	ScopeIP   = "ip"
+)
+
+// Limits: số lần sai trong Window trước khi khoá LockFor, 0 = tắt giới hạn đó
+type Limits struct {
+	MaxUserFailures int
+	MaxIPFailures   int // nhiều username từ 1 IP (dò mật khẩu hàng loạt)
+	Window          time.Duration
+	LockFor         time.Duration
+}
+
+// mặc định khi không cấu hình env
+var DefaultLimits = Limits{MaxUserFailures: 5, MaxIPFailures: 20, Window: 15 * time.Minute, LockFor: 15 * time.Minute}
+
+// lock_key VARCHAR(191): username / IP dài hơn (header giả) thì cắt
+const maxKeyLen = 191
+
+func clipKey(k string) string {
+	if len(k) > maxKeyLen {
+		return k[:maxKeyLen]
+	}
+	return k
+}
+
+// Lock: khoá đang có hiệu lực (hoặc vừa tạo)
+type Lock struct {
+	Scope    string
+	Key      string
+	Failures int
+	Until    time.Time
+}
+
+type Repository struct {
+	DB     *sql.DB
+	Limits Limits
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db, Limits: DefaultLimits}
+}
+
+// Check: khoá còn hiệu lực của username hoặc IP (hết hạn muộn nhất), nil = không bị khoá
+func (r *Repository) Check(ctx context.Context, username, ip string, now time.Time) (*Lock, error) {
+	var l Lock
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT scope, lock_key, failures, locked_until
+		FROM login_throttle
+		WHERE ((scope = 'user' AND lock_key = ?) OR (scope = 'ip' AND lock_key = ?))
+		  AND locked_until > ?
+		ORDER BY locked_until DESC
+		LIMIT 1
+	`, clipKey(username), clipKey(ip), now).Scan(&l.Scope, &l.Key, &l.Failures, &l.Until)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// RecordFailure: +1 lần sai cho username và IP, trả các khoá vừa tạo ở lần này
+func (r *Repository) RecordFailure(ctx context.Context, username, ip string, now time.Time) ([]Lock, error) {
+	var locks []Lock
+	for _, c := range []struct {
+		scope, key string
+		limit      int
+	}{
+		{ScopeUser, clipKey(username), r.Limits.MaxUserFailures},
+		{ScopeIP, clipKey(ip), r.Limits.MaxIPFailures},
+	} {
+		if c.limit <= 0 || c.key == "" {
+			continue
+		}
+		l, err := r.fail(ctx, c.scope, c.key, c.limit, now)
+		if err != nil {
+			return locks, err
+		}
+		if l != nil {
+			locks = append(locks, *l)
+		}
+	}
+	return locks, nil
+}
+
+func (r *Repository) fail(ctx context.Context, scope, key string, limit int, now time.Time) (*Lock, error) {
+	// lần sai đầu đã ra khỏi cửa sổ / khoá cũ đã hết -> đếm lại từ 1
+	// (MySQL gán từ trái sang phải: failures = 1 ở dưới là giá trị mới)
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT INTO login_throttle (scope, lock_key, failures, first_failed_at)
+		VALUES (?, ?, 1, ?)
+		ON DUPLICATE KEY UPDATE
+			failures = IF(first_failed_at < ? OR locked_until <= ?, 1, failures + 1),
+			first_failed_at = IF(failures = 1, VALUES(first_failed_at), first_failed_at),
+			locked_until = IF(failures = 1, NULL, locked_until)
+	`, scope, key, now, now.Add(-r.Limits.Window), now)
+	if err != nil {
+		return nil, err
+	}
+
+	var failures int
+	var lockedUntil sql.NullTime
+	err = r.DB.QueryRowContext(ctx, `
+		SELECT failures, locked_until FROM login_throttle WHERE scope = ? AND lock_key = ?
+	`, scope, key).Scan(&failures, &lockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	if failures < limit || (lockedUntil.Valid && lockedUntil.Time.After(now)) {
+		return nil, nil
+	}
+
+	until := now.Add(r.Limits.LockFor)
+	if _, err := r.DB.ExecContext(ctx, `
+		UPDATE login_throttle SET locked_until = ? WHERE scope = ? AND lock_key = ?
+	`, until, scope, key); err != nil {
+		return nil, err
+	}
+	return &Lock{Scope: scope, Key: key, Failures: failures, Until: until}, nil
+}
+
+// Reset: đăng nhập đúng -> xoá đếm sai của username (IP giữ nguyên, 1 tài khoản đúng không gỡ dò hàng loạt)
+func (r *Repository) Reset(ctx context.Context, username string) error {
+	_, err := r.DB.ExecContext(ctx, `DELETE FROM login_throttle WHERE scope = 'user' AND lock_key = ?`, clipKey(username))
+	return err
+}
@@ -0,0 +1,330 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"os"
+)
+
+// chất lượng khi phải encode lại ảnh đã xoay (gần bản gốc hơn thumbnail)
+const orientedJPEGQuality = 92
+
+var errBadImage = errors.New("media: malformed image")
+
+// StripImageMetadata: bỏ EXIF / XMP / IPTC (GPS, model máy, giờ chụp...) khỏi ảnh upload, ghi đè file
+// trả size mới; định dạng khác / không có metadata -> giữ nguyên file
+func StripImageMetadata(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	out, err := StripImageMetadataBytes(data)
+	if err != nil || out == nil {
+		return int64(len(data)), err
+	}
+
+	// ghi đè cùng inode (caller có thể còn giữ fd để upload lên object store)
+	if err := os.WriteFile(path, out, 0o644); err != nil {
+		return 0, err
+	}
+	return int64(len(out)), nil
+}
+
+// StripImageMetadataBytes: như StripImageMetadata nhưng trên bộ nhớ, nil = không cần đổi
+//   - jpeg / png: EXIF orientation != 1 -> xoay pixel cho đúng chiều rồi mới bỏ tag
+//     (không xoay thì ảnh chụp dọc hiện ngang sau khi mất EXIF)
+//   - webp: chỉ bỏ chunk EXIF / XMP (không decode được webp để xoay)
+func StripImageMetadataBytes(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8}):
+		return stripJPEG(data)
+	case bytes.HasPrefix(data, pngSignature):
+		return stripPNG(data)
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return stripWebP(data)
+	}
+	return nil, nil
+}
+
+// =======================================
+// JPEG
+// =======================================
+
+// stripJPEG: bỏ APP1 (Exif, XMP) + APP13 (Photoshop / IPTC), giữ JFIF / ICC / Adobe
+// nil = không có gì để bỏ
+func stripJPEG(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	orientation := 1
+	stripped := false
+
+	i := 2
+	for i < len(data) {
+		if data[i] != 0xFF || i+1 >= len(data) {
+			return nil, errBadImage
+		}
+		marker := data[i+1]
+		if marker == 0xFF { // byte đệm
+			out = append(out, 0xFF)
+			i++
+			continue
+		}
+		// SOS: phần còn lại là dữ liệu ảnh, chép nguyên
+		if marker == 0xDA || marker == 0xD9 {
+			out = append(out, data[i:]...)
+			break
+		}
+		// marker không có độ dài (RSTn, TEM)
+		if (marker >= 0xD0 && marker <= 0xD7) || marker == 0x01 {
+			out = append(out, data[i:i+2]...)
+			i += 2
+			continue
+		}
+		if i+4 > len(data) {
+			return nil, errBadImage
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+		if end > len(data) || end < i+4 {
+			return nil, errBadImage
+		}
+		payload := data[i+4 : end]
+
+		switch {
+		case marker == 0xE1 && bytes.HasPrefix(payload, []byte("Exif\x00\x00")):
+			if o := exifOrientation(payload[6:]); o != 1 {
+				orientation = o
+			}
+			stripped = true
+		case marker == 0xE1 && bytes.HasPrefix(payload, []byte("http://ns.adobe.com/")):
+			stripped = true
+		case marker == 0xED:
+			stripped = true
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+
+	if orientation != 1 {
+		// encoder của Go không ghi EXIF -> ảnh mới sạch metadata luôn
+		// xoay lỗi (ảnh quá lớn...) -> vẫn trả bản đã bỏ metadata, riêng tư quan trọng hơn chiều ảnh
+		rotated, err := reencodeOriented(out, orientation, func(buf *bytes.Buffer, img image.Image) error {
+			return jpeg.Encode(buf, img, &jpeg.Options{Quality: orientedJPEGQuality})
+		})
+		if err == nil {
+			return rotated, nil
+		}
+	}
+	if !stripped {
+		return nil, nil
+	}
+	return out, nil
+}
+
+// =======================================
+// PNG
+// =======================================
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// stripPNG: bỏ eXIf + chunk text (tEXt / zTXt / iTXt chứa XMP, "Raw profile type exif"...)
+func stripPNG(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	orientation := 1
+	stripped := false
+
+	i := len(pngSignature)
+	for i < len(data) {
+		if i+8 > len(data) {
+			return nil, errBadImage
+		}
+		n := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + n
+		if n < 0 || end > len(data) {
+			return nil, errBadImage
+		}
+
+		switch string(data[i+4 : i+8]) {
+		case "eXIf":
+			if o := exifOrientation(data[i+8 : i+8+n]); o != 1 {
+				orientation = o
+			}
+			stripped = true
+		case "tEXt", "zTXt", "iTXt":
+			stripped = true
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+
+	if orientation != 1 {
+		rotated, err := reencodeOriented(out, orientation, func(buf *bytes.Buffer, img image.Image) error {
+			return png.Encode(buf, img)
+		})
+		if err == nil {
+			return rotated, nil
+		}
+	}
+	if !stripped {
+		return nil, nil
+	}
+	return out, nil
+}
+
+// =======================================
+// WebP
+// =======================================
+
+// cờ trong chunk VP8X
+const (
+	webpFlagEXIF = 0x08
+	webpFlagXMP  = 0x04
+)
+
+// stripWebP: bỏ chunk EXIF / "XMP " + tắt cờ tương ứng trong VP8X, sửa lại size RIFF
+func stripWebP(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:12]...)
+	stripped := false
+	vp8x := -1
+
+	i := 12
+	for i < len(data) {
+		if i+8 > len(data) {
+			return nil, errBadImage
+		}
+		n := int(binary.LittleEndian.Uint32(data[i+4:]))
+		if n < 0 || i+8+n > len(data) {
+			return nil, errBadImage
+		}
+		end := min(i+8+n+n&1, len(data)) // chunk lẻ có 1 byte đệm (chunk cuối có thể thiếu)
+
+		switch string(data[i : i+4]) {
+		case "EXIF", "XMP ":
+			stripped = true
+			i = end
+			continue
+		case "VP8X":
+			vp8x = len(out)
+		}
+		out = append(out, data[i:end]...)
+		i = end
+	}
+
+	if !stripped {
+		return nil, nil
+	}
+	if vp8x >= 0 && vp8x+8 < len(out) {
+		out[vp8x+8] &^= webpFlagEXIF | webpFlagXMP
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, nil
+}
+
+// =======================================
+// EXIF orientation
+// =======================================
+
+// exifOrientation: đọc tag Orientation (0x0112) trong IFD0 của block TIFF, lỗi / không có -> 1
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var bo binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return 1
+	}
+
+	off := int(bo.Uint32(tiff[4:]))
+	if off < 8 || off+2 > len(tiff) {
+		return 1
+	}
+	count := int(bo.Uint16(tiff[off:]))
+	for k := 0; k < count; k++ {
+		e := off + 2 + k*12
+		if e+12 > len(tiff) {
+			break
+		}
+		if bo.Uint16(tiff[e:]) != 0x0112 {
+			continue
+		}
+		if v := int(bo.Uint16(tiff[e+8:])); v >= 1 && v <= 8 {
+			return v
+		}
+		return 1
+	}
+	return 1
+}
+
+// reencodeOriented: decode ảnh (đã bỏ metadata), xoay / lật theo orientation rồi encode lại
+func reencodeOriented(data []byte, orientation int, encode func(*bytes.Buffer, image.Image) error) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxVariantSourcePixels {
+		return nil, ErrThumbnailUnsupported
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	b := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+
+	var buf bytes.Buffer
+	if err := encode(&buf, applyOrientation(rgba, orientation)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// applyOrientation: 2 lật ngang, 3 xoay 180, 4 lật dọc, 5 transpose,
+// 6 xoay 90 (chiều kim đồng hồ), 7 transverse, 8 xoay 270
+func applyOrientation(src *image.RGBA, orientation int) *image.RGBA {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		row := src.Pix[y*src.Stride:]
+		for x := 0; x < w; x++ {
+			dx, dy := x, y
+			switch orientation {
+			case 2:
+				dx = w - 1 - x
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dy = h - 1 - y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dy*dst.Stride+dx*4:], row[x*4:x*4+4])
+		}
+	}
+	return dst
+}
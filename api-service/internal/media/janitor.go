@@ -0,0 +1,203 @@
+package media
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// grace mặc định: upload xong mà 24h chưa có message/user nào trỏ tới -> coi là rác
+const DefaultOrphanGrace = 24 * time.Hour
+
+// OrphanFile: 1 file không còn ai tham chiếu
+type OrphanFile struct {
+	Kind    string    `json:"kind"` // chat_upload | avatar
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// CleanupReport: kết quả 1 lần quét
+type CleanupReport struct {
+	DryRun       bool         `json:"dry_run"`
+	Scanned      int          `json:"scanned"`
+	Orphans      []OrphanFile `json:"orphans"`
+	Deleted      int          `json:"deleted"`
+	BytesFreed   int64        `json:"bytes_freed"`
+	StartedAt    time.Time    `json:"started_at"`
+	FinishedAt   time.Time    `json:"finished_at"`
+	ErrorsLogged int          `json:"errors_logged"`
+}
+
+// Janitor: dọn file upload mồ côi trong chatUploadDir / avatarDir
+type Janitor struct {
+	DB            *sql.DB
+	ChatUploadDir string
+	AvatarDir     string
+	Grace         time.Duration
+}
+
+func NewJanitor(db *sql.DB, chatUploadDir, avatarDir string, grace time.Duration) *Janitor {
+	if grace <= 0 {
+		grace = DefaultOrphanGrace
+	}
+	return &Janitor{
+		DB:            db,
+		ChatUploadDir: chatUploadDir,
+		AvatarDir:     avatarDir,
+		Grace:         grace,
+	}
+}
+
+// Start: chạy Cleanup định kỳ cho tới khi ctx bị cancel
+func (j *Janitor) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				rep, err := j.Cleanup(ctx, false)
+				if err != nil {
+					log.Println("[janitor] cleanup error:", err)
+					continue
+				}
+				if rep.Deleted > 0 {
+					log.Printf("[janitor] deleted %d orphan files (%d bytes)", rep.Deleted, rep.BytesFreed)
+				}
+			}
+		}
+	}()
+}
+
+// Cleanup: quét 2 thư mục, dryRun = true thì chỉ liệt kê không xoá
+func (j *Janitor) Cleanup(ctx context.Context, dryRun bool) (*CleanupReport, error) {
+	rep := &CleanupReport{
+		DryRun:    dryRun,
+		Orphans:   []OrphanFile{},
+		StartedAt: time.Now(),
+	}
+
+	if err := j.scanDir(ctx, rep, "chat_upload", j.ChatUploadDir, j.isChatUploadReferenced); err != nil {
+		return nil, err
+	}
+	if err := j.scanDir(ctx, rep, "avatar", j.AvatarDir, j.isAvatarReferenced); err != nil {
+		return nil, err
+	}
+
+	rep.FinishedAt = time.Now()
+	return rep, nil
+}
+
+func (j *Janitor) scanDir(
+	ctx context.Context,
+	rep *CleanupReport,
+	kind, dir string,
+	isReferenced func(ctx context.Context, name string) (bool, error),
+) error {
+	if dir == "" {
+		return nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	cutoff := time.Now().Add(-j.Grace)
+
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if e.IsDir() {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			rep.ErrorsLogged++
+			continue
+		}
+		rep.Scanned++
+
+		// file mới upload -> cho client thời gian gửi message
+		if info.ModTime().After(cutoff) {
+			continue
+		}
+
+		ok, err := isReferenced(ctx, e.Name())
+		if err != nil {
+			log.Printf("[janitor] check %s/%s: %v", kind, e.Name(), err)
+			rep.ErrorsLogged++
+			continue
+		}
+		if ok {
+			continue
+		}
+
+		rep.Orphans = append(rep.Orphans, OrphanFile{
+			Kind:    kind,
+			Name:    e.Name(),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+
+		if rep.DryRun {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, e.Name())); err != nil {
+			log.Printf("[janitor] remove %s/%s: %v", kind, e.Name(), err)
+			rep.ErrorsLogged++
+			continue
+		}
+		rep.Deleted++
+		rep.BytesFreed += info.Size()
+
+		if kind == "chat_upload" {
+			if _, err := j.DB.ExecContext(ctx, `DELETE FROM chat_uploads WHERE file_name = ?`, e.Name()); err != nil {
+				log.Printf("[janitor] delete chat_uploads row %s: %v", e.Name(), err)
+				rep.ErrorsLogged++
+			}
+		}
+	}
+
+	return nil
+}
+
+func (j *Janitor) isChatUploadReferenced(ctx context.Context, name string) (bool, error) {
+	url := "/static/chat_uploads/" + name
+
+	var ok int
+	err := j.DB.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM messages
+			WHERE content = ? OR media_url = ? OR media_poster_url = ?
+		) OR EXISTS(
+			SELECT 1 FROM attachments
+			WHERE file_path = ? OR file_path = ? OR thumb_url = ? OR medium_url = ?
+		) OR EXISTS(
+			SELECT 1 FROM rooms WHERE avatar_url = ?
+		) OR EXISTS(
+			SELECT 1 FROM reaction_emojis WHERE image_url = ?
+		)
+	`, url, url, url, url, name, url, url, url, url).Scan(&ok)
+	return ok == 1, err
+}
+
+func (j *Janitor) isAvatarReferenced(ctx context.Context, name string) (bool, error) {
+	var ok int
+	err := j.DB.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM users WHERE avatar_url = ?)
+	`, "/static/user_avatars/"+name).Scan(&ok)
+	return ok == 1, err
+}
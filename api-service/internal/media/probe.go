@@ -0,0 +1,157 @@
+package media
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"image"
+	_ "image/gif"  // đăng ký decoder cho DecodeConfig
+	_ "image/jpeg" // đăng ký decoder cho DecodeConfig
+	_ "image/png"  // đăng ký decoder cho DecodeConfig
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var ErrProbeUnsupported = errors.New("probe: unsupported media type")
+
+// Metadata: thông tin để client giữ chỗ layout trước khi tải media
+type Metadata struct {
+	Width      int   `json:"width,omitempty"`
+	Height     int   `json:"height,omitempty"`
+	DurationMs int64 `json:"duration_ms,omitempty"`
+}
+
+// FindFFprobe: ưu tiên ffprobe cùng thư mục với ffmpegPath, không có thì tìm trong PATH
+func FindFFprobe(ffmpegPath string) string {
+	if ffmpegPath != "" {
+		p := filepath.Join(filepath.Dir(ffmpegPath), "ffprobe"+filepath.Ext(ffmpegPath))
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	p, err := exec.LookPath("ffprobe")
+	if err != nil {
+		return ""
+	}
+	return p
+}
+
+// Probe: image đọc header trực tiếp, video/audio dùng ffprobe (rỗng -> ErrProbeUnsupported)
+func Probe(ctx context.Context, ffprobePath, path, mime string) (*Metadata, error) {
+	mime = strings.ToLower(mime)
+
+	switch {
+	case strings.HasPrefix(mime, "image/"):
+		return probeImage(path)
+
+	case strings.HasPrefix(mime, "video/"), strings.HasPrefix(mime, "audio/"):
+		if ffprobePath == "" {
+			return nil, ErrProbeUnsupported
+		}
+		return probeFFprobe(ctx, ffprobePath, path)
+
+	default:
+		return nil, ErrProbeUnsupported
+	}
+}
+
+func probeImage(path string) (*Metadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".webp") {
+		w, h, err := webpSize(f)
+		if err != nil {
+			return nil, err
+		}
+		return &Metadata{Width: w, Height: h}, nil
+	}
+
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return nil, err
+	}
+	return &Metadata{Width: cfg.Width, Height: cfg.Height}, nil
+}
+
+// webpSize: đọc kích thước từ chunk VP8 / VP8L / VP8X (stdlib không có decoder webp)
+func webpSize(r io.Reader) (int, int, error) {
+	var hdr [30]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, 0, err
+	}
+	if string(hdr[0:4]) != "RIFF" || string(hdr[8:12]) != "WEBP" {
+		return 0, 0, ErrProbeUnsupported
+	}
+
+	switch string(hdr[12:16]) {
+	case "VP8 ": // lossy: frame header sau 3 byte tag + 3 byte start code
+		w := int(binary.LittleEndian.Uint16(hdr[26:28]) & 0x3fff)
+		h := int(binary.LittleEndian.Uint16(hdr[28:30]) & 0x3fff)
+		return w, h, nil
+
+	case "VP8L": // lossless: 14 bit width-1, 14 bit height-1 sau signature 0x2f
+		if hdr[20] != 0x2f {
+			return 0, 0, ErrProbeUnsupported
+		}
+		bits := binary.LittleEndian.Uint32(hdr[21:25])
+		return int(bits&0x3fff) + 1, int((bits>>14)&0x3fff) + 1, nil
+
+	case "VP8X": // extended: 24 bit canvas width-1, height-1
+		w := int(hdr[24]) | int(hdr[25])<<8 | int(hdr[26])<<16
+		h := int(hdr[27]) | int(hdr[28])<<8 | int(hdr[29])<<16
+		return w + 1, h + 1, nil
+
+	default:
+		return 0, 0, ErrProbeUnsupported
+	}
+}
+
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecType string `json:"codec_type"`
+		Width     int    `json:"width"`
+		Height    int    `json:"height"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+func probeFFprobe(ctx context.Context, ffprobePath, path string) (*Metadata, error) {
+	cmd := exec.CommandContext(ctx, ffprobePath,
+		"-v", "error",
+		"-show_entries", "stream=codec_type,width,height:format=duration",
+		"-of", "json",
+		path,
+	)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var po ffprobeOutput
+	if err := json.Unmarshal(out, &po); err != nil {
+		return nil, err
+	}
+
+	md := &Metadata{}
+	for _, st := range po.Streams {
+		if st.CodecType == "video" && st.Width > 0 {
+			md.Width, md.Height = st.Width, st.Height
+			break
+		}
+	}
+	if sec, err := strconv.ParseFloat(po.Format.Duration, 64); err == nil && sec > 0 {
+		md.DurationMs = int64(sec * 1000)
+	}
+	return md, nil
+}
@@ -0,0 +1,162 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// biến thể ảnh cho message list: thumb (list) + medium (xem nhanh), bản gốc khi mở full
+const (
+	VariantThumb  = "thumb"
+	VariantMedium = "md"
+)
+
+// VariantSpec: cạnh dài nhất của biến thể
+type VariantSpec struct {
+	Name    string
+	MaxSide int
+}
+
+var DefaultVariants = []VariantSpec{
+	{Name: VariantThumb, MaxSide: 320},
+	{Name: VariantMedium, MaxSide: 1280},
+}
+
+// ảnh quá lớn (decompression bomb) thì không resize
+const maxVariantSourcePixels = 50_000_000
+
+const variantJPEGQuality = 82
+
+var ErrThumbnailUnsupported = errors.New("thumbnail: unsupported image type")
+
+// Variant: ảnh đã resize, chưa lưu
+type Variant struct {
+	Name   string
+	Width  int
+	Height int
+	Mime   string
+	Data   []byte
+}
+
+// VariantName: r1_u2_123.jpg + thumb -> r1_u2_123_thumb.jpg
+// png/gif giữ png (ảnh trong suốt), còn lại jpeg
+func VariantName(source, variant string) string {
+	ext := strings.ToLower(filepath.Ext(source))
+	stem := strings.TrimSuffix(source, filepath.Ext(source))
+	if ext == ".png" || ext == ".gif" {
+		return stem + "_" + variant + ".png"
+	}
+	return stem + "_" + variant + ".jpg"
+}
+
+// GenerateVariants: decode ảnh (jpeg/png/gif, gif lấy frame đầu) rồi thu nhỏ theo specs
+// ảnh gốc đã nhỏ hơn MaxSide thì bỏ qua spec đó (FE dùng luôn bản gốc)
+func GenerateVariants(path string, specs []VariantSpec) ([]Variant, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".webp" {
+		return nil, ErrThumbnailUnsupported
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return nil, ErrThumbnailUnsupported
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxVariantSourcePixels {
+		return nil, ErrThumbnailUnsupported
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		return nil, err
+	}
+
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	b := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+
+	asPNG := ext == ".png" || ext == ".gif"
+	var out []Variant
+	for _, spec := range specs {
+		w, h := fitSize(b.Dx(), b.Dy(), spec.MaxSide)
+		if w == b.Dx() && h == b.Dy() {
+			continue
+		}
+		dst := downscale(rgba, w, h)
+
+		var buf bytes.Buffer
+		v := Variant{Name: spec.Name, Width: w, Height: h}
+		if asPNG {
+			v.Mime = "image/png"
+			err = png.Encode(&buf, dst)
+		} else {
+			v.Mime = "image/jpeg"
+			err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: variantJPEGQuality})
+		}
+		if err != nil {
+			return nil, err
+		}
+		v.Data = buf.Bytes()
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// fitSize: giữ tỉ lệ, cạnh dài nhất <= maxSide (không phóng to)
+func fitSize(w, h, maxSide int) (int, int) {
+	if maxSide <= 0 || (w <= maxSide && h <= maxSide) {
+		return w, h
+	}
+	if w >= h {
+		return maxSide, max(1, h*maxSide/w)
+	}
+	return max(1, w*maxSide/h), maxSide
+}
+
+// downscale: box filter (trung bình các pixel nguồn phủ lên mỗi pixel đích)
+func downscale(src *image.RGBA, w, h int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		y0 := y * sh / h
+		y1 := max((y+1)*sh/h, y0+1)
+		for x := 0; x < w; x++ {
+			x0 := x * sw / w
+			x1 := max((x+1)*sw/w, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += uint64(p[0])
+					g += uint64(p[1])
+					b += uint64(p[2])
+					a += uint64(p[3])
+					n++
+				}
+			}
+			d := dst.Pix[y*dst.Stride+x*4:]
+			d[0] = uint8(r / n)
+			d[1] = uint8(g / n)
+			d[2] = uint8(b / n)
+			d[3] = uint8(a / n)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,146 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// thời gian tối đa cho 1 lần transcode (video dài quá thì bỏ)
+const TranscodeTimeout = 10 * time.Minute
+
+// TranscodeJob: 1 video cần convert sang MP4 streamable
+type TranscodeJob struct {
+	MessageID int64
+	RoomID    int64
+	SrcPath   string // đường dẫn vật lý file gốc trong chatUploadDir
+}
+
+// TranscodeResult: kết quả trả về cho callback
+type TranscodeResult struct {
+	Job        TranscodeJob
+	OutputName string // tên file mp4 (nằm cùng thư mục với file gốc)
+	PosterName string // tên file poster jpg (rỗng nếu extract fail)
+	OutputSize int64
+	Err        error
+}
+
+// Transcoder: worker chạy ffmpeg nền, 1 goroutine xử lý tuần tự
+type Transcoder struct {
+	ffmpegPath string
+	jobs       chan TranscodeJob
+	onDone     func(TranscodeResult)
+}
+
+// NewTranscoder: ffmpegPath rỗng -> tự tìm trong PATH, không có thì trả nil (tắt tính năng)
+func NewTranscoder(ffmpegPath string, onDone func(TranscodeResult)) *Transcoder {
+	if ffmpegPath == "" {
+		p, err := exec.LookPath("ffmpeg")
+		if err != nil {
+			return nil
+		}
+		ffmpegPath = p
+	}
+
+	return &Transcoder{
+		ffmpegPath: ffmpegPath,
+		jobs:       make(chan TranscodeJob, 32),
+		onDone:     onDone,
+	}
+}
+
+// Start: chạy worker cho tới khi ctx bị cancel
+func (t *Transcoder) Start(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case job := <-t.jobs:
+				res := t.process(ctx, job)
+				if res.Err != nil {
+					log.Printf("[transcode] message=%d failed: %v", job.MessageID, res.Err)
+				}
+				if t.onDone != nil {
+					t.onDone(res)
+				}
+			}
+		}
+	}()
+}
+
+// Enqueue: non-blocking, queue đầy thì báo lỗi để caller log
+func (t *Transcoder) Enqueue(job TranscodeJob) error {
+	select {
+	case t.jobs <- job:
+		return nil
+	default:
+		return errors.New("transcode queue is full")
+	}
+}
+
+func (t *Transcoder) process(parent context.Context, job TranscodeJob) TranscodeResult {
+	res := TranscodeResult{Job: job}
+
+	ctx, cancel := context.WithTimeout(parent, TranscodeTimeout)
+	defer cancel()
+
+	dir := filepath.Dir(job.SrcPath)
+	base := strings.TrimSuffix(filepath.Base(job.SrcPath), filepath.Ext(job.SrcPath))
+
+	// 1) MP4 H.264/AAC + faststart (moov atom đầu file -> play được khi đang tải)
+	outName := base + "_t.mp4"
+	outPath := filepath.Join(dir, outName)
+	cmd := exec.CommandContext(ctx, t.ffmpegPath,
+		"-y", "-i", job.SrcPath,
+		"-c:v", "libx264", "-preset", "veryfast", "-crf", "23",
+		"-pix_fmt", "yuv420p",
+		"-c:a", "aac", "-b:a", "128k",
+		"-movflags", "+faststart",
+		outPath,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		_ = os.Remove(outPath)
+		res.Err = fmt.Errorf("ffmpeg transcode: %w: %s", err, tail(out, 300))
+		return res
+	}
+
+	st, err := os.Stat(outPath)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	res.OutputName = outName
+	res.OutputSize = st.Size()
+
+	// 2) poster frame (giây thứ 1, video ngắn hơn thì ffmpeg lấy frame đầu)
+	posterName := base + "_poster.jpg"
+	posterPath := filepath.Join(dir, posterName)
+	cmd = exec.CommandContext(ctx, t.ffmpegPath,
+		"-y", "-ss", "1", "-i", outPath,
+		"-frames:v", "1", "-q:v", "3",
+		posterPath,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		// poster fail không làm hỏng cả job
+		log.Printf("[transcode] message=%d poster failed: %v: %s", job.MessageID, err, tail(out, 300))
+		_ = os.Remove(posterPath)
+	} else {
+		res.PosterName = posterName
+	}
+
+	return res
+}
+
+func tail(b []byte, n int) string {
+	if len(b) > n {
+		b = b[len(b)-n:]
+	}
+	return strings.TrimSpace(string(b))
+}
@@ -0,0 +1,195 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// số cột waveform mặc định (đủ cho UI voice message)
+const DefaultWaveformBuckets = 64
+
+// sample rate khi decode qua ffmpeg (waveform không cần cao)
+const waveformSampleRate = 8000
+
+var ErrWaveformUnsupported = errors.New("waveform: unsupported audio format")
+
+// Waveform: peaks chuẩn hoá 0..100 + độ dài audio
+type Waveform struct {
+	Peaks      []int `json:"peaks"`
+	DurationMs int64 `json:"duration_ms"`
+}
+
+// ComputeWaveform: .wav đọc trực tiếp, định dạng khác (ogg/webm/m4a/mp3) decode qua ffmpeg
+// ffmpegPath rỗng -> tìm trong PATH, không có thì chỉ hỗ trợ wav
+func ComputeWaveform(ctx context.Context, ffmpegPath, path string, buckets int) (*Waveform, error) {
+	if buckets <= 0 {
+		buckets = DefaultWaveformBuckets
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".wav") {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		samples, rate, err := readWavPCM16Mono(f)
+		if err != nil {
+			return nil, err
+		}
+		return buildWaveform(samples, rate, buckets), nil
+	}
+
+	if ffmpegPath == "" {
+		p, err := exec.LookPath("ffmpeg")
+		if err != nil {
+			return nil, ErrWaveformUnsupported
+		}
+		ffmpegPath = p
+	}
+
+	// decode -> PCM s16le mono 8kHz ra stdout
+	cmd := exec.CommandContext(ctx, ffmpegPath,
+		"-v", "error",
+		"-i", path,
+		"-f", "s16le", "-ac", "1", "-ar", "8000",
+		"-",
+	)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	samples := make([]int16, len(out)/2)
+	if err := binary.Read(bytes.NewReader(out[:len(samples)*2]), binary.LittleEndian, samples); err != nil {
+		return nil, err
+	}
+	return buildWaveform(samples, waveformSampleRate, buckets), nil
+}
+
+// buildWaveform: chia samples thành buckets, lấy peak tuyệt đối mỗi bucket, scale 0..100
+func buildWaveform(samples []int16, rate int, buckets int) *Waveform {
+	w := &Waveform{Peaks: make([]int, buckets)}
+	if rate > 0 {
+		w.DurationMs = int64(len(samples)) * 1000 / int64(rate)
+	}
+	if len(samples) == 0 {
+		return w
+	}
+
+	per := len(samples) / buckets
+	if per == 0 {
+		per = 1
+	}
+
+	raw := make([]int, buckets)
+	maxPeak := 1
+	for b := 0; b < buckets; b++ {
+		start := b * per
+		if start >= len(samples) {
+			break
+		}
+		end := start + per
+		if b == buckets-1 || end > len(samples) {
+			end = len(samples)
+		}
+
+		peak := 0
+		for _, s := range samples[start:end] {
+			v := int(s)
+			if v < 0 {
+				v = -v
+			}
+			if v > peak {
+				peak = v
+			}
+		}
+		raw[b] = peak
+		if peak > maxPeak {
+			maxPeak = peak
+		}
+	}
+
+	for i, p := range raw {
+		w.Peaks[i] = p * 100 / maxPeak
+	}
+	return w
+}
+
+// readWavPCM16Mono: parse RIFF/WAVE PCM 16-bit, nhiều kênh thì lấy kênh đầu
+func readWavPCM16Mono(r io.Reader) ([]int16, int, error) {
+	var hdr [12]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, 0, err
+	}
+	if string(hdr[0:4]) != "RIFF" || string(hdr[8:12]) != "WAVE" {
+		return nil, 0, ErrWaveformUnsupported
+	}
+
+	var (
+		channels   int
+		rate       int
+		bits       int
+		gotFormat  bool
+		chunkHdr   [8]byte
+		formatBody [16]byte
+	)
+
+	for {
+		if _, err := io.ReadFull(r, chunkHdr[:]); err != nil {
+			return nil, 0, ErrWaveformUnsupported
+		}
+		id := string(chunkHdr[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunkHdr[4:8]))
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, 0, ErrWaveformUnsupported
+			}
+			if _, err := io.ReadFull(r, formatBody[:]); err != nil {
+				return nil, 0, err
+			}
+			if binary.LittleEndian.Uint16(formatBody[0:2]) != 1 { // PCM
+				return nil, 0, ErrWaveformUnsupported
+			}
+			channels = int(binary.LittleEndian.Uint16(formatBody[2:4]))
+			rate = int(binary.LittleEndian.Uint32(formatBody[4:8]))
+			bits = int(binary.LittleEndian.Uint16(formatBody[14:16]))
+			gotFormat = true
+			if _, err := io.CopyN(io.Discard, r, size-16+size%2); err != nil {
+				return nil, 0, err
+			}
+
+		case "data":
+			if !gotFormat || bits != 16 || channels <= 0 {
+				return nil, 0, ErrWaveformUnsupported
+			}
+			data := make([]byte, size)
+			n, err := io.ReadFull(r, data)
+			if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, 0, err
+			}
+			data = data[:n]
+
+			frame := 2 * channels
+			samples := make([]int16, 0, len(data)/frame)
+			for i := 0; i+frame <= len(data); i += frame {
+				samples = append(samples, int16(binary.LittleEndian.Uint16(data[i:i+2])))
+			}
+			return samples, rate, nil
+
+		default:
+			if _, err := io.CopyN(io.Discard, r, size+size%2); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package moderation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// hành động admin không qua report (ghi audit + message_deletions)
+const (
+	ActionAdminDeleteMessage = "admin_delete_message"
+	ActionPurgeRoom          = "purge_room"
+)
+
+// purge theo lô nhỏ để không giữ lock lâu trên messages
+const purgeBatchSize = 500
+
+var (
+	ErrMessageNotFound = errors.New("moderation: message not found")
+	ErrAlreadyRemoved  = errors.New("moderation: message already removed")
+)
+
+// Deletion: snapshot tin bị admin xoá / purge
+type Deletion struct {
+	ID               int64     `json:"id"`
+	MessageID        int64     `json:"message_id"`
+	RoomID           int64     `json:"room_id"`
+	SenderID         int64     `json:"sender_id"`
+	SenderName       string    `json:"sender_name,omitempty"`
+	DeletedBy        int64     `json:"deleted_by"`
+	DeletedByName    string    `json:"deleted_by_name,omitempty"`
+	Action           string    `json:"action"`
+	MessageType      string    `json:"message_type"`
+	Content          string    `json:"content,omitempty"`
+	MediaURL         string    `json:"media_url,omitempty"`
+	Note             string    `json:"note,omitempty"`
+	MessageCreatedAt time.Time `json:"message_created_at"`
+	DeletedAt        time.Time `json:"deleted_at"`
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// snapshot cột messages -> message_deletions (dùng trong INSERT ... SELECT)
+const deletionSnapshot = `
+	SELECT id, room_id, sender_id, ?, ?, message_type, content, media_url, NULLIF(?, ''), created_at
+	FROM messages
+`
+
+// AdminDeleteMessage: admin gỡ 1 tin bất kỳ (giống delete_message của report)
+// snapshot + gỡ nội dung + audit trong cùng transaction, trả room để báo WS
+func (r *Repository) AdminDeleteMessage(ctx context.Context, messageID, adminID int64, note string) (roomID int64, err error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var senderID int64
+	var removedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, `
+		SELECT room_id, sender_id, removed_at FROM messages WHERE id = ? FOR UPDATE
+	`, messageID).Scan(&roomID, &senderID, &removedAt)
+	if err == sql.ErrNoRows {
+		return 0, ErrMessageNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if removedAt.Valid {
+		return 0, ErrAlreadyRemoved
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO message_deletions
+			(message_id, room_id, sender_id, deleted_by, action, message_type, content, media_url, note, message_created_at)
+	`+deletionSnapshot+` WHERE id = ?`, adminID, ActionAdminDeleteMessage, note, messageID); err != nil {
+		return 0, err
+	}
+	if err := removeMessage(ctx, tx, messageID, RemovedPlaceholder); err != nil {
+		return 0, err
+	}
+	if err := insertAudit(ctx, tx, &AuditEntry{
+		AdminID:      adminID,
+		Action:       ActionAdminDeleteMessage,
+		TargetUserID: senderID,
+		MessageID:    messageID,
+		Note:         note,
+	}); err != nil {
+		return 0, err
+	}
+	return roomID, tx.Commit()
+}
+
+// PurgeRoomMessages: xoá hẳn tin của room trong [from, to), snapshot từng lô trước khi xoá
+// receipt / reaction / attachment theo FK cascade, file mồ côi để janitor dọn
+// ctx hết hạn giữa chừng -> trả số đã xoá kèm lỗi (các lô đã commit vẫn giữ)
+func (r *Repository) PurgeRoomMessages(ctx context.Context, roomID int64, from, to time.Time, adminID int64, note string) (int64, error) {
+	var total int64
+	for {
+		n, err := r.purgeBatch(ctx, roomID, from, to, adminID, note)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < purgeBatchSize {
+			break
+		}
+	}
+
+	auditNote := fmt.Sprintf("room %d: %s -> %s, %d messages", roomID, from.Format(time.RFC3339), to.Format(time.RFC3339), total)
+	if note != "" {
+		auditNote += ". " + note
+	}
+	return total, insertAudit(ctx, r.DB, &AuditEntry{AdminID: adminID, Action: ActionPurgeRoom, Note: auditNote})
+}
+
+func (r *Repository) purgeBatch(ctx context.Context, roomID int64, from, to time.Time, adminID int64, note string) (int64, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id FROM messages
+		WHERE room_id = ? AND created_at >= ? AND created_at < ?
+		ORDER BY id
+		LIMIT ?
+		FOR UPDATE
+	`, roomID, from, to, purgeBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	args := []any{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		args = append(args, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(args) == 0 {
+		return 0, nil
+	}
+
+	ph := strings.TrimRight(strings.Repeat("?,", len(args)), ",")
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO message_deletions
+			(message_id, room_id, sender_id, deleted_by, action, message_type, content, media_url, note, message_created_at)
+	`+deletionSnapshot+` WHERE id IN (`+ph+`)`, append([]any{adminID, ActionPurgeRoom, note}, args...)...); err != nil {
+		return 0, err
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM messages WHERE id IN (`+ph+`)`, args...)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return n, nil
+}
+
+// ListDeletions: snapshot tin đã xoá, mới nhất trước (roomID = 0: mọi room)
+func (r *Repository) ListDeletions(ctx context.Context, roomID, beforeID int64, limit int) ([]*Deletion, error) {
+	q := `
+		SELECT d.id, d.message_id, d.room_id, d.sender_id, COALESCE(su.full_name, su.username, ''),
+		       d.deleted_by, COALESCE(au.full_name, au.username, ''), d.action, d.message_type,
+		       COALESCE(d.content, ''), COALESCE(d.media_url, ''), COALESCE(d.note, ''),
+		       d.message_created_at, d.deleted_at
+		FROM message_deletions d
+		LEFT JOIN users su ON su.id = d.sender_id
+		LEFT JOIN users au ON au.id = d.deleted_by
+		WHERE 1 = 1
+	`
+	args := []any{}
+	if roomID > 0 {
+		q += ` AND d.room_id = ?`
+		args = append(args, roomID)
+	}
+	if beforeID > 0 {
+		q += ` AND d.id < ?`
+		args = append(args, beforeID)
+	}
+	q += ` ORDER BY d.id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*Deletion{}
+	for rows.Next() {
+		var d Deletion
+		if err := rows.Scan(&d.ID, &d.MessageID, &d.RoomID, &d.SenderID, &d.SenderName,
+			&d.DeletedBy, &d.DeletedByName, &d.Action, &d.MessageType,
+			&d.Content, &d.MediaURL, &d.Note, &d.MessageCreatedAt, &d.DeletedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, &d)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,472 @@
+package moderation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// đối tượng bị báo cáo
+const (
+	TargetMessage = "message"
+	TargetUser    = "user"
+)
+
+// trạng thái report
+const (
+	StatusOpen      = "open"
+	StatusResolved  = "resolved"
+	StatusDismissed = "dismissed"
+)
+
+// hành động admin trên 1 report
+const (
+	ActionDismiss       = "dismiss"
+	ActionDeleteMessage = "delete_message"
+	ActionWarn          = "warn"
+	ActionSuspend       = "suspend"
+)
+
+// lý do report
+var Reasons = []string{"spam", "harassment", "hate", "nsfw", "violence", "other"}
+
+// nội dung thay thế cho tin bị gỡ
+const RemovedPlaceholder = "[Tin nhắn đã bị gỡ bởi quản trị viên]"
+
+// DeletedPlaceholder: người gửi tự xoá tin
+const DeletedPlaceholder = "[Tin nhắn đã bị xoá]"
+
+var (
+	ErrNotFound      = errors.New("moderation: report not found")
+	ErrAlreadyClosed = errors.New("moderation: report already handled")
+	ErrNoMessage     = errors.New("moderation: report has no message")
+)
+
+func IsValidReason(s string) bool {
+	for _, r := range Reasons {
+		if r == s {
+			return true
+		}
+	}
+	return false
+}
+
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// Report: 1 báo cáo (reporter_id = 0: hệ thống tự gắn cờ)
+type Report struct {
+	ID             int64      `json:"id"`
+	ReporterID     int64      `json:"reporter_id"`
+	ReporterName   string     `json:"reporter_name,omitempty"`
+	TargetType     string     `json:"target_type"`
+	MessageID      int64      `json:"message_id,omitempty"`
+	TargetUserID   int64      `json:"target_user_id"`
+	TargetUserName string     `json:"target_user_name,omitempty"`
+	RoomID         int64      `json:"room_id,omitempty"`
+	Reason         string     `json:"reason"`
+	Details        string     `json:"details,omitempty"`
+	ContentPreview string     `json:"content_preview,omitempty"` // snapshot lúc report
+	Status         string     `json:"status"`
+	Resolution     string     `json:"resolution,omitempty"`
+	ResolvedBy     int64      `json:"resolved_by,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+
+	// số report đang mở cùng đối tượng (queue gom nhóm)
+	OpenReports int `json:"open_reports,omitempty"`
+}
+
+// AuditEntry: log mọi hành động moderation
+type AuditEntry struct {
+	ID           int64     `json:"id"`
+	ReportID     int64     `json:"report_id,omitempty"`
+	AdminID      int64     `json:"admin_id"`
+	AdminName    string    `json:"admin_name,omitempty"`
+	Action       string    `json:"action"`
+	TargetUserID int64     `json:"target_user_id,omitempty"`
+	MessageID    int64     `json:"message_id,omitempty"`
+	Note         string    `json:"note,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (r *Repository) Create(ctx context.Context, rp *Report) error {
+	rp.Status = StatusOpen
+	res, err := r.DB.ExecContext(ctx, `
+		INSERT INTO moderation_reports
+			(reporter_id, target_type, message_id, target_user_id, room_id, reason, details, content_preview, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, rp.ReporterID, rp.TargetType, nullInt(rp.MessageID), rp.TargetUserID, nullInt(rp.RoomID),
+		rp.Reason, nullIfEmpty(rp.Details), nullIfEmpty(rp.ContentPreview), rp.Status)
+	if err != nil {
+		return err
+	}
+	rp.ID, _ = res.LastInsertId()
+	rp.CreatedAt = time.Now()
+	return nil
+}
+
+// HasOpenReport: reporter đã report đối tượng này và chưa xử lý (chống spam report)
+func (r *Repository) HasOpenReport(ctx context.Context, reporterID int64, targetType string, messageID, targetUserID int64) (bool, error) {
+	var n int
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM moderation_reports
+		WHERE reporter_id = ? AND target_type = ? AND status = ?
+		  AND COALESCE(message_id, 0) = ? AND target_user_id = ?
+	`, reporterID, targetType, StatusOpen, messageID, targetUserID).Scan(&n)
+	return n > 0, err
+}
+
+const selectReport = `
+	SELECT mr.id, mr.reporter_id, COALESCE(ru.full_name, ru.username, ''), mr.target_type,
+	       COALESCE(mr.message_id, 0), mr.target_user_id, COALESCE(tu.full_name, tu.username, ''),
+	       COALESCE(mr.room_id, 0), mr.reason, COALESCE(mr.details, ''), COALESCE(mr.content_preview, ''),
+	       mr.status, COALESCE(mr.resolution, ''), COALESCE(mr.resolved_by, 0), mr.resolved_at, mr.created_at
+	FROM moderation_reports mr
+	LEFT JOIN users ru ON ru.id = mr.reporter_id
+	LEFT JOIN users tu ON tu.id = mr.target_user_id
+`
+
+func scanReport(sc interface{ Scan(...any) error }) (*Report, error) {
+	var rp Report
+	var resolvedAt sql.NullTime
+	if err := sc.Scan(&rp.ID, &rp.ReporterID, &rp.ReporterName, &rp.TargetType,
+		&rp.MessageID, &rp.TargetUserID, &rp.TargetUserName,
+		&rp.RoomID, &rp.Reason, &rp.Details, &rp.ContentPreview,
+		&rp.Status, &rp.Resolution, &rp.ResolvedBy, &resolvedAt, &rp.CreatedAt); err != nil {
+		return nil, err
+	}
+	if resolvedAt.Valid {
+		rp.ResolvedAt = &resolvedAt.Time
+	}
+	return &rp, nil
+}
+
+func (r *Repository) Get(ctx context.Context, id int64) (*Report, error) {
+	rp, err := scanReport(r.DB.QueryRowContext(ctx, selectReport+` WHERE mr.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return rp, err
+}
+
+// Queue: report theo trạng thái (mặc định open), cũ nhất trước; targetType rỗng = tất cả
+func (r *Repository) Queue(ctx context.Context, status, targetType string, beforeID int64, limit int) ([]*Report, error) {
+	q := selectReport + ` WHERE mr.status = ?`
+	args := []any{status}
+	if targetType != "" {
+		q += ` AND mr.target_type = ?`
+		args = append(args, targetType)
+	}
+	if beforeID > 0 {
+		q += ` AND mr.id < ?`
+		args = append(args, beforeID)
+	}
+	if status == StatusOpen {
+		q += ` ORDER BY mr.id ASC LIMIT ?`
+	} else {
+		q += ` ORDER BY mr.id DESC LIMIT ?`
+	}
+	args = append(args, limit)
+
+	rows, err := r.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*Report{}
+	for rows.Next() {
+		rp, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, r.attachOpenCounts(ctx, out)
+}
+
+// ListByReporter: report user đã gửi, mới nhất trước (user xem kết quả xử lý)
+// không lộ admin xử lý
+func (r *Repository) ListByReporter(ctx context.Context, reporterID, beforeID int64, limit int) ([]*Report, error) {
+	q := selectReport + ` WHERE mr.reporter_id = ?`
+	args := []any{reporterID}
+	if beforeID > 0 {
+		q += ` AND mr.id < ?`
+		args = append(args, beforeID)
+	}
+	q += ` ORDER BY mr.id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*Report{}
+	for rows.Next() {
+		rp, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		rp.ResolvedBy = 0
+		out = append(out, rp)
+	}
+	return out, rows.Err()
+}
+
+func (r *Repository) attachOpenCounts(ctx context.Context, list []*Report) error {
+	if len(list) == 0 {
+		return nil
+	}
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT target_type, COALESCE(message_id, 0), target_user_id, COUNT(*)
+		FROM moderation_reports
+		WHERE status = ?
+		GROUP BY target_type, COALESCE(message_id, 0), target_user_id
+	`, StatusOpen)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type key struct {
+		t      string
+		msg, u int64
+	}
+	counts := map[key]int{}
+	for rows.Next() {
+		var k key
+		var n int
+		if err := rows.Scan(&k.t, &k.msg, &k.u, &n); err != nil {
+			return err
+		}
+		counts[k] = n
+	}
+	for _, rp := range list {
+		rp.OpenReports = counts[key{rp.TargetType, rp.MessageID, rp.TargetUserID}]
+	}
+	return rows.Err()
+}
+
+// Resolve: đóng report + ghi audit trong cùng transaction
+// các report đang mở khác cùng đối tượng cũng được đóng theo (status, resolution giống nhau)
+// trả danh sách reporter cần báo kết quả
+func (r *Repository) Resolve(ctx context.Context, rp *Report, adminID int64, action, note string) ([]int64, error) {
+	status := StatusResolved
+	if action == ActionDismiss {
+		status = StatusDismissed
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// lấy reporter của các report sẽ đóng (khoá dòng để 2 admin không xử lý trùng)
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, reporter_id FROM moderation_reports
+		WHERE status = ? AND target_type = ? AND COALESCE(message_id, 0) = ? AND target_user_id = ?
+		FOR UPDATE
+	`, StatusOpen, rp.TargetType, rp.MessageID, rp.TargetUserID)
+	if err != nil {
+		return nil, err
+	}
+	var ids, reporters []int64
+	found := false
+	seen := map[int64]bool{}
+	for rows.Next() {
+		var id, reporter int64
+		if err := rows.Scan(&id, &reporter); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+		if id == rp.ID {
+			found = true
+		}
+		if reporter > 0 && !seen[reporter] {
+			seen[reporter] = true
+			reporters = append(reporters, reporter)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrAlreadyClosed
+	}
+
+	ph := strings.TrimRight(strings.Repeat("?,", len(ids)), ",")
+	args := []any{status, action, adminID, time.Now()}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE moderation_reports SET status = ?, resolution = ?, resolved_by = ?, resolved_at = ?
+		WHERE id IN (`+ph+`)
+	`, args...); err != nil {
+		return nil, err
+	}
+
+	if err := insertAudit(ctx, tx, &AuditEntry{
+		ReportID:     rp.ID,
+		AdminID:      adminID,
+		Action:       action,
+		TargetUserID: rp.TargetUserID,
+		MessageID:    rp.MessageID,
+		Note:         note,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return reporters, nil
+}
+
+func insertAudit(ctx context.Context, tx execer, a *AuditEntry) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO moderation_audit_log (report_id, admin_id, action, target_user_id, message_id, note)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, nullInt(a.ReportID), a.AdminID, a.Action, nullInt(a.TargetUserID), nullInt(a.MessageID), nullIfEmpty(a.Note))
+	return err
+}
+
+func (r *Repository) ListAudit(ctx context.Context, beforeID int64, limit int) ([]*AuditEntry, error) {
+	q := `
+		SELECT a.id, COALESCE(a.report_id, 0), a.admin_id, COALESCE(u.full_name, u.username, ''), a.action,
+		       COALESCE(a.target_user_id, 0), COALESCE(a.message_id, 0), COALESCE(a.note, ''), a.created_at
+		FROM moderation_audit_log a
+		LEFT JOIN users u ON u.id = a.admin_id
+	`
+	args := []any{}
+	if beforeID > 0 {
+		q += ` WHERE a.id < ?`
+		args = append(args, beforeID)
+	}
+	q += ` ORDER BY a.id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*AuditEntry{}
+	for rows.Next() {
+		var a AuditEntry
+		if err := rows.Scan(&a.ID, &a.ReportID, &a.AdminID, &a.AdminName, &a.Action,
+			&a.TargetUserID, &a.MessageID, &a.Note, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, &a)
+	}
+	return out, rows.Err()
+}
+
+// ===== Hành động lên đối tượng =====
+
+// MessageSnapshot: room, người gửi, nội dung của tin (lưu kèm report)
+func (r *Repository) MessageSnapshot(ctx context.Context, messageID int64) (roomID, senderID int64, content string, err error) {
+	err = r.DB.QueryRowContext(ctx, `
+		SELECT room_id, sender_id, COALESCE(content, '') FROM messages WHERE id = ?
+	`, messageID).Scan(&roomID, &senderID, &content)
+	return
+}
+
+// RemoveMessage: gỡ nội dung tin (giữ dòng để reply / thứ tự không vỡ), xoá attachment
+func (r *Repository) RemoveMessage(ctx context.Context, messageID int64) error {
+	return r.RemoveMessageAs(ctx, messageID, RemovedPlaceholder)
+}
+
+// RemoveMessageAs: như RemoveMessage, placeholder tuỳ người xoá (chính chủ / quản trị room)
+func (r *Repository) RemoveMessageAs(ctx context.Context, messageID int64, placeholder string) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := removeMessage(ctx, tx, messageID, placeholder); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func removeMessage(ctx context.Context, tx *sql.Tx, messageID int64, placeholder string) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE messages
+		SET content = ?, message_type = 'text', media_url = NULL, media_mime = NULL, media_size = NULL,
+		    media_poster_url = NULL, buttons = NULL, embeds = NULL, removed_at = ?
+		WHERE id = ?
+	`, placeholder, time.Now(), messageID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM attachments WHERE message_id = ?`, messageID)
+	return err
+}
+
+// Suspend: khoá tài khoản tới until (không login / refresh / gửi tin được)
+func (r *Repository) Suspend(ctx context.Context, userID int64, until time.Time) error {
+	res, err := r.DB.ExecContext(ctx, `UPDATE users SET suspended_until = ? WHERE id = ?`, until, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Unsuspend: mở khoá ngay, trả false nếu user không bị khoá
+func (r *Repository) Unsuspend(ctx context.Context, userID int64) (bool, error) {
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE users SET suspended_until = NULL WHERE id = ? AND suspended_until > NOW()
+	`, userID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// SuspendedUntil: zero = không bị khoá
+func (r *Repository) SuspendedUntil(ctx context.Context, userID int64) (time.Time, error) {
+	var until sql.NullTime
+	err := r.DB.QueryRowContext(ctx, `SELECT suspended_until FROM users WHERE id = ?`, userID).Scan(&until)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	if !until.Valid || !until.Time.After(time.Now()) {
+		return time.Time{}, nil
+	}
+	return until.Time, nil
+}
+
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullInt(v int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: v, Valid: v != 0}
+}
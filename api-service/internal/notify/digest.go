@@ -0,0 +1,150 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// số tin preview mỗi room trong email
+const digestPreviewPerRoom = 3
+
+// DigestWorker: định kỳ gom tin chưa đọc của user offline -> gửi 1 email tóm tắt
+type DigestWorker struct {
+	Repo      *Repository
+	Mailer    Mailer
+	Templates *Templates // nil = template mặc định
+
+	// IsOnline: user đang có kết nối realtime thì bỏ qua
+	IsOnline func(userID int64) bool
+	// UnsubscribeURL: link tắt digest (đã ký) cho user
+	UnsubscribeURL func(userID int64) string
+	// AppURL: link mở app trong email (optional)
+	AppURL string
+}
+
+// Start: chạy RunOnce mỗi interval cho tới khi ctx bị cancel
+func (d *DigestWorker) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				sent, err := d.RunOnce(ctx)
+				if err != nil {
+					log.Println("[digest] run error:", err)
+					continue
+				}
+				if sent > 0 {
+					log.Printf("[digest] sent %d emails", sent)
+				}
+			}
+		}
+	}()
+}
+
+// RunOnce: 1 lượt quét, trả số email đã gửi
+func (d *DigestWorker) RunOnce(ctx context.Context) (int, error) {
+	candidates, err := d.Repo.ListDigestCandidates(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, c := range candidates {
+		if err := ctx.Err(); err != nil {
+			return sent, err
+		}
+		if d.IsOnline != nil && d.IsOnline(c.UserID) {
+			continue
+		}
+
+		// chỉ lấy tin đã nằm chưa đọc đủ lâu (user offline >= DigestAfterMinutes)
+		now := time.Now()
+		before := now.Add(-time.Duration(c.DigestAfterMinutes) * time.Minute)
+
+		rooms, err := d.Repo.ListUnreadForDigest(ctx, c, before, digestPreviewPerRoom)
+		if err != nil {
+			log.Printf("[digest] user=%d: %v", c.UserID, err)
+			continue
+		}
+		if len(rooms) == 0 {
+			continue
+		}
+
+		mail, err := d.compose(c, rooms)
+		if err != nil {
+			return sent, err
+		}
+		if err := d.Mailer.Send(ctx, mail); err != nil {
+			log.Printf("[digest] send user=%d: %v", c.UserID, err)
+			continue
+		}
+
+		// mốc = before: tin mới hơn before sẽ vào digest sau, không bị gửi trùng
+		if err := d.Repo.MarkDigestSent(ctx, c.UserID, before); err != nil {
+			log.Printf("[digest] MarkDigestSent user=%d: %v", c.UserID, err)
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+func (d *DigestWorker) compose(c DigestCandidate, rooms []DigestRoom) (Mail, error) {
+	data := DigestData{Name: c.FullName, AppURL: d.AppURL}
+	if data.Name == "" {
+		data.Name = c.Username
+	}
+
+	for _, r := range rooms {
+		data.Total += r.Unread
+		data.Mentions += r.Mentions
+
+		v := DigestRoomView{Title: r.RoomName, Unread: r.Unread, Mentions: r.Mentions}
+		if v.Title == "" && len(r.Latest) > 0 {
+			v.Title = r.Latest[0].SenderName
+		}
+		for _, m := range r.Latest {
+			v.Lines = append(v.Lines, fmt.Sprintf("[%s] %s: %s", m.CreatedAt.Format("15:04 02/01"), m.SenderName, digestPreview(m)))
+		}
+		data.Rooms = append(data.Rooms, v)
+	}
+
+	if d.UnsubscribeURL != nil {
+		data.UnsubscribeURL = d.UnsubscribeURL(c.UserID)
+	}
+
+	tpl := d.Templates
+	if tpl == nil {
+		tpl = DefaultTemplates()
+	}
+	mail, err := tpl.Compose(TemplateDigest, c.Email, data)
+	if err != nil {
+		return Mail{}, err
+	}
+	mail.UnsubscribeURL = data.UnsubscribeURL
+	return mail, nil
+}
+
+func digestPreview(m DigestMessage) string {
+	switch m.MessageType {
+	case "image":
+		return "[Hình ảnh]"
+	case "file":
+		return "[Tệp đính kèm]"
+	case "audio":
+		return "[Tin nhắn thoại]"
+	}
+	r := []rune(strings.TrimSpace(m.Content))
+	if len(r) > 140 {
+		return string(r[:140]) + "…"
+	}
+	return string(r)
+}
@@ -0,0 +1,241 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+)
+
+// Mail: 1 email, Body = text/plain, HTML optional (gửi multipart/alternative)
+type Mail struct {
+	To             string
+	Subject        string
+	Body           string
+	HTML           string
+	UnsubscribeURL string // -> header List-Unsubscribe
+	Attachments    []Attachment
+}
+
+// Attachment: file đính kèm (có file -> gửi multipart/mixed)
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Mailer: mọi tính năng gửi email đi qua interface này (SMTP / SendGrid / SES)
+type Mailer interface {
+	Send(ctx context.Context, msg Mail) error
+}
+
+// ErrMailDisabled: chưa cấu hình provider nào -> tắt các tính năng email
+var ErrMailDisabled = errors.New("notify: email is not configured (MAIL_PROVIDER / SMTP_HOST)")
+
+// NewMailerFromEnv: MAIL_PROVIDER = smtp | sendgrid | ses
+// (rỗng -> smtp nếu có SMTP_HOST, không thì ErrMailDisabled).
+// Người gửi: MAIL_FROM, fallback SMTP_FROM cho config cũ
+func NewMailerFromEnv() (Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = os.Getenv("SMTP_FROM")
+	}
+
+	provider := strings.ToLower(strings.TrimSpace(os.Getenv("MAIL_PROVIDER")))
+	if provider == "" {
+		if os.Getenv("SMTP_HOST") == "" {
+			return nil, ErrMailDisabled
+		}
+		provider = "smtp"
+	}
+	if from == "" {
+		return nil, errors.New("notify: MAIL_FROM is required")
+	}
+	if _, err := mail.ParseAddress(from); err != nil {
+		return nil, fmt.Errorf("notify: invalid MAIL_FROM: %w", err)
+	}
+
+	switch provider {
+	case "smtp":
+		return newSMTPMailerFromEnv(from)
+	case "sendgrid":
+		return newSendGridMailerFromEnv(from)
+	case "ses":
+		return newSESMailerFromEnv(from)
+	}
+	return nil, fmt.Errorf("notify: unknown MAIL_PROVIDER %q (smtp, sendgrid, ses)", provider)
+}
+
+// SMTPMailer: gửi qua SMTP (STARTTLS nếu server hỗ trợ, net/smtp tự xử lý)
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// SMTP_HOST, SMTP_PORT (mặc định 587), SMTP_USER, SMTP_PASSWORD
+func newSMTPMailerFromEnv(from string) (*SMTPMailer, error) {
+	m := &SMTPMailer{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     os.Getenv("SMTP_PORT"),
+		Username: os.Getenv("SMTP_USER"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     from,
+	}
+	if m.Host == "" {
+		return nil, errors.New("notify: SMTP_HOST is required")
+	}
+	if m.Port == "" {
+		m.Port = "587"
+	}
+	return m, nil
+}
+
+// Send: net/smtp không nhận context, chỉ check trước khi gửi
+func (m *SMTPMailer) Send(ctx context.Context, msg Mail) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// From có thể dạng "CronChat <no-reply@x.com>"
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return fmt.Errorf("notify: invalid MAIL_FROM: %w", err)
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	data, err := buildMIME(m.From, msg)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(net.JoinHostPort(m.Host, m.Port), auth, from.Address, []string{msg.To}, data)
+}
+
+// buildMIME: text/plain, hoặc multipart/alternative khi có HTML,
+// có file đính kèm thì bọc thêm multipart/mixed
+func buildMIME(from string, msg Mail) ([]byte, error) {
+	var buf bytes.Buffer
+	writeHeader := func(k, v string) {
+		// chặn header injection
+		v = strings.NewReplacer("\r", "", "\n", "").Replace(v)
+		fmt.Fprintf(&buf, "%s: %s\r\n", k, v)
+	}
+	writeHeader("From", from)
+	writeHeader("To", msg.To)
+	writeHeader("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	writeHeader("Date", time.Now().Format(time.RFC1123Z))
+	writeHeader("MIME-Version", "1.0")
+	if msg.UnsubscribeURL != "" {
+		writeHeader("List-Unsubscribe", "<"+msg.UnsubscribeURL+">")
+	}
+
+	contentType, body, err := buildBody(msg)
+	if err != nil {
+		return nil, err
+	}
+	if len(msg.Attachments) > 0 {
+		if contentType, body, err = wrapAttachments(contentType, body, msg.Attachments); err != nil {
+			return nil, err
+		}
+	}
+	writeHeader("Content-Type", contentType)
+	if !strings.HasPrefix(contentType, "multipart/") {
+		writeHeader("Content-Transfer-Encoding", "8bit")
+	}
+	buf.WriteString("\r\n")
+	buf.Write(body)
+	return buf.Bytes(), nil
+}
+
+// buildBody: phần nội dung (text hoặc text + html), trả Content-Type + body
+func buildBody(msg Mail) (string, []byte, error) {
+	text := strings.ReplaceAll(msg.Body, "\n", "\r\n")
+	if msg.HTML == "" {
+		return `text/plain; charset="utf-8"`, []byte(text), nil
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, p := range []struct{ ct, content string }{
+		{`text/plain; charset="utf-8"`, text},
+		{`text/html; charset="utf-8"`, strings.ReplaceAll(msg.HTML, "\n", "\r\n")},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.ct},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		if _, err := w.Write([]byte(p.content)); err != nil {
+			return "", nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return "", nil, err
+	}
+	return `multipart/alternative; boundary="` + mw.Boundary() + `"`, body.Bytes(), nil
+}
+
+// wrapAttachments: multipart/mixed = nội dung + từng file (base64, 76 ký tự / dòng)
+func wrapAttachments(contentType string, content []byte, files []Attachment) (string, []byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	h := textproto.MIMEHeader{"Content-Type": {contentType}}
+	if !strings.HasPrefix(contentType, "multipart/") {
+		h.Set("Content-Transfer-Encoding", "8bit")
+	}
+	w, err := mw.CreatePart(h)
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := w.Write(content); err != nil {
+		return "", nil, err
+	}
+
+	for _, f := range files {
+		ct := f.ContentType
+		if ct == "" {
+			ct = "application/octet-stream"
+		}
+		name := mime.QEncoding.Encode("utf-8", strings.NewReplacer("\r", "", "\n", "", `"`, "").Replace(f.Filename))
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {ct + `; name="` + name + `"`},
+			"Content-Disposition":       {`attachment; filename="` + name + `"`},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		enc := base64.StdEncoding.EncodeToString(f.Data)
+		for len(enc) > 76 {
+			if _, err := w.Write([]byte(enc[:76] + "\r\n")); err != nil {
+				return "", nil, err
+			}
+			enc = enc[76:]
+		}
+		if _, err := w.Write([]byte(enc + "\r\n")); err != nil {
+			return "", nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return "", nil, err
+	}
+	return `multipart/mixed; boundary="` + mw.Boundary() + `"`, body.Bytes(), nil
+}
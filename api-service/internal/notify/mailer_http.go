@@ -0,0 +1,252 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"os"
+	"strings"
+	"time"
+)
+
+// =======================================
+// SendGrid (v3 mail/send)
+// =======================================
+
+const sendGridURL = "https://api.sendgrid.com/v3/mail/send"
+
+type SendGridMailer struct {
+	APIKey string
+	From   string
+	URL    string // override để test / proxy
+
+	http *http.Client
+}
+
+// SENDGRID_API_KEY
+func newSendGridMailerFromEnv(from string) (*SendGridMailer, error) {
+	key := os.Getenv("SENDGRID_API_KEY")
+	if key == "" {
+		return nil, errors.New("notify: SENDGRID_API_KEY is required")
+	}
+	return &SendGridMailer{
+		APIKey: key,
+		From:   from,
+		URL:    sendGridURL,
+		http:   &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (m *SendGridMailer) Send(ctx context.Context, msg Mail) error {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return fmt.Errorf("notify: invalid MAIL_FROM: %w", err)
+	}
+
+	type addr struct {
+		Email string `json:"email"`
+		Name  string `json:"name,omitempty"`
+	}
+	type content struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+	contents := []content{{Type: "text/plain", Value: msg.Body}}
+	if msg.HTML != "" {
+		contents = append(contents, content{Type: "text/html", Value: msg.HTML})
+	}
+	payload := map[string]any{
+		"personalizations": []map[string]any{{"to": []addr{{Email: msg.To}}}},
+		"from":             addr{Email: from.Address, Name: from.Name},
+		"subject":          msg.Subject,
+		"content":          contents,
+	}
+	if msg.UnsubscribeURL != "" {
+		payload["headers"] = map[string]string{"List-Unsubscribe": "<" + msg.UnsubscribeURL + ">"}
+	}
+	if len(msg.Attachments) > 0 {
+		files := make([]map[string]string, 0, len(msg.Attachments))
+		for _, f := range msg.Attachments {
+			files = append(files, map[string]string{
+				"content":     base64.StdEncoding.EncodeToString(f.Data),
+				"filename":    f.Filename,
+				"type":        f.ContentType,
+				"disposition": "attachment",
+			})
+		}
+		payload["attachments"] = files
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+m.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+	return doMailRequest(m.http, req, "sendgrid")
+}
+
+// =======================================
+// Amazon SES (v2 SendEmail, ký SigV4)
+// =======================================
+
+type SESMailer struct {
+	Region       string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string // optional (IAM role tạm thời)
+	From         string
+	Endpoint     string // mặc định https://email.{region}.amazonaws.com
+
+	http *http.Client
+}
+
+// SES_REGION (fallback AWS_REGION), AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN
+func newSESMailerFromEnv(from string) (*SESMailer, error) {
+	m := &SESMailer{
+		Region:       os.Getenv("SES_REGION"),
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		From:         from,
+		http:         &http.Client{Timeout: 10 * time.Second},
+	}
+	if m.Region == "" {
+		m.Region = os.Getenv("AWS_REGION")
+	}
+	if m.Region == "" || m.AccessKey == "" || m.SecretKey == "" {
+		return nil, errors.New("notify: SES_REGION, AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY are required")
+	}
+	m.Endpoint = "https://email." + m.Region + ".amazonaws.com"
+	return m, nil
+}
+
+func (m *SESMailer) Send(ctx context.Context, msg Mail) error {
+	type text struct {
+		Data    string `json:"Data"`
+		Charset string `json:"Charset"`
+	}
+	bodyPart := map[string]text{"Text": {Data: msg.Body, Charset: "UTF-8"}}
+	if msg.HTML != "" {
+		bodyPart["Html"] = text{Data: msg.HTML, Charset: "UTF-8"}
+	}
+	simple := map[string]any{
+		"Subject": text{Data: msg.Subject, Charset: "UTF-8"},
+		"Body":    bodyPart,
+	}
+	if msg.UnsubscribeURL != "" {
+		simple["Headers"] = []map[string]string{{"Name": "List-Unsubscribe", "Value": "<" + msg.UnsubscribeURL + ">"}}
+	}
+	payload := map[string]any{
+		"FromEmailAddress": m.From,
+		"Destination":      map[string]any{"ToAddresses": []string{msg.To}},
+		"Content":          map[string]any{"Simple": simple},
+	}
+	// có file đính kèm -> gửi MIME thô (Raw) thay cho Simple
+	if len(msg.Attachments) > 0 {
+		raw, err := buildMIME(m.From, msg)
+		if err != nil {
+			return err
+		}
+		payload["Content"] = map[string]any{"Raw": map[string]string{"Data": base64.StdEncoding.EncodeToString(raw)}}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.Endpoint+"/v2/email/outbound-emails", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	m.sign(req, body)
+	return doMailRequest(m.http, req, "ses")
+}
+
+// sign: AWS SigV4 (service "ses"), ký host + content-type + x-amz-*
+func (m *SESMailer) sign(req *http.Request, body []byte) {
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := hexSHA256(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	names := []string{"content-type", "host", "x-amz-date"}
+	if m.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", m.SessionToken)
+		names = append(names, "x-amz-security-token")
+	}
+
+	var canonHeaders strings.Builder
+	for _, n := range names {
+		v := req.Header.Get(n)
+		if n == "host" {
+			v = req.URL.Host
+		}
+		canonHeaders.WriteString(n + ":" + strings.TrimSpace(v) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonReq := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + m.Region + "/ses/aws4_request"
+	toSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hexSHA256([]byte(canonReq))}, "\n")
+
+	k := hmacSHA256([]byte("AWS4"+m.SecretKey), date)
+	k = hmacSHA256(k, m.Region)
+	k = hmacSHA256(k, "ses")
+	k = hmacSHA256(k, "aws4_request")
+	sig := hex.EncodeToString(hmacSHA256(k, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		m.AccessKey, scope, signedHeaders, sig,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hexSHA256(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// doMailRequest: 2xx = ok, còn lại trả lỗi kèm 1 đoạn body để debug
+func doMailRequest(c *http.Client, req *http.Request, provider string) error {
+	resp, err := c.Do(req)
+	if err != nil {
+		return fmt.Errorf("notify: %s: %w", provider, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("notify: %s: status %d: %s", provider, resp.StatusCode, strings.TrimSpace(string(b)))
+}
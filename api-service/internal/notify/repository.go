@@ -0,0 +1,230 @@
+package notify
+
+import (
+	"context"
+	"cronhustler/api-service/internal/unread"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// mức email digest
+const (
+	EmailDigestOff      = "off"
+	EmailDigestMentions = "mentions" // chỉ tin nhắn @username
+	EmailDigestAll      = "all"
+)
+
+// mặc định: offline 30 phút mà còn tin chưa đọc thì gửi
+const DefaultDigestAfterMinutes = 30
+
+var ErrInvalidSettings = errors.New("invalid notification settings")
+
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// Settings: cấu hình thông báo của 1 user (chưa có row -> mặc định)
+type Settings struct {
+	UserID             int64      `json:"user_id"`
+	EmailDigest        string     `json:"email_digest"`
+	DigestAfterMinutes int        `json:"digest_after_minutes"`
+	LastDigestAt       *time.Time `json:"last_digest_at,omitempty"`
+}
+
+func IsValidEmailDigest(v string) bool {
+	return v == EmailDigestOff || v == EmailDigestMentions || v == EmailDigestAll
+}
+
+func (r *Repository) GetSettings(ctx context.Context, userID int64) (*Settings, error) {
+	st := &Settings{
+		UserID:             userID,
+		EmailDigest:        EmailDigestAll,
+		DigestAfterMinutes: DefaultDigestAfterMinutes,
+	}
+
+	var last sql.NullTime
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT email_digest, digest_after_minutes, last_digest_at
+		FROM user_notification_settings
+		WHERE user_id = ?
+	`, userID).Scan(&st.EmailDigest, &st.DigestAfterMinutes, &last)
+	if err == sql.ErrNoRows {
+		return st, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if last.Valid {
+		st.LastDigestAt = &last.Time
+	}
+	return st, nil
+}
+
+func (r *Repository) UpdateSettings(ctx context.Context, userID int64, emailDigest string, afterMinutes int) error {
+	if !IsValidEmailDigest(emailDigest) || afterMinutes < 5 || afterMinutes > 7*24*60 {
+		return ErrInvalidSettings
+	}
+
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT INTO user_notification_settings (user_id, email_digest, digest_after_minutes)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			email_digest = VALUES(email_digest),
+			digest_after_minutes = VALUES(digest_after_minutes),
+			updated_at = CURRENT_TIMESTAMP
+	`, userID, emailDigest, afterMinutes)
+	return err
+}
+
+// Unsubscribe: link trong email -> tắt digest
+func (r *Repository) Unsubscribe(ctx context.Context, userID int64) error {
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT INTO user_notification_settings (user_id, email_digest)
+		VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE email_digest = VALUES(email_digest), updated_at = CURRENT_TIMESTAMP
+	`, userID, EmailDigestOff)
+	return err
+}
+
+func (r *Repository) MarkDigestSent(ctx context.Context, userID int64, at time.Time) error {
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT INTO user_notification_settings (user_id, last_digest_at)
+		VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE last_digest_at = VALUES(last_digest_at)
+	`, userID, at)
+	return err
+}
+
+// DigestCandidate: user có email + chưa tắt digest
+type DigestCandidate struct {
+	UserID             int64
+	Username           string
+	FullName           string
+	Email              string
+	EmailDigest        string
+	DigestAfterMinutes int
+	LastDigestAt       time.Time // zero = chưa gửi lần nào
+}
+
+func (r *Repository) ListDigestCandidates(ctx context.Context) ([]DigestCandidate, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT
+			u.id, u.username, COALESCE(u.full_name, ''), u.email,
+			COALESCE(s.email_digest, ?),
+			COALESCE(s.digest_after_minutes, ?),
+			s.last_digest_at
+		FROM users u
+		LEFT JOIN user_notification_settings s ON s.user_id = u.id
+		WHERE u.is_active = 1
+		  AND u.email IS NOT NULL AND u.email <> ''
+		  AND COALESCE(s.email_digest, ?) <> ?
+	`, EmailDigestAll, DefaultDigestAfterMinutes, EmailDigestAll, EmailDigestOff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []DigestCandidate
+	for rows.Next() {
+		var c DigestCandidate
+		var last sql.NullTime
+		if err := rows.Scan(&c.UserID, &c.Username, &c.FullName, &c.Email, &c.EmailDigest, &c.DigestAfterMinutes, &last); err != nil {
+			return nil, err
+		}
+		if last.Valid {
+			c.LastDigestAt = last.Time
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// DigestRoom: tóm tắt tin chưa đọc trong 1 room
+type DigestRoom struct {
+	RoomID   int64
+	RoomName string
+	Unread   int
+	Mentions int
+	Latest   []DigestMessage // vài tin mới nhất
+}
+
+type DigestMessage struct {
+	SenderName  string
+	Content     string
+	MessageType string
+	CreatedAt   time.Time
+	IsMention   bool
+}
+
+// ListUnreadForDigest: tin chưa đọc, chưa từng nằm trong digest, đủ cũ (before),
+// bỏ room đang mute. mentionsOnly -> chỉ lấy tin có @username
+func (r *Repository) ListUnreadForDigest(ctx context.Context, c DigestCandidate, before time.Time, perRoom int) ([]DigestRoom, error) {
+	mention := "%@" + c.Username + "%"
+	mentionsOnly := c.EmailDigest == EmailDigestMentions
+
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT
+			m.room_id,
+			COALESCE(rm_room.name, ''),
+			COALESCE(u.full_name, u.username),
+			COALESCE(m.content, ''),
+			m.message_type,
+			m.created_at,
+			(m.message_type = 'text' AND m.content LIKE ?) AS is_mention
+		FROM room_members rm
+		JOIN rooms rm_room ON rm_room.id = rm.room_id
+		JOIN messages m ON m.room_id = rm.room_id
+		JOIN users u ON u.id = m.sender_id
+		WHERE rm.user_id = ?
+		  AND (rm.muted_until IS NULL OR rm.muted_until < NOW())
+		  AND `+unread.MessageCond+`
+		  AND m.created_at > ?
+		  AND m.created_at <= ?
+		  AND (? = 0 OR (m.message_type = 'text' AND m.content LIKE ?))
+		ORDER BY m.room_id ASC, m.created_at DESC, m.id DESC
+	`, mention, c.UserID, c.LastDigestAt, before, boolInt(mentionsOnly), mention)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []DigestRoom
+	idx := map[int64]int{}
+	for rows.Next() {
+		var roomID int64
+		var roomName string
+		var msg DigestMessage
+		var isMention int
+		if err := rows.Scan(&roomID, &roomName, &msg.SenderName, &msg.Content, &msg.MessageType, &msg.CreatedAt, &isMention); err != nil {
+			return nil, err
+		}
+		msg.IsMention = isMention == 1
+
+		i, ok := idx[roomID]
+		if !ok {
+			out = append(out, DigestRoom{RoomID: roomID, RoomName: roomName})
+			i = len(out) - 1
+			idx[roomID] = i
+		}
+		out[i].Unread++
+		if msg.IsMention {
+			out[i].Mentions++
+		}
+		if len(out[i].Latest) < perRoom {
+			out[i].Latest = append(out[i].Latest, msg)
+		}
+	}
+	return out, rows.Err()
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,238 @@
+package notify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+)
+
+// tên template email
+const (
+	TemplatePasswordReset = "password_reset"
+	TemplateVerifyEmail   = "verify_email"
+	TemplateDigest        = "digest"
+	TemplateRoomDigest    = "room_digest"
+	TemplateAdminReport   = "admin_report"
+)
+
+// PasswordResetData: dữ liệu cho TemplatePasswordReset
+type PasswordResetData struct {
+	Name      string
+	ResetURL  string
+	ExpiresIn string // vd "30 phút"
+}
+
+// VerifyEmailData: dữ liệu cho TemplateVerifyEmail
+type VerifyEmailData struct {
+	Name      string
+	VerifyURL string
+	ExpiresIn string
+}
+
+// DigestData: dữ liệu cho TemplateDigest
+type DigestData struct {
+	Name           string
+	Total          int
+	Mentions       int
+	Rooms          []DigestRoomView
+	AppURL         string
+	UnsubscribeURL string
+}
+
+type DigestRoomView struct {
+	Title    string
+	Unread   int
+	Mentions int
+	Lines    []string // "[15:04 02/01] Tên: nội dung"
+}
+
+// RoomDigestData: dữ liệu cho TemplateRoomDigest (tổng kết ngày của 1 room)
+type RoomDigestData struct {
+	Name         string
+	Room         string
+	Date         string // "02/01/2006"
+	MessageCount int
+	ActiveUsers  int
+	TopMessages  []string
+	Decisions    []string
+	Files        []string
+	AppURL       string
+}
+
+// AdminReportData: dữ liệu cho TemplateAdminReport (file báo cáo đính kèm)
+type AdminReportData struct {
+	Title    string
+	From     string
+	To       string
+	Summary  []string
+	Filename string
+}
+
+// template mặc định: subject / text / html (html rỗng = chỉ gửi text)
+var defaultTemplates = map[string][3]string{
+	TemplatePasswordReset: {
+		`Đặt lại mật khẩu CronChat`,
+		`Chào {{.Name}},
+
+Có yêu cầu đặt lại mật khẩu cho tài khoản của bạn. Mở link sau để đặt mật khẩu mới{{if .ExpiresIn}} (hết hạn sau {{.ExpiresIn}}){{end}}:
+
+{{.ResetURL}}
+
+Nếu bạn không yêu cầu, hãy bỏ qua email này.
+`,
+		`<p>Chào {{.Name}},</p>
+<p>Có yêu cầu đặt lại mật khẩu cho tài khoản của bạn.{{if .ExpiresIn}} Link hết hạn sau {{.ExpiresIn}}.{{end}}</p>
+<p><a href="{{.ResetURL}}">Đặt mật khẩu mới</a></p>
+<p>Nếu bạn không yêu cầu, hãy bỏ qua email này.</p>
+`,
+	},
+	TemplateVerifyEmail: {
+		`Xác nhận email CronChat`,
+		`Chào {{.Name}},
+
+Xác nhận địa chỉ email của bạn bằng link sau{{if .ExpiresIn}} (hết hạn sau {{.ExpiresIn}}){{end}}:
+
+{{.VerifyURL}}
+`,
+		`<p>Chào {{.Name}},</p>
+<p>Xác nhận địa chỉ email của bạn{{if .ExpiresIn}} (link hết hạn sau {{.ExpiresIn}}){{end}}:</p>
+<p><a href="{{.VerifyURL}}">Xác nhận email</a></p>
+`,
+	},
+	TemplateDigest: {
+		`{{if .Mentions}}Bạn được nhắc đến {{.Mentions}} lần, {{.Total}} tin nhắn chưa đọc{{else}}Bạn có {{.Total}} tin nhắn chưa đọc{{end}}`,
+		`Chào {{.Name}},
+
+Trong lúc bạn vắng mặt có {{.Total}} tin nhắn chưa đọc trong {{len .Rooms}} cuộc trò chuyện.
+
+{{range .Rooms}}== {{.Title}} ({{.Unread}} chưa đọc{{if .Mentions}}, {{.Mentions}} nhắc đến bạn{{end}})
+{{range .Lines}}  {{.}}
+{{end}}
+{{end}}{{if .AppURL}}Mở CronChat: {{.AppURL}}
+
+{{end}}{{if .UnsubscribeURL}}--
+Không muốn nhận email này nữa? Huỷ đăng ký: {{.UnsubscribeURL}}
+{{end}}`,
+		``,
+	},
+	TemplateRoomDigest: {
+		`[{{.Room}}] Tổng kết ngày {{.Date}}: {{.MessageCount}} tin nhắn`,
+		`Chào {{.Name}},
+
+Ngày {{.Date}} room "{{.Room}}" có {{.MessageCount}} tin nhắn từ {{.ActiveUsers}} thành viên.
+{{if .TopMessages}}
+Nổi bật:
+{{range .TopMessages}}  {{.}}
+{{end}}{{end}}{{if .Decisions}}
+Quyết định:
+{{range .Decisions}}  {{.}}
+{{end}}{{end}}{{if .Files}}
+File:
+{{range .Files}}  {{.}}
+{{end}}{{end}}{{if .AppURL}}
+Mở CronChat: {{.AppURL}}
+{{end}}
+--
+Tắt email này trong phần cài đặt digest của room.
+`,
+		``,
+	},
+	TemplateAdminReport: {
+		`[CronChat] Báo cáo {{.Title}} {{.From}} - {{.To}}`,
+		`Báo cáo định kỳ "{{.Title}}" từ {{.From}} đến {{.To}}.
+{{range .Summary}}
+  {{.}}{{end}}
+
+Chi tiết theo ngày trong file đính kèm: {{.Filename}}
+`,
+		``,
+	},
+}
+
+type mailTemplate struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template // nil = không có bản HTML
+}
+
+// Templates: template email, file trong MAIL_TEMPLATE_DIR ghi đè mặc định:
+//
+//	{name}.subject.txt, {name}.txt, {name}.html
+type Templates struct {
+	byName map[string]*mailTemplate
+}
+
+// LoadTemplates: dir rỗng = chỉ dùng mặc định
+func LoadTemplates(dir string) (*Templates, error) {
+	t := &Templates{byName: map[string]*mailTemplate{}}
+	for name, def := range defaultTemplates {
+		src := def
+		if dir != "" {
+			for i, ext := range []string{".subject.txt", ".txt", ".html"} {
+				b, err := os.ReadFile(filepath.Join(dir, name+ext))
+				if err == nil {
+					src[i] = string(b)
+				} else if !errors.Is(err, os.ErrNotExist) {
+					return nil, err
+				}
+			}
+		}
+
+		mt := &mailTemplate{}
+		var err error
+		if mt.subject, err = texttemplate.New(name + ".subject").Parse(strings.TrimSpace(src[0])); err != nil {
+			return nil, fmt.Errorf("notify: template %s subject: %w", name, err)
+		}
+		if mt.text, err = texttemplate.New(name + ".txt").Parse(src[1]); err != nil {
+			return nil, fmt.Errorf("notify: template %s text: %w", name, err)
+		}
+		if strings.TrimSpace(src[2]) != "" {
+			if mt.html, err = htmltemplate.New(name + ".html").Parse(src[2]); err != nil {
+				return nil, fmt.Errorf("notify: template %s html: %w", name, err)
+			}
+		}
+		t.byName[name] = mt
+	}
+	return t, nil
+}
+
+// DefaultTemplates: template built-in (không đọc file)
+func DefaultTemplates() *Templates {
+	t, err := LoadTemplates("")
+	if err != nil {
+		panic(err) // template built-in sai cú pháp = lỗi code
+	}
+	return t
+}
+
+// Compose: render template name thành Mail gửi tới to
+func (t *Templates) Compose(name, to string, data any) (Mail, error) {
+	mt := t.byName[name]
+	if mt == nil {
+		return Mail{}, fmt.Errorf("notify: unknown mail template %q", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := mt.subject.Execute(&subject, data); err != nil {
+		return Mail{}, err
+	}
+	if err := mt.text.Execute(&text, data); err != nil {
+		return Mail{}, err
+	}
+	if mt.html != nil {
+		if err := mt.html.Execute(&html, data); err != nil {
+			return Mail{}, err
+		}
+	}
+	return Mail{
+		To:      to,
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Body:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
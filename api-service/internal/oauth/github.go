@@ -0,0 +1,97 @@
+package oauth
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// GitHubProvider: GitHub OAuth app (không phải OIDC) -> lấy user + email qua REST API
+type GitHubProvider struct {
+	ClientID      string
+	ClientSecret  string
+	AuthEndpoint  string
+	TokenEndpoint string
+	APIBaseURL    string
+}
+
+func NewGitHub(clientID, clientSecret string) Provider {
+	return &GitHubProvider{
+		ClientID:      clientID,
+		ClientSecret:  clientSecret,
+		AuthEndpoint:  "https://github.com/login/oauth/authorize",
+		TokenEndpoint: "https://github.com/login/oauth/access_token",
+		APIBaseURL:    "https://api.github.com",
+	}
+}
+
+func (p *GitHubProvider) Name() string { return "github" }
+
+func (p *GitHubProvider) AuthURL(state, _, codeChallenge, redirectURI string) string {
+	q := url.Values{
+		"client_id":             {p.ClientID},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {"read:user user:email"},
+		"state":                 {state},
+		"allow_signup":          {"false"},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+	return p.AuthEndpoint + "?" + q.Encode()
+}
+
+func (p *GitHubProvider) Exchange(ctx context.Context, code, codeVerifier, _, redirectURI string) (*Identity, error) {
+	tr, err := exchangeCode(ctx, p.TokenEndpoint, url.Values{
+		"code":          {code},
+		"client_id":     {p.ClientID},
+		"client_secret": {p.ClientSecret},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {codeVerifier},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var u struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := p.get(ctx, tr.AccessToken, "/user", &u); err != nil {
+		return nil, err
+	}
+	id := &Identity{
+		Provider: "github",
+		Subject:  strconv.FormatInt(u.ID, 10),
+		Name:     u.Name,
+		Username: UsernameHint(u.Login),
+	}
+
+	// email public trên profile có thể chưa verify -> lấy email primary đã verify
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.get(ctx, tr.AccessToken, "/user/emails", &emails); err != nil {
+		return nil, err
+	}
+	for _, e := range emails {
+		if e.Primary && e.Verified {
+			id.Email, id.EmailVerified = e.Email, true
+			break
+		}
+	}
+	return id, nil
+}
+
+func (p *GitHubProvider) get(ctx context.Context, token, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.APIBaseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	return doJSON(req, out)
+}
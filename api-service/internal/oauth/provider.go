@@ -0,0 +1,169 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Identity: user đã xác thực ở provider
+type Identity struct {
+	Provider      string
+	Subject       string // id ổn định của user phía provider (sub / GitHub id)
+	Email         string
+	EmailVerified bool
+	Name          string
+	Username      string // gợi ý username khi tạo tài khoản mới
+}
+
+// Provider: 1 nhà cung cấp đăng nhập (authorization code + PKCE)
+type Provider interface {
+	Name() string
+	// AuthURL: trang đăng nhập của provider, quay về redirectURI kèm code + state
+	AuthURL(state, nonce, codeChallenge, redirectURI string) string
+	// Exchange: đổi code lấy Identity (nonce chỉ OIDC dùng)
+	Exchange(ctx context.Context, code, codeVerifier, nonce, redirectURI string) (*Identity, error)
+}
+
+// Config: provider đang bật + cách xử lý user chưa có tài khoản
+type Config struct {
+	Providers       map[string]Provider
+	RedirectBaseURL string // URL public của API, callback = {base}/auth/oauth/{provider}/callback
+	AllowSignup     bool   // identity chưa link + email không khớp ai -> tạo user mới
+}
+
+// ErrDisabled: chưa cấu hình provider nào -> tắt social login
+var ErrDisabled = errors.New("oauth: no OAUTH_*_CLIENT_ID configured")
+
+// NewConfigFromEnv:
+//
+//	OAUTH_GOOGLE_CLIENT_ID / OAUTH_GOOGLE_CLIENT_SECRET
+//	OAUTH_GITHUB_CLIENT_ID / OAUTH_GITHUB_CLIENT_SECRET
+//	OAUTH_REDIRECT_BASE_URL (bắt buộc khi bật), OAUTH_ALLOW_SIGNUP=0 -> chỉ cho user đã có
+func NewConfigFromEnv() (*Config, error) {
+	c := &Config{
+		Providers:       map[string]Provider{},
+		RedirectBaseURL: strings.TrimRight(strings.TrimSpace(os.Getenv("OAUTH_REDIRECT_BASE_URL")), "/"),
+		AllowSignup:     os.Getenv("OAUTH_ALLOW_SIGNUP") != "0",
+	}
+	for _, p := range []struct {
+		name string
+		new  func(id, secret string) Provider
+	}{
+		{"google", NewGoogle},
+		{"github", NewGitHub},
+	} {
+		prefix := "OAUTH_" + strings.ToUpper(p.name) + "_"
+		id := strings.TrimSpace(os.Getenv(prefix + "CLIENT_ID"))
+		if id == "" {
+			continue
+		}
+		secret := os.Getenv(prefix + "CLIENT_SECRET")
+		if secret == "" {
+			return nil, fmt.Errorf("oauth: %sCLIENT_SECRET is required", prefix)
+		}
+		c.Providers[p.name] = p.new(id, secret)
+	}
+	if len(c.Providers) == 0 {
+		return nil, ErrDisabled
+	}
+	if c.RedirectBaseURL == "" {
+		return nil, errors.New("oauth: OAUTH_REDIRECT_BASE_URL is required")
+	}
+	return c, nil
+}
+
+// Names: provider đang bật, theo thứ tự tên (FE vẽ nút đăng nhập)
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Providers))
+	for n := range c.Providers {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CodeChallenge: PKCE S256 của verifier
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+var usernameUnsafe = regexp.MustCompile(`[^a-z0-9._]+`)
+
+// UsernameHint: "Nguyen.Van-A@x.com" -> "nguyen.van_a", rỗng nếu không còn ký tự dùng được
+func UsernameHint(s string) string {
+	if i := strings.IndexByte(s, '@'); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.Trim(usernameUnsafe.ReplaceAllString(strings.ToLower(s), "_"), "._")
+	if len(s) > 30 {
+		s = s[:30]
+	}
+	return s
+}
+
+// =======================================
+// HTTP dùng chung
+// =======================================
+
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+// tokenResponse: RFC 6749 (+ id_token của OIDC), lỗi trả qua field error
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func exchangeCode(ctx context.Context, tokenURL string, form url.Values) (*tokenResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var tr tokenResponse
+	if err := doJSON(req, &tr); err != nil {
+		return nil, err
+	}
+	if tr.Error != "" {
+		return nil, fmt.Errorf("oauth: token: %s %s", tr.Error, tr.ErrorDescription)
+	}
+	if tr.AccessToken == "" && tr.IDToken == "" {
+		return nil, errors.New("oauth: token: empty response")
+	}
+	return &tr, nil
+}
+
+func doJSON(req *http.Request, out any) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("oauth: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("oauth: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("oauth: %s %s: status %d", req.Method, req.URL.Host+req.URL.Path, resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("oauth: invalid response from %s: %w", req.URL.Host, err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package oauth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+var ErrUsernameTaken = errors.New("oauth: username already exists")
+
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// FindUserID: user đã link identity này, 0 = chưa link
+func (r *Repository) FindUserID(ctx context.Context, provider, subject string) (int64, error) {
+	var id int64
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT user_id FROM oauth_identities WHERE provider = ? AND subject = ?
+	`, provider, subject).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// FindUserIDByEmail: đúng 1 user có email này, 0 = không có / trùng nhiều user (không đoán)
+func (r *Repository) FindUserIDByEmail(ctx context.Context, email string) (int64, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT id FROM users WHERE email = ? LIMIT 2`, email)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(ids) != 1 {
+		return 0, nil
+	}
+	return ids[0], nil
+}
+
+// Link: gắn identity vào user (đã gắn rồi thì cập nhật email + lần login)
+func (r *Repository) Link(ctx context.Context, userID int64, id *Identity) error {
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT INTO oauth_identities (user_id, provider, subject, email, last_login_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON DUPLICATE KEY UPDATE email = VALUES(email), last_login_at = CURRENT_TIMESTAMP
+	`, userID, id.Provider, id.Subject, nullIfEmpty(id.Email))
+	return err
+}
+
+// NewUser: tài khoản tạo từ social login (password ngẫu nhiên đã hash, không ai biết)
+type NewUser struct {
+	Username     string
+	PasswordHash string
+	FullName     string
+	Email        string
+}
+
+// CreateUser: tạo user + link identity trong 1 transaction
+func (r *Repository) CreateUser(ctx context.Context, u *NewUser, id *Identity, createdIP string) (int64, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO users (username, password, role, full_name, email, is_active, created_ip)
+		VALUES (?, ?, 'user', ?, ?, 1, ?)
+	`, u.Username, u.PasswordHash, nullIfEmpty(u.FullName), nullIfEmpty(u.Email), nullIfEmpty(createdIP))
+	if err != nil {
+		if isDuplicate(err) {
+			return 0, ErrUsernameTaken
+		}
+		return 0, err
+	}
+	userID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO oauth_identities (user_id, provider, subject, email, last_login_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+	`, userID, id.Provider, id.Subject, nullIfEmpty(id.Email)); err != nil {
+		return 0, err
+	}
+	return userID, tx.Commit()
+}
+
+// isDuplicate: MySQL "Duplicate entry" (1062)
+func isDuplicate(err error) bool {
+	return strings.Contains(err.Error(), "Duplicate entry")
+}
+
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
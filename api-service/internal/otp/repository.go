@@ -0,0 +1,194 @@
+package otp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// mục đích mã OTP
+const (
+	PurposeLogin       = "login"        // bước 2 khi đăng nhập (2FA)
+	PurposeVerifyPhone = "verify_phone" // xác minh số điện thoại
+)
+
+const (
+	CodeLength  = 6
+	CodeTTL     = 5 * time.Minute
+	MaxAttempts = 5                // nhập sai quá số lần -> mã bị huỷ
+	ResendAfter = 60 * time.Second // tối thiểu giữa 2 lần gửi
+	MaxPerHour  = 5                // số mã / user / mục đích / giờ
+)
+
+var (
+	ErrInvalidCode     = errors.New("otp: invalid or expired code")
+	ErrTooManyAttempts = errors.New("otp: too many attempts, request a new code")
+	ErrResendTooSoon   = errors.New("otp: code sent recently, try again later")
+	ErrHourlyLimit     = errors.New("otp: too many codes requested, try again later")
+)
+
+type Repository struct {
+	DB     *sql.DB
+	Secret []byte // key HMAC mã (DB lộ cũng không đọc được mã)
+}
+
+func NewRepository(db *sql.DB, secret []byte) *Repository {
+	return &Repository{DB: db, Secret: secret}
+}
+
+// PhoneSecurity: trạng thái xác minh số + 2FA SMS của user
+type PhoneSecurity struct {
+	UserID        int64      `json:"user_id"`
+	VerifiedPhone string     `json:"-"` // số đã xác minh (OTP 2FA luôn gửi về số này)
+	VerifiedAt    *time.Time `json:"verified_at,omitempty"`
+	SMSTwoFactor  bool       `json:"sms_2fa"`
+}
+
+func (r *Repository) hash(userID int64, purpose, code string) string {
+	mac := hmac.New(sha256.New, r.Secret)
+	fmt.Fprintf(mac, "otp\n%d\n%s\n%s", userID, purpose, code)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func randomCode() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < CodeLength; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", CodeLength, n), nil
+}
+
+// Issue: tạo mã mới (mã cũ cùng mục đích bị huỷ), trả mã plain để gửi SMS
+func (r *Repository) Issue(ctx context.Context, userID int64, purpose, phone string, now time.Time) (string, error) {
+	var last sql.NullTime
+	var perHour int
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT MAX(created_at), COUNT(*) FROM phone_otps
+		WHERE user_id = ? AND purpose = ? AND created_at >= ?
+	`, userID, purpose, now.Add(-time.Hour)).Scan(&last, &perHour)
+	if err != nil {
+		return "", err
+	}
+	if last.Valid && now.Sub(last.Time) < ResendAfter {
+		return "", ErrResendTooSoon
+	}
+	if perHour >= MaxPerHour {
+		return "", ErrHourlyLimit
+	}
+
+	code, err := randomCode()
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE phone_otps SET consumed_at = ? WHERE user_id = ? AND purpose = ? AND consumed_at IS NULL
+	`, now, userID, purpose); err != nil {
+		return "", err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO phone_otps (user_id, purpose, phone, code_hash, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, userID, purpose, phone, r.hash(userID, purpose, code), now.Add(CodeTTL), now); err != nil {
+		return "", err
+	}
+	return code, tx.Commit()
+}
+
+// Verify: đúng -> đánh dấu đã dùng, trả số điện thoại mã đã gửi tới
+func (r *Repository) Verify(ctx context.Context, userID int64, purpose, code string, now time.Time) (string, error) {
+	var id int64
+	var phone, codeHash string
+	var attempts int
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT id, phone, code_hash, attempts FROM phone_otps
+		WHERE user_id = ? AND purpose = ? AND consumed_at IS NULL AND expires_at > ?
+		ORDER BY id DESC LIMIT 1
+	`, userID, purpose, now).Scan(&id, &phone, &codeHash, &attempts)
+	if err == sql.ErrNoRows {
+		return "", ErrInvalidCode
+	}
+	if err != nil {
+		return "", err
+	}
+	if attempts >= MaxAttempts {
+		return "", ErrTooManyAttempts
+	}
+
+	if !hmac.Equal([]byte(codeHash), []byte(r.hash(userID, purpose, code))) {
+		if _, err := r.DB.ExecContext(ctx, `UPDATE phone_otps SET attempts = attempts + 1 WHERE id = ?`, id); err != nil {
+			return "", err
+		}
+		if attempts+1 >= MaxAttempts {
+			return "", ErrTooManyAttempts
+		}
+		return "", ErrInvalidCode
+	}
+
+	// UPDATE có điều kiện: 2 request cùng mã chỉ 1 cái thắng
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE phone_otps SET consumed_at = ? WHERE id = ? AND consumed_at IS NULL
+	`, now, id)
+	if err != nil {
+		return "", err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return "", ErrInvalidCode
+	}
+	return phone, nil
+}
+
+// GetSecurity: chưa có row -> chưa xác minh, 2FA tắt
+func (r *Repository) GetSecurity(ctx context.Context, userID int64) (*PhoneSecurity, error) {
+	ps := &PhoneSecurity{UserID: userID}
+	var phone sql.NullString
+	var at sql.NullTime
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT verified_phone, verified_at, sms_2fa FROM user_phone_security WHERE user_id = ?
+	`, userID).Scan(&phone, &at, &ps.SMSTwoFactor)
+	if err == sql.ErrNoRows {
+		return ps, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ps.VerifiedPhone = phone.String
+	if at.Valid {
+		ps.VerifiedAt = &at.Time
+	}
+	return ps, nil
+}
+
+func (r *Repository) MarkPhoneVerified(ctx context.Context, userID int64, phone string, now time.Time) error {
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT INTO user_phone_security (user_id, verified_phone, verified_at)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE verified_phone = VALUES(verified_phone), verified_at = VALUES(verified_at)
+	`, userID, phone, now)
+	return err
+}
+
+func (r *Repository) SetSMSTwoFactor(ctx context.Context, userID int64, enabled bool) error {
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT INTO user_phone_security (user_id, sms_2fa) VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE sms_2fa = VALUES(sms_2fa)
+	`, userID, enabled)
+	return err
+}
@@ -0,0 +1,119 @@
+package provisioning
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+// tối đa user / 1 request bulk
+const MaxBatch = 500
+
+var (
+	ErrNotFound          = errors.New("provisioning: external id not found")
+	ErrInvalidExternalID = errors.New("provisioning: external_id is required (max 191 chars)")
+	ErrUsernameTaken     = errors.New("provisioning: username already exists")
+	ErrExternalIDTaken   = errors.New("provisioning: external_id already linked")
+)
+
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// NewUser: user tạo từ hệ thống HR / IdP (password đã hash, user tự đặt lại sau)
+type NewUser struct {
+	ExternalID   string
+	Username     string
+	PasswordHash string
+	Role         string
+	FullName     string
+	Email        string
+	Phone        string
+	Active       bool
+}
+
+func ValidateExternalID(id string) (string, error) {
+	id = strings.TrimSpace(id)
+	if id == "" || len(id) > 191 {
+		return "", ErrInvalidExternalID
+	}
+	return id, nil
+}
+
+// UserIDByExternal: user đã được provision với external id này
+func (r *Repository) UserIDByExternal(ctx context.Context, externalID string) (int64, error) {
+	var id int64
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT user_id FROM user_external_ids WHERE external_id = ?
+	`, externalID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, ErrNotFound
+	}
+	return id, err
+}
+
+// ExternalIDByUser: "" = user không đến từ provisioning
+func (r *Repository) ExternalIDByUser(ctx context.Context, userID int64) (string, error) {
+	var ext string
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT external_id FROM user_external_ids WHERE user_id = ?
+	`, userID).Scan(&ext)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return ext, err
+}
+
+// Create: tạo user + gắn external id trong 1 transaction.
+// 2 request cùng external id chạy song song -> 1 cái nhận ErrExternalIDTaken (caller chuyển sang update)
+func (r *Repository) Create(ctx context.Context, u *NewUser, createdIP string) (int64, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	active := 0
+	if u.Active {
+		active = 1
+	}
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO users (username, password, role, full_name, email, phone, is_active, created_ip)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, u.Username, u.PasswordHash, u.Role, nullIfEmpty(u.FullName), nullIfEmpty(u.Email), nullIfEmpty(u.Phone), active, nullIfEmpty(createdIP))
+	if err != nil {
+		if isDuplicate(err) {
+			return 0, ErrUsernameTaken
+		}
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO user_external_ids (external_id, user_id) VALUES (?, ?)
+	`, u.ExternalID, id); err != nil {
+		if isDuplicate(err) {
+			return 0, ErrExternalIDTaken
+		}
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// isDuplicate: MySQL "Duplicate entry" (1062) / SQLite "UNIQUE constraint failed"
+func isDuplicate(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "Duplicate entry") || strings.Contains(msg, "UNIQUE")
+}
+
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
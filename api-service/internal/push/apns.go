@@ -0,0 +1,149 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	apnsProductionHost  = "https://api.push.apple.com"
+	apnsDevelopmentHost = "https://api.sandbox.push.apple.com"
+)
+
+// APNsSender: APNs HTTP/2, auth bằng token (.p8 key, ES256)
+type APNsSender struct {
+	keyID  string
+	teamID string
+	topic  string // bundle id của app iOS
+	host   string
+	key    any
+
+	http *http.Client
+
+	mu        sync.Mutex
+	jwtToken  string
+	jwtIssued time.Time
+}
+
+// NewAPNsSender: keyFile = AuthKey_XXXX.p8 tải từ Apple Developer
+func NewAPNsSender(keyFile, keyID, teamID, topic string, production bool) (*APNsSender, error) {
+	b, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := jwt.ParseECPrivateKeyFromPEM(b)
+	if err != nil {
+		return nil, fmt.Errorf("apns: parse key: %w", err)
+	}
+	if keyID == "" || teamID == "" || topic == "" {
+		return nil, fmt.Errorf("apns: key id, team id and topic are required")
+	}
+
+	host := apnsDevelopmentHost
+	if production {
+		host = apnsProductionHost
+	}
+
+	return &APNsSender{
+		keyID:  keyID,
+		teamID: teamID,
+		topic:  topic,
+		host:   host,
+		key:    key,
+		// net/http tự dùng HTTP/2 qua TLS
+		http: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// providerToken: Apple yêu cầu refresh 20-60 phút, dùng lại trong 50 phút
+func (a *APNsSender) providerToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.jwtToken != "" && time.Since(a.jwtIssued) < 50*time.Minute {
+		return a.jwtToken, nil
+	}
+
+	t := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": a.teamID,
+		"iat": time.Now().Unix(),
+	})
+	t.Header["kid"] = a.keyID
+
+	signed, err := t.SignedString(a.key)
+	if err != nil {
+		return "", err
+	}
+	a.jwtToken = signed
+	a.jwtIssued = time.Now()
+	return signed, nil
+}
+
+func (a *APNsSender) Send(ctx context.Context, deviceToken string, n Notification) error {
+	bearer, err := a.providerToken()
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]any{
+		"aps": map[string]any{
+			"alert": map[string]string{
+				"title": n.Title,
+				"body":  n.Body,
+			},
+			"sound":     "default",
+			"thread-id": n.CollapseKey, // gom notification theo room
+		},
+	}
+	for k, v := range n.Data {
+		payload[k] = v
+	}
+	body, _ := json.Marshal(payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.host+"/3/device/"+deviceToken, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("authorization", "bearer "+bearer)
+	req.Header.Set("apns-topic", a.topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+	if n.CollapseKey != "" {
+		req.Header.Set("apns-collapse-id", n.CollapseKey) // tối đa 64 byte
+	}
+
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var apnsErr struct {
+		Reason string `json:"reason"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	_ = json.Unmarshal(raw, &apnsErr)
+
+	switch {
+	case resp.StatusCode == http.StatusGone,
+		apnsErr.Reason == "BadDeviceToken",
+		apnsErr.Reason == "Unregistered",
+		apnsErr.Reason == "DeviceTokenNotForTopic":
+		return ErrInvalidToken
+	default:
+		return fmt.Errorf("apns: send: %s: %s", resp.Status, apnsErr.Reason)
+	}
+}
@@ -0,0 +1,177 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// FCMSender: FCM HTTP v1, auth bằng service account (JWT RS256 -> OAuth2 access token)
+type FCMSender struct {
+	projectID   string
+	clientEmail string
+	tokenURI    string
+	privateKey  any
+
+	http *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+type serviceAccount struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// NewFCMSender: đọc file service account JSON tải từ Firebase console
+func NewFCMSender(serviceAccountFile string) (*FCMSender, error) {
+	b, err := os.ReadFile(serviceAccountFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var sa serviceAccount
+	if err := json.Unmarshal(b, &sa); err != nil {
+		return nil, fmt.Errorf("fcm: parse service account: %w", err)
+	}
+	if sa.ProjectID == "" || sa.ClientEmail == "" || sa.PrivateKey == "" {
+		return nil, errors.New("fcm: service account missing project_id/client_email/private_key")
+	}
+	if sa.TokenURI == "" {
+		sa.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(sa.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("fcm: parse private key: %w", err)
+	}
+
+	return &FCMSender{
+		projectID:   sa.ProjectID,
+		clientEmail: sa.ClientEmail,
+		tokenURI:    sa.TokenURI,
+		privateKey:  key,
+		http:        &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// token: access token cache tới gần hết hạn
+func (f *FCMSender) token(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.accessToken != "" && time.Now().Before(f.expiresAt.Add(-time.Minute)) {
+		return f.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   f.clientEmail,
+		"scope": fcmScope,
+		"aud":   f.tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(f.privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := f.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("fcm: token exchange: %s: %s", resp.Status, msg)
+	}
+
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", err
+	}
+
+	f.accessToken = tok.AccessToken
+	f.expiresAt = now.Add(time.Duration(tok.ExpiresIn) * time.Second)
+	return f.accessToken, nil
+}
+
+func (f *FCMSender) Send(ctx context.Context, deviceToken string, n Notification) error {
+	access, err := f.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	msg := map[string]any{
+		"token": deviceToken,
+		"notification": map[string]string{
+			"title": n.Title,
+			"body":  n.Body,
+		},
+		"data": n.Data,
+		"android": map[string]any{
+			"collapse_key": n.CollapseKey,
+			"priority":     "high",
+		},
+		"apns": map[string]any{
+			"headers": map[string]string{"apns-collapse-id": n.CollapseKey},
+		},
+	}
+	body, _ := json.Marshal(map[string]any{"message": msg})
+
+	endpoint := "https://fcm.googleapis.com/v1/projects/" + url.PathEscape(f.projectID) + "/messages:send"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+access)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := f.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	msgBody, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+	// token chết: 404 UNREGISTERED hoặc 400 INVALID_ARGUMENT về token
+	if resp.StatusCode == http.StatusNotFound || bytes.Contains(msgBody, []byte("UNREGISTERED")) {
+		return ErrInvalidToken
+	}
+	return fmt.Errorf("fcm: send: %s: %s", resp.Status, msgBody)
+}
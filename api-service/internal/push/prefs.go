@@ -0,0 +1,127 @@
+package push
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// room_id = 0 trong notification_mutes: tắt push mọi room
+const MuteAllRooms int64 = 0
+
+// RoomMute: 1 room đang tắt push, Until nil = tới khi bật lại
+type RoomMute struct {
+	RoomID int64      `json:"room_id"`
+	Until  *time.Time `json:"until,omitempty"`
+}
+
+// Preferences: cài đặt push của 1 user (chỉ các mute còn hiệu lực)
+type Preferences struct {
+	MuteAll      bool       `json:"mute_all"`
+	MuteAllUntil *time.Time `json:"mute_all_until,omitempty"`
+	MutedRooms   []RoomMute `json:"muted_rooms"`
+}
+
+// SetMute: tắt push room (MuteAllRooms = tất cả), until nil = vô thời hạn
+func (r *Repository) SetMute(ctx context.Context, userID, roomID int64, until *time.Time) error {
+	var untilVal any
+	if until != nil {
+		untilVal = *until
+	}
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT INTO notification_mutes (user_id, room_id, muted_until)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE muted_until = VALUES(muted_until)
+	`, userID, roomID, untilVal)
+	return err
+}
+
+func (r *Repository) ClearMute(ctx context.Context, userID, roomID int64) error {
+	_, err := r.DB.ExecContext(ctx, `
+		DELETE FROM notification_mutes WHERE user_id = ? AND room_id = ?
+	`, userID, roomID)
+	return err
+}
+
+func (r *Repository) GetPreferences(ctx context.Context, userID int64) (*Preferences, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT room_id, muted_until
+		FROM notification_mutes
+		WHERE user_id = ? AND (muted_until IS NULL OR muted_until > NOW())
+		ORDER BY room_id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	p := &Preferences{MutedRooms: []RoomMute{}}
+	for rows.Next() {
+		var roomID int64
+		var until sql.NullTime
+		if err := rows.Scan(&roomID, &until); err != nil {
+			return nil, err
+		}
+		var u *time.Time
+		if until.Valid {
+			u = &until.Time
+		}
+		if roomID == MuteAllRooms {
+			p.MuteAll, p.MuteAllUntil = true, u
+			continue
+		}
+		p.MutedRooms = append(p.MutedRooms, RoomMute{RoomID: roomID, Until: u})
+	}
+	return p, rows.Err()
+}
+
+// MutedUsers: trong userIDs, ai đang tắt push room này (hoặc tắt tất cả)
+func (r *Repository) MutedUsers(ctx context.Context, roomID int64, userIDs []int64) (map[int64]bool, error) {
+	return r.mutedUsers(ctx, []int64{roomID, MuteAllRooms}, userIDs)
+}
+
+// RoomMutedUsers: chỉ mute riêng room này (mute all không tính), dùng cho WS unread
+func (r *Repository) RoomMutedUsers(ctx context.Context, roomID int64, userIDs []int64) (map[int64]bool, error) {
+	return r.mutedUsers(ctx, []int64{roomID}, userIDs)
+}
+
+func (r *Repository) mutedUsers(ctx context.Context, roomIDs, userIDs []int64) (map[int64]bool, error) {
+	out := make(map[int64]bool)
+	if len(userIDs) == 0 {
+		return out, nil
+	}
+
+	args := make([]any, 0, len(roomIDs)+len(userIDs))
+	for _, id := range roomIDs {
+		args = append(args, id)
+	}
+	for _, id := range userIDs {
+		args = append(args, id)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT DISTINCT user_id
+		FROM notification_mutes
+		WHERE room_id IN (`+placeholders(len(roomIDs))+`)
+		  AND user_id IN (`+placeholders(len(userIDs))+`)
+		  AND (muted_until IS NULL OR muted_until > NOW())
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var uid int64
+		if err := rows.Scan(&uid); err != nil {
+			return nil, err
+		}
+		out[uid] = true
+	}
+	return out, rows.Err()
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
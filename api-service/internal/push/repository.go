@@ -0,0 +1,92 @@
+package push
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidPlatform = errors.New("invalid platform")
+
+const (
+	PlatformFCM  = "fcm"  // Android + web (Firebase)
+	PlatformAPNs = "apns" // iOS
+)
+
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+type DeviceToken struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Platform  string    `json:"platform"`
+	Token     string    `json:"token"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func IsValidPlatform(p string) bool {
+	return p == PlatformFCM || p == PlatformAPNs
+}
+
+// UpsertToken: token là unique, đăng nhập user khác trên cùng máy -> chuyển owner
+func (r *Repository) UpsertToken(ctx context.Context, userID int64, platform, token string) error {
+	platform = strings.ToLower(strings.TrimSpace(platform))
+	if !IsValidPlatform(platform) {
+		return ErrInvalidPlatform
+	}
+
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT INTO device_tokens (user_id, platform, token)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			user_id = VALUES(user_id),
+			platform = VALUES(platform),
+			updated_at = CURRENT_TIMESTAMP
+	`, userID, platform, strings.TrimSpace(token))
+	return err
+}
+
+// DeleteToken: logout / tắt push trên 1 thiết bị
+func (r *Repository) DeleteToken(ctx context.Context, userID int64, token string) error {
+	_, err := r.DB.ExecContext(ctx, `
+		DELETE FROM device_tokens WHERE user_id = ? AND token = ?
+	`, userID, strings.TrimSpace(token))
+	return err
+}
+
+// DeleteInvalidToken: FCM/APNs báo token chết -> xoá
+func (r *Repository) DeleteInvalidToken(ctx context.Context, token string) error {
+	_, err := r.DB.ExecContext(ctx, `DELETE FROM device_tokens WHERE token = ?`, token)
+	return err
+}
+
+func (r *Repository) ListByUser(ctx context.Context, userID int64) ([]DeviceToken, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT id, user_id, platform, token, created_at, updated_at
+		FROM device_tokens
+		WHERE user_id = ?
+		ORDER BY updated_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []DeviceToken
+	for rows.Next() {
+		var d DeviceToken
+		if err := rows.Scan(&d.ID, &d.UserID, &d.Platform, &d.Token, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,136 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("push: device token is no longer valid")
+	ErrQueueFull    = errors.New("push: queue full")
+)
+
+// Notification: nội dung 1 push (CollapseKey theo room -> máy chỉ giữ bản mới nhất)
+type Notification struct {
+	Title       string
+	Body        string
+	CollapseKey string
+	Data        map[string]string
+}
+
+// Sender: 1 provider (FCM / APNs)
+type Sender interface {
+	Send(ctx context.Context, deviceToken string, n Notification) error
+}
+
+type job struct {
+	UserID int64
+	N      Notification
+}
+
+// Service: worker gửi push nền, sender nil = tắt platform đó
+type Service struct {
+	Repo *Repository
+	FCM  Sender
+	APNs Sender
+	// WebPush: trình duyệt (VAPID), nil = tắt
+	WebPush *WebPushSender
+
+	jobs chan job
+}
+
+func NewService(repo *Repository, fcm, apns Sender) *Service {
+	return &Service{
+		Repo: repo,
+		FCM:  fcm,
+		APNs: apns,
+		jobs: make(chan job, 256),
+	}
+}
+
+// Start: chạy worker cho tới khi ctx bị cancel
+func (s *Service) Start(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case j := <-s.jobs:
+				s.deliver(ctx, j)
+			}
+		}
+	}()
+}
+
+// Enqueue: không block request, queue đầy thì bỏ (push chỉ là best-effort)
+func (s *Service) Enqueue(userID int64, n Notification) error {
+	select {
+	case s.jobs <- job{UserID: userID, N: n}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (s *Service) deliver(ctx context.Context, j job) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tokens, err := s.Repo.ListByUser(ctx, j.UserID)
+	if err != nil {
+		log.Println("[push] ListByUser error:", err)
+		return
+	}
+
+	for _, t := range tokens {
+		var sender Sender
+		switch t.Platform {
+		case PlatformFCM:
+			sender = s.FCM
+		case PlatformAPNs:
+			sender = s.APNs
+		}
+		if sender == nil {
+			continue
+		}
+
+		err := sender.Send(ctx, t.Token, j.N)
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, ErrInvalidToken) {
+			if err := s.Repo.DeleteInvalidToken(ctx, t.Token); err != nil {
+				log.Println("[push] DeleteInvalidToken error:", err)
+			}
+			continue
+		}
+		log.Printf("[push] user=%d platform=%s: %v", j.UserID, t.Platform, err)
+	}
+
+	if s.WebPush != nil {
+		s.deliverWeb(ctx, j)
+	}
+}
+
+func (s *Service) deliverWeb(ctx context.Context, j job) {
+	subs, err := s.Repo.ListWebSubscriptions(ctx, j.UserID)
+	if err != nil {
+		log.Println("[push] ListWebSubscriptions error:", err)
+		return
+	}
+	for _, sub := range subs {
+		err := s.WebPush.SendTo(ctx, sub, j.N)
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, ErrInvalidToken) {
+			if err := s.Repo.DeleteInvalidWebSubscription(ctx, sub.Endpoint); err != nil {
+				log.Println("[push] DeleteInvalidWebSubscription error:", err)
+			}
+			continue
+		}
+		log.Printf("[push] user=%d platform=web: %v", j.UserID, err)
+	}
+}
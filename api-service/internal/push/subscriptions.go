@@ -0,0 +1,123 @@
+package push
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// WebSubscription: PushSubscription của 1 trình duyệt
+type WebSubscription struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Endpoint  string    `json:"endpoint"`
+	P256dh    string    `json:"-"`
+	Auth      string    `json:"-"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UpsertWebSubscription: endpoint unique, trình duyệt đổi user -> chuyển owner
+func (r *Repository) UpsertWebSubscription(ctx context.Context, sub *WebSubscription) error {
+	if err := ValidateSubscription(sub.Endpoint, sub.P256dh, sub.Auth); err != nil {
+		return err
+	}
+	ua := sub.UserAgent
+	if len(ua) > 255 {
+		ua = ua[:255]
+	}
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT INTO webpush_subscriptions (user_id, endpoint, p256dh, auth, user_agent)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			user_id = VALUES(user_id),
+			p256dh = VALUES(p256dh),
+			auth = VALUES(auth),
+			user_agent = VALUES(user_agent),
+			updated_at = CURRENT_TIMESTAMP
+	`, sub.UserID, strings.TrimSpace(sub.Endpoint), sub.P256dh, sub.Auth, ua)
+	return err
+}
+
+func (r *Repository) DeleteWebSubscription(ctx context.Context, userID int64, endpoint string) error {
+	_, err := r.DB.ExecContext(ctx, `
+		DELETE FROM webpush_subscriptions WHERE user_id = ? AND endpoint = ?
+	`, userID, strings.TrimSpace(endpoint))
+	return err
+}
+
+// DeleteInvalidWebSubscription: push service trả 404/410 -> xoá
+func (r *Repository) DeleteInvalidWebSubscription(ctx context.Context, endpoint string) error {
+	_, err := r.DB.ExecContext(ctx, `DELETE FROM webpush_subscriptions WHERE endpoint = ?`, endpoint)
+	return err
+}
+
+func (r *Repository) ListWebSubscriptions(ctx context.Context, userID int64) ([]WebSubscription, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT id, user_id, endpoint, p256dh, auth, COALESCE(user_agent, ''), created_at
+		FROM webpush_subscriptions
+		WHERE user_id = ?
+		ORDER BY updated_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []WebSubscription
+	for rows.Next() {
+		var s WebSubscription
+		if err := rows.Scan(&s.ID, &s.UserID, &s.Endpoint, &s.P256dh, &s.Auth, &s.UserAgent, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// LoadOrCreateVAPID: key lưu trong DB (1 row), chưa có thì sinh mới.
+// INSERT IGNORE -> nhiều instance khởi động cùng lúc vẫn dùng chung 1 cặp key
+func (r *Repository) LoadOrCreateVAPID(ctx context.Context) (VAPIDKeys, error) {
+	gen, err := GenerateVAPIDKeys()
+	if err != nil {
+		return VAPIDKeys{}, err
+	}
+	if _, err := r.DB.ExecContext(ctx, `
+		INSERT IGNORE INTO webpush_vapid (id, public_key, private_key) VALUES (1, ?, ?)
+	`, gen.Public, gen.Private); err != nil {
+		return VAPIDKeys{}, err
+	}
+
+	var k VAPIDKeys
+	err = r.DB.QueryRowContext(ctx, `
+		SELECT public_key, private_key FROM webpush_vapid WHERE id = 1
+	`).Scan(&k.Public, &k.Private)
+	if errors.Is(err, sql.ErrNoRows) {
+		return VAPIDKeys{}, errors.New("push: vapid keys missing")
+	}
+	return k, err
+}
+
+// RotateVAPID: key mới, subscription cũ gắn với key cũ -> xoá hết (client tự subscribe lại)
+func (r *Repository) RotateVAPID(ctx context.Context, k VAPIDKeys) (int64, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO webpush_vapid (id, public_key, private_key) VALUES (1, ?, ?)
+		ON DUPLICATE KEY UPDATE public_key = VALUES(public_key), private_key = VALUES(private_key), created_at = CURRENT_TIMESTAMP
+	`, k.Public, k.Private); err != nil {
+		return 0, err
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM webpush_subscriptions`)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return n, tx.Commit()
+}
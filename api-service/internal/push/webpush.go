@@ -0,0 +1,326 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"cronhustler/api-service/internal/linkpreview"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// record size trong header aes128gcm (payload push luôn < 4KB nên chỉ 1 record)
+const webPushRecordSize = 4096
+
+// payload tối đa push service nhận (sau mã hoá)
+const webPushMaxPayload = 3800
+
+// timeout 1 lần gửi tới push service
+const webPushTimeout = 10 * time.Second
+
+var ErrInvalidSubscription = errors.New("push: invalid web push subscription (https endpoint, p256dh 65 bytes, auth 16 bytes)")
+
+var b64 = base64.RawURLEncoding
+
+// VAPIDKeys: cặp key P-256 dạng base64url (public = 65 byte uncompressed, private = 32 byte scalar)
+type VAPIDKeys struct {
+	Public  string `json:"public_key"`
+	Private string `json:"-"`
+}
+
+func GenerateVAPIDKeys() (VAPIDKeys, error) {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return VAPIDKeys{}, err
+	}
+	priv, err := k.Bytes()
+	if err != nil {
+		return VAPIDKeys{}, err
+	}
+	pub, err := k.PublicKey.Bytes()
+	if err != nil {
+		return VAPIDKeys{}, err
+	}
+	return VAPIDKeys{Public: b64.EncodeToString(pub), Private: b64.EncodeToString(priv)}, nil
+}
+
+// parse: private key + check public khớp
+func (k VAPIDKeys) parse() (*ecdsa.PrivateKey, error) {
+	raw, err := decodeB64(k.Private)
+	if err != nil {
+		return nil, fmt.Errorf("push: vapid private key: %w", err)
+	}
+	priv, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), raw)
+	if err != nil {
+		return nil, fmt.Errorf("push: vapid private key: %w", err)
+	}
+	pub, err := priv.PublicKey.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if b64.EncodeToString(pub) != strings.TrimRight(k.Public, "=") {
+		return nil, errors.New("push: vapid public key does not match private key")
+	}
+	return priv, nil
+}
+
+// decodeB64: client có thể gửi base64url có / không padding, hoặc base64 thường
+func decodeB64(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), "=")
+	s = strings.NewReplacer("+", "-", "/", "_").Replace(s)
+	return b64.DecodeString(s)
+}
+
+// WebPushSender: Web Push (RFC 8030) + VAPID (RFC 8292), payload mã hoá aes128gcm (RFC 8291)
+type WebPushSender struct {
+	Subject string // mailto:admin@example.com hoặc https://...
+
+	http *http.Client
+
+	mu   sync.Mutex
+	keys VAPIDKeys
+	priv *ecdsa.PrivateKey
+	jwts map[string]cachedJWT // theo audience (origin của push service)
+}
+
+type cachedJWT struct {
+	token string
+	exp   time.Time
+}
+
+func NewWebPushSender(keys VAPIDKeys, subject string) (*WebPushSender, error) {
+	if subject == "" {
+		return nil, errors.New("push: VAPID_SUBJECT is required (mailto: or https:)")
+	}
+	// endpoint do client gửi lên -> chỉ dial IP public, port 443 (chặn SSRF vào mạng nội bộ / metadata)
+	w := &WebPushSender{Subject: subject, http: &http.Client{
+		Timeout:   webPushTimeout,
+		Transport: linkpreview.NewSafeTransport(webPushTimeout, "443"),
+	}}
+	if err := w.SetKeys(keys); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// SetKeys: đổi key lúc chạy (rotate), JWT cũ bỏ hết
+func (w *WebPushSender) SetKeys(keys VAPIDKeys) error {
+	priv, err := keys.parse()
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.keys, w.priv, w.jwts = keys, priv, map[string]cachedJWT{}
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *WebPushSender) PublicKey() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.keys.Public
+}
+
+// vapidAuth: header Authorization, JWT ES256 cache ~12h theo audience
+func (w *WebPushSender) vapidAuth(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	aud := u.Scheme + "://" + u.Host
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if c, ok := w.jwts[aud]; ok && time.Now().Before(c.exp.Add(-time.Hour)) {
+		return "vapid t=" + c.token + ", k=" + w.keys.Public, nil
+	}
+	exp := time.Now().Add(12 * time.Hour)
+	tok, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": aud,
+		"exp": exp.Unix(),
+		"sub": w.Subject,
+	}).SignedString(w.priv)
+	if err != nil {
+		return "", err
+	}
+	w.jwts[aud] = cachedJWT{token: tok, exp: exp}
+	return "vapid t=" + tok + ", k=" + w.keys.Public, nil
+}
+
+// ValidateSubscription: endpoint https (port 443, không phải IP nội bộ) + key đúng độ dài
+// tên miền resolve ra IP nội bộ bị chặn lúc gửi (transport check IP mỗi lần dial)
+func ValidateSubscription(endpoint, p256dh, auth string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme != "https" || u.Host == "" || u.User != nil || len(endpoint) > 1024 {
+		return ErrInvalidSubscription
+	}
+	if port := u.Port(); port != "" && port != "443" {
+		return ErrInvalidSubscription
+	}
+	if ip, err := netip.ParseAddr(u.Hostname()); err == nil && !linkpreview.IsPublicIP(ip) {
+		return ErrInvalidSubscription
+	}
+	pub, err := decodeB64(p256dh)
+	if err != nil || len(pub) != 65 || pub[0] != 0x04 {
+		return ErrInvalidSubscription
+	}
+	if a, err := decodeB64(auth); err != nil || len(a) != 16 {
+		return ErrInvalidSubscription
+	}
+	return nil
+}
+
+// SendTo: gửi 1 notification tới 1 subscription (404/410 -> ErrInvalidToken)
+func (w *WebPushSender) SendTo(ctx context.Context, sub WebSubscription, n Notification) error {
+	payload, err := json.Marshal(map[string]any{
+		"title": n.Title,
+		"body":  n.Body,
+		"tag":   n.CollapseKey,
+		"data":  n.Data,
+	})
+	if err != nil {
+		return err
+	}
+	if len(payload) > webPushMaxPayload {
+		return fmt.Errorf("push: web push payload too large (%d bytes)", len(payload))
+	}
+
+	body, err := encryptWebPush(sub.P256dh, sub.Auth, payload)
+	if err != nil {
+		return err
+	}
+	auth, err := w.vapidAuth(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", auth)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int((24 * time.Hour).Seconds())))
+	req.Header.Set("Urgency", "high")
+	if n.CollapseKey != "" {
+		// Topic: tối đa 32 ký tự base64url -> bản mới thay bản cũ chưa giao
+		req.Header.Set("Topic", webPushTopic(n.CollapseKey))
+	}
+
+	resp, err := w.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode/100 == 2:
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrInvalidToken
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("webpush: status %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+}
+
+func webPushTopic(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return b64.EncodeToString(sum[:])[:32]
+}
+
+// encryptWebPush: RFC 8291 (aes128gcm, 1 record)
+func encryptWebPush(p256dh, authSecret string, plaintext []byte) ([]byte, error) {
+	uaPubBytes, err := decodeB64(p256dh)
+	if err != nil {
+		return nil, ErrInvalidSubscription
+	}
+	auth, err := decodeB64(authSecret)
+	if err != nil || len(auth) != 16 {
+		return nil, ErrInvalidSubscription
+	}
+
+	curve := ecdh.P256()
+	uaPub, err := curve.NewPublicKey(uaPubBytes)
+	if err != nil {
+		return nil, ErrInvalidSubscription
+	}
+	asPriv, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := asPriv.ECDH(uaPub)
+	if err != nil {
+		return nil, err
+	}
+	asPub := asPriv.PublicKey().Bytes()
+
+	// IKM = HKDF(auth, shared, "WebPush: info" || 0 || ua_public || as_public)
+	prkKey, err := hkdf.Extract(sha256.New, shared, auth)
+	if err != nil {
+		return nil, err
+	}
+	keyInfo := "WebPush: info\x00" + string(uaPubBytes) + string(asPub)
+	ikm, err := hkdf.Expand(sha256.New, prkKey, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// 0x02 = delimiter của record cuối
+	record := append(append([]byte{}, plaintext...), 0x02)
+
+	// header: salt(16) | rs(4) | idlen(1) | keyid(as_public)
+	out := make([]byte, 0, 16+4+1+len(asPub)+len(record)+gcm.Overhead())
+	out = append(out, salt...)
+	out = binary.BigEndian.AppendUint32(out, webPushRecordSize)
+	out = append(out, byte(len(asPub)))
+	out = append(out, asPub...)
+	return gcm.Seal(out, nonce, record, nil), nil
+}
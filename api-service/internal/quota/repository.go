@@ -0,0 +1,220 @@
+package quota
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// loại giới hạn
+const (
+	KindMessagesPerMinute = "messages_per_minute"
+	KindMessagesPerDay    = "messages_per_day"
+	KindRoomsPerDay       = "rooms_per_day"
+)
+
+var Kinds = []string{KindMessagesPerMinute, KindMessagesPerDay, KindRoomsPerDay}
+
+// Limits: 0 = không giới hạn
+type Limits struct {
+	MessagesPerMinute int `json:"messages_per_minute"`
+	MessagesPerDay    int `json:"messages_per_day"`
+	RoomsPerDay       int `json:"rooms_per_day"`
+}
+
+// mặc định khi không cấu hình env
+var DefaultLimits = Limits{MessagesPerMinute: 30, MessagesPerDay: 5000, RoomsPerDay: 20}
+
+func (l Limits) Of(kind string) int {
+	switch kind {
+	case KindMessagesPerMinute:
+		return l.MessagesPerMinute
+	case KindMessagesPerDay:
+		return l.MessagesPerDay
+	case KindRoomsPerDay:
+		return l.RoomsPerDay
+	}
+	return 0
+}
+
+// Override: admin ghi đè cho 1 user (nil = dùng mặc định)
+type Override struct {
+	UserID            int64     `json:"user_id"`
+	MessagesPerMinute *int      `json:"messages_per_minute"`
+	MessagesPerDay    *int      `json:"messages_per_day"`
+	RoomsPerDay       *int      `json:"rooms_per_day"`
+	UpdatedBy         int64     `json:"updated_by"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// Status: trạng thái 1 giới hạn của user
+type Status struct {
+	Kind       string    `json:"kind"`
+	Limit      int       `json:"limit"` // 0 = không giới hạn
+	Used       int       `json:"used"`
+	Remaining  int       `json:"remaining"`
+	ResetsAt   time.Time `json:"resets_at"`
+	Overridden bool      `json:"overridden,omitempty"`
+}
+
+func (st *Status) Exceeded() bool {
+	return st.Limit > 0 && st.Used >= st.Limit
+}
+
+type Repository struct {
+	DB       *sql.DB
+	Defaults Limits
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db, Defaults: DefaultLimits}
+}
+
+func (r *Repository) GetOverride(ctx context.Context, userID int64) (*Override, error) {
+	var o Override
+	var perMin, perDay, rooms sql.NullInt64
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT user_id, messages_per_minute, messages_per_day, rooms_per_day, updated_by, updated_at
+		FROM user_quota_overrides WHERE user_id = ?
+	`, userID).Scan(&o.UserID, &perMin, &perDay, &rooms, &o.UpdatedBy, &o.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	o.MessagesPerMinute = nullInt(perMin)
+	o.MessagesPerDay = nullInt(perDay)
+	o.RoomsPerDay = nullInt(rooms)
+	return &o, nil
+}
+
+func nullInt(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	n := int(v.Int64)
+	return &n
+}
+
+func (r *Repository) SetOverride(ctx context.Context, o *Override) error {
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT INTO user_quota_overrides (user_id, messages_per_minute, messages_per_day, rooms_per_day, updated_by)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			messages_per_minute = VALUES(messages_per_minute),
+			messages_per_day = VALUES(messages_per_day),
+			rooms_per_day = VALUES(rooms_per_day),
+			updated_by = VALUES(updated_by)
+	`, o.UserID, o.MessagesPerMinute, o.MessagesPerDay, o.RoomsPerDay, o.UpdatedBy)
+	return err
+}
+
+func (r *Repository) DeleteOverride(ctx context.Context, userID int64) (bool, error) {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM user_quota_overrides WHERE user_id = ?`, userID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// Effective: mặc định + override của user
+func (r *Repository) Effective(ctx context.Context, userID int64) (Limits, *Override, error) {
+	l := r.Defaults
+	o, err := r.GetOverride(ctx, userID)
+	if err != nil || o == nil {
+		return l, nil, err
+	}
+	if o.MessagesPerMinute != nil {
+		l.MessagesPerMinute = *o.MessagesPerMinute
+	}
+	if o.MessagesPerDay != nil {
+		l.MessagesPerDay = *o.MessagesPerDay
+	}
+	if o.RoomsPerDay != nil {
+		l.RoomsPerDay = *o.RoomsPerDay
+	}
+	return l, o, nil
+}
+
+// Check: trạng thái 1 loại giới hạn tại thời điểm now
+func (r *Repository) Check(ctx context.Context, userID int64, kind string, now time.Time) (*Status, error) {
+	l, o, err := r.Effective(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return r.status(ctx, userID, kind, l, o, now)
+}
+
+// State: mọi giới hạn (GET /me/limits)
+func (r *Repository) State(ctx context.Context, userID int64, now time.Time) ([]*Status, error) {
+	l, o, err := r.Effective(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*Status, 0, len(Kinds))
+	for _, k := range Kinds {
+		st, err := r.status(ctx, userID, k, l, o, now)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+func (r *Repository) status(ctx context.Context, userID int64, kind string, l Limits, o *Override, now time.Time) (*Status, error) {
+	st := &Status{Kind: kind, Limit: l.Of(kind)}
+	if o != nil {
+		switch kind {
+		case KindMessagesPerMinute:
+			st.Overridden = o.MessagesPerMinute != nil
+		case KindMessagesPerDay:
+			st.Overridden = o.MessagesPerDay != nil
+		case KindRoomsPerDay:
+			st.Overridden = o.RoomsPerDay != nil
+		}
+	}
+
+	// ngày tính theo giờ server, reset lúc 0h
+	y, m, d := now.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+
+	var err error
+	switch kind {
+	case KindMessagesPerMinute:
+		// cửa sổ trượt 60s: slot trống lại khi tin cũ nhất trong cửa sổ ra ngoài
+		var oldest sql.NullTime
+		err = r.DB.QueryRowContext(ctx, `
+			SELECT COUNT(*), MIN(created_at) FROM messages
+			WHERE sender_id = ? AND is_temp = 0 AND created_at >= ?
+		`, userID, now.Add(-time.Minute)).Scan(&st.Used, &oldest)
+		st.ResetsAt = now.Add(time.Minute)
+		if oldest.Valid {
+			st.ResetsAt = oldest.Time.Add(time.Minute)
+		}
+	case KindMessagesPerDay:
+		err = r.DB.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM messages
+			WHERE sender_id = ? AND is_temp = 0 AND created_at >= ?
+		`, userID, midnight).Scan(&st.Used)
+		st.ResetsAt = midnight.AddDate(0, 0, 1)
+	case KindRoomsPerDay:
+		err = r.DB.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM rooms WHERE created_by = ? AND created_at >= ?
+		`, userID, midnight).Scan(&st.Used)
+		st.ResetsAt = midnight.AddDate(0, 0, 1)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if st.Limit > 0 {
+		st.Remaining = st.Limit - st.Used
+		if st.Remaining < 0 {
+			st.Remaining = 0
+		}
+	}
+	return st, nil
+}
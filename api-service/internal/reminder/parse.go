@@ -0,0 +1,138 @@
+package reminder
+
+import (
+	"cronhustler/api-service/internal/cron"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrBadCommand = errors.New(`usage: /remind me|room <text> at <HH:MM|YYYY-MM-DD HH:MM> | in <10m|2h|1d> | cron "<m h dom mon dow>"`)
+	ErrBadTime    = errors.New("reminder: invalid time")
+	ErrPastTime   = errors.New("reminder: time is in the past")
+)
+
+// Spec: kết quả parse lệnh /remind hoặc body REST
+type Spec struct {
+	Target   string
+	Text     string
+	FireAt   time.Time
+	CronSpec string
+}
+
+// ParseCommand: "/remind me gọi khách at 15:30", "/remind room standup cron \"0 9 * * 1-5\""
+func ParseCommand(content string, now time.Time) (*Spec, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(content), "/remind")
+	if !ok {
+		return nil, ErrBadCommand
+	}
+	rest = strings.TrimSpace(rest)
+
+	target, rest, _ := strings.Cut(rest, " ")
+	if target != TargetMe && target != TargetRoom {
+		return nil, ErrBadCommand
+	}
+
+	// tìm từ khoá thời gian cuối cùng để text được chứa " at " / " in "
+	kw, idx := "", -1
+	for _, k := range []string{" at ", " in ", " cron ", " every "} {
+		if i := strings.LastIndex(rest, k); i > idx {
+			kw, idx = strings.TrimSpace(k), i
+		}
+	}
+	if idx <= 0 {
+		return nil, ErrBadCommand
+	}
+	text := strings.TrimSpace(rest[:idx])
+	when := strings.Trim(strings.TrimSpace(rest[idx+len(kw)+2:]), `"'`)
+	if text == "" || when == "" {
+		return nil, ErrBadCommand
+	}
+
+	sp := &Spec{Target: target, Text: text}
+	switch kw {
+	case "at":
+		t, err := ParseAt(when, now)
+		if err != nil {
+			return nil, err
+		}
+		sp.FireAt = t
+	case "in":
+		d, err := ParseIn(when)
+		if err != nil {
+			return nil, err
+		}
+		sp.FireAt = now.Add(d)
+	default: // cron | every
+		next, err := NextCron(when, now)
+		if err != nil {
+			return nil, err
+		}
+		sp.CronSpec = when
+		sp.FireAt = next
+	}
+	return sp, nil
+}
+
+// ParseAt: "15:04" (hôm nay, qua rồi thì ngày mai), "2006-01-02 15:04", RFC3339
+func ParseAt(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	loc := now.Location()
+
+	if t, err := time.ParseInLocation("15:04", s, loc); err == nil {
+		at := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+		if !at.After(now) {
+			at = at.AddDate(0, 0, 1)
+		}
+		return at, nil
+	}
+
+	if rest, ok := strings.CutPrefix(s, "tomorrow "); ok {
+		t, err := time.ParseInLocation("15:04", strings.TrimSpace(rest), loc)
+		if err != nil {
+			return time.Time{}, ErrBadTime
+		}
+		return time.Date(now.Year(), now.Month(), now.Day()+1, t.Hour(), t.Minute(), 0, 0, loc), nil
+	}
+
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02T15:04", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			if !t.After(now) {
+				return time.Time{}, ErrPastTime
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, ErrBadTime
+}
+
+// ParseIn: duration Go + hậu tố d (ngày): "10m", "1h30m", "2d"
+func ParseIn(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if n, ok := strings.CutSuffix(s, "d"); ok {
+		days, err := strconv.Atoi(n)
+		if err != nil || days <= 0 || days > 366 {
+			return 0, ErrBadTime
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < time.Minute {
+		return 0, ErrBadTime
+	}
+	return d, nil
+}
+
+func NextCron(spec string, now time.Time) (time.Time, error) {
+	sched, err := cron.Parse(spec)
+	if err != nil {
+		return time.Time{}, err
+	}
+	next := sched.Next(now)
+	if next.IsZero() {
+		return time.Time{}, cron.ErrInvalidSpec
+	}
+	return next, nil
+}
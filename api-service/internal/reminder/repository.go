@@ -0,0 +1,178 @@
+package reminder
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// nhắc ai
+const (
+	TargetMe   = "me"   // DM từ Reminder bot
+	TargetRoom = "room" // post vào room
+)
+
+const (
+	StatusActive    = "active"
+	StatusDone      = "done"
+	StatusCancelled = "cancelled"
+)
+
+var ErrNotFound = errors.New("reminder: not found")
+
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+type Reminder struct {
+	ID        int64     `json:"id"`
+	CreatorID int64     `json:"creator_id"`
+	Target    string    `json:"target"`  // me | room
+	RoomID    int64     `json:"room_id"` // room tạo lệnh (target=room thì post vào đây)
+	Text      string    `json:"text"`
+	FireAt    time.Time `json:"fire_at"`
+	CronSpec  string    `json:"cron,omitempty"` // rỗng = 1 lần
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const selectReminder = `
+	SELECT id, creator_id, target, room_id, text, fire_at, COALESCE(cron_spec, ''), status, created_at
+	FROM reminders
+`
+
+func scanReminder(sc interface{ Scan(...any) error }) (*Reminder, error) {
+	var rm Reminder
+	if err := sc.Scan(&rm.ID, &rm.CreatorID, &rm.Target, &rm.RoomID, &rm.Text, &rm.FireAt, &rm.CronSpec, &rm.Status, &rm.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &rm, nil
+}
+
+func (r *Repository) Create(ctx context.Context, rm *Reminder) error {
+	rm.Status = StatusActive
+	res, err := r.DB.ExecContext(ctx, `
+		INSERT INTO reminders (creator_id, target, room_id, text, fire_at, cron_spec, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, rm.CreatorID, rm.Target, rm.RoomID, rm.Text, rm.FireAt, nullIfEmpty(rm.CronSpec), rm.Status)
+	if err != nil {
+		return err
+	}
+	rm.ID, _ = res.LastInsertId()
+	rm.CreatedAt = time.Now()
+	return nil
+}
+
+// ListByUser: reminder user tạo (mặc định chỉ active)
+func (r *Repository) ListByUser(ctx context.Context, userID int64, includeAll bool) ([]*Reminder, error) {
+	q := selectReminder + ` WHERE creator_id = ?`
+	args := []any{userID}
+	if !includeAll {
+		q += ` AND status = ?`
+		args = append(args, StatusActive)
+	}
+	q += ` ORDER BY fire_at ASC, id ASC LIMIT 200`
+
+	rows, err := r.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*Reminder{}
+	for rows.Next() {
+		rm, err := scanReminder(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rm)
+	}
+	return out, rows.Err()
+}
+
+func (r *Repository) GetForUser(ctx context.Context, id, userID int64) (*Reminder, error) {
+	rm, err := scanReminder(r.DB.QueryRowContext(ctx, selectReminder+` WHERE id = ? AND creator_id = ?`, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return rm, err
+}
+
+func (r *Repository) Cancel(ctx context.Context, id, userID int64) error {
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE reminders SET status = ? WHERE id = ? AND creator_id = ? AND status = ?
+	`, StatusCancelled, id, userID, StatusActive)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Snooze: dời lần nhắc kế tiếp (reminder 1 lần đã done thì bật lại)
+func (r *Repository) Snooze(ctx context.Context, id, userID int64, until time.Time) error {
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE reminders SET fire_at = ?, status = ?
+		WHERE id = ? AND creator_id = ? AND status <> ?
+	`, until, StatusActive, id, userID, StatusCancelled)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *Repository) ListDue(ctx context.Context, now time.Time, limit int) ([]*Reminder, error) {
+	rows, err := r.DB.QueryContext(ctx, selectReminder+`
+		WHERE status = ? AND fire_at <= ?
+		ORDER BY fire_at ASC, id ASC
+		LIMIT ?
+	`, StatusActive, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*Reminder
+	for rows.Next() {
+		rm, err := scanReminder(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rm)
+	}
+	return out, rows.Err()
+}
+
+// Claim: chốt lần fire trước khi gửi (next nil = done), false = đã có instance khác xử lý
+func (r *Repository) Claim(ctx context.Context, rm *Reminder, next *time.Time) (bool, error) {
+	var res sql.Result
+	var err error
+	if next == nil {
+		res, err = r.DB.ExecContext(ctx, `
+			UPDATE reminders SET status = ? WHERE id = ? AND status = ? AND fire_at = ?
+		`, StatusDone, rm.ID, StatusActive, rm.FireAt)
+	} else {
+		res, err = r.DB.ExecContext(ctx, `
+			UPDATE reminders SET fire_at = ? WHERE id = ? AND status = ? AND fire_at = ?
+		`, *next, rm.ID, StatusActive, rm.FireAt)
+	}
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
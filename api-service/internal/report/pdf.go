@@ -0,0 +1,113 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PDF tối giản: A4, font Courier có sẵn (không nhúng font) -> chỉ ký tự Latin-1,
+// bảng căn cột bằng khoảng trắng
+const (
+	pdfPageW      = 595
+	pdfPageH      = 842
+	pdfMargin     = 40
+	pdfFontSize   = 9
+	pdfLineHeight = 12
+	pdfColWidth   = 16
+)
+
+func (t *Table) lines() []string {
+	out := []string{
+		"CronChat report: " + t.Title,
+		fmt.Sprintf("Period: %s - %s", t.From.Format("2006-01-02"), t.To.Format("2006-01-02")),
+		"Generated: " + time.Now().Format("2006-01-02 15:04 MST"),
+		"",
+	}
+	out = append(out, t.Summary...)
+	out = append(out, "")
+
+	row := func(cells []string) string {
+		var b strings.Builder
+		for _, c := range cells {
+			b.WriteString(fmt.Sprintf("%-*s", pdfColWidth, c))
+		}
+		return strings.TrimRight(b.String(), " ")
+	}
+	header := row(t.Columns)
+	out = append(out, header, strings.Repeat("-", len(header)))
+	for _, r := range t.Rows {
+		out = append(out, row(r))
+	}
+	return out
+}
+
+// pdfString: escape + bỏ ký tự ngoài Latin-1
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 32 && r < 127:
+			b.WriteRune(r)
+		case r >= 160 && r <= 255:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+func (t *Table) PDF() []byte {
+	lines := t.lines()
+	perPage := (pdfPageH - 2*pdfMargin) / pdfLineHeight
+
+	var pages [][]string
+	for len(lines) > 0 {
+		n := min(perPage, len(lines))
+		pages = append(pages, lines[:n])
+		lines = lines[n:]
+	}
+
+	var buf bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+	// 1 catalog, 2 pages, 3 font, rồi mỗi trang = page + content
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+
+	for i, pg := range pages {
+		var content strings.Builder
+		fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", pdfFontSize, pdfLineHeight, pdfMargin, pdfPageH-pdfMargin)
+		for _, l := range pg {
+			fmt.Fprintf(&content, "(%s) Tj T*\n", pdfString(l))
+		}
+		content.WriteString("ET")
+
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pdfPageW, pdfPageH, 5+2*i))
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return buf.Bytes()
+}
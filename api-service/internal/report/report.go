@@ -0,0 +1,183 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"cronhustler/api-service/internal/analytics"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// loại báo cáo
+const (
+	KindUserGrowth    = "user_growth"
+	KindMessageVolume = "message_volume"
+	KindStorage       = "storage"
+)
+
+// định dạng file
+const (
+	FormatCSV = "csv"
+	FormatPDF = "pdf"
+)
+
+var Kinds = []string{KindUserGrowth, KindMessageVolume, KindStorage}
+
+var ErrUnknownKind = errors.New("report: unknown report kind")
+
+func IsValidKind(k string) bool {
+	for _, v := range Kinds {
+		if v == k {
+			return true
+		}
+	}
+	return false
+}
+
+func IsValidFormat(f string) bool {
+	return f == FormatCSV || f == FormatPDF
+}
+
+// Table: 1 báo cáo dạng bảng (mỗi dòng 1 ngày), Summary = vài dòng tổng kết
+type Table struct {
+	Kind    string
+	Title   string
+	From    time.Time
+	To      time.Time
+	Columns []string
+	Rows    [][]string
+	Summary []string
+}
+
+// Build: số liệu theo ngày [from, to] từ analytics
+func Build(ctx context.Context, a *analytics.Repository, kind string, from, to time.Time) (*Table, error) {
+	t := &Table{Kind: kind, From: from, To: to}
+
+	switch kind {
+	case KindUserGrowth:
+		t.Title = "User growth"
+		t.Columns = []string{"date", "new_users", "total_users", "active_users"}
+		newUsers, err := a.NewUsersPerDay(ctx, from, to)
+		if err != nil {
+			return nil, err
+		}
+		dau, err := a.DailyActiveUsers(ctx, from, to)
+		if err != nil {
+			return nil, err
+		}
+		total, err := a.UsersBefore(ctx, from)
+		if err != nil {
+			return nil, err
+		}
+		start := total
+		var sumNew, peak int64
+		for i, p := range newUsers {
+			total += p.Value
+			sumNew += p.Value
+			peak = max(peak, dau[i].Value)
+			t.Rows = append(t.Rows, []string{p.Date, itoa(p.Value), itoa(total), itoa(dau[i].Value)})
+		}
+		t.Summary = []string{
+			fmt.Sprintf("New users: %d (total %d -> %d)", sumNew, start, total),
+			fmt.Sprintf("Peak daily active users: %d", peak),
+		}
+
+	case KindMessageVolume:
+		t.Title = "Message volume"
+		t.Columns = []string{"date", "messages", "active_rooms"}
+		msgs, err := a.MessagesPerDay(ctx, from, to)
+		if err != nil {
+			return nil, err
+		}
+		rooms, err := a.ActiveRoomsPerDay(ctx, from, to)
+		if err != nil {
+			return nil, err
+		}
+		var sum, peak int64
+		for i, p := range msgs {
+			sum += p.Value
+			peak = max(peak, p.Value)
+			t.Rows = append(t.Rows, []string{p.Date, itoa(p.Value), itoa(rooms[i].Value)})
+		}
+		avg := float64(0)
+		if len(msgs) > 0 {
+			avg = float64(sum) / float64(len(msgs))
+		}
+		t.Summary = []string{
+			fmt.Sprintf("Messages: %d (avg %.1f/day, peak %d)", sum, avg, peak),
+		}
+
+	case KindStorage:
+		t.Title = "Storage"
+		t.Columns = []string{"date", "bytes_uploaded", "total_bytes"}
+		points, err := a.StoragePerDay(ctx, from, to)
+		if err != nil {
+			return nil, err
+		}
+		total, err := a.StorageBefore(ctx, from)
+		if err != nil {
+			return nil, err
+		}
+		start := total
+		for _, p := range points {
+			total += p.Value
+			t.Rows = append(t.Rows, []string{p.Date, itoa(p.Value), itoa(total)})
+		}
+		t.Summary = []string{
+			fmt.Sprintf("Uploaded: %s (total %s -> %s)", humanBytes(total-start), humanBytes(start), humanBytes(total)),
+		}
+
+	default:
+		return nil, ErrUnknownKind
+	}
+	return t, nil
+}
+
+// Filename: "user_growth_2024-01-01_2024-01-31.csv"
+func (t *Table) Filename(format string) string {
+	return fmt.Sprintf("%s_%s_%s.%s", t.Kind, t.From.Format("2006-01-02"), t.To.Format("2006-01-02"), format)
+}
+
+// Render: file theo format, trả bytes + content type
+func (t *Table) Render(format string) ([]byte, string, error) {
+	switch format {
+	case FormatCSV:
+		b, err := t.CSV()
+		return b, "text/csv; charset=utf-8", err
+	case FormatPDF:
+		return t.PDF(), "application/pdf", nil
+	}
+	return nil, "", fmt.Errorf("report: unknown format %q", format)
+}
+
+func (t *Table) CSV() ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(t.Columns); err != nil {
+		return nil, err
+	}
+	if err := w.WriteAll(t.Rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), w.Error()
+}
+
+func itoa(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
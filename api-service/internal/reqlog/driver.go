@@ -0,0 +1,188 @@
+package reqlog
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+)
+
+// QueryError: lỗi DB của 1 request, giữ nguyên lỗi gốc (errors.Is / errors.As vẫn dùng được)
+type QueryError struct {
+	RequestID string
+	Err       error
+}
+
+func (e *QueryError) Error() string {
+	return e.Err.Error() + " (request_id=" + e.RequestID + ")"
+}
+
+func (e *QueryError) Unwrap() error { return e.Err }
+
+// WrapConnector: lỗi query chạy với ctx của request -> log kèm request_id + gắn id vào error
+// (repository dùng QueryContext / ExecContext với r.Context())
+func WrapConnector(c driver.Connector) driver.Connector {
+	return &connector{Connector: c}
+}
+
+type connector struct {
+	driver.Connector
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	dc, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: dc}, nil
+}
+
+// annotate: ErrSkip / ErrBadConn là tín hiệu nội bộ của database/sql, trả nguyên
+func annotate(ctx context.Context, query string, err error) error {
+	if err == nil || err == driver.ErrSkip || errors.Is(err, driver.ErrBadConn) {
+		return err
+	}
+	id := RequestID(ctx)
+	if id == "" {
+		return err
+	}
+	if len(query) > 200 {
+		query = query[:200] + "..."
+	}
+	slog.WarnContext(ctx, "db error", "err", err, "query", query)
+	return &QueryError{RequestID: id, Err: err}
+}
+
+type conn struct {
+	driver.Conn
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var (
+		st  driver.Stmt
+		err error
+	)
+	if pc, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		st, err = pc.PrepareContext(ctx, query)
+	} else {
+		st, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, annotate(ctx, query, err)
+	}
+	return &stmt{Stmt: st, query: query, conn: c.Conn}, nil
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if bc, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err := bc.BeginTx(ctx, opts)
+		return tx, annotate(ctx, "BEGIN", err)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ec, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	res, err := ec.ExecContext(ctx, query, args)
+	return res, annotate(ctx, query, err)
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	qc, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	rows, err := qc.QueryContext(ctx, query, args)
+	return rows, annotate(ctx, query, err)
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if sr, ok := c.Conn.(driver.SessionResetter); ok {
+		return sr.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type stmt struct {
+	driver.Stmt
+	query string
+	conn  driver.Conn // stmt bọc luôn có CheckNamedValue -> database/sql không hỏi conn nữa
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if ec, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err := ec.ExecContext(ctx, args)
+		return res, annotate(ctx, s.query, err)
+	}
+	vals, err := namedToValues(args)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.Stmt.Exec(vals)
+	return res, annotate(ctx, s.query, err)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if qc, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err := qc.QueryContext(ctx, args)
+		return rows, annotate(ctx, s.query, err)
+	}
+	vals, err := namedToValues(args)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.Stmt.Query(vals)
+	return rows, annotate(ctx, s.query, err)
+}
+
+func (s *stmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	if nc, ok := s.conn.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (s *stmt) ColumnConverter(idx int) driver.ValueConverter {
+	if cc, ok := s.Stmt.(driver.ColumnConverter); ok {
+		return cc.ColumnConverter(idx)
+	}
+	return driver.DefaultParameterConverter
+}
+
+func namedToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	vals := make([]driver.Value, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			return nil, errors.New("reqlog: driver does not support named parameters")
+		}
+		vals[i] = a.Value
+	}
+	return vals, nil
+}
@@ -0,0 +1,246 @@
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// xoá theo lô nhỏ để không giữ lock lâu trên bảng lớn
+const batchSize = 1000
+
+// nội dung thay cho tin có file đã bị xoá theo retention
+const ExpiredUploadPlaceholder = "🗑 File đã hết hạn lưu trữ"
+
+// Result: kết quả purge 1 category
+type Result struct {
+	Category string `json:"category"`
+	Days     int    `json:"days"`
+	Cutoff   string `json:"cutoff"`
+	Deleted  int64  `json:"deleted"`
+	Bytes    int64  `json:"bytes,omitempty"`   // chỉ uploads
+	Partial  bool   `json:"partial,omitempty"` // hết thời gian -> lần chạy sau xoá tiếp
+	Error    string `json:"error,omitempty"`
+}
+
+// Report: kết quả 1 lần chạy (ghi vào output của job run)
+type Report struct {
+	DryRun     bool      `json:"dry_run,omitempty"`
+	Results    []Result  `json:"results"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+type Purger struct {
+	DB            *sql.DB
+	Repo          *Repository
+	ChatUploadDir string
+
+	// RemoveUpload: xoá file ở object store, nil = xoá trong ChatUploadDir
+	RemoveUpload func(ctx context.Context, name string) error
+}
+
+func NewPurger(repo *Repository, chatUploadDir string) *Purger {
+	return &Purger{DB: repo.DB, Repo: repo, ChatUploadDir: chatUploadDir}
+}
+
+// Run: áp dụng mọi policy days > 0; dryRun = chỉ đếm
+func (p *Purger) Run(ctx context.Context, now time.Time, dryRun bool) (*Report, error) {
+	policies, err := p.Repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rep := &Report{DryRun: dryRun, Results: []Result{}, StartedAt: now}
+	for _, pol := range policies {
+		if pol.Days <= 0 {
+			continue
+		}
+		cutoff := now.AddDate(0, 0, -pol.Days)
+		res := Result{Category: pol.Category, Days: pol.Days, Cutoff: cutoff.Format(time.RFC3339)}
+
+		var err error
+		if dryRun {
+			res.Deleted, res.Bytes, err = p.count(ctx, pol.Category, cutoff)
+		} else {
+			res.Deleted, res.Bytes, err = p.purge(ctx, pol.Category, cutoff)
+		}
+		if err != nil {
+			// hết giờ giữa chừng: giữ số đã xoá, lần sau làm tiếp
+			if ctx.Err() != nil {
+				res.Partial = true
+			} else {
+				res.Error = err.Error()
+				log.Printf("[retention] %s: %v", pol.Category, err)
+			}
+		}
+		rep.Results = append(rep.Results, res)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	rep.FinishedAt = time.Now()
+	return rep, nil
+}
+
+func (p *Purger) count(ctx context.Context, category string, cutoff time.Time) (int64, int64, error) {
+	var n, bytes int64
+	var err error
+	switch category {
+	case CategoryMessages:
+		err = p.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM messages WHERE created_at < ?`, cutoff).Scan(&n)
+	case CategoryReceipts:
+		err = p.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM message_receipts WHERE seen_at < ?`, cutoff).Scan(&n)
+	case CategoryLoginAudit:
+		err = p.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM login_history WHERE created_at < ?`, cutoff).Scan(&n)
+	case CategoryUploads:
+		err = p.DB.QueryRowContext(ctx, `
+			SELECT COUNT(*), COALESCE(SUM(file_size), 0) FROM chat_uploads cu
+			WHERE cu.created_at < ? AND `+notRoomAvatar+`
+		`, cutoff).Scan(&n, &bytes)
+	}
+	return n, bytes, err
+}
+
+func (p *Purger) purge(ctx context.Context, category string, cutoff time.Time) (int64, int64, error) {
+	switch category {
+	case CategoryMessages:
+		// receipt / reaction / attachment xoá theo FK cascade, file mồ côi để janitor dọn
+		n, err := p.deleteBatches(ctx, `DELETE FROM messages WHERE created_at < ? ORDER BY id LIMIT ?`, cutoff)
+		return n, 0, err
+	case CategoryReceipts:
+		n, err := p.deleteBatches(ctx, `DELETE FROM message_receipts WHERE seen_at < ? ORDER BY id LIMIT ?`, cutoff)
+		return n, 0, err
+	case CategoryLoginAudit:
+		n, err := p.deleteBatches(ctx, `DELETE FROM login_history WHERE created_at < ? ORDER BY id LIMIT ?`, cutoff)
+		return n, 0, err
+	case CategoryUploads:
+		return p.purgeUploads(ctx, cutoff)
+	}
+	return 0, 0, ErrInvalidPolicy
+}
+
+func (p *Purger) deleteBatches(ctx context.Context, q string, cutoff time.Time) (int64, error) {
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		res, err := p.DB.ExecContext(ctx, q, cutoff, batchSize)
+		if err != nil {
+			return total, err
+		}
+		n, _ := res.RowsAffected()
+		total += n
+		if n < batchSize {
+			return total, nil
+		}
+	}
+}
+
+// avatar group / ảnh custom emoji cũng là chat upload -> không purge khi còn dùng
+const notRoomAvatar = `NOT EXISTS (
+	SELECT 1 FROM rooms r WHERE r.avatar_url = CONCAT('/static/chat_uploads/', cu.file_name)
+) AND NOT EXISTS (
+	SELECT 1 FROM reaction_emojis re WHERE re.image_url = CONCAT('/static/chat_uploads/', cu.file_name)
+)`
+
+// purgeUploads: xoá file trên đĩa + row, tin đang trỏ tới file -> placeholder
+func (p *Purger) purgeUploads(ctx context.Context, cutoff time.Time) (int64, int64, error) {
+	var total, bytes int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, bytes, err
+		}
+
+		rows, err := p.DB.QueryContext(ctx, `
+			SELECT file_name, file_size FROM chat_uploads cu
+			WHERE cu.created_at < ? AND `+notRoomAvatar+`
+			ORDER BY cu.created_at ASC
+			LIMIT 200
+		`, cutoff)
+		if err != nil {
+			return total, bytes, err
+		}
+		type upload struct {
+			name string
+			size int64
+		}
+		var batch []upload
+		for rows.Next() {
+			var u upload
+			if err := rows.Scan(&u.name, &u.size); err != nil {
+				rows.Close()
+				return total, bytes, err
+			}
+			batch = append(batch, u)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return total, bytes, err
+		}
+		if len(batch) == 0 {
+			return total, bytes, nil
+		}
+
+		for _, u := range batch {
+			if err := p.expireUpload(ctx, u.name); err != nil {
+				return total, bytes, err
+			}
+			total++
+			bytes += u.size
+		}
+	}
+}
+
+func (p *Purger) expireUpload(ctx context.Context, name string) error {
+	url := "/static/chat_uploads/" + name
+
+	tx, err := p.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE messages
+		SET content = ?, message_type = 'text', media_url = NULL, media_mime = NULL, media_size = NULL
+		WHERE content = ? OR media_url = ?
+	`, ExpiredUploadPlaceholder, url, url); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM attachments WHERE file_path = ? OR file_path = ?
+	`, url, name); err != nil {
+		return err
+	}
+	// file là thumb / medium -> attachment quay về dùng bản gốc
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE attachments
+		SET thumb_url = NULLIF(thumb_url, ?), medium_url = NULLIF(medium_url, ?)
+		WHERE thumb_url = ? OR medium_url = ?
+	`, url, url, url, url); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM chat_uploads WHERE file_name = ?`, name); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// row đã xoá: file còn sót (lỗi xoá) thì janitor dọn sau
+	if p.RemoveUpload != nil {
+		if err := p.RemoveUpload(ctx, filepath.Base(name)); err != nil {
+			log.Printf("[retention] remove upload %s: %v", name, err)
+		}
+	} else if p.ChatUploadDir != "" {
+		if err := os.Remove(filepath.Join(p.ChatUploadDir, filepath.Base(name))); err != nil && !os.IsNotExist(err) {
+			log.Printf("[retention] remove upload %s: %v", name, err)
+		}
+	}
+	return nil
+}
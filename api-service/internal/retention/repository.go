@@ -0,0 +1,98 @@
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// loại dữ liệu có thể đặt thời hạn lưu
+const (
+	CategoryMessages   = "messages"    // tin nhắn (kèm receipt / reaction / attachment theo cascade)
+	CategoryReceipts   = "receipts"    // đã xem / đã nhận
+	CategoryLoginAudit = "login_audit" // lịch sử đăng nhập
+	CategoryUploads    = "uploads"     // file chat upload
+)
+
+var Categories = []string{CategoryMessages, CategoryReceipts, CategoryLoginAudit, CategoryUploads}
+
+// giới hạn số ngày (0 = giữ vĩnh viễn)
+const MaxDays = 3650
+
+var ErrInvalidPolicy = errors.New("retention: unknown category or days out of range (0 = keep forever, max 3650)")
+
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// Policy: số ngày giữ lại của 1 loại dữ liệu
+type Policy struct {
+	Category  string     `json:"category"`
+	Days      int        `json:"days"` // 0 = không xoá
+	UpdatedBy int64      `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+func IsValidCategory(c string) bool {
+	for _, v := range Categories {
+		if v == c {
+			return true
+		}
+	}
+	return false
+}
+
+// List: đủ mọi category (chưa cấu hình -> days = 0)
+func (r *Repository) List(ctx context.Context) ([]Policy, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT category, days, updated_by, updated_at FROM retention_settings
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byCat := map[string]Policy{}
+	for rows.Next() {
+		var p Policy
+		var at time.Time
+		if err := rows.Scan(&p.Category, &p.Days, &p.UpdatedBy, &at); err != nil {
+			return nil, err
+		}
+		p.UpdatedAt = &at
+		byCat[p.Category] = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]Policy, 0, len(Categories))
+	for _, c := range Categories {
+		p, ok := byCat[c]
+		if !ok {
+			p = Policy{Category: c}
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func (r *Repository) Set(ctx context.Context, category string, days int, by int64) error {
+	if !IsValidCategory(category) || days < 0 || days > MaxDays {
+		return ErrInvalidPolicy
+	}
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT INTO retention_settings (category, days, updated_by)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			days = VALUES(days),
+			updated_by = VALUES(updated_by),
+			updated_at = CURRENT_TIMESTAMP
+	`, category, days, by)
+	return err
+}
@@ -0,0 +1,255 @@
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tin tự huỷ theo room: 1 phút .. 365 ngày (0 = tắt)
+const (
+	MinRoomTTL = time.Minute
+	MaxRoomTTL = 365 * 24 * time.Hour
+)
+
+// mỗi lô xoá bao nhiêu tin hết hạn
+const roomTTLBatch = 500
+
+var ErrInvalidRoomTTL = errors.New(`ttl must be "off" or a duration between 1m and 365d (e.g. 30m, 24h, 7d)`)
+
+// ParseRoomTTL: "off" / "" -> 0, "24h", "7d", "90m"
+func ParseRoomTTL(s string) (time.Duration, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	if s == "" || s == "off" || s == "0" {
+		return 0, nil
+	}
+	var d time.Duration
+	if n, ok := strings.CutSuffix(s, "d"); ok {
+		days, err := strconv.Atoi(n)
+		if err != nil {
+			return 0, ErrInvalidRoomTTL
+		}
+		d = time.Duration(days) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, ErrInvalidRoomTTL
+		}
+	}
+	if d < MinRoomTTL || d > MaxRoomTTL {
+		return 0, ErrInvalidRoomTTL
+	}
+	return d, nil
+}
+
+// RoomTTL: 0 = room không bật tin tự huỷ
+func (r *Repository) RoomTTL(ctx context.Context, roomID int64) (time.Duration, error) {
+	var secs sql.NullInt64
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT message_ttl_seconds FROM rooms WHERE id = ?
+	`, roomID).Scan(&secs)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(secs.Int64) * time.Second, nil
+}
+
+func (r *Repository) SetRoomTTL(ctx context.Context, roomID int64, ttl time.Duration) error {
+	if ttl != 0 && (ttl < MinRoomTTL || ttl > MaxRoomTTL) {
+		return ErrInvalidRoomTTL
+	}
+	var secs any
+	if ttl > 0 {
+		secs = int64(ttl / time.Second)
+	}
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE rooms SET message_ttl_seconds = ? WHERE id = ?
+	`, secs, roomID)
+	return err
+}
+
+// PurgeRoomTTL: xoá tin quá TTL của room, theo lô; onExpired nhận id đã xoá từng room
+// (gọi sau khi commit). File đính kèm không còn ai tham chiếu thì xoá luôn.
+func (p *Purger) PurgeRoomTTL(ctx context.Context, onExpired func(roomID int64, ids []int64)) (int64, error) {
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+
+		byRoom, files, err := p.expiredRoomMessages(ctx)
+		if err != nil {
+			return total, err
+		}
+		if len(byRoom) == 0 {
+			return total, nil
+		}
+
+		n, err := p.deleteRoomMessages(ctx, byRoom)
+		if err != nil {
+			return total, err
+		}
+		total += n
+
+		for _, f := range files {
+			if err := p.removeUnreferencedUpload(ctx, f); err != nil {
+				log.Printf("[retention] room ttl upload %s: %v", f, err)
+			}
+		}
+		if onExpired != nil {
+			for roomID, ids := range byRoom {
+				onExpired(roomID, ids)
+			}
+		}
+
+		if n < roomTTLBatch {
+			return total, nil
+		}
+	}
+}
+
+// expiredRoomMessages: 1 lô tin hết hạn (room -> ids) + file chat upload các tin đó dùng
+func (p *Purger) expiredRoomMessages(ctx context.Context) (map[int64][]int64, []string, error) {
+	rows, err := p.DB.QueryContext(ctx, `
+		SELECT m.id, m.room_id, COALESCE(m.content, ''), COALESCE(m.media_url, ''), COALESCE(m.media_poster_url, '')
+		FROM messages m
+		JOIN rooms r ON r.id = m.room_id
+		WHERE r.message_ttl_seconds > 0
+		  AND m.created_at < NOW() - INTERVAL r.message_ttl_seconds SECOND
+		ORDER BY m.id
+		LIMIT ?
+	`, roomTTLBatch)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	byRoom := map[int64][]int64{}
+	seen := map[string]bool{}
+	var files []string
+	addFile := func(ref string) {
+		name, ok := strings.CutPrefix(ref, "/static/chat_uploads/")
+		if ok && name != "" && !seen[name] {
+			seen[name] = true
+			files = append(files, name)
+		}
+	}
+
+	var ids []any
+	for rows.Next() {
+		var id, roomID int64
+		var content, mediaURL, posterURL string
+		if err := rows.Scan(&id, &roomID, &content, &mediaURL, &posterURL); err != nil {
+			return nil, nil, err
+		}
+		byRoom[roomID] = append(byRoom[roomID], id)
+		ids = append(ids, id)
+		addFile(content)
+		addFile(mediaURL)
+		addFile(posterURL)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(ids) == 0 {
+		return byRoom, nil, nil
+	}
+
+	arows, err := p.DB.QueryContext(ctx, `
+		SELECT file_path, COALESCE(thumb_url, ''), COALESCE(medium_url, '')
+		FROM attachments WHERE message_id IN (`+placeholders(len(ids))+`)
+	`, ids...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer arows.Close()
+	for arows.Next() {
+		var path, thumb, medium string
+		if err := arows.Scan(&path, &thumb, &medium); err != nil {
+			return nil, nil, err
+		}
+		if !strings.HasPrefix(path, "/") {
+			path = "/static/chat_uploads/" + path
+		}
+		addFile(path)
+		addFile(thumb)
+		addFile(medium)
+	}
+	return byRoom, files, arows.Err()
+}
+
+func (p *Purger) deleteRoomMessages(ctx context.Context, byRoom map[int64][]int64) (int64, error) {
+	var ids []any
+	for _, list := range byRoom {
+		for _, id := range list {
+			ids = append(ids, id)
+		}
+	}
+	in := placeholders(len(ids))
+
+	tx, err := p.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// ghim không có FK -> xoá tay; receipt / reaction / attachment theo cascade
+	if _, err := tx.ExecContext(ctx, `DELETE FROM room_pins WHERE message_id IN (`+in+`)`, ids...); err != nil {
+		return 0, err
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM messages WHERE id IN (`+in+`)`, ids...)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return n, nil
+}
+
+// removeUnreferencedUpload: tin khác (forward), avatar group hoặc custom emoji còn dùng file thì giữ
+func (p *Purger) removeUnreferencedUpload(ctx context.Context, name string) error {
+	url := "/static/chat_uploads/" + name
+
+	var one int
+	err := p.DB.QueryRowContext(ctx, `
+		SELECT 1 FROM messages WHERE content = ? OR media_url = ? OR media_poster_url = ?
+		UNION ALL SELECT 1 FROM attachments WHERE file_path = ? OR file_path = ? OR thumb_url = ? OR medium_url = ?
+		UNION ALL SELECT 1 FROM rooms WHERE avatar_url = ?
+		UNION ALL SELECT 1 FROM reaction_emojis WHERE image_url = ?
+		LIMIT 1
+	`, url, url, url, url, name, url, url, url, url).Scan(&one)
+	if err == nil {
+		return nil
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	if _, err := p.DB.ExecContext(ctx, `DELETE FROM chat_uploads WHERE file_name = ?`, name); err != nil {
+		return err
+	}
+	if p.RemoveUpload != nil {
+		return p.RemoveUpload(ctx, filepath.Base(name))
+	}
+	if p.ChatUploadDir != "" {
+		if err := os.Remove(filepath.Join(p.ChatUploadDir, filepath.Base(name))); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func placeholders(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strings.Repeat("?,", n-1) + "?"
+}
@@ -0,0 +1,212 @@
+package room
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	ErrRoomNotFound = errors.New("room: not found")
+	ErrNotMember    = errors.New("room: user is not a member of this room")
+	ErrNotGroup     = errors.New("room: only group rooms have an owner to transfer")
+)
+
+// AdminRoom: 1 dòng trong console admin (mọi room, không chỉ room admin tham gia)
+type AdminRoom struct {
+	ID             int64      `json:"id"`
+	Name           string     `json:"name"`
+	Type           string     `json:"type"`
+	CreatedBy      int64      `json:"created_by"`
+	IsActive       int        `json:"is_active"`
+	CreatedAt      time.Time  `json:"created_at"`
+	MemberCount    int        `json:"member_count"`
+	OwnerID        int64      `json:"owner_id,omitempty"` // 0 = không còn owner
+	MessageCount   int64      `json:"message_count"`
+	LastActivityAt *time.Time `json:"last_activity_at"` // tin cuối (nil = chưa có tin)
+}
+
+// AdminRoomFilter: zero value = bỏ qua
+type AdminRoomFilter struct {
+	Type         string // direct | group
+	Query        string // tìm theo tên
+	MinMembers   int
+	MaxMembers   int  // -1 = bỏ qua (0 = room rỗng)
+	InactiveDays int  // không có tin trong N ngày
+	NoOwner      bool // group không còn owner
+	BeforeID     int64
+	Limit        int
+}
+
+func (r *Repository) AdminListRooms(ctx context.Context, f AdminRoomFilter) ([]*AdminRoom, error) {
+	q := `
+		SELECT r.id, COALESCE(r.name, ''), r.type, r.created_by, r.is_active, r.created_at,
+		       COALESCE(mc.cnt, 0), COALESCE(mc.owner_id, 0),
+		       COALESCE(ms.cnt, 0), ms.last_at
+		FROM rooms r
+		LEFT JOIN (
+			SELECT room_id, COUNT(*) AS cnt, MAX(CASE WHEN member_role = 'owner' THEN user_id END) AS owner_id
+			FROM room_members GROUP BY room_id
+		) mc ON mc.room_id = r.id
+		LEFT JOIN (
+			SELECT room_id, COUNT(*) AS cnt, MAX(created_at) AS last_at
+			FROM messages WHERE is_temp = 0 GROUP BY room_id
+		) ms ON ms.room_id = r.id
+		WHERE 1 = 1`
+	args := []any{}
+	if f.Type != "" {
+		q += ` AND r.type = ?`
+		args = append(args, f.Type)
+	}
+	if f.Query != "" {
+		q += ` AND r.name LIKE ?`
+		args = append(args, "%"+f.Query+"%")
+	}
+	if f.MinMembers > 0 {
+		q += ` AND COALESCE(mc.cnt, 0) >= ?`
+		args = append(args, f.MinMembers)
+	}
+	if f.MaxMembers >= 0 {
+		q += ` AND COALESCE(mc.cnt, 0) <= ?`
+		args = append(args, f.MaxMembers)
+	}
+	if f.InactiveDays > 0 {
+		cutoff := time.Now().AddDate(0, 0, -f.InactiveDays)
+		q += ` AND COALESCE(ms.last_at, r.created_at) < ?`
+		args = append(args, cutoff)
+	}
+	if f.NoOwner {
+		q += ` AND r.type = 'group' AND mc.owner_id IS NULL`
+	}
+	if f.BeforeID > 0 {
+		q += ` AND r.id < ?`
+		args = append(args, f.BeforeID)
+	}
+	q += ` ORDER BY r.id DESC LIMIT ?`
+	args = append(args, f.Limit)
+
+	rows, err := r.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*AdminRoom{}
+	for rows.Next() {
+		var ar AdminRoom
+		var last sql.NullTime
+		if err := rows.Scan(&ar.ID, &ar.Name, &ar.Type, &ar.CreatedBy, &ar.IsActive, &ar.CreatedAt,
+			&ar.MemberCount, &ar.OwnerID, &ar.MessageCount, &last); err != nil {
+			return nil, err
+		}
+		if last.Valid {
+			ar.LastActivityAt = &last.Time
+		}
+		out = append(out, &ar)
+	}
+	return out, rows.Err()
+}
+
+func (r *Repository) AdminGetRoom(ctx context.Context, roomID int64) (*AdminRoom, error) {
+	var ar AdminRoom
+	var last sql.NullTime
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT r.id, COALESCE(r.name, ''), r.type, r.created_by, r.is_active, r.created_at,
+		       (SELECT COUNT(*) FROM room_members WHERE room_id = r.id),
+		       COALESCE((SELECT user_id FROM room_members WHERE room_id = r.id AND member_role = 'owner' LIMIT 1), 0),
+		       (SELECT COUNT(*) FROM messages WHERE room_id = r.id AND is_temp = 0),
+		       (SELECT MAX(created_at) FROM messages WHERE room_id = r.id AND is_temp = 0)
+		FROM rooms r WHERE r.id = ?
+	`, roomID).Scan(&ar.ID, &ar.Name, &ar.Type, &ar.CreatedBy, &ar.IsActive, &ar.CreatedAt,
+		&ar.MemberCount, &ar.OwnerID, &ar.MessageCount, &last)
+	if err == sql.ErrNoRows {
+		return nil, ErrRoomNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if last.Valid {
+		ar.LastActivityAt = &last.Time
+	}
+	return &ar, nil
+}
+
+// TransferOwnership: newOwner phải là member; owner cũ (nếu có) xuống admin
+func (r *Repository) TransferOwnership(ctx context.Context, roomID, newOwnerID int64) (int64, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var roomType string
+	err = tx.QueryRowContext(ctx, `SELECT type FROM rooms WHERE id = ? FOR UPDATE`, roomID).Scan(&roomType)
+	if err == sql.ErrNoRows {
+		return 0, ErrRoomNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if roomType != "group" {
+		return 0, ErrNotGroup
+	}
+
+	var role string
+	err = tx.QueryRowContext(ctx, `
+		SELECT member_role FROM room_members WHERE room_id = ? AND user_id = ?
+	`, roomID, newOwnerID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return 0, ErrNotMember
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var oldOwnerID int64
+	err = tx.QueryRowContext(ctx, `
+		SELECT user_id FROM room_members WHERE room_id = ? AND member_role = 'owner' LIMIT 1
+	`, roomID).Scan(&oldOwnerID)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	if oldOwnerID == newOwnerID {
+		return oldOwnerID, tx.Commit()
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE room_members SET member_role = 'admin' WHERE room_id = ? AND member_role = 'owner'
+	`, roomID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE room_members SET member_role = 'owner' WHERE room_id = ? AND user_id = ?
+	`, roomID, newOwnerID); err != nil {
+		return 0, err
+	}
+	return oldOwnerID, tx.Commit()
+}
+
+// ForceDeleteRoom: admin xoá không cần là member (cascade như DeleteRoom)
+func (r *Repository) ForceDeleteRoom(ctx context.Context, roomID int64) error {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM rooms WHERE id = ?`, roomID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrRoomNotFound
+	}
+	return nil
+}
+
+// GetMemberRole: owner | admin | moderator | member, không phải thành viên -> ErrNotMember
+func (r *Repository) GetMemberRole(ctx context.Context, roomID, userID int64) (string, error) {
+	var role string
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT member_role FROM room_members WHERE room_id = ? AND user_id = ?
+	`, roomID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", ErrNotMember
+	}
+	return role, err
+}
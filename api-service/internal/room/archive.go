@@ -0,0 +1,56 @@
+package room
+
+import (
+	"context"
+	"time"
+)
+
+// SetArchived: archive / bỏ archive room của riêng user (room_members.archived_at)
+func (r *Repository) SetArchived(ctx context.Context, roomID, userID int64, archived bool) (*time.Time, error) {
+	var at *time.Time
+	if archived {
+		t := time.Now().UTC().Truncate(time.Second)
+		at = &t
+	}
+
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE room_members SET archived_at = ?
+		WHERE room_id = ? AND user_id = ?
+	`, at, roomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// không đổi (đã archive sẵn) cũng ra 0 -> check lại membership
+		ok, err := r.IsUserInRoom(roomID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrNotMember
+		}
+	}
+	return at, nil
+}
+
+// ArchivedMembers: member đã archive room (không bắn unread update cho họ)
+func (r *Repository) ArchivedMembers(ctx context.Context, roomID int64) (map[int64]bool, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT user_id FROM room_members
+		WHERE room_id = ? AND archived_at IS NOT NULL
+	`, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[int64]bool{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out[id] = true
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,115 @@
+package room
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrDirectSelf: room direct cần 2 user khác nhau
+var ErrDirectSelf = errors.New("room: cannot create direct room with yourself")
+
+// directPair: cặp (a, b) theo thứ tự trong bảng direct_rooms (user_a < user_b)
+func directPair(a, b int64) (int64, int64) {
+	if a > b {
+		return b, a
+	}
+	return a, b
+}
+
+// DirectRoomName: tên room direct (FE hiển thị tên partner, không dùng tên này)
+func DirectRoomName(a, b int64) string {
+	lo, hi := directPair(a, b)
+	return "direct-" + strconv.FormatInt(lo, 10) + "-" + strconv.FormatInt(hi, 10)
+}
+
+// GetDirectRoomBetweenUsers: room direct của cặp (a, b), sql.ErrNoRows nếu chưa có
+// tra theo direct_rooms nên không bao giờ trả group có cả 2 user
+func (r *Repository) GetDirectRoomBetweenUsers(a, b int64) (*Room, error) {
+	lo, hi := directPair(a, b)
+	row := r.DB.QueryRow(`
+		SELECT r.id, r.name, r.type, r.created_by, r.is_active, r.created_at, r.updated_at
+		FROM direct_rooms d
+		JOIN rooms r ON r.id = d.room_id
+		WHERE d.user_a = ? AND d.user_b = ?
+	`, lo, hi)
+
+	var rm Room
+	if err := row.Scan(&rm.ID, &rm.Name, &rm.Type, &rm.CreatedBy, &rm.IsActive, &rm.CreatedAt, &rm.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &rm, nil
+}
+
+// CreateDirectRoom: lấy hoặc tạo room direct giữa createdBy và other (idempotent)
+// created = false khi cặp đã có room, kể cả khi request khác vừa tạo xong (unique key của direct_rooms)
+func (r *Repository) CreateDirectRoom(ctx context.Context, createdBy, other int64) (rm *Room, created bool, err error) {
+	if createdBy == other {
+		return nil, false, ErrDirectSelf
+	}
+	if rm, err := r.GetDirectRoomBetweenUsers(createdBy, other); err == nil {
+		return rm, false, nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
+	}
+
+	roomID, err := r.insertDirectRoom(ctx, createdBy, other)
+	if err != nil {
+		if !isDuplicate(err) {
+			return nil, false, err
+		}
+		// thua race với request khác -> trả room bên kia đã tạo
+		rm, err := r.GetDirectRoomBetweenUsers(createdBy, other)
+		return rm, false, err
+	}
+
+	rm, err = r.GetRoomByID(roomID)
+	if err != nil {
+		return nil, false, err
+	}
+	return rm, true, nil
+}
+
+func (r *Repository) insertDirectRoom(ctx context.Context, createdBy, other int64) (int64, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO rooms (name, type, created_by, is_active)
+		VALUES (?, 'direct', ?, 1)
+	`, DirectRoomName(createdBy, other), createdBy)
+	if err != nil {
+		return 0, err
+	}
+	roomID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	// giữ chỗ cặp trước khi thêm member: request trùng dừng ở đây, rollback cả room
+	lo, hi := directPair(createdBy, other)
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO direct_rooms (user_a, user_b, room_id) VALUES (?, ?, ?)
+	`, lo, hi, roomID); err != nil {
+		return 0, err
+	}
+
+	for _, uid := range []int64{lo, hi} {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO room_members (room_id, user_id, member_role) VALUES (?, ?, 'member')
+		`, roomID, uid); err != nil {
+			return 0, err
+		}
+	}
+	return roomID, tx.Commit()
+}
+
+// isDuplicate: MySQL "Duplicate entry" (1062)
+func isDuplicate(err error) bool {
+	return strings.Contains(err.Error(), "Duplicate entry")
+}
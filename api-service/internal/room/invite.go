@@ -0,0 +1,158 @@
+package room
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// trạng thái lời mời vào group
+const (
+	InviteStatusPending  = "pending"
+	InviteStatusAccepted = "accepted"
+	InviteStatusDeclined = "declined"
+)
+
+var (
+	ErrInviteNotFound   = errors.New("invite not found")
+	ErrInviteNotPending = errors.New("invite already answered")
+)
+
+// Invite: lời mời vào room (kèm tên room / người mời để FE hiển thị)
+type Invite struct {
+	ID          int64      `json:"id"`
+	RoomID      int64      `json:"room_id"`
+	RoomName    string     `json:"room_name"`
+	InviterID   int64      `json:"inviter_id"`
+	InviterName string     `json:"inviter_name"`
+	InviteeID   int64      `json:"invitee_id"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+}
+
+const inviteSelect = `
+	SELECT i.id, i.room_id, COALESCE(r.name, ''), i.inviter_id,
+	       COALESCE(NULLIF(u.full_name, ''), u.username, ''),
+	       i.invitee_id, i.status, i.created_at, i.responded_at
+	FROM room_invites i
+	JOIN rooms r ON r.id = i.room_id
+	LEFT JOIN users u ON u.id = i.inviter_id
+`
+
+// CreateInvite: mời lại user đã từ chối -> dùng lại row cũ, reset về pending
+func (r *Repository) CreateInvite(ctx context.Context, roomID, inviterID, inviteeID int64) (*Invite, error) {
+	res, err := r.DB.ExecContext(ctx, `
+		INSERT INTO room_invites (room_id, inviter_id, invitee_id, status)
+		VALUES (?, ?, ?, 'pending')
+		ON DUPLICATE KEY UPDATE
+			id = LAST_INSERT_ID(id),
+			inviter_id = VALUES(inviter_id),
+			status = 'pending',
+			created_at = NOW(),
+			responded_at = NULL
+	`, roomID, inviterID, inviteeID)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return r.GetInvite(ctx, id)
+}
+
+func (r *Repository) GetInvite(ctx context.Context, id int64) (*Invite, error) {
+	row := r.DB.QueryRowContext(ctx, inviteSelect+` WHERE i.id = ?`, id)
+	inv, err := scanInvite(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrInviteNotFound
+	}
+	return inv, err
+}
+
+// ListPendingInvites: lời mời đang chờ của user, mới nhất lên đầu
+func (r *Repository) ListPendingInvites(ctx context.Context, userID int64) ([]*Invite, error) {
+	rows, err := r.DB.QueryContext(ctx, inviteSelect+`
+		WHERE i.invitee_id = ? AND i.status = 'pending' AND r.is_active = 1
+		ORDER BY i.created_at DESC, i.id DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*Invite{}
+	for rows.Next() {
+		inv, err := scanInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, inv)
+	}
+	return out, rows.Err()
+}
+
+// RespondInvite: chỉ người được mời mới trả lời được, accept -> thêm vào room (role member)
+func (r *Repository) RespondInvite(ctx context.Context, id, userID int64, accept bool) (*Invite, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var roomID, inviteeID int64
+	var status string
+	err = tx.QueryRowContext(ctx, `
+		SELECT room_id, invitee_id, status FROM room_invites WHERE id = ? FOR UPDATE
+	`, id).Scan(&roomID, &inviteeID, &status)
+	if err == sql.ErrNoRows || (err == nil && inviteeID != userID) {
+		return nil, ErrInviteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status != InviteStatusPending {
+		return nil, ErrInviteNotPending
+	}
+
+	newStatus := InviteStatusDeclined
+	if accept {
+		newStatus = InviteStatusAccepted
+		// đã được add bằng đường khác thì thôi
+		if _, err := tx.ExecContext(ctx, `
+			INSERT IGNORE INTO room_members (room_id, user_id, member_role)
+			VALUES (?, ?, 'member')
+		`, roomID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE room_invites SET status = ?, responded_at = NOW() WHERE id = ?
+	`, newStatus, id); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetInvite(ctx, id)
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanInvite(sc rowScanner) (*Invite, error) {
+	var inv Invite
+	var responded sql.NullTime
+	if err := sc.Scan(&inv.ID, &inv.RoomID, &inv.RoomName, &inv.InviterID, &inv.InviterName,
+		&inv.InviteeID, &inv.Status, &inv.CreatedAt, &responded); err != nil {
+		return nil, err
+	}
+	if responded.Valid {
+		inv.RespondedAt = &responded.Time
+	}
+	return &inv, nil
+}
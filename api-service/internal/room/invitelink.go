@@ -0,0 +1,198 @@
+package room
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"math/big"
+	"time"
+)
+
+// mã join: 10 ký tự, bỏ 0/O/1/I/l cho dễ đọc
+const (
+	inviteCodeLen      = 10
+	inviteCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789"
+)
+
+var (
+	ErrInviteLinkNotFound = errors.New("invite link not found")
+	ErrInviteLinkExpired  = errors.New("invite link expired or used up")
+)
+
+// InviteLink: link / mã tự join group, MaxUses = 0 -> không giới hạn
+type InviteLink struct {
+	ID        int64      `json:"id"`
+	RoomID    int64      `json:"room_id"`
+	Code      string     `json:"code"`
+	CreatedBy int64      `json:"created_by"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	MaxUses   int        `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// newInviteCode: rand.Int cho từng ký tự -> không lệch như byte % len(alphabet)
+func newInviteCode() (string, error) {
+	b := make([]byte, inviteCodeLen)
+	max := big.NewInt(int64(len(inviteCodeAlphabet)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = inviteCodeAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// CreateInviteLink: expiresIn = 0 -> không hết hạn. Hạn tính bằng NOW() của DB,
+// cùng đồng hồ với chỗ so sánh (ListInviteLinks, JoinByInviteCode)
+func (r *Repository) CreateInviteLink(ctx context.Context, roomID, createdBy int64, expiresIn time.Duration, maxUses int) (*InviteLink, error) {
+	code, err := newInviteCode()
+	if err != nil {
+		return nil, err
+	}
+	var expiresSec any // NULL -> NOW() + INTERVAL NULL SECOND = NULL
+	if expiresIn > 0 {
+		expiresSec = int64(expiresIn / time.Second)
+	}
+	res, err := r.DB.ExecContext(ctx, `
+		INSERT INTO room_invite_links (room_id, code, created_by, expires_at, max_uses)
+		VALUES (?, ?, ?, NOW() + INTERVAL ? SECOND, ?)
+	`, roomID, code, createdBy, expiresSec, maxUses)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	l := &InviteLink{ID: id, RoomID: roomID, Code: code, CreatedBy: createdBy, MaxUses: maxUses}
+	var exp sql.NullTime
+	if err := r.DB.QueryRowContext(ctx, `
+		SELECT expires_at, created_at FROM room_invite_links WHERE id = ?
+	`, id).Scan(&exp, &l.CreatedAt); err != nil {
+		return nil, err
+	}
+	if exp.Valid {
+		l.ExpiresAt = &exp.Time
+	}
+	return l, nil
+}
+
+// ListInviteLinks: link còn dùng được của room
+func (r *Repository) ListInviteLinks(ctx context.Context, roomID int64) ([]InviteLink, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT id, room_id, code, created_by, expires_at, max_uses, uses, created_at
+		FROM room_invite_links
+		WHERE room_id = ? AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > NOW())
+		  AND (max_uses = 0 OR uses < max_uses)
+		ORDER BY id DESC
+	`, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []InviteLink{}
+	for rows.Next() {
+		var l InviteLink
+		var exp sql.NullTime
+		if err := rows.Scan(&l.ID, &l.RoomID, &l.Code, &l.CreatedBy, &exp, &l.MaxUses, &l.Uses, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		if exp.Valid {
+			l.ExpiresAt = &exp.Time
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
+
+// RevokeInviteLink: false = không có link này trong room
+func (r *Repository) RevokeInviteLink(ctx context.Context, roomID int64, code string) (bool, error) {
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE room_invite_links SET revoked_at = NOW()
+		WHERE room_id = ? AND code = ? AND revoked_at IS NULL
+	`, roomID, code)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// JoinByInviteCode: thêm user vào room của code (role member), trả về roomID.
+// joined = false nếu user đã ở trong room (không tính lượt dùng)
+func (r *Repository) JoinByInviteCode(ctx context.Context, code string, userID int64) (roomID int64, joined bool, err error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	var (
+		linkID, maxUses, uses int64
+		expired, revoked      bool
+		roomType              string
+		active                int
+	)
+	err = tx.QueryRowContext(ctx, `
+		SELECT l.id, l.room_id, l.expires_at IS NOT NULL AND l.expires_at <= NOW(), l.max_uses, l.uses,
+		       l.revoked_at IS NOT NULL, r.type, r.is_active
+		FROM room_invite_links l
+		JOIN rooms r ON r.id = l.room_id
+		WHERE l.code = ?
+		FOR UPDATE
+	`, code).Scan(&linkID, &roomID, &expired, &maxUses, &uses, &revoked, &roomType, &active)
+	if err == sql.ErrNoRows {
+		return 0, false, ErrInviteLinkNotFound
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	if revoked || roomType != "group" || active != 1 {
+		return 0, false, ErrInviteLinkNotFound
+	}
+
+	var exists int
+	err = tx.QueryRowContext(ctx, `
+		SELECT 1 FROM room_members WHERE room_id = ? AND user_id = ?
+	`, roomID, userID).Scan(&exists)
+	if err == nil {
+		return roomID, false, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, false, err
+	}
+
+	if expired || (maxUses > 0 && uses >= maxUses) {
+		return 0, false, ErrInviteLinkExpired
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO room_members (room_id, user_id, member_role) VALUES (?, ?, 'member')
+	`, roomID, userID); err != nil {
+		return 0, false, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE room_invite_links SET uses = uses + 1 WHERE id = ?
+	`, linkID); err != nil {
+		return 0, false, err
+	}
+	// lời mời đang chờ (nếu có) coi như đã nhận
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE room_invites SET status = 'accepted', responded_at = NOW()
+		WHERE room_id = ? AND invitee_id = ? AND status = 'pending'
+	`, roomID, userID); err != nil {
+		return 0, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, false, err
+	}
+	return roomID, true, nil
+}
@@ -0,0 +1,206 @@
+package room
+
+import (
+	"context"
+	"cronhustler/api-service/internal/unread"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// phân trang GET /rooms
+const (
+	DefaultRoomsPageSize = 30
+	MaxRoomsPageSize     = 100
+
+	lastMessagePreviewLen = 120
+)
+
+var ErrInvalidRoomCursor = errors.New("invalid rooms cursor")
+
+// RoomLastMessage: tin mới nhất của room (preview ở sidebar)
+type RoomLastMessage struct {
+	ID          int64     `json:"id"`
+	SenderID    int64     `json:"sender_id"`
+	SenderName  string    `json:"sender_name"`
+	MessageType string    `json:"message_type"`
+	Preview     string    `json:"preview"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// join tin mới nhất (không tính tin tạm) + người gửi, dùng chung cho list room
+const lastMessageJoin = `
+	LEFT JOIN messages lm ON lm.id = (
+		SELECT m3.id
+		FROM messages m3
+		WHERE m3.room_id = r.id AND m3.is_temp = 0
+		ORDER BY m3.created_at DESC, m3.id DESC
+		LIMIT 1
+	)
+	LEFT JOIN users lu ON lu.id = lm.sender_id
+`
+
+// cột tương ứng lastMessageJoin, scan bằng lastMessageScan
+const lastMessageColumns = `
+	lm.id AS last_id, lm.sender_id AS last_sender_id,
+	COALESCE(lm.webhook_name, lu.full_name, lu.username) AS last_sender_name,
+	lm.message_type AS last_type, lm.content AS last_content, lm.created_at AS last_at
+`
+
+type lastMessageScan struct {
+	id, senderID      sql.NullInt64
+	senderName, mtype sql.NullString
+	content           sql.NullString
+	at                sql.NullTime
+}
+
+func (l *lastMessageScan) dest() []any {
+	return []any{&l.id, &l.senderID, &l.senderName, &l.mtype, &l.content, &l.at}
+}
+
+// message: nil nếu room chưa có tin
+func (l *lastMessageScan) message() *RoomLastMessage {
+	if !l.id.Valid {
+		return nil
+	}
+	return &RoomLastMessage{
+		ID:          l.id.Int64,
+		SenderID:    l.senderID.Int64,
+		SenderName:  l.senderName.String,
+		MessageType: l.mtype.String,
+		Preview:     lastMessagePreview(l.mtype.String, l.content.String),
+		CreatedAt:   l.at.Time,
+	}
+}
+
+// RoomCursor: vị trí room cuối của trang trước (sort theo last activity, rồi id)
+type RoomCursor struct {
+	ActivityAt time.Time
+	RoomID     int64
+}
+
+// Encode: token opaque cho next_cursor
+func (c RoomCursor) Encode() string {
+	raw := fmt.Sprintf("%d_%d", c.ActivityAt.Unix(), c.RoomID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeRoomCursor(token string) (*RoomCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidRoomCursor
+	}
+	var sec, id int64
+	if n, err := fmt.Sscanf(string(b), "%d_%d", &sec, &id); err != nil || n != 2 || id <= 0 {
+		return nil, ErrInvalidRoomCursor
+	}
+	return &RoomCursor{ActivityAt: time.Unix(sec, 0), RoomID: id}, nil
+}
+
+// GetRoomsByUserPage: 1 trang room của user, mới hoạt động nhất trước
+// unread_count + tin cuối lấy luôn trong 1 query, next = nil nếu hết
+// room đã archive chỉ có khi includeArchived
+func (r *Repository) GetRoomsByUserPage(ctx context.Context, userID int64, cursor *RoomCursor, limit int, includeArchived bool) (rooms []*Room, next *RoomCursor, err error) {
+	if limit <= 0 {
+		limit = DefaultRoomsPageSize
+	}
+	limit = min(limit, MaxRoomsPageSize)
+
+	var afterAt time.Time
+	var afterID int64
+	if cursor != nil {
+		afterAt, afterID = cursor.ActivityAt, cursor.RoomID
+	}
+
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT id, name, type, created_by, is_active, created_at, updated_at,
+		       description, avatar_url, unread_count,
+		       last_id, last_sender_id, last_sender_name, last_type, last_content, last_at, archived, activity_at
+		FROM (
+			SELECT
+				r.id,
+				r.name,
+				r.type,
+				r.created_by,
+				r.is_active,
+				r.created_at,
+				r.updated_at,
+				COALESCE(r.description, '') AS description,
+				COALESCE(r.avatar_url, '') AS avatar_url,
+				COALESCE((
+					SELECT COUNT(*)
+					FROM messages m
+					WHERE m.room_id = r.id AND `+unread.MessageCond+`
+				), 0) AS unread_count,
+				`+lastMessageColumns+`,
+				rm.archived_at IS NOT NULL AS archived,
+				COALESCE(lm.created_at, r.created_at) AS activity_at
+			FROM rooms r
+			JOIN room_members rm ON rm.room_id = r.id
+			`+lastMessageJoin+`
+			WHERE
+				rm.user_id = ?
+				AND (? OR rm.archived_at IS NULL)
+				AND (
+					r.type = 'group'
+					OR EXISTS (
+						SELECT 1
+						FROM messages m2
+						WHERE m2.room_id = r.id
+					)
+				)
+		) x
+		WHERE ? = 0
+		   OR x.activity_at < ?
+		   OR (x.activity_at = ? AND x.id < ?)
+		ORDER BY x.activity_at DESC, x.id DESC
+		LIMIT ?
+	`, userID, includeArchived, afterID, afterAt, afterAt, afterID, limit+1)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	rooms = []*Room{}
+	var lastActivity time.Time
+	for rows.Next() {
+		var rm Room
+		var last lastMessageScan
+		var activityAt time.Time
+		dest := append([]any{
+			&rm.ID, &rm.Name, &rm.Type, &rm.CreatedBy, &rm.IsActive, &rm.CreatedAt, &rm.UpdatedAt,
+			&rm.Description, &rm.AvatarURL, &rm.UnreadCount,
+		}, last.dest()...)
+		if err := rows.Scan(append(dest, &rm.Archived, &activityAt)...); err != nil {
+			return nil, nil, err
+		}
+		if len(rooms) == limit {
+			next = &RoomCursor{ActivityAt: lastActivity, RoomID: rooms[len(rooms)-1].ID}
+			break
+		}
+		rm.LastMessage = last.message()
+		lastActivity = activityAt
+		rooms = append(rooms, &rm)
+	}
+	return rooms, next, rows.Err()
+}
+
+// lastMessagePreview: media -> nhãn, text -> cắt ngắn trên 1 dòng
+func lastMessagePreview(messageType, content string) string {
+	switch messageType {
+	case "image":
+		return "📷 Image"
+	case "file":
+		return "📎 File"
+	case "audio":
+		return "🎤 Voice message"
+	}
+	txt := strings.Join(strings.Fields(content), " ")
+	if rs := []rune(txt); len(rs) > lastMessagePreviewLen {
+		txt = string(rs[:lastMessagePreviewLen]) + "…"
+	}
+	return txt
+}
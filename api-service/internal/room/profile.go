@@ -0,0 +1,81 @@
+package room
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// giới hạn tên / mô tả group
+const (
+	MaxRoomNameLen        = 100
+	MaxRoomDescriptionLen = 500
+)
+
+// RoomProfile: thông tin hiển thị của room (tên, mô tả, avatar)
+type RoomProfile struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	AvatarURL   string `json:"avatar_url"`
+}
+
+// ProfileUpdate: field nil = giữ nguyên, AvatarURL "" = bỏ avatar
+type ProfileUpdate struct {
+	Name        *string
+	Description *string
+	AvatarURL   *string
+}
+
+func (u ProfileUpdate) Empty() bool {
+	return u.Name == nil && u.Description == nil && u.AvatarURL == nil
+}
+
+func (r *Repository) GetRoomProfile(ctx context.Context, roomID int64) (*RoomProfile, error) {
+	var p RoomProfile
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT id, COALESCE(name, ''), COALESCE(description, ''), COALESCE(avatar_url, '')
+		FROM rooms WHERE id = ?
+	`, roomID).Scan(&p.ID, &p.Name, &p.Description, &p.AvatarURL)
+	if err == sql.ErrNoRows {
+		return nil, ErrRoomNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// UpdateRoomProfile: chỉ update field được gửi lên
+func (r *Repository) UpdateRoomProfile(ctx context.Context, roomID int64, u ProfileUpdate) error {
+	var sets []string
+	var args []any
+	if u.Name != nil {
+		sets = append(sets, "name = ?")
+		args = append(args, *u.Name)
+	}
+	if u.Description != nil {
+		sets = append(sets, "description = NULLIF(?, '')")
+		args = append(args, *u.Description)
+	}
+	if u.AvatarURL != nil {
+		sets = append(sets, "avatar_url = NULLIF(?, '')")
+		args = append(args, *u.AvatarURL)
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+	args = append(args, roomID)
+
+	res, err := r.DB.ExecContext(ctx, `UPDATE rooms SET `+strings.Join(sets, ", ")+` WHERE id = ?`, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// giá trị không đổi cũng ra 0 -> check room còn tồn tại
+		if _, err := r.GetRoomProfile(ctx, roomID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,7 +3,9 @@ package room
 import (
 	"context"
 	"cronhustler/api-service/internal/chat"
+	"cronhustler/api-service/internal/unread"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -30,7 +32,11 @@ type Room struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	UnreadCount int64     `json:"unread_count"` // NEW
+	Description string    `json:"description,omitempty"`
+	AvatarURL   string    `json:"avatar_url,omitempty"` // /static/chat_uploads/... (group)
 
+	LastMessage *RoomLastMessage `json:"last_message,omitempty"` // GetRoomsByUser / GetRoomsByUserPage
+	Archived    bool             `json:"archived"`               // user tự archive (room_members.archived_at)
 }
 
 type RoomMember struct {
@@ -145,20 +151,18 @@ func (r *Repository) GetRoomsByUser(userID int64) ([]*Room, error) {
 			r.is_active,
 			r.created_at,
 			r.updated_at,
+			COALESCE(r.description, ''),
+			COALESCE(r.avatar_url, ''),
 			COALESCE((
 				SELECT COUNT(*)
 				FROM messages m
-				WHERE
-					m.room_id   = r.id
-					AND m.is_temp = 0
-					AND m.sender_id <> rm.user_id
-					AND (
-						rm.last_seen_at IS NULL
-						OR m.created_at > rm.last_seen_at
-					)
-			), 0) AS unread_count
+				WHERE m.room_id = r.id AND `+unread.MessageCond+`
+			), 0) AS unread_count,
+			`+lastMessageColumns+`,
+			rm.archived_at IS NOT NULL AS archived
 		FROM rooms r
 		JOIN room_members rm ON rm.room_id = r.id
+		`+lastMessageJoin+`
 		WHERE
 			rm.user_id = ?
 			AND (
@@ -180,7 +184,8 @@ func (r *Repository) GetRoomsByUser(userID int64) ([]*Room, error) {
 
 	for rows.Next() {
 		var rm Room
-		err := rows.Scan(
+		var last lastMessageScan
+		err := rows.Scan(append([]any{
 			&rm.ID,
 			&rm.Name,
 			&rm.Type,
@@ -188,41 +193,20 @@ func (r *Repository) GetRoomsByUser(userID int64) ([]*Room, error) {
 			&rm.IsActive,
 			&rm.CreatedAt,
 			&rm.UpdatedAt,
+			&rm.Description,
+			&rm.AvatarURL,
 			&rm.UnreadCount,
-		)
+		}, append(last.dest(), &rm.Archived)...)...)
 		if err != nil {
 			return nil, err
 		}
+		rm.LastMessage = last.message()
 		rooms = append(rooms, &rm)
 	}
 
 	return rooms, nil
 }
 
-func (r *Repository) GetDirectRoomBetweenUsers(a, b int64) (*Room, error) {
-	row := r.DB.QueryRow(`
-        SELECT r.id, r.name, r.type, r.created_by, r.is_active, r.created_at, r.updated_at
-        FROM rooms r
-        JOIN room_members m1 ON m1.room_id = r.id
-        JOIN room_members m2 ON m2.room_id = r.id
-        WHERE r.type = 'direct'
-          AND m1.user_id = ?
-          AND m2.user_id = ?
-        LIMIT 1
-    `, a, b)
-
-	var rm Room
-	err := row.Scan(
-		&rm.ID, &rm.Name, &rm.Type, &rm.CreatedBy,
-		&rm.IsActive, &rm.CreatedAt, &rm.UpdatedAt,
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	return &rm, nil
-}
-
 type RoomWithMembers struct {
 	Room    *Room         `json:"room"`
 	Members []*RoomMember `json:"members"`
@@ -238,7 +222,7 @@ type Message struct {
 	SenderAvatarURL string `json:"sender_avatar_url,omitempty"`
 
 	Content string `json:"content"`      // text OR image/file url (fallback)
-	Type    string `json:"message_type"` // text | image | file | system
+	Type    string `json:"message_type"` // text | image | file | audio | system
 	IsTemp  int    `json:"is_temp"`
 
 	CreatedAt time.Time `json:"created_at"`
@@ -248,6 +232,13 @@ type Message struct {
 	MediaMIME string `json:"media_mime,omitempty"`
 	MediaSize int64  `json:"media_size,omitempty"`
 
+	MediaPosterURL string `json:"media_poster_url,omitempty"` // video poster (transcode xong mới có)
+
+	MediaDurationMs int64 `json:"media_duration_ms,omitempty"` // voice message
+
+	// file đính kèm không qua được quét virus (đã cách ly)
+	Blocked bool `json:"blocked,omitempty"`
+
 	// ===== Reply (NEW – denormalized) =====
 	ReplyToMessageID int64  `json:"reply_to_message_id,omitempty"`
 	ReplyPreview     string `json:"reply_preview,omitempty"`
@@ -255,6 +246,13 @@ type Message struct {
 	ReplyMessageType string `json:"reply_message_type,omitempty"`
 
 	Reactions []chat.ReactionSummaryItem `json:"reactions,omitempty"`
+
+	Attachments []chat.Attachment `json:"attachments,omitempty"`
+	// != 0: tin từ incoming webhook (SenderName/Avatar lấy theo webhook)
+	WebhookID int64 `json:"webhook_id,omitempty"`
+
+	Buttons []chat.Button `json:"buttons,omitempty"`
+	Embeds  []chat.Embed  `json:"embeds,omitempty"`
 }
 
 // internal/room/repository.go
@@ -304,11 +302,15 @@ func (r *Repository) GetRoomMessages(roomID int64, beforeID int64, beforeAt time
 		    m.id, m.room_id, m.sender_id,
 		    m.reply_to_message_id, m.reply_preview, m.reply_sender_name, m.reply_message_type,
 		    m.content, m.message_type, m.is_temp,
-		    m.media_url, m.media_mime, m.media_size,
+		    m.media_url, m.media_mime, m.media_size, m.media_poster_url, m.media_duration_ms,
+		    m.blocked_at IS NOT NULL,
 		    m.created_at,
-		    u.full_name, u.username, u.avatar_url
+		    u.full_name, u.username, u.avatar_url,
+		    m.webhook_id, COALESCE(m.webhook_name, iw.name), COALESCE(m.webhook_avatar_url, iw.avatar_url),
+		    m.buttons, m.embeds
 		  FROM messages m
 		  LEFT JOIN users u ON m.sender_id = u.id
+		  LEFT JOIN incoming_webhooks iw ON iw.id = m.webhook_id
 		  WHERE m.room_id = ?
 		    AND (
 		      ? = 0
@@ -325,6 +327,11 @@ func (r *Repository) GetRoomMessages(roomID int64, beforeID int64, beforeAt time
 	}
 	defer rows.Close()
 
+	return r.scanMessages(rows, userID)
+}
+
+// scanMessages: map rows (cột như GetRoomMessages) -> Message + reactions/attachments batch
+func (r *Repository) scanMessages(rows *sql.Rows, userID int64) ([]*Message, error) {
 	var msgs []*Message
 	var messageIDs []int64
 
@@ -334,6 +341,12 @@ func (r *Repository) GetRoomMessages(roomID int64, beforeID int64, beforeAt time
 		// user join nullable
 		var fullName, username, avatarURL sql.NullString
 
+		// incoming webhook nullable
+		var webhookID sql.NullInt64
+		var webhookName, webhookAvatar sql.NullString
+
+		var buttonsRaw, embedsRaw []byte
+
 		// reply nullable
 		var replyToID sql.NullInt64
 		var replyPreview, replySenderName, replyMessageType sql.NullString
@@ -342,6 +355,8 @@ func (r *Repository) GetRoomMessages(roomID int64, beforeID int64, beforeAt time
 		var mediaURL sql.NullString
 		var mediaMIME sql.NullString
 		var mediaSize sql.NullInt64
+		var mediaPosterURL sql.NullString
+		var mediaDurationMs sql.NullInt64
 
 		err := rows.Scan(
 			&m.ID,
@@ -360,12 +375,22 @@ func (r *Repository) GetRoomMessages(roomID int64, beforeID int64, beforeAt time
 			&mediaURL,
 			&mediaMIME,
 			&mediaSize,
+			&mediaPosterURL,
+			&mediaDurationMs,
+			&m.Blocked,
 
 			&m.CreatedAt,
 
 			&fullName,
 			&username,
 			&avatarURL,
+
+			&webhookID,
+			&webhookName,
+			&webhookAvatar,
+
+			&buttonsRaw,
+			&embedsRaw,
 		)
 		if err != nil {
 			return nil, err
@@ -385,6 +410,23 @@ func (r *Repository) GetRoomMessages(roomID int64, beforeID int64, beforeAt time
 			m.SenderAvatarURL = avatarURL.String
 		}
 
+		// Webhook: hiển thị theo tên/avatar của webhook thay vì người tạo
+		if webhookID.Valid {
+			m.WebhookID = webhookID.Int64
+			if webhookName.Valid && webhookName.String != "" {
+				m.SenderName = webhookName.String
+			}
+			m.SenderAvatarURL = webhookAvatar.String
+		}
+
+		// Buttons (JSON) - lỗi parse thì bỏ qua, không làm hỏng cả trang tin
+		if len(buttonsRaw) > 0 {
+			_ = json.Unmarshal(buttonsRaw, &m.Buttons)
+		}
+		if len(embedsRaw) > 0 {
+			_ = json.Unmarshal(embedsRaw, &m.Embeds)
+		}
+
 		// Media
 		if mediaURL.Valid {
 			m.MediaURL = mediaURL.String
@@ -395,6 +437,12 @@ func (r *Repository) GetRoomMessages(roomID int64, beforeID int64, beforeAt time
 		if mediaSize.Valid {
 			m.MediaSize = mediaSize.Int64
 		}
+		if mediaPosterURL.Valid {
+			m.MediaPosterURL = mediaPosterURL.String
+		}
+		if mediaDurationMs.Valid {
+			m.MediaDurationMs = mediaDurationMs.Int64
+		}
 
 		// Reply
 		if replyToID.Valid {
@@ -434,11 +482,107 @@ func (r *Repository) GetRoomMessages(roomID int64, beforeID int64, beforeAt time
 				m.Reactions = nil
 			}
 		}
+	
+		// ✅ Attach attachments batch (waveform audio, ...)
+		attMap, err := r.chatRepo.ListAttachmentsBatch(context.Background(), messageIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range msgs {
+			m.Attachments = attMap[m.ID]
+		}
 	}
 
 	return msgs, nil
 }
 
+// GetMessage: 1 message trong room (cùng shape với GetRoomMessages), không có -> sql.ErrNoRows
+func (r *Repository) GetMessage(ctx context.Context, roomID, messageID, userID int64) (*Message, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT
+		  m.id, m.room_id, m.sender_id,
+		  m.reply_to_message_id, m.reply_preview, m.reply_sender_name, m.reply_message_type,
+		  m.content, m.message_type, m.is_temp,
+		  m.media_url, m.media_mime, m.media_size, m.media_poster_url, m.media_duration_ms,
+		  m.blocked_at IS NOT NULL,
+		  m.created_at,
+		  u.full_name, u.username, u.avatar_url,
+		  m.webhook_id, COALESCE(m.webhook_name, iw.name), COALESCE(m.webhook_avatar_url, iw.avatar_url),
+		  m.buttons, m.embeds
+		FROM messages m
+		LEFT JOIN users u ON m.sender_id = u.id
+		LEFT JOIN incoming_webhooks iw ON iw.id = m.webhook_id
+		WHERE m.room_id = ? AND m.id = ?
+	`, roomID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	msgs, err := r.scanMessages(rows, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return msgs[0], nil
+}
+
+// độ sâu tối đa khi đi theo chuỗi reply (chặn CTE chạy vô hạn)
+const maxThreadDepth = 100
+
+// GetMessageThread: mọi message reply (trực tiếp hoặc gián tiếp) vào rootID, cũ -> mới
+// cursor: afterID + afterAt (message cuối của trang trước), afterID = 0 -> từ đầu
+func (r *Repository) GetMessageThread(ctx context.Context, roomID, rootID, afterID int64, afterAt time.Time, limit int, userID int64) ([]*Message, error) {
+	cursorEnabled := 0
+	var afterAtVal any = nil
+
+	if afterID > 0 && !afterAt.IsZero() {
+		cursorEnabled = 1
+		afterAtVal = afterAt
+	}
+
+	rows, err := r.DB.QueryContext(ctx, `
+		WITH RECURSIVE thread (id, depth) AS (
+		  SELECT id, 0 FROM messages WHERE id = ? AND room_id = ?
+		  UNION ALL
+		  SELECT c.id, t.depth + 1
+		  FROM messages c
+		  JOIN thread t ON c.reply_to_message_id = t.id
+		  WHERE c.room_id = ? AND t.depth < ?
+		)
+		SELECT
+		  m.id, m.room_id, m.sender_id,
+		  m.reply_to_message_id, m.reply_preview, m.reply_sender_name, m.reply_message_type,
+		  m.content, m.message_type, m.is_temp,
+		  m.media_url, m.media_mime, m.media_size, m.media_poster_url, m.media_duration_ms,
+		  m.blocked_at IS NOT NULL,
+		  m.created_at,
+		  u.full_name, u.username, u.avatar_url,
+		  m.webhook_id, COALESCE(m.webhook_name, iw.name), COALESCE(m.webhook_avatar_url, iw.avatar_url),
+		  m.buttons, m.embeds
+		FROM thread th
+		JOIN messages m ON m.id = th.id
+		LEFT JOIN users u ON m.sender_id = u.id
+		LEFT JOIN incoming_webhooks iw ON iw.id = m.webhook_id
+		WHERE th.depth > 0
+		  AND (
+		    ? = 0
+		    OR m.created_at > ?
+		    OR (m.created_at = ? AND m.id > ?)
+		  )
+		ORDER BY m.created_at ASC, m.id ASC
+		LIMIT ?
+	`, rootID, roomID, roomID, maxThreadDepth, cursorEnabled, afterAtVal, afterAtVal, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanMessages(rows, userID)
+}
+
 func (r *Repository) GetDirectPartnerFullNameByRoomID(roomID, currentUserID int64) (string, error) {
 	const query = `
 		SELECT u.full_name
@@ -541,12 +685,17 @@ func (r *Repository) CreateGroupRoom(name string, createdBy int64, memberIDs []i
 	return room, nil
 }
 
+// MarkRoomAsRead: dời watermark seen tới tin mới nhất của room (unread theo watermark)
 func (r *Repository) MarkRoomAsRead(roomID, userID int64) error {
 	_, err := r.DB.Exec(`
 		UPDATE room_members
-		SET last_seen_at = NOW()
+		SET last_seen_message_id = GREATEST(
+				COALESCE(last_seen_message_id, 0),
+				COALESCE((SELECT MAX(id) FROM messages WHERE room_id = ?), 0)
+			),
+			last_seen_at = NOW()
 		WHERE room_id = ? AND user_id = ?
-		`, roomID, userID)
+		`, roomID, roomID, userID)
 	return err
 }
 
@@ -771,14 +920,15 @@ func (r *Repository) GetRoomByIDLite(ctx context.Context, roomID int64) (*RoomLi
 	return &x, nil
 }
 
-type roomLiteResponse struct {
+// RoomBasic: id / type / tên hiển thị (header màn chat)
+type RoomBasic struct {
 	ID          int64  `json:"id"`
 	Type        string `json:"type,omitempty"`
 	Name        string `json:"name,omitempty"`        // raw name (group)
 	DisplayName string `json:"displayName,omitempty"` // tên hiển thị FE dùng
 }
 
-func (r *Repository) GetRoomBasic(ctx context.Context, roomID int64) (*roomLiteResponse, error) {
+func (r *Repository) GetRoomBasic(ctx context.Context, roomID int64) (*RoomBasic, error) {
 	// giả sử rooms có columns: id, type, name
 	var typ, name string
 	err := r.DB.QueryRowContext(ctx, `SELECT type, name FROM rooms WHERE id=?`, roomID).Scan(&typ, &name)
@@ -790,7 +940,7 @@ func (r *Repository) GetRoomBasic(ctx context.Context, roomID int64) (*roomLiteR
 	if strings.TrimSpace(display) == "" {
 		display = "Room"
 	}
-	return &roomLiteResponse{
+	return &RoomBasic{
 		ID:          roomID,
 		Type:        typ,
 		Name:        name,
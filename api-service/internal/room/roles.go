@@ -0,0 +1,142 @@
+package room
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// member_role trong room_members (thứ tự quyền: owner > admin > moderator > member)
+const (
+	RoleOwner     = "owner"
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+	RoleMember    = "member"
+)
+
+// Permission: hành động trong group room cần quyền
+type Permission string
+
+const (
+	PermKickMember    Permission = "kick_member"
+	PermRenameRoom    Permission = "rename_room" // đổi tên + avatar
+	PermPinMessage    Permission = "pin_message"
+	PermDeleteMessage Permission = "delete_message" // xoá tin của người khác (tin mình thì ai cũng xoá được)
+	PermManageRoles   Permission = "manage_roles"
+	PermInviteLink    Permission = "invite_link"    // tạo / thu hồi link join (chỉ owner)
+	PermRoomRetention Permission = "room_retention" // đặt TTL tin tự huỷ (chỉ owner)
+)
+
+var ErrInvalidRole = errors.New("invalid role")
+
+// rolePerms: ma trận quyền theo role (owner có mọi quyền)
+var rolePerms = map[string]map[Permission]bool{
+	RoleAdmin: {
+		PermKickMember: true, PermRenameRoom: true, PermPinMessage: true,
+		PermDeleteMessage: true, PermManageRoles: true,
+	},
+	RoleModerator: {
+		PermKickMember: true, PermPinMessage: true, PermDeleteMessage: true,
+	},
+}
+
+// Can: role có quyền perm không
+func Can(role string, perm Permission) bool {
+	if role == RoleOwner {
+		return true
+	}
+	return rolePerms[role][perm]
+}
+
+// RoleRank: so sánh role, chỉ được tác động lên người rank thấp hơn mình
+func RoleRank(role string) int {
+	switch role {
+	case RoleOwner:
+		return 4
+	case RoleAdmin:
+		return 3
+	case RoleModerator:
+		return 2
+	case RoleMember:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// IsAssignableRole: role đổi qua API (owner chỉ đổi bằng chuyển quyền)
+func IsAssignableRole(role string) bool {
+	return role == RoleAdmin || role == RoleModerator || role == RoleMember
+}
+
+// SetMemberRole: đổi role thành viên (không đụng owner)
+func (r *Repository) SetMemberRole(ctx context.Context, roomID, userID int64, role string) error {
+	if !IsAssignableRole(role) {
+		return ErrInvalidRole
+	}
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE room_members SET member_role = ?
+		WHERE room_id = ? AND user_id = ? AND member_role <> 'owner'
+	`, role, roomID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// không đổi gì: không phải member, là owner, hoặc role giữ nguyên
+		if _, err := r.GetMemberRole(ctx, roomID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ===== Pin =====
+
+type PinnedMessage struct {
+	MessageID int64     `json:"message_id"`
+	PinnedBy  int64     `json:"pinned_by"`
+	PinnedAt  time.Time `json:"pinned_at"`
+}
+
+// PinMessage: ghim trùng -> giữ người ghim cũ
+func (r *Repository) PinMessage(ctx context.Context, roomID, messageID, userID int64) error {
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT IGNORE INTO room_pins (room_id, message_id, pinned_by) VALUES (?, ?, ?)
+	`, roomID, messageID, userID)
+	return err
+}
+
+// UnpinMessage: false = tin chưa được ghim
+func (r *Repository) UnpinMessage(ctx context.Context, roomID, messageID int64) (bool, error) {
+	res, err := r.DB.ExecContext(ctx, `
+		DELETE FROM room_pins WHERE room_id = ? AND message_id = ?
+	`, roomID, messageID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ListPins: mới ghim lên đầu
+func (r *Repository) ListPins(ctx context.Context, roomID int64) ([]PinnedMessage, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT message_id, pinned_by, pinned_at
+		FROM room_pins WHERE room_id = ?
+		ORDER BY pinned_at DESC, message_id DESC
+	`, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []PinnedMessage{}
+	for rows.Next() {
+		var p PinnedMessage
+		if err := rows.Scan(&p.MessageID, &p.PinnedBy, &p.PinnedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
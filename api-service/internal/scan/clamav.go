@@ -0,0 +1,99 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// kích thước mỗi chunk INSTREAM (clamd nhận tối đa StreamMaxLength cho cả file)
+const clamChunkSize = 32 << 10
+
+// ClamAVScanner: gửi file tới clamd bằng lệnh INSTREAM
+type ClamAVScanner struct {
+	Network string // tcp | unix
+	Addr    string
+	Timeout time.Duration
+}
+
+// CLAMAV_ADDR: host:port (tcp, mặc định 127.0.0.1:3310) hoặc unix:/var/run/clamav/clamd.ctl
+func newClamAVScannerFromEnv() (*ClamAVScanner, error) {
+	c := &ClamAVScanner{Network: "tcp", Addr: "127.0.0.1:3310", Timeout: 2 * time.Minute}
+	if addr := strings.TrimSpace(os.Getenv("CLAMAV_ADDR")); addr != "" {
+		if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+			c.Network, c.Addr = "unix", path
+		} else {
+			c.Addr = strings.TrimPrefix(addr, "tcp://")
+		}
+	}
+	if c.Addr == "" {
+		return nil, errors.New("scan: invalid CLAMAV_ADDR")
+	}
+	return c, nil
+}
+
+func (c *ClamAVScanner) Scan(ctx context.Context, name string, r io.Reader) (Result, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, c.Network, c.Addr)
+	if err != nil {
+		return Result{}, fmt.Errorf("scan: clamav: %w", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(c.Timeout)
+	if dl, ok := ctx.Deadline(); ok && dl.Before(deadline) {
+		deadline = dl
+	}
+	_ = conn.SetDeadline(deadline)
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return Result{}, fmt.Errorf("scan: clamav: %w", err)
+	}
+
+	// mỗi chunk: độ dài 4 byte big-endian + data, chunk rỗng = hết file
+	buf := make([]byte, 4+clamChunkSize)
+	for {
+		n, rerr := r.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				// clamd cắt kết nối khi vượt StreamMaxLength, câu trả lời vẫn đọc được bên dưới
+				break
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return Result{}, rerr
+		}
+	}
+	_, _ = conn.Write([]byte{0, 0, 0, 0})
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return Result{}, fmt.Errorf("scan: clamav: %w", err)
+	}
+	return parseClamReply(reply)
+}
+
+// parseClamReply: "stream: OK" | "stream: Eicar-Signature FOUND" | "... ERROR"
+func parseClamReply(reply string) (Result, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	_, status, _ := strings.Cut(reply, ": ")
+	switch {
+	case status == "OK":
+		return Result{Clean: true}, nil
+	case strings.HasSuffix(status, " FOUND"):
+		return Result{Signature: strings.TrimSuffix(status, " FOUND")}, nil
+	default:
+		return Result{}, fmt.Errorf("scan: clamav: %s", reply)
+	}
+}
@@ -0,0 +1,99 @@
+package scan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Result: kết quả quét 1 file, Signature = tên mẫu virus / lý do (chỉ có khi không sạch)
+type Result struct {
+	Clean     bool
+	Signature string
+}
+
+// Scanner: quét nội dung 1 file upload, lỗi = không quét được (khác với file bẩn)
+type Scanner interface {
+	Scan(ctx context.Context, name string, r io.Reader) (Result, error)
+}
+
+// ErrDisabled: chưa cấu hình SCAN_PROVIDER -> không quét file upload
+var ErrDisabled = errors.New("scan: SCAN_PROVIDER is not configured")
+
+// NewScannerFromEnv: SCAN_PROVIDER = clamav | http
+func NewScannerFromEnv() (Scanner, error) {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("SCAN_PROVIDER"))) {
+	case "":
+		return nil, ErrDisabled
+	case "clamav":
+		return newClamAVScannerFromEnv()
+	case "http":
+		return newHTTPScannerFromEnv()
+	default:
+		return nil, fmt.Errorf("scan: unknown SCAN_PROVIDER %q (clamav, http)", os.Getenv("SCAN_PROVIDER"))
+	}
+}
+
+// =======================================
+// HTTP scanner ngoài
+// =======================================
+
+// HTTPScanner: POST nội dung file (octet-stream) tới URL, nhận {"clean": bool, "signature": "..."}
+type HTTPScanner struct {
+	URL   string
+	Token string // Authorization: Bearer (optional)
+
+	http *http.Client
+}
+
+// SCAN_HTTP_URL, SCAN_HTTP_TOKEN
+func newHTTPScannerFromEnv() (*HTTPScanner, error) {
+	h := &HTTPScanner{
+		URL:   strings.TrimSpace(os.Getenv("SCAN_HTTP_URL")),
+		Token: os.Getenv("SCAN_HTTP_TOKEN"),
+		http:  &http.Client{Timeout: 2 * time.Minute},
+	}
+	if h.URL == "" {
+		return nil, errors.New("scan: SCAN_HTTP_URL is required")
+	}
+	return h, nil
+}
+
+func (h *HTTPScanner) Scan(ctx context.Context, name string, r io.Reader) (Result, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, r)
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("X-File-Name", name)
+	if h.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.Token)
+	}
+
+	resp, err := h.http.Do(req)
+	if err != nil {
+		return Result{}, fmt.Errorf("scan: http: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return Result{}, fmt.Errorf("scan: http: status %d", resp.StatusCode)
+	}
+
+	var out struct {
+		Clean     *bool  `json:"clean"`
+		Signature string `json:"signature"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&out); err != nil {
+		return Result{}, fmt.Errorf("scan: http: invalid response: %w", err)
+	}
+	if out.Clean == nil {
+		return Result{}, errors.New("scan: http: response has no clean field")
+	}
+	return Result{Clean: *out.Clean, Signature: out.Signature}, nil
+}
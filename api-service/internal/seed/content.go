@@ -0,0 +1,111 @@
+package seed
+
+import (
+	"math/rand"
+	"strings"
+)
+
+var (
+	lastNames   = []string{"Nguyễn", "Trần", "Lê", "Phạm", "Hoàng", "Huỳnh", "Phan", "Vũ", "Võ", "Đặng", "Bùi", "Đỗ", "Hồ", "Ngô", "Dương", "Lý"}
+	middleNames = []string{"Văn", "Thị", "Minh", "Ngọc", "Hữu", "Thanh", "Quốc", "Gia", "Đức", "Thu", "Hoài", "Anh"}
+	firstNames  = []string{"An", "Bình", "Châu", "Dũng", "Giang", "Hà", "Hải", "Hiếu", "Hoa", "Hùng", "Huy", "Khánh", "Lan", "Linh", "Long", "Mai", "Nam", "Nga", "Nhung", "Phong", "Phúc", "Quân", "Quỳnh", "Sơn", "Tâm", "Thảo", "Trang", "Trung", "Tú", "Tuấn", "Vy", "Yến"}
+
+	groupNames = []string{"Team Backend", "Team Frontend", "Mobile Squad", "Dự án Alpha", "Dự án Phoenix", "QA & Release", "Design Review", "Marketing", "Sales Miền Nam", "HR Announcements", "Cà phê sáng", "Đá bóng thứ 7", "Infra On-call", "Data Team", "Customer Success", "Random"}
+
+	// tin nhắn kiểu team chat, {name} = tên người nhận / người trong room
+	phrases = []string{
+		"Chào mọi người 👋",
+		"Mọi người ơi, 10h họp standup nhé",
+		"Ok anh",
+		"Em đang check lại, chút em báo nhé",
+		"Build trên staging lỗi rồi, ai xem giúp với",
+		"Đã deploy bản mới lên staging",
+		"PR này review giúp mình với {name} ơi",
+		"Xong rồi nha",
+		"Hôm nay ai trực on-call vậy?",
+		"Lunch ăn gì đây mọi người?",
+		"Cảm ơn {name} nhiều 🙏",
+		"Mình nghĩ nên tách API này ra làm 2 endpoint",
+		"Có ai thấy log lỗi 500 ở /rooms không?",
+		"Đã fix, mọi người pull lại nhé",
+		"Deadline sprint này là thứ 6",
+		"Tài liệu mình để trong drive chung rồi",
+		"Ok, chốt phương án B",
+		"Để mình hỏi lại khách hàng",
+		"Chiều nay mình xin nghỉ sớm 1 tiếng",
+		"Test case này fail trên iOS thôi, Android ok",
+		"Quyết định: release vào thứ 3 tuần sau",
+		"+1",
+		"Haha 😂",
+		"Nghe hợp lý đó",
+		"Mình đồng ý với {name}",
+		"Ai có quyền admin DB không, cần chạy migration",
+		"Hotfix đã lên production",
+		"Cuối tuần này team building ở Vũng Tàu nhé 🏖️",
+		"Good morning!",
+		"Let me check and get back to you",
+		"LGTM, merge đi",
+		"Có ai rảnh pair debug chút không?",
+		"Sorry mình vào họp muộn 5 phút",
+		"Số liệu tuần này tăng 12% so với tuần trước 📈",
+		"Meeting notes mình gửi qua mail rồi nhé",
+	}
+
+	reactions = []string{"👍", "❤️", "😂", "😮", "😢", "🎉"}
+
+	fileKinds = []struct {
+		Prefix string
+		Ext    string
+		Mime   string
+	}{
+		{"bao-cao-tuan", ".csv", "text/csv"},
+		{"meeting-notes", ".txt", "text/plain"},
+		{"ke-hoach-sprint", ".md", "text/markdown"},
+	}
+)
+
+func pick[T any](rng *rand.Rand, list []T) T {
+	return list[rng.Intn(len(list))]
+}
+
+// fullName: "Nguyễn Văn An"
+func fullName(rng *rand.Rand) (last, middle, first string) {
+	return pick(rng, lastNames), pick(rng, middleNames), pick(rng, firstNames)
+}
+
+// bỏ dấu tiếng Việt (chữ thường)
+var vnFold = func() map[rune]rune {
+	m := map[rune]rune{}
+	for base, accented := range map[rune]string{
+		'a': "àáạảãâầấậẩẫăằắặẳẵ",
+		'e': "èéẹẻẽêềếệểễ",
+		'i': "ìíịỉĩ",
+		'o': "òóọỏõôồốộổỗơờớợởỡ",
+		'u': "ùúụủũưừứựửữ",
+		'y': "ỳýỵỷỹ",
+		'd': "đ",
+	} {
+		for _, r := range accented {
+			m[r] = base
+		}
+	}
+	return m
+}()
+
+// asciiSlug: "Đức Hải" -> "duchai" (username / email)
+func asciiSlug(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if f, ok := vnFold[r]; ok {
+			r = f
+		}
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func messageText(rng *rand.Rand, mention string) string {
+	return strings.ReplaceAll(pick(rng, phrases), "{name}", mention)
+}
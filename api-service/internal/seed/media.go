@@ -0,0 +1,63 @@
+package seed
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// fakeImage: PNG gradient 2 màu ngẫu nhiên (nén tốt, vài KB)
+func fakeImage(rng *rand.Rand, w, h int) []byte {
+	c1 := color.RGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), 255}
+	c2 := color.RGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), 255}
+	lerp := func(a, b uint8, t float64) uint8 { return uint8(float64(a) + (float64(b)-float64(a))*t) }
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		t := float64(y) / float64(max(h-1, 1))
+		c := color.RGBA{lerp(c1.R, c2.R, t), lerp(c1.G, c2.G, t), lerp(c1.B, c2.B, t), 255}
+		for x := 0; x < w; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+// fakeDocument: file text nhỏ theo loại (csv / txt / md)
+func fakeDocument(rng *rand.Rand, ext string, day time.Time) []byte {
+	var b strings.Builder
+	switch ext {
+	case ".csv":
+		b.WriteString("date,signups,messages,revenue\n")
+		for i := 0; i < 7; i++ {
+			fmt.Fprintf(&b, "%s,%d,%d,%d\n", day.AddDate(0, 0, i-6).Format("2006-01-02"), rng.Intn(200), rng.Intn(20000), rng.Intn(5000)*1000)
+		}
+	case ".md":
+		fmt.Fprintf(&b, "# Sprint plan %s\n\n", day.Format("2006-01-02"))
+		for i := 1; i <= 5; i++ {
+			fmt.Fprintf(&b, "- [ ] Task %d: %s\n", i, pick(rng, phrases))
+		}
+	default:
+		fmt.Fprintf(&b, "Meeting notes - %s\n\n", day.Format("02/01/2006"))
+		for i := 0; i < 6; i++ {
+			b.WriteString("* " + pick(rng, phrases) + "\n")
+		}
+	}
+	return []byte(b.String())
+}
+
+func writeFile(dir, name string, data []byte) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name), data, 0o644)
+}
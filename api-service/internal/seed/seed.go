@@ -0,0 +1,703 @@
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// user demo đều có prefix này -> Reset chỉ xoá đúng dữ liệu seed
+	UsernamePrefix  = "demo_"
+	DefaultPassword = "demo1234"
+
+	// sender của tin ngăn cách ngày (giống chat.DaySeparatorSenderID)
+	systemUserID = 99999
+
+	chatUploadPrefix = "/static/chat_uploads/"
+	avatarPrefix     = "/static/user_avatars/"
+
+	// số tin mỗi câu INSERT
+	messageChunk = 200
+	// receipt "seen" chỉ tạo cho N tin gần vị trí đã đọc (đủ cho UI "đã xem")
+	receiptWindow = 200
+)
+
+// Config: quy mô dữ liệu sinh ra
+type Config struct {
+	Users          int     `json:"users"`
+	GroupRooms     int     `json:"group_rooms"`
+	DirectRooms    int     `json:"direct_rooms"`
+	GroupSize      int     `json:"group_size"`      // số thành viên tối đa / group
+	GroupMessages  int     `json:"group_messages"`  // số tin / group
+	DirectMessages int     `json:"direct_messages"` // số tin / direct room
+	Days           int     `json:"days"`            // lịch sử trải đều N ngày gần nhất
+	MediaRatio     float64 `json:"media_ratio"`     // tỉ lệ tin là ảnh / file (0 = không tạo file)
+	Seed           int64   `json:"seed"`            // cùng seed -> cùng dữ liệu
+}
+
+// Scales: preset cho -scale
+var Scales = map[string]Config{
+	"small":  {Users: 20, GroupRooms: 4, DirectRooms: 15, GroupSize: 10, GroupMessages: 150, DirectMessages: 40, Days: 14, MediaRatio: 0.05},
+	"medium": {Users: 200, GroupRooms: 30, DirectRooms: 300, GroupSize: 30, GroupMessages: 600, DirectMessages: 80, Days: 60, MediaRatio: 0.04},
+	"large":  {Users: 2000, GroupRooms: 200, DirectRooms: 4000, GroupSize: 80, GroupMessages: 2000, DirectMessages: 150, Days: 180, MediaRatio: 0.02},
+}
+
+func (c Config) Validate() error {
+	switch {
+	case c.Users < 2:
+		return errors.New("users must be >= 2")
+	case c.GroupRooms < 0 || c.DirectRooms < 0:
+		return errors.New("rooms must be >= 0")
+	case c.GroupRooms > 0 && c.GroupSize < 2:
+		return errors.New("group size must be >= 2")
+	case c.GroupMessages < 0 || c.DirectMessages < 0:
+		return errors.New("messages must be >= 0")
+	case c.Days < 1:
+		return errors.New("days must be >= 1")
+	case c.MediaRatio < 0 || c.MediaRatio > 1:
+		return errors.New("media ratio must be 0..1")
+	}
+	if maxPairs := c.Users * (c.Users - 1) / 2; c.DirectRooms > maxPairs {
+		return fmt.Errorf("direct rooms: max %d pairs for %d users", maxPairs, c.Users)
+	}
+	return nil
+}
+
+// Report: số bản ghi đã tạo
+type Report struct {
+	Users       int    `json:"users"`
+	GroupRooms  int    `json:"group_rooms"`
+	DirectRooms int    `json:"direct_rooms"`
+	Members     int    `json:"members"`
+	Messages    int    `json:"messages"`
+	Replies     int    `json:"replies"`
+	Reactions   int64  `json:"reactions"`
+	Receipts    int64  `json:"receipts"`
+	Files       int    `json:"files"`
+	Password    string `json:"password"` // mật khẩu chung của user demo
+}
+
+type demoUser struct {
+	ID       int64
+	Username string
+	FullName string
+	First    string
+}
+
+type demoRoom struct {
+	ID      int64
+	Type    string
+	Created time.Time
+	Members []demoUser
+}
+
+// sentMsg: tin đã insert (để reply / reaction / receipt)
+type sentMsg struct {
+	ID      int64
+	Sender  demoUser
+	Content string
+	Type    string
+	At      time.Time
+}
+
+type Generator struct {
+	DB            *sql.DB
+	ChatUploadDir string
+	AvatarDir     string
+	Config        Config
+	Logf          func(format string, args ...any) // tiến độ, nil = im lặng
+
+	rng        *rand.Rand
+	now        time.Time
+	separators bool
+	report     *Report
+}
+
+func NewGenerator(db *sql.DB, cfg Config, chatUploadDir, avatarDir string) *Generator {
+	return &Generator{DB: db, Config: cfg, ChatUploadDir: chatUploadDir, AvatarDir: avatarDir}
+}
+
+func (g *Generator) logf(format string, args ...any) {
+	if g.Logf != nil {
+		g.Logf(format, args...)
+	}
+}
+
+// hashPassword: bcrypt giống httpserver, cost thấp cho nhanh (chỉ là user demo)
+func hashPassword(pw string) (string, error) {
+	h, err := bcrypt.GenerateFromPassword([]byte(pw), bcrypt.MinCost)
+	return string(h), err
+}
+
+// Reset: xoá user demo (cascade room / member / message) + file upload của họ
+func (g *Generator) Reset(ctx context.Context) (int64, error) {
+	like := strings.ReplaceAll(UsernamePrefix, "_", `\_`) + "%"
+
+	// file upload + avatar: lấy tên trước khi xoá DB
+	var files []string
+	rows, err := g.DB.QueryContext(ctx, `
+		SELECT cu.file_name FROM chat_uploads cu
+		JOIN users u ON u.id = cu.uploader_id
+		WHERE u.username LIKE ?
+	`, like)
+	if err != nil {
+		return 0, err
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		files = append(files, filepath.Join(g.ChatUploadDir, filepath.Base(name)))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if _, err := g.DB.ExecContext(ctx, `
+		DELETE cu FROM chat_uploads cu JOIN users u ON u.id = cu.uploader_id WHERE u.username LIKE ?
+	`, like); err != nil {
+		return 0, err
+	}
+	res, err := g.DB.ExecContext(ctx, `DELETE FROM users WHERE username LIKE ?`, like)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+
+	for _, f := range files {
+		_ = os.Remove(f)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(g.AvatarDir, UsernamePrefix+"*.png")); len(matches) > 0 {
+		for _, f := range matches {
+			_ = os.Remove(f)
+		}
+	}
+	return n, nil
+}
+
+// Run: sinh user -> room -> tin nhắn (reply, reaction, file) -> receipt
+func (g *Generator) Run(ctx context.Context) (*Report, error) {
+	if err := g.Config.Validate(); err != nil {
+		return nil, err
+	}
+	g.rng = rand.New(rand.NewSource(g.Config.Seed))
+	g.now = time.Now().Truncate(time.Second)
+	g.report = &Report{Password: DefaultPassword}
+
+	if err := g.DB.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM users WHERE id = ?)`, systemUserID).Scan(&g.separators); err != nil {
+		return nil, err
+	}
+	if !g.separators {
+		g.logf("user %d (system) không tồn tại -> bỏ qua tin ngăn cách ngày", systemUserID)
+	}
+
+	users, err := g.createUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("users: %w", err)
+	}
+	g.logf("users: %d", len(users))
+
+	rooms, err := g.createGroups(ctx, users)
+	if err != nil {
+		return nil, fmt.Errorf("groups: %w", err)
+	}
+	directs, err := g.createDirects(ctx, users)
+	if err != nil {
+		return nil, fmt.Errorf("direct rooms: %w", err)
+	}
+	rooms = append(rooms, directs...)
+	g.logf("rooms: %d group, %d direct", g.report.GroupRooms, g.report.DirectRooms)
+
+	for i, rm := range rooms {
+		if err := ctx.Err(); err != nil {
+			return g.report, err
+		}
+		n := g.Config.GroupMessages
+		if rm.Type == "direct" {
+			n = g.Config.DirectMessages
+		}
+		if err := g.fillRoom(ctx, rm, n); err != nil {
+			return g.report, fmt.Errorf("room %d: %w", rm.ID, err)
+		}
+		if (i+1)%50 == 0 || i == len(rooms)-1 {
+			g.logf("messages: %d/%d rooms, %d messages", i+1, len(rooms), g.report.Messages)
+		}
+	}
+	return g.report, nil
+}
+
+// between: thời điểm ngẫu nhiên trong [from, to)
+func (g *Generator) between(from, to time.Time) time.Time {
+	d := to.Sub(from)
+	if d <= 0 {
+		return from
+	}
+	return from.Add(time.Duration(g.rng.Int63n(int64(d))))
+}
+
+func (g *Generator) historyStart() time.Time {
+	return g.now.AddDate(0, 0, -g.Config.Days)
+}
+
+func (g *Generator) createUsers(ctx context.Context) ([]demoUser, error) {
+	var offset int
+	like := strings.ReplaceAll(UsernamePrefix, "_", `\_`) + "%"
+	if err := g.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE username LIKE ?`, like).Scan(&offset); err != nil {
+		return nil, err
+	}
+
+	tx, err := g.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO users (username, password, role, full_name, email, phone, avatar_url, is_active, created_ip, created_at)
+		VALUES (?, ?, 'user', ?, ?, ?, ?, 1, '127.0.0.1', ?)
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	pw, err := hashPassword(DefaultPassword)
+	if err != nil {
+		return nil, err
+	}
+	start := g.historyStart()
+	out := make([]demoUser, 0, g.Config.Users)
+	for i := 0; i < g.Config.Users; i++ {
+		last, middle, first := fullName(g.rng)
+		u := demoUser{
+			Username: fmt.Sprintf("%s%s%s%d", UsernamePrefix, asciiSlug(first), asciiSlug(last), offset+i+1),
+			FullName: last + " " + middle + " " + first,
+			First:    first,
+		}
+
+		var avatar any
+		if g.rng.Float64() < 0.7 {
+			name := u.Username + ".png"
+			if err := writeFile(g.AvatarDir, name, fakeImage(g.rng, 128, 128)); err != nil {
+				return nil, err
+			}
+			avatar = avatarPrefix + name
+		}
+
+		res, err := stmt.ExecContext(ctx, u.Username, pw, u.FullName,
+			u.Username+"@example.com",
+			fmt.Sprintf("09%08d", g.rng.Intn(100000000)),
+			avatar,
+			g.between(start.AddDate(0, 0, -30), start),
+		)
+		if err != nil {
+			return nil, err
+		}
+		if u.ID, err = res.LastInsertId(); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	g.report.Users = len(out)
+	return out, tx.Commit()
+}
+
+// insertRoom: room + member (role theo roles, mặc định member)
+func (g *Generator) insertRoom(ctx context.Context, tx *sql.Tx, name, typ string, members []demoUser, roles map[int64]string) (*demoRoom, error) {
+	rm := &demoRoom{Type: typ, Members: members}
+	rm.Created = g.between(g.historyStart().AddDate(0, 0, -7), g.historyStart())
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO rooms (name, type, created_by, is_active, created_at)
+		VALUES (?, ?, ?, 1, ?)
+	`, name, typ, members[0].ID, rm.Created)
+	if err != nil {
+		return nil, err
+	}
+	if rm.ID, err = res.LastInsertId(); err != nil {
+		return nil, err
+	}
+
+	q := `INSERT INTO room_members (room_id, user_id, member_role, joined_at) VALUES `
+	args := make([]any, 0, len(members)*4)
+	for i, m := range members {
+		if i > 0 {
+			q += ","
+		}
+		q += "(?, ?, ?, ?)"
+		role := roles[m.ID]
+		if role == "" {
+			role = "member"
+		}
+		args = append(args, rm.ID, m.ID, role, rm.Created)
+	}
+	if _, err := tx.ExecContext(ctx, q, args...); err != nil {
+		return nil, err
+	}
+	g.report.Members += len(members)
+	return rm, nil
+}
+
+func (g *Generator) createGroups(ctx context.Context, users []demoUser) ([]demoRoom, error) {
+	tx, err := g.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	used := map[string]int{}
+	out := make([]demoRoom, 0, g.Config.GroupRooms)
+	for i := 0; i < g.Config.GroupRooms; i++ {
+		name := pick(g.rng, groupNames)
+		if used[name]++; used[name] > 1 {
+			name = fmt.Sprintf("%s %d", name, used[name])
+		}
+
+		size := min(2+g.rng.Intn(g.Config.GroupSize-1), len(users))
+		members := make([]demoUser, size)
+		for j, idx := range g.rng.Perm(len(users))[:size] {
+			members[j] = users[idx]
+		}
+		// người đầu = owner, thêm 1 admin nếu group đủ đông
+		roles := map[int64]string{members[0].ID: "owner"}
+		if size > 4 {
+			roles[members[1].ID] = "admin"
+		}
+
+		rm, err := g.insertRoom(ctx, tx, name, "group", members, roles)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *rm)
+	}
+	g.report.GroupRooms = len(out)
+	return out, tx.Commit()
+}
+
+func (g *Generator) createDirects(ctx context.Context, users []demoUser) ([]demoRoom, error) {
+	tx, err := g.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	seen := map[[2]int64]bool{}
+	out := make([]demoRoom, 0, g.Config.DirectRooms)
+	for len(out) < g.Config.DirectRooms {
+		a, b := users[g.rng.Intn(len(users))], users[g.rng.Intn(len(users))]
+		lo, hi := min(a.ID, b.ID), max(a.ID, b.ID)
+		if lo == hi || seen[[2]int64{lo, hi}] {
+			continue
+		}
+		seen[[2]int64{lo, hi}] = true
+
+		// tên giống room.DirectRoomName
+		rm, err := g.insertRoom(ctx, tx, fmt.Sprintf("direct-%d-%d", lo, hi), "direct", []demoUser{a, b}, nil)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO direct_rooms (user_a, user_b, room_id) VALUES (?, ?, ?)`, lo, hi, rm.ID); err != nil {
+			return nil, err
+		}
+		out = append(out, *rm)
+	}
+	g.report.DirectRooms = len(out)
+	return out, tx.Commit()
+}
+
+// pendingMsg: 1 dòng sẽ INSERT vào messages
+type pendingMsg struct {
+	Sender    demoUser
+	Content   string
+	Type      string
+	At        time.Time
+	ReplyTo   *sentMsg
+	Upload    *pendingUpload
+	separator bool
+}
+
+type pendingUpload struct {
+	FileName string
+	Original string
+	Mime     string
+	Size     int
+	Width    int
+	Height   int
+}
+
+// fillRoom: n tin trải đều trong lịch sử, giờ hành chính mở rộng 8h-22h
+func (g *Generator) fillRoom(ctx context.Context, rm demoRoom, n int) error {
+	if n == 0 {
+		return nil
+	}
+	start := g.historyStart()
+	if rm.Created.After(start) {
+		start = rm.Created
+	}
+	times := make([]time.Time, n)
+	for i := range times {
+		t := g.between(start, g.now)
+		day := time.Date(t.Year(), t.Month(), t.Day(), 8, 0, 0, 0, t.Location())
+		t = day.Add(time.Duration(g.rng.Int63n(int64(14 * time.Hour))))
+		if t.After(g.now) {
+			t = g.now.Add(-time.Duration(g.rng.Intn(3600)) * time.Second)
+		}
+		times[i] = t
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	var history []sentMsg
+	var lastDay string
+	for off := 0; off < n; off += messageChunk {
+		chunk := make([]pendingMsg, 0, messageChunk+8)
+		for _, t := range times[off:min(off+messageChunk, n)] {
+			if day := t.Format("2006-01-02"); g.separators && day != lastDay {
+				midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+				chunk = append(chunk, pendingMsg{Content: "--- " + day + " ---", Type: "system", At: midnight, separator: true})
+				lastDay = day
+			}
+			p, err := g.newMessage(rm, t, history)
+			if err != nil {
+				return err
+			}
+			chunk = append(chunk, p)
+		}
+
+		sent, err := g.insertChunk(ctx, rm, chunk)
+		if err != nil {
+			return err
+		}
+		history = append(history, sent...)
+	}
+
+	return g.markSeen(ctx, rm, history)
+}
+
+func (g *Generator) newMessage(rm demoRoom, t time.Time, history []sentMsg) (pendingMsg, error) {
+	sender := pick(g.rng, rm.Members)
+	other := pick(g.rng, rm.Members)
+	p := pendingMsg{Sender: sender, Type: "text", At: t, Content: messageText(g.rng, other.First)}
+
+	// reply 1 trong 30 tin gần nhất
+	if len(history) > 0 && g.rng.Float64() < 0.1 {
+		recent := history[max(0, len(history)-30):]
+		target := recent[g.rng.Intn(len(recent))]
+		p.ReplyTo = &target
+	}
+
+	if g.Config.MediaRatio > 0 && g.rng.Float64() < g.Config.MediaRatio {
+		ns := t.UnixNano() + int64(g.rng.Intn(1_000_000))
+		var data []byte
+		up := &pendingUpload{}
+		if g.rng.Float64() < 0.8 {
+			up.Width, up.Height = 320+g.rng.Intn(480), 240+g.rng.Intn(360)
+			data = fakeImage(g.rng, up.Width, up.Height)
+			up.Mime = "image/png"
+			up.FileName = fmt.Sprintf("r%d_u%d_%d.png", rm.ID, sender.ID, ns)
+			up.Original = fmt.Sprintf("IMG_%s.png", t.Format("20060102_150405"))
+			p.Type = "image"
+		} else {
+			kind := pick(g.rng, fileKinds)
+			data = fakeDocument(g.rng, kind.Ext, t)
+			up.Mime = kind.Mime
+			up.FileName = fmt.Sprintf("r%d_u%d_%d%s", rm.ID, sender.ID, ns, kind.Ext)
+			up.Original = kind.Prefix + "-" + t.Format("2006-01-02") + kind.Ext
+			p.Type = "file"
+		}
+		if err := writeFile(g.ChatUploadDir, up.FileName, data); err != nil {
+			return p, err
+		}
+		up.Size = len(data)
+		p.Upload = up
+		p.Content = chatUploadPrefix + up.FileName
+	}
+	return p, nil
+}
+
+// replyPreview: giống chat.buildReplyPreview
+func replyPreview(m *sentMsg) string {
+	switch m.Type {
+	case "image":
+		return "📷 Image"
+	case "file":
+		return "📎 File"
+	}
+	if r := []rune(m.Content); len(r) > 300 {
+		return string(r[:300])
+	}
+	return m.Content
+}
+
+// insertChunk: 1 câu INSERT cho cả chunk, đọc lại id theo thứ tự rồi thêm upload / reaction
+func (g *Generator) insertChunk(ctx context.Context, rm demoRoom, chunk []pendingMsg) ([]sentMsg, error) {
+	tx, err := g.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var lastID int64
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0) FROM messages WHERE room_id = ?`, rm.ID).Scan(&lastID); err != nil {
+		return nil, err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`INSERT INTO messages (room_id, sender_id, reply_to_message_id, reply_preview, reply_sender_name, reply_message_type, content, message_type, is_temp, created_at) VALUES `)
+	args := make([]any, 0, len(chunk)*9)
+	for i, p := range chunk {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString("(?, ?, ?, ?, ?, ?, ?, ?, 0, ?)")
+		senderID := p.Sender.ID
+		if p.separator {
+			senderID = systemUserID
+		}
+		var replyID, preview, replyName, replyType any
+		if p.ReplyTo != nil {
+			replyID, preview, replyName, replyType = p.ReplyTo.ID, replyPreview(p.ReplyTo), p.ReplyTo.Sender.FullName, p.ReplyTo.Type
+		}
+		args = append(args, rm.ID, senderID, replyID, preview, replyName, replyType, p.Content, p.Type, p.At)
+	}
+	if _, err := tx.ExecContext(ctx, sb.String(), args...); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM messages WHERE room_id = ? AND id > ? ORDER BY id LIMIT ?`, rm.ID, lastID, len(chunk))
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(chunk))
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) != len(chunk) {
+		return nil, fmt.Errorf("inserted %d messages, read back %d", len(chunk), len(ids))
+	}
+
+	sent := make([]sentMsg, 0, len(chunk))
+	for i, p := range chunk {
+		if p.separator {
+			continue
+		}
+		m := sentMsg{ID: ids[i], Sender: p.Sender, Content: p.Content, Type: p.Type, At: p.At}
+		sent = append(sent, m)
+		g.report.Messages++
+		if p.ReplyTo != nil {
+			g.report.Replies++
+		}
+
+		if up := p.Upload; up != nil {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO chat_uploads (file_name, room_id, uploader_id, original_name, content_type, file_size, created_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?)
+			`, up.FileName, rm.ID, p.Sender.ID, up.Original, up.Mime, up.Size, p.At); err != nil {
+				return nil, err
+			}
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO attachments (message_id, file_name, file_size, content_type, file_path, width, height, created_at)
+				VALUES (?, ?, ?, ?, ?, NULLIF(?, 0), NULLIF(?, 0), ?)
+			`, m.ID, up.Original, up.Size, up.Mime, p.Content, up.Width, up.Height, p.At); err != nil {
+				return nil, err
+			}
+			g.report.Files++
+		}
+	}
+
+	if err := g.addReactions(ctx, tx, rm, sent); err != nil {
+		return nil, err
+	}
+	return sent, tx.Commit()
+}
+
+// addReactions: ~12% tin có 1-3 reaction của thành viên khác
+func (g *Generator) addReactions(ctx context.Context, tx *sql.Tx, rm demoRoom, sent []sentMsg) error {
+	var sb strings.Builder
+	var args []any
+	for _, m := range sent {
+		if g.rng.Float64() >= 0.12 {
+			continue
+		}
+		for k := 1 + g.rng.Intn(3); k > 0; k-- {
+			u := pick(g.rng, rm.Members)
+			if u.ID == m.Sender.ID {
+				continue
+			}
+			if len(args) > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString("(?, ?, ?, ?)")
+			args = append(args, m.ID, u.ID, pick(g.rng, reactions), m.At.Add(time.Duration(1+g.rng.Intn(120))*time.Minute))
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	res, err := tx.ExecContext(ctx, `INSERT IGNORE INTO message_reactions (message_id, user_id, reaction, created_at) VALUES `+sb.String(), args...)
+	if err != nil {
+		return err
+	}
+	n, _ := res.RowsAffected()
+	g.report.Reactions += n
+	return nil
+}
+
+// markSeen: mỗi thành viên đọc tới 1 vị trí (đa số đã đọc hết, số còn lại để lại vài tin chưa đọc)
+func (g *Generator) markSeen(ctx context.Context, rm demoRoom, history []sentMsg) error {
+	if len(history) == 0 {
+		return nil
+	}
+	for _, u := range rm.Members {
+		pos := len(history) - 1
+		if g.rng.Float64() < 0.3 {
+			pos -= 1 + g.rng.Intn(min(20, len(history)))
+		}
+		if pos < 0 {
+			continue
+		}
+		upTo := history[pos]
+		from := history[max(0, pos-receiptWindow+1)]
+
+		res, err := g.DB.ExecContext(ctx, `
+			INSERT IGNORE INTO message_receipts (room_id, message_id, user_id, status, seen_at)
+			SELECT m.room_id, m.id, ?, 'seen', m.created_at + INTERVAL 1 MINUTE
+			FROM messages m
+			WHERE m.room_id = ? AND m.id BETWEEN ? AND ? AND m.sender_id <> ? AND m.message_type <> 'system'
+		`, u.ID, rm.ID, from.ID, upTo.ID, u.ID)
+		if err != nil {
+			return err
+		}
+		n, _ := res.RowsAffected()
+		g.report.Receipts += n
+
+		if _, err := g.DB.ExecContext(ctx, `
+			UPDATE room_members SET last_seen_message_id = ?, last_seen_at = ? WHERE room_id = ? AND user_id = ?
+		`, upTo.ID, upTo.At.Add(time.Minute), rm.ID, u.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Sender: gửi 1 SMS, to dạng E.164 (+84...)
+type Sender interface {
+	Send(ctx context.Context, to, body string) error
+}
+
+// ErrDisabled: chưa cấu hình SMS_PROVIDER -> tắt OTP qua SMS
+var ErrDisabled = errors.New("sms: SMS_PROVIDER is not configured")
+
+// NewSenderFromEnv: SMS_PROVIDER = twilio | log (log = chỉ in ra log, dùng khi dev)
+func NewSenderFromEnv() (Sender, error) {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("SMS_PROVIDER"))) {
+	case "":
+		return nil, ErrDisabled
+	case "twilio":
+		return newTwilioSenderFromEnv()
+	case "log":
+		return LogSender{}, nil
+	default:
+		return nil, fmt.Errorf("sms: unknown SMS_PROVIDER %q (twilio, log)", os.Getenv("SMS_PROVIDER"))
+	}
+}
+
+// NormalizePhone: số nội địa 0xxxxxxxxx -> +{countryCode}xxxxxxxxx, số đã có + giữ nguyên
+func NormalizePhone(phone, countryCode string) string {
+	p := strings.NewReplacer(" ", "", "-", "", ".", "").Replace(strings.TrimSpace(phone))
+	if p == "" || strings.HasPrefix(p, "+") {
+		return p
+	}
+	if strings.HasPrefix(p, "00") {
+		return "+" + p[2:]
+	}
+	if strings.HasPrefix(p, "0") {
+		return "+" + countryCode + p[1:]
+	}
+	return "+" + p
+}
+
+// MaskPhone: +84912345678 -> +84*****678
+func MaskPhone(phone string) string {
+	r := []rune(phone)
+	if len(r) <= 6 {
+		return phone
+	}
+	return string(r[:3]) + strings.Repeat("*", len(r)-6) + string(r[len(r)-3:])
+}
+
+// =======================================
+// Twilio (Messages API)
+// =======================================
+
+type TwilioSender struct {
+	AccountSID string
+	AuthToken  string
+	From       string // số gửi Twilio (+1...)
+	ServiceSID string // Messaging Service SID (ưu tiên nếu có)
+	BaseURL    string
+
+	http *http.Client
+}
+
+// TWILIO_ACCOUNT_SID, TWILIO_AUTH_TOKEN, TWILIO_FROM hoặc TWILIO_MESSAGING_SERVICE_SID
+func newTwilioSenderFromEnv() (*TwilioSender, error) {
+	t := &TwilioSender{
+		AccountSID: os.Getenv("TWILIO_ACCOUNT_SID"),
+		AuthToken:  os.Getenv("TWILIO_AUTH_TOKEN"),
+		From:       os.Getenv("TWILIO_FROM"),
+		ServiceSID: os.Getenv("TWILIO_MESSAGING_SERVICE_SID"),
+		BaseURL:    "https://api.twilio.com",
+		http:       &http.Client{Timeout: 10 * time.Second},
+	}
+	if t.AccountSID == "" || t.AuthToken == "" {
+		return nil, errors.New("sms: TWILIO_ACCOUNT_SID and TWILIO_AUTH_TOKEN are required")
+	}
+	if t.From == "" && t.ServiceSID == "" {
+		return nil, errors.New("sms: TWILIO_FROM or TWILIO_MESSAGING_SERVICE_SID is required")
+	}
+	return t, nil
+}
+
+func (t *TwilioSender) Send(ctx context.Context, to, body string) error {
+	form := url.Values{}
+	form.Set("To", to)
+	form.Set("Body", body)
+	if t.ServiceSID != "" {
+		form.Set("MessagingServiceSid", t.ServiceSID)
+	} else {
+		form.Set("From", t.From)
+	}
+
+	endpoint := t.BaseURL + "/2010-04-01/Accounts/" + url.PathEscape(t.AccountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(t.AccountSID, t.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("sms: twilio: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	// lỗi Twilio: {"code": 21211, "message": "..."}
+	var e struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+	if json.Unmarshal(b, &e) == nil && e.Message != "" {
+		return fmt.Errorf("sms: twilio: status %d code %d: %s", resp.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Errorf("sms: twilio: status %d", resp.StatusCode)
+}
+
+// LogSender: không gửi thật, in nội dung ra log (dev / test)
+type LogSender struct{}
+
+func (LogSender) Send(_ context.Context, to, body string) error {
+	log.Printf("[sms] to=%s: %s", to, body)
+	return nil
+}
@@ -0,0 +1,300 @@
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// trạng thái 1 file trong storage_migrations
+const (
+	migrationUploaded = "uploaded" // đã lên object store + verify, chưa rewrite DB
+	migrationDone     = "done"     // đã rewrite DB
+)
+
+// MigrateSource: 1 thư mục local + prefix URL/key tương ứng
+type MigrateSource struct {
+	Kind      string // chat_upload | avatar
+	Dir       string
+	URLPrefix string // /static/chat_uploads/
+	KeyPrefix string // chat_uploads/
+}
+
+// MigrateReport: tổng kết 1 lần chạy
+type MigrateReport struct {
+	DryRun    bool  `json:"dry_run"`
+	Scanned   int   `json:"scanned"`
+	Skipped   int   `json:"skipped"` // đã done từ lần chạy trước
+	Uploaded  int   `json:"uploaded"`
+	Rewritten int   `json:"rewritten"`
+	Failed    int   `json:"failed"`
+	Bytes     int64 `json:"bytes"`
+}
+
+// Migrator: copy file local -> S3 theo batch, resume được nhờ bảng storage_migrations
+type Migrator struct {
+	DB        *sql.DB
+	Store     *S3Store
+	Sources   []MigrateSource
+	BatchSize int
+	DryRun    bool
+}
+
+func NewMigrator(db *sql.DB, store *S3Store, chatUploadDir, avatarDir string) *Migrator {
+	return &Migrator{
+		DB:    db,
+		Store: store,
+		Sources: []MigrateSource{
+			{Kind: "chat_upload", Dir: chatUploadDir, URLPrefix: "/static/chat_uploads/", KeyPrefix: ChatUploadKeyPrefix},
+			{Kind: "avatar", Dir: avatarDir, URLPrefix: "/static/user_avatars/", KeyPrefix: AvatarKeyPrefix},
+		},
+		BatchSize: 100,
+	}
+}
+
+// Run: chạy hết các source, lỗi từng file chỉ log + đếm Failed (lần sau chạy lại sẽ thử tiếp)
+func (m *Migrator) Run(ctx context.Context) (*MigrateReport, error) {
+	rep := &MigrateReport{DryRun: m.DryRun}
+	if m.BatchSize <= 0 {
+		m.BatchSize = 100
+	}
+
+	for _, src := range m.Sources {
+		if src.Dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(src.Dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return rep, err
+		}
+
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			if !e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+				names = append(names, e.Name())
+			}
+		}
+		rep.Scanned += len(names)
+
+		for start := 0; start < len(names); start += m.BatchSize {
+			if err := ctx.Err(); err != nil {
+				return rep, err
+			}
+			end := start + m.BatchSize
+			if end > len(names) {
+				end = len(names)
+			}
+			if err := m.runBatch(ctx, rep, src, names[start:end]); err != nil {
+				return rep, err
+			}
+		}
+	}
+
+	return rep, nil
+}
+
+func (m *Migrator) runBatch(ctx context.Context, rep *MigrateReport, src MigrateSource, names []string) error {
+	status, err := m.loadStatus(ctx, src.Kind, names)
+	if err != nil {
+		return err
+	}
+
+	// 1) upload + verify (file nào đã uploaded thì chỉ cần rewrite)
+	ready := make(map[string]string, len(names)) // name -> public url
+	for _, name := range names {
+		st := status[name]
+		if st.Status == migrationDone {
+			rep.Skipped++
+			continue
+		}
+		if st.Status == migrationUploaded {
+			ready[name] = st.PublicURL
+			continue
+		}
+		if m.DryRun {
+			rep.Uploaded++
+			continue
+		}
+
+		publicURL, size, err := m.uploadFile(ctx, src, name)
+		if err != nil {
+			log.Printf("[storage-migrate] %s/%s: %v", src.Kind, name, err)
+			rep.Failed++
+			continue
+		}
+		rep.Uploaded++
+		rep.Bytes += size
+		ready[name] = publicURL
+	}
+
+	if m.DryRun || len(ready) == 0 {
+		return nil
+	}
+
+	// 2) rewrite DB cho cả batch trong 1 transaction
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for name, publicURL := range ready {
+		if err := rewriteRefs(ctx, tx, src, src.URLPrefix+name, publicURL); err != nil {
+			return fmt.Errorf("rewrite %s: %w", name, err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE storage_migrations SET status = ?, updated_at = NOW()
+			WHERE kind = ? AND file_name = ?
+		`, migrationDone, src.Kind, name); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	rep.Rewritten += len(ready)
+	return nil
+}
+
+type migrationStatus struct {
+	Status    string
+	PublicURL string
+}
+
+func (m *Migrator) loadStatus(ctx context.Context, kind string, names []string) (map[string]migrationStatus, error) {
+	out := make(map[string]migrationStatus, len(names))
+	if len(names) == 0 {
+		return out, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(names)), ",")
+	args := make([]any, 0, len(names)+1)
+	args = append(args, kind)
+	for _, n := range names {
+		args = append(args, n)
+	}
+
+	rows, err := m.DB.QueryContext(ctx, `
+		SELECT file_name, status, public_url
+		FROM storage_migrations
+		WHERE kind = ? AND file_name IN (`+placeholders+`)
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		var st migrationStatus
+		if err := rows.Scan(&name, &st.Status, &st.PublicURL); err != nil {
+			return nil, err
+		}
+		out[name] = st
+	}
+	return out, rows.Err()
+}
+
+// uploadFile: hash file, PUT kèm Content-MD5, HEAD lại để so size + ETag, ghi trạng thái uploaded
+func (m *Migrator) uploadFile(ctx context.Context, src MigrateSource, name string) (string, int64, error) {
+	path := filepath.Join(src.Dir, name)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	md5h := md5.New()
+	shah := sha256.New()
+	size, err := io.Copy(io.MultiWriter(md5h, shah), f)
+	if err != nil {
+		return "", 0, err
+	}
+	md5Sum := md5h.Sum(nil)
+	md5Hex := hex.EncodeToString(md5Sum)
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", 0, err
+	}
+
+	key := src.KeyPrefix + name
+	ctype := mime.TypeByExtension(strings.ToLower(filepath.Ext(name)))
+
+	putCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	if err := m.Store.Put(putCtx, key, f, size, ctype, hex.EncodeToString(shah.Sum(nil)), base64.StdEncoding.EncodeToString(md5Sum)); err != nil {
+		return "", 0, err
+	}
+
+	// verify: size phải khớp, ETag dạng md5 (PUT 1 part) thì phải khớp md5
+	info, err := m.Store.Head(ctx, key)
+	if err != nil {
+		return "", 0, fmt.Errorf("verify: %w", err)
+	}
+	if info.Size != size {
+		return "", 0, fmt.Errorf("verify: size mismatch local=%d remote=%d", size, info.Size)
+	}
+	if len(info.ETag) == 32 && !strings.EqualFold(info.ETag, md5Hex) {
+		return "", 0, fmt.Errorf("verify: md5 mismatch local=%s remote=%s", md5Hex, info.ETag)
+	}
+
+	publicURL := m.Store.PublicURL(key)
+	_, err = m.DB.ExecContext(ctx, `
+		INSERT INTO storage_migrations (kind, file_name, object_key, public_url, md5_hex, file_size, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			object_key = VALUES(object_key),
+			public_url = VALUES(public_url),
+			md5_hex = VALUES(md5_hex),
+			file_size = VALUES(file_size),
+			status = VALUES(status),
+			updated_at = NOW()
+	`, src.Kind, name, key, publicURL, md5Hex, size, migrationUploaded)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return publicURL, size, nil
+}
+
+// rewriteRefs: đổi URL local -> URL object store ở mọi chỗ tham chiếu
+func rewriteRefs(ctx context.Context, tx *sql.Tx, src MigrateSource, oldURL, newURL string) error {
+	var stmts []string
+	switch src.Kind {
+	case "chat_upload":
+		stmts = []string{
+			`UPDATE messages SET media_url = ? WHERE media_url = ?`,
+			`UPDATE messages SET media_poster_url = ? WHERE media_poster_url = ?`,
+			`UPDATE messages SET content = ? WHERE message_type IN ('image','file') AND content = ?`,
+			`UPDATE attachments SET file_path = ? WHERE file_path = ?`,
+		}
+	case "avatar":
+		stmts = []string{
+			`UPDATE users SET avatar_url = ? WHERE avatar_url = ?`,
+		}
+	}
+
+	for _, q := range stmts {
+		if _, err := tx.ExecContext(ctx, q, newURL, oldURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
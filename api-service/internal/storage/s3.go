@@ -0,0 +1,347 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrObjectNotFound = errors.New("storage: object not found")
+
+// ObjectInfo: kết quả HEAD 1 object
+type ObjectInfo struct {
+	Size int64
+	ETag string // S3 PUT 1 part (không KMS) -> ETag = hex md5
+}
+
+// S3Store: client S3 tối giản (PUT/HEAD, ký SigV4), chạy được với AWS S3 / MinIO / R2
+type S3Store struct {
+	Endpoint      string // vd https://s3.ap-southeast-1.amazonaws.com hoặc http://minio:9000
+	Region        string
+	Bucket        string
+	AccessKey     string
+	SecretKey     string
+	PublicBaseURL string // URL public của bucket (CDN), rỗng = Endpoint/Bucket
+	PathStyle     bool   // MinIO thường cần path-style
+
+	HTTP *http.Client
+}
+
+// NewS3StoreFromEnv: S3_ENDPOINT, S3_REGION, S3_BUCKET, S3_ACCESS_KEY, S3_SECRET_KEY,
+// S3_PUBLIC_BASE_URL, S3_PATH_STYLE=1. Thiếu bucket/key -> error
+func NewS3StoreFromEnv() (*S3Store, error) {
+	st := &S3Store{
+		Endpoint:      strings.TrimRight(os.Getenv("S3_ENDPOINT"), "/"),
+		Region:        os.Getenv("S3_REGION"),
+		Bucket:        os.Getenv("S3_BUCKET"),
+		AccessKey:     os.Getenv("S3_ACCESS_KEY"),
+		SecretKey:     os.Getenv("S3_SECRET_KEY"),
+		PublicBaseURL: strings.TrimRight(os.Getenv("S3_PUBLIC_BASE_URL"), "/"),
+		PathStyle:     os.Getenv("S3_PATH_STYLE") == "1",
+		HTTP:          &http.Client{Timeout: 5 * time.Minute},
+	}
+
+	if st.Bucket == "" || st.AccessKey == "" || st.SecretKey == "" {
+		return nil, errors.New("storage: S3_BUCKET, S3_ACCESS_KEY, S3_SECRET_KEY are required")
+	}
+	if st.Region == "" {
+		st.Region = "us-east-1"
+	}
+	if st.Endpoint == "" {
+		st.Endpoint = "https://s3." + st.Region + ".amazonaws.com"
+	}
+	return st, nil
+}
+
+// objectURL: URL để gọi API (virtual-host hoặc path-style)
+func (st *S3Store) objectURL(key string) (*url.URL, error) {
+	u, err := url.Parse(st.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if st.PathStyle {
+		u.Path = "/" + st.Bucket + "/" + key
+		u.RawPath = "/" + st.Bucket + "/" + escapeKey(key)
+	} else {
+		u.Host = st.Bucket + "." + u.Host
+		u.Path = "/" + key
+		u.RawPath = "/" + escapeKey(key)
+	}
+	return u, nil
+}
+
+// PublicURL: URL lưu vào DB thay cho /static/...
+func (st *S3Store) PublicURL(key string) string {
+	if st.PublicBaseURL != "" {
+		return st.PublicBaseURL + "/" + escapeKey(key)
+	}
+	u, err := st.objectURL(key)
+	if err != nil {
+		return ""
+	}
+	return u.String()
+}
+
+// Put: upload body (đã biết size + sha256 + md5 base64), S3 tự verify Content-MD5
+func (st *S3Store) Put(ctx context.Context, key string, body io.Reader, size int64, contentType, sha256Hex, md5Base64 string) error {
+	u, err := st.objectURL(key)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Content-MD5", md5Base64)
+	st.sign(req, sha256Hex)
+
+	resp, err := st.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("storage: PUT %s: %s: %s", key, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Head: lấy size + ETag để verify sau khi upload
+func (st *S3Store) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	u, err := st.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	st.sign(req, emptySHA256)
+
+	resp, err := st.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrObjectNotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("storage: HEAD %s: %s", key, resp.Status)
+	}
+
+	return &ObjectInfo{
+		Size: resp.ContentLength,
+		ETag: strings.Trim(resp.Header.Get("ETag"), `"`),
+	}, nil
+}
+
+// Save: hash 1 lượt (sha256 cho SigV4 + md5 để S3 verify) rồi PUT
+func (st *S3Store) Save(ctx context.Context, key string, body io.ReadSeeker, contentType string) error {
+	md5h := md5.New()
+	shah := sha256.New()
+	size, err := io.Copy(io.MultiWriter(md5h, shah), body)
+	if err != nil {
+		return err
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return st.Put(ctx, key, body, size, contentType, hex.EncodeToString(shah.Sum(nil)), base64.StdEncoding.EncodeToString(md5h.Sum(nil)))
+}
+
+// Delete: S3 trả 204 cả khi key không tồn tại
+func (st *S3Store) Delete(ctx context.Context, key string) error {
+	u, err := st.objectURL(key)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	st.sign(req, emptySHA256)
+
+	resp, err := st.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusNotFound {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("storage: DELETE %s: %s: %s", key, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// LocalPath: object nằm trên S3
+func (st *S3Store) LocalPath(key string) string {
+	return ""
+}
+
+// presign tối đa 7 ngày (giới hạn của SigV4)
+const maxPresignTTL = 7 * 24 * time.Hour
+
+// PresignGet: URL GET ký SigV4 trên query string, bucket private vẫn tải được
+// contentType / disposition -> response-content-type / response-content-disposition
+func (st *S3Store) PresignGet(key string, ttl time.Duration, contentType, disposition string) (string, error) {
+	return st.presignGet(key, ttl, contentType, disposition, time.Now())
+}
+
+func (st *S3Store) presignGet(key string, ttl time.Duration, contentType, disposition string, now time.Time) (string, error) {
+	u, err := st.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+	ttl = min(max(ttl, time.Second), maxPresignTTL)
+
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	scope := date + "/" + st.Region + "/s3/aws4_request"
+
+	q := url.Values{}
+	q.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	q.Set("X-Amz-Credential", st.AccessKey+"/"+scope)
+	q.Set("X-Amz-Date", amzDate)
+	q.Set("X-Amz-Expires", strconv.FormatInt(int64(ttl/time.Second), 10))
+	q.Set("X-Amz-SignedHeaders", "host")
+	if contentType != "" {
+		q.Set("response-content-type", contentType)
+	}
+	if disposition != "" {
+		q.Set("response-content-disposition", disposition)
+	}
+	// SigV4 encode space = %20 (QueryEscape ra "+")
+	canonQuery := strings.ReplaceAll(q.Encode(), "+", "%20")
+
+	canonReq := strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		canonQuery,
+		"host:" + u.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	toSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hexSHA256([]byte(canonReq)),
+	}, "\n")
+	sig := hex.EncodeToString(hmacSHA256(st.signingKey(date), toSign))
+
+	u.RawQuery = canonQuery + "&X-Amz-Signature=" + sig
+	return u.String(), nil
+}
+
+// ==============================
+// SigV4
+// ==============================
+
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+func (st *S3Store) sign(req *http.Request, payloadHash string) {
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	// canonical headers: host + content-md5/content-type (nếu có) + x-amz-*
+	names := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	for _, h := range []string{"Content-Md5", "Content-Type"} {
+		if req.Header.Get(h) != "" {
+			names = append(names, strings.ToLower(h))
+		}
+	}
+	sort.Strings(names)
+
+	var canonHeaders strings.Builder
+	for _, n := range names {
+		v := req.Header.Get(n)
+		if n == "host" {
+			v = req.URL.Host
+		}
+		canonHeaders.WriteString(n + ":" + strings.TrimSpace(v) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonReq := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + st.Region + "/s3/aws4_request"
+	toSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hexSHA256([]byte(canonReq)),
+	}, "\n")
+
+	sig := hex.EncodeToString(hmacSHA256(st.signingKey(date), toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		st.AccessKey, scope, signedHeaders, sig,
+	))
+}
+
+func (st *S3Store) signingKey(date string) []byte {
+	kDate := hmacSHA256([]byte("AWS4"+st.SecretKey), date)
+	kRegion := hmacSHA256(kDate, st.Region)
+	kService := hmacSHA256(kRegion, "s3")
+	return hmacSHA256(kService, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hexSHA256(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// escapeKey: encode từng segment theo kiểu S3 (giữ "/")
+func escapeKey(key string) string {
+	parts := strings.Split(key, "/")
+	for i, p := range parts {
+		parts[i] = strings.ReplaceAll(url.PathEscape(p), "+", "%2B")
+	}
+	return strings.Join(parts, "/")
+}
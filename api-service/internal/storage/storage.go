@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// prefix key theo loại file (trùng tên thư mục local + prefix trong bucket)
+const (
+	ChatUploadKeyPrefix = "chat_uploads/"
+	AvatarKeyPrefix     = "user_avatars/"
+)
+
+// Storage: nơi lưu file upload (avatar + chat), key dạng "chat_uploads/<file>"
+type Storage interface {
+	// Save: ghi đè nếu key đã có
+	Save(ctx context.Context, key string, body io.ReadSeeker, contentType string) error
+	Head(ctx context.Context, key string) (*ObjectInfo, error)
+	// Delete: key không tồn tại -> nil
+	Delete(ctx context.Context, key string) error
+
+	// LocalPath: file trên đĩa, "" nếu object nằm ở remote (probe / transcode cần file local)
+	LocalPath(key string) string
+	// PresignGet: URL tạm để client tải thẳng từ object store, "" nếu driver không hỗ trợ
+	PresignGet(key string, ttl time.Duration, contentType, disposition string) (string, error)
+}
+
+// NewFromEnv: STORAGE_DRIVER = local (mặc định) | s3 | minio
+func NewFromEnv(chatUploadDir, avatarDir string) (Storage, error) {
+	switch driver := strings.ToLower(strings.TrimSpace(os.Getenv("STORAGE_DRIVER"))); driver {
+	case "", "local":
+		return NewLocalStore(chatUploadDir, avatarDir), nil
+	case "s3", "minio":
+		return NewS3StoreFromEnv()
+	default:
+		return nil, fmt.Errorf("storage: unknown STORAGE_DRIVER %q (local | s3 | minio)", driver)
+	}
+}
+
+// ==============================
+// Local disk
+// ==============================
+
+// LocalStore: mỗi prefix key map vào 1 thư mục (giữ layout ./data cũ)
+type LocalStore struct {
+	Dirs map[string]string // "chat_uploads/" -> ./data/chat_uploads
+}
+
+func NewLocalStore(chatUploadDir, avatarDir string) *LocalStore {
+	return &LocalStore{Dirs: map[string]string{
+		ChatUploadKeyPrefix: chatUploadDir,
+		AvatarKeyPrefix:     avatarDir,
+	}}
+}
+
+// path: key -> file trên đĩa, chỉ nhận 1 segment sau prefix (chặn path traversal)
+func (ls *LocalStore) path(key string) (string, error) {
+	for prefix, dir := range ls.Dirs {
+		name, ok := strings.CutPrefix(key, prefix)
+		if !ok {
+			continue
+		}
+		if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") || strings.Contains(name, "\\") {
+			return "", fmt.Errorf("storage: invalid key %q", key)
+		}
+		return filepath.Join(dir, name), nil
+	}
+	return "", fmt.Errorf("storage: unknown key prefix %q", key)
+}
+
+// Save: ghi file tạm cùng thư mục rồi rename -> không ai đọc được file dở dang
+func (ls *LocalStore) Save(ctx context.Context, key string, body io.ReadSeeker, contentType string) error {
+	p, err := ls.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+func (ls *LocalStore) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	p, err := ls.path(key)
+	if err != nil {
+		return nil, err
+	}
+	st, err := os.Stat(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, err
+	}
+	if st.IsDir() {
+		return nil, ErrObjectNotFound
+	}
+	return &ObjectInfo{Size: st.Size()}, nil
+}
+
+func (ls *LocalStore) Delete(ctx context.Context, key string) error {
+	p, err := ls.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (ls *LocalStore) LocalPath(key string) string {
+	p, err := ls.path(key)
+	if err != nil {
+		return ""
+	}
+	return p
+}
+
+// PresignGet: local không có URL trực tiếp, server tự stream file
+func (ls *LocalStore) PresignGet(key string, ttl time.Duration, contentType, disposition string) (string, error) {
+	return "", nil
+}
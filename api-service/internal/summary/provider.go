@@ -0,0 +1,183 @@
+package summary
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Provider: 1 LLM, nhận system prompt + nội dung, trả text
+type Provider interface {
+	Complete(ctx context.Context, system, prompt string, maxTokens int) (string, error)
+}
+
+// ErrDisabled: chưa cấu hình LLM_PROVIDER -> tắt /rooms/summarize
+var ErrDisabled = errors.New("summary: LLM_PROVIDER is not configured")
+
+// NewProviderFromEnv: LLM_PROVIDER = openai | anthropic
+// LLM_API_KEY, LLM_MODEL, LLM_BASE_URL (tuỳ chọn: endpoint tương thích OpenAI tự host)
+func NewProviderFromEnv() (Provider, error) {
+	kind := strings.ToLower(strings.TrimSpace(os.Getenv("LLM_PROVIDER")))
+	if kind == "" {
+		return nil, ErrDisabled
+	}
+	key := os.Getenv("LLM_API_KEY")
+	model := os.Getenv("LLM_MODEL")
+	base := strings.TrimRight(os.Getenv("LLM_BASE_URL"), "/")
+	if model == "" {
+		return nil, errors.New("summary: LLM_MODEL is required")
+	}
+	client := &http.Client{Timeout: 60 * time.Second}
+
+	switch kind {
+	case "openai":
+		if base == "" {
+			base = "https://api.openai.com/v1"
+		}
+		if key == "" && strings.HasPrefix(base, "https://api.openai.com") {
+			return nil, errors.New("summary: LLM_API_KEY is required")
+		}
+		return &OpenAIProvider{APIKey: key, Model: model, BaseURL: base, http: client}, nil
+	case "anthropic":
+		if base == "" {
+			base = "https://api.anthropic.com/v1"
+		}
+		if key == "" {
+			return nil, errors.New("summary: LLM_API_KEY is required")
+		}
+		return &AnthropicProvider{APIKey: key, Model: model, BaseURL: base, http: client}, nil
+	default:
+		return nil, fmt.Errorf("summary: unknown LLM_PROVIDER %q (openai, anthropic)", os.Getenv("LLM_PROVIDER"))
+	}
+}
+
+// =======================================
+// OpenAI (Chat Completions, cũng dùng được cho server tương thích: vLLM, Ollama...)
+// =======================================
+
+type OpenAIProvider struct {
+	APIKey  string
+	Model   string
+	BaseURL string
+
+	http *http.Client
+}
+
+func (p *OpenAIProvider) Complete(ctx context.Context, system, prompt string, maxTokens int) (string, error) {
+	body := map[string]any{
+		"model": p.Model,
+		"messages": []map[string]string{
+			{"role": "system", "content": system},
+			{"role": "user", "content": prompt},
+		},
+		"max_tokens":  maxTokens,
+		"temperature": 0.2,
+	}
+	headers := map[string]string{}
+	if p.APIKey != "" {
+		headers["Authorization"] = "Bearer " + p.APIKey
+	}
+
+	var out struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := postJSON(ctx, p.http, p.BaseURL+"/chat/completions", headers, body, &out); err != nil {
+		return "", fmt.Errorf("summary: openai: %w", err)
+	}
+	if len(out.Choices) == 0 {
+		return "", errors.New("summary: openai: empty response")
+	}
+	return out.Choices[0].Message.Content, nil
+}
+
+// =======================================
+// Anthropic (Messages API)
+// =======================================
+
+type AnthropicProvider struct {
+	APIKey  string
+	Model   string
+	BaseURL string
+
+	http *http.Client
+}
+
+func (p *AnthropicProvider) Complete(ctx context.Context, system, prompt string, maxTokens int) (string, error) {
+	body := map[string]any{
+		"model":      p.Model,
+		"system":     system,
+		"max_tokens": maxTokens,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+	}
+	headers := map[string]string{
+		"x-api-key":         p.APIKey,
+		"anthropic-version": "2023-06-01",
+	}
+
+	var out struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	if err := postJSON(ctx, p.http, p.BaseURL+"/messages", headers, body, &out); err != nil {
+		return "", fmt.Errorf("summary: anthropic: %w", err)
+	}
+	var b strings.Builder
+	for _, c := range out.Content {
+		if c.Type == "text" {
+			b.WriteString(c.Text)
+		}
+	}
+	if b.Len() == 0 {
+		return "", errors.New("summary: anthropic: empty response")
+	}
+	return b.String(), nil
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body, out any) error {
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		msg := string(b)
+		if len(msg) > 300 {
+			msg = msg[:300]
+		}
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(msg))
+	}
+	return json.Unmarshal(b, out)
+}
@@ -0,0 +1,169 @@
+package survey
+
+import (
+	"context"
+	"database/sql"
+)
+
+// số câu trả lời text gần nhất trả về mỗi câu hỏi
+const reportTextLimit = 50
+
+// QuestionReport: tổng hợp 1 câu hỏi
+type QuestionReport struct {
+	QuestionID string `json:"question_id"`
+	Type       string `json:"type"`
+	Text       string `json:"text"`
+	Answers    int    `json:"answers"`
+
+	// nps: % promoter (9-10) - % detractor (0-6), -100..100
+	NPS        *float64 `json:"nps,omitempty"`
+	Promoters  int      `json:"promoters,omitempty"`
+	Passives   int      `json:"passives,omitempty"`
+	Detractors int      `json:"detractors,omitempty"`
+
+	// nps / rating
+	Average      *float64    `json:"average,omitempty"`
+	Distribution map[int]int `json:"distribution,omitempty"`
+
+	// choice
+	Options []OptionCount `json:"options,omitempty"`
+
+	// text (mới nhất trước)
+	TextAnswers []string `json:"text_answers,omitempty"`
+}
+
+type OptionCount struct {
+	Option string `json:"option"`
+	Count  int    `json:"count"`
+}
+
+// Report: runID = 0 -> gộp mọi lần gửi
+type Report struct {
+	SurveyID    int64            `json:"survey_id"`
+	RunID       int64            `json:"run_id,omitempty"`
+	Respondents int              `json:"respondents"`
+	Questions   []QuestionReport `json:"questions"`
+}
+
+func (r *Repository) Report(ctx context.Context, s *Survey, runID int64) (*Report, error) {
+	filter := `survey_id = ?`
+	args := []any{s.ID}
+	if runID > 0 {
+		filter += ` AND run_id = ?`
+		args = append(args, runID)
+	}
+
+	out := &Report{SurveyID: s.ID, RunID: runID, Questions: []QuestionReport{}}
+	if err := r.DB.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT run_id, user_id) FROM survey_responses WHERE `+filter,
+		args...).Scan(&out.Respondents); err != nil {
+		return nil, err
+	}
+
+	// số lượng theo (câu hỏi, value)
+	counts := map[string]map[int]int{}
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT question_id, value, COUNT(*) FROM survey_responses
+		WHERE `+filter+` AND value IS NOT NULL
+		GROUP BY question_id, value
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var qid string
+		var v, n int
+		if err := rows.Scan(&qid, &v, &n); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if counts[qid] == nil {
+			counts[qid] = map[int]int{}
+		}
+		counts[qid][v] = n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, q := range s.Questions {
+		qr := QuestionReport{QuestionID: q.ID, Type: q.Type, Text: q.Text}
+		dist := counts[q.ID]
+
+		switch q.Type {
+		case QuestionNPS, QuestionRating:
+			var sum int
+			for v, n := range dist {
+				qr.Answers += n
+				sum += v * n
+				if q.Type == QuestionNPS {
+					switch {
+					case v >= 9:
+						qr.Promoters += n
+					case v >= 7:
+						qr.Passives += n
+					default:
+						qr.Detractors += n
+					}
+				}
+			}
+			qr.Distribution = dist
+			if qr.Answers > 0 {
+				avg := float64(sum) / float64(qr.Answers)
+				qr.Average = &avg
+				if q.Type == QuestionNPS {
+					nps := float64(qr.Promoters-qr.Detractors) * 100 / float64(qr.Answers)
+					qr.NPS = &nps
+				}
+			}
+
+		case QuestionChoice:
+			qr.Options = make([]OptionCount, len(q.Options))
+			for i, o := range q.Options {
+				qr.Options[i] = OptionCount{Option: o, Count: dist[i]}
+				qr.Answers += dist[i]
+			}
+
+		case QuestionText:
+			texts, total, err := r.textAnswers(ctx, filter, args, q.ID)
+			if err != nil {
+				return nil, err
+			}
+			qr.TextAnswers, qr.Answers = texts, total
+		}
+		out.Questions = append(out.Questions, qr)
+	}
+	return out, nil
+}
+
+func (r *Repository) textAnswers(ctx context.Context, filter string, args []any, questionID string) ([]string, int, error) {
+	qargs := append(append([]any{}, args...), questionID)
+	var total int
+	if err := r.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM survey_responses WHERE `+filter+` AND question_id = ? AND answer_text IS NOT NULL
+	`, qargs...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT answer_text FROM survey_responses
+		WHERE `+filter+` AND question_id = ? AND answer_text IS NOT NULL
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, append(qargs, reportTextLimit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	out := []string{}
+	for rows.Next() {
+		var t sql.NullString
+		if err := rows.Scan(&t); err != nil {
+			return nil, 0, err
+		}
+		out = append(out, t.String)
+	}
+	return out, total, rows.Err()
+}
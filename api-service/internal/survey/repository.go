@@ -0,0 +1,472 @@
+package survey
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// loại câu hỏi
+const (
+	QuestionNPS    = "nps"    // 0..10
+	QuestionRating = "rating" // 1..5
+	QuestionChoice = "choice" // 1 trong options
+	QuestionText   = "text"
+)
+
+const (
+	MaxQuestions  = 20
+	MaxTargets    = 200
+	MaxTextAnswer = 2000
+)
+
+var (
+	ErrNotFound      = errors.New("survey: not found")
+	ErrRunNotFound   = errors.New("survey: run not found")
+	ErrInvalidAnswer = errors.New("survey: invalid answer")
+)
+
+type Question struct {
+	ID       string   `json:"id"`
+	Type     string   `json:"type"`
+	Text     string   `json:"text"`
+	Options  []string `json:"options,omitempty"` // chỉ cho choice
+	Required bool     `json:"required"`
+}
+
+// Survey: bộ câu hỏi + nơi gửi (room và / hoặc DM từng user)
+type Survey struct {
+	ID        int64      `json:"id"`
+	Title     string     `json:"title"`
+	Intro     string     `json:"intro,omitempty"`
+	Questions []Question `json:"questions"`
+	RoomIDs   []int64    `json:"room_ids"`
+	UserIDs   []int64    `json:"user_ids"` // gửi DM từ bot
+	CronSpec  string     `json:"cron,omitempty"`
+	JobID     int64      `json:"job_id,omitempty"` // job survey_post tự tạo khi có cron
+	Active    bool       `json:"active"`
+	CreatedBy int64      `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// Validate: chuẩn hoá + kiểm tra, gán id câu hỏi còn thiếu (q1, q2, ...)
+func (s *Survey) Validate() error {
+	s.Title = strings.TrimSpace(s.Title)
+	if s.Title == "" || len([]rune(s.Title)) > 200 {
+		return errors.New("title is required (max 200 chars)")
+	}
+	s.Intro = strings.TrimSpace(s.Intro)
+	if len([]rune(s.Intro)) > 2000 {
+		return errors.New("intro too long (max 2000 chars)")
+	}
+	if len(s.Questions) == 0 || len(s.Questions) > MaxQuestions {
+		return fmt.Errorf("questions must have 1..%d items", MaxQuestions)
+	}
+	seen := map[string]bool{}
+	for i := range s.Questions {
+		q := &s.Questions[i]
+		q.ID = strings.TrimSpace(q.ID)
+		if q.ID == "" {
+			q.ID = fmt.Sprintf("q%d", i+1)
+		}
+		if len(q.ID) > 32 || seen[q.ID] {
+			return fmt.Errorf("questions[%d].id must be unique (max 32 chars)", i)
+		}
+		seen[q.ID] = true
+
+		q.Text = strings.TrimSpace(q.Text)
+		if q.Text == "" || len([]rune(q.Text)) > 500 {
+			return fmt.Errorf("questions[%d].text is required (max 500 chars)", i)
+		}
+		q.Type = strings.ToLower(strings.TrimSpace(q.Type))
+		switch q.Type {
+		case QuestionNPS, QuestionRating, QuestionText:
+			q.Options = nil
+		case QuestionChoice:
+			if len(q.Options) < 2 || len(q.Options) > 10 {
+				return fmt.Errorf("questions[%d].options must have 2..10 items", i)
+			}
+			for j, o := range q.Options {
+				q.Options[j] = strings.TrimSpace(o)
+				if q.Options[j] == "" || len([]rune(q.Options[j])) > 100 {
+					return fmt.Errorf("questions[%d].options[%d] is required (max 100 chars)", i, j)
+				}
+			}
+		default:
+			return fmt.Errorf("questions[%d].type must be nps, rating, choice or text", i)
+		}
+	}
+	s.RoomIDs = uniqueIDs(s.RoomIDs)
+	s.UserIDs = uniqueIDs(s.UserIDs)
+	if len(s.RoomIDs)+len(s.UserIDs) == 0 {
+		return errors.New("room_ids or user_ids is required")
+	}
+	if len(s.RoomIDs)+len(s.UserIDs) > MaxTargets {
+		return fmt.Errorf("too many targets (max %d)", MaxTargets)
+	}
+	return nil
+}
+
+func uniqueIDs(ids []int64) []int64 {
+	out := []int64{}
+	seen := map[int64]bool{}
+	for _, id := range ids {
+		if id > 0 && !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// Question: câu hỏi theo id (nil nếu không có)
+func (s *Survey) Question(id string) *Question {
+	for i := range s.Questions {
+		if s.Questions[i].ID == id {
+			return &s.Questions[i]
+		}
+	}
+	return nil
+}
+
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+const selectSurvey = `
+	SELECT id, title, COALESCE(intro, ''), questions, room_ids, user_ids, COALESCE(cron_spec, ''),
+	       COALESCE(job_id, 0), active, created_by, created_at, updated_at
+	FROM surveys
+`
+
+func scanSurvey(sc interface{ Scan(...any) error }) (*Survey, error) {
+	var s Survey
+	var questions, rooms, users []byte
+	var updated sql.NullTime
+	if err := sc.Scan(&s.ID, &s.Title, &s.Intro, &questions, &rooms, &users, &s.CronSpec,
+		&s.JobID, &s.Active, &s.CreatedBy, &s.CreatedAt, &updated); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(questions, &s.Questions); err != nil {
+		return nil, err
+	}
+	_ = json.Unmarshal(rooms, &s.RoomIDs)
+	_ = json.Unmarshal(users, &s.UserIDs)
+	if s.RoomIDs == nil {
+		s.RoomIDs = []int64{}
+	}
+	if s.UserIDs == nil {
+		s.UserIDs = []int64{}
+	}
+	if updated.Valid {
+		s.UpdatedAt = &updated.Time
+	}
+	return &s, nil
+}
+
+func (r *Repository) Create(ctx context.Context, s *Survey) error {
+	questions, rooms, users, err := marshalSurvey(s)
+	if err != nil {
+		return err
+	}
+	res, err := r.DB.ExecContext(ctx, `
+		INSERT INTO surveys (title, intro, questions, room_ids, user_ids, cron_spec, active, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, s.Title, nullIfEmpty(s.Intro), questions, rooms, users, nullIfEmpty(s.CronSpec), s.Active, s.CreatedBy)
+	if err != nil {
+		return err
+	}
+	s.ID, _ = res.LastInsertId()
+	s.CreatedAt = time.Now()
+	return nil
+}
+
+func (r *Repository) Update(ctx context.Context, s *Survey) error {
+	questions, rooms, users, err := marshalSurvey(s)
+	if err != nil {
+		return err
+	}
+	_, err = r.DB.ExecContext(ctx, `
+		UPDATE surveys
+		SET title = ?, intro = ?, questions = ?, room_ids = ?, user_ids = ?, cron_spec = ?, active = ?
+		WHERE id = ?
+	`, s.Title, nullIfEmpty(s.Intro), questions, rooms, users, nullIfEmpty(s.CronSpec), s.Active, s.ID)
+	return err
+}
+
+// SetJobID: gắn job survey_post (0 = bỏ)
+func (r *Repository) SetJobID(ctx context.Context, id, jobID int64) error {
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE surveys SET job_id = NULLIF(?, 0) WHERE id = ?
+	`, jobID, id)
+	return err
+}
+
+func marshalSurvey(s *Survey) (questions, rooms, users []byte, err error) {
+	if questions, err = json.Marshal(s.Questions); err != nil {
+		return
+	}
+	if rooms, err = json.Marshal(s.RoomIDs); err != nil {
+		return
+	}
+	users, err = json.Marshal(s.UserIDs)
+	return
+}
+
+// Delete: xoá survey + mọi lần gửi / câu trả lời
+func (r *Repository) Delete(ctx context.Context, id int64) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM surveys WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	for _, q := range []string{
+		`DELETE FROM survey_responses WHERE survey_id = ?`,
+		`DELETE FROM survey_deliveries WHERE run_id IN (SELECT id FROM survey_runs WHERE survey_id = ?)`,
+		`DELETE FROM survey_runs WHERE survey_id = ?`,
+	} {
+		if _, err := tx.ExecContext(ctx, q, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *Repository) Get(ctx context.Context, id int64) (*Survey, error) {
+	s, err := scanSurvey(r.DB.QueryRowContext(ctx, selectSurvey+` WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return s, err
+}
+
+func (r *Repository) List(ctx context.Context) ([]*Survey, error) {
+	rows, err := r.DB.QueryContext(ctx, selectSurvey+` ORDER BY id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*Survey{}
+	for rows.Next() {
+		s, err := scanSurvey(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// ===== lần gửi =====
+
+// Run: 1 lần bot gửi survey (theo lịch hoặc chạy tay)
+type Run struct {
+	ID        int64     `json:"id"`
+	SurveyID  int64     `json:"survey_id"`
+	Delivered int       `json:"delivered"`
+	Responses int       `json:"responses"` // số user đã trả lời
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (r *Repository) StartRun(ctx context.Context, surveyID int64) (int64, error) {
+	res, err := r.DB.ExecContext(ctx, `
+		INSERT INTO survey_runs (survey_id) VALUES (?)
+	`, surveyID)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// AddDelivery: room đã nhận tin survey của run
+func (r *Repository) AddDelivery(ctx context.Context, runID, roomID, messageID int64) error {
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT INTO survey_deliveries (run_id, room_id, message_id) VALUES (?, ?, ?)
+	`, runID, roomID, messageID)
+	return err
+}
+
+// GetRun: survey của run
+func (r *Repository) GetRun(ctx context.Context, runID int64) (*Run, *Survey, error) {
+	var run Run
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT id, survey_id, created_at FROM survey_runs WHERE id = ?
+	`, runID).Scan(&run.ID, &run.SurveyID, &run.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrRunNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	s, err := r.Get(ctx, run.SurveyID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil, ErrRunNotFound
+		}
+		return nil, nil, err
+	}
+	return &run, s, nil
+}
+
+// CanRespond: user là member của ít nhất 1 room đã nhận run này
+func (r *Repository) CanRespond(ctx context.Context, runID, userID int64) (bool, error) {
+	var n int
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM survey_deliveries d
+		JOIN room_members rm ON rm.room_id = d.room_id AND rm.user_id = ?
+		WHERE d.run_id = ?
+	`, userID, runID).Scan(&n)
+	return n > 0, err
+}
+
+func (r *Repository) ListRuns(ctx context.Context, surveyID int64, limit int) ([]*Run, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT sr.id, sr.survey_id, sr.created_at,
+		       (SELECT COUNT(*) FROM survey_deliveries d WHERE d.run_id = sr.id),
+		       (SELECT COUNT(DISTINCT x.user_id) FROM survey_responses x WHERE x.run_id = sr.id)
+		FROM survey_runs sr
+		WHERE sr.survey_id = ?
+		ORDER BY sr.id DESC
+		LIMIT ?
+	`, surveyID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*Run{}
+	for rows.Next() {
+		var run Run
+		if err := rows.Scan(&run.ID, &run.SurveyID, &run.CreatedAt, &run.Delivered, &run.Responses); err != nil {
+			return nil, err
+		}
+		out = append(out, &run)
+	}
+	return out, rows.Err()
+}
+
+// ===== câu trả lời =====
+
+// Answer: value cho nps / rating / choice (index option), text cho text
+type Answer struct {
+	QuestionID string `json:"question_id"`
+	Value      *int   `json:"value,omitempty"`
+	Text       string `json:"text,omitempty"`
+}
+
+// CheckAnswers: đối chiếu với câu hỏi của survey, thiếu câu required -> lỗi
+func CheckAnswers(s *Survey, answers []Answer) error {
+	got := map[string]bool{}
+	for i := range answers {
+		a := &answers[i]
+		q := s.Question(a.QuestionID)
+		if q == nil || got[a.QuestionID] {
+			return fmt.Errorf("%w: unknown or duplicate question_id %q", ErrInvalidAnswer, a.QuestionID)
+		}
+		got[a.QuestionID] = true
+
+		switch q.Type {
+		case QuestionNPS:
+			if a.Value == nil || *a.Value < 0 || *a.Value > 10 {
+				return fmt.Errorf("%w: %s must be 0..10", ErrInvalidAnswer, q.ID)
+			}
+			a.Text = ""
+		case QuestionRating:
+			if a.Value == nil || *a.Value < 1 || *a.Value > 5 {
+				return fmt.Errorf("%w: %s must be 1..5", ErrInvalidAnswer, q.ID)
+			}
+			a.Text = ""
+		case QuestionChoice:
+			if a.Value == nil || *a.Value < 0 || *a.Value >= len(q.Options) {
+				return fmt.Errorf("%w: %s must be an option index 0..%d", ErrInvalidAnswer, q.ID, len(q.Options)-1)
+			}
+			a.Text = ""
+		case QuestionText:
+			a.Text = strings.TrimSpace(a.Text)
+			if a.Text == "" || len([]rune(a.Text)) > MaxTextAnswer {
+				return fmt.Errorf("%w: %s must be 1..%d chars", ErrInvalidAnswer, q.ID, MaxTextAnswer)
+			}
+			a.Value = nil
+		}
+	}
+	for _, q := range s.Questions {
+		if q.Required && !got[q.ID] {
+			return fmt.Errorf("%w: %s is required", ErrInvalidAnswer, q.ID)
+		}
+	}
+	return nil
+}
+
+// SaveAnswers: trả lời lại cùng run -> ghi đè câu cũ
+func (r *Repository) SaveAnswers(ctx context.Context, runID, surveyID, userID int64, answers []Answer) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, a := range answers {
+		var v sql.NullInt64
+		if a.Value != nil {
+			v = sql.NullInt64{Int64: int64(*a.Value), Valid: true}
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO survey_responses (run_id, survey_id, user_id, question_id, value, answer_text)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE value = VALUES(value), answer_text = VALUES(answer_text), created_at = CURRENT_TIMESTAMP
+		`, runID, surveyID, userID, a.QuestionID, v, nullIfEmpty(a.Text)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// MyAnswers: câu trả lời đã gửi của user trong run
+func (r *Repository) MyAnswers(ctx context.Context, runID, userID int64) ([]Answer, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT question_id, value, COALESCE(answer_text, '')
+		FROM survey_responses WHERE run_id = ? AND user_id = ?
+	`, runID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Answer{}
+	for rows.Next() {
+		var a Answer
+		var v sql.NullInt64
+		if err := rows.Scan(&a.QuestionID, &v, &a.Text); err != nil {
+			return nil, err
+		}
+		if v.Valid {
+			n := int(v.Int64)
+			a.Value = &n
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,123 @@
+package unread
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+// MessageCond: tin m (messages) chưa đọc với member rm (room_members), nguồn duy nhất cho
+// REST, list room, WS push, digest. Đếm theo watermark last_seen_message_id (cùng mốc với
+// receipt seen), không theo last_seen_at -> tin chèn lùi giờ (separator 00:00) không lệch
+const MessageCond = `
+	m.id > COALESCE(rm.last_seen_message_id, 0)
+	AND m.sender_id <> rm.user_id
+	AND m.message_type <> 'system'
+	AND m.is_temp = 0
+	AND m.removed_at IS NULL
+`
+
+var ErrNotMember = errors.New("unread: not a room member")
+
+// Counts: số tin chưa đọc của 1 member trong 1 room
+type Counts struct {
+	Unread   int64 `json:"unread_count"`
+	Mentions int64 `json:"mention_count"` // phần chưa đọc có @ mình
+}
+
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// 1 dòng / member: unread + mention cùng điều kiện MessageCond
+const countsSelect = `
+	SELECT rm.room_id, rm.user_id,
+		(
+			SELECT COUNT(*) FROM messages m
+			WHERE m.room_id = rm.room_id AND ` + MessageCond + `
+		) AS unread_count,
+		(
+			SELECT COUNT(*) FROM message_mentions mm
+			JOIN messages m ON m.id = mm.message_id
+			WHERE mm.room_id = rm.room_id AND mm.user_id = rm.user_id AND ` + MessageCond + `
+		) AS mention_count
+	FROM room_members rm
+`
+
+type memberCounts struct {
+	roomID, userID int64
+	Counts
+}
+
+func (r *Repository) query(ctx context.Context, where string, args ...any) ([]memberCounts, error) {
+	rows, err := r.DB.QueryContext(ctx, countsSelect+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []memberCounts
+	for rows.Next() {
+		var c memberCounts
+		if err := rows.Scan(&c.roomID, &c.userID, &c.Unread, &c.Mentions); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// Room: unread của user trong 1 room (ErrNotMember nếu không còn là member)
+func (r *Repository) Room(ctx context.Context, roomID, userID int64) (Counts, error) {
+	rows, err := r.query(ctx, `WHERE rm.room_id = ? AND rm.user_id = ?`, roomID, userID)
+	if err != nil {
+		return Counts{}, err
+	}
+	if len(rows) == 0 {
+		return Counts{}, ErrNotMember
+	}
+	return rows[0].Counts, nil
+}
+
+// ByRooms: room_id -> unread của user, chỉ room có tin chưa đọc
+func (r *Repository) ByRooms(ctx context.Context, userID int64) (map[int64]Counts, error) {
+	rows, err := r.query(ctx, `WHERE rm.user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[int64]Counts)
+	for _, c := range rows {
+		if c.Unread > 0 || c.Mentions > 0 {
+			out[c.roomID] = c.Counts
+		}
+	}
+	return out, nil
+}
+
+// ForMembers: user_id -> unread trong room, 1 query cho cả danh sách người nhận WS
+func (r *Repository) ForMembers(ctx context.Context, roomID int64, userIDs []int64) (map[int64]Counts, error) {
+	out := make(map[int64]Counts, len(userIDs))
+	if len(userIDs) == 0 {
+		return out, nil
+	}
+
+	ph := strings.TrimRight(strings.Repeat("?,", len(userIDs)), ",")
+	args := []any{roomID}
+	for _, id := range userIDs {
+		args = append(args, id)
+	}
+
+	rows, err := r.query(ctx, `WHERE rm.room_id = ? AND rm.user_id IN (`+ph+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range rows {
+		out[c.userID] = c.Counts
+	}
+	return out, nil
+}
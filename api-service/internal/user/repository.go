@@ -48,6 +48,18 @@ func (r *Repository) UpdateLoginAudit(username, ip, lastLogin string) error {
 	return err
 }
 
+// RecordLogin: lịch sử đăng nhập (cả lần sai mật khẩu), xoá theo retention login_audit
+func (r *Repository) RecordLogin(userID int64, ip, userAgent string, success bool) error {
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	_, err := r.DB.Exec(`
+		INSERT INTO login_history (user_id, ip, user_agent, success)
+		VALUES (?, ?, ?, ?)
+	`, userID, ip, userAgent, success)
+	return err
+}
+
 // FindByUsername: dùng cho login
 func (r *Repository) FindByUsername(username string) (*User, error) {
 	row := r.DB.QueryRow(
@@ -229,7 +241,7 @@ func (r *Repository) UpdateUserDynamic(id int64, fields map[string]interface{})
 	query += " WHERE id = ?"
 	args = append(args, id)
 
-	log.Printf(query) // 👈 DÒNG NÀY
+	log.Println(query) // 👈 DÒNG NÀY
 
 	_, err := r.DB.Exec(query, args...)
 	return err
@@ -334,3 +346,27 @@ func (r *Repository) GetUserBrief(ctx context.Context, userID int64) (*UserBrief
 	}
 	return &u, nil
 }
+
+// ListAdminEmails: email của admin / superadmin đang active (người nhận báo cáo mặc định)
+func (r *Repository) ListAdminEmails(ctx context.Context) ([]string, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT email FROM users
+		WHERE role IN ('admin', 'superadmin') AND is_active = 1
+		  AND email IS NOT NULL AND email <> ''
+		ORDER BY id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var e string
+		if err := rows.Scan(&e); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,107 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrSessionRevoked: session đã bị đăng xuất / thu hồi hoặc không tồn tại
+var ErrSessionRevoked = errors.New("session revoked")
+
+// Session: 1 lần đăng nhập = 1 refresh token (id = jti trong JWT)
+type Session struct {
+	ID           string    `json:"id"`
+	UserID       int64     `json:"-"`
+	DeviceName   string    `json:"device_name"`
+	IP           string    `json:"ip"`
+	UserAgent    string    `json:"user_agent"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastActiveAt time.Time `json:"last_active_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+func (r *Repository) CreateSession(ctx context.Context, s *Session) error {
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT INTO user_sessions (id, user_id, device_name, ip, user_agent, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, s.ID, s.UserID, truncate(s.DeviceName, 100), s.IP, truncate(s.UserAgent, 255), s.ExpiresAt)
+	return err
+}
+
+// TouchSession: refresh / mở WS -> cập nhật last active, trả ErrSessionRevoked nếu
+// session không còn hiệu lực
+func (r *Repository) TouchSession(ctx context.Context, id string, userID int64, ip string) error {
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE user_sessions
+		SET last_active_at = CURRENT_TIMESTAMP, ip = ?
+		WHERE id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > NOW()
+	`, ip, id, userID)
+	if err != nil {
+		return err
+	}
+	n, _ := res.RowsAffected()
+	if n > 0 {
+		return nil
+	}
+
+	// MySQL không đếm row nếu giá trị không đổi (cùng giây, cùng IP) -> check lại
+	var ok int
+	err = r.DB.QueryRowContext(ctx, `
+		SELECT 1 FROM user_sessions
+		WHERE id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > NOW()
+	`, id, userID).Scan(&ok)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrSessionRevoked
+	}
+	return err
+}
+
+// ListSessions: session còn hiệu lực, mới hoạt động gần nhất lên đầu
+func (r *Repository) ListSessions(ctx context.Context, userID int64) ([]Session, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT id, user_id, device_name, COALESCE(ip, ''), COALESCE(user_agent, ''),
+		       created_at, last_active_at, expires_at
+		FROM user_sessions
+		WHERE user_id = ? AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_active_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Session{}
+	for rows.Next() {
+		var s Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.DeviceName, &s.IP, &s.UserAgent,
+			&s.CreatedAt, &s.LastActiveAt, &s.ExpiresAt); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// RevokeSession: chỉ thu hồi session của chính user, false = không tìm thấy
+func (r *Repository) RevokeSession(ctx context.Context, userID int64, id string) (bool, error) {
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE user_sessions SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND user_id = ? AND revoked_at IS NULL
+	`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func truncate(s string, n int) string {
+	s = strings.TrimSpace(s)
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
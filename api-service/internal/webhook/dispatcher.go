@@ -0,0 +1,223 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"cronhustler/api-service/internal/linkpreview"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// retry: 10s, 20s, 40s... tối đa MaxAttempts lần
+const (
+	MaxAttempts  = 6
+	baseBackoff  = 10 * time.Second
+	maxBackoff   = 30 * time.Minute
+	pollInterval = 5 * time.Second
+	batchSize    = 20
+	postTimeout  = 10 * time.Second
+)
+
+// Event: body gửi tới webhook
+type Event struct {
+	ID        int64     `json:"id"` // delivery id, receiver dùng để chống trùng
+	Type      string    `json:"type"`
+	RoomID    int64     `json:"room_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Dispatcher: worker đọc webhook_deliveries pending -> POST có ký. URL do user đặt nên
+// IP được check lúc dial (không gọi vào loopback / mạng nội bộ, kể cả qua DNS rebinding)
+type Dispatcher struct {
+	Repo   *Repository
+	Client *http.Client
+
+	wake chan struct{}
+}
+
+func NewDispatcher(repo *Repository) *Dispatcher {
+	return &Dispatcher{
+		Repo:   repo,
+		Client: &http.Client{Timeout: postTimeout, Transport: linkpreview.NewSafeTransport(postTimeout)}, // chỉ dial IP public
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Start: chạy worker cho tới khi ctx bị cancel
+func (d *Dispatcher) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		for {
+			d.runDue(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-d.wake:
+			}
+		}
+	}()
+}
+
+// Emit: lưu event cho webhook của room + bot trong room rồi đánh thức worker
+// actorID: user gây ra event (bot là actor thì không nhận lại event của chính nó)
+func (d *Dispatcher) Emit(ctx context.Context, roomID, actorID int64, event string, data any) error {
+	payload, err := marshalEvent(roomID, event, data)
+	if err != nil {
+		return err
+	}
+
+	n, err := d.Repo.EnqueueEvent(ctx, roomID, actorID, event, payload)
+	if n > 0 {
+		d.notify()
+	}
+	return err
+}
+
+// EmitSystem: event toàn hệ thống (vòng đời user) cho system webhook, room_id = 0
+func (d *Dispatcher) EmitSystem(ctx context.Context, event string, data any) error {
+	payload, err := marshalEvent(0, event, data)
+	if err != nil {
+		return err
+	}
+
+	n, err := d.Repo.EnqueueSystemEvent(ctx, event, payload)
+	if n > 0 {
+		d.notify()
+	}
+	return err
+}
+
+// EmitToBot: event riêng cho 1 bot (vd click button trên tin của bot)
+func (d *Dispatcher) EmitToBot(ctx context.Context, botUserID, roomID int64, event string, data any) error {
+	payload, err := marshalEvent(roomID, event, data)
+	if err != nil {
+		return err
+	}
+
+	n, err := d.Repo.EnqueueForBot(ctx, botUserID, event, payload)
+	if n > 0 {
+		d.notify()
+	}
+	return err
+}
+
+func marshalEvent(roomID int64, event string, data any) ([]byte, error) {
+	return json.Marshal(Event{
+		Type:      event,
+		RoomID:    roomID,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+}
+
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dispatcher) runDue(ctx context.Context) {
+	for {
+		list, err := d.Repo.ListDue(ctx, batchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Println("[webhook] ListDue error:", err)
+			}
+			return
+		}
+		for _, dl := range list {
+			d.deliver(ctx, dl)
+		}
+		if len(list) < batchSize {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, dl Delivery) {
+	// gắn delivery id vào payload (lúc enqueue chưa có id)
+	body := []byte(dl.Payload)
+	var ev map[string]json.RawMessage
+	if err := json.Unmarshal(body, &ev); err == nil {
+		ev["id"] = json.RawMessage(strconv.FormatInt(dl.ID, 10))
+		if b, err := json.Marshal(ev); err == nil {
+			body = b
+		}
+	}
+
+	code, err := d.post(ctx, dl, body)
+	if err == nil {
+		if err := d.Repo.MarkSuccess(ctx, dl.ID, code); err != nil {
+			log.Println("[webhook] MarkSuccess error:", err)
+		}
+		return
+	}
+
+	var next *time.Time
+	if dl.Attempts+1 < MaxAttempts {
+		t := time.Now().Add(backoff(dl.Attempts))
+		next = &t
+	}
+	if err := d.Repo.MarkAttemptFailed(ctx, dl.ID, code, err.Error(), next); err != nil {
+		log.Println("[webhook] MarkAttemptFailed error:", err)
+	}
+}
+
+func (d *Dispatcher) post(ctx context.Context, dl Delivery, body []byte) (int, error) {
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dl.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "CronChat-Webhook/1")
+	req.Header.Set("X-Cronchat-Event", dl.Event)
+	req.Header.Set("X-Cronchat-Delivery", strconv.FormatInt(dl.ID, 10))
+	req.Header.Set("X-Cronchat-Timestamp", ts)
+	req.Header.Set("X-Cronchat-Signature", "sha256="+Sign(dl.Secret, ts, body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign: hex(HMAC-SHA256(secret, timestamp + "." + body))
+// receiver tự tính lại và so với header X-Cronchat-Signature
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func backoff(attempts int) time.Duration {
+	d := baseBackoff << attempts
+	if d <= 0 || d > maxBackoff {
+		return maxBackoff
+	}
+	return d
+}
@@ -0,0 +1,124 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidName = errors.New("webhook: name is required (max 64 chars)")
+
+// IncomingWebhook: token cho hệ thống ngoài (CI, monitoring) post tin vào room
+// tin hiển thị theo Name/AvatarURL, sender_id = CreatedBy
+type IncomingWebhook struct {
+	ID        int64     `json:"id"`
+	RoomID    int64     `json:"room_id"`
+	Name      string    `json:"name"`
+	AvatarURL string    `json:"avatar_url,omitempty"`
+	Token     string    `json:"token,omitempty"` // chỉ trả lúc tạo, DB chỉ lưu hash
+	CreatedBy int64     `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newIncomingToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (r *Repository) CreateIncoming(ctx context.Context, w *IncomingWebhook) error {
+	w.Name = strings.TrimSpace(w.Name)
+	if w.Name == "" || len([]rune(w.Name)) > 64 {
+		return ErrInvalidName
+	}
+	if w.AvatarURL != "" {
+		if err := ValidateURL(w.AvatarURL); err != nil {
+			return err
+		}
+	}
+
+	token, err := newIncomingToken()
+	if err != nil {
+		return err
+	}
+
+	res, err := r.DB.ExecContext(ctx, `
+		INSERT INTO incoming_webhooks (room_id, name, avatar_url, token_hash, created_by)
+		VALUES (?, ?, ?, ?, ?)
+	`, w.RoomID, w.Name, nullIfEmpty(w.AvatarURL), hashToken(token), w.CreatedBy)
+	if err != nil {
+		return err
+	}
+	w.ID, _ = res.LastInsertId()
+	w.Token = token
+	w.CreatedAt = time.Now()
+	return nil
+}
+
+func (r *Repository) ListIncoming(ctx context.Context, roomID int64) ([]IncomingWebhook, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT id, room_id, name, COALESCE(avatar_url, ''), created_by, created_at
+		FROM incoming_webhooks
+		WHERE room_id = ?
+		ORDER BY id ASC
+	`, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []IncomingWebhook{}
+	for rows.Next() {
+		var w IncomingWebhook
+		if err := rows.Scan(&w.ID, &w.RoomID, &w.Name, &w.AvatarURL, &w.CreatedBy, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
+
+func (r *Repository) DeleteIncoming(ctx context.Context, roomID, id int64) error {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM incoming_webhooks WHERE id = ? AND room_id = ?`, id, roomID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetIncomingByToken: token sai / đã xoá -> ErrNotFound
+func (r *Repository) GetIncomingByToken(ctx context.Context, token string) (*IncomingWebhook, error) {
+	var w IncomingWebhook
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT id, room_id, name, COALESCE(avatar_url, ''), created_by, created_at
+		FROM incoming_webhooks
+		WHERE token_hash = ?
+	`, hashToken(token)).Scan(&w.ID, &w.RoomID, &w.Name, &w.AvatarURL, &w.CreatedBy, &w.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
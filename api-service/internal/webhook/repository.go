@@ -0,0 +1,366 @@
+package webhook
+
+import (
+	"context"
+	"cronhustler/api-service/internal/linkpreview"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// các event gửi ra webhook
+const (
+	EventMessageCreated = "message_created"
+	EventMemberAdded    = "member_added"
+	EventMemberRemoved  = "member_removed"
+	// rule automation của room có action = webhook
+	EventAutomationTriggered = "automation_triggered"
+)
+
+// resolve host lúc tạo webhook (chặn tên trỏ vào IP nội bộ)
+const resolveTimeout = 3 * time.Second
+
+// trạng thái 1 lần giao
+const (
+	StatusPending = "pending"
+	StatusSuccess = "success"
+	StatusFailed  = "failed" // hết lượt retry
+)
+
+var (
+	ErrInvalidURL    = errors.New("webhook: url must be absolute http(s)")
+	ErrBlockedURL    = errors.New("webhook: url must not point to a loopback, private or link-local address")
+	ErrInvalidEvents = errors.New("webhook: unknown event")
+	ErrNotFound      = errors.New("webhook: not found")
+)
+
+var AllEvents = []string{EventMessageCreated, EventMemberAdded, EventMemberRemoved, EventAutomationTriggered}
+
+func IsValidEvent(e string) bool {
+	for _, v := range AllEvents {
+		if v == e {
+			return true
+		}
+	}
+	return false
+}
+
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// Webhook: 1 URL nhận event của 1 room
+type Webhook struct {
+	ID        int64     `json:"id"`
+	RoomID    int64     `json:"room_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"` // chỉ trả lúc tạo
+	Events    []string  `json:"events"`
+	IsActive  bool      `json:"is_active"`
+	CreatedBy int64     `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Delivery: 1 event cần giao (cũng là log giao)
+type Delivery struct {
+	ID            int64      `json:"id"`
+	WebhookID     int64      `json:"webhook_id,omitempty"`
+	BotUserID     int64      `json:"bot_user_id,omitempty"`
+	Event         string     `json:"event"`
+	Payload       string     `json:"-"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastStatus    int        `json:"last_status_code,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+
+	// join từ room_webhooks / bots / system_webhooks khi worker lấy job
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+// ValidateURL: http(s) tuyệt đối, host (IP hoặc tên resolve ra) không thuộc mạng nội bộ.
+// DNS có thể đổi sau khi tạo -> Dispatcher check lại IP mỗi lần dial
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") || u.User != nil {
+		return ErrInvalidURL
+	}
+	host := u.Hostname()
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if !linkpreview.IsPublicIP(ip) {
+			return ErrBlockedURL
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		// DNS lỗi tạm thời không chặn tạo webhook, lúc giao vẫn bị check ở dial
+		return nil
+	}
+	for _, ip := range ips {
+		if !linkpreview.IsPublicIP(ip) {
+			return ErrBlockedURL
+		}
+	}
+	return nil
+}
+
+func NewSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func (r *Repository) Create(ctx context.Context, w *Webhook) error {
+	if err := ValidateURL(w.URL); err != nil {
+		return err
+	}
+	if len(w.Events) == 0 {
+		w.Events = AllEvents
+	}
+	for _, e := range w.Events {
+		if !IsValidEvent(e) {
+			return ErrInvalidEvents
+		}
+	}
+	if w.Secret == "" {
+		s, err := NewSecret()
+		if err != nil {
+			return err
+		}
+		w.Secret = s
+	}
+
+	res, err := r.DB.ExecContext(ctx, `
+		INSERT INTO room_webhooks (room_id, url, secret, events, is_active, created_by)
+		VALUES (?, ?, ?, ?, 1, ?)
+	`, w.RoomID, w.URL, w.Secret, strings.Join(w.Events, ","), w.CreatedBy)
+	if err != nil {
+		return err
+	}
+	w.ID, _ = res.LastInsertId()
+	w.IsActive = true
+	w.CreatedAt = time.Now()
+	return nil
+}
+
+func (r *Repository) ListByRoom(ctx context.Context, roomID int64) ([]Webhook, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT id, room_id, url, events, is_active, created_by, created_at
+		FROM room_webhooks
+		WHERE room_id = ?
+		ORDER BY id ASC
+	`, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Webhook{}
+	for rows.Next() {
+		var w Webhook
+		var events string
+		if err := rows.Scan(&w.ID, &w.RoomID, &w.URL, &events, &w.IsActive, &w.CreatedBy, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		w.Events = splitEvents(events)
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
+
+func (r *Repository) Delete(ctx context.Context, roomID, webhookID int64) error {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM room_webhooks WHERE id = ? AND room_id = ?`, webhookID, roomID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *Repository) BelongsToRoom(ctx context.Context, roomID, webhookID int64) (bool, error) {
+	var one int
+	err := r.DB.QueryRowContext(ctx, `SELECT 1 FROM room_webhooks WHERE id = ? AND room_id = ?`, webhookID, roomID).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// EnqueueEvent: tạo 1 delivery cho mỗi webhook active của room có đăng ký event
+// + mỗi bot trong room có subscription (bỏ bot chính là actor để tránh vòng lặp)
+func (r *Repository) EnqueueEvent(ctx context.Context, roomID, actorID int64, event string, payload []byte) (int, error) {
+	res, err := r.DB.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload, status, next_attempt_at)
+		SELECT id, ?, ?, ?, NOW()
+		FROM room_webhooks
+		WHERE room_id = ? AND is_active = 1 AND FIND_IN_SET(?, events) > 0
+	`, event, payload, StatusPending, roomID, event)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+
+	res, err = r.DB.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (bot_user_id, event, payload, status, next_attempt_at)
+		SELECT b.user_id, ?, ?, ?, NOW()
+		FROM bots b
+		JOIN room_members rm ON rm.user_id = b.user_id AND rm.room_id = ?
+		WHERE b.webhook_url IS NOT NULL AND b.user_id <> ? AND FIND_IN_SET(?, b.events) > 0
+	`, event, payload, StatusPending, roomID, actorID, event)
+	if err != nil {
+		return int(n), err
+	}
+	m, _ := res.RowsAffected()
+	return int(n + m), nil
+}
+
+// EnqueueForBot: event chỉ gửi cho 1 bot (vd button_clicked)
+func (r *Repository) EnqueueForBot(ctx context.Context, botUserID int64, event string, payload []byte) (int, error) {
+	res, err := r.DB.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (bot_user_id, event, payload, status, next_attempt_at)
+		SELECT user_id, ?, ?, ?, NOW()
+		FROM bots
+		WHERE user_id = ? AND webhook_url IS NOT NULL AND FIND_IN_SET(?, events) > 0
+	`, event, payload, StatusPending, botUserID, event)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// ListDue: delivery pending đã tới hạn retry (đích = room webhook, bot hoặc system webhook)
+func (r *Repository) ListDue(ctx context.Context, limit int) ([]Delivery, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT d.id, COALESCE(d.webhook_id, 0), COALESCE(d.bot_user_id, 0), d.event, d.payload, d.attempts,
+		       COALESCE(w.url, b.webhook_url, sw.url), COALESCE(w.secret, b.webhook_secret, sw.secret)
+		FROM webhook_deliveries d
+		LEFT JOIN room_webhooks w ON w.id = d.webhook_id
+		LEFT JOIN bots b ON b.user_id = d.bot_user_id
+		LEFT JOIN system_webhooks sw ON sw.id = d.system_webhook_id
+		WHERE d.status = ? AND d.next_attempt_at <= NOW()
+		  AND COALESCE(w.url, b.webhook_url, sw.url) IS NOT NULL
+		ORDER BY d.next_attempt_at ASC, d.id ASC
+		LIMIT ?
+	`, StatusPending, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Delivery
+	for rows.Next() {
+		var d Delivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.BotUserID, &d.Event, &d.Payload, &d.Attempts, &d.URL, &d.Secret); err != nil {
+			return nil, err
+		}
+		d.Status = StatusPending
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+func (r *Repository) MarkSuccess(ctx context.Context, id int64, statusCode int) error {
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = attempts + 1, last_status_code = ?, last_error = NULL,
+		    next_attempt_at = NULL, delivered_at = NOW()
+		WHERE id = ?
+	`, StatusSuccess, statusCode, id)
+	return err
+}
+
+// MarkAttemptFailed: next = nil -> hết lượt, status = failed
+func (r *Repository) MarkAttemptFailed(ctx context.Context, id int64, statusCode int, errMsg string, next *time.Time) error {
+	status := StatusPending
+	if next == nil {
+		status = StatusFailed
+	}
+	if len(errMsg) > 500 {
+		errMsg = errMsg[:500]
+	}
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = attempts + 1, last_status_code = ?, last_error = ?, next_attempt_at = ?
+		WHERE id = ?
+	`, status, nullIfZero(statusCode), errMsg, next, id)
+	return err
+}
+
+func (r *Repository) ListDeliveries(ctx context.Context, webhookID int64, limit int) ([]Delivery, error) {
+	return r.listDeliveries(ctx, "webhook_id", webhookID, limit)
+}
+
+func (r *Repository) ListBotDeliveries(ctx context.Context, botUserID int64, limit int) ([]Delivery, error) {
+	return r.listDeliveries(ctx, "bot_user_id", botUserID, limit)
+}
+
+// column: hằng trong package (webhook_id | bot_user_id | system_webhook_id), không nhận từ input
+func (r *Repository) listDeliveries(ctx context.Context, column string, id int64, limit int) ([]Delivery, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT id, COALESCE(webhook_id, 0), COALESCE(bot_user_id, 0), event, status, attempts,
+		       COALESCE(last_status_code, 0), COALESCE(last_error, ''),
+		       next_attempt_at, delivered_at, created_at
+		FROM webhook_deliveries
+		WHERE `+column+` = ?
+		ORDER BY id DESC
+		LIMIT ?
+	`, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Delivery{}
+	for rows.Next() {
+		var d Delivery
+		var next, delivered sql.NullTime
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.BotUserID, &d.Event, &d.Status, &d.Attempts,
+			&d.LastStatus, &d.LastError, &next, &delivered, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		if next.Valid {
+			d.NextAttemptAt = &next.Time
+		}
+		if delivered.Valid {
+			d.DeliveredAt = &delivered.Time
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+func splitEvents(s string) []string {
+	out := []string{}
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func nullIfZero(v int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(v), Valid: v != 0}
+}
@@ -0,0 +1,208 @@
+package webhook
+
+import (
+	"cronhustler/api-service/internal/chat"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// giới hạn để payload lạ không phình message
+const (
+	slackMaxAttachments = 10
+	slackMaxFields      = 20
+)
+
+var ErrEmptyPayload = errors.New("webhook: payload has no text, blocks or attachments")
+
+// SlackPayload: subset payload của Slack incoming webhook
+// (channel bị bỏ qua; username / icon_url đọc ở handler để ghi đè tên / avatar)
+type SlackPayload struct {
+	Text        string            `json:"text"`
+	Blocks      []slackBlock      `json:"blocks"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+type slackText struct {
+	Type string `json:"type"` // plain_text | mrkdwn
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"` // section | header | context | divider | image
+	Text     *slackText  `json:"text"`
+	Fields   []slackText `json:"fields"`
+	Elements []slackText `json:"elements"` // context: chỉ lấy phần tử có text
+	ImageURL string      `json:"image_url"`
+	AltText  string      `json:"alt_text"`
+}
+
+type slackAttachment struct {
+	Fallback  string `json:"fallback"`
+	Color     string `json:"color"` // good | warning | danger | #hex
+	Pretext   string `json:"pretext"`
+	Title     string `json:"title"`
+	TitleLink string `json:"title_link"`
+	Text      string `json:"text"`
+	Fields    []struct {
+		Title string `json:"title"`
+		Value string `json:"value"`
+		Short bool   `json:"short"`
+	} `json:"fields"`
+	Footer   string       `json:"footer"`
+	ImageURL string       `json:"image_url"`
+	Blocks   []slackBlock `json:"blocks"`
+}
+
+// ParseSlackPayload: JSON Slack -> content + embeds của message
+// content ưu tiên blocks (Slack cũng vậy), text làm fallback
+func ParseSlackPayload(raw []byte) (string, []chat.Embed, error) {
+	var p SlackPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return "", nil, err
+	}
+
+	content := renderSlackBlocks(p.Blocks)
+	if content == "" {
+		content = slackToPlain(p.Text)
+	}
+
+	var embeds []chat.Embed
+	for i, a := range p.Attachments {
+		if i >= slackMaxAttachments {
+			break
+		}
+		e := chat.Embed{
+			Color:     slackColor(a.Color),
+			Pretext:   slackToPlain(a.Pretext),
+			Title:     slackToPlain(a.Title),
+			TitleLink: a.TitleLink,
+			Text:      slackToPlain(a.Text),
+			Footer:    slackToPlain(a.Footer),
+			ImageURL:  a.ImageURL,
+		}
+		if e.Text == "" {
+			e.Text = renderSlackBlocks(a.Blocks)
+		}
+		for j, f := range a.Fields {
+			if j >= slackMaxFields {
+				break
+			}
+			e.Fields = append(e.Fields, chat.EmbedField{
+				Title: slackToPlain(f.Title),
+				Value: slackToPlain(f.Value),
+				Short: f.Short,
+			})
+		}
+		// attachment trống hết -> dùng fallback
+		if e.Title == "" && e.Text == "" && e.Pretext == "" && len(e.Fields) == 0 {
+			e.Text = slackToPlain(a.Fallback)
+		}
+		embeds = append(embeds, e)
+	}
+
+	// chỉ có attachments: content lấy fallback/title đầu tiên (preview, push, digest)
+	if content == "" && len(p.Attachments) > 0 {
+		a := p.Attachments[0]
+		switch {
+		case a.Fallback != "":
+			content = slackToPlain(a.Fallback)
+		case a.Pretext != "":
+			content = slackToPlain(a.Pretext)
+		case a.Title != "":
+			content = slackToPlain(a.Title)
+		default:
+			content = slackToPlain(a.Text)
+		}
+	}
+
+	content = strings.TrimSpace(content)
+	if content == "" && len(embeds) == 0 {
+		return "", nil, ErrEmptyPayload
+	}
+	return content, embeds, nil
+}
+
+// renderSlackBlocks: blocks-lite -> text nhiều dòng (block lạ bị bỏ qua)
+func renderSlackBlocks(blocks []slackBlock) string {
+	var lines []string
+	for _, b := range blocks {
+		switch b.Type {
+		case "header":
+			if b.Text != nil {
+				lines = append(lines, "*"+slackToPlain(b.Text.Text)+"*")
+			}
+		case "section":
+			if b.Text != nil {
+				lines = append(lines, slackToPlain(b.Text.Text))
+			}
+			for _, f := range b.Fields {
+				lines = append(lines, slackToPlain(f.Text))
+			}
+		case "context":
+			var parts []string
+			for _, el := range b.Elements {
+				if el.Text != "" {
+					parts = append(parts, slackToPlain(el.Text))
+				}
+			}
+			if len(parts) > 0 {
+				lines = append(lines, strings.Join(parts, " · "))
+			}
+		case "divider":
+			lines = append(lines, "---")
+		case "image":
+			if b.ImageURL != "" {
+				lines = append(lines, b.ImageURL)
+			}
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// <url|label> -> "label (url)", <url> -> url, <@U1> / <#C1|name> -> @U1 / #name
+var slackLinkRe = regexp.MustCompile(`<([^<>|]+)(?:\|([^<>]*))?>`)
+
+func slackToPlain(s string) string {
+	s = slackLinkRe.ReplaceAllStringFunc(s, func(m string) string {
+		sub := slackLinkRe.FindStringSubmatch(m)
+		target, label := sub[1], sub[2]
+		switch {
+		case strings.HasPrefix(target, "@"), strings.HasPrefix(target, "!"):
+			if label != "" {
+				return "@" + label
+			}
+			return "@" + strings.TrimLeft(target, "@!")
+		case strings.HasPrefix(target, "#"):
+			if label != "" {
+				return "#" + label
+			}
+			return target
+		case label != "":
+			return label + " (" + target + ")"
+		default:
+			return target
+		}
+	})
+	// Slack escape &amp; &lt; &gt;
+	return strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&").Replace(s)
+}
+
+var hexColorRe = regexp.MustCompile(`^#?[0-9a-fA-F]{6}$`)
+
+// slackColor: good/warning/danger -> màu Slack, hex giữ nguyên, còn lại bỏ
+func slackColor(c string) string {
+	switch c {
+	case "good":
+		return "#2EB67D"
+	case "warning":
+		return "#ECB22E"
+	case "danger":
+		return "#E01E5A"
+	}
+	if hexColorRe.MatchString(c) {
+		return "#" + strings.ToUpper(strings.TrimPrefix(c, "#"))
+	}
+	return ""
+}
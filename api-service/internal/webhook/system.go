@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// event vòng đời user, gửi tới system webhook (admin quản lý, không theo room)
+const (
+	EventUserCreated     = "user_created"
+	EventUserUpdated     = "user_updated"
+	EventUserSuspended   = "user_suspended"
+	EventUserDeactivated = "user_deactivated"
+)
+
+var SystemEvents = []string{EventUserCreated, EventUserUpdated, EventUserSuspended, EventUserDeactivated}
+
+func IsValidSystemEvent(e string) bool {
+	for _, v := range SystemEvents {
+		if v == e {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateSystem: webhook toàn hệ thống (RoomID = 0), secret chỉ trả lúc tạo
+func (r *Repository) CreateSystem(ctx context.Context, w *Webhook) error {
+	if err := ValidateURL(w.URL); err != nil {
+		return err
+	}
+	if len(w.Events) == 0 {
+		w.Events = SystemEvents
+	}
+	for _, e := range w.Events {
+		if !IsValidSystemEvent(e) {
+			return ErrInvalidEvents
+		}
+	}
+	if w.Secret == "" {
+		s, err := NewSecret()
+		if err != nil {
+			return err
+		}
+		w.Secret = s
+	}
+
+	res, err := r.DB.ExecContext(ctx, `
+		INSERT INTO system_webhooks (url, secret, events, is_active, created_by)
+		VALUES (?, ?, ?, 1, ?)
+	`, w.URL, w.Secret, strings.Join(w.Events, ","), w.CreatedBy)
+	if err != nil {
+		return err
+	}
+	w.ID, _ = res.LastInsertId()
+	w.IsActive = true
+	w.CreatedAt = time.Now()
+	return nil
+}
+
+func (r *Repository) ListSystem(ctx context.Context) ([]Webhook, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT id, url, events, is_active, created_by, created_at
+		FROM system_webhooks
+		ORDER BY id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Webhook{}
+	for rows.Next() {
+		var w Webhook
+		var events string
+		if err := rows.Scan(&w.ID, &w.URL, &events, &w.IsActive, &w.CreatedBy, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		w.Events = splitEvents(events)
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
+
+func (r *Repository) DeleteSystem(ctx context.Context, id int64) error {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM system_webhooks WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// EnqueueSystemEvent: 1 delivery cho mỗi system webhook active có đăng ký event
+func (r *Repository) EnqueueSystemEvent(ctx context.Context, event string, payload []byte) (int, error) {
+	res, err := r.DB.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (system_webhook_id, event, payload, status, next_attempt_at)
+		SELECT id, ?, ?, ?, NOW()
+		FROM system_webhooks
+		WHERE is_active = 1 AND FIND_IN_SET(?, events) > 0
+	`, event, payload, StatusPending, event)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+func (r *Repository) ListSystemDeliveries(ctx context.Context, webhookID int64, limit int) ([]Delivery, error) {
+	return r.listDeliveries(ctx, "system_webhook_id", webhookID, limit)
+}
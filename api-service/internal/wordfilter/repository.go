@@ -0,0 +1,284 @@
+package wordfilter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mode của 1 từ
+const (
+	ModeReject = "reject" // chặn không cho gửi
+	ModeMask   = "mask"   // thay bằng ***
+	ModeFlag   = "flag"   // vẫn gửi, đẩy vào hàng đợi moderation
+	ModeAllow  = "allow"  // chỉ dùng ở room: bỏ qua từ của list chung
+)
+
+// Action: kết quả kiểm tra 1 tin (ưu tiên reject > flag > mask)
+const (
+	ActionNone   = ""
+	ActionReject = "reject"
+	ActionMask   = "mask"
+	ActionFlag   = "flag"
+)
+
+var (
+	ErrNotFound       = errors.New("wordfilter: not found")
+	ErrInvalidMode    = errors.New("wordfilter: mode must be reject, mask, flag (or allow for room lists)")
+	ErrInvalidPattern = errors.New("wordfilter: pattern must be 1-100 chars, letters/digits/spaces, optional trailing *")
+)
+
+// cache list đã compile, hết hạn để instance khác sửa list vẫn cập nhật
+const cacheTTL = 30 * time.Second
+
+type Entry struct {
+	ID        int64     `json:"id"`
+	RoomID    int64     `json:"room_id,omitempty"` // 0 = toàn hệ thống
+	Pattern   string    `json:"pattern"`           // "word" hoặc "prefix*"
+	Mode      string    `json:"mode"`
+	CreatedBy int64     `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Result struct {
+	Action  string   `json:"action"`
+	Content string   `json:"content"` // nội dung sau khi mask
+	Matches []string `json:"matches"` // pattern bị khớp
+	Flagged []string `json:"flagged,omitempty"`
+}
+
+type rule struct {
+	pattern string
+	mode    string
+	re      *regexp.Regexp
+}
+
+type cached struct {
+	rules   []rule
+	allowed map[string]bool // room: pattern list chung bị tắt
+	at      time.Time
+}
+
+type Repository struct {
+	DB *sql.DB
+
+	mu    sync.Mutex
+	cache map[int64]*cached // 0 = list chung
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db, cache: map[int64]*cached{}}
+}
+
+var patternRe = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N} _.'-]{0,98}\*?$`)
+
+func normalizePattern(p string) (string, error) {
+	p = strings.ToLower(strings.Join(strings.Fields(p), " "))
+	if !patternRe.MatchString(p) {
+		return "", ErrInvalidPattern
+	}
+	return p, nil
+}
+
+// compile: khớp nguyên từ (không dính chữ / số hai bên), "abc*" khớp tiền tố
+func compile(p string) *regexp.Regexp {
+	prefix := strings.HasSuffix(p, "*")
+	p = strings.TrimSuffix(p, "*")
+	expr := `(?i)(?:^|[^\p{L}\p{N}_])(` + regexp.QuoteMeta(p)
+	if prefix {
+		expr += `[\p{L}\p{N}_]*`
+	}
+	expr += `)(?:$|[^\p{L}\p{N}_])`
+	return regexp.MustCompile(expr)
+}
+
+// ===== CRUD =====
+
+func (r *Repository) List(ctx context.Context, roomID int64) ([]*Entry, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT id, room_id, pattern, mode, created_by, created_at
+		FROM word_filters
+		WHERE room_id = ?
+		ORDER BY pattern ASC
+	`, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*Entry{}
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.ID, &e.RoomID, &e.Pattern, &e.Mode, &e.CreatedBy, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, &e)
+	}
+	return out, rows.Err()
+}
+
+// Upsert: thêm / đổi mode (1 pattern chỉ 1 dòng trong mỗi list)
+func (r *Repository) Upsert(ctx context.Context, e *Entry) error {
+	p, err := normalizePattern(e.Pattern)
+	if err != nil {
+		return err
+	}
+	e.Pattern = p
+	switch e.Mode {
+	case ModeReject, ModeMask, ModeFlag:
+	case ModeAllow:
+		if e.RoomID == 0 {
+			return ErrInvalidMode
+		}
+	default:
+		return ErrInvalidMode
+	}
+
+	_, err = r.DB.ExecContext(ctx, `
+		INSERT INTO word_filters (room_id, pattern, mode, created_by)
+		VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE mode = VALUES(mode)
+	`, e.RoomID, e.Pattern, e.Mode, e.CreatedBy)
+	if err != nil {
+		return err
+	}
+	err = r.DB.QueryRowContext(ctx, `
+		SELECT id, created_at FROM word_filters WHERE room_id = ? AND pattern = ?
+	`, e.RoomID, e.Pattern).Scan(&e.ID, &e.CreatedAt)
+	r.invalidate(e.RoomID)
+	return err
+}
+
+func (r *Repository) Get(ctx context.Context, id int64) (*Entry, error) {
+	var e Entry
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT id, room_id, pattern, mode, created_by, created_at
+		FROM word_filters WHERE id = ?
+	`, id).Scan(&e.ID, &e.RoomID, &e.Pattern, &e.Mode, &e.CreatedBy, &e.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *Repository) Delete(ctx context.Context, e *Entry) error {
+	if _, err := r.DB.ExecContext(ctx, `DELETE FROM word_filters WHERE id = ?`, e.ID); err != nil {
+		return err
+	}
+	r.invalidate(e.RoomID)
+	return nil
+}
+
+func (r *Repository) invalidate(roomID int64) {
+	r.mu.Lock()
+	delete(r.cache, roomID)
+	r.mu.Unlock()
+}
+
+// ===== Kiểm tra =====
+
+func (r *Repository) load(ctx context.Context, roomID int64) (*cached, error) {
+	r.mu.Lock()
+	c := r.cache[roomID]
+	r.mu.Unlock()
+	if c != nil && time.Since(c.at) < cacheTTL {
+		return c, nil
+	}
+
+	list, err := r.List(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	c = &cached{allowed: map[string]bool{}, at: time.Now()}
+	for _, e := range list {
+		if e.Mode == ModeAllow {
+			c.allowed[e.Pattern] = true
+			continue
+		}
+		c.rules = append(c.rules, rule{pattern: e.Pattern, mode: e.Mode, re: compile(e.Pattern)})
+	}
+
+	r.mu.Lock()
+	r.cache[roomID] = c
+	r.mu.Unlock()
+	return c, nil
+}
+
+// Check: list của room ghi đè list chung (cùng pattern -> dùng mode của room, allow -> bỏ qua)
+func (r *Repository) Check(ctx context.Context, roomID int64, content string) (*Result, error) {
+	global, err := r.load(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	rules := global.rules
+	if roomID > 0 {
+		room, err := r.load(ctx, roomID)
+		if err != nil {
+			return nil, err
+		}
+		if len(room.rules) > 0 || len(room.allowed) > 0 {
+			override := map[string]bool{}
+			for _, ru := range room.rules {
+				override[ru.pattern] = true
+			}
+			merged := make([]rule, 0, len(global.rules)+len(room.rules))
+			for _, ru := range global.rules {
+				if !override[ru.pattern] && !room.allowed[ru.pattern] {
+					merged = append(merged, ru)
+				}
+			}
+			rules = append(merged, room.rules...)
+		}
+	}
+
+	res := &Result{Action: ActionNone, Content: content, Matches: []string{}}
+	for _, ru := range rules {
+		if !ru.re.MatchString(content) {
+			continue
+		}
+		res.Matches = append(res.Matches, ru.pattern)
+		switch ru.mode {
+		case ModeReject:
+			res.Action = ActionReject
+		case ModeFlag:
+			res.Flagged = append(res.Flagged, ru.pattern)
+			if res.Action != ActionReject {
+				res.Action = ActionFlag
+			}
+		case ModeMask:
+			res.Content = mask(ru.re, res.Content)
+			if res.Action == ActionNone {
+				res.Action = ActionMask
+			}
+		}
+	}
+	return res, nil
+}
+
+// mask: giữ ký tự đầu, còn lại thành * ("shit" -> "s***")
+// quét tiếp từ cuối từ vừa khớp (ký tự biên không bị nuốt -> "shit shit" mask cả 2)
+func mask(re *regexp.Regexp, s string) string {
+	var b strings.Builder
+	pos := 0
+	for pos < len(s) {
+		m := re.FindStringSubmatchIndex(s[pos:])
+		if m == nil {
+			break
+		}
+		start, end := pos+m[2], pos+m[3] // group 1 = từ khớp (không gồm ký tự biên)
+		b.WriteString(s[pos:start])
+		word := []rune(s[start:end])
+		b.WriteString(string(word[0]))
+		b.WriteString(strings.Repeat("*", len(word)-1))
+		pos = end
+	}
+	b.WriteString(s[pos:])
+	return b.String()
+}
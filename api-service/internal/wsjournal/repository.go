@@ -0,0 +1,144 @@
+package wsjournal
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// DefaultTTL: event giữ trong journal bao lâu (client offline lâu hơn -> refetch REST)
+const DefaultTTL = 24 * time.Hour
+
+// xoá theo lô để không giữ lock lâu trên ws_events
+const pruneBatchSize = 1000
+
+// Event: 1 WS event đã ghi journal, Seq = ws_events.id
+type Event struct {
+	Seq       int64
+	Type      string
+	RoomID    int64
+	Payload   json.RawMessage // data của envelope, nil nếu không có
+	CreatedAt time.Time
+}
+
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// Append: ghi event cho danh sách người nhận, trả seq
+func (r *Repository) Append(ctx context.Context, typ string, roomID int64, payload []byte, userIDs []int64) (int64, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var data any
+	if len(payload) > 0 {
+		data = payload
+	}
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO ws_events (type, room_id, payload) VALUES (?, NULLIF(?, 0), ?)
+	`, typ, roomID, data)
+	if err != nil {
+		return 0, err
+	}
+	seq, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if len(userIDs) > 0 {
+		ph := strings.TrimRight(strings.Repeat("(?, ?),", len(userIDs)), ",")
+		args := make([]any, 0, 2*len(userIDs))
+		for _, uid := range userIDs {
+			args = append(args, uid, seq)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT IGNORE INTO ws_event_recipients (user_id, event_id) VALUES `+ph,
+			args...); err != nil {
+			return 0, err
+		}
+	}
+	return seq, tx.Commit()
+}
+
+// Since: event của user có seq > afterSeq, cũ trước, tối đa limit
+func (r *Repository) Since(ctx context.Context, userID, afterSeq int64, limit int) ([]Event, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT e.id, e.type, COALESCE(e.room_id, 0), e.payload, e.created_at
+		FROM ws_event_recipients er
+		JOIN ws_events e ON e.id = er.event_id
+		WHERE er.user_id = ? AND er.event_id > ?
+		ORDER BY er.event_id
+		LIMIT ?
+	`, userID, afterSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Event
+	for rows.Next() {
+		var ev Event
+		var payload []byte
+		if err := rows.Scan(&ev.Seq, &ev.Type, &ev.RoomID, &payload, &ev.CreatedAt); err != nil {
+			return nil, err
+		}
+		if len(payload) > 0 {
+			ev.Payload = payload
+		}
+		out = append(out, ev)
+	}
+	return out, rows.Err()
+}
+
+// Latest: seq mới nhất gửi cho user (0 = chưa có)
+func (r *Repository) Latest(ctx context.Context, userID int64) (int64, error) {
+	var seq int64
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(event_id), 0) FROM ws_event_recipients WHERE user_id = ?
+	`, userID).Scan(&seq)
+	return seq, err
+}
+
+// Oldest: seq nhỏ nhất còn trong journal (0 = rỗng)
+// client có last_seq < Oldest-1 có thể đã lỡ event bị prune
+func (r *Repository) Oldest(ctx context.Context) (int64, error) {
+	var seq int64
+	err := r.DB.QueryRowContext(ctx, `SELECT COALESCE(MIN(id), 0) FROM ws_events`).Scan(&seq)
+	return seq, err
+}
+
+// Prune: xoá event tạo trước before (recipients xoá theo FK)
+// luôn giữ event mới nhất để Oldest còn mốc so last_seq, journal không bao giờ rỗng lại
+func (r *Repository) Prune(ctx context.Context, before time.Time) (int64, error) {
+	var newest int64
+	if err := r.DB.QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0) FROM ws_events`).Scan(&newest); err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		res, err := r.DB.ExecContext(ctx, `
+			DELETE FROM ws_events WHERE created_at < ? AND id < ? ORDER BY id LIMIT ?
+		`, before, newest, pruneBatchSize)
+		if err != nil {
+			return total, err
+		}
+		n, _ := res.RowsAffected()
+		total += n
+		if n < pruneBatchSize {
+			return total, nil
+		}
+	}
+}
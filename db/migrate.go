@@ -0,0 +1,379 @@
+package db
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// LegacyVersion: các version khớp đúng database.sql (bản baseline) apply tay trước khi có migration runner
+// DB cũ (có bảng users nhưng chưa có schema_migrations) được đánh dấu tới version này, không chạy lại
+// -> DDL thêm sau baseline phải nằm ở version lớn hơn để DB cũ vẫn được chạy
+const LegacyVersion = 4
+
+const (
+	migrationsTable = "schema_migrations"
+	migrationLock   = "cronchat_schema_migrations"
+	lockTimeout     = 60 // giây, chờ instance khác migrate xong
+)
+
+// ErrIrreversible: migration không có phần Down
+var ErrIrreversible = errors.New("migrate: migration has no down section")
+
+// Migration: 1 file NNNN_name.sql trong db/migrations
+//
+// Format kiểu goose / sql-migrate:
+//
+//	-- +migrate Up
+//	CREATE TABLE ...;
+//	-- +migrate StatementBegin
+//	CREATE PROCEDURE ... BEGIN ...; END
+//	-- +migrate StatementEnd
+//	-- +migrate Down
+//	DROP TABLE ...;
+type Migration struct {
+	Version int
+	Name    string
+	Up      []string
+	Down    []string // rỗng = không rollback được
+}
+
+// MigrationStatus: 1 dòng của `server migrate status`
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time // nil = chưa chạy
+}
+
+// Migrations: danh sách migration nhúng trong binary, sắp theo version
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	var out []Migration
+	seen := map[int]string{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+		base := strings.TrimSuffix(e.Name(), ".sql")
+		num, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(num)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migrate: bad file name %q (want NNNN_name.sql)", e.Name())
+		}
+		if prev, dup := seen[version]; dup {
+			return nil, fmt.Errorf("migrate: version %d used by %s and %s", version, prev, e.Name())
+		}
+		seen[version] = e.Name()
+
+		raw, err := migrationFiles.ReadFile(path.Join("migrations", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		up, down, err := parseMigration(string(raw))
+		if err != nil {
+			return nil, fmt.Errorf("migrate: %s: %w", e.Name(), err)
+		}
+		out = append(out, Migration{Version: version, Name: name, Up: up, Down: down})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// parseMigration: tách phần Up / Down thành từng câu lệnh (kết thúc bằng ; cuối dòng)
+func parseMigration(src string) (up, down []string, err error) {
+	var (
+		section *[]string
+		buf     strings.Builder
+		inBlock bool
+	)
+	flush := func() {
+		stmt := strings.TrimSpace(buf.String())
+		stmt = strings.TrimSpace(strings.TrimSuffix(stmt, ";"))
+		if stmt != "" {
+			*section = append(*section, stmt)
+		}
+		buf.Reset()
+	}
+
+	sc := bufio.NewScanner(strings.NewReader(src))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		trimmed := strings.TrimSpace(line)
+
+		switch trimmed {
+		case "-- +migrate Up":
+			section = &up
+			continue
+		case "-- +migrate Down":
+			if buf.Len() > 0 || inBlock {
+				return nil, nil, fmt.Errorf("line %d: unterminated statement before Down", n)
+			}
+			section = &down
+			continue
+		case "-- +migrate StatementBegin":
+			if section == nil || buf.Len() > 0 {
+				return nil, nil, fmt.Errorf("line %d: StatementBegin in wrong place", n)
+			}
+			inBlock = true
+			continue
+		case "-- +migrate StatementEnd":
+			if !inBlock {
+				return nil, nil, fmt.Errorf("line %d: StatementEnd without StatementBegin", n)
+			}
+			inBlock = false
+			flush()
+			continue
+		}
+
+		if inBlock {
+			buf.WriteString(line)
+			buf.WriteByte('\n')
+			continue
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		if section == nil {
+			return nil, nil, fmt.Errorf("line %d: statement before -- +migrate Up", n)
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+		if strings.HasSuffix(trimmed, ";") {
+			flush()
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, nil, err
+	}
+	if inBlock || buf.Len() > 0 {
+		return nil, nil, errors.New("unterminated statement at end of file")
+	}
+	if len(up) == 0 {
+		return nil, nil, errors.New("no -- +migrate Up statements")
+	}
+	return up, down, nil
+}
+
+// Migrator: chạy migration trên 1 connection, lock bằng GET_LOCK nên nhiều instance cùng start vẫn an toàn
+// DDL của MySQL tự commit -> migration lỗi giữa chừng phải sửa tay rồi chạy lại (version chỉ ghi khi chạy hết)
+type Migrator struct {
+	DB         *sql.DB
+	Migrations []Migration
+	Logf       func(format string, args ...any)
+}
+
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	ms, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{DB: db, Migrations: ms, Logf: log.Printf}, nil
+}
+
+// Up: chạy mọi migration chưa apply, trả số migration đã chạy
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			legacy, err := tableExists(ctx, conn, "users")
+			if err != nil {
+				return err
+			}
+			if legacy {
+				m.Logf("📜 Schema có sẵn (database.sql), đánh dấu đã apply tới version %d", LegacyVersion)
+				if err := m.markUpTo(ctx, conn, LegacyVersion, applied); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, mig := range m.Migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			m.Logf("📜 Migrate up      : %04d_%s", mig.Version, mig.Name)
+			if err := execAll(ctx, conn, mig.Version, mig.Up); err != nil {
+				return err
+			}
+			if _, err := conn.ExecContext(ctx,
+				"INSERT INTO "+migrationsTable+" (version, name) VALUES (?, ?)", mig.Version, mig.Name); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Down: rollback steps migration mới nhất
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps <= 0 {
+		return nil
+	}
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.Migrations) - 1; i >= 0 && steps > 0; i-- {
+			mig := m.Migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if len(mig.Down) == 0 {
+				return fmt.Errorf("%w: %04d_%s", ErrIrreversible, mig.Version, mig.Name)
+			}
+			m.Logf("📜 Migrate down    : %04d_%s", mig.Version, mig.Name)
+			if err := execAll(ctx, conn, mig.Version, mig.Down); err != nil {
+				return err
+			}
+			if _, err := conn.ExecContext(ctx,
+				"DELETE FROM "+migrationsTable+" WHERE version = ?", mig.Version); err != nil {
+				return err
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// Baseline: đánh dấu đã apply tới version mà không chạy SQL (DB dựng tay / restore từ dump)
+func (m *Migrator) Baseline(ctx context.Context, version int) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		return m.markUpTo(ctx, conn, version, applied)
+	})
+}
+
+// Status: mọi migration + thời điểm apply
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]MigrationStatus, 0, len(m.Migrations))
+	for _, mig := range m.Migrations {
+		st := MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if t, ok := applied[mig.Version]; ok {
+			st.AppliedAt = &t
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+func (m *Migrator) markUpTo(ctx context.Context, conn *sql.Conn, version int, applied map[int]time.Time) error {
+	for _, mig := range m.Migrations {
+		if mig.Version > version {
+			break
+		}
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		if _, err := conn.ExecContext(ctx,
+			"INSERT INTO "+migrationsTable+" (version, name) VALUES (?, ?)", mig.Version, mig.Name); err != nil {
+			return err
+		}
+		applied[mig.Version] = time.Now()
+	}
+	return nil
+}
+
+// applied: version -> applied_at (tạo bảng nếu chưa có)
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS `+migrationsTable+` (
+			version INT UNSIGNED NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (version)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`); err != nil {
+		return nil, err
+	}
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM "+migrationsTable)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[int]time.Time{}
+	for rows.Next() {
+		var v int
+		var at time.Time
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		out[v] = at
+	}
+	return out, rows.Err()
+}
+
+// withLock: GET_LOCK gắn với session -> mọi lệnh chạy trên cùng conn
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var got sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", migrationLock, lockTimeout).Scan(&got); err != nil {
+		return err
+	}
+	if !got.Valid || got.Int64 != 1 {
+		return errors.New("migrate: another instance is holding the migration lock")
+	}
+	defer conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", migrationLock)
+
+	return fn(conn)
+}
+
+func execAll(ctx context.Context, conn *sql.Conn, version int, stmts []string) error {
+	for i, stmt := range stmts {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("migrate: version %d statement %d: %w", version, i+1, err)
+		}
+	}
+	return nil
+}
+
+func tableExists(ctx context.Context, conn *sql.Conn, name string) (bool, error) {
+	var n int
+	err := conn.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM information_schema.tables
+		WHERE table_schema = DATABASE() AND table_name = ?
+	`, name).Scan(&n)
+	return n > 0, err
+}
@@ -0,0 +1,82 @@
+-- +migrate Up
+SET NAMES utf8mb4;
+
+-- =========================================
+-- USERS
+-- =========================================
+CREATE TABLE `users` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+
+  `username` varchar(191) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `password` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `role` enum('user','admin','superadmin') COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'user',
+
+  `full_name` varchar(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `email` varchar(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `phone` varchar(50) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `avatar_url` text COLLATE utf8mb4_unicode_ci,
+
+  `is_active` tinyint(1) NOT NULL DEFAULT 1,
+  `last_login` datetime DEFAULT NULL,
+  `login_ip` varchar(50) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `created_ip` varchar(50) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_users_username` (`username`),
+  KEY `idx_users_full_name` (`full_name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- =========================================
+-- ROOMS
+-- =========================================
+CREATE TABLE `rooms` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+
+  `name` varchar(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `type` enum('direct','group') COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'direct',
+  `created_by` int unsigned NOT NULL,
+
+  `is_active` tinyint(1) NOT NULL DEFAULT 1,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  KEY `idx_rooms_type` (`type`),
+  KEY `idx_rooms_created_by` (`created_by`),
+  CONSTRAINT `fk_rooms_created_by`
+    FOREIGN KEY (`created_by`) REFERENCES `users` (`id`)
+    ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- =========================================
+-- ROOM_MEMBERS
+-- =========================================
+CREATE TABLE `room_members` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+
+  `room_id` int unsigned NOT NULL,
+  `user_id` int unsigned NOT NULL,
+  `member_role` enum('member','admin','owner') COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'member',
+
+  `joined_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `last_seen_at` datetime DEFAULT NULL,
+
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_room_members_room_user` (`room_id`,`user_id`),
+  KEY `idx_room_members_user_id` (`user_id`),
+
+  CONSTRAINT `fk_room_members_room`
+    FOREIGN KEY (`room_id`) REFERENCES `rooms` (`id`)
+    ON DELETE CASCADE,
+  CONSTRAINT `fk_room_members_user`
+    FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)
+    ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +migrate Down
+DROP TABLE IF EXISTS `room_members`;
+DROP TABLE IF EXISTS `rooms`;
+DROP TABLE IF EXISTS `users`;
@@ -0,0 +1,49 @@
+-- +migrate Up
+SET NAMES utf8mb4;
+
+-- =========================================
+-- MESSAGES
+-- =========================================
+CREATE TABLE `messages` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+
+  `room_id` int unsigned NOT NULL,
+  `sender_id` int unsigned NOT NULL,
+
+  `content` text COLLATE utf8mb4_unicode_ci,
+  `message_type` enum('text','image','file','system') COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'text',
+  `is_temp` tinyint(1) NOT NULL DEFAULT 0,
+
+  `media_url` text COLLATE utf8mb4_unicode_ci,
+  `media_mime` varchar(100) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `media_size` bigint DEFAULT NULL,
+
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime DEFAULT NULL ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  KEY `idx_messages_room_id_created_at` (`room_id`,`created_at`),
+  KEY `idx_messages_sender_id_created_at` (`sender_id`,`created_at`),
+
+  CONSTRAINT `fk_messages_room`
+    FOREIGN KEY (`room_id`) REFERENCES `rooms` (`id`)
+    ON DELETE CASCADE,
+  CONSTRAINT `fk_messages_sender`
+    FOREIGN KEY (`sender_id`) REFERENCES `users` (`id`)
+    ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+ALTER TABLE `messages`
+  ADD COLUMN `reply_to_message_id` INT UNSIGNED NULL AFTER `sender_id`,
+  ADD KEY `idx_messages_reply_to` (`reply_to_message_id`),
+  ADD CONSTRAINT `fk_messages_reply_to`
+    FOREIGN KEY (`reply_to_message_id`) REFERENCES `messages` (`id`)
+    ON DELETE SET NULL;
+
+ALTER TABLE `messages`
+  ADD COLUMN `reply_preview` VARCHAR(300) NULL AFTER `reply_to_message_id`,
+  ADD COLUMN `reply_sender_name` VARCHAR(255) NULL AFTER `reply_preview`,
+  ADD COLUMN `reply_message_type` ENUM('text','image','file','system') NULL AFTER `reply_sender_name`;
+
+-- +migrate Down
+DROP TABLE IF EXISTS `messages`;
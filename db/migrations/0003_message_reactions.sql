@@ -0,0 +1,25 @@
+-- +migrate Up
+SET NAMES utf8mb4;
+
+CREATE TABLE `message_reactions` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `message_id` INT UNSIGNED NOT NULL,
+  `user_id` INT UNSIGNED NOT NULL,
+  `reaction` VARCHAR(32) NOT NULL, -- 'like', '❤️', '😂', ...
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_reaction_message_user_reaction` (`message_id`,`user_id`,`reaction`),
+  KEY `idx_reaction_message_id` (`message_id`),
+  KEY `idx_reaction_user_id` (`user_id`),
+
+  CONSTRAINT `fk_reactions_message`
+    FOREIGN KEY (`message_id`) REFERENCES `messages` (`id`)
+    ON DELETE CASCADE,
+  CONSTRAINT `fk_reactions_user`
+    FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)
+    ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +migrate Down
+DROP TABLE IF EXISTS `message_reactions`;
@@ -0,0 +1,26 @@
+-- +migrate Up
+SET NAMES utf8mb4;
+
+CREATE TABLE `message_receipts` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `room_id` INT UNSIGNED NOT NULL,
+  `message_id` INT UNSIGNED NOT NULL,
+  `user_id` INT UNSIGNED NOT NULL,
+  `status` ENUM('delivered','seen') NOT NULL DEFAULT 'seen',
+  `seen_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_receipt_message_user` (`message_id`,`user_id`),
+  KEY `idx_receipt_room_user` (`room_id`,`user_id`,`message_id`),
+  KEY `idx_receipt_message` (`message_id`),
+
+  CONSTRAINT `fk_receipt_room`
+    FOREIGN KEY (`room_id`) REFERENCES `rooms` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_receipt_message`
+    FOREIGN KEY (`message_id`) REFERENCES `messages` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_receipt_user`
+    FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +migrate Down
+DROP TABLE IF EXISTS `message_receipts`;
@@ -0,0 +1,904 @@
+-- +migrate Up
+SET NAMES utf8mb4;
+
+-- attachments (CreateMessageWithAttachments)
+CREATE TABLE IF NOT EXISTS `attachments` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `message_id` INT UNSIGNED NOT NULL,
+  `file_name` VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `file_size` BIGINT NOT NULL DEFAULT 0,
+  `content_type` VARCHAR(100) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `file_path` TEXT COLLATE utf8mb4_unicode_ci NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  KEY `idx_attachments_message` (`message_id`),
+
+  CONSTRAINT `fk_attachments_message`
+    FOREIGN KEY (`message_id`) REFERENCES `messages` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- video transcode: poster frame cho message video
+ALTER TABLE `messages`
+  ADD COLUMN `media_poster_url` TEXT COLLATE utf8mb4_unicode_ci NULL AFTER `media_size`;
+
+-- voice message: waveform (peaks 0..100, JSON array) tính lúc gửi
+ALTER TABLE `attachments`
+  ADD COLUMN `waveform` JSON NULL AFTER `file_path`,
+  ADD COLUMN `duration_ms` INT UNSIGNED NULL AFTER `waveform`;
+
+-- media metadata (ảnh: width/height, video: width/height/duration, audio: duration)
+ALTER TABLE `attachments`
+  ADD COLUMN `width` INT UNSIGNED NULL AFTER `waveform`,
+  ADD COLUMN `height` INT UNSIGNED NULL AFTER `width`;
+
+-- chat uploads: giữ tên file gốc (file trên đĩa đặt tên r{room}_u{user}_{ts})
+CREATE TABLE IF NOT EXISTS `chat_uploads` (
+  `file_name` VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `room_id` INT UNSIGNED NOT NULL,
+  `uploader_id` INT UNSIGNED NOT NULL,
+  `original_name` VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `content_type` VARCHAR(100) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `file_size` BIGINT NOT NULL DEFAULT 0,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`file_name`),
+  KEY `idx_chat_uploads_room` (`room_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- migrate-storage: trạng thái copy file local -> object store (resume được)
+CREATE TABLE IF NOT EXISTS `storage_migrations` (
+  `kind` VARCHAR(20) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `file_name` VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `object_key` VARCHAR(512) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `public_url` TEXT COLLATE utf8mb4_unicode_ci NOT NULL,
+  `md5_hex` CHAR(32) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `file_size` BIGINT NOT NULL DEFAULT 0,
+  `status` ENUM('uploaded','done') COLLATE utf8mb4_unicode_ci NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`kind`, `file_name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- push notification: device token FCM (android/web) / APNs (iOS)
+CREATE TABLE IF NOT EXISTS `device_tokens` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `user_id` INT UNSIGNED NOT NULL,
+  `platform` ENUM('fcm','apns') COLLATE utf8mb4_unicode_ci NOT NULL,
+  `token` VARCHAR(512) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_device_tokens_token` (`token`),
+  KEY `idx_device_tokens_user` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- web push (trình duyệt): PushSubscription theo user, endpoint ascii để unique index vừa giới hạn
+CREATE TABLE IF NOT EXISTS `webpush_subscriptions` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `user_id` INT UNSIGNED NOT NULL,
+  `endpoint` VARCHAR(1024) CHARACTER SET ascii COLLATE ascii_bin NOT NULL,
+  `p256dh` VARCHAR(128) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `auth` VARCHAR(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `user_agent` VARCHAR(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_webpush_endpoint` (`endpoint`),
+  KEY `idx_webpush_user` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- web push: cặp key VAPID tự sinh (1 row, id = 1), không dùng khi set VAPID_* trong env
+CREATE TABLE IF NOT EXISTS `webpush_vapid` (
+  `id` TINYINT UNSIGNED NOT NULL,
+  `public_key` VARCHAR(128) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `private_key` VARCHAR(128) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- email digest: cấu hình thông báo theo user (chưa có row = mặc định)
+CREATE TABLE IF NOT EXISTS `user_notification_settings` (
+  `user_id` INT UNSIGNED NOT NULL,
+  `email_digest` ENUM('off','mentions','all') COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'all',
+  `digest_after_minutes` INT NOT NULL DEFAULT 30,
+  `last_digest_at` DATETIME DEFAULT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- mute room: digest / push bỏ qua room đang mute
+ALTER TABLE `room_members`
+  ADD COLUMN `muted_until` DATETIME DEFAULT NULL;
+
+-- outgoing webhook theo room (owner quản lý)
+CREATE TABLE IF NOT EXISTS `room_webhooks` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `room_id` INT UNSIGNED NOT NULL,
+  `url` VARCHAR(2048) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `secret` VARCHAR(128) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `events` VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'message_created,member_added,member_removed',
+  `is_active` TINYINT(1) NOT NULL DEFAULT 1,
+  `created_by` INT UNSIGNED NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  KEY `idx_room_webhooks_room` (`room_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- hàng đợi + log giao webhook (retry theo next_attempt_at)
+CREATE TABLE IF NOT EXISTS `webhook_deliveries` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `webhook_id` BIGINT UNSIGNED NOT NULL,
+  `event` VARCHAR(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `payload` JSON NOT NULL,
+  `status` ENUM('pending','success','failed') COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'pending',
+  `attempts` INT NOT NULL DEFAULT 0,
+  `last_status_code` INT DEFAULT NULL,
+  `last_error` VARCHAR(500) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `next_attempt_at` DATETIME DEFAULT NULL,
+  `delivered_at` DATETIME DEFAULT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  KEY `idx_webhook_deliveries_due` (`status`, `next_attempt_at`),
+  KEY `idx_webhook_deliveries_webhook` (`webhook_id`, `id`),
+  CONSTRAINT `fk_webhook_deliveries_webhook` FOREIGN KEY (`webhook_id`) REFERENCES `room_webhooks` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- incoming webhook: hệ thống ngoài post tin vào room bằng token (chỉ lưu hash)
+CREATE TABLE IF NOT EXISTS `incoming_webhooks` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `room_id` INT UNSIGNED NOT NULL,
+  `name` VARCHAR(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `avatar_url` VARCHAR(2048) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `token_hash` CHAR(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `created_by` INT UNSIGNED NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_incoming_webhooks_token` (`token_hash`),
+  KEY `idx_incoming_webhooks_room` (`room_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- tin gửi qua incoming webhook (NULL = user gửi)
+ALTER TABLE `messages`
+  ADD COLUMN `webhook_id` BIGINT UNSIGNED DEFAULT NULL;
+
+-- bot: user điều khiển bằng API key, nhận event qua webhook_url hoặc WS
+CREATE TABLE IF NOT EXISTS `bots` (
+  `user_id` INT UNSIGNED NOT NULL,
+  `owner_id` INT UNSIGNED NOT NULL,
+  `api_key_hash` CHAR(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `webhook_url` VARCHAR(2048) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `webhook_secret` VARCHAR(128) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `events` VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'message_created,member_added,member_removed,button_clicked',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`user_id`),
+  UNIQUE KEY `uq_bots_api_key` (`api_key_hash`),
+  KEY `idx_bots_owner` (`owner_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- delivery cho bot: webhook_id NULL, bot_user_id = bot nhận
+ALTER TABLE `webhook_deliveries`
+  MODIFY COLUMN `webhook_id` BIGINT UNSIGNED DEFAULT NULL,
+  ADD COLUMN `bot_user_id` INT UNSIGNED DEFAULT NULL AFTER `webhook_id`,
+  ADD KEY `idx_webhook_deliveries_bot` (`bot_user_id`, `id`);
+
+-- nút tương tác trên tin của bot
+ALTER TABLE `messages`
+  ADD COLUMN `buttons` JSON DEFAULT NULL;
+
+-- khối nội dung có màu (Slack attachments từ incoming webhook)
+ALTER TABLE `messages`
+  ADD COLUMN `embeds` JSON DEFAULT NULL;
+
+-- user được @ trong tin (đếm badge mention riêng với unread)
+CREATE TABLE IF NOT EXISTS `message_mentions` (
+  `message_id` BIGINT UNSIGNED NOT NULL,
+  `room_id` INT UNSIGNED NOT NULL,
+  `user_id` INT UNSIGNED NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`message_id`, `user_id`),
+  KEY `idx_message_mentions_user_room` (`user_id`, `room_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- mức thông báo theo room: mentions = badge/unread update chỉ theo tin nhắc đến mình
+ALTER TABLE `room_members`
+  ADD COLUMN `notify_level` ENUM('all','mentions') COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'all';
+
+-- /remind: nhắc 1 lần (fire_at) hoặc lặp theo cron (cron_spec)
+CREATE TABLE IF NOT EXISTS `reminders` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `creator_id` INT UNSIGNED NOT NULL,
+  `target` ENUM('me','room') COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'me',
+  `room_id` INT UNSIGNED NOT NULL DEFAULT 0,
+  `text` VARCHAR(1000) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `fire_at` DATETIME NOT NULL,
+  `cron_spec` VARCHAR(100) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `status` ENUM('active','done','cancelled') COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'active',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  KEY `idx_reminders_due` (`status`, `fire_at`),
+  KEY `idx_reminders_creator` (`creator_id`, `status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- cron job hệ thống (locked_by/locked_until: lock phân tán giữa các instance)
+CREATE TABLE IF NOT EXISTS `jobs` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `name` VARCHAR(100) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `cron_spec` VARCHAR(100) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `action_type` VARCHAR(50) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `payload` JSON DEFAULT NULL,
+  `enabled` TINYINT(1) NOT NULL DEFAULT 1,
+  `next_run_at` DATETIME DEFAULT NULL,
+  `last_run_at` DATETIME DEFAULT NULL,
+  `last_status` ENUM('success','failed') COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `locked_by` VARCHAR(100) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `locked_until` DATETIME DEFAULT NULL,
+  `created_by` INT UNSIGNED NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  KEY `idx_jobs_due` (`enabled`, `next_run_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `job_runs` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `job_id` INT UNSIGNED NOT NULL,
+  `trigger_type` ENUM('schedule','manual') COLLATE utf8mb4_unicode_ci NOT NULL,
+  `status` ENUM('running','success','failed') COLLATE utf8mb4_unicode_ci NOT NULL,
+  `instance` VARCHAR(100) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `output` TEXT COLLATE utf8mb4_unicode_ci,
+  `error` TEXT COLLATE utf8mb4_unicode_ci,
+  `started_at` DATETIME NOT NULL,
+  `finished_at` DATETIME DEFAULT NULL,
+  `duration_ms` BIGINT NOT NULL DEFAULT 0,
+
+  PRIMARY KEY (`id`),
+  KEY `idx_job_runs_job` (`job_id`, `id`),
+  KEY `idx_job_runs_status` (`status`, `started_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- lịch sự kiện của room (recurrence: RRULE, next_remind_at: lần nhắc kế tiếp)
+CREATE TABLE IF NOT EXISTS `room_events` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `room_id` INT UNSIGNED NOT NULL,
+  `creator_id` INT UNSIGNED NOT NULL,
+  `title` VARCHAR(200) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `description` TEXT COLLATE utf8mb4_unicode_ci,
+  `location` VARCHAR(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `start_at` DATETIME NOT NULL,
+  `end_at` DATETIME NOT NULL,
+  `recurrence` VARCHAR(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `remind_minutes` INT NOT NULL DEFAULT 15,
+  `next_remind_at` DATETIME DEFAULT NULL,
+  `remind_occurrence_at` DATETIME DEFAULT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  KEY `idx_room_events_room` (`room_id`, `start_at`),
+  KEY `idx_room_events_remind` (`next_remind_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `room_event_rsvps` (
+  `event_id` BIGINT UNSIGNED NOT NULL,
+  `user_id` INT UNSIGNED NOT NULL,
+  `status` ENUM('yes','no','maybe') COLLATE utf8mb4_unicode_ci NOT NULL,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`event_id`, `user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- analytics: user hoạt động theo ngày (login / refresh / mở WS / gửi tin) -> DAU/WAU/MAU
+CREATE TABLE IF NOT EXISTS `user_daily_activity` (
+  `day` DATE NOT NULL,
+  `user_id` INT UNSIGNED NOT NULL,
+
+  PRIMARY KEY (`day`, `user_id`),
+  KEY `idx_user_daily_activity_user` (`user_id`, `day`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- thống kê theo ngày trên users / chat_uploads
+ALTER TABLE `users` ADD KEY `idx_users_created_at` (`created_at`);
+ALTER TABLE `chat_uploads` ADD KEY `idx_chat_uploads_created_at` (`created_at`);
+
+-- moderation: report tin / user (reporter_id = 0: hệ thống tự gắn cờ)
+CREATE TABLE IF NOT EXISTS `moderation_reports` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `reporter_id` INT UNSIGNED NOT NULL DEFAULT 0,
+  `target_type` ENUM('message','user') COLLATE utf8mb4_unicode_ci NOT NULL,
+  `message_id` INT UNSIGNED DEFAULT NULL,
+  `target_user_id` INT UNSIGNED NOT NULL,
+  `room_id` INT UNSIGNED DEFAULT NULL,
+  `reason` VARCHAR(30) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `details` VARCHAR(1000) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `content_preview` TEXT COLLATE utf8mb4_unicode_ci,
+  `status` ENUM('open','resolved','dismissed') COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'open',
+  `resolution` VARCHAR(30) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `resolved_by` INT UNSIGNED DEFAULT NULL,
+  `resolved_at` DATETIME DEFAULT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  KEY `idx_moderation_reports_status` (`status`, `id`),
+  KEY `idx_moderation_reports_target` (`target_type`, `message_id`, `target_user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `moderation_audit_log` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `report_id` BIGINT UNSIGNED DEFAULT NULL,
+  `admin_id` INT UNSIGNED NOT NULL,
+  `action` VARCHAR(30) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `target_user_id` INT UNSIGNED DEFAULT NULL,
+  `message_id` INT UNSIGNED DEFAULT NULL,
+  `note` VARCHAR(1000) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  KEY `idx_moderation_audit_target` (`target_user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- tin bị admin gỡ (nội dung thay bằng placeholder)
+ALTER TABLE `messages`
+  ADD COLUMN `removed_at` DATETIME DEFAULT NULL;
+
+-- khoá tài khoản tạm thời (login / refresh / gửi tin bị chặn tới thời điểm này)
+ALTER TABLE `users`
+  ADD COLUMN `suspended_until` DATETIME DEFAULT NULL;
+
+-- word filter: room_id = 0 là list chung, room khác ghi đè (mode allow = bỏ từ của list chung)
+CREATE TABLE IF NOT EXISTS `word_filters` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `room_id` BIGINT NOT NULL DEFAULT 0,
+  `pattern` VARCHAR(100) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `mode` ENUM('reject','mask','flag','allow') COLLATE utf8mb4_unicode_ci NOT NULL,
+  `created_by` BIGINT NOT NULL DEFAULT 0,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_word_filters_room_pattern` (`room_id`, `pattern`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- thông báo hệ thống (banner) + người nhận (audience = users) + xác nhận đã đọc
+CREATE TABLE IF NOT EXISTS `announcements` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `title` VARCHAR(200) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `body` TEXT COLLATE utf8mb4_unicode_ci NOT NULL,
+  `level` ENUM('info','warning','critical') COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'info',
+  `audience` ENUM('all','users') COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'all',
+  `require_ack` TINYINT(1) NOT NULL DEFAULT 0,
+  `created_by` BIGINT NOT NULL,
+  `expires_at` DATETIME DEFAULT NULL,
+  `revoked_at` DATETIME DEFAULT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_announcements_active` (`revoked_at`, `expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `announcement_targets` (
+  `announcement_id` BIGINT NOT NULL,
+  `user_id` BIGINT NOT NULL,
+  PRIMARY KEY (`announcement_id`, `user_id`),
+  KEY `idx_announcement_targets_user` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `announcement_acks` (
+  `announcement_id` BIGINT NOT NULL,
+  `user_id` BIGINT NOT NULL,
+  `acked_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`announcement_id`, `user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- audit log toàn hệ thống (khoá, đổi role, thu hồi token, đổi cấu hình, xoá room...) - chỉ INSERT
+CREATE TABLE IF NOT EXISTS `audit_log` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `actor_id` BIGINT NOT NULL DEFAULT 0,
+  `action` VARCHAR(50) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `target_type` VARCHAR(20) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `target_id` BIGINT NOT NULL DEFAULT 0,
+  `details` JSON DEFAULT NULL,
+  `ip` VARCHAR(50) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_audit_log_actor` (`actor_id`, `id`),
+  KEY `idx_audit_log_target` (`target_type`, `target_id`, `id`),
+  KEY `idx_audit_log_created` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TRIGGER `trg_audit_log_no_update` BEFORE UPDATE ON `audit_log` FOR EACH ROW
+  SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';
+
+CREATE TRIGGER `trg_audit_log_no_delete` BEFORE DELETE ON `audit_log` FOR EACH ROW
+  SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';
+
+-- lịch sử đăng nhập (cả lần sai mật khẩu), xoá theo retention login_audit
+CREATE TABLE IF NOT EXISTS `login_history` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `user_id` BIGINT NOT NULL,
+  `ip` VARCHAR(50) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `user_agent` VARCHAR(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `success` TINYINT(1) NOT NULL DEFAULT 1,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_login_history_user` (`user_id`, `id`),
+  KEY `idx_login_history_created` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- retention: số ngày giữ mỗi loại dữ liệu (0 = vĩnh viễn), purge qua job retention_purge
+CREATE TABLE IF NOT EXISTS `retention_settings` (
+  `category` VARCHAR(30) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `days` INT NOT NULL DEFAULT 0,
+  `updated_by` BIGINT NOT NULL DEFAULT 0,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`category`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE `message_receipts`
+  ADD KEY `idx_receipt_seen_at` (`seen_at`);
+
+-- export dữ liệu cá nhân (GDPR): hàng đợi + trạng thái, file zip hết hạn sau EXPORT_TTL
+CREATE TABLE IF NOT EXISTS `data_exports` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `user_id` BIGINT NOT NULL,
+  `requested_by` BIGINT NOT NULL,
+  `status` ENUM('queued','running','done','failed','expired') COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'queued',
+  `progress` TINYINT UNSIGNED NOT NULL DEFAULT 0,
+  `claim_token` CHAR(32) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `heartbeat_at` DATETIME DEFAULT NULL,
+  `file_name` VARCHAR(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `file_size` BIGINT NOT NULL DEFAULT 0,
+  `error` VARCHAR(500) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `expires_at` DATETIME DEFAULT NULL,
+  `started_at` DATETIME DEFAULT NULL,
+  `finished_at` DATETIME DEFAULT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_data_exports_user` (`user_id`, `id`),
+  KEY `idx_data_exports_status` (`status`, `id`),
+  KEY `idx_data_exports_claim` (`claim_token`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- giới hạn riêng theo user (NULL = dùng mặc định QUOTA_*, 0 = không giới hạn)
+CREATE TABLE IF NOT EXISTS `user_quota_overrides` (
+  `user_id` int unsigned NOT NULL,
+  `messages_per_minute` int unsigned DEFAULT NULL,
+  `messages_per_day` int unsigned DEFAULT NULL,
+  `rooms_per_day` int unsigned DEFAULT NULL,
+  `updated_by` int unsigned NOT NULL DEFAULT 0,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`user_id`),
+  CONSTRAINT `fk_user_quota_overrides_user`
+    FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- đếm room tạo trong ngày (quota rooms_per_day)
+ALTER TABLE `rooms` ADD KEY `idx_rooms_created_by_created_at` (`created_by`, `created_at`);
+
+-- allow / deny theo IP hoặc dải CIDR (scope auth = chỉ login / đăng ký, all = mọi request)
+CREATE TABLE IF NOT EXISTS `ip_rules` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `cidr` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `action` enum('allow','deny') COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'deny',
+  `scope` enum('auth','all') COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'auth',
+  `note` varchar(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `expires_at` datetime DEFAULT NULL,
+  `created_by` int unsigned NOT NULL DEFAULT 0,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- mã OTP gửi qua SMS (chỉ lưu HMAC của mã)
+CREATE TABLE IF NOT EXISTS `phone_otps` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int unsigned NOT NULL,
+  `purpose` enum('login','verify_phone') COLLATE utf8mb4_unicode_ci NOT NULL,
+  `phone` varchar(50) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `code_hash` char(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `attempts` tinyint unsigned NOT NULL DEFAULT 0,
+  `expires_at` datetime NOT NULL,
+  `consumed_at` datetime DEFAULT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_phone_otps_user` (`user_id`, `purpose`, `created_at`),
+  CONSTRAINT `fk_phone_otps_user`
+    FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- số điện thoại đã xác minh + 2FA SMS (verified_phone khác users.phone = số mới chưa xác minh)
+CREATE TABLE IF NOT EXISTS `user_phone_security` (
+  `user_id` int unsigned NOT NULL,
+  `verified_phone` varchar(50) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `verified_at` datetime DEFAULT NULL,
+  `sms_2fa` tinyint(1) NOT NULL DEFAULT 0,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`user_id`),
+  CONSTRAINT `fk_user_phone_security_user`
+    FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- automation: rule theo room (tin khớp pattern -> react / webhook / reply / flag)
+CREATE TABLE IF NOT EXISTS `automation_rules` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `room_id` INT UNSIGNED NOT NULL,
+  `name` VARCHAR(64) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+  `match_type` ENUM('contains','word','regex') COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'contains',
+  `pattern` VARCHAR(200) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `action` ENUM('react','webhook','reply','flag') COLLATE utf8mb4_unicode_ci NOT NULL,
+  `reaction` VARCHAR(32) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `reply_text` VARCHAR(1000) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `cooldown_seconds` INT UNSIGNED NOT NULL DEFAULT 0,
+  `enabled` TINYINT(1) NOT NULL DEFAULT 1,
+  `created_by` INT UNSIGNED NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  KEY `idx_automation_rules_room` (`room_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- system webhook: event vòng đời user (user_created / user_updated / user_suspended / user_deactivated)
+CREATE TABLE IF NOT EXISTS `system_webhooks` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `url` VARCHAR(2048) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `secret` VARCHAR(128) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `events` VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'user_created,user_updated,user_suspended,user_deactivated',
+  `is_active` TINYINT(1) NOT NULL DEFAULT 1,
+  `created_by` INT UNSIGNED NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- delivery cho system webhook: webhook_id / bot_user_id NULL
+ALTER TABLE `webhook_deliveries`
+  ADD COLUMN `system_webhook_id` BIGINT UNSIGNED DEFAULT NULL AFTER `bot_user_id`,
+  ADD KEY `idx_webhook_deliveries_system` (`system_webhook_id`, `id`),
+  ADD CONSTRAINT `fk_webhook_deliveries_system` FOREIGN KEY (`system_webhook_id`) REFERENCES `system_webhooks` (`id`) ON DELETE CASCADE;
+
+-- provisioning: external id (HR / IdP) -> user, idempotent khi đồng bộ lại
+CREATE TABLE IF NOT EXISTS `user_external_ids` (
+  `external_id` VARCHAR(191) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `user_id` INT UNSIGNED NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`external_id`),
+  UNIQUE KEY `uq_user_external_ids_user` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- tóm tắt room bằng LLM: cache theo (room, tin cuối, số tin) + đếm lượt gọi / user
+CREATE TABLE IF NOT EXISTS `room_summaries` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `room_id` INT UNSIGNED NOT NULL,
+  `requested_by` INT UNSIGNED NOT NULL,
+  `summary` TEXT COLLATE utf8mb4_unicode_ci NOT NULL,
+  `action_items` JSON NOT NULL,
+  `message_count` INT NOT NULL,
+  `from_message_id` BIGINT UNSIGNED NOT NULL,
+  `last_message_id` BIGINT UNSIGNED NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  KEY `idx_room_summaries_cache` (`room_id`, `last_message_id`, `message_count`),
+  KEY `idx_room_summaries_user` (`requested_by`, `created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- digest hằng ngày theo room (job action room_digest), chưa có dòng = tắt
+CREATE TABLE IF NOT EXISTS `room_digest_settings` (
+  `room_id` INT UNSIGNED NOT NULL,
+  `enabled` TINYINT(1) NOT NULL DEFAULT 0,
+  `post_message` TINYINT(1) NOT NULL DEFAULT 1,
+  `timezone` VARCHAR(64) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `last_sent_at` DATETIME DEFAULT NULL,
+  `updated_by` INT UNSIGNED NOT NULL DEFAULT 0,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`room_id`),
+  KEY `idx_room_digest_enabled` (`enabled`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- user đăng ký nhận digest của room qua email
+CREATE TABLE IF NOT EXISTS `room_digest_subscriptions` (
+  `room_id` INT UNSIGNED NOT NULL,
+  `user_id` INT UNSIGNED NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`room_id`, `user_id`),
+  KEY `idx_room_digest_sub_user` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- room insights (opt-in bởi owner): chỉ số đếm theo giờ, không nội dung / user id
+CREATE TABLE IF NOT EXISTS `room_insights_settings` (
+  `room_id` INT UNSIGNED NOT NULL,
+  `enabled` TINYINT(1) NOT NULL DEFAULT 0,
+  `enabled_by` INT UNSIGNED NOT NULL DEFAULT 0,
+  `enabled_at` DATETIME DEFAULT NULL,
+  `rolled_up_until` DATETIME DEFAULT NULL,
+
+  PRIMARY KEY (`room_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `room_activity_hourly` (
+  `room_id` INT UNSIGNED NOT NULL,
+  `bucket_hour` DATETIME NOT NULL,
+  `messages` INT UNSIGNED NOT NULL DEFAULT 0,
+  `responses` INT UNSIGNED NOT NULL DEFAULT 0,
+  `response_ms_sum` BIGINT UNSIGNED NOT NULL DEFAULT 0,
+
+  PRIMARY KEY (`room_id`, `bucket_hour`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- survey / NPS: admin định nghĩa câu hỏi, bot gửi theo lịch (job survey_post)
+CREATE TABLE IF NOT EXISTS `surveys` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `title` VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `intro` TEXT COLLATE utf8mb4_unicode_ci,
+  `questions` JSON NOT NULL,
+  `room_ids` JSON NOT NULL,
+  `user_ids` JSON NOT NULL,
+  `cron_spec` VARCHAR(100) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `job_id` BIGINT UNSIGNED DEFAULT NULL,
+  `active` TINYINT(1) NOT NULL DEFAULT 1,
+  `created_by` INT UNSIGNED NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME DEFAULT NULL ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `survey_runs` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `survey_id` BIGINT UNSIGNED NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  KEY `idx_survey_runs_survey` (`survey_id`, `id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- room đã nhận tin survey của 1 run (member của room mới được trả lời)
+CREATE TABLE IF NOT EXISTS `survey_deliveries` (
+  `run_id` BIGINT UNSIGNED NOT NULL,
+  `room_id` INT UNSIGNED NOT NULL,
+  `message_id` INT UNSIGNED NOT NULL,
+
+  PRIMARY KEY (`run_id`, `room_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `survey_responses` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `run_id` BIGINT UNSIGNED NOT NULL,
+  `survey_id` BIGINT UNSIGNED NOT NULL,
+  `user_id` INT UNSIGNED NOT NULL,
+  `question_id` VARCHAR(32) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `value` INT DEFAULT NULL,
+  `answer_text` TEXT COLLATE utf8mb4_unicode_ci,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_survey_response` (`run_id`, `user_id`, `question_id`),
+  KEY `idx_survey_responses_survey` (`survey_id`, `question_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- tắt push theo user: room_id = 0 -> mọi room, muted_until NULL -> tới khi bật lại
+CREATE TABLE IF NOT EXISTS `notification_mutes` (
+  `user_id` INT UNSIGNED NOT NULL,
+  `room_id` INT UNSIGNED NOT NULL,
+  `muted_until` DATETIME DEFAULT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`user_id`, `room_id`),
+  KEY `idx_notification_mutes_room` (`room_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- phiên đăng nhập: 1 refresh token = 1 row (id = jti), /auth/sessions để xem / thu hồi
+CREATE TABLE IF NOT EXISTS `user_sessions` (
+  `id` CHAR(32) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `user_id` BIGINT NOT NULL,
+  `device_name` VARCHAR(100) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+  `ip` VARCHAR(50) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `user_agent` VARCHAR(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `last_active_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `expires_at` DATETIME NOT NULL,
+  `revoked_at` DATETIME DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_user_sessions_user` (`user_id`, `last_active_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- role trong group: owner > admin > moderator > member (ma trận quyền ở room/roles.go)
+ALTER TABLE `room_members`
+  MODIFY `member_role` enum('member','moderator','admin','owner') COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'member';
+
+-- tin ghim trong room (moderator trở lên)
+CREATE TABLE IF NOT EXISTS `room_pins` (
+  `room_id` BIGINT NOT NULL,
+  `message_id` BIGINT NOT NULL,
+  `pinned_by` BIGINT NOT NULL,
+  `pinned_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`room_id`, `message_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- group: mô tả + avatar (file lưu như chat upload, URL /static/chat_uploads/...)
+ALTER TABLE `rooms`
+  ADD COLUMN `description` VARCHAR(500) COLLATE utf8mb4_unicode_ci DEFAULT NULL AFTER `name`,
+  ADD COLUMN `avatar_url` VARCHAR(512) COLLATE utf8mb4_unicode_ci DEFAULT NULL AFTER `description`;
+
+-- lời mời vào group: 1 row / (room, user), mời lại thì reset về pending
+CREATE TABLE IF NOT EXISTS `room_invites` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `room_id` INT UNSIGNED NOT NULL,
+  `inviter_id` INT UNSIGNED NOT NULL,
+  `invitee_id` INT UNSIGNED NOT NULL,
+  `status` ENUM('pending','accepted','declined') COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'pending',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `responded_at` DATETIME DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_room_invites_room_user` (`room_id`, `invitee_id`),
+  KEY `idx_room_invites_invitee_status` (`invitee_id`, `status`),
+  CONSTRAINT `fk_room_invites_room` FOREIGN KEY (`room_id`) REFERENCES `rooms` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- link / mã tự join group (owner tạo), max_uses = 0 -> không giới hạn
+CREATE TABLE IF NOT EXISTS `room_invite_links` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `room_id` INT UNSIGNED NOT NULL,
+  `code` VARCHAR(32) COLLATE utf8mb4_bin NOT NULL,
+  `created_by` INT UNSIGNED NOT NULL,
+  `expires_at` DATETIME DEFAULT NULL,
+  `max_uses` INT UNSIGNED NOT NULL DEFAULT 0,
+  `uses` INT UNSIGNED NOT NULL DEFAULT 0,
+  `revoked_at` DATETIME DEFAULT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_room_invite_links_code` (`code`),
+  KEY `idx_room_invite_links_room` (`room_id`),
+  CONSTRAINT `fk_room_invite_links_room` FOREIGN KEY (`room_id`) REFERENCES `rooms` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- lời mời kết bạn: 1 row / (người gửi, người nhận), gửi lại sau khi bị từ chối thì reset
+CREATE TABLE IF NOT EXISTS `contact_requests` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `from_user_id` INT UNSIGNED NOT NULL,
+  `to_user_id` INT UNSIGNED NOT NULL,
+  `status` ENUM('pending','accepted','declined') COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'pending',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `responded_at` DATETIME DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_contact_requests_pair` (`from_user_id`, `to_user_id`),
+  KEY `idx_contact_requests_to_status` (`to_user_id`, `status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- danh bạ: lưu 2 chiều (A->B và B->A)
+CREATE TABLE IF NOT EXISTS `user_contacts` (
+  `user_id` INT UNSIGNED NOT NULL,
+  `contact_id` INT UNSIGNED NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`user_id`, `contact_id`),
+  CONSTRAINT `fk_user_contacts_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_user_contacts_contact` FOREIGN KEY (`contact_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- tin tự huỷ theo room (NULL = tắt), purger xoá tin cũ hơn TTL
+ALTER TABLE `rooms`
+  ADD COLUMN `message_ttl_seconds` INT UNSIGNED DEFAULT NULL;
+
+-- voice message: message_type audio + độ dài (ms) để FE hiện trước khi tải file
+ALTER TABLE `messages`
+  MODIFY COLUMN `message_type` ENUM('text','image','file','audio','system') COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'text',
+  MODIFY COLUMN `reply_message_type` ENUM('text','image','file','audio','system') NULL,
+  ADD COLUMN `media_duration_ms` INT UNSIGNED DEFAULT NULL AFTER `media_poster_url`;
+
+-- ảnh: thumb (320px) + medium (1280px) tạo lúc upload, message list không tải bản gốc
+ALTER TABLE `attachments`
+  ADD COLUMN `thumb_url` VARCHAR(512) NULL AFTER `file_path`,
+  ADD COLUMN `medium_url` VARCHAR(512) NULL AFTER `thumb_url`;
+
+-- preview link trong tin text (OG metadata), url_hash để dùng lại preview cùng URL
+CREATE TABLE IF NOT EXISTS `message_link_previews` (
+  `message_id` INT UNSIGNED NOT NULL,
+  `url` VARCHAR(2048) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `url_hash` CHAR(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `title` VARCHAR(300) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `description` VARCHAR(1000) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `image_url` VARCHAR(2048) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `site_name` VARCHAR(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `fetched_at` DATETIME NOT NULL,
+  PRIMARY KEY (`message_id`),
+  KEY `idx_link_previews_url` (`url_hash`, `fetched_at`),
+  CONSTRAINT `fk_link_previews_message` FOREIGN KEY (`message_id`) REFERENCES `messages` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- incoming webhook: tên / avatar ghi đè theo từng tin (NULL = theo incoming_webhooks)
+ALTER TABLE `messages`
+  ADD COLUMN `webhook_name` VARCHAR(64) COLLATE utf8mb4_unicode_ci DEFAULT NULL AFTER `webhook_id`,
+  ADD COLUMN `webhook_avatar_url` VARCHAR(512) COLLATE utf8mb4_unicode_ci DEFAULT NULL AFTER `webhook_name`;
+
+-- seen watermark / member: đếm seen theo last_seen_message_id, receipt từng tin chỉ giữ cho room nhỏ
+ALTER TABLE `room_members`
+  ADD COLUMN `last_seen_message_id` INT UNSIGNED DEFAULT NULL AFTER `last_seen_at`;
+
+UPDATE `room_members` rm
+JOIN (
+  SELECT room_id, user_id, MAX(message_id) AS last_id
+  FROM `message_receipts`
+  WHERE status = 'seen'
+  GROUP BY room_id, user_id
+) r ON r.room_id = rm.room_id AND r.user_id = rm.user_id
+SET rm.last_seen_message_id = GREATEST(COALESCE(rm.last_seen_message_id, 0), r.last_id);
+
+-- delivered watermark: client ack message_created qua WS (sent -> delivered -> seen)
+ALTER TABLE `room_members`
+  ADD COLUMN `last_delivered_message_id` INT UNSIGNED DEFAULT NULL AFTER `last_seen_message_id`;
+
+-- archive room theo user: ẩn khỏi list room, không bắn unread update
+ALTER TABLE `room_members`
+  ADD COLUMN `archived_at` DATETIME DEFAULT NULL;
+
+-- catalog reaction: chỉ code trong bảng (is_active = 1) mới react được
+-- utf8mb4_bin: unicode_ci coi nhiều emoji khác nhau là bằng nhau
+ALTER TABLE `message_reactions`
+  MODIFY `reaction` VARCHAR(32) COLLATE utf8mb4_bin NOT NULL;
+
+CREATE TABLE IF NOT EXISTS `reaction_emojis` (
+  `code` VARCHAR(32) COLLATE utf8mb4_bin NOT NULL, -- 👍 | like | :party_parrot:
+  `kind` ENUM('unicode','custom') COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'unicode',
+  `glyph` VARCHAR(32) COLLATE utf8mb4_bin DEFAULT NULL,
+  `image_url` VARCHAR(512) COLLATE utf8mb4_unicode_ci DEFAULT NULL, -- custom: /static/chat_uploads/...
+  `label` VARCHAR(64) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `sort_order` INT NOT NULL DEFAULT 0,
+  `is_active` TINYINT(1) NOT NULL DEFAULT 1,
+  `created_by` INT UNSIGNED DEFAULT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`code`),
+  KEY `idx_reaction_emojis_active` (`is_active`, `sort_order`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+INSERT IGNORE INTO `reaction_emojis` (`code`, `kind`, `glyph`, `label`, `sort_order`) VALUES
+  ('like', 'unicode', '👍', 'Like', 10),
+  ('love', 'unicode', '❤️', 'Love', 20),
+  ('laugh', 'unicode', '😂', 'Haha', 30),
+  ('wow', 'unicode', '😮', 'Wow', 40),
+  ('sad', 'unicode', '😢', 'Sad', 50),
+  ('angry', 'unicode', '😡', 'Angry', 60),
+  ('👍', 'unicode', '👍', 'Thumbs up', 100),
+  ('❤️', 'unicode', '❤️', 'Heart', 110),
+  ('😂', 'unicode', '😂', 'Joy', 120),
+  ('😮', 'unicode', '😮', 'Surprised', 130),
+  ('😢', 'unicode', '😢', 'Crying', 140),
+  ('🙏', 'unicode', '🙏', 'Thanks', 150),
+  ('🎉', 'unicode', '🎉', 'Party', 160),
+  ('✅', 'unicode', '✅', 'Done', 170);
+
+-- reaction cũ ngoài danh sách: giữ metadata để hiện, nhưng tắt (không react mới được)
+INSERT IGNORE INTO `reaction_emojis` (`code`, `kind`, `glyph`, `sort_order`, `is_active`)
+SELECT DISTINCT `reaction`, 'unicode', `reaction`, 1000, 0 FROM `message_reactions`;
+
+-- tin bị admin xoá / purge: giữ snapshot nội dung để tra cứu sau
+CREATE TABLE IF NOT EXISTS `message_deletions` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `message_id` INT UNSIGNED NOT NULL,
+  `room_id` INT UNSIGNED NOT NULL,
+  `sender_id` INT UNSIGNED NOT NULL,
+  `deleted_by` INT UNSIGNED NOT NULL,
+  `action` VARCHAR(30) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `message_type` VARCHAR(20) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'text',
+  `content` TEXT COLLATE utf8mb4_unicode_ci,
+  `media_url` TEXT COLLATE utf8mb4_unicode_ci,
+  `note` VARCHAR(1000) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `message_created_at` DATETIME NOT NULL,
+  `deleted_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  KEY `idx_message_deletions_room` (`room_id`, `id`),
+  KEY `idx_message_deletions_message` (`message_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,66 @@
+-- +migrate Up
+-- DB dựng từ database.sql cũ đã có procedure (bản 6 tham số) + trigger -> tạo lại
+DROP TRIGGER IF EXISTS `trg_messages_after_insert`;
+DROP PROCEDURE IF EXISTS `sp_send_message_with_day_sep`;
+
+-- tin thật + tin ngăn cách ngày (system, sender 99999) khi là tin đầu tiên trong ngày của room
+-- +migrate StatementBegin
+CREATE PROCEDURE `sp_send_message_with_day_sep`(
+  IN p_room_id INT UNSIGNED,
+  IN p_sender_id INT UNSIGNED,
+  IN p_content TEXT,
+  IN p_message_type VARCHAR(20),
+  IN p_is_temp TINYINT(1),
+  IN p_reply_to_message_id INT UNSIGNED,
+  IN p_reply_preview VARCHAR(300),
+  IN p_reply_sender_name VARCHAR(255),
+  IN p_reply_message_type VARCHAR(20)
+)
+BEGIN
+  DECLARE v_sys_id INT UNSIGNED DEFAULT 99999;
+  DECLARE v_created DATETIME;
+  DECLARE v_day DATE;
+  DECLARE v_label VARCHAR(64);
+
+  SET v_created = NOW();
+  SET v_day = DATE(v_created);
+  SET v_label = CONCAT('--- ', DATE_FORMAT(v_day, '%Y-%m-%d'), ' ---');
+
+  IF p_message_type <> 'system' AND NOT EXISTS (
+    SELECT 1
+    FROM messages m
+    WHERE m.room_id = p_room_id
+      AND m.sender_id = v_sys_id
+      AND m.message_type = 'system'
+      AND m.content = v_label
+      AND m.created_at >= v_day
+    LIMIT 1
+  ) THEN
+    INSERT INTO messages (room_id, sender_id, content, message_type, is_temp, created_at)
+    VALUES (p_room_id, v_sys_id, v_label, 'system', 0, TIMESTAMP(v_day));
+  END IF;
+
+  INSERT INTO messages (
+    room_id, sender_id,
+    reply_to_message_id, reply_preview, reply_sender_name, reply_message_type,
+    content, message_type, is_temp, created_at
+  )
+  VALUES (
+    p_room_id, p_sender_id,
+    p_reply_to_message_id, p_reply_preview, p_reply_sender_name, p_reply_message_type,
+    p_content, p_message_type, IFNULL(p_is_temp, 0), v_created
+  );
+END
+-- +migrate StatementEnd
+
+-- room lên đầu list khi có tin mới
+-- +migrate StatementBegin
+CREATE TRIGGER `trg_messages_after_insert` AFTER INSERT ON `messages` FOR EACH ROW
+BEGIN
+  UPDATE rooms SET updated_at = NEW.created_at WHERE id = NEW.room_id;
+END
+-- +migrate StatementEnd
+
+-- +migrate Down
+DROP TRIGGER IF EXISTS `trg_messages_after_insert`;
+DROP PROCEDURE IF EXISTS `sp_send_message_with_day_sep`;
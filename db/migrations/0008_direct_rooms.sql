@@ -0,0 +1,31 @@
+-- +migrate Up
+-- room direct: mỗi cặp user đúng 1 room, cặp lưu theo thứ tự user_a < user_b
+-- (trước đây chỉ tìm qua room_members -> 2 request cùng lúc tạo được 2 room)
+CREATE TABLE IF NOT EXISTS `direct_rooms` (
+  `user_a` INT UNSIGNED NOT NULL,
+  `user_b` INT UNSIGNED NOT NULL,
+  `room_id` INT UNSIGNED NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`user_a`, `user_b`),
+  UNIQUE KEY `uq_direct_rooms_room` (`room_id`),
+  CONSTRAINT `chk_direct_rooms_pair` CHECK (`user_a` < `user_b`),
+  CONSTRAINT `fk_direct_rooms_room`
+    FOREIGN KEY (`room_id`) REFERENCES `rooms` (`id`)
+    ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- backfill: room direct đúng 2 member, cặp bị trùng thì giữ room cũ nhất (id nhỏ nhất)
+INSERT IGNORE INTO `direct_rooms` (`user_a`, `user_b`, `room_id`, `created_at`)
+SELECT p.user_a, p.user_b, p.room_id, r.created_at
+FROM (
+  SELECT m.room_id, MIN(m.user_id) AS user_a, MAX(m.user_id) AS user_b
+  FROM room_members m
+  JOIN rooms x ON x.id = m.room_id AND x.type = 'direct'
+  GROUP BY m.room_id
+  HAVING COUNT(*) = 2
+) p
+JOIN rooms r ON r.id = p.room_id
+ORDER BY p.room_id;
+
+-- +migrate Down
+DROP TABLE IF EXISTS `direct_rooms`;
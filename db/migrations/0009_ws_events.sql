@@ -0,0 +1,26 @@
+-- +migrate Up
+-- journal WS event để client kết nối lại gửi last_seq và nhận lại event bị lỡ
+-- id = seq (tăng dần toàn cục, mỗi user thấy 1 dãy con tăng dần), payload = data của envelope
+CREATE TABLE IF NOT EXISTS `ws_events` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `type` VARCHAR(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `room_id` INT UNSIGNED DEFAULT NULL,
+  `payload` JSON DEFAULT NULL,
+  `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+  PRIMARY KEY (`id`),
+  KEY `idx_ws_events_created` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `ws_event_recipients` (
+  `user_id` INT UNSIGNED NOT NULL,
+  `event_id` BIGINT UNSIGNED NOT NULL,
+  PRIMARY KEY (`user_id`, `event_id`),
+  KEY `idx_ws_event_recipients_event` (`event_id`),
+  CONSTRAINT `fk_ws_event_recipients_event`
+    FOREIGN KEY (`event_id`) REFERENCES `ws_events` (`id`)
+    ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +migrate Down
+DROP TABLE IF EXISTS `ws_event_recipients`;
+DROP TABLE IF EXISTS `ws_events`;
@@ -0,0 +1,11 @@
+-- +migrate Up
+-- id do client sinh khi gửi tin: retry sau lỗi mạng trả lại tin cũ thay vì tạo tin trùng
+-- NULL (tin cũ, bot, webhook, hệ thống) không dính unique
+ALTER TABLE `messages`
+  ADD COLUMN `client_msg_id` VARCHAR(64) COLLATE utf8mb4_unicode_ci NULL AFTER `sender_id`,
+  ADD UNIQUE KEY `uq_messages_sender_client_msg` (`sender_id`, `client_msg_id`);
+
+-- +migrate Down
+ALTER TABLE `messages`
+  DROP INDEX `uq_messages_sender_client_msg`,
+  DROP COLUMN `client_msg_id`;
@@ -0,0 +1,8 @@
+-- +migrate Up
+-- GET /messages/reactions/{id}/users?reaction= phân trang theo id: lọc reaction + seek id không phải sort
+ALTER TABLE `message_reactions`
+  ADD KEY `idx_reaction_message_reaction_id` (`message_id`, `reaction`, `id`);
+
+-- +migrate Down
+ALTER TABLE `message_reactions`
+  DROP INDEX `idx_reaction_message_reaction_id`;
@@ -0,0 +1,18 @@
+-- +migrate Up
+-- quét virus file upload (SCAN_PROVIDER): file không sạch bị cách ly, tin trỏ tới file bị chặn
+ALTER TABLE `chat_uploads`
+  ADD COLUMN `quarantined_at` DATETIME NULL AFTER `file_size`,
+  ADD COLUMN `scan_signature` VARCHAR(255) COLLATE utf8mb4_unicode_ci NULL AFTER `quarantined_at`;
+
+ALTER TABLE `messages`
+  ADD COLUMN `blocked_at` DATETIME NULL AFTER `media_duration_ms`,
+  ADD COLUMN `blocked_reason` VARCHAR(255) COLLATE utf8mb4_unicode_ci NULL AFTER `blocked_at`;
+
+-- +migrate Down
+ALTER TABLE `messages`
+  DROP COLUMN `blocked_reason`,
+  DROP COLUMN `blocked_at`;
+
+ALTER TABLE `chat_uploads`
+  DROP COLUMN `scan_signature`,
+  DROP COLUMN `quarantined_at`;
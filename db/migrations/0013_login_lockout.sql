@@ -0,0 +1,14 @@
+-- +migrate Up
+-- đếm đăng nhập sai theo username / IP, quá ngưỡng trong cửa sổ -> khoá tạm (locked_until)
+CREATE TABLE IF NOT EXISTS `login_throttle` (
+  `scope` VARCHAR(10) COLLATE utf8mb4_unicode_ci NOT NULL, -- user | ip
+  `lock_key` VARCHAR(191) COLLATE utf8mb4_unicode_ci NOT NULL, -- username / IP
+  `failures` INT NOT NULL DEFAULT 0,
+  `first_failed_at` DATETIME NOT NULL,
+  `locked_until` DATETIME NULL,
+  PRIMARY KEY (`scope`, `lock_key`),
+  KEY `idx_login_throttle_first_failed` (`first_failed_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +migrate Down
+DROP TABLE IF EXISTS `login_throttle`;
@@ -0,0 +1,18 @@
+-- +migrate Up
+-- tài khoản Google / GitHub đã link với user (1 user có thể link nhiều provider)
+CREATE TABLE IF NOT EXISTS `oauth_identities` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `user_id` INT UNSIGNED NOT NULL,
+  `provider` VARCHAR(30) COLLATE utf8mb4_unicode_ci NOT NULL, -- google | github
+  `subject` VARCHAR(191) COLLATE utf8mb4_unicode_ci NOT NULL, -- sub (OIDC) / id GitHub
+  `email` VARCHAR(255) COLLATE utf8mb4_unicode_ci NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `last_login_at` DATETIME NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_oauth_identities_provider_subject` (`provider`, `subject`),
+  KEY `idx_oauth_identities_user` (`user_id`),
+  CONSTRAINT `fk_oauth_identities_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +migrate Down
+DROP TABLE IF EXISTS `oauth_identities`;
@@ -0,0 +1,23 @@
+-- +migrate Up
+-- API key cho service ngoài / cron (header X-API-Key): hành động dưới tên user_id, giới hạn theo scopes
+CREATE TABLE IF NOT EXISTS `api_keys` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `name` VARCHAR(100) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `key_prefix` VARCHAR(16) COLLATE utf8mb4_unicode_ci NOT NULL, -- vài ký tự đầu, để nhận ra key
+  `key_hash` CHAR(64) COLLATE utf8mb4_unicode_ci NOT NULL, -- sha256 hex của key
+  `user_id` INT UNSIGNED NOT NULL,
+  `scopes` VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL, -- send_message,read_rooms
+  `created_by` INT UNSIGNED NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `expires_at` DATETIME NULL,
+  `last_used_at` DATETIME NULL,
+  `revoked_at` DATETIME NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_api_keys_hash` (`key_hash`),
+  KEY `idx_api_keys_user` (`user_id`),
+  CONSTRAINT `fk_api_keys_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_api_keys_created_by` FOREIGN KEY (`created_by`) REFERENCES `users` (`id`) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +migrate Down
+DROP TABLE IF EXISTS `api_keys`;
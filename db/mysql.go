@@ -2,8 +2,9 @@ package db
 
 import (
 	"database/sql"
+	"database/sql/driver"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
 func OpenMySQL(dsn string) (*sql.DB, error) {
@@ -13,3 +14,16 @@ func OpenMySQL(dsn string) (*sql.DB, error) {
 	}
 	return db, nil
 }
+
+// OpenMySQLWrapped: như OpenMySQL nhưng bọc driver (vd gắn request id vào lỗi query)
+func OpenMySQLWrapped(dsn string, wrap func(driver.Connector) driver.Connector) (*sql.DB, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	c, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(wrap(c)), nil
+}